@@ -1,6 +1,8 @@
 DROP TABLE IF EXISTS payments;
 DROP TABLE IF EXISTS balances;
 DROP TABLE IF EXISTS accounts;
+DROP TABLE IF EXISTS allowance_templates;
+DROP TABLE IF EXISTS renter_tokens;
 
 CREATE TABLE accounts (
 	id             INT NOT NULL AUTO_INCREMENT,
@@ -36,6 +38,33 @@ CREATE TABLE payments (
 	FOREIGN KEY (email) REFERENCES accounts(email)
 );
 
+CREATE TABLE allowance_templates (
+	id                           INT NOT NULL AUTO_INCREMENT,
+	name                         VARCHAR(64) NOT NULL UNIQUE,
+	funds                        VARCHAR(64) NOT NULL,
+	hosts                        BIGINT UNSIGNED NOT NULL,
+	period                       BIGINT UNSIGNED NOT NULL,
+	renew_window                 BIGINT UNSIGNED NOT NULL,
+	expected_storage             BIGINT UNSIGNED NOT NULL,
+	expected_upload              BIGINT UNSIGNED NOT NULL,
+	expected_download            BIGINT UNSIGNED NOT NULL,
+	expected_redundancy          DOUBLE NOT NULL,
+	max_rpc_price                VARCHAR(64) NOT NULL,
+	max_contract_price           VARCHAR(64) NOT NULL,
+	max_download_bandwidth_price VARCHAR(64) NOT NULL,
+	max_sector_access_price      VARCHAR(64) NOT NULL,
+	max_storage_price            VARCHAR(64) NOT NULL,
+	max_upload_bandwidth_price   VARCHAR(64) NOT NULL,
+	PRIMARY KEY (id)
+);
+
+CREATE TABLE renter_tokens (
+	renter_pk  VARCHAR(128) NOT NULL,
+	token_hash VARCHAR(64) NOT NULL,
+	created    BIGINT NOT NULL,
+	PRIMARY KEY (renter_pk)
+);
+
 DROP TABLE IF EXISTS hosts;
 DROP TABLE IF EXISTS scanhistory;
 DROP TABLE IF EXISTS ipnets;
@@ -99,10 +128,17 @@ CREATE TABLE ipnets (
 DROP TABLE IF EXISTS renters;
 DROP TABLE IF EXISTS contracts;
 DROP TABLE IF EXISTS transactions;
+DROP TABLE IF EXISTS contract_utility_history;
+DROP TABLE IF EXISTS contract_payouts;
+DROP TABLE IF EXISTS contract_no_refresh;
+DROP TABLE IF EXISTS contract_cancellations;
+DROP TABLE IF EXISTS contract_formation_scores;
+DROP TABLE IF EXISTS renter_host_allowlists;
 
 CREATE TABLE renters (
 	id                           INT NOT NULL AUTO_INCREMENT,
-	email                        VARCHAR(64) NOT NULL UNIQUE,
+	email                        VARCHAR(64) NOT NULL,
+	suffix                       VARCHAR(64) NOT NULL,
 	public_key                   VARCHAR(128) NOT NULL UNIQUE,
 	current_period               BIGINT UNSIGNED NOT NULL,
 	funds                        VARCHAR(64) NOT NULL,
@@ -118,8 +154,19 @@ CREATE TABLE renters (
 	max_download_bandwidth_price VARCHAR(64) NOT NULL,
 	max_sector_access_price      VARCHAR(64) NOT NULL,
 	max_storage_price            VARCHAR(64) NOT NULL,
-	max_upload_bandwidth_price   VARCHAR(64) NOT NULL,	
+	max_upload_bandwidth_price   VARCHAR(64) NOT NULL,
+	allow_redundant_ips          BOOL NOT NULL,
+	max_storage_bytes            BIGINT UNSIGNED NOT NULL,
+	max_contracts_per_region     BIGINT UNSIGNED NOT NULL,
+	paused                       BOOL NOT NULL,
+	prefer_collateral            BOOL NOT NULL,
+	spend_rate_refresh           BOOL NOT NULL,
+	max_host_latency             BIGINT UNSIGNED NOT NULL,
+	local_region                 VARCHAR(8) NOT NULL,
+	local_fraction               DOUBLE NOT NULL,
+	reuse_refund_address         BOOL NOT NULL,
 	PRIMARY KEY (id),
+	UNIQUE (email, suffix),
 	FOREIGN KEY (email) REFERENCES accounts(email)
 );
 
@@ -184,3 +231,50 @@ CREATE TABLE transactions (
 	PRIMARY KEY (id),
 	FOREIGN KEY (contract_id) REFERENCES contracts(contract_id)
 );
+
+CREATE TABLE contract_utility_history (
+	id                  INT NOT NULL AUTO_INCREMENT,
+	contract_id         VARCHAR(64) NOT NULL,
+	old_good_for_upload BOOL NOT NULL,
+	old_good_for_renew  BOOL NOT NULL,
+	old_bad_contract    BOOL NOT NULL,
+	old_locked          BOOL NOT NULL,
+	new_good_for_upload BOOL NOT NULL,
+	new_good_for_renew  BOOL NOT NULL,
+	new_bad_contract    BOOL NOT NULL,
+	new_locked          BOOL NOT NULL,
+	height              BIGINT UNSIGNED NOT NULL,
+	reason              VARCHAR(255) NOT NULL,
+	PRIMARY KEY (id)
+);
+
+CREATE TABLE contract_payouts (
+	contract_id VARCHAR(64) NOT NULL,
+	renter_pk   VARCHAR(128) NOT NULL,
+	amount      VARCHAR(64) NOT NULL,
+	height      BIGINT UNSIGNED NOT NULL,
+	PRIMARY KEY (contract_id)
+);
+
+CREATE TABLE contract_no_refresh (
+	contract_id VARCHAR(64) NOT NULL,
+	PRIMARY KEY (contract_id)
+);
+
+CREATE TABLE contract_cancellations (
+	contract_id VARCHAR(64) NOT NULL,
+	height      BIGINT UNSIGNED NOT NULL,
+	PRIMARY KEY (contract_id)
+);
+
+CREATE TABLE contract_formation_scores (
+	contract_id VARCHAR(64) NOT NULL,
+	score       VARCHAR(64) NOT NULL,
+	PRIMARY KEY (contract_id)
+);
+
+CREATE TABLE renter_host_allowlists (
+	renter_pk VARCHAR(128) NOT NULL,
+	host_pk   VARCHAR(128) NOT NULL,
+	PRIMARY KEY (renter_pk, host_pk)
+);
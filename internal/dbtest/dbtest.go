@@ -0,0 +1,207 @@
+// Package dbtest provides an in-memory database/sql driver for the tests.
+// It does not interpret SQL. The executed statements are recorded, and
+// the results of the queries and statements are supplied by the hooks of
+// the test.
+package dbtest
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Statement is an executed statement with its arguments.
+type Statement struct {
+	Query string
+	Args  []driver.Value
+}
+
+// Rows is the result of a query.
+type Rows struct {
+	Columns []string
+	Values  [][]driver.Value
+}
+
+// DB is the state of a database opened with Open.
+type DB struct {
+	mu        sync.Mutex
+	execs     []Statement
+	execHook  func(query string, args []driver.Value) error
+	queryHook func(query string, args []driver.Value) (*Rows, error)
+}
+
+var (
+	registerOnce sync.Once
+	mu           sync.Mutex
+	dbs          = make(map[string]*DB)
+	counter      int
+)
+
+// Open returns a new empty database. By default, all statements succeed,
+// the COUNT queries return zero, and all other queries return no rows.
+func Open() (*sql.DB, *DB) {
+	registerOnce.Do(func() {
+		sql.Register("dbtest", testDriver{})
+	})
+	mu.Lock()
+	counter++
+	name := strconv.Itoa(counter)
+	d := &DB{}
+	dbs[name] = d
+	mu.Unlock()
+	db, _ := sql.Open("dbtest", name)
+	return db, d
+}
+
+// OnExec sets the function called for every executed statement. A
+// non-nil error fails the statement.
+func (d *DB) OnExec(hook func(query string, args []driver.Value) error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.execHook = hook
+}
+
+// OnQuery sets the function called for every query. If it returns nil
+// rows, the default result is used.
+func (d *DB) OnQuery(hook func(query string, args []driver.Value) (*Rows, error)) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.queryHook = hook
+}
+
+// Execs returns the statements executed so far, including BEGIN, COMMIT
+// and ROLLBACK.
+func (d *DB) Execs() []Statement {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]Statement(nil), d.execs...)
+}
+
+// ExecsLike returns the executed statements containing the substring.
+func (d *DB) ExecsLike(substr string) []Statement {
+	var matches []Statement
+	for _, s := range d.Execs() {
+		if strings.Contains(s.Query, substr) {
+			matches = append(matches, s)
+		}
+	}
+	return matches
+}
+
+// exec records the statement and runs the hook.
+func (d *DB) exec(query string, args []driver.Value) error {
+	d.mu.Lock()
+	hook := d.execHook
+	d.mu.Unlock()
+	if hook != nil {
+		if err := hook(query, args); err != nil {
+			return err
+		}
+	}
+	d.mu.Lock()
+	d.execs = append(d.execs, Statement{Query: query, Args: args})
+	d.mu.Unlock()
+	return nil
+}
+
+// query runs the query hook or returns the default result.
+func (d *DB) query(query string, args []driver.Value) (*Rows, error) {
+	d.mu.Lock()
+	hook := d.queryHook
+	d.mu.Unlock()
+	if hook != nil {
+		rows, err := hook(query, args)
+		if err != nil || rows != nil {
+			return rows, err
+		}
+	}
+	if strings.Contains(query, "COUNT(") {
+		return &Rows{Columns: []string{"count"}, Values: [][]driver.Value{{int64(0)}}}, nil
+	}
+	return &Rows{}, nil
+}
+
+type testDriver struct{}
+
+func (testDriver) Open(name string) (driver.Conn, error) {
+	mu.Lock()
+	d, exists := dbs[name]
+	mu.Unlock()
+	if !exists {
+		return nil, errors.New("unknown database")
+	}
+	return &conn{db: d}, nil
+}
+
+type conn struct {
+	db *DB
+}
+
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	return &stmt{db: c.db, query: query}, nil
+}
+
+func (c *conn) Close() error { return nil }
+
+func (c *conn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *conn) BeginTx(_ context.Context, _ driver.TxOptions) (driver.Tx, error) {
+	if err := c.db.exec("BEGIN", nil); err != nil {
+		return nil, err
+	}
+	return &tx{db: c.db}, nil
+}
+
+type tx struct {
+	db *DB
+}
+
+func (t *tx) Commit() error   { return t.db.exec("COMMIT", nil) }
+func (t *tx) Rollback() error { return t.db.exec("ROLLBACK", nil) }
+
+type stmt struct {
+	db    *DB
+	query string
+}
+
+func (s *stmt) Close() error  { return nil }
+func (s *stmt) NumInput() int { return -1 }
+
+func (s *stmt) Exec(args []driver.Value) (driver.Result, error) {
+	if err := s.db.exec(s.query, args); err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (s *stmt) Query(args []driver.Value) (driver.Rows, error) {
+	r, err := s.db.query(s.query, args)
+	if err != nil {
+		return nil, err
+	}
+	return &rows{r: r}, nil
+}
+
+type rows struct {
+	r   *Rows
+	pos int
+}
+
+func (r *rows) Columns() []string { return r.r.Columns }
+func (r *rows) Close() error      { return nil }
+
+func (r *rows) Next(dest []driver.Value) error {
+	if r.pos >= len(r.r.Values) {
+		return io.EOF
+	}
+	copy(dest, r.r.Values[r.pos])
+	r.pos++
+	return nil
+}
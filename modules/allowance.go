@@ -0,0 +1,71 @@
+package modules
+
+import (
+	"fmt"
+
+	"gitlab.com/NebulousLabs/errors"
+
+	smodules "go.sia.tech/siad/modules"
+)
+
+var (
+	// ErrAllowanceZeroFunds is returned if the allowance funds are zero.
+	ErrAllowanceZeroFunds = errors.New("funds must be non-zero")
+	// ErrAllowanceNoHosts is returned if the allowance's hosts are zero.
+	ErrAllowanceNoHosts = errors.New("hosts must be non-zero")
+	// ErrAllowanceZeroPeriod is returned if the allowance period is zero.
+	ErrAllowanceZeroPeriod = errors.New("period must be non-zero")
+	// ErrAllowanceZeroWindow is returned if the allowance's renew window is
+	// zero.
+	ErrAllowanceZeroWindow = errors.New("renew window must be non-zero")
+	// ErrAllowanceWindowSize is returned if the allowance's renew window is
+	// not shorter than the period.
+	ErrAllowanceWindowSize = errors.New("renew window must be less than period")
+	// ErrAllowanceZeroExpectedStorage is returned if the allowance's expected
+	// storage is zero.
+	ErrAllowanceZeroExpectedStorage = errors.New("expected storage must be non-zero")
+	// ErrAllowanceZeroExpectedRedundancy is returned if the allowance's
+	// expected redundancy is zero.
+	ErrAllowanceZeroExpectedRedundancy = errors.New("expected redundancy must be non-zero")
+	// ErrAllowanceBadShards is returned if the erasure coding parameters
+	// are invalid.
+	ErrAllowanceBadShards = errors.New("min shards must be non-zero and not exceed total shards")
+)
+
+// ValidateAllowance performs the sanity checks of the allowance shared by
+// the API and the provider.
+//
+// minShards and totalShards are the erasure coding parameters of a renter
+// request, from which the expected redundancy is derived. If both are zero,
+// the expected redundancy must be set instead. If they are set, the funds
+// may be zero, because the satellite estimates them for such requests.
+func ValidateAllowance(a smodules.Allowance, minShards, totalShards uint64) error {
+	if a.Hosts == 0 {
+		return ErrAllowanceNoHosts
+	}
+	if a.Period == 0 {
+		return ErrAllowanceZeroPeriod
+	}
+	if a.RenewWindow == 0 {
+		return ErrAllowanceZeroWindow
+	}
+	if a.RenewWindow >= a.Period {
+		return errors.AddContext(ErrAllowanceWindowSize, fmt.Sprintf("renew window of %v blocks, period of %v blocks", a.RenewWindow, a.Period))
+	}
+	if a.ExpectedStorage == 0 {
+		return ErrAllowanceZeroExpectedStorage
+	}
+	if minShards == 0 && totalShards == 0 {
+		if a.ExpectedRedundancy == 0 {
+			return ErrAllowanceZeroExpectedRedundancy
+		}
+		if a.Funds.IsZero() {
+			return ErrAllowanceZeroFunds
+		}
+		return nil
+	}
+	if minShards == 0 || minShards > totalShards {
+		return ErrAllowanceBadShards
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package modules
+
+import (
+	"testing"
+
+	"gitlab.com/NebulousLabs/errors"
+
+	smodules "go.sia.tech/siad/modules"
+	"go.sia.tech/siad/types"
+)
+
+// TestValidateAllowance tests each invariant of the allowance.
+func TestValidateAllowance(t *testing.T) {
+	valid := smodules.Allowance{
+		Funds:              types.SiacoinPrecision.Mul64(1000),
+		Hosts:              10,
+		Period:             1000,
+		RenewWindow:        100,
+		ExpectedStorage:    1e12,
+		ExpectedRedundancy: 3,
+	}
+	tests := []struct {
+		name        string
+		update      func(*smodules.Allowance)
+		minShards   uint64
+		totalShards uint64
+		err         error
+	}{
+		{"valid", func(*smodules.Allowance) {}, 0, 0, nil},
+		{"valid shards", func(a *smodules.Allowance) { a.Funds, a.ExpectedRedundancy = types.ZeroCurrency, 0 }, 10, 30, nil},
+		{"equal shards", func(*smodules.Allowance) {}, 10, 10, nil},
+		{"zero funds", func(a *smodules.Allowance) { a.Funds = types.ZeroCurrency }, 0, 0, ErrAllowanceZeroFunds},
+		{"zero hosts", func(a *smodules.Allowance) { a.Hosts = 0 }, 0, 0, ErrAllowanceNoHosts},
+		{"zero period", func(a *smodules.Allowance) { a.Period = 0 }, 0, 0, ErrAllowanceZeroPeriod},
+		{"zero renew window", func(a *smodules.Allowance) { a.RenewWindow = 0 }, 0, 0, ErrAllowanceZeroWindow},
+		{"renew window equals period", func(a *smodules.Allowance) { a.RenewWindow = a.Period }, 0, 0, ErrAllowanceWindowSize},
+		{"renew window exceeds period", func(a *smodules.Allowance) { a.RenewWindow = a.Period + 1 }, 0, 0, ErrAllowanceWindowSize},
+		{"zero expected storage", func(a *smodules.Allowance) { a.ExpectedStorage = 0 }, 0, 0, ErrAllowanceZeroExpectedStorage},
+		{"zero expected redundancy", func(a *smodules.Allowance) { a.ExpectedRedundancy = 0 }, 0, 0, ErrAllowanceZeroExpectedRedundancy},
+		{"zero min shards", func(*smodules.Allowance) {}, 0, 30, ErrAllowanceBadShards},
+		{"min shards exceed total", func(*smodules.Allowance) {}, 31, 30, ErrAllowanceBadShards},
+	}
+	for _, tt := range tests {
+		a := valid
+		tt.update(&a)
+		err := ValidateAllowance(a, tt.minShards, tt.totalShards)
+		if tt.err == nil && err != nil {
+			t.Errorf("%v: expected no error, got %v", tt.name, err)
+		} else if tt.err != nil && !errors.Contains(err, tt.err) {
+			t.Errorf("%v: expected %v, got %v", tt.name, tt.err, err)
+		}
+	}
+}
@@ -1,6 +1,9 @@
 package modules
 
 import (
+	"context"
+	"time"
+
 	"go.sia.tech/siad/crypto"
 	smodules "go.sia.tech/siad/modules"
 	"go.sia.tech/siad/types"
@@ -56,6 +59,10 @@ type Satellite interface {
 	// SetFilterMode sets the hostdb's filter mode.
 	SetFilterMode(smodules.FilterMode, []types.SiaPublicKey, []string) error
 
+	// UpdateFilter atomically adds entries to and removes entries from the
+	// hostdb's filter list.
+	UpdateFilter(addHosts, removeHosts []types.SiaPublicKey, addNetAddresses, removeNetAddresses []string) (smodules.FilterMode, map[string]types.SiaPublicKey, []string, error)
+
 	// Host provides the DB entry and score breakdown for the requested host.
 	Host(types.SiaPublicKey) (smodules.HostDBEntry, bool, error)
 
@@ -93,6 +100,129 @@ type Satellite interface {
 	// Renters retrieves the list of renters.
 	Renters() []Renter
 
+	// AddRenter creates a new renter with the initial allowance.
+	AddRenter(string, types.SiaPublicKey, smodules.Allowance) (Renter, error)
+
+	// AllowanceTemplates returns the allowance templates.
+	AllowanceTemplates() ([]AllowanceTemplate, error)
+
+	// AllowanceTemplate returns the allowance template with the given name.
+	AllowanceTemplate(string) (AllowanceTemplate, error)
+
+	// SaveAllowanceTemplate creates or replaces an allowance template.
+	SaveAllowanceTemplate(AllowanceTemplate) error
+
+	// DeleteAllowanceTemplate removes the allowance template.
+	DeleteAllowanceTemplate(string) error
+
+	// IssueRenterToken generates a new API token giving access to the
+	// renter's own data.
+	IssueRenterToken(types.SiaPublicKey) (string, error)
+
+	// RevokeRenterToken removes the renter's API token.
+	RevokeRenterToken(types.SiaPublicKey) error
+
+	// VerifyRenterToken returns true if the token is the renter's API
+	// token.
+	VerifyRenterToken(types.SiaPublicKey, string) bool
+
+	// CheckRenterConsistency compares the renters in the database with the
+	// ones in memory, optionally repairing the differences.
+	CheckRenterConsistency(bool) ([]RenterInconsistency, error)
+
+	// ContractorSettings returns the contractor tunables.
+	ContractorSettings() ContractorSettings
+
+	// SetContractorSettings validates and applies the contractor
+	// tunables.
+	SetContractorSettings(ContractorSettings) error
+
+	// DebugSnapshot returns a copy of the contractor's internal maps.
+	DebugSnapshot() ContractorSnapshot
+
+	// DeleteRenters deletes the renters with the given emails. The
+	// renters with active contracts are only deleted if forced. The
+	// deletion has to be confirmed with a token.
+	DeleteRenters([]string, bool, string) ([]RenterDeletion, error)
+
+	// MaintenanceTimings returns the phase timings of the last contract
+	// maintenance cycle.
+	MaintenanceTimings() MaintenanceTimings
+
+	// SetNoRefresh sets whether the contract is excluded from the
+	// refreshes.
+	SetNoRefresh(types.FileContractID, bool) error
+
+	// FormationScore returns the host score of the contract at the time
+	// it was formed.
+	FormationScore(types.FileContractID) (types.Currency, bool)
+
+	// WalletUsage reports how the wallet addresses are used by the
+	// contracts.
+	WalletUsage() (WalletUsage, error)
+
+	// HostDecision reports which of the contract formation checks the host
+	// passes for the renter's allowance.
+	HostDecision(types.SiaPublicKey, types.SiaPublicKey) (HostDecision, error)
+
+	// RenewAllDue renews the due contracts of all renters within the given
+	// budget.
+	RenewAllDue(types.Currency) ([]RenewalSummary, error)
+
+	// ContractsDue returns the contracts of all renters that end within
+	// the given number of blocks.
+	ContractsDue(types.BlockHeight) ([]DueContract, error)
+
+	// RenewalFeeBreakdown returns the fees included in the renewal
+	// estimates of the renter's contracts.
+	RenewalFeeBreakdown(types.SiaPublicKey) ([]RenewalFeeBreakdown, error)
+
+	// Runway projects whether the renter's funds last until the end of
+	// the current period.
+	Runway(types.SiaPublicKey) (RenterRunway, error)
+
+	// SetAllowRedundantIPs sets whether the renter's contracts with the
+	// hosts sharing an address range are kept.
+	SetAllowRedundantIPs(types.SiaPublicKey, bool) error
+
+	// SetMaxStorageBytes sets the renter's storage quota.
+	SetMaxStorageBytes(types.SiaPublicKey, uint64) error
+
+	// SetMaxContractsPerRegion sets the renter's region cap.
+	SetMaxContractsPerRegion(types.SiaPublicKey, uint64) error
+
+	// SetPreferCollateral toggles the renter's collateral preference.
+	SetPreferCollateral(types.SiaPublicKey, bool) error
+
+	// SetSpendRateRefresh toggles the renter's refresh sizing strategy.
+	SetSpendRateRefresh(types.SiaPublicKey, bool) error
+
+	// SetMaxHostLatency sets the maximum host response time for the
+	// renter's contract formation.
+	SetMaxHostLatency(types.SiaPublicKey, time.Duration) error
+
+	// SetLocalRegion sets the region where the given fraction of the
+	// renter's contracts is formed.
+	SetLocalRegion(types.SiaPublicKey, string, float64) error
+
+	// SetReuseRefundAddress sets whether the renter's contracts share a
+	// single refund address.
+	SetReuseRefundAddress(types.SiaPublicKey, bool) error
+
+	// HostAllowlist returns the hosts that the renter's contracts are
+	// restricted to.
+	HostAllowlist(types.SiaPublicKey) ([]types.SiaPublicKey, error)
+
+	// SetHostAllowlist restricts the renter's new contracts to the given
+	// hosts. An empty list removes the restriction.
+	SetHostAllowlist(types.SiaPublicKey, []types.SiaPublicKey) error
+
+	// PauseRenter freezes all automated contract activity of the renter.
+	PauseRenter(string) error
+
+	// ResumeRenter restores the automated contract activity of the renter.
+	ResumeRenter(string) error
+
 	// GetBalance retrieves the balance information on the account.
 	GetBalance(string) (*UserBalance, error)
 
@@ -104,6 +234,30 @@ type Satellite interface {
 
 	// OldContracts returns the contracts that have expired.
 	OldContracts() []RenterContract
+
+	// ContractLineage returns the renewal chain of the contract.
+	ContractLineage(types.FileContractID) ([]ContractLineageLink, error)
+
+	// ContractUtilityHistory returns the utility transitions of the
+	// contract.
+	ContractUtilityHistory(types.FileContractID) ([]UtilityTransition, error)
+
+	// WatchdogStatus returns the state of the contract watchdog.
+	WatchdogStatus() WatchdogStatus
+
+	// RenewFailures returns the contracts with failed renewals.
+	RenewFailures() []RenewFailure
+
+	// FundsAtRisk returns the renter funds locked in unhealthy contracts.
+	FundsAtRisk() FundsAtRisk
+
+	// FundAudit compares the renter's locked funds with the cost of the
+	// renter's active contracts.
+	FundAudit(types.SiaPublicKey) (FundAudit, error)
+
+	// RedundancyHealth reports the redundancy the renter's GoodForUpload
+	// contracts allow for the given erasure coding parameters.
+	RedundancyHealth(types.SiaPublicKey, uint64, uint64) (RedundancyHealth, error)
 }
 
 // Manager implements the methods necessary to communicate with the
@@ -167,6 +321,10 @@ type HostDB interface {
 	// SetFilterMode sets the renter's hostdb filter mode.
 	SetFilterMode(smodules.FilterMode, []types.SiaPublicKey, []string) error
 
+	// UpdateFilter atomically adds entries to and removes entries from the
+	// filter list, keeping the filter mode.
+	UpdateFilter(addHosts, removeHosts []types.SiaPublicKey, addNetAddresses, removeNetAddresses []string) (smodules.FilterMode, map[string]types.SiaPublicKey, []string, error)
+
 	// Host returns the HostDBEntry for a given host.
 	Host(pk types.SiaPublicKey) (smodules.HostDBEntry, bool, error)
 
@@ -200,6 +358,10 @@ type HostDB interface {
 	// limits set in the allowance instead of calculating the weight function.
 	RandomHostsWithLimits(int, []types.SiaPublicKey, []types.SiaPublicKey, smodules.Allowance) ([]smodules.HostDBEntry, error)
 
+	// RandomHostsWithLimitsSeeded works as RandomHostsWithLimits but makes
+	// the selection repeatable for the given seed.
+	RandomHostsWithLimitsSeeded(int, []types.SiaPublicKey, []types.SiaPublicKey, smodules.Allowance, int64) ([]smodules.HostDBEntry, error)
+
 	// ScoreBreakdown returns a detailed explanation of the various properties
 	// of the host.
 	ScoreBreakdown(smodules.HostDBEntry) (smodules.HostScoreBreakdown, error)
@@ -237,6 +399,9 @@ type ContractFormer interface {
 	PublicKey() types.SiaPublicKey
 	SecretKey() crypto.SecretKey
 	UserExists(rpk types.SiaPublicKey) (bool, error)
-	FormContracts(types.SiaPublicKey, smodules.Allowance) ([]RenterContract, error)
+	FormContracts(context.Context, types.SiaPublicKey, smodules.Allowance, []types.SiaPublicKey) ([]RenterContract, error)
+	PreviewContracts(context.Context, types.SiaPublicKey, smodules.Allowance, []types.SiaPublicKey) ([]FormationPreview, error)
 	RenewContracts(types.SiaPublicKey, smodules.Allowance, []types.FileContractID) ([]RenterContract, error)
+	RenterContracts(types.SiaPublicKey) []RenterContract
+	BlockHeight() types.BlockHeight
 }
@@ -1,6 +1,12 @@
 package modules
 
 import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"gitlab.com/NebulousLabs/errors"
 	"gitlab.com/NebulousLabs/fastrand"
 
 	"go.sia.tech/siad/crypto"
@@ -8,6 +14,24 @@ import (
 	"go.sia.tech/siad/types"
 )
 
+// ErrRenterExists is returned when a renter with the same email or public
+// key already exists.
+var ErrRenterExists = errors.New("renter already exists")
+
+// ErrTemplateNotFound is returned when there is no allowance template with
+// the given name.
+var ErrTemplateNotFound = errors.New("allowance template not found")
+
+// ErrRenterTokenNotFound is returned when the renter has no API token.
+var ErrRenterTokenNotFound = errors.New("renter token not found")
+
+// AllowanceTemplate is a named allowance that new renters can be created
+// with.
+type AllowanceTemplate struct {
+	Name      string             `json:"name"`
+	Allowance smodules.Allowance `json:"allowance"`
+}
+
 // RecoverableContract is a types.FileContract as it appears on the blockchain
 // with additional fields which contain the information required to recover its
 // latest revision from a host.
@@ -92,6 +116,367 @@ type Renter struct {
 	CurrentPeriod types.BlockHeight  `json:"currentperiod"`
 	PublicKey     types.SiaPublicKey `json:"publickey"`
 	Email         string             `json:"email"` // Link to the user account.
+	Suffix        string             `json:"suffix"` // Distinguishes identities sharing one email.
+
+	// AllowRedundantIPs excludes the renter's contracts from being canceled
+	// when several hosts share the same address range.
+	AllowRedundantIPs bool `json:"allowredundantips"`
+
+	// MaxStorageBytes caps the storage capacity the renter can provision,
+	// i.e. the number of hosts times the expected storage. Zero means
+	// unlimited.
+	MaxStorageBytes uint64 `json:"maxstoragebytes"`
+
+	// MaxContractsPerRegion caps the number of the renter's contracts with
+	// the hosts located in any single region. Zero means no limit.
+	MaxContractsPerRegion uint64 `json:"maxcontractsperregion"`
+
+	// Paused freezes all automated contract activity of the renter. The
+	// existing contracts are left to expire.
+	Paused bool `json:"paused"`
+
+	// PreferCollateral makes the contract formation prefer the hosts
+	// offering more collateral among the candidates of a similar score.
+	PreferCollateral bool `json:"prefercollateral"`
+
+	// SpendRateRefresh sizes the refreshed contracts by their recent spend
+	// rate instead of doubling their funding.
+	SpendRateRefresh bool `json:"spendraterefresh"`
+
+	// MaxHostLatency is the maximum response time of a host, in
+	// milliseconds, for it to be picked during the contract formation.
+	// Zero means no limit.
+	MaxHostLatency uint64 `json:"maxhostlatency"`
+
+	// LocalRegion is the region where the contract formation places
+	// LocalFraction of the renter's contracts, so that the hot data is
+	// kept close. An empty region or a zero fraction means no preference.
+	LocalRegion   string  `json:"localregion"`
+	LocalFraction float64 `json:"localfraction"`
+
+	// ReuseRefundAddress makes the renter's contracts share a single
+	// refund address instead of using a fresh wallet address each.
+	ReuseRefundAddress bool `json:"reuserefundaddress"`
+}
+
+// RenterInconsistency describes a difference between the renter record
+// stored in the database and the one kept in memory.
+type RenterInconsistency struct {
+	PublicKey         types.SiaPublicKey `json:"publickey"`
+	Email             string             `json:"email"`
+	Fields            []string           `json:"fields"`
+	MissingInMemory   bool               `json:"missinginmemory"`
+	MissingInDatabase bool               `json:"missingindatabase"`
+}
+
+// RenterDeletion is the outcome of deleting the renters of an account.
+// Deleted is the number of the renters deleted. Error is set if the
+// renters couldn't be deleted.
+type RenterDeletion struct {
+	Email   string `json:"email"`
+	Deleted int    `json:"deleted"`
+	Error   string `json:"error,omitempty"`
+}
+
+// BulkDeleteToken returns the token confirming a bulk deletion of the
+// renters with the given emails. The token depends on the set of emails
+// and on the force flag, so it can't be reused for another deletion.
+func BulkDeleteToken(emails []string, force bool) string {
+	sorted := append([]string(nil), emails...)
+	sort.Strings(sorted)
+	h := crypto.HashAll(strings.Join(sorted, "\n"), force)
+	return h.String()[:16]
+}
+
+// HostCheck is the result of a single check that a host has to pass in
+// order to be selected for contract formation.
+type HostCheck struct {
+	Name   string `json:"name"`
+	Passed bool   `json:"passed"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// HostDecision explains whether a host would be considered for contract
+// formation with the renter's current allowance.
+type HostDecision struct {
+	HostPublicKey types.SiaPublicKey `json:"hostpublickey"`
+	Eligible      bool               `json:"eligible"`
+	Checks        []HostCheck        `json:"checks"`
+}
+
+// RenewalSummary contains the outcome of a renewal sweep for one renter.
+type RenewalSummary struct {
+	PublicKey types.SiaPublicKey `json:"publickey"`
+	Email     string             `json:"email"`
+	Due       int                `json:"due"`
+	Renewed   int                `json:"renewed"`
+	Failed    int                `json:"failed"`
+	Skipped   int                `json:"skipped"`
+	Spent     types.Currency     `json:"spent"`
+}
+
+// ContractLineageLink is a contract in a renewal chain.
+type ContractLineageLink struct {
+	ID          types.FileContractID `json:"id"`
+	StartHeight types.BlockHeight    `json:"startheight"`
+	EndHeight   types.BlockHeight    `json:"endheight"`
+}
+
+// UtilityTransition is a change of the contract utility.
+type UtilityTransition struct {
+	OldUtility smodules.ContractUtility `json:"oldutility"`
+	NewUtility smodules.ContractUtility `json:"newutility"`
+	Height     types.BlockHeight        `json:"height"`
+	Reason     string                   `json:"reason"`
+}
+
+// DoubleSpentContract is a contract that was double-spent at the given
+// height.
+type DoubleSpentContract struct {
+	ID     types.FileContractID `json:"id"`
+	Height types.BlockHeight    `json:"height"`
+}
+
+// RenewFailure is a contract whose renewal has failed the given number of
+// times in a row.
+type RenewFailure struct {
+	ID       types.FileContractID `json:"id"`
+	Failures types.BlockHeight    `json:"failures"`
+}
+
+// FundsAtRisk is the renter money locked in the unhealthy contracts. Each
+// contract is counted once, with double-spending taking precedence.
+type FundsAtRisk struct {
+	Total           types.Currency `json:"total"`
+	DoubleSpent     types.Currency `json:"doublespent"`
+	NotGoodForRenew types.Currency `json:"notgoodforrenew"`
+	Contracts       int            `json:"contracts"`
+}
+
+// FundAudit compares the funds locked in the renter's balance with the cost
+// of the renter's active contracts. Locked is in the balance currency, the
+// other amounts are in siacoins. A non-zero Difference means that the
+// ledger doesn't match the contracts.
+type FundAudit struct {
+	Currency       string  `json:"currency"`
+	Locked         float64 `json:"locked"`
+	LockedSC       float64 `json:"lockedsc"`
+	ContractsCost  float64 `json:"contractscost"`
+	ExpectedLocked float64 `json:"expectedlocked"`
+	Difference     float64 `json:"difference"`
+	Contracts      int     `json:"contracts"`
+}
+
+// RedundancyHealth relates the renter's erasure coding parameters to the
+// renter's GoodForUpload contracts. Redundancy is the number of the
+// GoodForUpload contracts divided by TotalShards, so 1 means that every
+// shard can be uploaded to a separate host.
+type RedundancyHealth struct {
+	MinShards      uint64  `json:"minshards"`
+	TotalShards    uint64  `json:"totalshards"`
+	GFUContracts   uint64  `json:"gfucontracts"`
+	Redundancy     float64 `json:"redundancy"`
+	MeetsMinShards bool    `json:"meetsminshards"`
+	Warning        string  `json:"warning,omitempty"`
+}
+
+// WatchdogStatus contains the state of the contract watchdog.
+type WatchdogStatus struct {
+	MonitoredContracts   int                   `json:"monitoredcontracts"`
+	ArchivedContracts    int                   `json:"archivedcontracts"`
+	DoubleSpentContracts []DoubleSpentContract `json:"doublespentcontracts"`
+}
+
+// DueContract describes an active contract that is about to end.
+type DueContract struct {
+	ID              types.FileContractID `json:"id"`
+	RenterPublicKey types.SiaPublicKey   `json:"renterpublickey"`
+	Email           string               `json:"email"`
+	HostPublicKey   types.SiaPublicKey   `json:"hostpublickey"`
+	EndHeight       types.BlockHeight    `json:"endheight"`
+	RenewHeight     types.BlockHeight    `json:"renewheight"`
+	EstimatedCost   types.Currency       `json:"estimatedcost"`
+}
+
+// RenewalFeeBreakdown shows the fees included in the renewal estimate of a
+// contract. Overhead is the sum of the contract price, the siafund fee, and
+// the transaction fee.
+type RenewalFeeBreakdown struct {
+	ContractID    types.FileContractID `json:"contractid"`
+	HostPublicKey types.SiaPublicKey   `json:"hostpublickey"`
+	ContractPrice types.Currency       `json:"contractprice"`
+	SiafundFee    types.Currency       `json:"siafundfee"`
+	TxnFee        types.Currency       `json:"txnfee"`
+	Overhead      types.Currency       `json:"overhead"`
+	EstimatedCost types.Currency       `json:"estimatedcost"`
+}
+
+// FormationPreview describes a host that a contract formation would
+// attempt, together with the projected contract funding.
+type FormationPreview struct {
+	HostPublicKey types.SiaPublicKey `json:"hostpublickey"`
+	Funding       types.Currency     `json:"funding"`
+}
+
+// HostSpending contains the funds spent on a host during a contract
+// formation. The funds may have been spent even if the formation failed.
+// In that case, Formed is false, ContractID is empty, and Error contains
+// the reason of the failure.
+type HostSpending struct {
+	HostPublicKey types.SiaPublicKey   `json:"hostpublickey"`
+	ContractID    types.FileContractID `json:"contractid"`
+	Spent         types.Currency       `json:"spent"`
+	Formed        bool                 `json:"formed"`
+	Error         string               `json:"error,omitempty"`
+}
+
+// FormationResult contains the renter's contract set after a contract
+// formation and the breakdown of the funds spent per host.
+type FormationResult struct {
+	Contracts []RenterContract `json:"contracts"`
+	Spending  []HostSpending   `json:"spending"`
+}
+
+// formationProgressKey is the context key of the formation progress
+// callback.
+type formationProgressKey struct{}
+
+// WithFormationProgress returns a context that makes a contract formation
+// call fn for each newly formed contract.
+func WithFormationProgress(ctx context.Context, fn func()) context.Context {
+	return context.WithValue(ctx, formationProgressKey{}, fn)
+}
+
+// ReportFormationProgress calls the formation progress callback of the
+// context, if any.
+func ReportFormationProgress(ctx context.Context) {
+	if fn, ok := ctx.Value(formationProgressKey{}).(func()); ok {
+		fn()
+	}
+}
+
+// ContractorSnapshot is a copy of the contractor's internal bookkeeping of
+// the contracts, used for debugging. The maps are keyed by the contract
+// IDs.
+type ContractorSnapshot struct {
+	RenewedFrom          map[string]types.FileContractID `json:"renewedfrom"`
+	RenewedTo            map[string]types.FileContractID `json:"renewedto"`
+	OldContracts         []types.FileContractID          `json:"oldcontracts"`
+	NumFailedRenews      map[string]types.BlockHeight    `json:"numfailedrenews"`
+	Renewing             []types.FileContractID          `json:"renewing"`
+	DoubleSpentContracts map[string]types.BlockHeight    `json:"doublespentcontracts"`
+}
+
+// MaintenancePhase is the time spent in a single phase of the contract
+// maintenance.
+type MaintenancePhase struct {
+	Name     string        `json:"name"`
+	Duration time.Duration `json:"duration"`
+}
+
+// MaintenanceTimings lists the phases of the last contract maintenance
+// cycle in the order they were run. The phases skipped in that cycle are
+// not listed.
+type MaintenanceTimings struct {
+	Started time.Time          `json:"started"`
+	Phases  []MaintenancePhase `json:"phases"`
+}
+
+// RenterRunway projects whether the renter's unspent allowance funds
+// last until the end of the current period. SpendRate is the average
+// spending per block since the start of the period. If RunsOut is set,
+// ExhaustionHeight is the approximate height at which the funds run out.
+type RenterRunway struct {
+	BlockHeight       types.BlockHeight `json:"blockheight"`
+	PeriodEnd         types.BlockHeight `json:"periodend"`
+	BlocksRemaining   types.BlockHeight `json:"blocksremaining"`
+	Spent             types.Currency    `json:"spent"`
+	Unspent           types.Currency    `json:"unspent"`
+	SpendRate         types.Currency    `json:"spendrate"`
+	ProjectedSpending types.Currency    `json:"projectedspending"`
+	RunsOut           bool              `json:"runsout"`
+	ExhaustionHeight  types.BlockHeight `json:"exhaustionheight,omitempty"`
+}
+
+// ContractorSettings are the contractor tunables that can be adjusted at
+// runtime. The score leeways are the factors by which a host may miss the
+// goal score and still be GoodForRenew or GoodForUpload. The initial
+// funding of a new contract lies between Funds / Hosts / MinDiv and
+// Funds / Hosts * MaxMul / MaxDiv. MaxContractsPerHost limits the active
+// contracts with any one host across all renters, zero means no limit.
+// DiversityWeight is the largest fraction by which the minimum scores of a
+// host are lowered for the region diversity it adds, zero means off. An
+// out-of-funds contract is only refreshed if it has at least
+// MinRefreshRemainingBlocks left until its renew height. At most
+// MaxConcurrentRenewals renewals run at the same time. Two successful
+// formations are FormationDelay plus a random share of FormationJitter
+// apart, zero means no pause. A host dropped from the GoodForUpload set of
+// a renter by the GFU limiter stays out for GFUChurnCooldown blocks, zero
+// means no cooldown. While the transaction pool holds more than
+// TpoolCongestionThreshold transactions, fewer contracts are formed, zero
+// disables the check. A critical alert is registered if a renter has
+// GoodForUpload contracts with fewer than MinProvisionedFraction of the
+// allowance hosts after a formation. At most MaxRenewHistoryDepth previous
+// contracts are followed when summing up the spending of a contract line.
+// GFUStoredDataWeight is the weight of the stored data, relative to the
+// host score, when the GFU limiter picks the contracts to keep. A canceled
+// contract is archived after CanceledContractRetention blocks, zero means
+// never. The first maintenance after startup waits for SyncGraceChanges
+// consecutive synced consensus changes. After HostDBBreakerThreshold
+// consecutive hostdb failures, the hostdb-dependent maintenance is paused
+// for HostDBBreakerCooldown. A non-zero HostSelectionSeed makes the host
+// selection during the formation repeatable.
+type ContractorSettings struct {
+	MaxStoragePrice          types.Currency    `json:"maxstorageprice"`
+	MaxCollateral            types.Currency    `json:"maxcollateral"`
+	ScoreLeewayGoodForRenew  uint64            `json:"scoreleewaygoodforrenew"`
+	ScoreLeewayGoodForUpload uint64            `json:"scoreleewaygoodforupload"`
+	MinimumFunding           float64           `json:"minimumfunding"`
+	InitialFundingMaxMul     uint64            `json:"initialfundingmaxmul"`
+	InitialFundingMaxDiv     uint64            `json:"initialfundingmaxdiv"`
+	InitialFundingMinDiv     uint64            `json:"initialfundingmindiv"`
+	HostOversample           int               `json:"hostoversample"`
+	EndHeightHorizon         types.BlockHeight `json:"endheighthorizon"`
+	ClampEndHeight           bool              `json:"clampendheight"`
+	MaxContractsPerHost      int               `json:"maxcontractsperhost"`
+	ChurnExcessHostContracts bool              `json:"churnexcesshostcontracts"`
+	DiversityWeight          float64           `json:"diversityweight"`
+
+	MinRefreshRemainingBlocks types.BlockHeight `json:"minrefreshremainingblocks"`
+	MaxConcurrentRenewals     int               `json:"maxconcurrentrenewals"`
+	FormationDelay            time.Duration     `json:"formationdelay"`
+	FormationJitter           time.Duration     `json:"formationjitter"`
+	GFUChurnCooldown          types.BlockHeight `json:"gfuchurncooldown"`
+	TpoolCongestionThreshold  int               `json:"tpoolcongestionthreshold"`
+	MinProvisionedFraction    float64           `json:"minprovisionedfraction"`
+	MaxRenewHistoryDepth      int               `json:"maxrenewhistorydepth"`
+	GFUStoredDataWeight       float64           `json:"gfustoreddataweight"`
+	CanceledContractRetention types.BlockHeight `json:"canceledcontractretention"`
+	SyncGraceChanges          int               `json:"syncgracechanges"`
+	HostDBBreakerThreshold    int               `json:"hostdbbreakerthreshold"`
+	HostDBBreakerCooldown     time.Duration     `json:"hostdbbreakercooldown"`
+	HostSelectionSeed         int64             `json:"hostselectionseed"`
+}
+
+// WalletUsage describes how the wallet addresses are used by the
+// contracts, for diagnosing the address leakage.
+type WalletUsage struct {
+	// ReservedAddresses are the addresses currently reserved for the
+	// refund outputs of the formations and renewals in progress.
+	ReservedAddresses []types.UnlockHash `json:"reservedaddresses"`
+
+	// ActiveContracts is the number of the active contracts, and
+	// RefundAddresses is the number of the distinct refund addresses used
+	// by them.
+	ActiveContracts int `json:"activecontracts"`
+	RefundAddresses int `json:"refundaddresses"`
+
+	// WalletAddresses is the number of the addresses known to the wallet.
+	WalletAddresses int `json:"walletaddresses"`
+
+	// UnknownRefundAddresses are the refund addresses of the active
+	// contracts that the wallet doesn't know.
+	UnknownRefundAddresses []types.UnlockHash `json:"unknownrefundaddresses"`
 }
 
 // contractEndHeight returns the height at which the renter's contracts
@@ -101,12 +486,18 @@ func (r *Renter) ContractEndHeight() types.BlockHeight {
 }
 
 // DeriveRenterSeed derives a seed to be used by the renter for accessing the
-// file contracts.
+// file contracts. An empty suffix yields the default identity of the email,
+// any other suffix yields a separate identity under the same email.
 // NOTE: The seed returned by this function should be wiped once it's no longer
 // in use.
-func DeriveRenterSeed(walletSeed smodules.Seed, email string) smodules.RenterSeed {
+func DeriveRenterSeed(walletSeed smodules.Seed, email, suffix string) smodules.RenterSeed {
 	var renterSeed smodules.RenterSeed
-	rs := crypto.HashAll(walletSeed, []byte(email))
+	var rs crypto.Hash
+	if suffix == "" {
+		rs = crypto.HashAll(walletSeed, []byte(email))
+	} else {
+		rs = crypto.HashAll(walletSeed, []byte(email), []byte(suffix))
+	}
 	defer fastrand.Read(rs[:])
 	copy(renterSeed[:], rs[:])
 	return renterSeed
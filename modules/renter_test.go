@@ -0,0 +1,29 @@
+package modules
+
+import (
+	"testing"
+
+	smodules "go.sia.tech/siad/modules"
+)
+
+// TestDeriveRenterSeedIdentities tests that the identities of one email
+// derive distinct seeds, and that the default identity is stable.
+func TestDeriveRenterSeedIdentities(t *testing.T) {
+	var walletSeed smodules.Seed
+	walletSeed[0] = 1
+
+	personal := DeriveRenterSeed(walletSeed, "user@example.com", "")
+	work := DeriveRenterSeed(walletSeed, "user@example.com", "work")
+	if personal == work {
+		t.Fatal("two identities under one email derived the same seed")
+	}
+	if DeriveRenterSeed(walletSeed, "user@example.com", "") != personal {
+		t.Fatal("the default identity isn't deterministic")
+	}
+	if DeriveRenterSeed(walletSeed, "user@example.com", "work") != work {
+		t.Fatal("the suffixed identity isn't deterministic")
+	}
+	if DeriveRenterSeed(walletSeed, "other@example.com", "work") == work {
+		t.Fatal("the same suffix under two emails derived the same seed")
+	}
+}
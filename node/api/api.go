@@ -103,6 +103,9 @@ type (
 		router            http.Handler
 		routerMu          sync.RWMutex
 
+		alertAcks         map[smodules.AlertID]alertAck
+		alertAcksMu       sync.Mutex
+
 		requiredUserAgent string
 		requiredPassword  string
 		modulesSet        bool
@@ -145,6 +148,7 @@ func New(requiredUserAgent string, requiredPassword string, cs smodules.Consensu
 		wallet:            w,
 		requiredUserAgent: requiredUserAgent,
 		requiredPassword:  requiredPassword,
+		alertAcks:         make(map[smodules.AlertID]alertAck),
 	}
 
 	// Register API handlers
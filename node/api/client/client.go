@@ -235,6 +235,27 @@ func (c *Client) head(resource string) (int, http.Header, error) {
 	return res.StatusCode, res.Header, nil
 }
 
+// delete makes a DELETE request to the resource at `resource`.
+func (c *Client) delete(resource string) error {
+	req, err := c.NewRequest("DELETE", resource, nil)
+	if err != nil {
+		return errors.AddContext(err, "failed to construct DELETE request")
+	}
+	httpClient := http.Client{CheckRedirect: c.CheckRedirect}
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return errors.AddContext(err, "DELETE request failed")
+	}
+	defer drainAndClose(res.Body)
+
+	// If the status code is not 2xx, decode and return the accompanying
+	// api.Error.
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return errors.AddContext(readAPIError(res.Body), "DELETE request error")
+	}
+	return nil
+}
+
 // postRawResponse requests the specified resource. The response, if provided,
 // will be returned in a byte slice
 func (c *Client) postRawResponse(resource string, body io.Reader) (http.Header, []byte, error) {
@@ -1,7 +1,12 @@
 package client
 
 import (
+	"net/url"
+	"time"
+
 	"github.com/mike76-dev/sia-satellite/node/api"
+
+	"go.sia.tech/siad/modules"
 )
 
 // DaemonAlertsGet requests the /daemon/alerts resource.
@@ -10,6 +15,18 @@ func (c *Client) DaemonAlertsGet() (dag api.DaemonAlertsGet, err error) {
 	return
 }
 
+// DaemonAlertsAckPost acknowledges the alert with the given ID. A zero
+// snooze hides the alert until it re-triggers.
+func (c *Client) DaemonAlertsAckPost(id modules.AlertID, snooze time.Duration) (err error) {
+	values := url.Values{}
+	values.Set("id", string(id))
+	if snooze > 0 {
+		values.Set("snooze", snooze.String())
+	}
+	err = c.post("/daemon/alerts/ack", values.Encode(), nil)
+	return
+}
+
 // DaemonVersionGet requests the /daemon/version resource.
 func (c *Client) DaemonVersionGet() (dvg api.DaemonVersionGet, err error) {
 	err = c.get("/daemon/version", &dvg)
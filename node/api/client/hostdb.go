@@ -50,8 +50,32 @@ func (c *Client) HostDbFilterModePost(fm modules.FilterMode, hosts []types.SiaPu
 	return
 }
 
+// HostDbFilterModeExportGet requests the /hostdb/filtermode/export GET
+// endpoint.
+func (c *Client) HostDbFilterModeExportGet() (hdfe api.HostdbFilterExport, err error) {
+	err = c.get("/hostdb/filtermode/export", &hdfe)
+	return
+}
+
+// HostDbFilterModeImportPost requests the /hostdb/filtermode/import POST
+// endpoint.
+func (c *Client) HostDbFilterModeImportPost(hdfe api.HostdbFilterExport) (err error) {
+	data, err := json.Marshal(hdfe)
+	if err != nil {
+		return err
+	}
+	err = c.post("/hostdb/filtermode/import", string(data), nil)
+	return
+}
+
 // HostDbHostsGet request the /hostdb/hosts/:pubkey endpoint's resources.
 func (c *Client) HostDbHostsGet(pk types.SiaPublicKey) (hhg api.HostdbHostsGET, err error) {
 	err = c.get("/hostdb/hosts/" + pk.String(), &hhg)
 	return
 }
+
+// HostDbVersionsGet requests the /hostdb/versions endpoint's resources.
+func (c *Client) HostDbVersionsGet() (hvg api.HostdbVersionsGET, err error) {
+	err = c.get("/hostdb/versions", &hvg)
+	return
+}
@@ -1,8 +1,17 @@
 package client
 
 import (
+	"encoding/json"
+	"io"
+	"net/url"
+	"strconv"
+	"time"
+
 	"github.com/mike76-dev/sia-satellite/modules"
 	"github.com/mike76-dev/sia-satellite/node/api"
+
+	smodules "go.sia.tech/siad/modules"
+	"go.sia.tech/siad/types"
 )
 
 // SatelliteContractsGet requests the /satellite/contracts resource.
@@ -15,6 +24,68 @@ func (c *Client) SatelliteContractsGet(key string) (rc api.RenterContracts, err
 	return
 }
 
+// SatelliteContractsCSVGet requests the /satellite/contracts.csv resource,
+// or its per-renter variant if the key is provided. The caller must close
+// the returned reader.
+func (c *Client) SatelliteContractsCSVGet(key string) (io.ReadCloser, error) {
+	url := "/satellite/contracts.csv"
+	if key != "" {
+		url = "/satellite/renter/" + key + "/contracts.csv"
+	}
+	_, r, err := c.getReaderResponse(url)
+	return r, err
+}
+
+// SatelliteContractsDueGet requests the /satellite/contracts/due resource.
+func (c *Client) SatelliteContractsDueGet(within types.BlockHeight) (cdg api.ContractsDueGET, err error) {
+	values := url.Values{}
+	values.Set("within", strconv.FormatUint(uint64(within), 10))
+	err = c.get("/satellite/contracts/due?"+values.Encode(), &cdg)
+	return
+}
+
+// SatelliteContractLineageGet requests the /satellite/contracts/:id/lineage
+// resource.
+func (c *Client) SatelliteContractLineageGet(fcid types.FileContractID) (clg api.ContractLineageGET, err error) {
+	err = c.get("/satellite/contracts/"+fcid.String()+"/lineage", &clg)
+	return
+}
+
+// SatelliteContractUtilityHistoryGet requests the
+// /satellite/contracts/:id/utilityhistory resource.
+func (c *Client) SatelliteContractUtilityHistoryGet(fcid types.FileContractID) (uhg api.ContractUtilityHistoryGET, err error) {
+	err = c.get("/satellite/contracts/"+fcid.String()+"/utilityhistory", &uhg)
+	return
+}
+
+// SatelliteContractNoRefreshPost uses the /satellite/contracts/:id/norefresh
+// endpoint to exclude the contract from the refreshes or to include it
+// again.
+func (c *Client) SatelliteContractNoRefreshPost(fcid types.FileContractID, noRefresh bool) (err error) {
+	values := url.Values{}
+	values.Set("norefresh", strconv.FormatBool(noRefresh))
+	err = c.post("/satellite/contracts/"+fcid.String()+"/norefresh", values.Encode(), nil)
+	return
+}
+
+// SatelliteWatchdogGet requests the /satellite/watchdog resource.
+func (c *Client) SatelliteWatchdogGet() (ws modules.WatchdogStatus, err error) {
+	err = c.get("/satellite/watchdog", &ws)
+	return
+}
+
+// SatelliteFundsAtRiskGet requests the /satellite/fundsatrisk resource.
+func (c *Client) SatelliteFundsAtRiskGet() (far modules.FundsAtRisk, err error) {
+	err = c.get("/satellite/fundsatrisk", &far)
+	return
+}
+
+// SatelliteRenewFailuresGet requests the /satellite/renewfailures resource.
+func (c *Client) SatelliteRenewFailuresGet() (rfg api.RenewFailuresGET, err error) {
+	err = c.get("/satellite/renewfailures", &rfg)
+	return
+}
+
 // SatelliteRenterGet requests the /satellite/renter resource.
 func (c *Client) SatelliteRenterGet(key string) (r modules.Renter, err error) {
 	url := "/satellite/renter/" + key
@@ -22,6 +93,76 @@ func (c *Client) SatelliteRenterGet(key string) (r modules.Renter, err error) {
 	return
 }
 
+// SatelliteContractSearchGet requests the
+// /satellite/renter/:publickey/contracts/search resource.
+func (c *Client) SatelliteContractSearchGet(key, netAddress string) (csg api.ContractSearchGET, err error) {
+	values := url.Values{}
+	values.Set("netaddress", netAddress)
+	err = c.get("/satellite/renter/"+key+"/contracts/search?"+values.Encode(), &csg)
+	return
+}
+
+// SatelliteFundAuditGet requests the
+// /satellite/renter/:publickey/fundaudit resource.
+func (c *Client) SatelliteFundAuditGet(key string) (fa modules.FundAudit, err error) {
+	err = c.get("/satellite/renter/"+key+"/fundaudit", &fa)
+	return
+}
+
+// SatelliteRedundancyGet requests the
+// /satellite/renter/:publickey/redundancy resource. Zero erasure coding
+// parameters are derived from the renter's allowance.
+func (c *Client) SatelliteRedundancyGet(key string, minShards, totalShards uint64) (rh modules.RedundancyHealth, err error) {
+	values := url.Values{}
+	if minShards > 0 || totalShards > 0 {
+		values.Set("minshards", strconv.FormatUint(minShards, 10))
+		values.Set("totalshards", strconv.FormatUint(totalShards, 10))
+	}
+	err = c.get("/satellite/renter/"+key+"/redundancy?"+values.Encode(), &rh)
+	return
+}
+
+// SatelliteRunwayGet requests the /satellite/renter/:publickey/runway
+// resource.
+func (c *Client) SatelliteRunwayGet(key string) (r modules.RenterRunway, err error) {
+	err = c.get("/satellite/renter/"+key+"/runway", &r)
+	return
+}
+
+// SatelliteRenterAllowlistGet requests the
+// /satellite/renter/:publickey/allowlist resource.
+func (c *Client) SatelliteRenterAllowlistGet(key string) (ra api.RenterAllowlist, err error) {
+	err = c.get("/satellite/renter/"+key+"/allowlist", &ra)
+	return
+}
+
+// SatelliteRenterAllowlistPost uses the
+// /satellite/renter/:publickey/allowlist endpoint to restrict the
+// renter's new contracts to the given hosts. An empty list removes the
+// restriction.
+func (c *Client) SatelliteRenterAllowlistPost(key string, hosts []types.SiaPublicKey) (err error) {
+	data, err := json.Marshal(api.RenterAllowlist{Hosts: hosts})
+	if err != nil {
+		return
+	}
+	err = c.post("/satellite/renter/"+key+"/allowlist", string(data), nil)
+	return
+}
+
+// SatelliteFeeBreakdownGet requests the
+// /satellite/renter/:publickey/feebreakdown resource.
+func (c *Client) SatelliteFeeBreakdownGet(key string) (fbg api.FeeBreakdownGET, err error) {
+	err = c.get("/satellite/renter/"+key+"/feebreakdown", &fbg)
+	return
+}
+
+// SatelliteHostDecisionGet requests the
+// /satellite/renter/:publickey/hostdecision/:hostkey resource.
+func (c *Client) SatelliteHostDecisionGet(key, hostKey string) (hd modules.HostDecision, err error) {
+	err = c.get("/satellite/renter/"+key+"/hostdecision/"+hostKey, &hd)
+	return
+}
+
 // SatelliteBalanceGet requests the /satellite/balance resource.
 func (c *Client) SatelliteBalanceGet(key string) (ub modules.UserBalance, err error) {
 	url := "/satellite/balance/" + key
@@ -34,3 +175,236 @@ func (c *Client) SatelliteRentersGet() (rg api.RentersGET, err error) {
 	err = c.get("/satellite/renters", &rg)
 	return
 }
+
+// SatelliteRenterPausePost uses the /satellite/renter/:publickey/pause
+// endpoint to pause or resume all automated contract activity of the renter.
+func (c *Client) SatelliteRenterPausePost(key string, paused bool) (err error) {
+	values := url.Values{}
+	values.Set("paused", strconv.FormatBool(paused))
+	err = c.post("/satellite/renter/"+key+"/pause", values.Encode(), nil)
+	return
+}
+
+// SatelliteRentersTemplatePost uses the /satellite/renters endpoint to
+// create a new renter with the allowance of the template.
+func (c *Client) SatelliteRentersTemplatePost(email string, pk types.SiaPublicKey, template string) (r modules.Renter, err error) {
+	data, err := json.Marshal(api.RentersPOST{
+		Email:     email,
+		PublicKey: pk,
+		Template:  template,
+	})
+	if err != nil {
+		return
+	}
+	err = c.post("/satellite/renters", string(data), &r)
+	return
+}
+
+// SatelliteTemplatesGet requests the /satellite/templates resource.
+func (c *Client) SatelliteTemplatesGet() (tg api.TemplatesGET, err error) {
+	err = c.get("/satellite/templates", &tg)
+	return
+}
+
+// SatelliteTemplatesPost uses the /satellite/templates endpoint to create
+// or replace an allowance template.
+func (c *Client) SatelliteTemplatesPost(t modules.AllowanceTemplate) error {
+	data, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+	return c.post("/satellite/templates", string(data), nil)
+}
+
+// SatelliteTemplatesDelete uses the /satellite/templates endpoint to
+// remove an allowance template.
+func (c *Client) SatelliteTemplatesDelete(name string) error {
+	values := url.Values{}
+	values.Set("name", name)
+	return c.delete("/satellite/templates?" + values.Encode())
+}
+
+// SatelliteRentersPost uses the /satellite/renters endpoint to create a new
+// renter with the initial allowance.
+func (c *Client) SatelliteRentersPost(email string, pk types.SiaPublicKey, a smodules.Allowance) (r modules.Renter, err error) {
+	data, err := json.Marshal(api.RentersPOST{
+		Email:     email,
+		PublicKey: pk,
+		Allowance: a,
+	})
+	if err != nil {
+		return
+	}
+	err = c.post("/satellite/renters", string(data), &r)
+	return
+}
+
+// SatelliteRentersConsistencyGet requests the /satellite/renters/consistency
+// resource.
+func (c *Client) SatelliteRentersConsistencyGet() (rcg api.RenterConsistencyGET, err error) {
+	err = c.get("/satellite/renters/consistency", &rcg)
+	return
+}
+
+// SatelliteRentersConsistencyPost uses the /satellite/renters/consistency
+// endpoint to repair the in-memory renters from the database.
+func (c *Client) SatelliteRentersConsistencyPost() (rcg api.RenterConsistencyGET, err error) {
+	err = c.post("/satellite/renters/consistency", "", &rcg)
+	return
+}
+
+// SatelliteRenterTokenPost uses the /satellite/renter/:publickey/token
+// endpoint to issue a new API token for the renter.
+func (c *Client) SatelliteRenterTokenPost(key string) (rtp api.RenterTokenPOST, err error) {
+	err = c.post("/satellite/renter/"+key+"/token", "", &rtp)
+	return
+}
+
+// SatelliteRenterTokenDelete uses the /satellite/renter/:publickey/token
+// endpoint to revoke the renter's API token.
+func (c *Client) SatelliteRenterTokenDelete(key string) (err error) {
+	err = c.delete("/satellite/renter/" + key + "/token")
+	return
+}
+
+// SatelliteSettingsGet requests the /satellite/settings resource.
+func (c *Client) SatelliteSettingsGet() (cs modules.ContractorSettings, err error) {
+	err = c.get("/satellite/settings", &cs)
+	return
+}
+
+// SatelliteSettingsPost uses the /satellite/settings endpoint to adjust
+// the contractor tunables.
+func (c *Client) SatelliteSettingsPost(cs modules.ContractorSettings) error {
+	data, err := json.Marshal(cs)
+	if err != nil {
+		return err
+	}
+	return c.post("/satellite/settings", string(data), nil)
+}
+
+// SatelliteRentersBulkDeletePost uses the /satellite/renters/bulkdelete
+// endpoint to delete the renters of the given accounts. confirm must be
+// the confirmation token of the request.
+func (c *Client) SatelliteRentersBulkDeletePost(emails []string, force bool, confirm string) (rbd api.RentersBulkDeletePOST, err error) {
+	data, err := json.Marshal(api.RentersBulkDeleteRequest{
+		Emails:  emails,
+		Force:   force,
+		Confirm: confirm,
+	})
+	if err != nil {
+		return
+	}
+	err = c.post("/satellite/renters/bulkdelete", string(data), &rbd)
+	return
+}
+
+// SatelliteDebugContractorGet requests the /satellite/debug/contractor
+// resource.
+func (c *Client) SatelliteDebugContractorGet() (cs modules.ContractorSnapshot, err error) {
+	err = c.get("/satellite/debug/contractor", &cs)
+	return
+}
+
+// SatelliteMaintenanceGet requests the /satellite/maintenance resource.
+func (c *Client) SatelliteMaintenanceGet() (mt modules.MaintenanceTimings, err error) {
+	err = c.get("/satellite/maintenance", &mt)
+	return
+}
+
+// SatelliteWalletUsageGet requests the /satellite/walletusage resource.
+func (c *Client) SatelliteWalletUsageGet() (wu modules.WalletUsage, err error) {
+	err = c.get("/satellite/walletusage", &wu)
+	return
+}
+
+// SatelliteRenewAllPost uses the /satellite/renewall endpoint to renew the
+// due contracts of all renters within the given budget.
+func (c *Client) SatelliteRenewAllPost(maxSpend types.Currency) (rap api.RenewAllPOST, err error) {
+	values := url.Values{}
+	values.Set("maxspend", maxSpend.String())
+	err = c.post("/satellite/renewall", values.Encode(), &rap)
+	return
+}
+
+// SatelliteRenterAllowRedundantIPsPost uses the
+// /satellite/renter/:publickey/settings endpoint to set whether the renter's
+// contracts with the hosts sharing an address range are kept.
+func (c *Client) SatelliteRenterAllowRedundantIPsPost(key string, allow bool) (err error) {
+	values := url.Values{}
+	values.Set("allowredundantips", strconv.FormatBool(allow))
+	err = c.post("/satellite/renter/"+key+"/settings", values.Encode(), nil)
+	return
+}
+
+// SatelliteRenterMaxStorageBytesPost uses the
+// /satellite/renter/:publickey/settings endpoint to set the renter's storage
+// quota. Zero means unlimited.
+func (c *Client) SatelliteRenterMaxStorageBytesPost(key string, maxStorage uint64) (err error) {
+	values := url.Values{}
+	values.Set("maxstoragebytes", strconv.FormatUint(maxStorage, 10))
+	err = c.post("/satellite/renter/"+key+"/settings", values.Encode(), nil)
+	return
+}
+
+// SatelliteRenterMaxContractsPerRegionPost uses the
+// /satellite/renter/:publickey/settings endpoint to cap the number of the
+// renter's contracts in any single region. Zero means no limit.
+func (c *Client) SatelliteRenterMaxContractsPerRegionPost(key string, maxContracts uint64) (err error) {
+	values := url.Values{}
+	values.Set("maxcontractsperregion", strconv.FormatUint(maxContracts, 10))
+	err = c.post("/satellite/renter/"+key+"/settings", values.Encode(), nil)
+	return
+}
+
+// SatelliteRenterPreferCollateralPost uses the
+// /satellite/renter/:publickey/settings endpoint to toggle the preference
+// for the hosts offering more collateral.
+func (c *Client) SatelliteRenterPreferCollateralPost(key string, prefer bool) (err error) {
+	values := url.Values{}
+	values.Set("prefercollateral", strconv.FormatBool(prefer))
+	err = c.post("/satellite/renter/"+key+"/settings", values.Encode(), nil)
+	return
+}
+
+// SatelliteRenterSpendRateRefreshPost uses the
+// /satellite/renter/:publickey/settings endpoint to toggle sizing the
+// refreshed contracts by their spend rate.
+func (c *Client) SatelliteRenterSpendRateRefreshPost(key string, enabled bool) (err error) {
+	values := url.Values{}
+	values.Set("spendraterefresh", strconv.FormatBool(enabled))
+	err = c.post("/satellite/renter/"+key+"/settings", values.Encode(), nil)
+	return
+}
+
+// SatelliteRenterMaxHostLatencyPost uses the
+// /satellite/renter/:publickey/settings endpoint to set the maximum host
+// response time for the contract formation. Zero means no limit.
+func (c *Client) SatelliteRenterMaxHostLatencyPost(key string, latency time.Duration) (err error) {
+	values := url.Values{}
+	values.Set("maxhostlatency", latency.String())
+	err = c.post("/satellite/renter/"+key+"/settings", values.Encode(), nil)
+	return
+}
+
+// SatelliteRenterLocalRegionPost uses the
+// /satellite/renter/:publickey/settings endpoint to set the region where
+// the given fraction of the renter's contracts is formed. A zero fraction
+// disables the preference.
+func (c *Client) SatelliteRenterLocalRegionPost(key, region string, fraction float64) (err error) {
+	values := url.Values{}
+	values.Set("localregion", region)
+	values.Set("localfraction", strconv.FormatFloat(fraction, 'f', -1, 64))
+	err = c.post("/satellite/renter/"+key+"/settings", values.Encode(), nil)
+	return
+}
+
+// SatelliteRenterReuseRefundAddressPost uses the
+// /satellite/renter/:publickey/settings endpoint to set whether the
+// renter's contracts share a single refund address.
+func (c *Client) SatelliteRenterReuseRefundAddressPost(key string, enabled bool) (err error) {
+	values := url.Values{}
+	values.Set("reuserefundaddress", strconv.FormatBool(enabled))
+	err = c.post("/satellite/renter/"+key+"/settings", values.Encode(), nil)
+	return
+}
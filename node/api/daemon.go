@@ -3,9 +3,11 @@ package api
 import (
 	"log"
 	"net/http"
+	"time"
 
 	"github.com/julienschmidt/httprouter"
 
+	"go.sia.tech/siad/crypto"
 	"go.sia.tech/siad/modules"
 )
 
@@ -16,19 +18,107 @@ type (
 	// DaemonAlertsGet contains information about currently registered alerts
 	// across all loaded modules.
 	DaemonAlertsGet struct {
-		Alerts         []modules.Alert `json:"alerts"`
-		CriticalAlerts []modules.Alert `json:"criticalalerts"`
-		ErrorAlerts    []modules.Alert `json:"erroralerts"`
-		WarningAlerts  []modules.Alert `json:"warningalerts"`
-		InfoAlerts     []modules.Alert `json:"infoalerts"`
+		Alerts         []modules.Alert   `json:"alerts"`
+		IDs            []modules.AlertID `json:"ids"`
+		CriticalAlerts []modules.Alert   `json:"criticalalerts"`
+		ErrorAlerts    []modules.Alert   `json:"erroralerts"`
+		WarningAlerts  []modules.Alert   `json:"warningalerts"`
+		InfoAlerts     []modules.Alert   `json:"infoalerts"`
 	}
 
 	// DaemonVersionGet contains information about the running daemon's version.
 	DaemonVersionGet struct {
 		Version string
 	}
+
+	// alertAck is an acknowledgement of an alert. The alert is hidden until
+	// it re-triggers, i.e. its cause changes or it is unregistered and then
+	// registered again, or until the snooze expires. A zero expiry means no
+	// snooze.
+	alertAck struct {
+		cause  string
+		expiry time.Time
+	}
 )
 
+// alertID returns the ID of the alert. The alerts don't carry the IDs they
+// were registered with, so the ID is derived from the module and the message.
+func alertID(alert modules.Alert) modules.AlertID {
+	h := crypto.HashAll(alert.Module, alert.Msg)
+	return modules.AlertID(h.String()[:16])
+}
+
+// filterAcknowledged removes the acknowledged alerts from the list. The
+// acknowledgements of the alerts that are gone, have re-triggered or whose
+// snooze has expired are dropped.
+func (api *API) filterAcknowledged(alerts []modules.Alert) []modules.Alert {
+	api.alertAcksMu.Lock()
+	defer api.alertAcksMu.Unlock()
+	active := make(map[modules.AlertID]struct{})
+	filtered := make([]modules.Alert, 0, len(alerts))
+	for _, alert := range alerts {
+		active[alertID(alert)] = struct{}{}
+		ack, exists := api.alertAcks[alertID(alert)]
+		if exists && (ack.cause != alert.Cause || (!ack.expiry.IsZero() && time.Now().After(ack.expiry))) {
+			delete(api.alertAcks, alertID(alert))
+			exists = false
+		}
+		if !exists {
+			filtered = append(filtered, alert)
+		}
+	}
+	for id := range api.alertAcks {
+		if _, ok := active[id]; !ok {
+			delete(api.alertAcks, id)
+		}
+	}
+	return filtered
+}
+
+// daemonAlertsAckHandlerPOST handles the API call to acknowledge an alert.
+func (api *API) daemonAlertsAckHandlerPOST(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	id := modules.AlertID(req.FormValue("id"))
+	if id == "" {
+		WriteError(w, Error{"alert ID not specified"}, http.StatusBadRequest)
+		return
+	}
+	var snooze time.Duration
+	if s := req.FormValue("snooze"); s != "" {
+		var err error
+		snooze, err = time.ParseDuration(s)
+		if err != nil || snooze < 0 {
+			WriteError(w, Error{"unable to parse snooze duration"}, http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Find the alert.
+	var found *modules.Alert
+	for _, alert := range api.alerts() {
+		if alertID(alert) == id {
+			a := alert
+			found = &a
+			break
+		}
+	}
+	if found == nil {
+		WriteError(w, Error{"alert not found"}, http.StatusBadRequest)
+		return
+	}
+
+	ack := alertAck{
+		cause: found.Cause,
+	}
+	if snooze > 0 {
+		ack.expiry = time.Now().Add(snooze)
+	}
+	api.alertAcksMu.Lock()
+	api.alertAcks[id] = ack
+	api.alertAcksMu.Unlock()
+
+	WriteSuccess(w)
+}
+
 // daemonAlertsHandlerGET handles the API call that returns the alerts of all
 // loaded modules.
 func (api *API) daemonAlertsHandlerGET(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
@@ -37,17 +127,27 @@ func (api *API) daemonAlertsHandlerGET(w http.ResponseWriter, _ *http.Request, _
 	err := make([]modules.Alert, 0, 6)
 	warn := make([]modules.Alert, 0, 6)
 	info := make([]modules.Alert, 0, 6)
-	if api.gateway != nil {
-		c, e, w, i := api.gateway.Alerts()
-		crit = append(crit, c...)
-		err = append(err, e...)
-		warn = append(warn, w...)
-		info = append(info, i...)
+	for _, alert := range api.filterAcknowledged(api.alerts()) {
+		switch alert.Severity {
+		case modules.SeverityCritical:
+			crit = append(crit, alert)
+		case modules.SeverityError:
+			err = append(err, alert)
+		case modules.SeverityWarning:
+			warn = append(warn, alert)
+		default:
+			info = append(info, alert)
+		}
 	}
 	// Sort alerts by severity. Critical first, then Error and finally Warning.
 	alerts := append(append(crit, append(err, warn...)...), info...)
+	ids := make([]modules.AlertID, 0, len(alerts))
+	for _, alert := range alerts {
+		ids = append(ids, alertID(alert))
+	}
 	WriteJSON(w, DaemonAlertsGet{
 		Alerts:         alerts,
+		IDs:            ids,
 		CriticalAlerts: crit,
 		ErrorAlerts:    err,
 		WarningAlerts:  warn,
@@ -55,6 +155,19 @@ func (api *API) daemonAlertsHandlerGET(w http.ResponseWriter, _ *http.Request, _
 	})
 }
 
+// alerts returns the alerts of all loaded modules.
+func (api *API) alerts() []modules.Alert {
+	var alerts []modules.Alert
+	if api.gateway != nil {
+		c, e, w, i := api.gateway.Alerts()
+		alerts = append(alerts, c...)
+		alerts = append(alerts, e...)
+		alerts = append(alerts, w...)
+		alerts = append(alerts, i...)
+	}
+	return alerts
+}
+
 // daemonVersionHandler handles the API call that requests the daemon's version.
 func (api *API) daemonVersionHandler(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
 	WriteJSON(w, DaemonVersionGet{Version: DaemonVersion})
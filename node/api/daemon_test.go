@@ -0,0 +1,88 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"go.sia.tech/siad/modules"
+)
+
+// testGateway is a gateway with a fixed set of alerts.
+type testGateway struct {
+	modules.Gateway
+	alerts []modules.Alert
+}
+
+// Alerts implements modules.Gateway.
+func (g *testGateway) Alerts() (crit, err, warn, info []modules.Alert) {
+	return nil, nil, g.alerts, nil
+}
+
+// TestAlertAck tests that an acknowledged alert is hidden, and that it
+// reappears once the snooze expires or the alert re-triggers.
+func TestAlertAck(t *testing.T) {
+	g := &testGateway{alerts: []modules.Alert{
+		{Module: "gateway", Msg: "first", Cause: "cause", Severity: modules.SeverityWarning},
+		{Module: "gateway", Msg: "second", Cause: "cause", Severity: modules.SeverityWarning},
+	}}
+	api := &API{gateway: g, alertAcks: make(map[modules.AlertID]alertAck)}
+
+	get := func() []modules.Alert {
+		t.Helper()
+		w := httptest.NewRecorder()
+		api.daemonAlertsHandlerGET(w, httptest.NewRequest("GET", "/daemon/alerts", nil), nil)
+		var dag DaemonAlertsGet
+		if err := json.NewDecoder(w.Body).Decode(&dag); err != nil {
+			t.Fatal(err)
+		}
+		return dag.Alerts
+	}
+	ack := func(id modules.AlertID, snooze string) int {
+		values := url.Values{}
+		values.Set("id", string(id))
+		if snooze != "" {
+			values.Set("snooze", snooze)
+		}
+		req := httptest.NewRequest("POST", "/daemon/alerts/ack", strings.NewReader(values.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		api.daemonAlertsAckHandlerPOST(w, req, nil)
+		return w.Code
+	}
+
+	if alerts := get(); len(alerts) != 2 {
+		t.Fatalf("expected 2 alerts, got %v", len(alerts))
+	}
+	if code := ack("unknown", ""); code != http.StatusBadRequest {
+		t.Fatalf("expected status %v for an unknown alert, got %v", http.StatusBadRequest, code)
+	}
+
+	// A snoozed alert is hidden until the snooze expires.
+	if code := ack(alertID(g.alerts[0]), "100ms"); code != http.StatusNoContent {
+		t.Fatalf("expected status %v, got %v", http.StatusNoContent, code)
+	}
+	if alerts := get(); len(alerts) != 1 || alerts[0].Msg != "second" {
+		t.Fatal("the snoozed alert wasn't hidden")
+	}
+	time.Sleep(150 * time.Millisecond)
+	if alerts := get(); len(alerts) != 2 {
+		t.Fatal("the alert didn't reappear after the snooze expired")
+	}
+
+	// An acknowledged alert is hidden until it re-triggers.
+	if code := ack(alertID(g.alerts[1]), ""); code != http.StatusNoContent {
+		t.Fatalf("expected status %v, got %v", http.StatusNoContent, code)
+	}
+	if alerts := get(); len(alerts) != 1 || alerts[0].Msg != "first" {
+		t.Fatal("the acknowledged alert wasn't hidden")
+	}
+	g.alerts[1].Cause = "another cause"
+	if alerts := get(); len(alerts) != 2 {
+		t.Fatal("the alert didn't reappear after it re-triggered")
+	}
+}
@@ -4,9 +4,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"sort"
 
 	"github.com/julienschmidt/httprouter"
 
+	"go.sia.tech/siad/build"
 	"go.sia.tech/siad/modules"
 	"go.sia.tech/siad/types"
 )
@@ -58,6 +60,37 @@ type (
 		Hosts        []types.SiaPublicKey `json:"hosts"`
 		NetAddresses []string             `json:"netaddresses"`
 	}
+
+	// HostdbFilterExport is the portable form of the hostdb's filter, used
+	// to move the filter between nodes.
+	HostdbFilterExport struct {
+		FilterMode   string   `json:"filtermode"`
+		Hosts        []string `json:"hosts"`
+		NetAddresses []string `json:"netaddresses"`
+	}
+
+	// HostVersionCount is the number of active hosts running a version.
+	HostVersionCount struct {
+		Version string `json:"version"`
+		Hosts   int    `json:"hosts"`
+	}
+
+	// HostdbVersionsGET contains the version distribution of the active
+	// hosts and the number of current contracts with hosts running a
+	// version below the minimum, which won't be renewed.
+	HostdbVersionsGET struct {
+		Versions          []HostVersionCount `json:"versions"`
+		MinimumVersion    string             `json:"minimumversion"`
+		OutdatedContracts int                `json:"outdatedcontracts"`
+	}
+
+	// HostdbFilterModePATCH contains the changes to apply to the filter list.
+	HostdbFilterModePATCH struct {
+		AddHosts           []types.SiaPublicKey `json:"addhosts"`
+		RemoveHosts        []types.SiaPublicKey `json:"removehosts"`
+		AddNetAddresses    []string             `json:"addnetaddresses"`
+		RemoveNetAddresses []string             `json:"removenetaddresses"`
+	}
 )
 
 // hostdbHandler handles the API call asking for the status of HostDB.
@@ -212,3 +245,139 @@ func (api *API) hostdbFilterModeHandlerPOST(w http.ResponseWriter, req *http.Req
 	}
 	WriteSuccess(w)
 }
+
+// hostdbFilterModeHandlerPATCH handles the API call to add entries to and
+// remove entries from the hostdb's filter list without changing the filter
+// mode. The resulting filter is returned.
+func (api *API) hostdbFilterModeHandlerPATCH(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	// Parse parameters
+	var params HostdbFilterModePATCH
+	err := json.NewDecoder(req.Body).Decode(&params)
+	if err != nil {
+		WriteError(w, Error{"invalid parameters: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	// Update the filter
+	fm, hostMap, netAddresses, err := api.satellite.UpdateFilter(params.AddHosts, params.RemoveHosts, params.AddNetAddresses, params.RemoveNetAddresses)
+	if err != nil {
+		WriteError(w, Error{"failed to update the filter: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	var hosts []string
+	for key := range hostMap {
+		hosts = append(hosts, key)
+	}
+	WriteJSON(w, HostdbFilterModeGET{
+		FilterMode:   fm.String(),
+		Hosts:        hosts,
+		NetAddresses: netAddresses,
+	})
+}
+
+// hostdbFilterModeExportHandlerGET handles the API call to export the
+// hostdb's filter.
+func (api *API) hostdbFilterModeExportHandlerGET(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+	fm, hostMap, netAddresses, err := api.satellite.Filter()
+	if err != nil {
+		WriteError(w, Error{"unable to get filter mode: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	hosts := make([]string, 0, len(hostMap))
+	for key := range hostMap {
+		hosts = append(hosts, key)
+	}
+	sort.Strings(hosts)
+	if netAddresses == nil {
+		netAddresses = []string{}
+	}
+	WriteJSON(w, HostdbFilterExport{
+		FilterMode:   fm.String(),
+		Hosts:        hosts,
+		NetAddresses: netAddresses,
+	})
+}
+
+// hostdbFilterModeImportHandlerPOST handles the API call to replace the
+// hostdb's filter with an exported one. Nothing is changed if any of the
+// host keys is malformed.
+func (api *API) hostdbFilterModeImportHandlerPOST(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	// Parse parameters
+	var params HostdbFilterExport
+	err := json.NewDecoder(req.Body).Decode(&params)
+	if err != nil {
+		WriteError(w, Error{"invalid parameters: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	var fm modules.FilterMode
+	if err = fm.FromString(params.FilterMode); err != nil {
+		WriteError(w, Error{"unable to load filter mode from string: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	// Validate the host keys.
+	hosts := make([]types.SiaPublicKey, 0, len(params.Hosts))
+	for i, key := range params.Hosts {
+		var spk types.SiaPublicKey
+		if err := spk.LoadString(key); err != nil {
+			WriteError(w, Error{fmt.Sprintf("invalid host key #%d: %v", i, err)}, http.StatusBadRequest)
+			return
+		}
+		if spk.Algorithm != types.SignatureEd25519 || len(spk.Key) != 32 {
+			WriteError(w, Error{fmt.Sprintf("invalid host key #%d: not an ed25519 public key", i)}, http.StatusBadRequest)
+			return
+		}
+		hosts = append(hosts, spk)
+	}
+
+	// Replace the filter.
+	if err := api.satellite.SetFilterMode(fm, hosts, params.NetAddresses); err != nil {
+		WriteError(w, Error{"failed to set the list mode: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteSuccess(w)
+}
+
+// hostdbVersionsHandlerGET handles the API call to /hostdb/versions.
+func (api *API) hostdbVersionsHandlerGET(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+	hosts, err := api.satellite.ActiveHosts()
+	if err != nil {
+		WriteError(w, Error{"unable to get active hosts: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	// Build the histogram, newest version first.
+	counts := make(map[string]int)
+	for _, host := range hosts {
+		counts[host.Version]++
+	}
+	versions := make([]HostVersionCount, 0, len(counts))
+	for v, n := range counts {
+		versions = append(versions, HostVersionCount{
+			Version: v,
+			Hosts:   n,
+		})
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return build.VersionCmp(versions[i].Version, versions[j].Version) > 0
+	})
+
+	// Count the contracts with the outdated hosts.
+	var outdated int
+	for _, contract := range api.satellite.Contracts() {
+		host, exists, err := api.satellite.Host(contract.HostPublicKey)
+		if err != nil || !exists {
+			continue
+		}
+		if build.VersionCmp(host.Version, modules.MinimumSupportedRenterHostProtocolVersion) < 0 {
+			outdated++
+		}
+	}
+
+	WriteJSON(w, HostdbVersionsGET{
+		Versions:          versions,
+		MinimumVersion:    modules.MinimumSupportedRenterHostProtocolVersion,
+		OutdatedContracts: outdated,
+	})
+}
@@ -0,0 +1,138 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/mike76-dev/sia-satellite/modules"
+
+	smodules "go.sia.tech/siad/modules"
+	"go.sia.tech/siad/types"
+)
+
+// testFilterSatellite is a satellite that only keeps the hostdb filter.
+type testFilterSatellite struct {
+	modules.Satellite
+	fm           smodules.FilterMode
+	hosts        map[string]types.SiaPublicKey
+	netAddresses []string
+}
+
+// Filter implements modules.Satellite.
+func (s *testFilterSatellite) Filter() (smodules.FilterMode, map[string]types.SiaPublicKey, []string, error) {
+	return s.fm, s.hosts, s.netAddresses, nil
+}
+
+// SetFilterMode implements modules.Satellite.
+func (s *testFilterSatellite) SetFilterMode(fm smodules.FilterMode, hosts []types.SiaPublicKey, netAddresses []string) error {
+	s.fm = fm
+	s.hosts = make(map[string]types.SiaPublicKey)
+	for _, host := range hosts {
+		s.hosts[host.String()] = host
+	}
+	s.netAddresses = netAddresses
+	return nil
+}
+
+// TestFilterExportImport tests that an exported filter is imported to the
+// identical state, and that a malformed host key is rejected.
+func TestFilterExportImport(t *testing.T) {
+	s := &testFilterSatellite{}
+	if err := s.SetFilterMode(smodules.HostDBActiveWhitelist, []types.SiaPublicKey{testKey(1), testKey(2)}, []string{"10.0.0.1"}); err != nil {
+		t.Fatal(err)
+	}
+	api := &API{satellite: s}
+	export := func() HostdbFilterExport {
+		t.Helper()
+		rw := httptest.NewRecorder()
+		api.hostdbFilterModeExportHandlerGET(rw, httptest.NewRequest("GET", "/hostdb/filtermode/export", nil), nil)
+		if rw.Code != http.StatusOK {
+			t.Fatal("export failed:", rw.Body.String())
+		}
+		var hdfe HostdbFilterExport
+		if err := json.NewDecoder(rw.Body).Decode(&hdfe); err != nil {
+			t.Fatal(err)
+		}
+		return hdfe
+	}
+	importFilter := func(hdfe HostdbFilterExport) int {
+		data, err := json.Marshal(hdfe)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rw := httptest.NewRecorder()
+		api.hostdbFilterModeImportHandlerPOST(rw, httptest.NewRequest("POST", "/hostdb/filtermode/import", bytes.NewReader(data)), nil)
+		return rw.Code
+	}
+
+	// Import the export into a node with no filter.
+	hdfe := export()
+	fm, hosts, netAddresses := s.fm, s.hosts, s.netAddresses
+	if err := s.SetFilterMode(smodules.HostDBDisableFilter, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if code := importFilter(hdfe); code != http.StatusNoContent {
+		t.Fatal("import failed with", code)
+	}
+	if s.fm != fm || !reflect.DeepEqual(s.hosts, hosts) || !reflect.DeepEqual(s.netAddresses, netAddresses) {
+		t.Fatal("imported filter differs from the exported one")
+	}
+	if again := export(); !reflect.DeepEqual(again, hdfe) {
+		t.Fatal("exports differ:", again, hdfe)
+	}
+
+	// A malformed host key leaves the filter unchanged.
+	bad := hdfe
+	bad.FilterMode = smodules.HostDBActivateBlacklist.String()
+	bad.Hosts = append([]string{hdfe.Hosts[0]}, "ed25519:xyz")
+	if code := importFilter(bad); code != http.StatusBadRequest {
+		t.Fatal("expected a malformed key to be rejected, got", code)
+	}
+	bad.Hosts = []string{"ed25519:abcd"}
+	if code := importFilter(bad); code != http.StatusBadRequest {
+		t.Fatal("expected a short key to be rejected, got", code)
+	}
+	if s.fm != fm || !reflect.DeepEqual(s.hosts, hosts) {
+		t.Fatal("filter changed by a rejected import")
+	}
+}
+
+// TestHostVersions tests the version distribution of the active hosts and
+// the number of the contracts with the hosts below the minimum version.
+func TestHostVersions(t *testing.T) {
+	s := &testSatellite{}
+	for i, version := range []string{"1.5.10", "1.3.7", "1.5.10", "1.4.1"} {
+		var host smodules.HostDBEntry
+		host.PublicKey = testKey(byte(10 + i))
+		host.Version = version
+		s.hosts = append(s.hosts, host)
+	}
+	// Two contracts with the outdated host, one with a current host, and
+	// one with an unknown host.
+	for _, b := range []byte{11, 11, 10, 20} {
+		s.contracts = append(s.contracts, modules.RenterContract{HostPublicKey: testKey(b)})
+	}
+
+	api := &API{satellite: s}
+	rw := httptest.NewRecorder()
+	api.hostdbVersionsHandlerGET(rw, httptest.NewRequest("GET", "/hostdb/versions", nil), nil)
+	var hvg HostdbVersionsGET
+	if err := json.NewDecoder(rw.Body).Decode(&hvg); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []HostVersionCount{{"1.5.10", 2}, {"1.4.1", 1}, {"1.3.7", 1}}
+	if !reflect.DeepEqual(hvg.Versions, expected) {
+		t.Fatalf("expected versions %v, got %v", expected, hvg.Versions)
+	}
+	if hvg.MinimumVersion != smodules.MinimumSupportedRenterHostProtocolVersion {
+		t.Fatal("wrong minimum version:", hvg.MinimumVersion)
+	}
+	if hvg.OutdatedContracts != 2 {
+		t.Fatalf("expected 2 outdated contracts, got %v", hvg.OutdatedContracts)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/mike76-dev/sia-satellite/modules"
 	"github.com/julienschmidt/httprouter"
 )
 
@@ -28,6 +29,7 @@ func (api *API) buildHTTPRoutes() {
 
 	// Daemon API Calls.
 	router.GET("/daemon/alerts", api.daemonAlertsHandlerGET)
+	router.POST("/daemon/alerts/ack", RequirePassword(api.daemonAlertsAckHandlerPOST, requiredPassword))
 	router.GET("/daemon/stop", RequirePassword(api.daemonStopHandler, requiredPassword))
 	router.GET("/daemon/version", api.daemonVersionHandler)
 
@@ -57,17 +59,54 @@ func (api *API) buildHTTPRoutes() {
 		router.GET("/hostdb/active", api.hostdbActiveHandler)
 		router.GET("/hostdb/all", api.hostdbAllHandler)
 		router.GET("/hostdb/hosts/:pubkey", api.hostdbHostsHandler)
+		router.GET("/hostdb/versions", api.hostdbVersionsHandlerGET)
 		router.GET("/hostdb/filtermode", api.hostdbFilterModeHandlerGET)
 		router.POST("/hostdb/filtermode", RequirePassword(api.hostdbFilterModeHandlerPOST, requiredPassword))
+		router.PATCH("/hostdb/filtermode", RequirePassword(api.hostdbFilterModeHandlerPATCH, requiredPassword))
+		router.GET("/hostdb/filtermode/export", RequirePassword(api.hostdbFilterModeExportHandlerGET, requiredPassword))
+		router.POST("/hostdb/filtermode/import", RequirePassword(api.hostdbFilterModeImportHandlerPOST, requiredPassword))
 	}
 
 	// Satellite API Calls.
 	if api.satellite != nil {
 		router.GET("/satellite/renters", RequirePassword(api.satelliteRentersHandlerGET, requiredPassword))
-		router.GET("/satellite/renter/:publickey", RequirePassword(api.satelliteRenterHandlerGET, requiredPassword))
-		router.GET("/satellite/balance/:publickey", RequirePassword(api.satelliteBalanceHandlerGET, requiredPassword))
+		router.POST("/satellite/renters", RequirePassword(api.satelliteRentersHandlerPOST, requiredPassword))
+		router.GET("/satellite/templates", RequirePassword(api.satelliteTemplatesHandlerGET, requiredPassword))
+		router.POST("/satellite/templates", RequirePassword(api.satelliteTemplatesHandlerPOST, requiredPassword))
+		router.DELETE("/satellite/templates", RequirePassword(api.satelliteTemplatesHandlerDELETE, requiredPassword))
+		router.POST("/satellite/renters/bulkdelete", RequirePassword(api.satelliteRentersBulkDeleteHandlerPOST, requiredPassword))
+		router.GET("/satellite/renters/consistency", RequirePassword(api.satelliteRentersConsistencyHandlerGET, requiredPassword))
+		router.POST("/satellite/renters/consistency", RequirePassword(api.satelliteRentersConsistencyHandlerPOST, requiredPassword))
+		router.GET("/satellite/settings", RequirePassword(api.satelliteSettingsHandlerGET, requiredPassword))
+		router.POST("/satellite/settings", RequirePassword(api.satelliteSettingsHandlerPOST, requiredPassword))
+		router.GET("/satellite/debug/contractor", RequirePassword(api.satelliteDebugContractorHandlerGET, requiredPassword))
+		router.GET("/satellite/maintenance", RequirePassword(api.satelliteMaintenanceHandlerGET, requiredPassword))
+		router.GET("/satellite/walletusage", RequirePassword(api.satelliteWalletUsageHandlerGET, requiredPassword))
+		router.GET("/satellite/renter/:publickey", api.requireRenterAccess(api.satelliteRenterHandlerGET, requiredPassword))
+		router.POST("/satellite/renter/:publickey/settings", RequirePassword(api.satelliteRenterSettingsHandlerPOST, requiredPassword))
+		router.POST("/satellite/renter/:publickey/pause", RequirePassword(api.satelliteRenterPauseHandlerPOST, requiredPassword))
+		router.POST("/satellite/renter/:publickey/token", RequirePassword(api.satelliteRenterTokenHandlerPOST, requiredPassword))
+		router.DELETE("/satellite/renter/:publickey/token", RequirePassword(api.satelliteRenterTokenHandlerDELETE, requiredPassword))
+		router.GET("/satellite/renter/:publickey/hostdecision/:hostkey", api.requireRenterAccess(api.satelliteHostDecisionHandlerGET, requiredPassword))
+		router.GET("/satellite/renter/:publickey/contracts/search", api.requireRenterAccess(api.satelliteContractSearchHandlerGET, requiredPassword))
+		router.GET("/satellite/renter/:publickey/feebreakdown", api.requireRenterAccess(api.satelliteFeeBreakdownHandlerGET, requiredPassword))
+		router.GET("/satellite/renter/:publickey/fundaudit", api.requireRenterAccess(api.satelliteFundAuditHandlerGET, requiredPassword))
+		router.GET("/satellite/renter/:publickey/redundancy", api.requireRenterAccess(api.satelliteRedundancyHandlerGET, requiredPassword))
+		router.GET("/satellite/renter/:publickey/runway", api.requireRenterAccess(api.satelliteRunwayHandlerGET, requiredPassword))
+		router.GET("/satellite/renter/:publickey/allowlist", api.requireRenterAccess(api.satelliteRenterAllowlistHandlerGET, requiredPassword))
+		router.POST("/satellite/renter/:publickey/allowlist", RequirePassword(api.satelliteRenterAllowlistHandlerPOST, requiredPassword))
+		router.GET("/satellite/renter/:publickey/contracts.csv", api.requireRenterAccess(api.satelliteContractsCSVHandlerGET, requiredPassword))
+		router.GET("/satellite/balance/:publickey", api.requireRenterAccess(api.satelliteBalanceHandlerGET, requiredPassword))
+		router.POST("/satellite/renewall", RequirePassword(api.satelliteRenewAllHandlerPOST, requiredPassword))
+		router.GET("/satellite/watchdog", RequirePassword(api.satelliteWatchdogHandlerGET, requiredPassword))
+		router.GET("/satellite/renewfailures", RequirePassword(api.satelliteRenewFailuresHandlerGET, requiredPassword))
+		router.GET("/satellite/fundsatrisk", RequirePassword(api.satelliteFundsAtRiskHandlerGET, requiredPassword))
 		router.GET("/satellite/contracts", RequirePassword(api.satelliteContractsHandlerGET, requiredPassword))
-		router.GET("/satellite/contracts/:publickey", RequirePassword(api.satelliteContractsHandlerGET, requiredPassword))
+		router.GET("/satellite/contracts.csv", RequirePassword(api.satelliteContractsCSVHandlerGET, requiredPassword))
+		router.GET("/satellite/contracts/:publickey", api.requireRenterAccess(api.satelliteContractsHandlerGET, requiredPassword))
+		router.GET("/satellite/contracts/:publickey/lineage", RequirePassword(api.satelliteContractLineageHandlerGET, requiredPassword))
+		router.GET("/satellite/contracts/:publickey/utilityhistory", RequirePassword(api.satelliteContractUtilityHistoryHandlerGET, requiredPassword))
+		router.POST("/satellite/contracts/:publickey/norefresh", RequirePassword(api.satelliteContractNoRefreshHandlerPOST, requiredPassword))
 	}
 
 	// Apply UserAgent middleware and return the Router.
@@ -121,3 +160,29 @@ func RequirePassword(h httprouter.Handle, password string) httprouter.Handle {
 		h(w, req, ps)
 	}
 }
+
+// requireRenterAccess is like RequirePassword, but it also accepts the API
+// token of the renter specified by the publickey parameter in place of the
+// password. This gives a renter read access to their own data.
+func (api *API) requireRenterAccess(h httprouter.Handle, password string) httprouter.Handle {
+	// An empty password is equivalent to no password.
+	if password == "" {
+		return h
+	}
+	return func(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+		_, pass, ok := req.BasicAuth()
+		if ok && pass == password {
+			h(w, req, ps)
+			return
+		}
+		if ok && ps.ByName("publickey") != "" {
+			rpk := modules.ReadPublicKey(ps.ByName("publickey"))
+			if api.satellite.VerifyRenterToken(rpk, pass) {
+				h(w, req, ps)
+				return
+			}
+		}
+		w.Header().Set("WWW-Authenticate", "Basic realm=\"SatAPI\"")
+		WriteError(w, Error{"API authentication failed."}, http.StatusUnauthorized)
+	}
+}
@@ -1,12 +1,20 @@
 package api
 
 import (
+	"encoding/csv"
+	"encoding/json"
 	"fmt"
+	"math"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/mike76-dev/sia-satellite/modules"
 	"github.com/julienschmidt/httprouter"
 
+	"gitlab.com/NebulousLabs/errors"
+
 	smodules "go.sia.tech/siad/modules"
 	"go.sia.tech/siad/types"
 )
@@ -23,6 +31,91 @@ type (
 		Renters []Renter `json:"renters"`
 	}
 
+	// RentersPOST contains the parameters of a new renter.
+	// If Template is set, the allowance of the template is used instead
+	// of Allowance.
+	RentersPOST struct {
+		Email     string             `json:"email"`
+		PublicKey types.SiaPublicKey `json:"publickey"`
+		Allowance smodules.Allowance `json:"allowance"`
+		Template  string             `json:"template,omitempty"`
+	}
+
+	// RenterTokenPOST contains a newly issued renter API token.
+	RenterTokenPOST struct {
+		Token string `json:"token"`
+	}
+
+	// TemplatesGET contains the allowance templates.
+	TemplatesGET struct {
+		Templates []modules.AllowanceTemplate `json:"templates"`
+	}
+
+	// RenterConsistencyGET contains the differences between the renters
+	// in the database and the ones in memory.
+	RenterConsistencyGET struct {
+		Repaired        bool                          `json:"repaired"`
+		Inconsistencies []modules.RenterInconsistency `json:"inconsistencies"`
+	}
+
+	// RentersBulkDeletePOST contains the outcome of a bulk deletion of
+	// the renters.
+	RentersBulkDeletePOST struct {
+		Results []modules.RenterDeletion `json:"results"`
+	}
+
+	// RentersBulkDeleteRequest is the request to delete the renters of
+	// the given accounts. Confirm must be the confirmation token returned
+	// by a request without it.
+	RentersBulkDeleteRequest struct {
+		Emails  []string `json:"emails"`
+		Force   bool     `json:"force"`
+		Confirm string   `json:"confirm"`
+	}
+
+	// RenterAllowlist contains the hosts that the renter's contracts are
+	// restricted to. An empty list means no restriction.
+	RenterAllowlist struct {
+		Hosts []types.SiaPublicKey `json:"hosts"`
+	}
+
+	// RenewAllPOST contains the outcome of a renewal sweep.
+	RenewAllPOST struct {
+		Renters []modules.RenewalSummary `json:"renters"`
+	}
+
+	// ContractsDueGET contains the contracts that are due for renewal.
+	ContractsDueGET struct {
+		Contracts []modules.DueContract `json:"contracts"`
+	}
+
+	// FeeBreakdownGET contains the fees included in the renewal estimates
+	// of a renter's contracts.
+	FeeBreakdownGET struct {
+		Contracts []modules.RenewalFeeBreakdown `json:"contracts"`
+	}
+
+	// ContractLineageGET contains the renewal chain of a contract.
+	ContractLineageGET struct {
+		Lineage []modules.ContractLineageLink `json:"lineage"`
+	}
+
+	// ContractUtilityHistoryGET contains the utility transitions of a
+	// contract.
+	ContractUtilityHistoryGET struct {
+		History []modules.UtilityTransition `json:"history"`
+	}
+
+	// RenewFailuresGET contains the contracts with failed renewals.
+	RenewFailuresGET struct {
+		Failures []modules.RenewFailure `json:"failures"`
+	}
+
+	// ContractSearchGET contains the contracts matching a search.
+	ContractSearchGET struct {
+		Contracts []RenterContract `json:"contracts"`
+	}
+
 	// RenterContract represents a contract formed by the renter.
 	RenterContract struct {
 		// Amount of contract funds that have been spent on downloads.
@@ -69,6 +162,13 @@ type (
 		GoodForRenew bool `json:"goodforrenew"`
 		// Signals if a contract has been marked as bad.
 		BadContract bool `json:"badcontract"`
+		// Score of the host at the time the contract was formed. Zero if
+		// unknown.
+		FormationScore types.Currency `json:"formationscore"`
+		// Number of bytes that the remaining funds can store at the host's
+		// current storage price until the end of the contract. Zero if
+		// unknown.
+		RemainingStorageBytes uint64 `json:"remainingstoragebytes"`
 	}
 
 	// RenterContracts contains the renter's contracts.
@@ -100,6 +200,254 @@ func (api *API) satelliteRentersHandlerGET(w http.ResponseWriter, _ *http.Reques
 	WriteJSON(w, r)
 }
 
+// satelliteRentersHandlerPOST handles the API call to create a new renter.
+func (api *API) satelliteRentersHandlerPOST(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	var params RentersPOST
+	err := json.NewDecoder(req.Body).Decode(&params)
+	if err != nil {
+		WriteError(w, Error{"invalid parameters: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	if params.Email == "" {
+		WriteError(w, Error{"email not specified"}, http.StatusBadRequest)
+		return
+	}
+	if params.PublicKey.Algorithm != types.SignatureEd25519 || len(params.PublicKey.Key) != 32 {
+		WriteError(w, Error{"invalid public key"}, http.StatusBadRequest)
+		return
+	}
+	if params.Template != "" {
+		t, err := api.satellite.AllowanceTemplate(params.Template)
+		if err != nil {
+			WriteError(w, Error{"unable to get template: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+		params.Allowance = t.Allowance
+	}
+	if err := modules.ValidateAllowance(params.Allowance, 0, 0); err != nil {
+		WriteError(w, Error{"invalid allowance: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	renter, err := api.satellite.AddRenter(params.Email, params.PublicKey, params.Allowance)
+	if errors.Contains(err, modules.ErrRenterExists) {
+		WriteError(w, Error{"unable to create renter: " + err.Error()}, http.StatusConflict)
+		return
+	}
+	if err != nil {
+		WriteError(w, Error{"unable to create renter: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	WriteJSON(w, renter)
+}
+
+// satelliteTemplatesHandlerGET handles the API call to GET
+// /satellite/templates.
+func (api *API) satelliteTemplatesHandlerGET(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+	templates, err := api.satellite.AllowanceTemplates()
+	if err != nil {
+		WriteError(w, Error{"unable to get templates: " + err.Error()}, http.StatusInternalServerError)
+		return
+	}
+
+	WriteJSON(w, TemplatesGET{Templates: templates})
+}
+
+// satelliteTemplatesHandlerPOST handles the API call to POST
+// /satellite/templates. It creates or replaces an allowance template.
+func (api *API) satelliteTemplatesHandlerPOST(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	var t modules.AllowanceTemplate
+	err := json.NewDecoder(req.Body).Decode(&t)
+	if err != nil {
+		WriteError(w, Error{"invalid parameters: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	if t.Name == "" {
+		WriteError(w, Error{"template name not specified"}, http.StatusBadRequest)
+		return
+	}
+	if err := modules.ValidateAllowance(t.Allowance, 0, 0); err != nil {
+		WriteError(w, Error{"invalid allowance: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	if err := api.satellite.SaveAllowanceTemplate(t); err != nil {
+		WriteError(w, Error{"unable to save template: " + err.Error()}, http.StatusInternalServerError)
+		return
+	}
+
+	WriteSuccess(w)
+}
+
+// satelliteTemplatesHandlerDELETE handles the API call to DELETE
+// /satellite/templates.
+func (api *API) satelliteTemplatesHandlerDELETE(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	name := req.FormValue("name")
+	if name == "" {
+		WriteError(w, Error{"template name not specified"}, http.StatusBadRequest)
+		return
+	}
+
+	err := api.satellite.DeleteAllowanceTemplate(name)
+	if errors.Contains(err, modules.ErrTemplateNotFound) {
+		WriteError(w, Error{err.Error()}, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		WriteError(w, Error{"unable to delete template: " + err.Error()}, http.StatusInternalServerError)
+		return
+	}
+
+	WriteSuccess(w)
+}
+
+// satelliteRentersConsistencyHandlerGET handles the API call to
+// /satellite/renters/consistency.
+func (api *API) satelliteRentersConsistencyHandlerGET(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+	api.writeRenterConsistency(w, false)
+}
+
+// satelliteRentersConsistencyHandlerPOST handles the API call to POST
+// /satellite/renters/consistency. The in-memory renters are repaired,
+// treating the database as the source of truth.
+func (api *API) satelliteRentersConsistencyHandlerPOST(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+	api.writeRenterConsistency(w, true)
+}
+
+// writeRenterConsistency runs the renter consistency check and writes the
+// result.
+func (api *API) writeRenterConsistency(w http.ResponseWriter, repair bool) {
+	inconsistencies, err := api.satellite.CheckRenterConsistency(repair)
+	if err != nil {
+		WriteError(w, Error{"unable to check renters: " + err.Error()}, http.StatusInternalServerError)
+		return
+	}
+
+	WriteJSON(w, RenterConsistencyGET{
+		Repaired:        repair && len(inconsistencies) > 0,
+		Inconsistencies: inconsistencies,
+	})
+}
+
+// satelliteRenterTokenHandlerPOST handles the API call to POST
+// /satellite/renter/:publickey/token. It issues a new API token for the
+// renter, replacing the previous one.
+func (api *API) satelliteRenterTokenHandlerPOST(w http.ResponseWriter, _ *http.Request, ps httprouter.Params) {
+	pk := ps.ByName("publickey")
+	if pk == "" {
+		WriteError(w, Error{"public key not specified"}, http.StatusBadRequest)
+		return
+	}
+
+	token, err := api.satellite.IssueRenterToken(modules.ReadPublicKey(pk))
+	if err != nil {
+		WriteError(w, Error{"unable to issue token: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	WriteJSON(w, RenterTokenPOST{Token: token})
+}
+
+// satelliteRenterTokenHandlerDELETE handles the API call to DELETE
+// /satellite/renter/:publickey/token. It revokes the renter's API token.
+func (api *API) satelliteRenterTokenHandlerDELETE(w http.ResponseWriter, _ *http.Request, ps httprouter.Params) {
+	pk := ps.ByName("publickey")
+	if pk == "" {
+		WriteError(w, Error{"public key not specified"}, http.StatusBadRequest)
+		return
+	}
+
+	err := api.satellite.RevokeRenterToken(modules.ReadPublicKey(pk))
+	if errors.Contains(err, modules.ErrRenterTokenNotFound) {
+		WriteError(w, Error{err.Error()}, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		WriteError(w, Error{"unable to revoke token: " + err.Error()}, http.StatusInternalServerError)
+		return
+	}
+
+	WriteSuccess(w)
+}
+
+// satelliteSettingsHandlerGET handles the API call to GET
+// /satellite/settings.
+func (api *API) satelliteSettingsHandlerGET(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+	WriteJSON(w, api.satellite.ContractorSettings())
+}
+
+// satelliteSettingsHandlerPOST handles the API call to POST
+// /satellite/settings. The fields missing in the request keep their
+// current values.
+func (api *API) satelliteSettingsHandlerPOST(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	cs := api.satellite.ContractorSettings()
+	err := json.NewDecoder(req.Body).Decode(&cs)
+	if err != nil {
+		WriteError(w, Error{"invalid parameters: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	if err := api.satellite.SetContractorSettings(cs); err != nil {
+		WriteError(w, Error{"invalid settings: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	WriteSuccess(w)
+}
+
+// satelliteRentersBulkDeleteHandlerPOST handles the API call to
+// /satellite/renters/bulkdelete. A request without the correct
+// confirmation token is refused, and the error contains the token to
+// repeat the request with.
+func (api *API) satelliteRentersBulkDeleteHandlerPOST(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	var r RentersBulkDeleteRequest
+	err := json.NewDecoder(req.Body).Decode(&r)
+	if err != nil {
+		WriteError(w, Error{"invalid parameters: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	if len(r.Emails) == 0 {
+		WriteError(w, Error{"no emails specified"}, http.StatusBadRequest)
+		return
+	}
+	if token := modules.BulkDeleteToken(r.Emails, r.Force); r.Confirm != token {
+		WriteError(w, Error{"confirmation required, repeat the request with confirm=" + token}, http.StatusBadRequest)
+		return
+	}
+
+	results, err := api.satellite.DeleteRenters(r.Emails, r.Force, r.Confirm)
+	if err != nil {
+		WriteError(w, Error{"unable to delete renters: " + err.Error()}, http.StatusInternalServerError)
+		return
+	}
+
+	WriteJSON(w, RentersBulkDeletePOST{Results: results})
+}
+
+// satelliteDebugContractorHandlerGET handles the API call to
+// /satellite/debug/contractor.
+func (api *API) satelliteDebugContractorHandlerGET(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+	WriteJSON(w, api.satellite.DebugSnapshot())
+}
+
+// satelliteMaintenanceHandlerGET handles the API call to
+// /satellite/maintenance.
+func (api *API) satelliteMaintenanceHandlerGET(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+	WriteJSON(w, api.satellite.MaintenanceTimings())
+}
+
+// satelliteWalletUsageHandlerGET handles the API call to
+// /satellite/walletusage.
+func (api *API) satelliteWalletUsageHandlerGET(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+	wu, err := api.satellite.WalletUsage()
+	if err != nil {
+		WriteError(w, Error{"unable to get wallet usage: " + err.Error()}, http.StatusInternalServerError)
+		return
+	}
+	WriteJSON(w, wu)
+}
+
 // satelliteRenterHandlerGET handles the API call to /satellite/renter.
 func (api *API) satelliteRenterHandlerGET(w http.ResponseWriter, _ *http.Request, ps httprouter.Params) {
 	pk := ps.ByName("publickey")
@@ -118,6 +466,181 @@ func (api *API) satelliteRenterHandlerGET(w http.ResponseWriter, _ *http.Request
 	WriteJSON(w, renter)
 }
 
+// satelliteRenterPauseHandlerPOST handles the API call to pause or resume
+// all automated contract activity of the renter.
+func (api *API) satelliteRenterPauseHandlerPOST(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	pk := ps.ByName("publickey")
+	if pk == "" {
+		WriteError(w, Error{"public key not specified"}, http.StatusBadRequest)
+		return
+	}
+
+	paused := true
+	if p := req.FormValue("paused"); p != "" {
+		var err error
+		paused, err = scanBool(p)
+		if err != nil {
+			WriteError(w, Error{"unable to parse paused: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+	}
+
+	renter, err := api.satellite.GetRenter(modules.ReadPublicKey(pk))
+	if err != nil {
+		WriteError(w, Error{"renter not found: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	if paused {
+		err = api.satellite.PauseRenter(renter.Email)
+	} else {
+		err = api.satellite.ResumeRenter(renter.Email)
+	}
+	if err != nil {
+		WriteError(w, Error{"unable to update renter: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	WriteSuccess(w)
+}
+
+// satelliteHostDecisionHandlerGET handles the API call to
+// /satellite/renter/:publickey/hostdecision/:hostkey.
+func (api *API) satelliteHostDecisionHandlerGET(w http.ResponseWriter, _ *http.Request, ps httprouter.Params) {
+	pk := ps.ByName("publickey")
+	if pk == "" {
+		WriteError(w, Error{"public key not specified"}, http.StatusBadRequest)
+		return
+	}
+	hk := ps.ByName("hostkey")
+	if hk == "" {
+		WriteError(w, Error{"host key not specified"}, http.StatusBadRequest)
+		return
+	}
+
+	var hpk types.SiaPublicKey
+	if err := hpk.LoadString(hk); err != nil {
+		WriteError(w, Error{"unable to parse host key: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	hd, err := api.satellite.HostDecision(modules.ReadPublicKey(pk), hpk)
+	if err != nil {
+		WriteError(w, Error{"unable to check host: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	WriteJSON(w, hd)
+}
+
+// satelliteRenterSettingsHandlerPOST handles the API call to
+// /satellite/renter/:publickey/settings.
+func (api *API) satelliteRenterSettingsHandlerPOST(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	pk := ps.ByName("publickey")
+	if pk == "" {
+		WriteError(w, Error{"public key not specified"}, http.StatusBadRequest)
+		return
+	}
+	key := modules.ReadPublicKey(pk)
+
+	if a := req.FormValue("allowredundantips"); a != "" {
+		allow, err := scanBool(a)
+		if err != nil {
+			WriteError(w, Error{"unable to parse allowredundantips: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+		if err := api.satellite.SetAllowRedundantIPs(key, allow); err != nil {
+			WriteError(w, Error{"unable to update renter: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+	}
+
+	if m := req.FormValue("maxstoragebytes"); m != "" {
+		maxStorage, err := strconv.ParseUint(m, 10, 64)
+		if err != nil {
+			WriteError(w, Error{"unable to parse maxstoragebytes: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+		if err := api.satellite.SetMaxStorageBytes(key, maxStorage); err != nil {
+			WriteError(w, Error{"unable to update renter: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+	}
+
+	if m := req.FormValue("maxcontractsperregion"); m != "" {
+		maxContracts, err := strconv.ParseUint(m, 10, 64)
+		if err != nil {
+			WriteError(w, Error{"unable to parse maxcontractsperregion: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+		if err := api.satellite.SetMaxContractsPerRegion(key, maxContracts); err != nil {
+			WriteError(w, Error{"unable to update renter: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+	}
+
+	if p := req.FormValue("prefercollateral"); p != "" {
+		prefer, err := scanBool(p)
+		if err != nil {
+			WriteError(w, Error{"unable to parse prefercollateral: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+		if err := api.satellite.SetPreferCollateral(key, prefer); err != nil {
+			WriteError(w, Error{"unable to update renter: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+	}
+
+	if s := req.FormValue("spendraterefresh"); s != "" {
+		enabled, err := scanBool(s)
+		if err != nil {
+			WriteError(w, Error{"unable to parse spendraterefresh: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+		if err := api.satellite.SetSpendRateRefresh(key, enabled); err != nil {
+			WriteError(w, Error{"unable to update renter: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+	}
+
+	if s := req.FormValue("maxhostlatency"); s != "" {
+		latency, err := time.ParseDuration(s)
+		if err != nil {
+			WriteError(w, Error{"unable to parse maxhostlatency: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+		if err := api.satellite.SetMaxHostLatency(key, latency); err != nil {
+			WriteError(w, Error{"unable to update renter: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+	}
+
+	if s := req.FormValue("localfraction"); s != "" {
+		fraction, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			WriteError(w, Error{"unable to parse localfraction: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+		if err := api.satellite.SetLocalRegion(key, req.FormValue("localregion"), fraction); err != nil {
+			WriteError(w, Error{"unable to update renter: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+	}
+
+	if s := req.FormValue("reuserefundaddress"); s != "" {
+		enabled, err := scanBool(s)
+		if err != nil {
+			WriteError(w, Error{"unable to parse reuserefundaddress: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+		if err := api.satellite.SetReuseRefundAddress(key, enabled); err != nil {
+			WriteError(w, Error{"unable to update renter: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+	}
+
+	WriteSuccess(w)
+}
+
 // satelliteBalanceHandlerGET handles the API call to /satellite/balance.
 func (api *API) satelliteBalanceHandlerGET(w http.ResponseWriter, _ *http.Request, ps httprouter.Params) {
 	pk := ps.ByName("publickey")
@@ -142,6 +665,357 @@ func (api *API) satelliteBalanceHandlerGET(w http.ResponseWriter, _ *http.Reques
 	WriteJSON(w, ub)
 }
 
+// satelliteRenewAllHandlerPOST handles the API call to /satellite/renewall.
+func (api *API) satelliteRenewAllHandlerPOST(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	maxSpend, ok := scanAmount(req.FormValue("maxspend"))
+	if !ok {
+		WriteError(w, Error{"could not read maxspend from POST call to /satellite/renewall"}, http.StatusBadRequest)
+		return
+	}
+
+	summaries, err := api.satellite.RenewAllDue(maxSpend)
+	if err != nil {
+		WriteError(w, Error{"unable to renew contracts: " + err.Error()}, http.StatusInternalServerError)
+		return
+	}
+
+	WriteJSON(w, RenewAllPOST{Renters: summaries})
+}
+
+// satelliteContractsDueHandlerGET handles the API call to
+// /satellite/contracts/due.
+func (api *API) satelliteContractsDueHandlerGET(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	within, err := strconv.ParseUint(req.FormValue("within"), 10, 64)
+	if err != nil {
+		WriteError(w, Error{"unable to parse within: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	contracts, err := api.satellite.ContractsDue(types.BlockHeight(within))
+	if err != nil {
+		WriteError(w, Error{"unable to get contracts: " + err.Error()}, http.StatusInternalServerError)
+		return
+	}
+
+	WriteJSON(w, ContractsDueGET{Contracts: contracts})
+}
+
+// renterContract builds the API representation of an active contract.
+func (api *API) renterContract(c modules.RenterContract) RenterContract {
+	// Fetch host address.
+	var netAddress smodules.NetAddress
+	hdbe, exists, _ := api.satellite.Host(c.HostPublicKey)
+	if exists {
+		netAddress = hdbe.NetAddress
+	}
+	score, _ := api.satellite.FormationScore(c.ID)
+
+	var remaining uint64
+	if exists {
+		remaining = remainingStorage(c.RenterFunds, hdbe.StoragePrice, c.EndHeight, api.cs.Height())
+	}
+
+	return RenterContract{
+		BadContract:         c.Utility.BadContract,
+		DownloadSpending:    c.DownloadSpending,
+		EndHeight:           c.EndHeight,
+		Fees:                c.TxnFee.Add(c.SiafundFee).Add(c.ContractFee),
+		FormationScore:      score,
+		FundAccountSpending: c.FundAccountSpending,
+		GoodForUpload:       c.Utility.GoodForUpload,
+		GoodForRenew:        c.Utility.GoodForRenew,
+		RenterPublicKey:     c.RenterPublicKey,
+		HostPublicKey:       c.HostPublicKey,
+		HostVersion:         hdbe.Version,
+		ID:                  c.ID,
+		LastTransaction:     c.Transaction,
+		NetAddress:          netAddress,
+		MaintenanceSpending: c.MaintenanceSpending,
+		RenterFunds:         c.RenterFunds,
+		Size:                c.Size(),
+		StartHeight:         c.StartHeight,
+		StorageSpending:     c.StorageSpending,
+		TotalCost:           c.TotalCost,
+		UploadSpending:      c.UploadSpending,
+
+		RemainingStorageBytes: remaining,
+	}
+}
+
+// remainingStorage returns the number of bytes that the funds can store
+// at the given storage price from the current height until endHeight.
+// Zero is returned if the contract has ended, and math.MaxUint64 if the
+// storage is free.
+func remainingStorage(funds, storagePrice types.Currency, endHeight, height types.BlockHeight) uint64 {
+	if endHeight <= height {
+		return 0
+	}
+	if storagePrice.IsZero() {
+		return math.MaxUint64
+	}
+	bytes, err := funds.Div(storagePrice.Mul64(uint64(endHeight - height))).Uint64()
+	if err != nil {
+		return math.MaxUint64
+	}
+	return bytes
+}
+
+// satelliteContractSearchHandlerGET handles the API call to
+// /satellite/renter/:publickey/contracts/search. It returns the active
+// contracts of the renter with the hosts whose net address contains the
+// given substring.
+func (api *API) satelliteContractSearchHandlerGET(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	pk := ps.ByName("publickey")
+	if pk == "" {
+		WriteError(w, Error{"public key not specified"}, http.StatusBadRequest)
+		return
+	}
+	key := modules.ReadPublicKey(pk)
+	if _, err := api.satellite.GetRenter(key); err != nil {
+		WriteError(w, Error{"unable to find renter: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	substr := req.FormValue("netaddress")
+	if substr == "" {
+		WriteError(w, Error{"net address not specified"}, http.StatusBadRequest)
+		return
+	}
+
+	contracts := []RenterContract{}
+	for _, c := range api.satellite.Contracts() {
+		if c.RenterPublicKey.String() != pk {
+			continue
+		}
+		contract := api.renterContract(c)
+		if !strings.Contains(string(contract.NetAddress), substr) {
+			continue
+		}
+		contracts = append(contracts, contract)
+	}
+
+	WriteJSON(w, ContractSearchGET{Contracts: contracts})
+}
+
+// satelliteFeeBreakdownHandlerGET handles the API call to
+// /satellite/renter/:publickey/feebreakdown.
+func (api *API) satelliteFeeBreakdownHandlerGET(w http.ResponseWriter, _ *http.Request, ps httprouter.Params) {
+	pk := ps.ByName("publickey")
+	if pk == "" {
+		WriteError(w, Error{"public key not specified"}, http.StatusBadRequest)
+		return
+	}
+
+	breakdown, err := api.satellite.RenewalFeeBreakdown(modules.ReadPublicKey(pk))
+	if err != nil {
+		WriteError(w, Error{"unable to get fee breakdown: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	WriteJSON(w, FeeBreakdownGET{Contracts: breakdown})
+}
+
+// satelliteFundAuditHandlerGET handles the API call to
+// /satellite/renter/:publickey/fundaudit.
+func (api *API) satelliteFundAuditHandlerGET(w http.ResponseWriter, _ *http.Request, ps httprouter.Params) {
+	pk := ps.ByName("publickey")
+	if pk == "" {
+		WriteError(w, Error{"public key not specified"}, http.StatusBadRequest)
+		return
+	}
+
+	fa, err := api.satellite.FundAudit(modules.ReadPublicKey(pk))
+	if err != nil {
+		WriteError(w, Error{"unable to audit funds: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	WriteJSON(w, fa)
+}
+
+// satelliteRedundancyHandlerGET handles the API call to
+// /satellite/renter/:publickey/redundancy. The erasure coding parameters
+// can be passed as minshards and totalshards, otherwise they are derived
+// from the renter's allowance.
+func (api *API) satelliteRedundancyHandlerGET(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	pk := ps.ByName("publickey")
+	if pk == "" {
+		WriteError(w, Error{"public key not specified"}, http.StatusBadRequest)
+		return
+	}
+
+	var minShards, totalShards uint64
+	if ms := req.FormValue("minshards"); ms != "" {
+		var err error
+		minShards, err = strconv.ParseUint(ms, 10, 64)
+		if err != nil {
+			WriteError(w, Error{"unable to parse minshards: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+	}
+	if ts := req.FormValue("totalshards"); ts != "" {
+		var err error
+		totalShards, err = strconv.ParseUint(ts, 10, 64)
+		if err != nil {
+			WriteError(w, Error{"unable to parse totalshards: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+	}
+
+	rh, err := api.satellite.RedundancyHealth(modules.ReadPublicKey(pk), minShards, totalShards)
+	if err != nil {
+		WriteError(w, Error{"unable to get redundancy: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	WriteJSON(w, rh)
+}
+
+// satelliteRunwayHandlerGET handles the API call to
+// /satellite/renter/:publickey/runway.
+func (api *API) satelliteRunwayHandlerGET(w http.ResponseWriter, _ *http.Request, ps httprouter.Params) {
+	pk := ps.ByName("publickey")
+	if pk == "" {
+		WriteError(w, Error{"public key not specified"}, http.StatusBadRequest)
+		return
+	}
+
+	r, err := api.satellite.Runway(modules.ReadPublicKey(pk))
+	if err != nil {
+		WriteError(w, Error{"unable to get runway: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	WriteJSON(w, r)
+}
+
+// satelliteRenterAllowlistHandlerGET handles the API call to GET
+// /satellite/renter/:publickey/allowlist.
+func (api *API) satelliteRenterAllowlistHandlerGET(w http.ResponseWriter, _ *http.Request, ps httprouter.Params) {
+	pk := ps.ByName("publickey")
+	if pk == "" {
+		WriteError(w, Error{"public key not specified"}, http.StatusBadRequest)
+		return
+	}
+
+	hosts, err := api.satellite.HostAllowlist(modules.ReadPublicKey(pk))
+	if err != nil {
+		WriteError(w, Error{"unable to get allowlist: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	WriteJSON(w, RenterAllowlist{Hosts: hosts})
+}
+
+// satelliteRenterAllowlistHandlerPOST handles the API call to POST
+// /satellite/renter/:publickey/allowlist. The allowlist is replaced with
+// the given hosts.
+func (api *API) satelliteRenterAllowlistHandlerPOST(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	pk := ps.ByName("publickey")
+	if pk == "" {
+		WriteError(w, Error{"public key not specified"}, http.StatusBadRequest)
+		return
+	}
+
+	var ra RenterAllowlist
+	if err := json.NewDecoder(req.Body).Decode(&ra); err != nil {
+		WriteError(w, Error{"invalid parameters: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	if err := api.satellite.SetHostAllowlist(modules.ReadPublicKey(pk), ra.Hosts); err != nil {
+		WriteError(w, Error{"unable to update allowlist: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	WriteSuccess(w)
+}
+
+// satelliteContractLineageHandlerGET handles the API call to
+// /satellite/contracts/:id/lineage.
+func (api *API) satelliteContractLineageHandlerGET(w http.ResponseWriter, _ *http.Request, ps httprouter.Params) {
+	// The router requires the same parameter name as in
+	// /satellite/contracts/:publickey.
+	var fcid types.FileContractID
+	if err := fcid.LoadString(ps.ByName("publickey")); err != nil {
+		WriteError(w, Error{"unable to parse contract ID: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	lineage, err := api.satellite.ContractLineage(fcid)
+	if err != nil {
+		WriteError(w, Error{"unable to get contract lineage: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	WriteJSON(w, ContractLineageGET{Lineage: lineage})
+}
+
+// satelliteContractUtilityHistoryHandlerGET handles the API call to
+// /satellite/contracts/:id/utilityhistory.
+func (api *API) satelliteContractUtilityHistoryHandlerGET(w http.ResponseWriter, _ *http.Request, ps httprouter.Params) {
+	// The router requires the same parameter name as in
+	// /satellite/contracts/:publickey.
+	var fcid types.FileContractID
+	if err := fcid.LoadString(ps.ByName("publickey")); err != nil {
+		WriteError(w, Error{"unable to parse contract ID: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	history, err := api.satellite.ContractUtilityHistory(fcid)
+	if err != nil {
+		WriteError(w, Error{"unable to get contract utility history: " + err.Error()}, http.StatusInternalServerError)
+		return
+	}
+
+	WriteJSON(w, ContractUtilityHistoryGET{History: history})
+}
+
+// satelliteContractNoRefreshHandlerPOST handles the API call to
+// /satellite/contracts/:id/norefresh.
+func (api *API) satelliteContractNoRefreshHandlerPOST(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	// The router requires the same parameter name as in
+	// /satellite/contracts/:publickey.
+	var fcid types.FileContractID
+	if err := fcid.LoadString(ps.ByName("publickey")); err != nil {
+		WriteError(w, Error{"unable to parse contract ID: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	noRefresh := true
+	if s := req.FormValue("norefresh"); s != "" {
+		var err error
+		noRefresh, err = scanBool(s)
+		if err != nil {
+			WriteError(w, Error{"unable to parse norefresh: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+	}
+
+	if err := api.satellite.SetNoRefresh(fcid, noRefresh); err != nil {
+		WriteError(w, Error{"unable to update contract: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	WriteSuccess(w)
+}
+
+// satelliteWatchdogHandlerGET handles the API call to /satellite/watchdog.
+func (api *API) satelliteWatchdogHandlerGET(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+	WriteJSON(w, api.satellite.WatchdogStatus())
+}
+
+// satelliteFundsAtRiskHandlerGET handles the API call to
+// /satellite/fundsatrisk.
+func (api *API) satelliteFundsAtRiskHandlerGET(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+	WriteJSON(w, api.satellite.FundsAtRisk())
+}
+
+// satelliteRenewFailuresHandlerGET handles the API call to
+// /satellite/renewfailures.
+func (api *API) satelliteRenewFailuresHandlerGET(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+	WriteJSON(w, RenewFailuresGET{Failures: api.satellite.RenewFailures()})
+}
+
 // satelliteContractsHandlerGET handles the API call to /satellite/contracts.
 //
 // Active contracts are contracts that are actively being used to store data
@@ -162,8 +1036,14 @@ func (api *API) satelliteBalanceHandlerGET(w http.ResponseWriter, _ *http.Reques
 //
 // ExpiredRefreshed contracts are refreshed contracts who's endheights are in
 // the past.
-func (api *API) satelliteContractsHandlerGET(w http.ResponseWriter, _ *http.Request, ps httprouter.Params) {
+func (api *API) satelliteContractsHandlerGET(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
 	pk := ps.ByName("publickey")
+	// The router doesn't allow a static segment next to the public key
+	// parameter, so /satellite/contracts/due is dispatched from here.
+	if pk == "due" {
+		api.satelliteContractsDueHandlerGET(w, req, ps)
+		return
+	}
 	var rc RenterContracts
 	currentBlockHeight := api.cs.Height()
 
@@ -174,7 +1054,8 @@ func (api *API) satelliteContractsHandlerGET(w http.ResponseWriter, _ *http.Requ
 		key := modules.ReadPublicKey(pk)
 		renter, err = api.satellite.GetRenter(key)
 		if err != nil {
-			pk = ""
+			WriteError(w, Error{"unable to find renter: " + err.Error()}, http.StatusNotFound)
+			return
 		}
 	}
 
@@ -184,36 +1065,8 @@ func (api *API) satelliteContractsHandlerGET(w http.ResponseWriter, _ *http.Requ
 			continue
 		}
 
-		// Fetch host address.
-		var netAddress smodules.NetAddress
-		hdbe, exists, _ := api.satellite.Host(c.HostPublicKey)
-		if exists {
-			netAddress = hdbe.NetAddress
-		}
-
 		// Build the contract.
-		contract := RenterContract{
-			BadContract:         c.Utility.BadContract,
-			DownloadSpending:    c.DownloadSpending,
-			EndHeight:           c.EndHeight,
-			Fees:                c.TxnFee.Add(c.SiafundFee).Add(c.ContractFee),
-			FundAccountSpending: c.FundAccountSpending,
-			GoodForUpload:       c.Utility.GoodForUpload,
-			GoodForRenew:        c.Utility.GoodForRenew,
-			RenterPublicKey:     c.RenterPublicKey,
-			HostPublicKey:       c.HostPublicKey,
-			HostVersion:         hdbe.Version,
-			ID:                  c.ID,
-			LastTransaction:     c.Transaction,
-			NetAddress:          netAddress,
-			MaintenanceSpending: c.MaintenanceSpending,
-			RenterFunds:         c.RenterFunds,
-			Size:                c.Size(),
-			StartHeight:         c.StartHeight,
-			StorageSpending:     c.StorageSpending,
-			TotalCost:           c.TotalCost,
-			UploadSpending:      c.UploadSpending,
-		}
+		contract := api.renterContract(c)
 
 		// Determine contract status.
 		refreshed := api.satellite.RefreshedContract(c.ID)
@@ -303,3 +1156,81 @@ func (api *API) satelliteContractsHandlerGET(w http.ResponseWriter, _ *http.Requ
 
 	WriteJSON(w, rc)
 }
+
+// contractsCSVHeader lists the columns of the contracts CSV export.
+var contractsCSVHeader = []string{
+	"id",
+	"renteremail",
+	"hostpublickey",
+	"netaddress",
+	"startheight",
+	"endheight",
+	"totalcost",
+	"renterfunds",
+	"goodforupload",
+	"goodforrenew",
+	"badcontract",
+	"locked",
+}
+
+// satelliteContractsCSVHandlerGET handles the API calls to
+// /satellite/contracts.csv and /satellite/renter/:publickey/contracts.csv.
+// It streams the active contracts, optionally of a single renter, as CSV.
+func (api *API) satelliteContractsCSVHandlerGET(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	pk := ps.ByName("publickey")
+	filename := "contracts.csv"
+	if pk != "" {
+		if _, err := api.satellite.GetRenter(modules.ReadPublicKey(pk)); err != nil {
+			WriteError(w, Error{"renter not found: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+		filename = "contracts-" + strings.TrimPrefix(pk, "ed25519:") + ".csv"
+	}
+
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", "attachment; filename=\""+filename+"\"")
+	cw := csv.NewWriter(w)
+	cw.Write(contractsCSVHeader)
+
+	emails := make(map[string]string)
+	for _, c := range api.satellite.Contracts() {
+		rpk := c.RenterPublicKey.String()
+		if pk != "" && rpk != pk {
+			continue
+		}
+
+		// Look up the renter email once per renter.
+		email, ok := emails[rpk]
+		if !ok {
+			if renter, err := api.satellite.GetRenter(c.RenterPublicKey); err == nil {
+				email = renter.Email
+			}
+			emails[rpk] = email
+		}
+
+		var netAddress smodules.NetAddress
+		if hdbe, exists, _ := api.satellite.Host(c.HostPublicKey); exists {
+			netAddress = hdbe.NetAddress
+		}
+
+		err := cw.Write([]string{
+			c.ID.String(),
+			email,
+			c.HostPublicKey.String(),
+			string(netAddress),
+			strconv.FormatUint(uint64(c.StartHeight), 10),
+			strconv.FormatUint(uint64(c.EndHeight), 10),
+			c.TotalCost.String(),
+			c.RenterFunds.String(),
+			strconv.FormatBool(c.Utility.GoodForUpload),
+			strconv.FormatBool(c.Utility.GoodForRenew),
+			strconv.FormatBool(c.Utility.BadContract),
+			strconv.FormatBool(c.Utility.Locked),
+		})
+		if err != nil {
+			// The client has most likely gone away.
+			return
+		}
+	}
+	cw.Flush()
+}
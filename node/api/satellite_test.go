@@ -0,0 +1,657 @@
+package api
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/mike76-dev/sia-satellite/modules"
+
+	"go.sia.tech/siad/crypto"
+	smodules "go.sia.tech/siad/modules"
+	"go.sia.tech/siad/types"
+)
+
+// testSatellite is a satellite with a fixed set of renters, hosts and
+// contracts. Only the methods used by the tests are implemented.
+type testSatellite struct {
+	modules.Satellite
+	renters   []modules.Renter
+	hosts     []smodules.HostDBEntry
+	contracts []modules.RenterContract
+	failures  []modules.RenewFailure
+	templates map[string]modules.AllowanceTemplate
+	tokens    map[string]string
+	scores    map[types.FileContractID]types.Currency
+	deleted   []string
+}
+
+// GetRenter implements modules.Satellite.
+func (s *testSatellite) GetRenter(pk types.SiaPublicKey) (modules.Renter, error) {
+	for _, renter := range s.renters {
+		if renter.PublicKey.Equals(pk) {
+			return renter, nil
+		}
+	}
+	return modules.Renter{}, errors.New("renter not found")
+}
+
+// Host implements modules.Satellite.
+func (s *testSatellite) Host(pk types.SiaPublicKey) (smodules.HostDBEntry, bool, error) {
+	for _, host := range s.hosts {
+		if host.PublicKey.Equals(pk) {
+			return host, true, nil
+		}
+	}
+	return smodules.HostDBEntry{}, false, nil
+}
+
+// ActiveHosts implements modules.Satellite.
+func (s *testSatellite) ActiveHosts() ([]smodules.HostDBEntry, error) {
+	return s.hosts, nil
+}
+
+// Contracts implements modules.Satellite.
+func (s *testSatellite) Contracts() []modules.RenterContract {
+	return s.contracts
+}
+
+// AddRenter implements modules.Satellite.
+func (s *testSatellite) AddRenter(email string, pk types.SiaPublicKey, a smodules.Allowance) (modules.Renter, error) {
+	for _, renter := range s.renters {
+		if renter.Email == email || renter.PublicKey.Equals(pk) {
+			return modules.Renter{}, modules.ErrRenterExists
+		}
+	}
+	renter := modules.Renter{Email: email, PublicKey: pk, Allowance: a}
+	s.renters = append(s.renters, renter)
+	return renter, nil
+}
+
+// RenewFailures implements modules.Satellite.
+func (s *testSatellite) RenewFailures() []modules.RenewFailure {
+	return s.failures
+}
+
+// AllowanceTemplate implements modules.Satellite.
+func (s *testSatellite) AllowanceTemplate(name string) (modules.AllowanceTemplate, error) {
+	t, exists := s.templates[name]
+	if !exists {
+		return modules.AllowanceTemplate{}, modules.ErrTemplateNotFound
+	}
+	return t, nil
+}
+
+// SaveAllowanceTemplate implements modules.Satellite.
+func (s *testSatellite) SaveAllowanceTemplate(t modules.AllowanceTemplate) error {
+	if s.templates == nil {
+		s.templates = make(map[string]modules.AllowanceTemplate)
+	}
+	s.templates[t.Name] = t
+	return nil
+}
+
+// FormationScore implements modules.Satellite.
+func (s *testSatellite) FormationScore(id types.FileContractID) (types.Currency, bool) {
+	score, exists := s.scores[id]
+	return score, exists
+}
+
+// DeleteRenters implements modules.Satellite.
+func (s *testSatellite) DeleteRenters(emails []string, force bool, token string) ([]modules.RenterDeletion, error) {
+	var results []modules.RenterDeletion
+	for _, email := range emails {
+		s.deleted = append(s.deleted, email)
+		results = append(results, modules.RenterDeletion{Email: email, Deleted: 1})
+	}
+	return results, nil
+}
+
+// RefreshedContract implements modules.Satellite.
+func (s *testSatellite) RefreshedContract(types.FileContractID) bool {
+	return false
+}
+
+// OldContracts implements modules.Satellite.
+func (s *testSatellite) OldContracts() []modules.RenterContract {
+	return nil
+}
+
+// testCS is a consensus set at a fixed height.
+type testCS struct {
+	smodules.ConsensusSet
+	height types.BlockHeight
+}
+
+// Height implements modules.ConsensusSet.
+func (cs testCS) Height() types.BlockHeight { return cs.height }
+
+// testKey returns a public key derived from b.
+func testKey(b byte) types.SiaPublicKey {
+	var pk crypto.PublicKey
+	pk[0] = b
+	return types.Ed25519PublicKey(pk)
+}
+
+// TestRemainingStorage tests the remaining storage capacity of a contract.
+func TestRemainingStorage(t *testing.T) {
+	funds := types.SiacoinPrecision.Mul64(100)
+	price := types.SiacoinPrecision.Div64(1e6) // 1 SC per 1 MB per block
+	tests := []struct {
+		name      string
+		price     types.Currency
+		endHeight types.BlockHeight
+		height    types.BlockHeight
+		remaining uint64
+	}{
+		{"known price", price, 1100, 1000, 1e6},
+		{"ended", price, 1000, 1000, 0},
+		{"free storage", types.ZeroCurrency, 1100, 1000, math.MaxUint64},
+		{"overflow", types.NewCurrency64(1), 1001, 1000, math.MaxUint64},
+	}
+	for _, tt := range tests {
+		if remaining := remainingStorage(funds, tt.price, tt.endHeight, tt.height); remaining != tt.remaining {
+			t.Errorf("%v: expected %v, got %v", tt.name, tt.remaining, remaining)
+		}
+	}
+}
+
+// TestContractSearch tests that the contracts of a renter are filtered by
+// a substring of the host net address.
+func TestContractSearch(t *testing.T) {
+	renter, other := testKey(1), testKey(2)
+	s := &testSatellite{
+		renters: []modules.Renter{{PublicKey: renter}, {PublicKey: other}},
+	}
+	for i, addr := range []string{"10.0.0.1:9982", "10.0.0.2:9982", "host.example.com:9982"} {
+		var host smodules.HostDBEntry
+		host.PublicKey = testKey(byte(10 + i))
+		host.NetAddress = smodules.NetAddress(addr)
+		s.hosts = append(s.hosts, host)
+	}
+	for i, c := range []struct {
+		renter types.SiaPublicKey
+		host   byte
+	}{{renter, 10}, {renter, 11}, {renter, 12}, {other, 10}, {renter, 13}} {
+		var id types.FileContractID
+		id[0] = byte(i + 1)
+		s.contracts = append(s.contracts, modules.RenterContract{
+			ID:              id,
+			RenterPublicKey: c.renter,
+			HostPublicKey:   testKey(c.host),
+		})
+	}
+	api := &API{cs: testCS{}, satellite: s}
+
+	search := func(key types.SiaPublicKey, netAddress string) (int, ContractSearchGET) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/satellite/renter/"+key.String()+"/contracts/search?netaddress="+netAddress, nil)
+		api.satelliteContractSearchHandlerGET(w, req, httprouter.Params{{Key: "publickey", Value: key.String()}})
+		var csg ContractSearchGET
+		if w.Code == http.StatusOK {
+			if err := json.NewDecoder(w.Body).Decode(&csg); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return w.Code, csg
+	}
+
+	tests := []struct {
+		netAddress string
+		hosts      []byte
+	}{
+		{"10.0.0.", []byte{10, 11}},
+		{"10.0.0.2", []byte{11}},
+		{"example.com", []byte{12}},
+		{"10.0.1.", nil},
+	}
+	for _, tt := range tests {
+		code, csg := search(renter, tt.netAddress)
+		if code != http.StatusOK {
+			t.Fatalf("%v: expected status %v, got %v", tt.netAddress, http.StatusOK, code)
+		}
+		if len(csg.Contracts) != len(tt.hosts) {
+			t.Fatalf("%v: expected %v contracts, got %v", tt.netAddress, len(tt.hosts), len(csg.Contracts))
+		}
+		for i, c := range csg.Contracts {
+			if !c.HostPublicKey.Equals(testKey(tt.hosts[i])) || !c.RenterPublicKey.Equals(renter) {
+				t.Fatalf("%v: unexpected contract with host %v", tt.netAddress, c.HostPublicKey)
+			}
+		}
+	}
+
+	// An unknown renter and a missing net address are rejected.
+	if code, _ := search(testKey(3), "10.0.0."); code != http.StatusBadRequest {
+		t.Fatalf("expected status %v for an unknown renter, got %v", http.StatusBadRequest, code)
+	}
+	if code, _ := search(renter, ""); code != http.StatusBadRequest {
+		t.Fatalf("expected status %v for a missing net address, got %v", http.StatusBadRequest, code)
+	}
+}
+
+// TestRentersPOST tests that a renter is created, and that a duplicate is
+// rejected with a conflict.
+func TestRentersPOST(t *testing.T) {
+	s := &testSatellite{}
+	api := &API{satellite: s}
+	allowance := smodules.Allowance{
+		Funds:              types.SiacoinPrecision.Mul64(1000),
+		Hosts:              10,
+		Period:             1000,
+		RenewWindow:        100,
+		ExpectedStorage:    1e12,
+		ExpectedRedundancy: 3,
+	}
+	create := func(email string, pk types.SiaPublicKey) int {
+		t.Helper()
+		body, err := json.Marshal(RentersPOST{Email: email, PublicKey: pk, Allowance: allowance})
+		if err != nil {
+			t.Fatal(err)
+		}
+		w := httptest.NewRecorder()
+		api.satelliteRentersHandlerPOST(w, httptest.NewRequest("POST", "/satellite/renters", bytes.NewReader(body)), nil)
+		return w.Code
+	}
+
+	if code := create("renter@example.com", testKey(1)); code != http.StatusOK {
+		t.Fatalf("expected status %v, got %v", http.StatusOK, code)
+	}
+	if len(s.renters) != 1 || s.renters[0].Email != "renter@example.com" {
+		t.Fatal("renter not created")
+	}
+	if code := create("renter@example.com", testKey(2)); code != http.StatusConflict {
+		t.Fatalf("expected status %v for a duplicate email, got %v", http.StatusConflict, code)
+	}
+	if code := create("other@example.com", testKey(1)); code != http.StatusConflict {
+		t.Fatalf("expected status %v for a duplicate public key, got %v", http.StatusConflict, code)
+	}
+	if code := create("", testKey(3)); code != http.StatusBadRequest {
+		t.Fatalf("expected status %v for a missing email, got %v", http.StatusBadRequest, code)
+	}
+}
+
+// TestRenewFailures tests that the failed renewals are reported with their
+// failure counts.
+func TestRenewFailures(t *testing.T) {
+	s := &testSatellite{}
+	api := &API{satellite: s}
+	get := func() RenewFailuresGET {
+		t.Helper()
+		rw := httptest.NewRecorder()
+		api.satelliteRenewFailuresHandlerGET(rw, httptest.NewRequest("GET", "/satellite/renewfailures", nil), nil)
+		var rfg RenewFailuresGET
+		if err := json.NewDecoder(rw.Body).Decode(&rfg); err != nil {
+			t.Fatal(err)
+		}
+		return rfg
+	}
+
+	if rfg := get(); len(rfg.Failures) != 0 {
+		t.Fatal("expected no failures, got", rfg.Failures)
+	}
+
+	s.failures = []modules.RenewFailure{
+		{ID: types.FileContractID{3}, Failures: 5},
+		{ID: types.FileContractID{1}, Failures: 2},
+	}
+	rfg := get()
+	if len(rfg.Failures) != 2 {
+		t.Fatalf("expected 2 failures, got %v", len(rfg.Failures))
+	}
+	for i, f := range s.failures {
+		if rfg.Failures[i] != f {
+			t.Fatalf("expected %+v, got %+v", f, rfg.Failures[i])
+		}
+	}
+}
+
+// TestContractsCSV tests that the contracts are exported as CSV with a
+// header row, optionally filtered by the renter.
+func TestContractsCSV(t *testing.T) {
+	renter, other := testKey(1), testKey(2)
+	var host smodules.HostDBEntry
+	host.PublicKey = testKey(10)
+	host.NetAddress = "10.0.0.1:9982"
+	s := &testSatellite{
+		renters: []modules.Renter{{Email: "renter@example.com", PublicKey: renter}, {PublicKey: other}},
+		hosts:   []smodules.HostDBEntry{host},
+		contracts: []modules.RenterContract{
+			{
+				ID:              types.FileContractID{1},
+				RenterPublicKey: renter,
+				HostPublicKey:   host.PublicKey,
+				StartHeight:     100,
+				EndHeight:       1100,
+				TotalCost:       types.SiacoinPrecision.Mul64(5),
+				RenterFunds:     types.SiacoinPrecision.Mul64(4),
+				Utility:         smodules.ContractUtility{GoodForUpload: true, GoodForRenew: true},
+			},
+			{
+				ID:              types.FileContractID{2},
+				RenterPublicKey: other,
+				HostPublicKey:   testKey(11),
+			},
+		},
+	}
+	api := &API{satellite: s}
+	get := func(key string) (*httptest.ResponseRecorder, [][]string) {
+		t.Helper()
+		rw := httptest.NewRecorder()
+		api.satelliteContractsCSVHandlerGET(rw, httptest.NewRequest("GET", "/satellite/contracts.csv", nil), httprouter.Params{{Key: "publickey", Value: key}})
+		if rw.Code != http.StatusOK {
+			return rw, nil
+		}
+		records, err := csv.NewReader(rw.Body).ReadAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return rw, records
+	}
+
+	rw, records := get("")
+	if ct := rw.Header().Get("Content-Type"); ct != "text/csv" {
+		t.Fatal("expected content type text/csv, got", ct)
+	}
+	if cd := rw.Header().Get("Content-Disposition"); !strings.Contains(cd, "filename=\"contracts.csv\"") {
+		t.Fatal("unexpected content disposition", cd)
+	}
+	if len(records) != 3 {
+		t.Fatalf("expected a header and 2 rows, got %v records", len(records))
+	}
+	if !reflect.DeepEqual(records[0], contractsCSVHeader) {
+		t.Fatal("unexpected header", records[0])
+	}
+	row := []string{
+		types.FileContractID{1}.String(),
+		"renter@example.com",
+		host.PublicKey.String(),
+		"10.0.0.1:9982",
+		"100",
+		"1100",
+		types.SiacoinPrecision.Mul64(5).String(),
+		types.SiacoinPrecision.Mul64(4).String(),
+		"true",
+		"true",
+		"false",
+		"false",
+	}
+	if !reflect.DeepEqual(records[1], row) {
+		t.Fatalf("expected row %v, got %v", row, records[1])
+	}
+
+	// The per-renter variant only exports the contracts of the renter.
+	rw, records = get(other.String())
+	id := types.FileContractID{2}
+	if len(records) != 2 || records[1][0] != id.String() {
+		t.Fatal("expected only the contract of the other renter, got", records)
+	}
+	if records[1][1] != "" || records[1][3] != "" {
+		t.Fatal("expected no email and no net address, got", records[1])
+	}
+	if cd := rw.Header().Get("Content-Disposition"); !strings.Contains(cd, strings.TrimPrefix(other.String(), "ed25519:")) {
+		t.Fatal("expected the renter key in the filename, got", cd)
+	}
+
+	// An unknown renter is rejected.
+	if rw, _ := get(testKey(3).String()); rw.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %v for an unknown renter, got %v", http.StatusBadRequest, rw.Code)
+	}
+}
+
+// TestRenterFromTemplate tests that a renter created from an allowance
+// template gets the allowance of the template.
+func TestRenterFromTemplate(t *testing.T) {
+	s := &testSatellite{}
+	api := &API{satellite: s}
+	post := func(handler httprouter.Handle, v interface{}) int {
+		t.Helper()
+		body, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w := httptest.NewRecorder()
+		handler(w, httptest.NewRequest("POST", "/", bytes.NewReader(body)), nil)
+		return w.Code
+	}
+
+	template := modules.AllowanceTemplate{
+		Name: "standard",
+		Allowance: smodules.Allowance{
+			Funds:              types.SiacoinPrecision.Mul64(1000),
+			Hosts:              30,
+			Period:             4032,
+			RenewWindow:        1008,
+			ExpectedStorage:    1e12,
+			ExpectedRedundancy: 3,
+			MaxStoragePrice:    types.SiacoinPrecision.Div64(1e6),
+		},
+	}
+	if code := post(api.satelliteTemplatesHandlerPOST, template); code != http.StatusNoContent {
+		t.Fatalf("expected status %v, got %v", http.StatusNoContent, code)
+	}
+	if code := post(api.satelliteTemplatesHandlerPOST, modules.AllowanceTemplate{Allowance: template.Allowance}); code != http.StatusBadRequest {
+		t.Fatalf("expected status %v for a missing name, got %v", http.StatusBadRequest, code)
+	}
+
+	// The allowance of the template replaces the one in the request.
+	rp := RentersPOST{
+		Email:     "renter@example.com",
+		PublicKey: testKey(1),
+		Allowance: smodules.Allowance{Hosts: 1},
+		Template:  "standard",
+	}
+	if code := post(api.satelliteRentersHandlerPOST, rp); code != http.StatusOK {
+		t.Fatalf("expected status %v, got %v", http.StatusOK, code)
+	}
+	if len(s.renters) != 1 || !reflect.DeepEqual(s.renters[0].Allowance, template.Allowance) {
+		t.Fatal("expected the renter to get the allowance of the template, got", s.renters)
+	}
+
+	// An unknown template is rejected.
+	rp.Email, rp.PublicKey, rp.Template = "other@example.com", testKey(2), "premium"
+	if code := post(api.satelliteRentersHandlerPOST, rp); code != http.StatusBadRequest {
+		t.Fatalf("expected status %v for an unknown template, got %v", http.StatusBadRequest, code)
+	}
+	if len(s.renters) != 1 {
+		t.Fatal("expected no renter to be created from an unknown template")
+	}
+}
+
+// IssueRenterToken implements modules.Satellite.
+func (s *testSatellite) IssueRenterToken(pk types.SiaPublicKey) (string, error) {
+	if _, err := s.GetRenter(pk); err != nil {
+		return "", err
+	}
+	if s.tokens == nil {
+		s.tokens = make(map[string]string)
+	}
+	token := "token-" + pk.String()
+	s.tokens[pk.String()] = token
+	return token, nil
+}
+
+// VerifyRenterToken implements modules.Satellite.
+func (s *testSatellite) VerifyRenterToken(pk types.SiaPublicKey, token string) bool {
+	return token != "" && s.tokens[pk.String()] == token
+}
+
+// TestRenterToken tests that a renter token gives access to the renter's
+// own data only, and that the admin password gives access to all renters.
+func TestRenterToken(t *testing.T) {
+	renter, other := testKey(1), testKey(2)
+	s := &testSatellite{
+		renters: []modules.Renter{{Email: "renter@example.com", PublicKey: renter}, {Email: "other@example.com", PublicKey: other}},
+		contracts: []modules.RenterContract{
+			{ID: types.FileContractID{1}, RenterPublicKey: renter, HostPublicKey: testKey(10)},
+			{ID: types.FileContractID{2}, RenterPublicKey: other, HostPublicKey: testKey(11)},
+		},
+	}
+	api := New("Sat-Agent", "admin", testCS{}, nil, nil, s, nil, nil)
+	serve := func(method, path, password string) int {
+		t.Helper()
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("User-Agent", "Sat-Agent")
+		if password != "" {
+			req.SetBasicAuth("", password)
+		}
+		rw := httptest.NewRecorder()
+		api.ServeHTTP(rw, req)
+		return rw.Code
+	}
+
+	// Only the admin can issue a token.
+	if code := serve("POST", "/satellite/renter/"+renter.String()+"/token", ""); code != http.StatusUnauthorized {
+		t.Fatal("expected issuing a token without the password to fail, got", code)
+	}
+	if code := serve("POST", "/satellite/renter/"+renter.String()+"/token", "admin"); code != http.StatusOK {
+		t.Fatal("expected the admin to issue a token, got", code)
+	}
+	token := s.tokens[renter.String()]
+	if code := serve("POST", "/satellite/renter/"+other.String()+"/token", token); code != http.StatusUnauthorized {
+		t.Fatal("expected a renter token not to issue tokens, got", code)
+	}
+
+	paths := func(pk types.SiaPublicKey) []string {
+		return []string{
+			"/satellite/renter/" + pk.String(),
+			"/satellite/renter/" + pk.String() + "/contracts.csv",
+		}
+	}
+
+	// The renter token accesses the renter's own data.
+	for _, path := range paths(renter) {
+		if code := serve("GET", path, token); code != http.StatusOK {
+			t.Fatalf("expected the token to access %v, got %v", path, code)
+		}
+		if code := serve("GET", path, "wrong"); code != http.StatusUnauthorized {
+			t.Fatalf("expected a wrong token to be rejected for %v, got %v", path, code)
+		}
+	}
+
+	// But not the data of another renter.
+	for _, path := range paths(other) {
+		if code := serve("GET", path, token); code != http.StatusUnauthorized {
+			t.Fatalf("expected the token to be rejected for %v, got %v", path, code)
+		}
+	}
+
+	// Nor the admin-only routes.
+	if code := serve("GET", "/satellite/renters", token); code != http.StatusUnauthorized {
+		t.Fatal("expected the token to be rejected for the renters, got", code)
+	}
+
+	// The admin password accesses all renters.
+	for _, path := range append(paths(renter), paths(other)...) {
+		if code := serve("GET", path, "admin"); code != http.StatusOK {
+			t.Fatalf("expected the admin to access %v, got %v", path, code)
+		}
+	}
+}
+
+// TestContractsFormationScore tests that the contracts listing includes
+// the formation-time host scores.
+func TestContractsFormationScore(t *testing.T) {
+	renter := testKey(1)
+	score := types.NewCurrency64(12345)
+	s := &testSatellite{
+		renters: []modules.Renter{{PublicKey: renter}},
+		contracts: []modules.RenterContract{
+			{
+				ID:              types.FileContractID{1},
+				RenterPublicKey: renter,
+				HostPublicKey:   testKey(10),
+				Utility:         smodules.ContractUtility{GoodForUpload: true, GoodForRenew: true},
+			},
+			{
+				ID:              types.FileContractID{2},
+				RenterPublicKey: renter,
+				HostPublicKey:   testKey(11),
+				Utility:         smodules.ContractUtility{GoodForUpload: true, GoodForRenew: true},
+			},
+		},
+		scores: map[types.FileContractID]types.Currency{{1}: score},
+	}
+	api := &API{cs: testCS{}, satellite: s}
+	rw := httptest.NewRecorder()
+	api.satelliteContractsHandlerGET(rw, httptest.NewRequest("GET", "/satellite/contracts", nil), httprouter.Params{{Key: "publickey", Value: renter.String()}})
+	if rw.Code != http.StatusOK {
+		t.Fatal("expected status 200, got", rw.Code)
+	}
+	var rc RenterContracts
+	if err := json.NewDecoder(rw.Body).Decode(&rc); err != nil {
+		t.Fatal(err)
+	}
+	if len(rc.ActiveContracts) != 2 {
+		t.Fatal("expected 2 active contracts, got", len(rc.ActiveContracts))
+	}
+	for _, c := range rc.ActiveContracts {
+		expected := types.ZeroCurrency
+		if c.ID == (types.FileContractID{1}) {
+			expected = score
+		}
+		if !c.FormationScore.Equals(expected) {
+			t.Fatalf("expected contract %v to have score %v, got %v", c.ID, expected, c.FormationScore)
+		}
+	}
+}
+
+// TestRentersBulkDelete tests that a bulk deletion of the renters is only
+// carried out with the confirmation token of the request.
+func TestRentersBulkDelete(t *testing.T) {
+	s := &testSatellite{}
+	api := &API{satellite: s}
+	post := func(r RentersBulkDeleteRequest) *httptest.ResponseRecorder {
+		t.Helper()
+		body, err := json.Marshal(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rw := httptest.NewRecorder()
+		api.satelliteRentersBulkDeleteHandlerPOST(rw, httptest.NewRequest("POST", "/satellite/renters/bulkdelete", bytes.NewReader(body)), nil)
+		return rw
+	}
+
+	// Without the confirmation, the token is returned.
+	emails := []string{"b@example.com", "a@example.com"}
+	token := modules.BulkDeleteToken(emails, false)
+	rw := post(RentersBulkDeleteRequest{Emails: emails})
+	if rw.Code != http.StatusBadRequest || !strings.Contains(rw.Body.String(), "confirm=" + token) {
+		t.Fatal("expected the confirmation token to be requested, got", rw.Code, rw.Body.String())
+	}
+
+	// The token is bound to the force flag and to the emails.
+	if rw := post(RentersBulkDeleteRequest{Emails: emails, Force: true, Confirm: token}); rw.Code != http.StatusBadRequest {
+		t.Fatal("expected the token to be refused with force, got", rw.Code)
+	}
+	if rw := post(RentersBulkDeleteRequest{Emails: emails[:1], Confirm: token}); rw.Code != http.StatusBadRequest {
+		t.Fatal("expected the token to be refused for other emails, got", rw.Code)
+	}
+	if rw := post(RentersBulkDeleteRequest{Confirm: token}); rw.Code != http.StatusBadRequest {
+		t.Fatal("expected a request without emails to be refused, got", rw.Code)
+	}
+	if len(s.deleted) != 0 {
+		t.Fatal("expected nothing deleted, got", s.deleted)
+	}
+
+	// The order of the emails doesn't matter.
+	rw = post(RentersBulkDeleteRequest{Emails: []string{"a@example.com", "b@example.com"}, Confirm: token})
+	if rw.Code != http.StatusOK {
+		t.Fatal("expected the deletion to succeed, got", rw.Code, rw.Body.String())
+	}
+	var rbd RentersBulkDeletePOST
+	if err := json.NewDecoder(rw.Body).Decode(&rbd); err != nil {
+		t.Fatal(err)
+	}
+	if len(rbd.Results) != 2 || len(s.deleted) != 2 {
+		t.Fatal("expected 2 accounts deleted, got", rbd.Results)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"strconv"
 	"time"
 
 	"gitlab.com/NebulousLabs/errors"
@@ -103,6 +104,12 @@ func New(config *persist.SatdConfig, dbPassword string, loadStartTime time.Time)
 		Addr:		"127.0.0.1:3306",
 		DBName: config.DBName,
 	}
+	if config.DBLockTimeout > 0 {
+		if cfg.Params == nil {
+			cfg.Params = make(map[string]string)
+		}
+		cfg.Params["innodb_lock_wait_timeout"] = strconv.Itoa(config.DBLockTimeout)
+	}
 	db, err := sql.Open("mysql", cfg.FormatDSN())
 	if err != nil {
 		log.Fatalf("Could not connect to the database: %v\n", err)
@@ -173,7 +180,7 @@ func New(config *persist.SatdConfig, dbPassword string, loadStartTime time.Time)
 	if err := os.MkdirAll(satDir, 0700); err != nil {
 		return nil, errChan
 	}
-	s, err := satellite.New(cs, g, tp, w, db, mux, config.SatelliteAddr, satDir)
+	s, err := satellite.New(cs, g, tp, w, db, mux, config.SatelliteAddr, satDir, config.LogFormat, config.RPCRateLimit, time.Duration(config.MaxFormationExtension) * time.Second, config.RequireDeposit)
 	if err != nil {
 		errChan <- errors.Extend(err, errors.New("unable to create satellite"))
 		return nil, errChan
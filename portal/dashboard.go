@@ -428,13 +428,79 @@ func (api *portalAPI) seedHandlerGET(w http.ResponseWriter, req *http.Request, _
 			}, http.StatusInternalServerError)
 		return
 	}
-	renterSeed := modules.DeriveRenterSeed(walletSeed, email)
+	renterSeed := modules.DeriveRenterSeed(walletSeed, email, req.FormValue("identity"))
 	defer fastrand.Read(renterSeed[:])
 	
 	w.Header().Set("Renter-Seed", hex.EncodeToString(renterSeed[:]))
 	writeSuccess(w)
 }
 
+// identityHandlerPOST handles the POST /dashboard/identity requests.
+func (api *portalAPI) identityHandlerPOST(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	// Decode and verify the token.
+	token := getCookie(req, "satellite")
+	email, err := api.verifyCookie(w, token)
+	if err != nil {
+		return
+	}
+
+	// Check the identity suffix. An empty suffix is reserved for the
+	// default identity, which is created with the first payment.
+	identity := req.FormValue("identity")
+	if identity == "" || len(identity) > 64 {
+		writeError(w,
+			Error{
+				Code: httpErrorBadRequest,
+				Message: "invalid identity",
+			}, http.StatusBadRequest)
+		return
+	}
+
+	// Only accounts with a balance can have renter identities.
+	var ub *modules.UserBalance
+	if ub, err = api.portal.satellite.GetBalance(email); err != nil {
+		api.portal.log.Printf("ERROR: error querying database: %v\n", err)
+		writeError(w,
+			Error{
+				Code: httpErrorInternal,
+				Message: "internal error",
+			}, http.StatusInternalServerError)
+		return
+	}
+	if !ub.IsUser {
+		writeError(w,
+			Error{
+				Code: httpErrorNotFound,
+				Message: "no such account",
+			}, http.StatusBadRequest)
+		return
+	}
+
+	// Check if this identity already exists.
+	for _, r := range api.portal.satellite.Renters() {
+		if r.Email == email && r.Suffix == identity {
+			writeError(w,
+				Error{
+					Code: httpErrorBadRequest,
+					Message: "identity already exists",
+				}, http.StatusBadRequest)
+			return
+		}
+	}
+
+	if err := api.portal.createRenterIdentity(email, identity); err != nil {
+		api.portal.log.Printf("ERROR: error creating renter identity: %v\n", err)
+		writeError(w,
+			Error{
+				Code: httpErrorInternal,
+				Message: "internal error",
+			}, http.StatusInternalServerError)
+		return
+	}
+
+	writeSuccess(w)
+}
+
 // keyHandlerGET handles the GET /dashboard/key requests.
 func (api *portalAPI) keyHandlerGET(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
 	writeJSON(w, struct{Key string `json:"key"`}{Key: api.portal.satellite.PublicKey().String()})
@@ -516,11 +582,12 @@ func (api *portalAPI) contractsHandlerGET(w http.ResponseWriter, req *http.Reque
 		return
 	}
 
-	// Get the renter.
+	// Get the renter. An empty identity selects the default one.
 	var renter modules.Renter
+	identity := req.FormValue("identity")
 	renters := api.portal.satellite.Renters()
 	for _, r := range renters {
-		if r.Email == email {
+		if r.Email == email && r.Suffix == identity {
 			renter = r
 			break
 		}
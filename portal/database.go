@@ -119,8 +119,13 @@ func (p *Portal) threadedPruneUnverifiedAccounts() {
 	}
 }
 
-// deleteAccount deletes the user account from the database.
+// deleteAccount deletes the user account from the database. The API tokens
+// of the renters are deleted before the renter records they refer to.
 func (p *Portal) deleteAccount(email string) error {
+	_, errTokens := p.db.Exec("DELETE FROM renter_tokens WHERE renter_pk IN (SELECT public_key FROM renters WHERE email = ?)", email)
+	if errTokens != nil {
+		return errTokens
+	}
 	_, err0 := p.db.Exec("DELETE FROM renters WHERE email = ?", email)
 	_, err1 := p.db.Exec("DELETE FROM payments WHERE email = ?", email)
 	_, err2 := p.db.Exec("DELETE FROM balances WHERE email = ?", email)
@@ -243,17 +248,7 @@ func (p *Portal) addPayment(id string, amount float64, currency string) error {
 	}
 	if ub.Currency == "" {
 		// New renter, need to create a new record.
-		seed, err := p.satellite.GetWalletSeed()
-		defer fastrand.Read(seed[:])
-		if err != nil {
-			return err
-		}
-		renterSeed := modules.DeriveRenterSeed(seed, email)
-		defer fastrand.Read(renterSeed[:])
-		var sk crypto.SecretKey
-		copy(sk[:], ed25519.NewKeyFromSeed(renterSeed[:]))
-		pk := types.Ed25519PublicKey(sk.PublicKey())
-		if err = p.createNewRenter(email, pk); err != nil {
+		if err = p.createRenterIdentity(email, ""); err != nil {
 			return err
 		}
 		ub.Currency = currency
@@ -317,20 +312,41 @@ func (p *Portal) getPayments(email string, from, to int) ([]userPayment, error)
 	return payments, nil
 }
 
-// createNewRenter creates a new renter record in the database.
-func (p *Portal) createNewRenter(email string, pk types.SiaPublicKey) error {
+// createRenterIdentity derives the renter key for the given email and
+// identity suffix and creates a new renter record with it.
+func (p *Portal) createRenterIdentity(email, suffix string) error {
+	seed, err := p.satellite.GetWalletSeed()
+	defer fastrand.Read(seed[:])
+	if err != nil {
+		return err
+	}
+	renterSeed := modules.DeriveRenterSeed(seed, email, suffix)
+	defer fastrand.Read(renterSeed[:])
+	var sk crypto.SecretKey
+	copy(sk[:], ed25519.NewKeyFromSeed(renterSeed[:]))
+	defer fastrand.Read(sk[:])
+	pk := types.Ed25519PublicKey(sk.PublicKey())
+	return p.createNewRenter(email, suffix, pk)
+}
+
+// createNewRenter creates a new renter record in the database. The suffix
+// is empty for the default identity of the account.
+func (p *Portal) createNewRenter(email, suffix string, pk types.SiaPublicKey) error {
 	_, err := p.db.Exec(`
-		INSERT INTO renters (email, public_key, current_period, funds, hosts,
-			renew_window, expected_storage, expected_upload, expected_download,
-			expected_redundancy, max_rpc_price, max_contract_price,
-			max_download_bandwidth_price, max_sector_access_price,
-			max_storage_price, max_upload_bandwidth_price)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
-	`, email, pk.String(), 0, "", 0, 0, 0, 0, 0, 0, "", "", "", "", "", "")
+		INSERT INTO renters (email, suffix, public_key, current_period, funds,
+			hosts, renew_window, expected_storage, expected_upload,
+			expected_download, expected_redundancy, max_rpc_price,
+			max_contract_price, max_download_bandwidth_price,
+			max_sector_access_price, max_storage_price, max_upload_bandwidth_price,
+			allow_redundant_ips, max_storage_bytes, max_contracts_per_region,
+			paused, prefer_collateral, spend_rate_refresh, max_host_latency,
+			local_region, local_fraction, reuse_refund_address)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, email, suffix, pk.String(), 0, "", 0, 0, 0, 0, 0, 0, "", "", "", "", "", "", false, 0, 0, false, false, false, 0, "", 0, false)
 	if err != nil {
 		return err
 	}
-	p.satellite.CreateNewRenter(email, pk)
+	p.satellite.CreateNewRenter(email, suffix, pk)
 
 	return nil
 }
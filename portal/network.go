@@ -44,6 +44,8 @@ const (
 	httpErrorTokenExpired         = 41
 
 	httpErrorNotFound             = 50
+
+	httpErrorPaymentsDisabled     = 60
 )
 
 // portalAPI implements the http.Handler interface.
@@ -107,6 +109,9 @@ func (api *portalAPI) buildHTTPRoutes() {
 	router.GET("/dashboard/seed", func(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
 		api.seedHandlerGET(w, req, ps)
 	})
+	router.POST("/dashboard/identity", func(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+		api.identityHandlerPOST(w, req, ps)
+	})
 	router.GET("/dashboard/key", func(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
 		api.keyHandlerGET(w, req, ps)
 	})
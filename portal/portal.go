@@ -7,6 +7,7 @@ import (
 	"net"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 
 	"github.com/mike76-dev/sia-satellite/mail"
@@ -14,6 +15,7 @@ import (
 	"github.com/mike76-dev/sia-satellite/persist"
 	"github.com/mike76-dev/sia-satellite/satellite"
 
+	"github.com/stripe/stripe-go/v74"
 	"gitlab.com/NebulousLabs/errors"
 
 	smodules "go.sia.tech/siad/modules"
@@ -30,6 +32,9 @@ type Portal struct {
 	// Server-related fields.
 	apiPort    string
 
+	// stripeTestMode allows dry-run payment intents.
+	stripeTestMode bool
+
 	// Atomic stats.
 	authStats     map[string]authenticationStats
 
@@ -90,6 +95,20 @@ func New(config *persist.SatdConfig, s *satellite.Satellite, db *sql.DB, persist
 	})
 	p.log.Println("INFO: portal created, started logging")
 
+	// Only allow dry-run payment intents with a Stripe test key, so that
+	// they can't be enabled in production.
+	if config.StripeTestMode {
+		if strings.HasPrefix(stripe.Key, "sk_test_") {
+			p.stripeTestMode = true
+			p.log.Println("WARN: Stripe test mode enabled, dry-run payment intents are allowed")
+		} else {
+			p.log.Println("ERROR: Stripe test mode requires a test key, ignoring")
+		}
+	}
+
+	// Warn if the payments are disabled.
+	p.checkStripeKey()
+
 	// Create the mail client.
 	ms, err := mail.New(persistDir)
 	if err != nil {
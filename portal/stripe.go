@@ -2,6 +2,7 @@ package portal
 
 import (
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
 	"net/http"
 	"os"
@@ -12,11 +13,38 @@ import (
 	"github.com/stripe/stripe-go/v74/customer"
 	"github.com/stripe/stripe-go/v74/paymentintent"
 	"github.com/stripe/stripe-go/v74/webhook"
+
+	smodules "go.sia.tech/siad/modules"
 )
 
 // maxBodyBytes specifies the maximum body size for /webhook requests.
 const maxBodyBytes = int64(65536)
 
+// AlertIDStripeKeyMissing is the ID of the alert registered when no Stripe
+// key is configured.
+const AlertIDStripeKeyMissing = smodules.AlertID("portal-stripe-key-missing")
+
+// AlertMSGStripeKeyMissing indicates that the payments are disabled
+// because no Stripe key is configured.
+const AlertMSGStripeKeyMissing = "Payments are disabled because no Stripe key is configured"
+
+// paymentsConfigured returns true if a Stripe key is configured.
+func paymentsConfigured() bool {
+	return stripe.Key != ""
+}
+
+// checkStripeKey logs a warning and registers an alert if no Stripe key
+// is configured, so that the operator learns that the payments are
+// disabled before a renter tries to pay.
+func (p *Portal) checkStripeKey() {
+	if paymentsConfigured() {
+		p.staticAlerter.UnregisterAlert(AlertIDStripeKeyMissing)
+		return
+	}
+	p.log.Println("WARN: SATD_STRIPE_KEY is not set, payments are disabled")
+	p.staticAlerter.RegisterAlert(AlertIDStripeKeyMissing, AlertMSGStripeKeyMissing, "SATD_STRIPE_KEY is not set", smodules.SeverityWarning)
+}
+
 type item struct {
 	ID string `json:"id"`
 }
@@ -32,6 +60,12 @@ func (p *Portal) calculateOrderAmount(email string) (int64, string, error) {
 	return int64(up.Amount * 100), strings.ToLower(up.Currency), nil
 }
 
+// fakeClientSecret returns a deterministic client secret for the dry-run
+// payment intents.
+func fakeClientSecret(amount int64, currency string) string {
+	return fmt.Sprintf("pi_dryrun_%d%s_secret_dryrun", amount, currency)
+}
+
 // paymentHandlerPOST handles the POST /stripe/create-payment-intent requests.
 func (api *portalAPI) paymentHandlerPOST(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
 	// Decode and verify the token.
@@ -41,6 +75,74 @@ func (api *portalAPI) paymentHandlerPOST(w http.ResponseWriter, req *http.Reques
 		return
 	}
 
+	// Fail early if the payments are not configured.
+	if !paymentsConfigured() {
+		writeError(w,
+			Error{
+				Code: httpErrorPaymentsDisabled,
+				Message: "payments not configured",
+			}, http.StatusServiceUnavailable)
+		return
+	}
+
+	// Prepare the decoder and decode the parameters.
+	dec, decErr := prepareDecoder(w, req)
+	if decErr != nil {
+		return
+	}
+
+	var data struct {
+		Items []item `json:"items"`
+	}
+	err, code := api.handleDecodeError(w, dec.Decode(&data))
+	if code != http.StatusOK {
+		writeError(w, err, code)
+		return
+	}
+
+	// Reject the items without an ID.
+	for _, it := range data.Items {
+		if it.ID == "" {
+			writeError(w,
+				Error{
+					Code: httpErrorBadRequest,
+					Message: "item ID not specified",
+				}, http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Calculate the amount and currency.
+	amount, currency, pErr := api.portal.calculateOrderAmount(email)
+	if pErr != nil {
+		api.portal.log.Println("ERROR: couldn't read pending payment:", pErr)
+		writeError(w,
+			Error{
+				Code: httpErrorInternal,
+				Message: "internal error",
+			}, http.StatusInternalServerError)
+		return
+	}
+
+	// In the dry-run mode, return a fake client secret without
+	// touching Stripe.
+	if req.URL.Query().Get("dryrun") == "true" {
+		if !api.portal.stripeTestMode {
+			writeError(w,
+				Error{
+					Code: httpErrorBadRequest,
+					Message: "dry run not enabled",
+				}, http.StatusBadRequest)
+			return
+		}
+		writeJSON(w, struct {
+			ClientSecret string `json:"clientSecret"`
+		}{
+			ClientSecret: fakeClientSecret(amount, currency),
+		})
+		return
+	}
+
 	// Retrieve account balance.
 	ub, cErr := api.portal.satellite.GetBalance(email)
 	if cErr != nil {
@@ -96,32 +198,7 @@ func (api *portalAPI) paymentHandlerPOST(w http.ResponseWriter, req *http.Reques
 		}
 	}
 
-	// Prepare the decoder and decode the parameters.
-	dec, decErr := prepareDecoder(w, req)
-	if decErr != nil {
-		return
-	}
-
-	var data struct {
-		Items []item `json:"items"`
-	}
-	err, code := api.handleDecodeError(w, dec.Decode(&data))
-	if code != http.StatusOK {
-		writeError(w, err, code)
-		return
-	}
-
 	// Create a PaymentIntent with amount and currency.
-	amount, currency, pErr := api.portal.calculateOrderAmount(email)
-	if pErr != nil {
-		api.portal.log.Println("ERROR: couldn't read pending payment:", pErr)
-		writeError(w,
-			Error{
-				Code: httpErrorInternal,
-				Message: "internal error",
-			}, http.StatusInternalServerError)
-		return
-	}
 	params := &stripe.PaymentIntentParams{
 		Customer: stripe.String(cust.ID),
 		Amount:   stripe.Int64(amount),
@@ -0,0 +1,222 @@
+package portal
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mike76-dev/sia-satellite/internal/dbtest"
+	"github.com/mike76-dev/sia-satellite/satellite"
+
+	"github.com/stripe/stripe-go/v74"
+
+	smodules "go.sia.tech/siad/modules"
+	spersist "go.sia.tech/siad/persist"
+)
+
+// TestItemDecoding tests that the item IDs are decoded from the request.
+func TestItemDecoding(t *testing.T) {
+	if tag := reflect.TypeOf(item{}).Field(0).Tag.Get("json"); tag != "id" {
+		t.Fatalf("expected the JSON tag id, got %q", tag)
+	}
+
+	body := `{"items": [{"id": "storage"}, {"id": "bandwidth"}]}`
+	req := httptest.NewRequest("POST", "/stripe/create-payment-intent", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	dec, err := prepareDecoder(httptest.NewRecorder(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var data struct {
+		Items []item `json:"items"`
+	}
+	if err := dec.Decode(&data); err != nil {
+		t.Fatal(err)
+	}
+	if len(data.Items) != 2 || data.Items[0].ID != "storage" || data.Items[1].ID != "bandwidth" {
+		t.Fatalf("wrong items decoded: %+v", data.Items)
+	}
+}
+
+// TestPaymentDryRun tests that a dry-run payment intent returns a
+// deterministic fake client secret, and that it requires the test mode.
+func TestPaymentDryRun(t *testing.T) {
+	db, fake := dbtest.Open()
+	logger, err := spersist.NewFileLogger(filepath.Join(t.TempDir(), "portal.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer logger.Close()
+	p := &Portal{
+		db:        db,
+		satellite: &satellite.Satellite{},
+		log:       logger,
+	}
+	api := &portalAPI{portal: p}
+
+	// The test mode is only enabled with a Stripe test key.
+	key := stripe.Key
+	stripe.Key = "sk_test_dryrun"
+	defer func() { stripe.Key = key }()
+
+	// The nonce saved with the token is returned when verifying it.
+	var nonce string
+	fake.OnExec(func(query string, args []driver.Value) error {
+		if strings.Contains(query, "SET nonce") {
+			nonce = args[0].(string)
+		}
+		return nil
+	})
+	fake.OnQuery(func(query string, _ []driver.Value) (*dbtest.Rows, error) {
+		switch {
+		case strings.Contains(query, "SELECT nonce"):
+			return &dbtest.Rows{Columns: []string{"nonce"}, Values: [][]driver.Value{{nonce}}}, nil
+		case strings.Contains(query, "COUNT(*) FROM accounts"):
+			return &dbtest.Rows{Columns: []string{"count"}, Values: [][]driver.Value{{int64(1)}}}, nil
+		case strings.Contains(query, "FROM payments"):
+			return &dbtest.Rows{
+				Columns: []string{"amount", "currency", "amount_usd", "made"},
+				Values:  [][]driver.Value{{12.5, "EUR", 13.75, int64(1)}},
+			}, nil
+		}
+		return nil, nil
+	})
+	token, err := p.generateToken(cookiePrefix, "user@example.com", time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pay := func(body string) (int, string) {
+		req := httptest.NewRequest("POST", "/stripe/create-payment-intent?dryrun=true", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.AddCookie(&http.Cookie{Name: "satellite", Value: token})
+		rw := httptest.NewRecorder()
+		api.paymentHandlerPOST(rw, req, nil)
+		var resp struct {
+			ClientSecret string `json:"clientSecret"`
+		}
+		json.NewDecoder(rw.Body).Decode(&resp)
+		return rw.Code, resp.ClientSecret
+	}
+	body := `{"items": [{"id": "storage"}]}`
+
+	// The dry run is refused outside the test mode.
+	if code, _ := pay(body); code != http.StatusBadRequest {
+		t.Fatal("expected the dry run to be refused, got", code)
+	}
+
+	// In the test mode, the secret only depends on the amount.
+	p.stripeTestMode = true
+	code, secret := pay(body)
+	if code != http.StatusOK {
+		t.Fatal("dry run failed with", code)
+	}
+	if secret != "pi_dryrun_1250eur_secret_dryrun" {
+		t.Fatal("unexpected client secret:", secret)
+	}
+	if _, again := pay(body); again != secret {
+		t.Fatal("client secret not deterministic:", again, secret)
+	}
+
+	// The request is still validated.
+	if code, _ := pay(`{"items": [{"id": ""}]}`); code != http.StatusBadRequest {
+		t.Fatal("expected an item without an ID to be rejected, got", code)
+	}
+}
+
+// TestStripeKeyMissing tests that a missing Stripe key is warned about at
+// startup, and that the payments are rejected as not configured.
+func TestStripeKeyMissing(t *testing.T) {
+	db, fake := dbtest.Open()
+	logFile := filepath.Join(t.TempDir(), "portal.log")
+	logger, err := spersist.NewFileLogger(logFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer logger.Close()
+	p := &Portal{
+		db:            db,
+		satellite:     &satellite.Satellite{},
+		log:           logger,
+		staticAlerter: smodules.NewAlerter("portal"),
+	}
+	api := &portalAPI{portal: p}
+
+	key := stripe.Key
+	stripe.Key = ""
+	defer func() { stripe.Key = key }()
+
+	hasAlert := func() bool {
+		_, _, warn, _ := p.Alerts()
+		for _, alert := range warn {
+			if alert.Msg == AlertMSGStripeKeyMissing {
+				return true
+			}
+		}
+		return false
+	}
+
+	// The missing key is logged and alerted.
+	p.checkStripeKey()
+	if !hasAlert() {
+		t.Fatal("expected the missing key to be alerted")
+	}
+	b, err := os.ReadFile(logFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), "payments are disabled") {
+		t.Fatal("expected the missing key to be logged")
+	}
+
+	// The payments are rejected after the authentication.
+	var nonce string
+	fake.OnExec(func(query string, args []driver.Value) error {
+		if strings.Contains(query, "SET nonce") {
+			nonce = args[0].(string)
+		}
+		return nil
+	})
+	fake.OnQuery(func(query string, _ []driver.Value) (*dbtest.Rows, error) {
+		switch {
+		case strings.Contains(query, "SELECT nonce"):
+			return &dbtest.Rows{Columns: []string{"nonce"}, Values: [][]driver.Value{{nonce}}}, nil
+		case strings.Contains(query, "COUNT(*) FROM accounts"):
+			return &dbtest.Rows{Columns: []string{"count"}, Values: [][]driver.Value{{int64(1)}}}, nil
+		}
+		return nil, nil
+	})
+	token, err := p.generateToken(cookiePrefix, "user@example.com", time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest("POST", "/stripe/create-payment-intent", strings.NewReader(`{"items": [{"id": "storage"}]}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.AddCookie(&http.Cookie{Name: "satellite", Value: token})
+	rw := httptest.NewRecorder()
+	api.paymentHandlerPOST(rw, req, nil)
+	if rw.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected status %v, got %v", http.StatusServiceUnavailable, rw.Code)
+	}
+	var e Error
+	if err := json.NewDecoder(rw.Body).Decode(&e); err != nil {
+		t.Fatal(err)
+	}
+	if e.Code != httpErrorPaymentsDisabled || e.Message != "payments not configured" {
+		t.Fatalf("unexpected error: %+v", e)
+	}
+
+	// The alert is cleared once a key is configured.
+	stripe.Key = "sk_test_configured"
+	p.checkStripeKey()
+	if hasAlert() {
+		t.Fatal("expected the alert to be cleared")
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strings"
 
 	"github.com/mike76-dev/sia-satellite/persist"
 
@@ -24,12 +25,28 @@ var defaultConfig = persist.SatdConfig{
 	DBUser:        "",
 	DBName:        "satellite",
 	PortalPort:    ":8080",
+	LogFormat:     "text",
+	RPCRateLimit:  6,
+
+	MaxFormationExtension: 300,
 }
 
 var config persist.SatdConfig
 var configDir string
 
-func getAPIPassword() string {
+// getAPIPassword returns the API password. If passwordFile is set, the
+// password is read from the file, so that it can be mounted as a secret.
+// An empty file means no password.
+func getAPIPassword(passwordFile string) string {
+	if passwordFile != "" {
+		pw, err := os.ReadFile(passwordFile)
+		if err != nil {
+			log.Fatalf("Could not read API password file: %v\n", err)
+		}
+		fmt.Println("Using API password file.")
+		return strings.TrimRight(string(pw), "\r\n")
+	}
+
 	apiPassword := os.Getenv("SATD_API_PASSWORD")
 	if apiPassword != "" {
 		fmt.Println("Using SATD_API_PASSWORD environment variable.")
@@ -89,6 +106,13 @@ func main() {
 	dbUser := flag.String("db-user", "", "username for accessing the database")
 	dbName := flag.String("db-name", "", "name of MYSQL database")
 	portalPort := flag.String("portal", "", "port number the portal server listens at")
+	logFormat := flag.String("log-format", "", "format of the contract lifecycle logs (text or json)")
+	rpcRateLimit := flag.Float64("rpc-rate", 0, "number of requests per minute a single renter can make")
+	apiPasswordFile := flag.String("api-password-file", "", "file to read the API password from")
+	requireDeposit := flag.Bool("require-deposit", false, "reject new renters whose balance doesn't cover their allowance")
+	stripeTestMode := flag.Bool("stripe-test-mode", false, "allow dry-run payment intents (requires a Stripe test key)")
+	dbLockTimeout := flag.Int("db-lock-timeout", 0, "seconds a database write waits for a lock before failing")
+	maxExtension := flag.Int("max-formation-extension", 0, "longest extension of the formation deadline in seconds that a renter can be granted")
 	flag.Parse()
 	if *userAgent != "" {
 		config.UserAgent = *userAgent
@@ -121,6 +145,22 @@ func main() {
 	if *portalPort != "" {
 		config.PortalPort = *portalPort
 	}
+	if *logFormat != "" {
+		config.LogFormat = *logFormat
+	}
+	if *rpcRateLimit > 0 {
+		config.RPCRateLimit = *rpcRateLimit
+	}
+	config.StripeTestMode = *stripeTestMode
+	if *requireDeposit {
+		config.RequireDeposit = true
+	}
+	if *dbLockTimeout > 0 {
+		config.DBLockTimeout = *dbLockTimeout
+	}
+	if *maxExtension > 0 {
+		config.MaxFormationExtension = *maxExtension
+	}
 
 	// Save the configuration.
 	err = config.Save(configDir)
@@ -128,8 +168,12 @@ func main() {
 		log.Fatalln("Unable to save config file")
 	}
 
-	// Fetch API password.
-	apiPassword := getAPIPassword()
+	// Fetch API password. The file given on the command line takes
+	// precedence over the environment variable.
+	if *apiPasswordFile == "" {
+		*apiPasswordFile = os.Getenv("SATD_API_PASSWORD_FILE")
+	}
+	apiPassword := getAPIPassword(*apiPasswordFile)
 
 	// Fetch DB password.
 	dbPassword := getDBPassword()
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mike76-dev/sia-satellite/node/api"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// TestAPIPasswordFile tests that the password read from a file
+// authenticates the API requests, and that an empty file means no
+// authentication.
+func TestAPIPasswordFile(t *testing.T) {
+	dir := t.TempDir()
+	handler := func(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+		w.WriteHeader(http.StatusOK)
+	}
+	serve := func(password, auth string) int {
+		req := httptest.NewRequest("GET", "/", nil)
+		if auth != "" {
+			req.SetBasicAuth("", auth)
+		}
+		rw := httptest.NewRecorder()
+		api.RequirePassword(handler, password)(rw, req, nil)
+		return rw.Code
+	}
+
+	// The trailing newline is not part of the password.
+	path := filepath.Join(dir, "apipassword")
+	if err := os.WriteFile(path, []byte("secret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	password := getAPIPassword(path)
+	if password != "secret" {
+		t.Fatalf("expected password secret, got %q", password)
+	}
+	if code := serve(password, "secret"); code != http.StatusOK {
+		t.Fatal("expected the password to authenticate, got", code)
+	}
+	if code := serve(password, "wrong"); code != http.StatusUnauthorized {
+		t.Fatal("expected a wrong password to fail, got", code)
+	}
+	if code := serve(password, ""); code != http.StatusUnauthorized {
+		t.Fatal("expected a missing password to fail, got", code)
+	}
+
+	// An empty file disables the authentication.
+	path = filepath.Join(dir, "empty")
+	if err := os.WriteFile(path, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	password = getAPIPassword(path)
+	if password != "" {
+		t.Fatalf("expected no password, got %q", password)
+	}
+	if code := serve(password, ""); code != http.StatusOK {
+		t.Fatal("expected no authentication, got", code)
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"errors"
 
 	"github.com/mike76-dev/sia-satellite/modules"
+
+	"go.sia.tech/siad/types"
 )
 
 // GetBalance retrieves the balance information on the account.
@@ -101,6 +103,48 @@ func (s *Satellite) LockSiacoins(email string, amount float64) error {
 	return s.UpdateBalance(email, ub)
 }
 
+// FundAudit compares the funds locked in the renter's balance with the
+// cost of the renter's active contracts, including the Satellite fee.
+func (s *Satellite) FundAudit(rpk types.SiaPublicKey) (modules.FundAudit, error) {
+	renter, err := s.GetRenter(rpk)
+	if err != nil {
+		return modules.FundAudit{}, err
+	}
+	return s.fundAudit(renter, s.Contracts())
+}
+
+// fundAudit compares the funds locked in the renter's balance with the
+// cost of the renter's contracts among the given ones.
+func (s *Satellite) fundAudit(renter modules.Renter, contracts []modules.RenterContract) (modules.FundAudit, error) {
+	ub, err := s.GetBalance(renter.Email)
+	if err != nil {
+		return modules.FundAudit{}, err
+	}
+	scRate, _ := s.GetSiacoinRate(ub.Currency)
+	if scRate == 0 {
+		return modules.FundAudit{}, errors.New("unable to fetch SC rate")
+	}
+
+	fa := modules.FundAudit{
+		Currency: ub.Currency,
+		Locked:   ub.Locked,
+		LockedSC: ub.Locked / scRate,
+	}
+	hastings, _ := types.SiacoinPrecision.Float64()
+	for _, contract := range contracts {
+		if contract.RenterPublicKey.String() != renter.PublicKey.String() {
+			continue
+		}
+		cost, _ := contract.TotalCost.Float64()
+		fa.ContractsCost += cost / hastings
+		fa.Contracts++
+	}
+	fa.ExpectedLocked = fa.ContractsCost * modules.SatelliteOverhead
+	fa.Difference = fa.LockedSC - fa.ExpectedLocked
+
+	return fa, nil
+}
+
 // UnlockSiacoins implements FundLocker interface.
 func (s *Satellite) UnlockSiacoins(email string, amount, total float64) error {
 	// Sanity check.
@@ -0,0 +1,79 @@
+package satellite
+
+import (
+	"database/sql/driver"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/mike76-dev/sia-satellite/internal/dbtest"
+	"github.com/mike76-dev/sia-satellite/modules"
+
+	"go.sia.tech/siad/crypto"
+	"go.sia.tech/siad/types"
+)
+
+// TestFundAudit tests that the audit reports the exact gap between the
+// locked funds and the cost of the renter's contracts.
+func TestFundAudit(t *testing.T) {
+	db, fake := dbtest.Open()
+	s := &Satellite{
+		db:        db,
+		exchRates: map[string]float64{"USD": 1, "EUR": 1.25},
+		scusdRate: 0.01,
+	}
+
+	// 6 USD are locked, which is 600 SC.
+	currency := "USD"
+	fake.OnQuery(func(query string, args []driver.Value) (*dbtest.Rows, error) {
+		if strings.Contains(query, "FROM balances") && args[0] == "renter@example.com" {
+			return &dbtest.Rows{
+				Columns: []string{"subscribed", "balance", "locked", "currency", "stripe_id"},
+				Values:  [][]driver.Value{{false, 10.0, 6.0, currency, ""}},
+			}, nil
+		}
+		return nil, nil
+	})
+
+	var pk, other crypto.PublicKey
+	pk[0], other[0] = 1, 2
+	renter := modules.Renter{Email: "renter@example.com", PublicKey: types.Ed25519PublicKey(pk)}
+	contracts := []modules.RenterContract{
+		{RenterPublicKey: renter.PublicKey, TotalCost: types.SiacoinPrecision.Mul64(200)},
+		{RenterPublicKey: types.Ed25519PublicKey(other), TotalCost: types.SiacoinPrecision.Mul64(1000)},
+		{RenterPublicKey: renter.PublicKey, TotalCost: types.SiacoinPrecision.Mul64(300)},
+	}
+	equal := func(a, b float64) bool { return math.Abs(a - b) < 1e-9 }
+
+	// The contracts cost 500 SC, so 550 SC are expected to be locked with
+	// the fee, leaving a gap of 50 SC.
+	fa, err := s.fundAudit(renter, contracts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fa.Contracts != 2 || fa.Currency != "USD" || !equal(fa.Locked, 6) {
+		t.Fatalf("unexpected audit: %+v", fa)
+	}
+	if !equal(fa.LockedSC, 600) || !equal(fa.ContractsCost, 500) || !equal(fa.ExpectedLocked, 500 * modules.SatelliteOverhead) {
+		t.Fatalf("unexpected amounts: %+v", fa)
+	}
+	if !equal(fa.Difference, 600 - 500 * modules.SatelliteOverhead) {
+		t.Fatal("expected a difference of 50 SC, got", fa.Difference)
+	}
+
+	// The locked funds are converted from the balance currency.
+	currency = "EUR"
+	fa, err = s.fundAudit(renter, contracts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !equal(fa.LockedSC, 480) || !equal(fa.Difference, 480 - 500 * modules.SatelliteOverhead) {
+		t.Fatalf("unexpected amounts in EUR: %+v", fa)
+	}
+
+	// The audit fails without an exchange rate.
+	currency = "GBP"
+	if _, err := s.fundAudit(renter, contracts); err == nil {
+		t.Fatal("expected the audit to fail without an exchange rate")
+	}
+}
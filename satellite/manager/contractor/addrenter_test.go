@@ -0,0 +1,46 @@
+package contractor
+
+import (
+	"testing"
+
+	"github.com/mike76-dev/sia-satellite/modules"
+
+	"gitlab.com/NebulousLabs/errors"
+)
+
+// TestAddRenter tests that a new renter is created in the database and in
+// memory, and that the duplicate emails and public keys are rejected.
+func TestAddRenter(t *testing.T) {
+	c, fake := newTestContractor(t)
+	existing := testRenter(c, testKey(1))
+	c.mu.Lock()
+	c.blockHeight = 500
+	c.mu.Unlock()
+
+	rpk := testKey(2)
+	renter, err := c.AddRenter("new@example.com", rpk, existing.Allowance)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if renter.CurrentPeriod != 500 || renter.Email != "new@example.com" || !renter.PublicKey.Equals(rpk) {
+		t.Fatalf("wrong renter created: %+v", renter)
+	}
+	if stored, err := c.GetRenter(rpk); err != nil || stored.Email != "new@example.com" {
+		t.Fatal("renter not added to memory:", err)
+	}
+	inserts := fake.ExecsLike("INSERT INTO renters")
+	if len(inserts) != 1 || inserts[0].Args[0] != "new@example.com" || inserts[0].Args[2] != rpk.String() {
+		t.Fatal("renter record not inserted")
+	}
+
+	// Duplicates are rejected.
+	if _, err := c.AddRenter("other@example.com", rpk, existing.Allowance); !errors.Contains(err, modules.ErrRenterExists) {
+		t.Fatal("expected ErrRenterExists for a duplicate public key, got", err)
+	}
+	if _, err := c.AddRenter(existing.Email, testKey(3), existing.Allowance); !errors.Contains(err, modules.ErrRenterExists) {
+		t.Fatal("expected ErrRenterExists for a duplicate email, got", err)
+	}
+	if n := len(fake.ExecsLike("INSERT INTO renters")); n != 1 {
+		t.Fatalf("expected 1 insert, got %v", n)
+	}
+}
@@ -1,9 +1,32 @@
 package contractor
 
-import "go.sia.tech/siad/modules"
+import (
+	"fmt"
+
+	"go.sia.tech/siad/modules"
+	"go.sia.tech/siad/types"
+)
 
 // Alerts implements the modules.Alerter interface for the contractor. It returns
 // all alerts of the contractor.
 func (c *Contractor) Alerts() (crit, err, warn, info []modules.Alert) {
 	return c.staticAlerter.Alerts()
 }
+
+// managedLowFundsCause returns the cause of the low allowance funds alert.
+// Besides the generic cause, it contains the remaining allowance funds, the
+// cost of the contract that couldn't be afforded, the gap between them, and
+// the confirmed wallet balance, so that the operator can tell whether to
+// add funds or to adjust the allowance.
+func (c *Contractor) managedLowFundsCause(fundsRemaining, cost types.Currency) string {
+	var gap types.Currency
+	if cost.Cmp(fundsRemaining) > 0 {
+		gap = cost.Sub(fundsRemaining)
+	}
+	cause := fmt.Sprintf("%v: remaining %v, needed %v, gap %v", AlertCauseInsufficientAllowanceFunds, fundsRemaining.HumanString(), cost.HumanString(), gap.HumanString())
+	balance, _, _, err := c.wallet.ConfirmedBalance()
+	if err != nil {
+		return cause + ", wallet balance unavailable"
+	}
+	return cause + ", wallet balance " + balance.HumanString()
+}
@@ -4,42 +4,38 @@ import (
 	"errors"
 	"reflect"
 
-	"go.sia.tech/siad/modules"
+	"github.com/mike76-dev/sia-satellite/modules"
+
+	smodules "go.sia.tech/siad/modules"
 	"go.sia.tech/siad/types"
 )
 
 var (
 	errAllowanceNotSynced = errors.New("you must be synced to set an allowance")
 
-	// ErrAllowanceZeroFunds is returned if the allowance funds are being set to
-	// zero when not cancelling the allowance.
-	ErrAllowanceZeroFunds = errors.New("funds must be non-zero")
-	// ErrAllowanceZeroPeriod is returned if the allowance period is being set
-	// to zero when not cancelling the allowance.
-	ErrAllowanceZeroPeriod = errors.New("period must be non-zero")
-	// ErrAllowanceZeroWindow is returned if the allowance's renew window is being
-	// set to zero when not cancelling the allowance.
-	ErrAllowanceZeroWindow = errors.New("renew window must be non-zero")
-	// ErrAllowanceNoHosts is returned if the allowance's hosts are being set to
-	// zero when not cancelling the allowance.
-	ErrAllowanceNoHosts = errors.New("hosts must be non-zero")
-	// ErrAllowanceZeroExpectedStorage is returned if the allowance's expected
-	// storage is being set to zero when not cancelling the allowance.
-	ErrAllowanceZeroExpectedStorage = errors.New("expected storage must be non-zero")
 	// ErrAllowanceZeroExpectedUpload is returned if the allowance's expected
 	// upload is being set to zero when not cancelling the allowance.
 	ErrAllowanceZeroExpectedUpload = errors.New("expected upload  must be non-zero")
 	// ErrAllowanceZeroExpectedDownload is returned if the allowance's expected
 	// download is being set to zero when not cancelling the allowance.
 	ErrAllowanceZeroExpectedDownload = errors.New("expected download  must be non-zero")
-	// ErrAllowanceZeroExpectedRedundancy is returned if the allowance's expected
-	// redundancy is being set to zero when not cancelling the allowance.
-	ErrAllowanceZeroExpectedRedundancy = errors.New("expected redundancy must be non-zero")
 	// ErrRenterNotFound is returned when no renter matches the provided public
 	// key.
 	ErrRenterNotFound = errors.New("no renter found with this public key")
 )
 
+// checkAllowance performs the sanity checks of a non-empty allowance.
+func checkAllowance(a smodules.Allowance) error {
+	if err := modules.ValidateAllowance(a, 0, 0); err != nil {
+		return err
+	} else if a.ExpectedUpload == 0 {
+		return ErrAllowanceZeroExpectedUpload
+	} else if a.ExpectedDownload == 0 {
+		return ErrAllowanceZeroExpectedDownload
+	}
+	return nil
+}
+
 // SetAllowance sets the amount of money the Contractor is allowed to spend on
 // contracts over a given time period, divided among the number of hosts
 // specified.
@@ -49,30 +45,18 @@ var (
 //
 // NOTE: At this time, transaction fees are not counted towards the allowance.
 // This means the contractor may spend more than allowance.Funds.
-func (c *Contractor) SetAllowance(rpk types.SiaPublicKey, a modules.Allowance) error {
-	if reflect.DeepEqual(a, modules.Allowance{}) {
+func (c *Contractor) SetAllowance(rpk types.SiaPublicKey, a smodules.Allowance) error {
+	if reflect.DeepEqual(a, smodules.Allowance{}) {
 		return c.managedCancelAllowance(rpk)
 	}
 
 	// Sanity checks.
-	if a.Funds.Cmp(types.ZeroCurrency) <= 0 {
-		return ErrAllowanceZeroFunds
-	} else if a.Hosts == 0 {
-		return ErrAllowanceNoHosts
-	} else if a.Period == 0 {
-		return ErrAllowanceZeroPeriod
-	} else if a.RenewWindow == 0 {
-		return ErrAllowanceZeroWindow
-	} else if a.ExpectedStorage == 0 {
-		return ErrAllowanceZeroExpectedStorage
-	} else if a.ExpectedUpload == 0 {
-		return ErrAllowanceZeroExpectedUpload
-	} else if a.ExpectedDownload == 0 {
-		return ErrAllowanceZeroExpectedDownload
-	} else if a.ExpectedRedundancy == 0 {
-		return ErrAllowanceZeroExpectedRedundancy
+	if err := checkAllowance(a); err != nil {
+		return err
 	} else if !c.cs.Synced() {
 		return errAllowanceNotSynced
+	} else if _, err := c.managedFormationEndHeight(a, 0); err != nil {
+		return err
 	}
 
 	// Check if we know this renter.
@@ -108,7 +92,7 @@ func (c *Contractor) SetAllowance(rpk types.SiaPublicKey, a modules.Allowance) e
 	// was set to the empty allowance before.
 	c.mu.Lock()
 	unlockContracts := false
-	if reflect.DeepEqual(renter.Allowance, modules.Allowance{}) {
+	if reflect.DeepEqual(renter.Allowance, smodules.Allowance{}) {
 		renter.CurrentPeriod = c.blockHeight
 		if a.Period > a.RenewWindow {
 			renter.CurrentPeriod -= a.RenewWindow
@@ -134,7 +118,7 @@ func (c *Contractor) SetAllowance(rpk types.SiaPublicKey, a modules.Allowance) e
 			}
 			utility := contract.Utility()
 			utility.Locked = false
-			err := c.callUpdateUtility(contract, utility, false)
+			err := c.callUpdateUtility(contract, utility, false, "allowance set")
 			c.staticContracts.Return(contract)
 			if err != nil {
 				return err
@@ -142,6 +126,10 @@ func (c *Contractor) SetAllowance(rpk types.SiaPublicKey, a modules.Allowance) e
 		}
 	}
 
+	// Keep track of the funds to be released if the allowance was reduced
+	// below what has already been allocated.
+	c.managedUpdateOverAllocation(rpk, a.Funds)
+
 	// Inform the watchdog about the allowance change.
 	c.staticWatchdog.callAllowanceUpdated(rpk, a)
 
@@ -193,7 +181,7 @@ func (c *Contractor) managedCancelAllowance(rpk types.SiaPublicKey) error {
 
 	// Clear out the allowance and save.
 	c.mu.Lock()
-	renter.Allowance = modules.Allowance{}
+	renter.Allowance = smodules.Allowance{}
 	renter.CurrentPeriod = 0
 	c.renters[rpk.String()] = renter
 	c.mu.Unlock()
@@ -213,7 +201,7 @@ func (c *Contractor) managedCancelAllowance(rpk types.SiaPublicKey) error {
 		utility.GoodForRenew = false
 		utility.GoodForUpload = false
 		utility.Locked = true
-		err := c.callUpdateUtility(contract, utility, false)
+		err := c.callUpdateUtility(contract, utility, false, "allowance canceled")
 		c.staticContracts.Return(contract)
 		if err != nil {
 			return err
@@ -0,0 +1,140 @@
+package contractor
+
+import (
+	"github.com/mike76-dev/sia-satellite/modules"
+
+	smodules "go.sia.tech/siad/modules"
+	"go.sia.tech/siad/types"
+)
+
+// HostAllowlist returns the hosts that the renter's contracts are formed
+// with. An empty list means that the hosts are selected from the hostdb.
+func (c *Contractor) HostAllowlist(rpk types.SiaPublicKey) ([]types.SiaPublicKey, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if _, exists := c.renters[rpk.String()]; !exists {
+		return nil, ErrRenterNotFound
+	}
+	return append([]types.SiaPublicKey{}, c.hostAllowlists[rpk.String()]...), nil
+}
+
+// SetHostAllowlist replaces the renter's host allowlist. If the list is
+// not empty, the new contracts of the renter are only formed with the
+// listed hosts. An empty list restores the normal host selection.
+func (c *Contractor) SetHostAllowlist(rpk types.SiaPublicKey, hosts []types.SiaPublicKey) error {
+	c.mu.RLock()
+	_, exists := c.renters[rpk.String()]
+	c.mu.RUnlock()
+	if !exists {
+		return ErrRenterNotFound
+	}
+
+	// Remove the duplicates.
+	seen := make(map[string]struct{})
+	var allowlist []types.SiaPublicKey
+	for _, hpk := range hosts {
+		if _, dup := seen[hpk.String()]; dup {
+			continue
+		}
+		seen[hpk.String()] = struct{}{}
+		allowlist = append(allowlist, hpk)
+	}
+
+	// Replace the stored list in a single transaction, so that a failure
+	// doesn't leave a partial list behind.
+	tx, err := c.db.Begin()
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM renter_host_allowlists WHERE renter_pk = ?", rpk.String()); err != nil {
+		tx.Rollback()
+		return err
+	}
+	for _, hpk := range allowlist {
+		if _, err := tx.Exec(`
+			INSERT INTO renter_host_allowlists (renter_pk, host_pk)
+			VALUES (?, ?)
+		`, rpk.String(), hpk.String()); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	if len(allowlist) == 0 {
+		delete(c.hostAllowlists, rpk.String())
+	} else {
+		c.hostAllowlists[rpk.String()] = allowlist
+	}
+	c.mu.Unlock()
+	return nil
+}
+
+// allowedHosts returns the hosts that are on the allowlist, keeping their
+// order.
+func allowedHosts(hosts, allowlist []types.SiaPublicKey) []types.SiaPublicKey {
+	allowed := make(map[string]struct{}, len(allowlist))
+	for _, hpk := range allowlist {
+		allowed[hpk.String()] = struct{}{}
+	}
+	var filtered []types.SiaPublicKey
+	for _, hpk := range hosts {
+		if _, ok := allowed[hpk.String()]; ok {
+			filtered = append(filtered, hpk)
+		}
+	}
+	return filtered
+}
+
+// managedFilterAddresses drops the hosts that violate the IP rules of the
+// hostdb, either with a host of the address blacklist or with a host
+// earlier in the list, keeping the order of the rest.
+func (c *Contractor) managedFilterAddresses(hosts []smodules.HostDBEntry, addressBlacklist []types.SiaPublicKey) ([]smodules.HostDBEntry, error) {
+	kept := append([]types.SiaPublicKey{}, addressBlacklist...)
+	badHosts, err := c.hdb.CheckForIPViolations(kept)
+	c.managedHostDBResult(err)
+	if err != nil {
+		return nil, err
+	}
+	violations := len(badHosts)
+
+	var filtered []smodules.HostDBEntry
+	for _, host := range hosts {
+		badHosts, err := c.hdb.CheckForIPViolations(append(kept, host.PublicKey))
+		c.managedHostDBResult(err)
+		if err != nil {
+			return nil, err
+		}
+		// The host is dropped if it adds a violation, no matter which of
+		// the conflicting hosts is reported.
+		if len(badHosts) > violations {
+			continue
+		}
+		kept = append(kept, host.PublicKey)
+		filtered = append(filtered, host)
+	}
+	return filtered, nil
+}
+
+// loadHostAllowlists loads the host allowlists of the renters.
+func (c *Contractor) loadHostAllowlists() error {
+	rows, err := c.db.Query("SELECT renter_pk, host_pk FROM renter_host_allowlists")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var rpk, hpk string
+	for rows.Next() {
+		if err := rows.Scan(&rpk, &hpk); err != nil {
+			c.log.Println("Error scanning database row:", err)
+			continue
+		}
+		c.hostAllowlists[rpk] = append(c.hostAllowlists[rpk], modules.ReadPublicKey(hpk))
+	}
+
+	return rows.Err()
+}
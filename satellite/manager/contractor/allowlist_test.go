@@ -0,0 +1,112 @@
+package contractor
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"go.sia.tech/siad/types"
+)
+
+// TestAllowlistFormation tests that only the allowlisted hosts that pass
+// the IP filter are formed with, and that an empty allowlist restores the
+// random selection.
+func TestAllowlistFormation(t *testing.T) {
+	c, _ := newTestContractor(t)
+	setTestSynced(c)
+	rpk := testKey(1)
+	renter := testRenter(c, rpk)
+	hdb := newTestHostDB(c)
+	for i := 0; i < 20; i++ {
+		hdb.addHost(testHost(byte(10 + i), fmt.Sprintf("host%v.example.com:9982", i)), 100)
+	}
+	// The same address as host 10 and as host 11.
+	hdb.addHost(testHost(40, "host0.example.com:9983"), 100)
+	hdb.addHost(testHost(41, "host1.example.com:9983"), 100)
+	testContract(t, c, rpk, testKey(11), 1, 0, 1000, types.SiacoinPrecision)
+
+	allowlist := []types.SiaPublicKey{testKey(12), testKey(10), testKey(40), testKey(41), testKey(15), testKey(12)}
+	if err := c.SetHostAllowlist(rpk, allowlist); err != nil {
+		t.Fatal(err)
+	}
+	fp, err := c.managedFormationPlan(context.Background(), renter, nil, 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []types.SiaPublicKey{testKey(12), testKey(10), testKey(15)}
+	if len(fp.hosts) != len(expected) {
+		t.Fatalf("expected %v hosts, got %v", len(expected), len(fp.hosts))
+	}
+	for i, hpk := range expected {
+		if !fp.hosts[i].PublicKey.Equals(hpk) {
+			t.Fatalf("expected host %v at position %v, got %v", hpk, i, fp.hosts[i].PublicKey)
+		}
+	}
+
+	// An empty allowlist uses the hostdb selection.
+	if err := c.SetHostAllowlist(rpk, nil); err != nil {
+		t.Fatal(err)
+	}
+	if list, err := c.HostAllowlist(rpk); err != nil || len(list) != 0 {
+		t.Fatal("allowlist not cleared:", list, err)
+	}
+	fp, err = c.managedFormationPlan(context.Background(), renter, nil, 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fp.hosts) <= len(expected) {
+		t.Fatalf("expected the random selection, got %v hosts", len(fp.hosts))
+	}
+	for _, host := range fp.hosts {
+		if host.PublicKey.Equals(testKey(11)) {
+			t.Fatal("host with an existing contract selected")
+		}
+	}
+}
+
+// TestSetHostAllowlistTransaction tests that the allowlist is replaced in
+// a single transaction, and that a failure keeps the previous list.
+func TestSetHostAllowlistTransaction(t *testing.T) {
+	c, fake := newTestContractor(t)
+	rpk := testKey(1)
+	testRenter(c, rpk)
+
+	if err := c.SetHostAllowlist(rpk, []types.SiaPublicKey{testKey(10), testKey(11)}); err != nil {
+		t.Fatal(err)
+	}
+	var queries []string
+	for _, s := range fake.Execs() {
+		queries = append(queries, strings.Fields(s.Query)[0])
+	}
+	if strings.Join(queries, " ") != "BEGIN DELETE INSERT INSERT COMMIT" {
+		t.Fatal("unexpected statements:", queries)
+	}
+
+	// Fail the second insert.
+	inserts := 0
+	fake.OnExec(func(query string, _ []driver.Value) error {
+		if strings.Contains(query, "INSERT INTO renter_host_allowlists") {
+			inserts++
+			if inserts == 2 {
+				return errors.New("insert failed")
+			}
+		}
+		return nil
+	})
+	if err := c.SetHostAllowlist(rpk, []types.SiaPublicKey{testKey(12), testKey(13)}); err == nil {
+		t.Fatal("expected an error")
+	}
+	if len(fake.ExecsLike("ROLLBACK")) != 1 || len(fake.ExecsLike("COMMIT")) != 1 {
+		t.Fatal("expected the transaction to be rolled back")
+	}
+	list, err := c.HostAllowlist(rpk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 || !list[0].Equals(testKey(10)) || !list[1].Equals(testKey(11)) {
+		t.Fatal("previous allowlist not kept:", list)
+	}
+}
@@ -0,0 +1,126 @@
+package contractor
+
+import (
+	"sync"
+	"time"
+
+	smodules "go.sia.tech/siad/modules"
+)
+
+// Circuit breaker states.
+const (
+	breakerClosed = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+// hostDBBreaker is a circuit breaker around the hostdb calls made during
+// contract maintenance. After a number of consecutive failures it opens and
+// makes the hostdb-dependent phases be skipped for a cooldown period. After
+// the cooldown, it lets a single probe through, and the other callers are
+// refused until the probe reports back. A success closes the breaker
+// again, a failure reopens it. If the probe doesn't report back within
+// another cooldown, a new probe is let through.
+type hostDBBreaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	state    int
+	failures int
+	openedAt time.Time
+	probedAt time.Time
+	mu       sync.Mutex
+}
+
+// newHostDBBreaker returns a closed breaker.
+func newHostDBBreaker(threshold int, cooldown time.Duration) *hostDBBreaker {
+	return &hostDBBreaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+	}
+}
+
+// setLimits changes the failure threshold and the cooldown of the breaker.
+// They apply to the next failure and the next check, respectively.
+func (b *hostDBBreaker) setLimits(threshold int, cooldown time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.threshold = threshold
+	b.cooldown = cooldown
+}
+
+// allow reports whether a hostdb call may be attempted.
+func (b *hostDBBreaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case breakerOpen:
+		if time.Since(b.openedAt) < b.cooldown {
+			return false
+		}
+		b.state = breakerHalfOpen
+		b.probedAt = time.Now()
+		return true
+	case breakerHalfOpen:
+		if time.Since(b.probedAt) < b.cooldown {
+			return false
+		}
+		b.probedAt = time.Now()
+		return true
+	default:
+		return true
+	}
+}
+
+// success records a successful hostdb call. It returns true if the breaker
+// was closed by this call.
+func (b *hostDBBreaker) success() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures = 0
+	if b.state == breakerClosed {
+		return false
+	}
+	b.state = breakerClosed
+	return true
+}
+
+// failure records a failed hostdb call. It returns true if the breaker was
+// opened by this call.
+func (b *hostDBBreaker) failure() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	if b.state == breakerHalfOpen || (b.state == breakerClosed && b.failures >= b.threshold) {
+		b.state = breakerOpen
+		b.openedAt = time.Now()
+		return true
+	}
+	return false
+}
+
+// managedHostDBAllowed reports whether the hostdb-dependent maintenance
+// phases may run.
+func (c *Contractor) managedHostDBAllowed() bool {
+	if c.staticHostDBBreaker.allow() {
+		return true
+	}
+	c.log.Println("WARN: skipping hostdb-dependent maintenance while the hostdb is unavailable")
+	return false
+}
+
+// managedHostDBResult updates the breaker with the outcome of a hostdb call
+// and (un)registers the alert when the breaker changes its state.
+func (c *Contractor) managedHostDBResult(err error) {
+	if err == nil {
+		if c.staticHostDBBreaker.success() {
+			c.log.Println("INFO: hostdb recovered, resuming maintenance")
+			c.staticAlerter.UnregisterAlert(AlertIDHostDBUnavailable)
+		}
+		return
+	}
+	if c.staticHostDBBreaker.failure() {
+		c.log.Println("WARN: too many hostdb failures, pausing hostdb-dependent maintenance:", err)
+		c.staticAlerter.RegisterAlert(AlertIDHostDBUnavailable, AlertMSGHostDBUnavailable, err.Error(), smodules.SeverityWarning)
+	}
+}
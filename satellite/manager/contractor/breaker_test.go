@@ -0,0 +1,84 @@
+package contractor
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestHostDBBreaker tests the transitions of the breaker.
+func TestHostDBBreaker(t *testing.T) {
+	b := newHostDBBreaker(2, 50 * time.Millisecond)
+	if !b.allow() {
+		t.Fatal("closed breaker refused a call")
+	}
+	if b.failure() {
+		t.Fatal("breaker opened below the threshold")
+	}
+	if !b.failure() {
+		t.Fatal("breaker didn't open at the threshold")
+	}
+	if b.allow() {
+		t.Fatal("open breaker allowed a call during the cooldown")
+	}
+
+	// After the cooldown, a single probe is let through.
+	time.Sleep(60 * time.Millisecond)
+	if !b.allow() {
+		t.Fatal("no probe allowed after the cooldown")
+	}
+	if b.allow() {
+		t.Fatal("second call allowed while the probe is in flight")
+	}
+
+	// A failed probe reopens the breaker.
+	if !b.failure() {
+		t.Fatal("failed probe didn't reopen the breaker")
+	}
+	if b.allow() {
+		t.Fatal("reopened breaker allowed a call")
+	}
+
+	// A successful probe closes it.
+	time.Sleep(60 * time.Millisecond)
+	if !b.allow() {
+		t.Fatal("no probe allowed after the cooldown")
+	}
+	if !b.success() {
+		t.Fatal("successful probe didn't close the breaker")
+	}
+	if !b.allow() || !b.allow() {
+		t.Fatal("closed breaker refused a call")
+	}
+}
+
+// TestHostDBBreakerSingleProbe tests that only one of the concurrent
+// callers is let through when the breaker is half-open.
+func TestHostDBBreakerSingleProbe(t *testing.T) {
+	b := newHostDBBreaker(1, 20 * time.Millisecond)
+	b.failure()
+	time.Sleep(30 * time.Millisecond)
+
+	var allowed int32
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if b.allow() {
+				atomic.AddInt32(&allowed, 1)
+			}
+		}()
+	}
+	wg.Wait()
+	if allowed != 1 {
+		t.Fatalf("expected a single probe, got %v", allowed)
+	}
+
+	// A probe that never reports back is replaced after the cooldown.
+	time.Sleep(30 * time.Millisecond)
+	if !b.allow() {
+		t.Fatal("stale probe not replaced")
+	}
+}
@@ -0,0 +1,49 @@
+package contractor
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/mike76-dev/sia-satellite/modules"
+
+	smodules "go.sia.tech/siad/modules"
+	"go.sia.tech/siad/types"
+)
+
+// TestCancelFormation tests that canceling the context mid-formation stops
+// further formations, and that the contracts formed so far are kept.
+func TestCancelFormation(t *testing.T) {
+	c, _ := newTestContractor(t)
+	setTestSynced(c)
+	c.wallet = &testWallet{unlocked: true, balance: types.SiacoinPrecision.Mul64(1e4)}
+	newTestFundLocker(c)
+	rpk := testKey(1)
+	testRenter(c, rpk)
+	hdb := newTestHostDB(c)
+	for i := 0; i < 10; i++ {
+		hdb.addHost(testHost(byte(10 + i), fmt.Sprintf("host%v.example.com:9982", i)), 100)
+	}
+
+	// The renter cancels the formation after the second contract.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var attempts byte
+	form := func(rpk types.SiaPublicKey, host smodules.HostDBEntry, funds types.Currency, endHeight types.BlockHeight) (types.Currency, modules.RenterContract, error) {
+		attempts++
+		if attempts == 2 {
+			cancel()
+		}
+		return funds, testContract(t, c, rpk, host.PublicKey, attempts, 0, endHeight, funds), nil
+	}
+	result, err := c.managedFormContracts(ctx, rpk, nil, form)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attempts != 2 {
+		t.Fatalf("expected 2 formations, got %v", attempts)
+	}
+	if len(result.Contracts) != 2 {
+		t.Fatalf("expected 2 contracts to be kept, got %v", len(result.Contracts))
+	}
+}
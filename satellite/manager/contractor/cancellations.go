@@ -0,0 +1,65 @@
+package contractor
+
+import (
+	"go.sia.tech/siad/types"
+)
+
+// managedRecordCancellation records the current height as the height at
+// which the contract was canceled.
+func (c *Contractor) managedRecordCancellation(id types.FileContractID) {
+	c.mu.RLock()
+	height := c.blockHeight
+	c.mu.RUnlock()
+	_, err := c.execWithRetry(`
+		INSERT INTO contract_cancellations (contract_id, height)
+		VALUES (?, ?)
+		ON DUPLICATE KEY UPDATE height = VALUES(height)
+	`, id.String(), uint64(height))
+	if err != nil {
+		c.log.Println("ERROR: couldn't record the contract cancellation:", err)
+	}
+	c.mu.Lock()
+	c.canceledAt[id] = height
+	c.mu.Unlock()
+}
+
+// managedClearCancellation removes the cancellation record of the contract.
+func (c *Contractor) managedClearCancellation(id types.FileContractID) {
+	c.mu.Lock()
+	_, exists := c.canceledAt[id]
+	delete(c.canceledAt, id)
+	c.mu.Unlock()
+	if !exists {
+		return
+	}
+	if _, err := c.execWithRetry("DELETE FROM contract_cancellations WHERE contract_id = ?", id.String()); err != nil {
+		c.log.Println("ERROR: couldn't delete the contract cancellation:", err)
+	}
+}
+
+// loadCancellations loads the heights at which the contracts were
+// canceled.
+func (c *Contractor) loadCancellations() error {
+	rows, err := c.db.Query("SELECT contract_id, height FROM contract_cancellations")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var id string
+	var height uint64
+	var fcid types.FileContractID
+	for rows.Next() {
+		if err := rows.Scan(&id, &height); err != nil {
+			c.log.Println("Error scanning database row:", err)
+			continue
+		}
+		if err := fcid.LoadString(id); err != nil {
+			c.log.Println("ERROR: wrong contract ID:", err)
+			continue
+		}
+		c.canceledAt[fcid] = types.BlockHeight(height)
+	}
+
+	return rows.Err()
+}
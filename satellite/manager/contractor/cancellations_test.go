@@ -0,0 +1,63 @@
+package contractor
+
+import (
+	"testing"
+
+	smodules "go.sia.tech/siad/modules"
+	"go.sia.tech/siad/types"
+)
+
+// TestArchiveCanceledContracts tests that the canceled contracts are
+// archived once the retention period has passed since the cancellation,
+// and that the contracts locked by an allowance cancel are kept.
+func TestArchiveCanceledContracts(t *testing.T) {
+	c, fake := newTestContractor(t)
+	rpk := testKey(1)
+	testRenter(c, rpk)
+	funds := types.SiacoinPrecision.Mul64(100)
+	canceled := testContract(t, c, rpk, testKey(2), 1, 0, 5000, funds)
+	locked := testContract(t, c, rpk, testKey(3), 2, 0, 5000, funds)
+	setTestUtility(t, c, canceled.ID, smodules.ContractUtility{GoodForUpload: true, GoodForRenew: true})
+	setTestUtility(t, c, locked.ID, smodules.ContractUtility{Locked: true})
+
+	// Cancel the contract well after it was formed.
+	c.mu.Lock()
+	c.blockHeight = 2000
+	c.mu.Unlock()
+	if err := c.managedCancelContract(canceled.ID); err != nil {
+		t.Fatal(err)
+	}
+	if len(fake.ExecsLike("INSERT INTO contract_cancellations")) != 1 {
+		t.Fatal("expected the cancellation to be recorded")
+	}
+
+	// Just before the retention period passes, nothing is archived.
+	c.mu.Lock()
+	c.blockHeight = 2000 + c.canceledContractRetention - 1
+	c.mu.Unlock()
+	c.managedArchiveContracts()
+	if _, ok := c.staticContracts.View(canceled.ID); !ok {
+		t.Fatal("canceled contract archived too early")
+	}
+
+	// Once it has passed, only the canceled contract is archived.
+	c.mu.Lock()
+	c.blockHeight = 2000 + c.canceledContractRetention
+	c.mu.Unlock()
+	c.managedArchiveContracts()
+	if _, ok := c.staticContracts.View(canceled.ID); ok {
+		t.Fatal("canceled contract not archived")
+	}
+	if _, ok := c.staticContracts.View(locked.ID); !ok {
+		t.Fatal("locked contract archived")
+	}
+	c.mu.RLock()
+	_, exists := c.canceledAt[canceled.ID]
+	c.mu.RUnlock()
+	if exists {
+		t.Fatal("cancellation not cleared")
+	}
+	if len(fake.ExecsLike("DELETE FROM contract_cancellations")) != 1 {
+		t.Fatal("expected the cancellation to be deleted")
+	}
+}
@@ -0,0 +1,75 @@
+package contractor
+
+import (
+	"sort"
+
+	smodules "go.sia.tech/siad/modules"
+	"go.sia.tech/siad/types"
+)
+
+// SetPreferCollateral sets whether the contract formation on behalf of the
+// renter prefers the hosts offering more collateral.
+func (c *Contractor) SetPreferCollateral(rpk types.SiaPublicKey, prefer bool) error {
+	c.mu.Lock()
+	renter, exists := c.renters[rpk.String()]
+	if !exists {
+		c.mu.Unlock()
+		return ErrRenterNotFound
+	}
+	renter.PreferCollateral = prefer
+	c.renters[rpk.String()] = renter
+	c.mu.Unlock()
+	return c.UpdateRenter(renter)
+}
+
+// offeredCollateral returns the collateral the host offers, capped at
+// the collateral ceiling the same way managedNewContract does.
+func offeredCollateral(host smodules.HostDBEntry, ceiling types.Currency) types.Currency {
+	if host.MaxCollateral.Cmp(ceiling) > 0 {
+		return ceiling
+	}
+	return host.MaxCollateral
+}
+
+// managedPreferCollateral reorders the candidate hosts, so that the hosts
+// offering more collateral come first when their scores are within
+// CollateralScoreTolerance of the best remaining host.
+func (c *Contractor) managedPreferCollateral(hosts []smodules.HostDBEntry) []smodules.HostDBEntry {
+	type candidate struct {
+		host       smodules.HostDBEntry
+		collateral types.Currency
+		score      types.Currency
+	}
+	candidates := make([]candidate, 0, len(hosts))
+	_, ceiling := c.managedPriceCeilings()
+	for _, host := range hosts {
+		sb, err := c.managedScoreBreakdown(host)
+		if err != nil {
+			continue
+		}
+		candidates = append(candidates, candidate{
+			host:       host,
+			collateral: offeredCollateral(host, ceiling),
+			score:      sb.Score,
+		})
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].score.Cmp(candidates[j].score) > 0
+	})
+
+	preferred := make([]smodules.HostDBEntry, 0, len(candidates))
+	for len(candidates) > 0 {
+		// Among the hosts with a score close to the best one, pick the one
+		// offering the most collateral.
+		threshold := candidates[0].score.MulFloat(1 - CollateralScoreTolerance)
+		best := 0
+		for i := 1; i < len(candidates) && candidates[i].score.Cmp(threshold) >= 0; i++ {
+			if candidates[i].collateral.Cmp(candidates[best].collateral) > 0 {
+				best = i
+			}
+		}
+		preferred = append(preferred, candidates[best].host)
+		candidates = append(candidates[:best], candidates[best+1:]...)
+	}
+	return preferred
+}
@@ -0,0 +1,70 @@
+package contractor
+
+import (
+	"context"
+	"testing"
+
+	smodules "go.sia.tech/siad/modules"
+	"go.sia.tech/siad/types"
+)
+
+// TestPreferCollateral tests that with the preference enabled, a host
+// offering more collateral is chosen over an equally scored host offering
+// less, but not over a much better scored one.
+func TestPreferCollateral(t *testing.T) {
+	c, _ := newTestContractor(t)
+	setTestSynced(c)
+	rpk := testKey(1)
+	testRenter(c, rpk)
+	hdb := newTestHostDB(c)
+	low := testHost(10, "low.example.com:9982")
+	low.MaxCollateral = types.SiacoinPrecision
+	high := testHost(11, "high.example.com:9982")
+	high.MaxCollateral = types.SiacoinPrecision.Mul64(10)
+	best := testHost(12, "best.example.com:9982")
+	best.MaxCollateral = types.ZeroCurrency
+	hdb.addHost(best, 200)
+	hdb.addHost(low, 100)
+	hdb.addHost(high, 100)
+
+	order := func() []types.SiaPublicKey {
+		t.Helper()
+		c.mu.RLock()
+		renter := c.renters[rpk.String()]
+		c.mu.RUnlock()
+		fp, err := c.managedFormationPlan(context.Background(), renter, nil, 0, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var pks []types.SiaPublicKey
+		for _, host := range fp.hosts {
+			pks = append(pks, host.PublicKey)
+		}
+		return pks
+	}
+	check := func(expected ...smodules.HostDBEntry) {
+		t.Helper()
+		pks := order()
+		if len(pks) != len(expected) {
+			t.Fatalf("expected %v hosts, got %v", len(expected), len(pks))
+		}
+		for i, host := range expected {
+			if !pks[i].Equals(host.PublicKey) {
+				t.Fatalf("expected %v at position %v, got %v", host.NetAddress, i, pks[i])
+			}
+		}
+	}
+
+	// The preference is off by default.
+	check(best, low, high)
+
+	if err := c.SetPreferCollateral(rpk, true); err != nil {
+		t.Fatal(err)
+	}
+	check(best, high, low)
+
+	if err := c.SetPreferCollateral(rpk, false); err != nil {
+		t.Fatal(err)
+	}
+	check(best, low, high)
+}
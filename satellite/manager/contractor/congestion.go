@@ -0,0 +1,36 @@
+package contractor
+
+import (
+	"fmt"
+
+	smodules "go.sia.tech/siad/modules"
+)
+
+// managedFormationLimit returns the number of contracts that may be formed
+// in this run, out of the needed ones. If the transaction pool holds more
+// transactions than the congestion threshold, the formations are limited
+// to CongestedFormationLimit, so that they don't worsen the confirmation
+// delays, and an informational alert is registered. The alert is
+// unregistered once the congestion clears, or the check is disabled.
+func (c *Contractor) managedFormationLimit(needed int) int {
+	c.mu.RLock()
+	threshold := c.tpoolCongestionThreshold
+	c.mu.RUnlock()
+	if threshold == 0 {
+		c.staticAlerter.UnregisterAlert(AlertIDTpoolCongested)
+		return needed
+	}
+	pending := len(c.tpool.TransactionList())
+	if pending <= threshold {
+		c.staticAlerter.UnregisterAlert(AlertIDTpoolCongested)
+		return needed
+	}
+
+	cause := fmt.Sprintf("%v transactions in the pool, threshold %v", pending, threshold)
+	c.staticAlerter.RegisterAlert(AlertIDTpoolCongested, AlertMSGTpoolCongested, cause, smodules.SeverityInfo)
+	if needed > CongestedFormationLimit {
+		c.log.Printf("INFO: transaction pool congested (%v), forming %v of %v needed contracts\n", cause, CongestedFormationLimit, needed)
+		return CongestedFormationLimit
+	}
+	return needed
+}
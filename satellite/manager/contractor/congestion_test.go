@@ -0,0 +1,89 @@
+package contractor
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/mike76-dev/sia-satellite/modules"
+
+	smodules "go.sia.tech/siad/modules"
+	"go.sia.tech/siad/types"
+)
+
+// TestFormationCongestion tests that the formation is throttled while the
+// transaction pool is congested, and that it resumes once the congestion
+// clears.
+func TestFormationCongestion(t *testing.T) {
+	c, _ := newTestContractor(t)
+	setTestSynced(c)
+	c.wallet = &testWallet{unlocked: true, balance: types.SiacoinPrecision.Mul64(1e4)}
+	newTestFundLocker(c)
+	rpk := testKey(1)
+	testRenter(c, rpk)
+	hdb := newTestHostDB(c)
+	for i := 0; i < 20; i++ {
+		hdb.addHost(testHost(byte(10 + i), fmt.Sprintf("host%v.example.com:9982", i)), 100)
+	}
+
+	var id byte
+	form := func(rpk types.SiaPublicKey, host smodules.HostDBEntry, funds types.Currency, endHeight types.BlockHeight) (types.Currency, modules.RenterContract, error) {
+		id++
+		return funds, testContract(t, c, rpk, host.PublicKey, id, 0, endHeight, funds), nil
+	}
+	run := func(pending int) int {
+		t.Helper()
+		c.mu.Lock()
+		c.tpool = testTpool{pending: pending}
+		c.mu.Unlock()
+		formed := id
+		if _, err := c.managedFormContracts(context.Background(), rpk, nil, form); err != nil {
+			t.Fatal(err)
+		}
+		return int(id - formed)
+	}
+
+	// Without the formations, the congestion defers all of them.
+	threshold := 100
+	if err := setTestSettings(c, func(s *modules.ContractorSettings) { s.TpoolCongestionThreshold = threshold }); err != nil {
+		t.Fatal(err)
+	}
+	limit := CongestedFormationLimit
+	defer func() { CongestedFormationLimit = limit }()
+	CongestedFormationLimit = 0
+	n := run(threshold + 1)
+	CongestedFormationLimit = limit
+	if n != 0 {
+		t.Fatalf("expected the formations to be deferred, got %v", n)
+	}
+
+	// The congested pool limits the formations.
+	if n := run(threshold + 1); n != CongestedFormationLimit {
+		t.Fatalf("expected %v contracts while congested, got %v", CongestedFormationLimit, n)
+	}
+	if !hasAlert(c, AlertMSGTpoolCongested) {
+		t.Fatal("expected the congestion alert")
+	}
+
+	// Disabling the check clears the alert.
+	if err := setTestSettings(c, func(s *modules.ContractorSettings) { s.TpoolCongestionThreshold = 0 }); err != nil {
+		t.Fatal(err)
+	}
+	if n := c.managedFormationLimit(10); n != 10 {
+		t.Fatalf("expected no limit with the check disabled, got %v", n)
+	}
+	if hasAlert(c, AlertMSGTpoolCongested) {
+		t.Fatal("expected the congestion alert to be cleared")
+	}
+
+	// Once the congestion clears, the remaining contracts are formed.
+	if err := setTestSettings(c, func(s *modules.ContractorSettings) { s.TpoolCongestionThreshold = threshold }); err != nil {
+		t.Fatal(err)
+	}
+	if n := run(threshold); n != 10 - CongestedFormationLimit {
+		t.Fatalf("expected %v contracts after the congestion, got %v", 10 - CongestedFormationLimit, n)
+	}
+	if hasAlert(c, AlertMSGTpoolCongested) {
+		t.Fatal("expected the congestion alert to be cleared")
+	}
+}
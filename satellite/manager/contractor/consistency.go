@@ -0,0 +1,164 @@
+package contractor
+
+import (
+	"github.com/mike76-dev/sia-satellite/modules"
+
+	"gitlab.com/NebulousLabs/errors"
+)
+
+// renterMismatches returns the names of the fields that differ between
+// the two renter records.
+func renterMismatches(mem, db modules.Renter) (fields []string) {
+	a, b := mem.Allowance, db.Allowance
+	if mem.Email != db.Email {
+		fields = append(fields, "email")
+	}
+	if mem.Suffix != db.Suffix {
+		fields = append(fields, "suffix")
+	}
+	if mem.AllowRedundantIPs != db.AllowRedundantIPs {
+		fields = append(fields, "allowredundantips")
+	}
+	if mem.MaxStorageBytes != db.MaxStorageBytes {
+		fields = append(fields, "maxstoragebytes")
+	}
+	if mem.MaxContractsPerRegion != db.MaxContractsPerRegion {
+		fields = append(fields, "maxcontractsperregion")
+	}
+	if mem.Paused != db.Paused {
+		fields = append(fields, "paused")
+	}
+	if mem.PreferCollateral != db.PreferCollateral {
+		fields = append(fields, "prefercollateral")
+	}
+	if mem.SpendRateRefresh != db.SpendRateRefresh {
+		fields = append(fields, "spendraterefresh")
+	}
+	if mem.MaxHostLatency != db.MaxHostLatency {
+		fields = append(fields, "maxhostlatency")
+	}
+	if mem.LocalRegion != db.LocalRegion || mem.LocalFraction != db.LocalFraction {
+		fields = append(fields, "localregion")
+	}
+	if mem.ReuseRefundAddress != db.ReuseRefundAddress {
+		fields = append(fields, "reuserefundaddress")
+	}
+	if mem.CurrentPeriod != db.CurrentPeriod {
+		fields = append(fields, "currentperiod")
+	}
+	if !a.Funds.Equals(b.Funds) {
+		fields = append(fields, "funds")
+	}
+	if a.Hosts != b.Hosts {
+		fields = append(fields, "hosts")
+	}
+	if a.Period != b.Period {
+		fields = append(fields, "period")
+	}
+	if a.RenewWindow != b.RenewWindow {
+		fields = append(fields, "renewwindow")
+	}
+	if a.ExpectedStorage != b.ExpectedStorage {
+		fields = append(fields, "expectedstorage")
+	}
+	if a.ExpectedUpload != b.ExpectedUpload {
+		fields = append(fields, "expectedupload")
+	}
+	if a.ExpectedDownload != b.ExpectedDownload {
+		fields = append(fields, "expecteddownload")
+	}
+	if a.ExpectedRedundancy != b.ExpectedRedundancy {
+		fields = append(fields, "expectedredundancy")
+	}
+	if !a.MaxRPCPrice.Equals(b.MaxRPCPrice) {
+		fields = append(fields, "maxrpcprice")
+	}
+	if !a.MaxContractPrice.Equals(b.MaxContractPrice) {
+		fields = append(fields, "maxcontractprice")
+	}
+	if !a.MaxDownloadBandwidthPrice.Equals(b.MaxDownloadBandwidthPrice) {
+		fields = append(fields, "maxdownloadbandwidthprice")
+	}
+	if !a.MaxSectorAccessPrice.Equals(b.MaxSectorAccessPrice) {
+		fields = append(fields, "maxsectoraccessprice")
+	}
+	if !a.MaxStoragePrice.Equals(b.MaxStoragePrice) {
+		fields = append(fields, "maxstorageprice")
+	}
+	if !a.MaxUploadBandwidthPrice.Equals(b.MaxUploadBandwidthPrice) {
+		fields = append(fields, "maxuploadbandwidthprice")
+	}
+	return
+}
+
+// CheckRenterConsistency compares the renter records in the database with
+// the in-memory renters map and returns the differences found. If repair
+// is true, the database is treated as the source of truth and the map is
+// updated accordingly.
+func (c *Contractor) CheckRenterConsistency(repair bool) ([]modules.RenterInconsistency, error) {
+	if err := c.tg.Add(); err != nil {
+		return nil, err
+	}
+	defer c.tg.Done()
+
+	// Hold the lock across both reads, so that an update in between isn't
+	// reported as a difference.
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	dbRenters, err := c.loadRenters()
+	if err != nil {
+		return nil, errors.AddContext(err, "could not load the renters")
+	}
+
+	var inconsistencies []modules.RenterInconsistency
+	for key, dbRenter := range dbRenters {
+		renter, exists := c.renters[key]
+		if !exists {
+			inconsistencies = append(inconsistencies, modules.RenterInconsistency{
+				PublicKey:       dbRenter.PublicKey,
+				Email:           dbRenter.Email,
+				MissingInMemory: true,
+			})
+		} else if fields := renterMismatches(renter, dbRenter); len(fields) > 0 {
+			inconsistencies = append(inconsistencies, modules.RenterInconsistency{
+				PublicKey: dbRenter.PublicKey,
+				Email:     dbRenter.Email,
+				Fields:    fields,
+			})
+		} else {
+			continue
+		}
+		if repair {
+			c.renters[key] = dbRenter
+		}
+	}
+	for key, renter := range c.renters {
+		if _, exists := dbRenters[key]; exists {
+			continue
+		}
+		inconsistencies = append(inconsistencies, modules.RenterInconsistency{
+			PublicKey:         renter.PublicKey,
+			Email:             renter.Email,
+			MissingInDatabase: true,
+		})
+		if repair {
+			delete(c.renters, key)
+		}
+	}
+
+	return inconsistencies, nil
+}
+
+// managedLogRenterConsistency runs the consistency check without repairing
+// and logs the differences found.
+func (c *Contractor) managedLogRenterConsistency() {
+	inconsistencies, err := c.CheckRenterConsistency(false)
+	if err != nil {
+		c.log.Println("ERROR: could not check renter consistency:", err)
+		return
+	}
+	for _, ri := range inconsistencies {
+		c.log.Printf("WARN: renter %v (%v) is inconsistent: fields %v, missing in memory: %v, missing in database: %v\n", ri.PublicKey.String(), ri.Email, ri.Fields, ri.MissingInMemory, ri.MissingInDatabase)
+	}
+}
@@ -0,0 +1,83 @@
+package contractor
+
+import (
+	"testing"
+)
+
+// TestCheckRenterConsistency tests that a divergence between the database
+// and the memory is detected, and that the repair makes the memory match
+// the database.
+func TestCheckRenterConsistency(t *testing.T) {
+	c, fake := newTestContractor(t)
+	renter := testRenter(c, testKey(1))
+	stored := renter
+	stored.Allowance.Hosts = 20
+	stored.Paused = true
+	serveRenters(fake, stored)
+
+	inconsistencies, err := c.CheckRenterConsistency(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(inconsistencies) != 1 {
+		t.Fatalf("expected one inconsistency, got %v", len(inconsistencies))
+	}
+	fields := inconsistencies[0].Fields
+	if len(fields) != 2 || fields[0] != "paused" || fields[1] != "hosts" {
+		t.Fatal("wrong fields reported:", fields)
+	}
+	if c.renters[renter.PublicKey.String()].Allowance.Hosts != 10 {
+		t.Fatal("the memory was changed without a repair")
+	}
+
+	// Repair the memory.
+	if _, err := c.CheckRenterConsistency(true); err != nil {
+		t.Fatal(err)
+	}
+	if c.renters[renter.PublicKey.String()].Allowance.Hosts != 20 || !c.renters[renter.PublicKey.String()].Paused {
+		t.Fatal("the memory wasn't repaired")
+	}
+	inconsistencies, err = c.CheckRenterConsistency(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(inconsistencies) != 0 {
+		t.Fatal("inconsistencies left after the repair:", inconsistencies)
+	}
+}
+
+// TestCheckRenterConsistencyMissing tests that the renters missing on
+// either side are reported.
+func TestCheckRenterConsistencyMissing(t *testing.T) {
+	c, fake := newTestContractor(t)
+	inMemory := testRenter(c, testKey(1))
+	inDatabase := inMemory
+	inDatabase.PublicKey = testKey(2)
+	serveRenters(fake, inDatabase)
+
+	inconsistencies, err := c.CheckRenterConsistency(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(inconsistencies) != 2 {
+		t.Fatalf("expected two inconsistencies, got %v", len(inconsistencies))
+	}
+	for _, ri := range inconsistencies {
+		switch ri.PublicKey.String() {
+		case inMemory.PublicKey.String():
+			if !ri.MissingInDatabase {
+				t.Fatal("renter missing in the database not reported")
+			}
+		case inDatabase.PublicKey.String():
+			if !ri.MissingInMemory {
+				t.Fatal("renter missing in memory not reported")
+			}
+		}
+	}
+	if _, exists := c.renters[inMemory.PublicKey.String()]; exists {
+		t.Fatal("renter missing in the database not removed")
+	}
+	if _, exists := c.renters[inDatabase.PublicKey.String()]; !exists {
+		t.Fatal("renter missing in memory not added")
+	}
+}
@@ -1,6 +1,8 @@
 package contractor
 
 import (
+	"time"
+
 	"go.sia.tech/siad/modules"
 	"go.sia.tech/siad/types"
 )
@@ -19,11 +21,104 @@ var (
 	// AlertMSGFailedContractRenewal indicates that the contract renewal failed.
 	AlertMSGFailedContractRenewal = "Contractor is attempting to renew/refresh contracts but failed"
 
+	// AlertMSGHostDBUnavailable indicates that the hostdb-dependent phases
+	// of contract maintenance are paused because of repeated hostdb failures.
+	AlertMSGHostDBUnavailable = "Contract maintenance is paused due to repeated hostdb failures"
+
+	// AlertMSGRenewWindowTooLong indicates that a contract formation was
+	// rejected because the renter's renew window is not shorter than the
+	// period, so the contracts couldn't be renewed cleanly.
+	AlertMSGRenewWindowTooLong = "Contract formation rejected due to the renter's renew window not being shorter than the period"
+
+	// AlertMSGUnderProvisioned indicates that a contract formation left
+	// the renter with too few contracts.
+	AlertMSGUnderProvisioned = "Contract formation left the renter with too few contracts"
+
+	// AlertMSGNotEnoughHosts indicates that the hostdb returned fewer
+	// candidate hosts than the contracts needed.
+	AlertMSGNotEnoughHosts = "The network doesn't have enough hosts to form the needed contracts"
+
+	// AlertMSGStorageQuotaExceeded indicates that a contract formation or
+	// renewal was rejected because the renter's allowance exceeds their
+	// storage quota.
+	AlertMSGStorageQuotaExceeded = "Contract formation/renewal rejected due to the renter's storage quota"
+
+	// AlertMSGTpoolCongested indicates that the contract formations are
+	// throttled because the transaction pool is congested.
+	AlertMSGTpoolCongested = "Contract formation is throttled due to a congested transaction pool"
+
 	// AlertMSGWalletLockedDuringMaintenance indicates that forming/renewing a
 	// contract during contract maintenance isn't possible due to a locked wallet.
 	AlertMSGWalletLockedDuringMaintenance = "At least one contract failed to form/renew due to the wallet being locked"
 )
 
+// AlertIDHostDBUnavailable is the ID of the alert registered when the
+// hostdb circuit breaker opens.
+const AlertIDHostDBUnavailable = modules.AlertID("contractor-hostdb-unavailable")
+
+// AlertIDWalletLocked is the ID of the alert registered when a contract
+// formation or renewal finds the wallet locked.
+const AlertIDWalletLocked = modules.AlertID("contractor-wallet-locked")
+
+// AlertIDTpoolCongested is the ID of the alert registered when the
+// contract formations are throttled because of a congested transaction
+// pool.
+const AlertIDTpoolCongested = modules.AlertID("contractor-tpool-congested")
+
+// Constants related to the transaction pool congestion.
+var (
+	// defaultTpoolCongestionThreshold is the default number of
+	// transactions in the transaction pool above which the pool is
+	// considered congested. See SetContractorSettings.
+	defaultTpoolCongestionThreshold = 1000
+
+	// CongestedFormationLimit is the maximum number of contracts formed
+	// per run while the transaction pool is congested. Zero defers the
+	// formations until the congestion clears.
+	CongestedFormationLimit = 5
+)
+
+// defaultMinProvisionedFraction is the default share of the allowance
+// hosts that a renter needs GoodForUpload contracts with after a contract
+// formation. See SetContractorSettings.
+var defaultMinProvisionedFraction = 0.5
+
+// WalletLockedRetryInterval is how long the contractor waits before running
+// an operation again after it failed because of a locked wallet.
+var WalletLockedRetryInterval = time.Minute
+
+// WalletLockedMaxRetries is how many times an operation that failed because
+// of a locked wallet is retried.
+var WalletLockedMaxRetries = 5
+
+// defaultSyncGraceChanges is the default number of consecutive synced
+// consensus changes required after startup before the first contract
+// maintenance runs. See SetContractorSettings.
+var defaultSyncGraceChanges = 3
+
+// Constants related to the database writes.
+var (
+	// DBWriteRetries is the number of times a database write is retried
+	// after a transient error, such as a lock wait timeout or a deadlock.
+	DBWriteRetries = 3
+
+	// DBWriteRetryBackoff is the pause before the first retry of a
+	// database write. It doubles with every further retry.
+	DBWriteRetryBackoff = 100 * time.Millisecond
+)
+
+// Constants related to the hostdb circuit breaker.
+var (
+	// defaultHostDBBreakerThreshold is the default number of consecutive
+	// hostdb failures after which the hostdb-dependent maintenance phases
+	// are paused. See SetContractorSettings.
+	defaultHostDBBreakerThreshold = 5
+
+	// defaultHostDBBreakerCooldown is the default time the hostdb-dependent
+	// maintenance phases stay paused before another attempt is made.
+	defaultHostDBBreakerCooldown = 30 * time.Minute
+)
+
 // Constants related to contract formation parameters.
 var (
 	// ContractFeeFundingMulFactor is the multiplying factor for contract fees
@@ -38,6 +133,77 @@ var (
 	// determining the maximum amount of funds to put into a new contract.
 	MaxInitialContractFundingMulFactor = uint64(2)
 
+	// defaultGFUStoredDataWeight is the default weight of the stored data,
+	// relative to the host score, when deciding which contracts stay
+	// GoodForUpload. See SetContractorSettings.
+	defaultGFUStoredDataWeight = float64(0.3)
+
+	// defaultGFUChurnCooldown is the default number of blocks a host marked
+	// !GoodForUpload by the GFU limiter stays out before it can be
+	// GoodForUpload again. See SetContractorSettings.
+	defaultGFUChurnCooldown = types.BlockHeight(144)
+
+	// defaultMaxConcurrentRenewals is the default maximum number of
+	// renewals, across all renters, that can run at the same time. See
+	// SetContractorSettings.
+	defaultMaxConcurrentRenewals = 4
+
+	// defaultCanceledContractRetention is the default number of blocks a
+	// canceled contract stays in the active contract set before it is
+	// archived. See SetContractorSettings.
+	defaultCanceledContractRetention = types.BlockHeight(1008) // ~1 week
+
+	// MinRefreshSpendHistory is the minimum age of a contract for its spend
+	// rate to be used for sizing the refresh. Younger contracts get their
+	// funding doubled instead.
+	MinRefreshSpendHistory = types.BlockHeight(144) // ~1 day
+
+	// RefreshSpendRateBuffer is the extra share of the projected spending
+	// added to the funding of a refreshed contract sized by the spend rate.
+	RefreshSpendRateBuffer = float64(0.2)
+
+	// defaultMinRefreshRemainingBlocks is the default minimum number of
+	// blocks left until the renew height for an out-of-funds contract to be
+	// refreshed. The contracts closer to their renew height are left to be
+	// renewed for expiry instead. See SetContractorSettings.
+	defaultMinRefreshRemainingBlocks = types.BlockHeight(144) // ~1 day
+
+	// SessionPoolTTL is the time a pooled session with a host is kept
+	// alive for reuse.
+	SessionPoolTTL = 2 * time.Minute
+
+	// HostSettingsCacheTTL is the time the settings fetched from a host
+	// are reused for the renewals of its other contracts.
+	HostSettingsCacheTTL = 30 * time.Second
+
+	// HostLatencyProbeTimeout is the longest time a host response time
+	// probe may take, regardless of the renter's latency bound.
+	HostLatencyProbeTimeout = 10 * time.Second
+
+	// HostLatencyProbeWorkers is the number of hosts probed for their
+	// response time at once.
+	HostLatencyProbeWorkers = 10
+
+	// HostLatencyMaxProbes is the maximum number of hosts probed for their
+	// response time during a contract formation. The hosts beyond it are
+	// skipped.
+	HostLatencyMaxProbes = 50
+
+	// defaultMaxRenewHistoryDepth is the default maximum number of the
+	// previous contracts followed when summing up the spending of a
+	// contract line. See SetContractorSettings.
+	defaultMaxRenewHistoryDepth = 10000
+
+	// RegionScoreTolerance is the relative score difference within which
+	// a host from a less used region is preferred over a better scoring
+	// one, if the renter has a region cap set.
+	RegionScoreTolerance = float64(0.1)
+
+	// CollateralScoreTolerance is the relative score difference within
+	// which a host offering more collateral is preferred over a better
+	// scoring one, if the renter prefers collateral.
+	CollateralScoreTolerance = float64(0.1)
+
 	// MinInitialContractFundingDivFactor is the dividing factor for determining
 	// the minimum amount of funds to put into a new contract.
 	MinInitialContractFundingDivFactor = uint64(20)
@@ -46,12 +212,12 @@ var (
 	// attempt to be renewed before it is marked as !goodForRenew.
 	consecutiveRenewalsBeforeReplacement = types.BlockHeight(12) // ~2h
 
-	// fileContractMinimumFunding is the lowest percentage of an allowace (on a
-	// per-contract basis) that is allowed to go into funding a contract. If the
-	// allowance is 100 SC per contract (5,000 SC total for 50 contracts, or
-	// 2,000 SC total for 20 contracts, etc.), then the minimum amount of funds
-	// that a contract would be allowed to have is fileContractMinimumFunding *
-	// 100SC.
+	// fileContractMinimumFunding is the default lowest percentage of an
+	// allowace (on a per-contract basis) that is allowed to go into funding a
+	// contract. If the allowance is 100 SC per contract (5,000 SC total for 50
+	// contracts, or 2,000 SC total for 20 contracts, etc.), then the minimum
+	// amount of funds that a contract would be allowed to have is
+	// fileContractMinimumFunding * 100SC. See SetContractorSettings.
 	fileContractMinimumFunding = float64(0.15)
 
 	// MinContractFundRenewalThreshold defines the ratio of remaining funds to
@@ -73,6 +239,11 @@ var (
 	// failure mode of 'can't retrieve stuff already uploaded'.
 	MinContractFundUploadThreshold = float64(0.05) // 5%
 
+	// defaultHostOversample is the default number of candidate hosts
+	// fetched per needed contract during the contract formation. See
+	// SetContractorSettings.
+	defaultHostOversample = 4
+
 	// randomHostsBufferForScore defines how many extra hosts are queried when trying
 	// to figure out an appropriate minimum score for the hosts that we have.
 	randomHostsBufferForScore = 50
@@ -83,12 +254,11 @@ var (
 )
 
 // Constants related to the safety values for when the contractor is forming
-// contracts.
+// contracts. The storage price and collateral ceilings and the score leeways
+// are the defaults, which can be adjusted with SetContractorSettings.
 var (
-	maxCollateral    = types.SiacoinPrecision.Mul64(1e3) // 1k SC
-	maxDownloadPrice = maxStoragePrice.Mul64(3 * uint64(types.BlocksPerMonth))
-	maxStoragePrice  = types.SiacoinPrecision.Mul64(30e3).Div(modules.BlockBytesPerMonthTerabyte) // 30k SC / TB / Month
-	maxUploadPrice   = maxStoragePrice.Mul64(3 * uint64(types.BlocksPerMonth)) // 3 months of storage
+	maxCollateral   = types.SiacoinPrecision.Mul64(1e3) // 1k SC
+	maxStoragePrice = types.SiacoinPrecision.Mul64(30e3).Div(modules.BlockBytesPerMonthTerabyte) // 30k SC / TB / Month
 
 	// scoreLeewayGoodForRenew defines the factor by which a host can miss the
 	// goal score for a set of hosts and still be GoodForRenew. To determine the
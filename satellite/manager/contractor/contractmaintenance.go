@@ -5,6 +5,7 @@ package contractor
 // contracts need to be renewed, and if contracts need to be blacklisted.
 
 import (
+	"context"
 	"fmt"
 	"math/big"
 	"reflect"
@@ -51,25 +52,19 @@ type (
 		amount          types.Currency
 		hostPubKey      types.SiaPublicKey
 		renterPubKey    types.SiaPublicKey
+		endHeight       types.BlockHeight
 	}
 )
 
-// callNotifyDoubleSpend is used by the watchdog to alert the contractor
-// whenever a monitored file contract input is double-spent. This function
-// marks down the host score, and marks the contract as !GoodForRenew and
+// managedMarkDoubleSpentBad is called after the watchdog found that the
+// inputs of a monitored file contract were double-spent. The contract has
+// already been recorded as double-spent while processing the consensus
+// change. This function marks the contract as !GoodForRenew and
 // !GoodForUpload.
-func (c *Contractor) callNotifyDoubleSpend(fcID types.FileContractID, blockHeight types.BlockHeight) {
-	c.log.Println("Watchdog found a double-spend: ", fcID, blockHeight)
-
-	// Mark the contract as double-spent. This will cause the contract to be
-	// excluded in period spending.
-	c.mu.Lock()
-	c.doubleSpentContracts[fcID] = blockHeight
-	c.mu.Unlock()
-
+func (c *Contractor) managedMarkDoubleSpentBad(fcID types.FileContractID) {
 	err := c.MarkContractBad(fcID)
 	if err != nil {
-		c.log.Println("callNotifyDoubleSpend error in MarkContractBad", err)
+		c.log.Println("managedMarkDoubleSpentBad error in MarkContractBad", err)
 	}
 }
 
@@ -143,16 +138,29 @@ func (c *Contractor) managedCheckForDuplicates() {
 // storage is in the contract and what the historic usage pattern of the
 // contract has been.
 func (c *Contractor) managedEstimateRenewFundingRequirements(contract modules.RenterContract, blockHeight types.BlockHeight, allowance smodules.Allowance) (types.Currency, error) {
+	fees, err := c.managedEstimateRenewFunding(contract, blockHeight, allowance)
+	return fees.EstimatedCost, err
+}
+
+// managedEstimateRenewFunding estimates the renewal funding of a contract
+// like managedEstimateRenewFundingRequirements and also returns the fees
+// included in the estimate.
+func (c *Contractor) managedEstimateRenewFunding(contract modules.RenterContract, blockHeight types.BlockHeight, allowance smodules.Allowance) (modules.RenewalFeeBreakdown, error) {
+	fees := modules.RenewalFeeBreakdown{
+		ContractID:    contract.ID,
+		HostPublicKey: contract.HostPublicKey,
+	}
+
 	// Fetch the host pricing to use in the estimate.
 	host, exists, err := c.hdb.Host(contract.HostPublicKey)
 	if err != nil {
-		return types.ZeroCurrency, errors.AddContext(err, "error getting host from hostdb:")
+		return modules.RenewalFeeBreakdown{}, errors.AddContext(err, "error getting host from hostdb:")
 	}
 	if !exists {
-		return types.ZeroCurrency, errors.New("could not find host in hostdb")
+		return modules.RenewalFeeBreakdown{}, errors.New("could not find host in hostdb")
 	}
 	if host.Filtered {
-		return types.ZeroCurrency, errHostBlocked
+		return modules.RenewalFeeBreakdown{}, errHostBlocked
 	}
 
 	// Fetch the renter.
@@ -160,7 +168,7 @@ func (c *Contractor) managedEstimateRenewFundingRequirements(contract modules.Re
 	renter, exists := c.renters[contract.RenterPublicKey.String()]
 	c.mu.RUnlock()
 	if !exists {
-		return types.ZeroCurrency, ErrRenterNotFound
+		return modules.RenewalFeeBreakdown{}, ErrRenterNotFound
 	}
 
 	// Estimate the amount of money that's going to be needed for existing
@@ -179,7 +187,9 @@ func (c *Contractor) managedEstimateRenewFundingRequirements(contract modules.Re
 	prevMaintenanceSpending := contract.MaintenanceSpending
 	c.mu.Lock()
 	currentID := contract.ID
-	for i := 0; i < 10e3; i++ { // Prevent an infinite loop if there's an [impossible] contract cycle.
+	maxDepth := c.maxRenewHistoryDepth
+	var depth int
+	for depth = 0; depth < maxDepth; depth++ { // Prevent an infinite loop if there's an [impossible] contract cycle.
 		// If there is no previous contract, nothing to do.
 		var exists bool
 		currentID, exists = c.renewedFrom[currentID]
@@ -208,6 +218,9 @@ func (c *Contractor) managedEstimateRenewFundingRequirements(contract modules.Re
 		prevMaintenanceSpending = prevMaintenanceSpending.Add(currentContract.MaintenanceSpending)
 	}
 	c.mu.Unlock()
+	if depth == maxDepth {
+		c.log.Printf("WARN: renew history of contract %v exceeds %v contracts, the contract data may be corrupted\n", contract.ID, maxDepth)
+	}
 
 	// Estimate the amount of money that's going to be needed for new storage
 	// based on the amount of new storage added in the previous period. Account
@@ -261,14 +274,20 @@ func (c *Contractor) managedEstimateRenewFundingRequirements(contract modules.Re
 	estimatedCost := afterSiafundFeesEstimate.Add(txnFees)
 	estimatedCost = estimatedCost.Add(estimatedCost.Div64(3))
 
+	fees.ContractPrice = contractPrice
+	fees.SiafundFee = afterSiafundFeesEstimate.Sub(beforeSiafundFeesEstimate)
+	fees.TxnFee = txnFees
+	fees.Overhead = fees.ContractPrice.Add(fees.SiafundFee).Add(fees.TxnFee)
+
 	// Check for a sane minimum. The contractor should not be forming contracts
-	// with less than 'fileContractMinimumFunding / (num contracts)' of the
-	// value of the allowance.
-	minimum := allowance.Funds.MulFloat(fileContractMinimumFunding).Div64(allowance.Hosts)
+	// with less than 'minimumFunding / (num contracts)' of the value of the
+	// allowance.
+	minimum := c.managedMinimumContractFunding(allowance)
 	if estimatedCost.Cmp(minimum) < 0 {
 		estimatedCost = minimum
 	}
-	return estimatedCost, nil
+	fees.EstimatedCost = estimatedCost
+	return fees, nil
 }
 
 // callInterruptContractMaintenance will issue an interrupt signal to any
@@ -299,6 +318,8 @@ func (c *Contractor) callInterruptContractMaintenance() {
 
 // managedFindMinAllowedHostScores uses a set of random hosts from the hostdb to
 // calculate minimum acceptable score for a host to be marked GFR and GFU.
+// The scores are adjusted per host for the region diversity by
+// managedDiversityAdjustedScores.
 func (c *Contractor) managedFindMinAllowedHostScores(rpk types.SiaPublicKey) (types.Currency, types.Currency, error) {
 	// Check if we know this renter.
 	c.mu.RLock()
@@ -313,7 +334,11 @@ func (c *Contractor) managedFindMinAllowedHostScores(rpk types.SiaPublicKey) (ty
 	// be used as a baseline for determining whether our existing contracts are
 	// worthwhile.
 	hostCount := int(renter.Allowance.Hosts)
+	if !c.managedHostDBAllowed() {
+		return types.Currency{}, types.Currency{}, errors.New("hostdb is unavailable")
+	}
 	hosts, err := c.hdb.RandomHostsWithLimits(hostCount + randomHostsBufferForScore, nil, nil, renter.Allowance)
+	c.managedHostDBResult(err)
 	if err != nil {
 		return types.Currency{}, types.Currency{}, err
 	}
@@ -325,14 +350,14 @@ func (c *Contractor) managedFindMinAllowedHostScores(rpk types.SiaPublicKey) (ty
 	// Find the minimum score that a host is allowed to have to be considered
 	// good for upload.
 	var minScoreGFR, minScoreGFU types.Currency
-	sb, err := c.hdb.ScoreBreakdown(hosts[0])
+	sb, err := c.managedScoreBreakdown(hosts[0])
 	if err != nil {
 		return types.Currency{}, types.Currency{}, err
 	}
 
 	lowestScore := sb.Score
 	for i := 1; i < len(hosts); i++ {
-		score, err := c.hdb.ScoreBreakdown(hosts[i])
+		score, err := c.managedScoreBreakdown(hosts[i])
 		if err != nil {
 			return types.Currency{}, types.Currency{}, err
 		}
@@ -341,12 +366,93 @@ func (c *Contractor) managedFindMinAllowedHostScores(rpk types.SiaPublicKey) (ty
 		}
 	}
 	// Set the minimum acceptable score to a factor of the lowest score.
-	minScoreGFR = lowestScore.Div(scoreLeewayGoodForRenew)
-	minScoreGFU = lowestScore.Div(scoreLeewayGoodForUpload)
+	c.mu.RLock()
+	minScoreGFR = lowestScore.Div(c.scoreLeewayGFR)
+	minScoreGFU = lowestScore.Div(c.scoreLeewayGFU)
+	c.mu.RUnlock()
 
 	return minScoreGFR, minScoreGFU, nil
 }
 
+// managedMarkContractsUtility checks every active contract and updates its
+// GoodForUpload and GoodForRenew flags. The minimum host scores are found
+// once per renter, a renter they can't be found for is skipped. The locked
+// contracts and the contracts of the renters without an allowance are left
+// alone.
+func (c *Contractor) managedMarkContractsUtility() {
+	contracts := make(map[string][]modules.RenterContract)
+	for _, contract := range c.staticContracts.ViewAll() {
+		if contract.Utility.Locked {
+			continue
+		}
+		key := contract.RenterPublicKey.String()
+		contracts[key] = append(contracts[key], contract)
+	}
+
+	for key, renterContracts := range contracts {
+		c.mu.RLock()
+		renter, exists := c.renters[key]
+		c.mu.RUnlock()
+		if !exists || !renter.Allowance.Active() {
+			continue
+		}
+		minScoreGFR, minScoreGFU, err := c.managedFindMinAllowedHostScores(renter.PublicKey)
+		if err != nil {
+			c.log.Println("WARN: unable to find the minimum host scores for renter", key + ":", err)
+			continue
+		}
+		for _, contract := range renterContracts {
+			if err := c.managedMarkContractUtility(contract, minScoreGFR, minScoreGFU); err != nil {
+				c.log.Println("WARN: unable to update the contract utility:", contract.ID, err)
+			}
+		}
+	}
+}
+
+// managedMarkContractUtility runs the utility checks on the contract and
+// updates its utility. A contract passing all checks is marked as
+// GoodForRenew, and as GoodForUpload unless the host is in the GFU
+// cooldown.
+func (c *Contractor) managedMarkContractUtility(contract modules.RenterContract, minScoreGFR, minScoreGFU types.Currency) error {
+	fc, ok := c.staticContracts.Acquire(contract.ID)
+	if !ok {
+		return errors.New("failed to acquire contract")
+	}
+	defer c.staticContracts.Return(fc)
+
+	u, err := func() (smodules.ContractUtility, error) {
+		host, u, needsUpdate := c.managedHostInHostDBCheck(contract)
+		if needsUpdate {
+			return u, nil
+		}
+		u, needsUpdate = c.managedCriticalUtilityChecks(fc, host)
+		if needsUpdate {
+			return u, nil
+		}
+		sb, err := c.managedScoreBreakdown(host)
+		c.managedHostDBResult(err)
+		if err != nil {
+			return smodules.ContractUtility{}, err
+		}
+		u, status := c.managedCheckHostScore(contract, sb, minScoreGFR, minScoreGFU)
+		if status != noUpdate {
+			return u, nil
+		}
+
+		// All checks passed.
+		if !u.GoodForUpload || !u.GoodForRenew {
+			c.log.Println("Marking contract as being both GoodForUpload and GoodForRenew:", contract.ID)
+		}
+		u.GoodForUpload = !c.managedInGFUCooldown(contract.RenterPublicKey, contract.HostPublicKey)
+		u.GoodForRenew = true
+		return u, nil
+	}()
+	if err != nil {
+		return err
+	}
+	return c.managedUpdateContractUtility(fc, u, "maintenance checks")
+}
+
 // managedNewContract negotiates an initial file contract with the specified
 // host, saves it, and returns it.
 func (c *Contractor) managedNewContract(rpk types.SiaPublicKey, host smodules.HostDBEntry, contractFunding types.Currency, endHeight types.BlockHeight) (_ types.Currency, _ modules.RenterContract, err error) {
@@ -359,7 +465,8 @@ func (c *Contractor) managedNewContract(rpk types.SiaPublicKey, host smodules.Ho
 	}
 
 	// Reject hosts that are too expensive.
-	if host.StoragePrice.Cmp(maxStoragePrice) > 0 {
+	storageCeiling, collateralCeiling := c.managedPriceCeilings()
+	if host.StoragePrice.Cmp(storageCeiling) > 0 {
 		return types.ZeroCurrency, modules.RenterContract{}, errTooExpensive
 	}
 	// Determine if host settings align with allowance period.
@@ -377,8 +484,8 @@ func (c *Contractor) managedNewContract(rpk types.SiaPublicKey, host smodules.Ho
 		return types.ZeroCurrency, modules.RenterContract{}, err
 	}
 	// Cap host.MaxCollateral.
-	if host.MaxCollateral.Cmp(maxCollateral) > 0 {
-		host.MaxCollateral = maxCollateral
+	if host.MaxCollateral.Cmp(collateralCeiling) > 0 {
+		host.MaxCollateral = collateralCeiling
 	}
 
 	// Check for price gouging.
@@ -388,15 +495,19 @@ func (c *Contractor) managedNewContract(rpk types.SiaPublicKey, host smodules.Ho
 	}
 
 	// Get an address to use for negotiation.
-	uc, err := c.wallet.NextAddress()
+	refundAddress, uc, fresh, err := c.managedRefundAddress(renter)
 	if err != nil {
 		return types.ZeroCurrency, modules.RenterContract{}, err
 	}
-	defer func() {
-		if err != nil {
-			err = errors.Compose(err, c.wallet.MarkAddressUnused(uc))
-		}
-	}()
+	if fresh {
+		c.managedReserveAddress(refundAddress)
+		defer c.managedReleaseAddress(refundAddress)
+		defer func() {
+			if err != nil {
+				err = errors.Compose(err, c.wallet.MarkAddressUnused(uc))
+			}
+		}()
+	}
 
 	// Get the wallet seed.
 	seed, _, err := c.wallet.PrimarySeed()
@@ -405,7 +516,7 @@ func (c *Contractor) managedNewContract(rpk types.SiaPublicKey, host smodules.Ho
 	}
 
 	// Derive the renter seed and wipe it once we are done with it.
-	renterSeed := modules.DeriveRenterSeed(seed, renter.Email)
+	renterSeed := modules.DeriveRenterSeed(seed, renter.Email, renter.Suffix)
 	defer fastrand.Read(renterSeed[:])
 
 	// Create contract params.
@@ -416,7 +527,7 @@ func (c *Contractor) managedNewContract(rpk types.SiaPublicKey, host smodules.Ho
 		Funding:       contractFunding,
 		StartHeight:   c.blockHeight,
 		EndHeight:     endHeight,
-		RefundAddress: uc.UnlockHash(),
+		RefundAddress: refundAddress,
 		RenterSeed:    smodules.EphemeralRenterSeed(renterSeed), // The seed should not be mutated.
 	}
 	c.mu.RUnlock()
@@ -467,6 +578,7 @@ func (c *Contractor) managedNewContract(rpk types.SiaPublicKey, host smodules.Ho
 
 	contractValue := contract.RenterFunds
 	c.log.Printf("Formed contract %v with %v for %v\n", contract.ID, host.NetAddress, contractValue.HumanString())
+	c.logEvent("INFO", eventContractFormed, contract.ID, contract.RenterPublicKey, contract.HostPublicKey, "formed contract with %v for %v", host.NetAddress, contractValue.HumanString())
 
 	// Update the hostdb to include the new contract.
 	err = c.hdb.UpdateContracts(c.staticContracts.ViewAll())
@@ -479,7 +591,8 @@ func (c *Contractor) managedNewContract(rpk types.SiaPublicKey, host smodules.Ho
 // managedPruneRedundantAddressRange uses the hostdb to find hosts that
 // violate the rules about address ranges and cancels them.
 func (c *Contractor) managedPruneRedundantAddressRange() {
-	// Get all contracts which are not canceled.
+	// Get all contracts which are not canceled. Skip the contracts of the
+	// renters who opted out of pruning.
 	allContracts := c.staticContracts.ViewAll()
 	var contracts []modules.RenterContract
 	for _, contract := range allContracts {
@@ -487,6 +600,12 @@ func (c *Contractor) managedPruneRedundantAddressRange() {
 			// Contract is canceled.
 			continue
 		}
+		c.mu.RLock()
+		renter, exists := c.renters[contract.RenterPublicKey.String()]
+		c.mu.RUnlock()
+		if exists && renter.AllowRedundantIPs {
+			continue
+		}
 		contracts = append(contracts, contract)
 	}
 
@@ -514,6 +633,7 @@ func (c *Contractor) managedPruneRedundantAddressRange() {
 	// Let the hostdb filter out bad hosts and cancel contracts with those
 	// hosts.
 	badHosts, err := c.hdb.CheckForIPViolations(pks)
+	c.managedHostDBResult(err)
 	if err != nil {
 		c.log.Println("WARN: error checking for IP violations:", err)
 		return
@@ -533,11 +653,13 @@ func (c *Contractor) managedPruneRedundantAddressRange() {
 func (c *Contractor) managedLimitGFUHosts() {
 	c.mu.Lock()
 	renters := c.renters
+	weight := c.gfuStoredDataWeight
 	c.mu.Unlock()
 	// Get all GFU contracts and their score.
 	type gfuContract struct {
-		c     modules.RenterContract
-		score types.Currency
+		c         modules.RenterContract
+		score     types.Currency
+		retention float64
 	}
 	var gfuContracts []gfuContract
 	var key string
@@ -554,7 +676,8 @@ func (c *Contractor) managedLimitGFUHosts() {
 				c.log.Println("managedLimitGFUHosts was run after updating contract utility but found contract without host in hostdb that's GFU", contract.HostPublicKey)
 				continue
 			}
-			score, err := c.hdb.ScoreBreakdown(host)
+			score, err := c.managedScoreBreakdown(host)
+			c.managedHostDBResult(err)
 			if err != nil {
 				c.log.Println("managedLimitGFUHosts: failed to get score breakdown for GFU host")
 				continue
@@ -567,9 +690,36 @@ func (c *Contractor) managedLimitGFUHosts() {
 			score: hostScore,
 		})
 	}
-	// Sort gfuContracts by score.
+	// Rank the contracts by a mix of the host score and the amount of data
+	// stored with the host, both relative to the best contract of the same
+	// renter. This way a host holding a lot of the renter's data isn't
+	// dropped in favor of an empty host with a slightly higher score.
+	maxScores := make(map[string]types.Currency)
+	maxSizes := make(map[string]uint64)
+	for _, contract := range gfuContracts {
+		key = contract.c.RenterPublicKey.String()
+		if contract.score.Cmp(maxScores[key]) > 0 {
+			maxScores[key] = contract.score
+		}
+		if size := contract.c.Size(); size > maxSizes[key] {
+			maxSizes[key] = size
+		}
+	}
+	for i, contract := range gfuContracts {
+		key = contract.c.RenterPublicKey.String()
+		var relScore, relSize float64
+		if !maxScores[key].IsZero() {
+			relScore, _ = new(big.Rat).SetFrac(contract.score.Big(), maxScores[key].Big()).Float64()
+		}
+		if maxSizes[key] > 0 {
+			relSize = float64(contract.c.Size()) / float64(maxSizes[key])
+		}
+		gfuContracts[i].retention = (1-weight)*relScore + weight*relSize
+	}
+
+	// Sort gfuContracts by retention rank, best first.
 	sort.Slice(gfuContracts, func(i, j int) bool {
-		return gfuContracts[i].score.Cmp(gfuContracts[j].score) < 0
+		return gfuContracts[i].retention > gfuContracts[j].retention
 	})
 	// Mark them bad for upload until we are below the expected number of hosts
 	// for each renter.
@@ -591,12 +741,13 @@ func (c *Contractor) managedLimitGFUHosts() {
 		}
 		u := sc.Utility()
 		u.GoodForUpload = false
-		err := c.managedUpdateContractUtility(sc, u)
+		err := c.managedUpdateContractUtility(sc, u, "too many GFU hosts")
 		c.staticContracts.Return(sc)
 		if err != nil {
 			c.log.Println("managedLimitGFUHosts: failed to update GFU contract utility")
 			continue
 		}
+		c.managedStartGFUCooldown(contract.c.RenterPublicKey, contract.c.HostPublicKey)
 	}
 }
 
@@ -619,6 +770,13 @@ func staticCheckFormPaymentContractGouging(allowance smodules.Allowance, hostSet
 	return nil
 }
 
+// refreshDeferred returns true if an out-of-funds contract is too close to
+// its renew height to be refreshed, and should be left to the renewal for
+// expiry instead.
+func refreshDeferred(endHeight, blockHeight, renewWindow, minRemaining types.BlockHeight) bool {
+	return blockHeight + renewWindow + minRemaining > endHeight
+}
+
 // checkFormContractGouging will check whether the pricing for forming
 // this contract triggers any price gouging warnings.
 func checkFormContractGouging(allowance smodules.Allowance, hostSettings smodules.HostExternalSettings) error {
@@ -658,20 +816,21 @@ func (c *Contractor) managedRenew(id types.FileContractID, rpk types.SiaPublicKe
 		return modules.RenterContract{}, errors.New("called managedRenew but allowance isn't set")
 	}
 	period := renter.Allowance.Period
+	storageCeiling, collateralCeiling := c.managedPriceCeilings()
 
 	if !ok {
 		return modules.RenterContract{}, errHostNotFound
 	} else if host.Filtered {
 		return modules.RenterContract{}, errHostBlocked
-	} else if host.StoragePrice.Cmp(maxStoragePrice) > 0 {
+	} else if host.StoragePrice.Cmp(storageCeiling) > 0 {
 		return modules.RenterContract{}, errTooExpensive
 	} else if host.MaxDuration < period {
 		return modules.RenterContract{}, errors.New("insufficient MaxDuration of host")
 	}
 
 	// Cap host.MaxCollateral.
-	if host.MaxCollateral.Cmp(maxCollateral) > 0 {
-		host.MaxCollateral = maxCollateral
+	if host.MaxCollateral.Cmp(collateralCeiling) > 0 {
+		host.MaxCollateral = collateralCeiling
 	}
 
 	// Check for price gouging on the renewal.
@@ -680,16 +839,30 @@ func (c *Contractor) managedRenew(id types.FileContractID, rpk types.SiaPublicKe
 		return modules.RenterContract{}, errors.AddContext(err, "unable to renew - price gouging protection enabled")
 	}
 
+	// Make sure the renewal doesn't eat into the fees reserved for the
+	// ongoing contract formations.
+	available, err := c.managedUnreservedBalance()
+	if err != nil {
+		return modules.RenterContract{}, err
+	}
+	if available.Cmp(contractFunding) < 0 {
+		return modules.RenterContract{}, errInsufficientUnreservedBalance
+	}
+
 	// Get an address to use for negotiation.
-	uc, err := c.wallet.NextAddress()
+	refundAddress, uc, fresh, err := c.managedRefundAddress(renter)
 	if err != nil {
 		return modules.RenterContract{}, err
 	}
-	defer func() {
-		if err != nil {
-			err = errors.Compose(err, c.wallet.MarkAddressUnused(uc))
-		}
-	}()
+	if fresh {
+		c.managedReserveAddress(refundAddress)
+		defer c.managedReleaseAddress(refundAddress)
+		defer func() {
+			if err != nil {
+				err = errors.Compose(err, c.wallet.MarkAddressUnused(uc))
+			}
+		}()
+	}
 
 	// Get the wallet seed.
 	seed, _, err := c.wallet.PrimarySeed()
@@ -698,7 +871,7 @@ func (c *Contractor) managedRenew(id types.FileContractID, rpk types.SiaPublicKe
 	}
 
 	// Derive the renter seed and wipe it after we are done with it.
-	renterSeed := modules.DeriveRenterSeed(seed, renter.Email)
+	renterSeed := modules.DeriveRenterSeed(seed, renter.Email, renter.Suffix)
 	defer fastrand.Read(renterSeed[:])
 
 	// Create contract params.
@@ -709,7 +882,7 @@ func (c *Contractor) managedRenew(id types.FileContractID, rpk types.SiaPublicKe
 		Funding:       contractFunding,
 		StartHeight:   c.blockHeight,
 		EndHeight:     newEndHeight,
-		RefundAddress: uc.UnlockHash(),
+		RefundAddress: refundAddress,
 		RenterSeed:    smodules.EphemeralRenterSeed(renterSeed), // The seed should not be mutated.
 	}
 	c.mu.RUnlock()
@@ -740,9 +913,12 @@ func (c *Contractor) managedRenew(id types.FileContractID, rpk types.SiaPublicKe
 
 	oldContract, ok := c.staticContracts.Acquire(id)
 	if !ok {
+		txnBuilder.Drop() // Return unused outputs to wallet.
 		return modules.RenterContract{}, errContractNotFound
 	}
 	if !oldContract.Utility().GoodForRenew {
+		c.staticContracts.Return(oldContract)
+		txnBuilder.Drop() // Return unused outputs to wallet.
 		return modules.RenterContract{}, errContractNotGFR
 	}
 	newContract, formationTxnSet, err = c.staticContracts.Renew(oldContract, params, txnBuilder, c.tpool, c.hdb, c.tg.StopChan())
@@ -783,6 +959,28 @@ func (c *Contractor) managedRenew(id types.FileContractID, rpk types.SiaPublicKe
 	return newContract, nil
 }
 
+// managedPaceFormation pauses between two contract formations. It returns
+// early if the context is done, and with an error if the contractor is
+// stopped.
+func (c *Contractor) managedPaceFormation(ctx context.Context) error {
+	c.mu.RLock()
+	delay, jitter := c.formationDelay, c.formationJitter
+	c.mu.RUnlock()
+	if delay+jitter <= 0 {
+		return nil
+	}
+	if jitter > 0 {
+		delay += time.Duration(fastrand.Uint64n(uint64(jitter)))
+	}
+	select {
+	case <-c.tg.StopChan():
+		return errors.New("the manager was stopped")
+	case <-ctx.Done():
+	case <-time.After(delay):
+	}
+	return nil
+}
+
 // managedRenewContract will use the renew instructions to renew a contract,
 // returning the amount of money that was put into the contract for renewal.
 func (c *Contractor) managedRenewContract(renewInstructions fileContractRenewal, blockHeight, endHeight types.BlockHeight) (fundsSpent types.Currency, newContract modules.RenterContract, err error) {
@@ -802,13 +1000,25 @@ func (c *Contractor) managedRenewContract(renewInstructions fileContractRenewal,
 	hostPubKey := renewInstructions.hostPubKey
 	allowance := renter.Allowance
 
-	// Get a session with the host, before marking it as being renewed.
-	hs, err := c.Session(renterPubKey, hostPubKey, c.tg.StopChan())
+	// Wait for a renewal slot, so that the number of concurrent renewals
+	// stays bounded.
+	c.mu.RLock()
+	slots := c.renewalSlots
+	c.mu.RUnlock()
+	select {
+	case slots <- struct{}{}:
+	case <-c.tg.StopChan():
+		return types.ZeroCurrency, newContract, errors.New("the manager was stopped")
+	}
+	defer func() {
+		<-slots
+	}()
+
+	// Get the host settings, before marking the contract as being renewed.
+	hostSettings, err := c.managedHostSettings(id, renterPubKey, hostPubKey)
 	if err != nil {
-		err = errors.AddContext(err, "Unable to establish session with host")
 		return
 	}
-	s := hs.(*hostSession)
 
 	// Mark the contract as being renewed, and defer logic to unmark it
 	// once renewing is complete.
@@ -823,13 +1033,12 @@ func (c *Contractor) managedRenewContract(renewInstructions fileContractRenewal,
 		c.mu.Unlock()
 	}()
 
-	// Use the Settings RPC with the host and then invalidate the session.
-	hostSettings, err := s.Settings()
-	if err != nil {
-		err = errors.AddContext(err, "Unable to get host settings")
-		return
-	}
-	s.invalidate()
+	// Invalidate the session on the contract, if there is one, so that
+	// the contract isn't revised mid-renewal. Do it again on the way out,
+	// so that the session is released on every exit path, including the
+	// failed renewals.
+	c.managedInvalidateSession(id)
+	defer c.managedInvalidateSession(id)
 
 	// Perform the actual renewal. If the renewal succeeds, return the
 	// contract. If the renewal fails we check how often it has failed
@@ -845,6 +1054,9 @@ func (c *Contractor) managedRenewContract(renewInstructions fileContractRenewal,
 	}
 	oldUtility := oldContract.Utility()
 	if errRenew != nil {
+		// Don't reuse the host settings, they may have been stale.
+		c.staticSettingsCache.invalidate(hostPubKey)
+
 		// Increment the number of failed renewals for the contract if it
 		// was the host's fault.
 		if smodules.IsHostsFault(errRenew) {
@@ -866,12 +1078,13 @@ func (c *Contractor) managedRenewContract(renewInstructions fileContractRenewal,
 			oldUtility.GoodForRenew = false
 			oldUtility.GoodForUpload = false
 			oldUtility.Locked = true
-			err := c.callUpdateUtility(oldContract, oldUtility, true)
+			err := c.callUpdateUtility(oldContract, oldUtility, true, "too many failed renewals")
 			if err != nil {
 				c.log.Println("WARN: failed to mark contract as !goodForRenew:", err)
 			}
 			c.log.Printf("WARN: consistently failed to renew %v, marked as bad and locked: %v\n",
 				oldContract.Metadata().HostPublicKey, errRenew)
+			c.logEvent("WARN", eventContractCanceled, id, renterPubKey, hostPubKey, "consistently failed to renew, marked as bad and locked: %v", errRenew)
 			c.staticContracts.Return(oldContract)
 			return types.ZeroCurrency, newContract, errors.AddContext(errRenew, "contract marked as bad for too many consecutive failed renew attempts")
 		}
@@ -880,18 +1093,20 @@ func (c *Contractor) managedRenewContract(renewInstructions fileContractRenewal,
 		// failure and number of renews that have failed so far.
 		c.log.Printf("WARN: failed to renew contract %v [%v]: '%v', current height: %v, proposed end height: %v, max duration: %v",
 			oldContract.Metadata().HostPublicKey, numRenews, errRenew, blockHeight, endHeight, hostSettings.MaxDuration)
+		c.logEvent("WARN", eventContractRenewalFailed, id, renterPubKey, hostPubKey, "renewal failed [%v]: %v", numRenews, errRenew)
 		c.staticContracts.Return(oldContract)
 		return types.ZeroCurrency, newContract, errors.AddContext(errRenew, "contract renewal with host was unsuccessful")
 	}
 	c.log.Printf("Renewed contract %v\n", id)
+	c.logEvent("INFO", eventContractRenewed, newContract.ID, renterPubKey, hostPubKey, "renewed contract %v", id)
 
 	// Update the utility values for the new contract, and for the old
 	// contract.
 	newUtility := smodules.ContractUtility{
-		GoodForUpload: true,
+		GoodForUpload: !c.managedInGFUCooldown(renterPubKey, hostPubKey),
 		GoodForRenew:  true,
 	}
-	if err := c.managedAcquireAndUpdateContractUtility(newContract.ID, newUtility); err != nil {
+	if err := c.managedAcquireAndUpdateContractUtility(newContract.ID, newUtility, "formed by renewal"); err != nil {
 		c.log.Println("Failed to update the contract utilities", err)
 		c.staticContracts.Return(oldContract)
 		return amount, newContract, nil
@@ -899,7 +1114,7 @@ func (c *Contractor) managedRenewContract(renewInstructions fileContractRenewal,
 	oldUtility.GoodForRenew = false
 	oldUtility.GoodForUpload = false
 	oldUtility.Locked = true
-	if err := c.callUpdateUtility(oldContract, oldUtility, true); err != nil {
+	if err := c.callUpdateUtility(oldContract, oldUtility, true, "renewed"); err != nil {
 		c.log.Println("Failed to update the contract utilities", err)
 		c.staticContracts.Return(oldContract)
 		return amount, newContract, nil
@@ -925,6 +1140,7 @@ func (c *Contractor) managedRenewContract(renewInstructions fileContractRenewal,
 	if err != nil {
 		c.log.Println("Failed to update contracts in the database.")
 	}
+	c.managedCarryNoRefresh(id, newContract.ID)
 
 	// Delete the old contract.
 	c.staticContracts.Delete(oldContract)
@@ -937,19 +1153,19 @@ func (c *Contractor) managedRenewContract(renewInstructions fileContractRenewal,
 
 // managedAcquireAndUpdateContractUtility is a helper function that acquires a contract, updates
 // its ContractUtility and returns the contract again.
-func (c *Contractor) managedAcquireAndUpdateContractUtility(id types.FileContractID, utility smodules.ContractUtility) error {
+func (c *Contractor) managedAcquireAndUpdateContractUtility(id types.FileContractID, utility smodules.ContractUtility, reason string) error {
 	fileContract, ok := c.staticContracts.Acquire(id)
 	if !ok {
 		return errors.New("failed to acquire contract for update")
 	}
 	defer c.staticContracts.Return(fileContract)
 
-	return c.managedUpdateContractUtility(fileContract, utility)
+	return c.managedUpdateContractUtility(fileContract, utility, reason)
 }
 
 // managedUpdateContractUtility is a helper function that updates the contract
 // with the given utility.
-func (c *Contractor) managedUpdateContractUtility(fileContract *proto.FileContract, utility smodules.ContractUtility) error {
+func (c *Contractor) managedUpdateContractUtility(fileContract *proto.FileContract, utility smodules.ContractUtility, reason string) error {
 	// Sanity check to verify that we aren't attempting to set a good utility on
 	// a contract that has been renewed.
 	c.mu.Lock()
@@ -959,17 +1175,75 @@ func (c *Contractor) managedUpdateContractUtility(fileContract *proto.FileContra
 		c.log.Println("CRITICAL: attempting to update contract utility on a contract that has been renewed")
 	}
 
-	return c.callUpdateUtility(fileContract, utility, false)
+	return c.callUpdateUtility(fileContract, utility, false, reason)
 }
 
 // callUpdateUtility updates the utility of a contract. This method should
 // *always* be used as opposed to calling UpdateUtility directly on a safe
 // contract from the contractor. Pass in renewed as true if the contract
-// has been renewed.
-func (c *Contractor) callUpdateUtility(fileContract *proto.FileContract, newUtility smodules.ContractUtility, renewed bool) error {
+// has been renewed. The reason is recorded in the utility history of the
+// contract.
+func (c *Contractor) callUpdateUtility(fileContract *proto.FileContract, newUtility smodules.ContractUtility, renewed bool, reason string) error {
 	// TODO Think about implementing ChurnLimiter.
 
-	return fileContract.UpdateUtility(newUtility)
+	// Skip the write if nothing has changed.
+	oldUtility := fileContract.Utility()
+	if oldUtility == newUtility {
+		return nil
+	}
+	if err := fileContract.UpdateUtility(newUtility); err != nil {
+		return err
+	}
+
+	// An unlocked contract is not canceled anymore.
+	if oldUtility.Locked && !newUtility.Locked {
+		c.managedClearCancellation(fileContract.Metadata().ID)
+	}
+
+	// Record the transition if any of the flags has changed.
+	if oldUtility.GoodForUpload == newUtility.GoodForUpload &&
+		oldUtility.GoodForRenew == newUtility.GoodForRenew &&
+		oldUtility.BadContract == newUtility.BadContract &&
+		oldUtility.Locked == newUtility.Locked {
+		return nil
+	}
+	c.mu.RLock()
+	height := c.blockHeight
+	c.mu.RUnlock()
+	if err := c.insertUtilityTransition(fileContract.Metadata().ID, oldUtility, newUtility, height, reason); err != nil {
+		c.log.Println("WARN: unable to record the utility transition:", err)
+	}
+
+	return nil
+}
+
+// managedFinalizeRenewal locks the funds spent on a renewal in the
+// database, marks the new contract as GFU and GFR, and saves the contractor.
+func (c *Contractor) managedFinalizeRenewal(email string, fundsSpent types.Currency, newContract modules.RenterContract) {
+	// Lock the funds in the database.
+	funds, _ := fundsSpent.Float64()
+	hastings, _ := types.SiacoinPrecision.Float64()
+	amount := funds / hastings
+	err := c.satellite.LockSiacoins(email, amount)
+	if err != nil {
+		c.log.Println("ERROR: couldn't lock funds")
+	}
+
+	// Add this contract to the contractor and save.
+	err = c.managedAcquireAndUpdateContractUtility(newContract.ID, smodules.ContractUtility{
+		GoodForUpload: !c.managedInGFUCooldown(newContract.RenterPublicKey, newContract.HostPublicKey),
+		GoodForRenew:  true,
+	}, "formed by renewal")
+	if err != nil {
+		c.log.Println("Failed to update the contract utilities", err)
+		return
+	}
+	c.mu.Lock()
+	err = c.save()
+	c.mu.Unlock()
+	if err != nil {
+		c.log.Println("Unable to save the contractor:", err)
+	}
 }
 
 // threadedContractMaintenance checks the set of contracts that the contractor
@@ -990,6 +1264,10 @@ func (c *Contractor) threadedContractMaintenance() {
 		c.log.Println("Skipping contract maintenance since consensus isn't synced yet")
 		return
 	}
+	if !c.managedSyncGracePassed() {
+		c.log.Println("Skipping contract maintenance since consensus hasn't been synced for long enough")
+		return
+	}
 	c.log.Println("starting contract maintenance")
 
 	// Only one instance of this thread should be running at a time. It is
@@ -1001,30 +1279,114 @@ func (c *Contractor) threadedContractMaintenance() {
 	}
 	defer c.maintenanceLock.Unlock()
 
+	// Check the wallet, so that the wallet alert is cleared once the wallet
+	// is unlocked. None of the phases below need the wallet.
+	if err := c.managedCheckWallet(); err != nil {
+		c.log.Println("INFO: contract maintenance running without the wallet:", err)
+	}
+
+	// Score each host at most once during this cycle.
+	c.staticScoreCache.begin()
+	defer c.staticScoreCache.end()
+
+	// Record the time spent in each phase.
+	mt := newMaintenanceTimer()
+	defer c.managedSetMaintenanceTimings(mt)
+
 	// Perform general cleanup of the contracts. This includes archiving
 	// contracts and other cleanup work.
 	c.managedArchiveContracts()
+	c.managedRecordPayouts()
+	mt.record("archive")
 	c.managedCheckForDuplicates()
+	mt.record("dedup")
 	c.managedUpdatePubKeysToContractIDMap()
-	c.managedPruneRedundantAddressRange()
-	if err != nil {
-		c.log.Println("Unable to mark contract utilities:", err)
+	mt.record("pubkey-map")
+	if n := c.managedRepairRenewedLinks(); n > 0 {
+		c.log.Println("INFO: orphaned renewal links removed:", n)
+	}
+	mt.record("renewed-links")
+
+	// Skip the phases that depend on the hostdb while it keeps failing.
+	if !c.managedHostDBAllowed() {
 		return
 	}
+	c.managedPruneRedundantAddressRange()
+	mt.record("prune")
+
+	// The utility checks only run with the diversity weighting, so that the
+	// hosts keep or lose their flags as the region mix of the renter's
+	// contracts changes.
+	c.mu.RLock()
+	weighted := c.diversityWeight > 0
+	c.mu.RUnlock()
+	if weighted {
+		c.managedMarkContractsUtility()
+		mt.record("utility")
+	}
 	err = c.hdb.UpdateContracts(c.staticContracts.ViewAll())
+	c.managedHostDBResult(err)
+	mt.record("hostdb-update")
 	if err != nil {
 		c.log.Println("Unable to update hostdb contracts:", err)
 		return
 	}
+	if n := c.managedChurnExcessHostContracts(); n > 0 {
+		c.log.Println("INFO: contracts churned for exceeding the per-host limit:", n)
+	}
+	mt.record("host-limit")
 	c.managedLimitGFUHosts()
+	mt.record("gfu-limit")
 }
 
 // FormContracts forms up to the specified number of contracts, puts them
-// in the contract set, and returns them.
-func (c *Contractor) FormContracts(rpk types.SiaPublicKey) ([]modules.RenterContract, error) {
+// in the contract set, and returns them. The preferred hosts are attempted
+// first.
+func (c *Contractor) FormContracts(ctx context.Context, rpk types.SiaPublicKey, preferred []types.SiaPublicKey) ([]modules.RenterContract, error) {
+	fr, err := c.FormContractsWithSpending(ctx, rpk, preferred)
+	return fr.Contracts, err
+}
+
+// managedChargeFormation locks the funds spent on a contract formation in
+// the database and returns the spending entry. The funds are accounted for
+// even if the formation failed after spending them, in which case the
+// entry is marked as failed.
+func (c *Contractor) managedChargeFormation(renter modules.Renter, host smodules.HostDBEntry, fundsSpent types.Currency, contract modules.RenterContract, formErr error) modules.HostSpending {
+	hs := modules.HostSpending{
+		HostPublicKey: host.PublicKey,
+		Spent:         fundsSpent,
+		Formed:        formErr == nil,
+	}
+	if formErr == nil {
+		hs.ContractID = contract.ID
+	} else {
+		hs.Error = formErr.Error()
+	}
+
+	// Lock the funds in the database.
+	funds, _ := fundsSpent.Float64()
+	hastings, _ := types.SiacoinPrecision.Float64()
+	amount := funds / hastings
+	if lockErr := c.satellite.LockSiacoins(renter.Email, amount); lockErr != nil {
+		c.log.Println("ERROR: couldn't lock funds")
+	}
+	return hs
+}
+
+// FormContractsWithSpending forms up to the specified number of contracts,
+// puts them in the contract set, and returns them together with the funds
+// spent on each host during this run. If ctx is canceled, no further
+// contracts are formed and the ones formed so far are returned.
+func (c *Contractor) FormContractsWithSpending(ctx context.Context, rpk types.SiaPublicKey, preferred []types.SiaPublicKey) (modules.FormationResult, error) {
+	return c.managedFormContracts(ctx, rpk, preferred, c.managedNewContract)
+}
+
+// managedFormContracts performs the contract formation of
+// FormContractsWithSpending. Each contract is formed by calling form.
+func (c *Contractor) managedFormContracts(ctx context.Context, rpk types.SiaPublicKey, preferred []types.SiaPublicKey, form func(types.SiaPublicKey, smodules.HostDBEntry, types.Currency, types.BlockHeight) (types.Currency, modules.RenterContract, error)) (modules.FormationResult, error) {
 	// No contract formation until the contractor is synced.
 	if !c.managedSynced() {
-		return nil, errors.New("contractor isn't synced yet")
+		return modules.FormationResult{}, errors.New("contractor isn't synced yet")
 	}
 
 	// Check if we know this renter.
@@ -1033,99 +1395,81 @@ func (c *Contractor) FormContracts(rpk types.SiaPublicKey) ([]modules.RenterCont
 	blockHeight := c.blockHeight
 	c.mu.RUnlock()
 	if !exists {
-		return nil, ErrRenterNotFound
+		return modules.FormationResult{}, ErrRenterNotFound
+	}
+	if renter.Paused {
+		return modules.FormationResult{}, ErrRenterPaused
+	}
+
+	// Check the renter's storage quota.
+	if err := c.managedCheckStorageQuota(renter); err != nil {
+		return modules.FormationResult{}, err
+	}
+
+	// Check the renter's renew window.
+	if err := c.managedCheckRenewWindow(renter); err != nil {
+		return modules.FormationResult{}, err
 	}
 
 	// Register or unregister and alerts related to contract formation.
 	var registerLowFundsAlert bool
+	var lowFundsCause string
 	defer func() {
 		if registerLowFundsAlert {
-			c.staticAlerter.RegisterAlert(smodules.AlertIDRenterAllowanceLowFunds, AlertMSGAllowanceLowFunds, AlertCauseInsufficientAllowanceFunds, smodules.SeverityWarning)
+			c.staticAlerter.RegisterAlert(smodules.AlertIDRenterAllowanceLowFunds, AlertMSGAllowanceLowFunds, lowFundsCause, smodules.SeverityWarning)
 		} else {
 			c.staticAlerter.UnregisterAlert(smodules.AlertIDRenterAllowanceLowFunds)
 		}
 	}()
 
-	// Check if the renter has enough contracts according to their allowance.
-	var fundsRemaining types.Currency
-	numHosts := renter.Allowance.Hosts
-	if numHosts == 0 {
-		return nil, errors.New("zero number of hosts specified")
-	}
-	endHeight := blockHeight + renter.Allowance.Period + renter.Allowance.RenewWindow
-
-	// Depend on the PeriodSpending function to get a breakdown of spending in
-	// the contractor. Then use that to determine how many funds remain
-	// available in the allowance.
-	spending, err := c.PeriodSpending(renter.PublicKey)
+	// Select the hosts to form the contracts with.
+	fp, err := c.managedFormationPlan(ctx, renter, preferred, blockHeight, false)
 	if err != nil {
-		// This should only error if the contractor is shutting down.
-		return nil, err
-	}
-
-	// Check for an underflow. This can happen if the user reduced their
-	// allowance at some point to less than what we've already spent.
-	fundsRemaining = renter.Allowance.Funds
-	if spending.TotalAllocated.Cmp(fundsRemaining) < 0 {
-		fundsRemaining = fundsRemaining.Sub(spending.TotalAllocated)
-	}
-
-	// Count the number of contracts which are good for uploading, and then make
-	// more as needed to fill the gap.
-	contractSet := make([]modules.RenterContract, 0, renter.Allowance.Hosts)
-	uploadContracts := 0
-	for _, contract := range c.staticContracts.ByRenter(renter.PublicKey) {
-		if cu, ok := c.managedContractUtility(contract.ID); ok && cu.GoodForUpload {
-			contractSet = append(contractSet, contract)
-			uploadContracts++
-			if uploadContracts >= int(renter.Allowance.Hosts) {
-				break
-			}
-		}
+		return modules.FormationResult{}, err
 	}
-	neededContracts := int(renter.Allowance.Hosts) - uploadContracts
+	contractSet := fp.contractSet
+	neededContracts := fp.neededContracts
 	if neededContracts <= 0 {
-		return contractSet, nil
+		c.managedCheckProvisioning(renter, len(contractSet), "")
+		return modules.FormationResult{Contracts: contractSet}, nil
 	}
-
 	c.log.Println("need more contracts:", neededContracts)
 
-	// Assemble two exclusion lists. The first one includes all hosts that we
-	// already have contracts with and the second one includes all hosts we
-	// have active contracts with. Then select a new batch of hosts to attempt
-	// contract formation with.
-	allContracts := c.staticContracts.ByRenter(renter.PublicKey)
-	var blacklist []types.SiaPublicKey
-	var addressBlacklist []types.SiaPublicKey
-	for _, contract := range allContracts {
-		blacklist = append(blacklist, contract.HostPublicKey)
-		if !contract.Utility.Locked || contract.Utility.GoodForRenew || contract.Utility.GoodForUpload {
-			addressBlacklist = append(addressBlacklist, contract.HostPublicKey)
+	// Form fewer contracts if the transaction pool is congested.
+	limit := c.managedFormationLimit(neededContracts)
+	throttled := limit < neededContracts
+	neededContracts = limit
+	if neededContracts <= 0 {
+		return modules.FormationResult{Contracts: contractSet}, nil
+	}
+	txnFee := fp.txnFee
+	var spending []modules.HostSpending
+
+	// Keep track of the hosts that failed during this run, so that they
+	// are not attempted again if they show up in the candidates twice.
+	failedHosts := make(map[string]struct{})
+
+	// Reserve the transaction fees in the wallet, so that concurrent wallet
+	// usage doesn't leave us without the funds to pay them. The fees of each
+	// formed contract are released as we go, the rest upon return.
+	var reservedFees types.Currency
+	if neededContracts > 0 {
+		reservedFees = txnFee.Mul64(uint64(neededContracts))
+		if err := c.managedReserveFees(reservedFees); err != nil {
+			return modules.FormationResult{}, errors.AddContext(err, "unable to reserve the transaction fees")
 		}
 	}
-
-	// Determine the max and min initial contract funding based on the
-	// allowance settings.
-	maxInitialContractFunds := renter.Allowance.Funds.Div64(renter.Allowance.Hosts).Mul64(MaxInitialContractFundingMulFactor).Div64(MaxInitialContractFundingDivFactor)
-	minInitialContractFunds := renter.Allowance.Funds.Div64(renter.Allowance.Hosts).Div64(MinInitialContractFundingDivFactor)
-
-	// Get Hosts.
-	hosts, err := c.hdb.RandomHostsWithLimits(neededContracts * 4 + randomHostsBufferForScore, blacklist, addressBlacklist, renter.Allowance)
-	if err != nil {
-		return nil, err
-	}
-
-	// Calculate the anticipated transaction fee.
-	_, maxFee := c.tpool.FeeEstimation()
-	txnFee := maxFee.Mul64(smodules.EstimatedFileContractTransactionSetSize)
+	defer func() {
+		c.managedReleaseFees(reservedFees)
+	}()
 
 	// Form contracts with the hosts one at a time, until we have enough
 	// contracts.
-	for _, host := range hosts {
+	for _, host := range fp.hosts {
 		// Return here if an interrupt or kill signal has been sent.
 		select {
 		case <-c.tg.StopChan():
-			return nil, errors.New("the manager was stopped")
+			return modules.FormationResult{}, errors.New("the manager was stopped")
 			default:
 		}
 
@@ -1134,76 +1478,129 @@ func (c *Contractor) FormContracts(rpk types.SiaPublicKey) ([]modules.RenterCont
 			break
 		}
 
-		// Calculate the contract funding with the host.
-		contractFunds := host.ContractPrice.Add(txnFee).Mul64(ContractFeeFundingMulFactor)
+		// Stop forming contracts if the request was canceled, keeping the
+		// ones formed so far.
+		if ctx.Err() != nil {
+			c.log.Println("INFO: contract formation canceled, new contracts formed:", len(contractSet)-len(fp.contractSet))
+			break
+		}
 
-		// Check that the contract funding is reasonable compared to the max and
-		// min initial funding. This is to protect against increases to
-		// allowances being used up to fast and not being able to spread the
-		// funds across new contracts properly, as well as protecting against
-		// contracts renewing too quickly.
-		if contractFunds.Cmp(maxInitialContractFunds) > 0 {
-			contractFunds = maxInitialContractFunds
+		// Skip the host if it has already failed during this run.
+		if _, failed := failedHosts[host.PublicKey.String()]; failed {
+			continue
 		}
-		if contractFunds.Cmp(minInitialContractFunds) < 0 {
-			contractFunds = minInitialContractFunds
+
+		// Skip the host if its region is at the cap.
+		var region string
+		if renter.MaxContractsPerRegion > 0 {
+			region = c.managedHostRegion(host)
+			if fp.regionCounts[region] >= renter.MaxContractsPerRegion {
+				continue
+			}
 		}
 
+		// Calculate the contract funding with the host.
+		contractFunds := fp.contractFunding(host)
+
 		// Confirm that the wallet is unlocked.
-		unlocked, err := c.wallet.Unlocked()
-		if !unlocked || err != nil {
-			return nil, errors.New("the wallet is locked")
+		if err := c.managedCheckWallet(); err != nil {
+			return modules.FormationResult{}, err
 		}
 
-		// Determine if we have enough money to form a new contract.
-		if fundsRemaining.Cmp(contractFunds) < 0 {
+		// Determine if we have enough money to form a new contract. The
+		// period spending is re-read, so that the concurrent formations and
+		// renewals are accounted for.
+		fundsRemaining, reserved, err := c.managedReserveSpending(renter, contractFunds)
+		if err != nil {
+			return modules.FormationResult{}, err
+		}
+		if !reserved {
 			registerLowFundsAlert = true
+			lowFundsCause = c.managedLowFundsCause(fundsRemaining, contractFunds)
 			c.log.Println("WARN: need to form new contracts, but unable to because of a low allowance")
 			break
 		}
 
 		// Attempt forming a contract with this host.
 		start := time.Now()
-		fundsSpent, newContract, err := c.managedNewContract(renter.PublicKey, host, contractFunds, endHeight)
+		fundsSpent, newContract, err := form(renter.PublicKey, host, contractFunds, fp.endHeight)
+		if err != nil && !fundsSpent.IsZero() {
+			c.managedReleaseFailedSpending(renter, contractFunds, fundsSpent)
+		} else {
+			c.managedReleaseSpending(renter.PublicKey, contractFunds)
+		}
+		if !fundsSpent.IsZero() {
+			spending = append(spending, c.managedChargeFormation(renter, host, fundsSpent, newContract, err))
+		}
 		if err != nil {
+			failedHosts[host.PublicKey.String()] = struct{}{}
 			c.log.Printf("Attempted to form a contract with %v, time spent %v, but negotiation failed: %v\n", host.NetAddress, time.Since(start).Round(time.Millisecond), err)
+			c.logEvent("WARN", eventContractFormationFailed, types.FileContractID{}, renter.PublicKey, host.PublicKey, "negotiation with %v failed: %v", host.NetAddress, err)
 			continue
 		}
-		fundsRemaining = fundsRemaining.Sub(fundsSpent)
 		neededContracts--
-
-		// Lock the funds in the database.
-		funds, _ := fundsSpent.Float64()
-		hastings, _ := types.SiacoinPrecision.Float64()
-		amount := funds / hastings
-		err = c.satellite.LockSiacoins(renter.Email, amount)
-		if err != nil {
-			c.log.Println("ERROR: couldn't lock funds")
+		if renter.MaxContractsPerRegion > 0 {
+			fp.regionCounts[region]++
 		}
+		reservedFees = reservedFees.Sub(txnFee)
+		c.managedReleaseFees(txnFee)
 
-		// Add this contract to the contractor and save.
+		// Add this contract to the contractor and save. The contractor is
+		// saved even if the utility can't be updated, so that the watchdog
+		// keeps monitoring the contract after a restart.
 		contractSet = append(contractSet, newContract)
-		err = c.managedAcquireAndUpdateContractUtility(newContract.ID, smodules.ContractUtility{
+		modules.ReportFormationProgress(ctx)
+		c.managedRecordFormationScore(newContract.ID, host)
+		utilityErr := c.managedAcquireAndUpdateContractUtility(newContract.ID, smodules.ContractUtility{
 			GoodForUpload: true,
 			GoodForRenew:  true,
-		})
-		if err != nil {
-			c.log.Println("Failed to update the contract utilities", err)
-			continue
-		}
+		}, "formed")
 		c.mu.Lock()
 		err = c.save()
 		c.mu.Unlock()
 		if err != nil {
 			c.log.Println("Unable to save the contractor:", err)
 		}
+		if utilityErr != nil {
+			c.log.Println("Failed to update the contract utilities", utilityErr)
+			continue
+		}
+
+		// Pace the formations, so that the transactions don't hit the
+		// transaction pool all at once.
+		if neededContracts > 0 {
+			if err := c.managedPaceFormation(ctx); err != nil {
+				return modules.FormationResult{}, err
+			}
+		}
 	}
 
-	return contractSet, nil
+	// Check if the renter ended up with too few contracts, unless fewer
+	// contracts were requested on purpose.
+	if !throttled && ctx.Err() == nil {
+		cause := underProvisionedNoHosts
+		if registerLowFundsAlert {
+			cause = underProvisionedLowFunds
+		} else if len(fp.hosts) > 0 {
+			cause = underProvisionedHostsExhausted
+		}
+		c.managedCheckProvisioning(renter, len(contractSet), cause)
+	}
+
+	return modules.FormationResult{
+		Contracts: contractSet,
+		Spending:  spending,
+	}, nil
 }
 
 // RenewContracts tries to renew a given set of contracts.
 func (c *Contractor) RenewContracts(rpk types.SiaPublicKey, contracts []types.FileContractID) ([]modules.RenterContract, error) {
+	return c.managedRenewContracts(rpk, contracts, c.managedRenewContract)
+}
+
+// managedRenewContracts performs the contract renewal of RenewContracts.
+// Each contract is renewed by calling renew.
+func (c *Contractor) managedRenewContracts(rpk types.SiaPublicKey, contracts []types.FileContractID, renew func(fileContractRenewal, types.BlockHeight, types.BlockHeight) (types.Currency, modules.RenterContract, error)) ([]modules.RenterContract, error) {
 	// No contract renewal until the contractor is synced.
 	if !c.managedSynced() {
 		return nil, errors.New("contractor isn't synced yet")
@@ -1213,10 +1610,25 @@ func (c *Contractor) RenewContracts(rpk types.SiaPublicKey, contracts []types.Fi
 	c.mu.RLock()
 	renter, exists := c.renters[rpk.String()]
 	blockHeight := c.blockHeight
+	minRefreshRemaining := c.minRefreshRemainingBlocks
 	c.mu.RUnlock()
 	if !exists {
 		return nil, ErrRenterNotFound
 	}
+	if renter.Paused {
+		return nil, ErrRenterPaused
+	}
+
+	// Check the renter's storage quota.
+	if err := c.managedCheckStorageQuota(renter); err != nil {
+		return nil, err
+	}
+
+	// Apply the end height horizon to the renewed contracts.
+	endHeight, err := c.managedRenewEndHeight(renter, blockHeight)
+	if err != nil {
+		return nil, err
+	}
 
 	// The total number of renews that failed for any reason.
 	var numRenewFails int
@@ -1224,9 +1636,10 @@ func (c *Contractor) RenewContracts(rpk types.SiaPublicKey, contracts []types.Fi
 
 	// Register or unregister and alerts related to contract renewal.
 	var registerLowFundsAlert bool
+	var lowFundsCause string
 	defer func() {
 		if registerLowFundsAlert {
-			c.staticAlerter.RegisterAlert(smodules.AlertIDRenterAllowanceLowFunds, AlertMSGAllowanceLowFunds, AlertCauseInsufficientAllowanceFunds, smodules.SeverityWarning)
+			c.staticAlerter.RegisterAlert(smodules.AlertIDRenterAllowanceLowFunds, AlertMSGAllowanceLowFunds, lowFundsCause, smodules.SeverityWarning)
 		} else {
 			c.staticAlerter.UnregisterAlert(smodules.AlertIDRenterAllowanceLowFunds)
 		}
@@ -1234,7 +1647,6 @@ func (c *Contractor) RenewContracts(rpk types.SiaPublicKey, contracts []types.Fi
 
 	var renewSet []fileContractRenewal
 	var refreshSet []fileContractRenewal
-	var fundsRemaining types.Currency
 
 	// Iterate through the contracts. If the end height is not passed yet, and
 	// if the contract is still GFU, add it to the resulting set.
@@ -1265,23 +1677,6 @@ func (c *Contractor) RenewContracts(rpk types.SiaPublicKey, contracts []types.Fi
 		// allowance does not have enough money, the contractor will prefer to save
 		// data in the long term rather than renew a contract.
 
-		// Depend on the PeriodSpending function to get a breakdown of spending in
-		// the contractor. Then use that to determine how many funds remain
-		// available in the allowance for renewals.
-		spending, err := c.PeriodSpending(renter.PublicKey)
-		if err != nil {
-			// This should only error if the contractor is shutting down.
-			c.log.Println("WARN: error getting period spending:", err)
-			return nil, err
-		}
-
-		// Check for an underflow. This can happen if the user reduced their
-		// allowance at some point to less than what we've already spent.
-		fundsRemaining = renter.Allowance.Funds
-		if spending.TotalAllocated.Cmp(fundsRemaining) < 0 {
-			fundsRemaining = fundsRemaining.Sub(spending.TotalAllocated)
-		}
-
 		// Skip any host that does not match our whitelist/blacklist filter
 		// settings.
 		host, _, err := c.hdb.Host(rc.HostPublicKey)
@@ -1320,6 +1715,7 @@ func (c *Contractor) RenewContracts(rpk types.SiaPublicKey, contracts []types.Fi
 				amount:       renewAmount,
 				renterPubKey: renter.PublicKey,
 				hostPubKey:   rc.HostPublicKey,
+				endHeight:    rc.EndHeight,
 			})
 			c.log.Println("Contract has been added to the renew set for being past the renew height")
 			continue
@@ -1337,6 +1733,19 @@ func (c *Contractor) RenewContracts(rpk types.SiaPublicKey, contracts []types.Fi
 		sectorPrice := sectorStoragePrice.Add(sectorBandwidthPrice)
 		percentRemaining, _ := big.NewRat(0, 1).SetFrac(rc.RenterFunds.Big(), rc.TotalCost.Big()).Float64()
 		if rc.RenterFunds.Cmp(sectorPrice.Mul64(3)) < 0 || percentRemaining < MinContractFundRenewalThreshold {
+			// Don't refresh a contract that the operator has excluded.
+			if c.managedNoRefresh(rc.ID) {
+				c.log.Println("Contract is out of funds but not refreshed, because it is flagged noRefresh:", rc.ID)
+				continue
+			}
+
+			// Don't refresh a contract that reaches its renew height soon.
+			// The renewal for expiry will take care of it.
+			if refreshDeferred(rc.EndHeight, blockHeight, renter.Allowance.RenewWindow, minRefreshRemaining) {
+				c.log.Println("Contract is out of funds but not refreshed, because it is due for renewal soon:", rc.ID)
+				continue
+			}
+
 			// Renew the contract with double the amount of funds that the
 			// contract had previously. The reason that we double the funding
 			// instead of doing anything more clever is that we don't know what
@@ -1348,12 +1757,9 @@ func (c *Contractor) RenewContracts(rpk types.SiaPublicKey, contracts []types.Fi
 			// quickly without consuming too many transaction fees, however this
 			// does mean that a larger percentage of funds get locked away from
 			// the user in the event that the user stops uploading immediately
-			// after the renew.
-			refreshAmount := rc.TotalCost.Mul64(2)
-			minimum := renter.Allowance.Funds.MulFloat(fileContractMinimumFunding).Div64(renter.Allowance.Hosts)
-			if refreshAmount.Cmp(minimum) < 0 {
-				refreshAmount = minimum
-			}
+			// after the renew. The renters can opt for sizing the refresh by
+			// the spend rate of the contract instead.
+			refreshAmount := c.managedRefreshAmount(rc, renter, blockHeight, endHeight)
 			refreshSet = append(refreshSet, fileContractRenewal{
 				id:           rc.ID,
 				amount:       refreshAmount,
@@ -1367,6 +1773,12 @@ func (c *Contractor) RenewContracts(rpk types.SiaPublicKey, contracts []types.Fi
 		c.log.Printf("renewing %v contracts and refreshing %v contracts\n", len(renewSet), len(refreshSet))
 	}
 
+	// Sort the renewSet by the end height, so that the contracts which are
+	// closest to expiring get renewed first if the funds run out.
+	sort.SliceStable(renewSet, func(i, j int) bool {
+		return renewSet[i].endHeight < renewSet[j].endHeight
+	})
+
 	// Go through the contracts we've assembled for renewal. Any contracts that
 	// need to be renewed because they are expiring (renewSet) get priority over
 	// contracts that need to be renewed because they have exhausted their funds
@@ -1381,23 +1793,30 @@ func (c *Contractor) RenewContracts(rpk types.SiaPublicKey, contracts []types.Fi
 		default:
 		}
 
-		unlocked, err := c.wallet.Unlocked()
-		if !unlocked || err != nil {
-			c.log.Println("Contractor is attempting to renew contracts that are about to expire, however the wallet is locked")
+		if err := c.managedCheckWallet(); err != nil {
+			c.log.Println("Contractor is attempting to renew contracts that are about to expire, however the wallet is unavailable:", err)
 			return nil, err
 		}
 
 		// Skip this renewal if we don't have enough funds remaining.
-		if renewal.amount.Cmp(fundsRemaining) > 0 {
+		fundsRemaining, reserved, err := c.managedReserveSpending(renter, renewal.amount)
+		if err != nil {
+			return nil, err
+		}
+		if !reserved {
 			c.log.Println("Skipping renewal because there are not enough funds remaining in the allowance", renewal.id, renewal.amount.HumanString(), fundsRemaining.HumanString())
 			registerLowFundsAlert = true
+			if lowFundsCause == "" {
+				lowFundsCause = c.managedLowFundsCause(fundsRemaining, renewal.amount)
+			}
 			continue
 		}
 
 		// Renew one contract. The error is ignored because the renew function
 		// already will have logged the error, and in the event of an error,
 		// 'fundsSpent' will return '0'.
-		fundsSpent, newContract, err := c.managedRenewContract(renewal, blockHeight, renter.ContractEndHeight())
+		fundsSpent, newContract, err := renew(renewal, blockHeight, endHeight)
+		c.managedReleaseSpending(renter.PublicKey, renewal.amount)
 		if errors.Contains(err, errContractNotGFR) {
 			// Do not add a renewal error.
 			c.log.Println("Contract skipped because it is not good for renew", renewal.id)
@@ -1406,34 +1825,9 @@ func (c *Contractor) RenewContracts(rpk types.SiaPublicKey, contracts []types.Fi
 			renewErr = errors.Compose(renewErr, err)
 			numRenewFails++
 		}
-		fundsRemaining = fundsRemaining.Sub(fundsSpent)
-
 		if err == nil {
-			// Lock the funds in the database.
-			funds, _ := fundsSpent.Float64()
-			hastings, _ := types.SiacoinPrecision.Float64()
-			amount := funds / hastings
-			err = c.satellite.LockSiacoins(renter.Email, amount)
-			if err != nil {
-				c.log.Println("ERROR: couldn't lock funds")
-			}
-
-			// Add this contract to the contractor and save.
 			contractSet = append(contractSet, newContract)
-			err = c.managedAcquireAndUpdateContractUtility(newContract.ID, smodules.ContractUtility{
-				GoodForUpload: true,
-				GoodForRenew:  true,
-			})
-			if err != nil {
-				c.log.Println("Failed to update the contract utilities", err)
-				continue
-			}
-			c.mu.Lock()
-			err = c.save()
-			c.mu.Unlock()
-			if err != nil {
-				c.log.Println("Unable to save the contractor:", err)
-			}
+			c.managedFinalizeRenewal(renter.Email, fundsSpent, newContract)
 		}
 	}
 	for _, renewal := range refreshSet {
@@ -1445,56 +1839,38 @@ func (c *Contractor) RenewContracts(rpk types.SiaPublicKey, contracts []types.Fi
 		default:
 		}
 	
-		unlocked, err := c.wallet.Unlocked()
-		if !unlocked || err != nil {
-			c.log.Println("contractor is attempting to refresh contracts that have run out of funds, however the wallet is locked")
+		if err := c.managedCheckWallet(); err != nil {
+			c.log.Println("contractor is attempting to refresh contracts that have run out of funds, however the wallet is unavailable:", err)
 			return nil, err
 		}
 
 		// Skip this renewal if we don't have enough funds remaining.
-		if renewal.amount.Cmp(fundsRemaining) > 0 {
+		fundsRemaining, reserved, err := c.managedReserveSpending(renter, renewal.amount)
+		if err != nil {
+			return nil, err
+		}
+		if !reserved {
 			c.log.Println("skipping refresh because there are not enough funds remaining in the allowance", renewal.id, renewal.amount.HumanString(), fundsRemaining.HumanString())
 			registerLowFundsAlert = true
+			if lowFundsCause == "" {
+				lowFundsCause = c.managedLowFundsCause(fundsRemaining, renewal.amount)
+			}
 			continue
 		}
 
 		// Renew one contract. The error is ignored because the renew function
 		// already will have logged the error, and in the event of an error,
 		// 'fundsSpent' will return '0'.
-		fundsSpent, newContract, err := c.managedRenewContract(renewal, blockHeight, renter.ContractEndHeight())
+		fundsSpent, newContract, err := renew(renewal, blockHeight, endHeight)
+		c.managedReleaseSpending(renter.PublicKey, renewal.amount)
 		if err != nil {
 			c.log.Println("Error refreshing a contract", renewal.id, err)
 			renewErr = errors.Compose(renewErr, err)
 			numRenewFails++
 		}
-		fundsRemaining = fundsRemaining.Sub(fundsSpent)
-
 		if err == nil {
-			// Lock the funds in the database.
-			funds, _ := fundsSpent.Float64()
-			hastings, _ := types.SiacoinPrecision.Float64()
-			amount := funds / hastings
-			err = c.satellite.LockSiacoins(renter.Email, amount)
-			if err != nil {
-				c.log.Println("ERROR: couldn't lock funds")
-			}
-
-			// Add this contract to the contractor and save.
 			contractSet = append(contractSet, newContract)
-			err = c.managedAcquireAndUpdateContractUtility(newContract.ID, smodules.ContractUtility{
-				GoodForUpload: true,
-				GoodForRenew:  true,
-			})
-			if err != nil {
-				c.log.Println("Failed to update the contract utilities", err)
-				continue
-			}
-			c.mu.Lock()
-			err = c.save()
-			c.mu.Unlock()
-			if err != nil {
-				c.log.Println("Unable to save the contractor:", err)
-			}
+			c.managedFinalizeRenewal(renter.Email, fundsSpent, newContract)
 		}
 	}
 
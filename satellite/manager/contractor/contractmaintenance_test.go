@@ -0,0 +1,129 @@
+package contractor
+
+import (
+	"testing"
+	"time"
+
+	smodules "go.sia.tech/siad/modules"
+	"go.sia.tech/siad/types"
+)
+
+// TestRefreshDeferred tests that a nearly expired, out-of-funds contract
+// is not refreshed, but left to the renewal for expiry.
+func TestRefreshDeferred(t *testing.T) {
+	c, _ := newTestContractor(t)
+	settings := c.ContractorSettings()
+	if settings.MinRefreshRemainingBlocks != defaultMinRefreshRemainingBlocks {
+		t.Fatal("wrong default:", settings.MinRefreshRemainingBlocks)
+	}
+	settings.MinRefreshRemainingBlocks = 144
+	if err := c.SetContractorSettings(settings); err != nil {
+		t.Fatal(err)
+	}
+	minRemaining := c.ContractorSettings().MinRefreshRemainingBlocks
+
+	endHeight, renewWindow := types.BlockHeight(1000), types.BlockHeight(100)
+	tests := []struct {
+		height   types.BlockHeight
+		deferred bool
+	}{
+		{700, false},
+		{756, false},
+		{757, true},
+		{850, true},
+	}
+	for _, test := range tests {
+		if deferred := refreshDeferred(endHeight, test.height, renewWindow, minRemaining); deferred != test.deferred {
+			t.Errorf("height %v: expected deferred %v, got %v", test.height, test.deferred, deferred)
+		}
+	}
+
+	// The deferred contract is picked up by the renewal for expiry once
+	// it reaches its renew height.
+	for height := types.BlockHeight(757); height < endHeight; height++ {
+		if height + renewWindow >= endHeight {
+			return
+		}
+		if !refreshDeferred(endHeight, height, renewWindow, minRemaining) {
+			t.Fatalf("contract refreshed at height %v", height)
+		}
+	}
+	t.Fatal("contract never due for renewal")
+}
+
+// TestPruneRedundantAddressRangeOptOut tests that a contract with a host
+// in the same address range as another is canceled, unless its renter
+// allows redundant IPs.
+func TestPruneRedundantAddressRangeOptOut(t *testing.T) {
+	c, _ := newTestContractor(t)
+	older, younger := testHost(10, "host.example.com:9982"), testHost(11, "host.example.com:9983")
+	younger.LastIPNetChange = older.LastIPNetChange.Add(time.Hour)
+	newTestHostDB(c, older, younger)
+	allowing, pruned := testKey(1), testKey(2)
+	renter := testRenter(c, allowing)
+	renter.AllowRedundantIPs = true
+	c.mu.Lock()
+	c.renters[allowing.String()] = renter
+	c.mu.Unlock()
+	testRenter(c, pruned)
+
+	gfr := smodules.ContractUtility{GoodForUpload: true, GoodForRenew: true}
+	var ids []types.FileContractID
+	for i, rpk := range []types.SiaPublicKey{allowing, pruned} {
+		for j, hpk := range []types.SiaPublicKey{testKey(10), testKey(11)} {
+			contract := testContract(t, c, rpk, hpk, byte(2 * i + j + 1), 0, 1000, types.SiacoinPrecision)
+			setTestUtility(t, c, contract.ID, gfr)
+			ids = append(ids, contract.ID)
+		}
+	}
+
+	c.managedPruneRedundantAddressRange()
+
+	// Only the contract of the second renter with the younger host is
+	// canceled.
+	for i, id := range ids {
+		u, ok := c.managedContractUtility(id)
+		if !ok {
+			t.Fatal("contract not found")
+		}
+		canceled := u.Locked && !u.GoodForRenew && !u.GoodForUpload
+		if canceled != (i == 3) {
+			t.Fatalf("contract %v: expected canceled %v, got %+v", i, i == 3, u)
+		}
+	}
+}
+
+// TestUnchangedUtility tests that a utility update that changes nothing
+// doesn't write to the database, while a changed utility does.
+func TestUnchangedUtility(t *testing.T) {
+	c, fake := newTestContractor(t)
+	rpk := testKey(1)
+	testRenter(c, rpk)
+	contract := testContract(t, c, rpk, testKey(10), 1, 0, 1000, types.SiacoinPrecision)
+	u := smodules.ContractUtility{GoodForUpload: true, GoodForRenew: true}
+	setTestUtility(t, c, contract.ID, u)
+
+	// The same utility is not written again.
+	execs := len(fake.Execs())
+	if err := c.managedAcquireAndUpdateContractUtility(contract.ID, u, "unchanged"); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(fake.Execs()) - execs; n != 0 {
+		t.Fatalf("expected no writes, got %v", n)
+	}
+
+	// A different one is, together with the transition.
+	u.GoodForUpload = false
+	if err := c.managedAcquireAndUpdateContractUtility(contract.ID, u, "changed"); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(fake.Execs()) - execs; n == 0 {
+		t.Fatal("expected the utility to be written")
+	}
+	if n := len(fake.ExecsLike("contract_utility_history")); n != 1 {
+		t.Fatalf("expected 1 transition recorded, got %v", n)
+	}
+	if updated, _ := c.staticContracts.View(contract.ID); updated.Utility != u {
+		t.Fatal("expected the utility to be updated, got", updated.Utility)
+	}
+}
@@ -3,11 +3,14 @@ package contractor
 import (
 	"bytes"
 	"database/sql"
+	"fmt"
 	"io"
+	"math/rand"
 	"os"
 	"path/filepath"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/mike76-dev/sia-satellite/modules"
 	"github.com/mike76-dev/sia-satellite/satellite/manager/proto"
@@ -42,6 +45,10 @@ type Contractor struct {
 	satellite     modules.FundLocker
 	log           *persist.Logger
 	mu            sync.RWMutex
+
+	// staticEventLog is set if the contract lifecycle events are to be
+	// logged in the JSON format.
+	staticEventLog *eventLogger
 	persistDir    string
 	staticAlerter *smodules.GenericAlerter
 	tg            threadgroup.ThreadGroup
@@ -56,12 +63,121 @@ type Contractor struct {
 	synced        chan struct{}
 	lastChange    smodules.ConsensusChangeID
 
+	// syncedStreak counts the consecutive synced consensus changes until
+	// the startup grace is over.
+	syncedStreak int
+	gracePassed  bool
+
 	renters       map[string]modules.Renter
 
 	sessions        map[types.FileContractID]*hostSession
 	numFailedRenews map[types.FileContractID]types.BlockHeight
 	renewing        map[types.FileContractID]bool // Prevent revising during renewal.
 
+	// hostSelectionRNG makes the host selection deterministic if set.
+	// hostSelectionSeed is the seed it was initialized with, zero means
+	// random selection.
+	hostSelectionRNG      *rand.Rand
+	hostSelectionSeed     int64
+	nextHostSelectionSeed int64
+
+	// minimumFunding is the lowest fraction of an allowance (on a
+	// per-contract basis) that is allowed to go into funding a contract.
+	minimumFunding float64
+
+	// initialFunding holds the factors that determine the initial funding
+	// of new contracts.
+	initialFunding InitialFundingFactors
+
+	// hostOversample is the number of candidate hosts fetched per needed
+	// contract during the contract formation.
+	hostOversample int
+
+	// endHeightHorizon caps how far in the future a formed contract may
+	// end. clampEndHeight determines if the end height is clamped to it or
+	// the formation is refused.
+	endHeightHorizon types.BlockHeight
+	clampEndHeight   bool
+
+	// maxStoragePrice and maxCollateral are the price ceilings applied to
+	// all hosts. scoreLeewayGFR and scoreLeewayGFU are the factors by which
+	// a host may miss the goal score and still be GoodForRenew or
+	// GoodForUpload, respectively.
+	maxStoragePrice types.Currency
+	maxCollateral   types.Currency
+	scoreLeewayGFR  types.Currency
+	scoreLeewayGFU  types.Currency
+
+	// maxContractsPerHost limits the number of the active contracts with
+	// any one host across all renters. Zero means no limit. If
+	// churnExcessHostContracts is set, the contracts over the limit are
+	// churned during the contract maintenance.
+	maxContractsPerHost      int
+	churnExcessHostContracts bool
+
+	// diversityWeight is the largest fraction by which the minimum scores
+	// of a host are lowered for the region diversity it adds. Zero
+	// disables the adjustment.
+	diversityWeight float64
+
+	// minRefreshRemainingBlocks is the minimum number of blocks left until
+	// the renew height for an out-of-funds contract to be refreshed.
+	minRefreshRemainingBlocks types.BlockHeight
+
+	// maxConcurrentRenewals is the maximum number of renewals, across all
+	// renters, that can run at the same time.
+	maxConcurrentRenewals int
+
+	// formationDelay is the pause between two successful contract
+	// formations, which smooths the load on the transaction pool.
+	// formationJitter is the upper bound of a random delay added to it.
+	formationDelay  time.Duration
+	formationJitter time.Duration
+
+	// gfuChurnCooldown is the number of blocks a host marked !GoodForUpload
+	// by the GFU limiter stays out before it can be GoodForUpload again.
+	// Zero disables the cooldown.
+	gfuChurnCooldown types.BlockHeight
+
+	// tpoolCongestionThreshold is the number of transactions in the
+	// transaction pool above which the pool is considered congested. Zero
+	// disables the check.
+	tpoolCongestionThreshold int
+
+	// minProvisionedFraction is the share of the allowance hosts that a
+	// renter needs GoodForUpload contracts with after a contract
+	// formation. Below it, a critical alert is registered.
+	minProvisionedFraction float64
+
+	// maxRenewHistoryDepth is the maximum number of the previous contracts
+	// followed when summing up the spending of a contract line. It guards
+	// against an [impossible] contract cycle.
+	maxRenewHistoryDepth int
+
+	// gfuStoredDataWeight is the weight of the stored data, relative to
+	// the host score, when deciding which contracts stay GoodForUpload if
+	// a renter has more of them than needed. It ranges from 0 (score only)
+	// to 1 (stored data only).
+	gfuStoredDataWeight float64
+
+	// canceledContractRetention is the number of blocks a canceled
+	// contract stays in the active contract set before it is archived.
+	// Zero disables the archiving of canceled contracts.
+	canceledContractRetention types.BlockHeight
+
+	// syncGraceChanges is the number of consecutive synced consensus
+	// changes required after startup before the first contract
+	// maintenance runs. This keeps the maintenance from acting on a height
+	// that is still flapping, e.g. during a reorg. Zero or one disables the
+	// grace.
+	syncGraceChanges int
+
+	// hostDBBreakerThreshold is the number of consecutive hostdb failures
+	// after which the hostdb-dependent maintenance phases are paused for
+	// hostDBBreakerCooldown. Both are mirrored in staticHostDBBreaker.
+	hostDBBreakerThreshold int
+	hostDBBreakerCooldown  time.Duration
+
 	// pubKeysToContractID is a map of renter and host pubkeys to the latest contract ID
 	// that is formed with the host. The contract also has to have an end height
 	// in the future.
@@ -70,14 +186,91 @@ type Contractor struct {
 	// renewedFrom links the new contract's ID to the old contract's ID
 	// renewedTo links the old contract's ID to the new contract's ID
 	// doubleSpentContracts keep track of all contracts that were double spent by
-	// either the renter or host.
+	// either the renter or host. It is written while processing a consensus
+	// change and must only be accessed while holding mu.
 	staticContracts      *proto.ContractSet
 	oldContracts         map[types.FileContractID]modules.RenterContract
 	doubleSpentContracts map[types.FileContractID]types.BlockHeight
 	renewedFrom          map[types.FileContractID]types.FileContractID
 	renewedTo            map[types.FileContractID]types.FileContractID
 
+	// overAllocated keeps track of the funds to be released as the
+	// contracts expire, after the renter's allowance was reduced below
+	// what had already been allocated.
+	overAllocated map[string]types.Currency
+
+	// payouts keeps track of the expired contracts whose renter payout
+	// has been recorded.
+	payouts map[types.FileContractID]struct{}
+
+	// noRefresh keeps track of the contracts that are not refreshed when
+	// they run out of funds. They are still renewed at expiry.
+	noRefresh map[types.FileContractID]struct{}
+
+	// canceledAt keeps the heights at which the contracts were canceled.
+	canceledAt map[types.FileContractID]types.BlockHeight
+
+	// formationScores keeps the host scores of the contracts at the time
+	// they were formed.
+	formationScores map[types.FileContractID]types.Currency
+
+	// hostAllowlists keeps the hosts that the renters' contracts are
+	// formed with, if the renter has restricted them.
+	hostAllowlists map[string][]types.SiaPublicKey
+
+	// lastMaintenance holds the phase timings of the last maintenance
+	// cycle.
+	lastMaintenance modules.MaintenanceTimings
+
+	// gfuCooldowns keeps track of the hosts marked !GoodForUpload by the
+	// GFU limiter, keyed by the renter and the host public keys, and the
+	// heights until which they stay out.
+	gfuCooldowns map[string]types.BlockHeight
+
+	// reservedAddresses are the wallet addresses reserved for the refund
+	// outputs of the formations and renewals in progress.
+	reservedAddresses map[types.UnlockHash]struct{}
+
 	staticWatchdog *watchdog
+
+	staticHostDBBreaker *hostDBBreaker
+
+	staticFeeReserve *feeReserve
+
+	// staticSpendReserve keeps track of the allowance funds set aside for
+	// the ongoing formations and renewals.
+	staticSpendReserve *spendReserve
+
+	// renewalSlots limits the number of concurrent renewals to
+	// maxConcurrentRenewals. It is replaced when the limit changes, the
+	// renewals in progress release the slots of the channel they took them
+	// from.
+	renewalSlots chan struct{}
+
+	// regionResolver determines the host regions for the region cap.
+	regionResolver regionResolver
+
+	// latencyProber measures the host response times for the latency
+	// bound.
+	latencyProber LatencyProber
+
+	// sessionDialer opens the RPC sessions with the hosts.
+	sessionDialer sessionDialer
+
+	// staticSessionPool shares the sessions with the hosts during renewals.
+	staticSessionPool *sessionPool
+
+	// staticSettingsCache shares the recently fetched host settings
+	// between renewals.
+	staticSettingsCache *settingsCache
+
+	// staticScoreCache shares the host scores between the phases of a
+	// maintenance cycle.
+	staticScoreCache *scoreCache
+
+	// walletRetries are the operations with a retry pending after they
+	// failed because of a locked wallet.
+	walletRetries map[string]struct{}
 }
 
 // PaymentDetails is a helper struct that contains extra information on a
@@ -363,11 +556,13 @@ func (c *Contractor) Synced() <-chan struct{} {
 
 // Close closes the Contractor.
 func (c *Contractor) Close() error {
+	// Stopping the threadgroup waits for the running threads and then
+	// performs a final save, the error of which is returned.
 	return c.tg.Stop()
 }
 
 // New returns a new Contractor.
-func New(cs smodules.ConsensusSet, wallet smodules.Wallet, tpool smodules.TransactionPool, hdb modules.HostDB, db *sql.DB, persistDir string) (*Contractor, <-chan error) {
+func New(cs smodules.ConsensusSet, wallet smodules.Wallet, tpool smodules.TransactionPool, hdb modules.HostDB, db *sql.DB, persistDir string, logFormat string) (*Contractor, <-chan error) {
 	errChan := make(chan error, 1)
 	defer close(errChan)
 	// Check for nil inputs.
@@ -400,6 +595,20 @@ func New(cs smodules.ConsensusSet, wallet smodules.Wallet, tpool smodules.Transa
 		errChan <- err
 		return nil, errChan
 	}
+	// Create the event logger if requested.
+	var eventLog *eventLogger
+	switch logFormat {
+	case "", LogFormatText:
+	case LogFormatJSON:
+		eventLog, err = newEventLogger(persistDir)
+		if err != nil {
+			errChan <- err
+			return nil, errChan
+		}
+	default:
+		errChan <- fmt.Errorf("unknown log format %q", logFormat)
+		return nil, errChan
+	}
 	// Create the contract set.
 	contractSet, err := proto.NewContractSet(db, logger)
 	if err != nil {
@@ -408,7 +617,7 @@ func New(cs smodules.ConsensusSet, wallet smodules.Wallet, tpool smodules.Transa
 	}
 
 	// Handle blocking startup.
-	c, err := contractorBlockingStartup(cs, wallet, tpool, hdb, persistDir, contractSet, db, logger)
+	c, err := contractorBlockingStartup(cs, wallet, tpool, hdb, persistDir, contractSet, db, logger, eventLog)
 	if err != nil {
 		errChan <- err
 		return nil, errChan
@@ -431,8 +640,8 @@ func New(cs smodules.ConsensusSet, wallet smodules.Wallet, tpool smodules.Transa
 	return c, errChan
 }
 
-// contractorBlockingStartup handles the blocking portion of New.
-func contractorBlockingStartup(cs smodules.ConsensusSet, w smodules.Wallet, tp smodules.TransactionPool, hdb modules.HostDB, persistDir string, contractSet *proto.ContractSet, db *sql.DB, l *persist.Logger) (*Contractor, error) {
+// newContractor creates the Contractor object with its defaults.
+func newContractor(cs smodules.ConsensusSet, w smodules.Wallet, tp smodules.TransactionPool, hdb modules.HostDB, persistDir string, contractSet *proto.ContractSet, db *sql.DB, l *persist.Logger, el *eventLogger) *Contractor {
 	// Create the Contractor object.
 	c := &Contractor{
 		staticAlerter: smodules.NewAlerter("contractor"),
@@ -441,6 +650,8 @@ func contractorBlockingStartup(cs smodules.ConsensusSet, w smodules.Wallet, tp s
 		hdb:           hdb,
 		log:           l,
 		persistDir:    persistDir,
+
+		staticEventLog: el,
 		tpool:         tp,
 		wallet:        w,
 
@@ -451,16 +662,68 @@ func contractorBlockingStartup(cs smodules.ConsensusSet, w smodules.Wallet, tp s
 
 		staticContracts:      contractSet,
 		sessions:             make(map[types.FileContractID]*hostSession),
+		numFailedRenews:      make(map[types.FileContractID]types.BlockHeight),
+		pubKeysToContractID:  make(map[string]types.FileContractID),
 		oldContracts:         make(map[types.FileContractID]modules.RenterContract),
 		doubleSpentContracts: make(map[types.FileContractID]types.BlockHeight),
 		renewing:             make(map[types.FileContractID]bool),
 		renewedFrom:          make(map[types.FileContractID]types.FileContractID),
 		renewedTo:            make(map[types.FileContractID]types.FileContractID),
+		overAllocated:        make(map[string]types.Currency),
+		payouts:              make(map[types.FileContractID]struct{}),
+		noRefresh:            make(map[types.FileContractID]struct{}),
+		canceledAt:           make(map[types.FileContractID]types.BlockHeight),
+		formationScores:      make(map[types.FileContractID]types.Currency),
+		hostAllowlists:       make(map[string][]types.SiaPublicKey),
+		gfuCooldowns:         make(map[string]types.BlockHeight),
+		reservedAddresses:    make(map[types.UnlockHash]struct{}),
+		walletRetries:        make(map[string]struct{}),
+		regionResolver:       tldResolver{},
+		latencyProber:        settingsProber{},
+		minimumFunding:       fileContractMinimumFunding,
+		initialFunding:       defaultInitialFundingFactors(),
+		hostOversample:       defaultHostOversample,
+		maxStoragePrice:      maxStoragePrice,
+		maxCollateral:        maxCollateral,
+		scoreLeewayGFR:       scoreLeewayGoodForRenew,
+		scoreLeewayGFU:       scoreLeewayGoodForUpload,
+
+		minRefreshRemainingBlocks: defaultMinRefreshRemainingBlocks,
+		maxConcurrentRenewals:     defaultMaxConcurrentRenewals,
+		gfuChurnCooldown:          defaultGFUChurnCooldown,
+		tpoolCongestionThreshold:  defaultTpoolCongestionThreshold,
+		minProvisionedFraction:    defaultMinProvisionedFraction,
+		maxRenewHistoryDepth:      defaultMaxRenewHistoryDepth,
+		gfuStoredDataWeight:       defaultGFUStoredDataWeight,
+		canceledContractRetention: defaultCanceledContractRetention,
+		syncGraceChanges:          defaultSyncGraceChanges,
+		hostDBBreakerThreshold:    defaultHostDBBreakerThreshold,
+		hostDBBreakerCooldown:     defaultHostDBBreakerCooldown,
+		renewalSlots:              make(chan struct{}, defaultMaxConcurrentRenewals),
 	}
 	c.staticWatchdog = newWatchdog(c)
+	c.staticHostDBBreaker = newHostDBBreaker(c.hostDBBreakerThreshold, c.hostDBBreakerCooldown)
+	c.staticFeeReserve = &feeReserve{}
+	c.staticSpendReserve = newSpendReserve()
+	c.sessionDialer = contractSetDialer{c}
+	c.staticSessionPool = newSessionPool()
+	c.staticScoreCache = newScoreCache()
+	c.staticSettingsCache = newSettingsCache()
+
+	return c
+}
 
-	// Close the logger upon shutdown.
+// contractorBlockingStartup handles the blocking portion of New.
+func contractorBlockingStartup(cs smodules.ConsensusSet, w smodules.Wallet, tp smodules.TransactionPool, hdb modules.HostDB, persistDir string, contractSet *proto.ContractSet, db *sql.DB, l *persist.Logger, el *eventLogger) (*Contractor, error) {
+	c := newContractor(cs, w, tp, hdb, persistDir, contractSet, db, l, el)
+
+	// Close the loggers upon shutdown.
 	err := c.tg.AfterStop(func() error {
+		if c.staticEventLog != nil {
+			if err := c.staticEventLog.Close(); err != nil {
+				return errors.AddContext(err, "failed to close the contractor event log")
+			}
+		}
 		if err := c.log.Close(); err != nil {
 			return errors.AddContext(err, "failed to close the contractor logger")
 		}
@@ -476,12 +739,29 @@ func contractorBlockingStartup(cs smodules.ConsensusSet, w smodules.Wallet, tp s
 		return nil, err
 	}
 
-	// Spin up a goroutine to periodically save the Contractor.
-	go c.threadedSaveLoop()
+	// Save the final state upon shutdown, once all threads have returned.
+	// This runs before the loggers are closed.
+	err = c.tg.AfterStop(func() error {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if err := c.save(); err != nil {
+			c.log.Println("ERROR: final save of the contractor failed:", err)
+			return errors.AddContext(err, "failed to save the contractor on shutdown")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
 
-	// Update the pubkeysToContractID map.
+	// Rebuild the pubkeysToContractID map from the loaded contract set
+	// before anything else can use it, so that the lookups by the renter
+	// and host keys are correct right after startup.
 	c.managedUpdatePubKeysToContractIDMap()
 
+	// Spin up a goroutine to periodically save the Contractor.
+	go c.threadedSaveLoop()
+
 	// Unsubscribe from the consensus set upon shutdown.
 	err = c.tg.OnStop(func() error {
 		cs.Unsubscribe(c)
@@ -491,6 +771,15 @@ func contractorBlockingStartup(cs smodules.ConsensusSet, w smodules.Wallet, tp s
 		return nil, err
 	}
 
+	// Release the pooled sessions upon shutdown.
+	err = c.tg.OnStop(func() error {
+		c.staticSessionPool.close()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
 	// We may have resubscribed. Save now so that we don't lose our work.
 	c.mu.Lock()
 	err = c.save()
@@ -520,6 +809,14 @@ func contractorAsyncStartup(c *Contractor, cs smodules.ConsensusSet) error {
 	return nil
 }
 
+// managedSyncGracePassed returns true if the consensus has stayed synced
+// for long enough after startup to run the contract maintenance.
+func (c *Contractor) managedSyncGracePassed() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.gracePassed
+}
+
 // managedSynced returns true if the contractor is synced with the consensusset.
 func (c *Contractor) managedSynced() bool {
 	c.mu.RLock()
@@ -564,15 +861,59 @@ func (c *Contractor) GetRenter(rpk types.SiaPublicKey) (modules.Renter, error) {
 }
 
 // CreateNewRenter inserts a new renter into the map.
-func (c *Contractor) CreateNewRenter(email string, pk types.SiaPublicKey) {
+func (c *Contractor) CreateNewRenter(email, suffix string, pk types.SiaPublicKey) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	c.renters[pk.String()] = modules.Renter{
 		Email:     email,
+		Suffix:    suffix,
 		PublicKey: pk,
 	}
 }
 
+// AddRenter creates a new renter with the initial allowance, both in the
+// database and in memory. The current period starts at the current block
+// height.
+func (c *Contractor) AddRenter(email string, pk types.SiaPublicKey, a smodules.Allowance) (modules.Renter, error) {
+	if err := checkAllowance(a); err != nil {
+		return modules.Renter{}, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, exists := c.renters[pk.String()]; exists {
+		return modules.Renter{}, modules.ErrRenterExists
+	}
+	for _, renter := range c.renters {
+		if renter.Email == email && renter.Suffix == "" {
+			return modules.Renter{}, modules.ErrRenterExists
+		}
+	}
+
+	renter := modules.Renter{
+		Allowance:     a,
+		CurrentPeriod: c.blockHeight,
+		PublicKey:     pk,
+		Email:         email,
+	}
+	if err := c.insertRenter(renter); err != nil {
+		return modules.Renter{}, errors.AddContext(err, "unable to create the renter record")
+	}
+	c.renters[pk.String()] = renter
+
+	return renter, nil
+}
+
+// DeleteRenter removes the renter from memory after its database record
+// has been deleted. The renter's contracts are kept, but they won't be
+// renewed anymore.
+func (c *Contractor) DeleteRenter(rpk types.SiaPublicKey) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.renters, rpk.String())
+	delete(c.hostAllowlists, rpk.String())
+}
+
 // Renters returns the list of renters.
 func (c *Contractor) Renters() []modules.Renter {
 	c.mu.Lock()
@@ -584,6 +925,21 @@ func (c *Contractor) Renters() []modules.Renter {
 	return renters
 }
 
+// SetAllowRedundantIPs sets whether the renter's contracts with the hosts
+// sharing an address range are kept.
+func (c *Contractor) SetAllowRedundantIPs(rpk types.SiaPublicKey, allow bool) error {
+	c.mu.Lock()
+	renter, exists := c.renters[rpk.String()]
+	if !exists {
+		c.mu.Unlock()
+		return ErrRenterNotFound
+	}
+	renter.AllowRedundantIPs = allow
+	c.renters[rpk.String()] = renter
+	c.mu.Unlock()
+	return c.UpdateRenter(renter)
+}
+
 // SetSatellite sets the satellite dependency.
 func (c *Contractor) SetSatellite(fl modules.FundLocker) {
 	c.satellite = fl
@@ -0,0 +1,97 @@
+package contractor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	smodules "go.sia.tech/siad/modules"
+	"go.sia.tech/siad/persist"
+	"go.sia.tech/siad/types"
+)
+
+// testCS is a consensus set that the contractor can unsubscribe from. The
+// methods that are not overridden panic.
+type testCS struct {
+	smodules.ConsensusSet
+}
+
+// Unsubscribe implements smodules.ConsensusSet.
+func (testCS) Unsubscribe(smodules.ConsensusSetSubscriber) {}
+
+// TestStartupPubKeysMap tests that after a restart, a formed contract is
+// resolvable by its key pair before any maintenance runs.
+func TestStartupPubKeysMap(t *testing.T) {
+	c, _ := newTestContractor(t)
+	rpk, hpk := testKey(1), testKey(10)
+	testRenter(c, rpk)
+	contract := testContract(t, c, rpk, hpk, 1, 0, 1000, types.SiacoinPrecision)
+	c.mu.Lock()
+	err := c.save()
+	c.mu.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Simulate a restart. The restarted contractor closes its own logger.
+	logger, err := persist.NewFileLogger(filepath.Join(t.TempDir(), "contractor.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	restarted, err := contractorBlockingStartup(testCS{}, nil, nil, nil, c.persistDir, c.staticContracts, c.db, logger, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer restarted.tg.Stop()
+	rc, ok := restarted.ContractByPublicKeys(rpk, hpk)
+	if !ok {
+		t.Fatal("contract not resolvable after the restart")
+	}
+	if rc.ID != contract.ID {
+		t.Fatalf("expected contract %v, got %v", contract.ID, rc.ID)
+	}
+	if _, ok := restarted.ContractByPublicKeys(testKey(2), hpk); ok {
+		t.Fatal("contract resolvable by a wrong renter key")
+	}
+}
+
+// TestCloseSave tests that closing the contractor persists its final state,
+// and that a failed final save is reported.
+func TestCloseSave(t *testing.T) {
+	startup := func() *Contractor {
+		c, _ := newTestContractor(t)
+		logger, err := persist.NewFileLogger(filepath.Join(t.TempDir(), "contractor.log"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		c, err = contractorBlockingStartup(testCS{}, nil, nil, nil, c.persistDir, c.staticContracts, c.db, logger, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+
+	c := startup()
+	c.mu.Lock()
+	c.blockHeight = 1234
+	c.mu.Unlock()
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+	var data contractorPersist
+	if err := persist.LoadJSON(persistMeta, &data, filepath.Join(c.persistDir, PersistFilename)); err != nil {
+		t.Fatal(err)
+	}
+	if data.BlockHeight != 1234 {
+		t.Fatalf("expected the block height 1234 to be persisted, got %v", data.BlockHeight)
+	}
+
+	// The final save fails if the persist dir is gone.
+	c = startup()
+	if err := os.RemoveAll(c.persistDir); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Close(); err == nil {
+		t.Fatal("expected the final save to fail")
+	}
+}
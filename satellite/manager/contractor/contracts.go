@@ -1,6 +1,8 @@
 package contractor
 
 import (
+	"sort"
+
 	"github.com/mike76-dev/sia-satellite/satellite/manager/proto"
 	"github.com/mike76-dev/sia-satellite/modules"
 
@@ -14,11 +16,34 @@ import (
 // false and locking the utilities. The contract can still be used for
 // downloads after this but it won't be used for uploads or renewals.
 func (c *Contractor) managedCancelContract(cid types.FileContractID) error {
-	return c.managedAcquireAndUpdateContractUtility(cid, smodules.ContractUtility{
+	err := c.managedAcquireAndUpdateContractUtility(cid, smodules.ContractUtility{
 		GoodForRenew:  false,
 		GoodForUpload: false,
 		Locked:        true,
-	})
+	}, "canceled")
+	if err == nil {
+		c.managedRecordCancellation(cid)
+		if contract, ok := c.staticContracts.View(cid); ok {
+			c.logEvent("INFO", eventContractCanceled, cid, contract.RenterPublicKey, contract.HostPublicKey, "contract canceled")
+		}
+	}
+	return err
+}
+
+// managedCanceledLongAgo returns true if the contract was canceled at least
+// the retention period ago. Only the contracts canceled with
+// managedCancelContract count. The contracts locked otherwise, e.g. because
+// the allowance was canceled, may still be unlocked and are kept.
+func (c *Contractor) managedCanceledLongAgo(contract modules.RenterContract, currentHeight types.BlockHeight) bool {
+	u := contract.Utility
+	if !u.Locked || u.GoodForUpload || u.GoodForRenew {
+		return false
+	}
+	c.mu.RLock()
+	retention := c.canceledContractRetention
+	canceledAt, canceled := c.canceledAt[contract.ID]
+	c.mu.RUnlock()
+	return retention > 0 && canceled && currentHeight >= canceledAt + retention
 }
 
 // managedContractByPublicKey returns the contract with the key specified, if
@@ -132,6 +157,20 @@ func (c *Contractor) Contracts() []modules.RenterContract {
 	return c.staticContracts.ViewAll()
 }
 
+// RenterContracts returns the contracts of the renter that are currently
+// held by the contractor. Canceled contracts are not returned.
+func (c *Contractor) RenterContracts(rpk types.SiaPublicKey) []modules.RenterContract {
+	var contracts []modules.RenterContract
+	for _, contract := range c.staticContracts.ByRenter(rpk) {
+		u := contract.Utility
+		if u.Locked && !u.GoodForRenew && !u.GoodForUpload {
+			continue
+		}
+		contracts = append(contracts, contract)
+	}
+	return contracts
+}
+
 // ContractUtility returns the utility fields for the given contract.
 func (c *Contractor) ContractUtility(rpk, hpk types.SiaPublicKey) (smodules.ContractUtility, bool) {
 	c.mu.RLock()
@@ -170,12 +209,126 @@ func (c *Contractor) OldContracts() []modules.RenterContract {
 	return contracts
 }
 
+// ContractLineage returns the renewal chain of the contract, from the
+// originally formed contract to the latest renewal.
+func (c *Contractor) ContractLineage(id types.FileContractID) ([]modules.ContractLineageLink, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	// Walk back to the original contract.
+	first := id
+	for i := 0; i < 10e3; i++ { // Prevent an infinite loop if there's an [impossible] contract cycle.
+		prev, exists := c.renewedFrom[first]
+		if !exists {
+			break
+		}
+		first = prev
+	}
+
+	// Walk forward to the latest renewal.
+	var lineage []modules.ContractLineageLink
+	current := first
+	for i := 0; i < 10e3; i++ {
+		link := modules.ContractLineageLink{ID: current}
+		if contract, exists := c.staticContracts.View(current); exists {
+			link.StartHeight = contract.StartHeight
+			link.EndHeight = contract.EndHeight
+		} else if contract, exists := c.oldContracts[current]; exists {
+			link.StartHeight = contract.StartHeight
+			link.EndHeight = contract.EndHeight
+		}
+		lineage = append(lineage, link)
+		next, exists := c.renewedTo[current]
+		if !exists {
+			break
+		}
+		current = next
+	}
+
+	// Make sure that the contract is known at all.
+	if len(lineage) == 1 && lineage[0].StartHeight == 0 && lineage[0].EndHeight == 0 {
+		return nil, errors.New("contract not found")
+	}
+
+	return lineage, nil
+}
+
+// ContractUtilityHistory returns the utility transitions of the contract,
+// oldest first.
+func (c *Contractor) ContractUtilityHistory(id types.FileContractID) ([]modules.UtilityTransition, error) {
+	return c.loadUtilityHistory(id)
+}
+
+// WatchdogStatus reports the number of the contracts monitored by the
+// watchdog and the contracts that were double-spent.
+func (c *Contractor) WatchdogStatus() modules.WatchdogStatus {
+	monitored, archived := c.staticWatchdog.callStatus()
+	status := modules.WatchdogStatus{
+		MonitoredContracts: monitored,
+		ArchivedContracts:  archived,
+	}
+	c.mu.RLock()
+	for fcid, height := range c.doubleSpentContracts {
+		status.DoubleSpentContracts = append(status.DoubleSpentContracts, modules.DoubleSpentContract{
+			ID:     fcid,
+			Height: height,
+		})
+	}
+	c.mu.RUnlock()
+	sort.Slice(status.DoubleSpentContracts, func(i, j int) bool {
+		return status.DoubleSpentContracts[i].Height < status.DoubleSpentContracts[j].Height
+	})
+	return status
+}
+
+// FundsAtRisk sums up the renter funds remaining in the contracts that were
+// double-spent or are not good for renew anymore.
+func (c *Contractor) FundsAtRisk() modules.FundsAtRisk {
+	var far modules.FundsAtRisk
+	contracts := c.staticContracts.ViewAll()
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, contract := range contracts {
+		if _, doubleSpent := c.doubleSpentContracts[contract.ID]; doubleSpent {
+			far.DoubleSpent = far.DoubleSpent.Add(contract.RenterFunds)
+		} else if !contract.Utility.GoodForRenew {
+			far.NotGoodForRenew = far.NotGoodForRenew.Add(contract.RenterFunds)
+		} else {
+			continue
+		}
+		far.Total = far.Total.Add(contract.RenterFunds)
+		far.Contracts++
+	}
+	return far
+}
+
+// RenewFailures returns the contracts whose renewals have failed, together
+// with the number of the consecutive failures, most failures first.
+func (c *Contractor) RenewFailures() []modules.RenewFailure {
+	c.mu.RLock()
+	failures := make([]modules.RenewFailure, 0, len(c.numFailedRenews))
+	for fcid, n := range c.numFailedRenews {
+		if n == 0 {
+			continue
+		}
+		failures = append(failures, modules.RenewFailure{
+			ID:       fcid,
+			Failures: n,
+		})
+	}
+	c.mu.RUnlock()
+	sort.Slice(failures, func(i, j int) bool {
+		return failures[i].Failures > failures[j].Failures
+	})
+	return failures
+}
+
 // managedMarkContractBad marks an already acquired SafeContract as bad.
 func (c *Contractor) managedMarkContractBad(fc *proto.FileContract) error {
 	u := fc.Utility()
 	u.GoodForUpload = false
 	u.GoodForRenew = false
 	u.BadContract = true
-	err := c.callUpdateUtility(fc, u, false)
+	err := c.callUpdateUtility(fc, u, false, "marked as bad")
 	return errors.AddContext(err, "unable to mark contract as bad")
 }
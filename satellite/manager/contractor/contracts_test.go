@@ -0,0 +1,99 @@
+package contractor
+
+import (
+	"testing"
+
+	smodules "go.sia.tech/siad/modules"
+	"go.sia.tech/siad/types"
+)
+
+// TestRenewFailures tests that the contracts with failed renewals are
+// reported with their failure counts, most failures first.
+func TestRenewFailures(t *testing.T) {
+	c, _ := newTestContractor(t)
+	if failures := c.RenewFailures(); len(failures) != 0 {
+		t.Fatal("expected no failures, got", failures)
+	}
+
+	counts := map[byte]types.BlockHeight{1: 2, 2: 0, 3: 5, 4: 1}
+	c.mu.Lock()
+	for b, n := range counts {
+		var fcid types.FileContractID
+		fcid[0] = b
+		c.numFailedRenews[fcid] = n
+	}
+	c.mu.Unlock()
+
+	failures := c.RenewFailures()
+	expected := []byte{3, 1, 4}
+	if len(failures) != len(expected) {
+		t.Fatalf("expected %v failures, got %v", len(expected), len(failures))
+	}
+	for i, b := range expected {
+		if failures[i].ID[0] != b || failures[i].Failures != counts[b] {
+			t.Fatalf("expected contract %v with %v failures at position %v, got %+v", b, counts[b], i, failures[i])
+		}
+	}
+}
+
+// TestFundsAtRisk tests that the renter funds in the double-spent and the
+// not-GFR contracts are summed up by the reason, and that the healthy
+// contracts are not counted.
+func TestFundsAtRisk(t *testing.T) {
+	c, _ := newTestContractor(t)
+	rpk := testKey(1)
+	testRenter(c, rpk)
+	add := func(id byte, funds uint64, gfr, doubleSpent bool) {
+		contract := testContract(t, c, rpk, testKey(10 + id), id, 0, 1000, types.SiacoinPrecision.Mul64(funds))
+		setTestUtility(t, c, contract.ID, smodules.ContractUtility{GoodForUpload: gfr, GoodForRenew: gfr})
+		if doubleSpent {
+			c.mu.Lock()
+			c.doubleSpentContracts[contract.ID] = 100
+			c.mu.Unlock()
+		}
+	}
+	add(1, 5, true, false)
+	add(2, 7, false, false)
+	add(3, 11, true, true)
+	add(4, 13, false, true)
+
+	far := c.FundsAtRisk()
+	sc := types.SiacoinPrecision.Mul64
+	if !far.DoubleSpent.Equals(sc(24)) {
+		t.Fatalf("expected %v double-spent, got %v", sc(24), far.DoubleSpent)
+	}
+	if !far.NotGoodForRenew.Equals(sc(7)) {
+		t.Fatalf("expected %v not GFR, got %v", sc(7), far.NotGoodForRenew)
+	}
+	if !far.Total.Equals(sc(31)) || far.Contracts != 3 {
+		t.Fatalf("expected %v in 3 contracts, got %v in %v", sc(31), far.Total, far.Contracts)
+	}
+}
+
+// TestRenterContracts tests that the renter's contracts are returned
+// without the canceled ones and the contracts of other renters.
+func TestRenterContracts(t *testing.T) {
+	c, _ := newTestContractor(t)
+	rpk, other := testKey(1), testKey(2)
+	testRenter(c, rpk)
+	testRenter(c, other)
+	active := testContract(t, c, rpk, testKey(10), 1, 0, 1000, types.SiacoinPrecision)
+	setTestUtility(t, c, active.ID, smodules.ContractUtility{GoodForUpload: true, GoodForRenew: true})
+	passive := testContract(t, c, rpk, testKey(11), 2, 0, 1000, types.SiacoinPrecision)
+	setTestUtility(t, c, passive.ID, smodules.ContractUtility{GoodForRenew: true, Locked: true})
+	canceled := testContract(t, c, rpk, testKey(12), 3, 0, 1000, types.SiacoinPrecision)
+	setTestUtility(t, c, canceled.ID, smodules.ContractUtility{GoodForUpload: true, GoodForRenew: true})
+	testContract(t, c, other, testKey(10), 4, 0, 1000, types.SiacoinPrecision)
+	if err := c.managedCancelContract(canceled.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	contracts := c.RenterContracts(rpk)
+	ids := make(map[types.FileContractID]bool)
+	for _, contract := range contracts {
+		ids[contract.ID] = true
+	}
+	if len(contracts) != 2 || !ids[active.ID] || !ids[passive.ID] {
+		t.Fatal("expected the active and the passive contract, got", ids)
+	}
+}
@@ -3,31 +3,169 @@ package contractor
 import (
 	"github.com/mike76-dev/sia-satellite/modules"
 
+	smodules "go.sia.tech/siad/modules"
 	"go.sia.tech/siad/types"
 )
 
 // UpdateRenter updates the renter record in the database.
 // The record must have already been created.
 func (c *Contractor) UpdateRenter(renter modules.Renter) error {
-	_, err := c.db.Exec(`
+	_, err := c.execWithRetry(`
 		UPDATE renters
 		SET current_period = ?, funds = ?, hosts = ?, period = ?, renew_window = ?,
 			expected_storage = ?, expected_upload = ?, expected_download = ?,
 			expected_redundancy = ?, max_rpc_price = ?, max_contract_price = ?,
 			max_download_bandwidth_price = ?, max_sector_access_price = ?,
-			max_storage_price = ?, max_upload_bandwidth_price = ?
-		WHERE email = ?
-	`, uint64(renter.CurrentPeriod), renter.Allowance.Funds.String(), renter.Allowance.Hosts, uint64(renter.Allowance.Period), uint64(renter.Allowance.RenewWindow), renter.Allowance.ExpectedStorage, renter.Allowance.ExpectedUpload, renter.Allowance.ExpectedDownload, renter.Allowance.ExpectedRedundancy, renter.Allowance.MaxRPCPrice.String(), renter.Allowance.MaxContractPrice.String(), renter.Allowance.MaxDownloadBandwidthPrice.String(), renter.Allowance.MaxSectorAccessPrice.String(), renter.Allowance.MaxStoragePrice.String(), renter.Allowance.MaxUploadBandwidthPrice.String(), renter.Email)
+			max_storage_price = ?, max_upload_bandwidth_price = ?,
+			allow_redundant_ips = ?, max_storage_bytes = ?,
+			max_contracts_per_region = ?, paused = ?,
+			prefer_collateral = ?, spend_rate_refresh = ?,
+			max_host_latency = ?, local_region = ?, local_fraction = ?,
+			reuse_refund_address = ?
+		WHERE public_key = ?
+	`, uint64(renter.CurrentPeriod), renter.Allowance.Funds.String(), renter.Allowance.Hosts, uint64(renter.Allowance.Period), uint64(renter.Allowance.RenewWindow), renter.Allowance.ExpectedStorage, renter.Allowance.ExpectedUpload, renter.Allowance.ExpectedDownload, renter.Allowance.ExpectedRedundancy, renter.Allowance.MaxRPCPrice.String(), renter.Allowance.MaxContractPrice.String(), renter.Allowance.MaxDownloadBandwidthPrice.String(), renter.Allowance.MaxSectorAccessPrice.String(), renter.Allowance.MaxStoragePrice.String(), renter.Allowance.MaxUploadBandwidthPrice.String(), renter.AllowRedundantIPs, renter.MaxStorageBytes, renter.MaxContractsPerRegion, renter.Paused, renter.PreferCollateral, renter.SpendRateRefresh, renter.MaxHostLatency, renter.LocalRegion, renter.LocalFraction, renter.ReuseRefundAddress, renter.PublicKey.String())
+	return err
+}
+
+// insertRenter creates a new renter record in the database. It is called
+// with c.mu held, so the write is not retried.
+func (c *Contractor) insertRenter(renter modules.Renter) error {
+	_, err := c.db.Exec(`
+		INSERT INTO renters (email, suffix, public_key, current_period, funds,
+			hosts, period, renew_window, expected_storage, expected_upload,
+			expected_download, expected_redundancy, max_rpc_price,
+			max_contract_price, max_download_bandwidth_price,
+			max_sector_access_price, max_storage_price, max_upload_bandwidth_price,
+			allow_redundant_ips, max_storage_bytes, max_contracts_per_region,
+			paused, prefer_collateral, spend_rate_refresh, max_host_latency,
+			local_region, local_fraction, reuse_refund_address)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, renter.Email, renter.Suffix, renter.PublicKey.String(), uint64(renter.CurrentPeriod), renter.Allowance.Funds.String(), renter.Allowance.Hosts, uint64(renter.Allowance.Period), uint64(renter.Allowance.RenewWindow), renter.Allowance.ExpectedStorage, renter.Allowance.ExpectedUpload, renter.Allowance.ExpectedDownload, renter.Allowance.ExpectedRedundancy, renter.Allowance.MaxRPCPrice.String(), renter.Allowance.MaxContractPrice.String(), renter.Allowance.MaxDownloadBandwidthPrice.String(), renter.Allowance.MaxSectorAccessPrice.String(), renter.Allowance.MaxStoragePrice.String(), renter.Allowance.MaxUploadBandwidthPrice.String(), renter.AllowRedundantIPs, renter.MaxStorageBytes, renter.MaxContractsPerRegion, renter.Paused, renter.PreferCollateral, renter.SpendRateRefresh, renter.MaxHostLatency, renter.LocalRegion, renter.LocalFraction, renter.ReuseRefundAddress)
 	return err
 }
 
 // updateRenewedContract updates renewed_from and renewed_to
 // fields in the contracts table.
 func (c *Contractor) updateRenewedContract(oldID, newID types.FileContractID) error {
-	_, err := c.db.Exec("UPDATE contracts SET renewed_from = ? WHERE contract_id = ?", oldID.String(), newID.String())
+	_, err := c.execWithRetry("UPDATE contracts SET renewed_from = ? WHERE contract_id = ?", oldID.String(), newID.String())
 	if err != nil {
 		return err
 	}
-	_, err = c.db.Exec("UPDATE contracts SET renewed_to = ? WHERE contract_id = ?", newID.String(), oldID.String())
+	_, err = c.execWithRetry("UPDATE contracts SET renewed_to = ? WHERE contract_id = ?", newID.String(), oldID.String())
+	return err
+}
+
+// clearRenewedLink empties the renewed_from or renewed_to field of the
+// contract in the contracts table.
+func (c *Contractor) clearRenewedLink(id types.FileContractID, field string) error {
+	_, err := c.execWithRetry("UPDATE contracts SET "+field+" = '' WHERE contract_id = ?", id.String())
 	return err
 }
+
+// insertUtilityTransition records a change of the contract utility in the
+// database.
+func (c *Contractor) insertUtilityTransition(id types.FileContractID, oldUtility, newUtility smodules.ContractUtility, height types.BlockHeight, reason string) error {
+	_, err := c.execWithRetry(`
+		INSERT INTO contract_utility_history (contract_id, old_good_for_upload,
+			old_good_for_renew, old_bad_contract, old_locked, new_good_for_upload,
+			new_good_for_renew, new_bad_contract, new_locked, height, reason)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, id.String(), oldUtility.GoodForUpload, oldUtility.GoodForRenew, oldUtility.BadContract, oldUtility.Locked, newUtility.GoodForUpload, newUtility.GoodForRenew, newUtility.BadContract, newUtility.Locked, uint64(height), reason)
+	return err
+}
+
+// loadUtilityHistory reads the utility transitions of the contract from
+// the database in the order they were recorded.
+func (c *Contractor) loadUtilityHistory(id types.FileContractID) ([]modules.UtilityTransition, error) {
+	rows, err := c.db.Query(`
+		SELECT old_good_for_upload, old_good_for_renew, old_bad_contract, old_locked,
+			new_good_for_upload, new_good_for_renew, new_bad_contract, new_locked,
+			height, reason
+		FROM contract_utility_history
+		WHERE contract_id = ?
+		ORDER BY id ASC
+	`, id.String())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var history []modules.UtilityTransition
+	for rows.Next() {
+		var t modules.UtilityTransition
+		var height uint64
+		if err := rows.Scan(&t.OldUtility.GoodForUpload, &t.OldUtility.GoodForRenew, &t.OldUtility.BadContract, &t.OldUtility.Locked, &t.NewUtility.GoodForUpload, &t.NewUtility.GoodForRenew, &t.NewUtility.BadContract, &t.NewUtility.Locked, &height, &t.Reason); err != nil {
+			return nil, err
+		}
+		t.Height = types.BlockHeight(height)
+		history = append(history, t)
+	}
+
+	return history, rows.Err()
+}
+
+// loadRenters reads the renter records from the database and returns them
+// in a map keyed by the renter public key.
+func (c *Contractor) loadRenters() (map[string]modules.Renter, error) {
+	rows, err := c.db.Query(`
+		SELECT email, suffix, public_key, current_period, funds, hosts, period, renew_window,
+			expected_storage, expected_upload, expected_download, expected_redundancy,
+			max_rpc_price, max_contract_price, max_download_bandwidth_price,
+			max_sector_access_price, max_storage_price, max_upload_bandwidth_price,
+			allow_redundant_ips, max_storage_bytes, max_contracts_per_region,
+			paused, prefer_collateral, spend_rate_refresh, max_host_latency,
+			local_region, local_fraction, reuse_refund_address
+		FROM renters`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	renters := make(map[string]modules.Renter)
+	var entry renterData
+	for rows.Next() {
+		if err := rows.Scan(&entry.Email, &entry.Suffix, &entry.PublicKey, &entry.CurrentPeriod, &entry.Funds, &entry.Hosts, &entry.Period, &entry.RenewWindow, &entry.ExpectedStorage, &entry.ExpectedUpload, &entry.ExpectedDownload, &entry.ExpectedRedundancy, &entry.MaxRPCPrice, &entry.MaxContractPrice, &entry.MaxDownloadBandwidthPrice, &entry.MaxSectorAccessPrice, &entry.MaxStoragePrice, &entry.MaxUploadBandwidthPrice, &entry.AllowRedundantIPs, &entry.MaxStorageBytes, &entry.MaxContractsPerRegion, &entry.Paused, &entry.PreferCollateral, &entry.SpendRateRefresh, &entry.MaxHostLatency, &entry.LocalRegion, &entry.LocalFraction, &entry.ReuseRefundAddress); err != nil {
+			c.log.Println("ERROR: could not load the renter:", err)
+			continue
+		}
+
+		renters[entry.PublicKey] = modules.Renter{
+			Allowance: smodules.Allowance{
+				Funds:       modules.ReadCurrency(entry.Funds),
+				Hosts:       entry.Hosts,
+				Period:      types.BlockHeight(entry.Period),
+				RenewWindow: types.BlockHeight(entry.RenewWindow),
+
+				ExpectedStorage:    entry.ExpectedStorage,
+				ExpectedUpload:     entry.ExpectedUpload,
+				ExpectedDownload:   entry.ExpectedDownload,
+				ExpectedRedundancy: entry.ExpectedRedundancy,
+
+				MaxRPCPrice:               modules.ReadCurrency(entry.MaxRPCPrice),
+				MaxContractPrice:          modules.ReadCurrency(entry.MaxContractPrice),
+				MaxDownloadBandwidthPrice: modules.ReadCurrency(entry.MaxDownloadBandwidthPrice),
+				MaxSectorAccessPrice:      modules.ReadCurrency(entry.MaxSectorAccessPrice),
+				MaxStoragePrice:           modules.ReadCurrency(entry.MaxStoragePrice),
+				MaxUploadBandwidthPrice:   modules.ReadCurrency(entry.MaxUploadBandwidthPrice),
+			},
+			CurrentPeriod: types.BlockHeight(entry.CurrentPeriod),
+			PublicKey:     modules.ReadPublicKey(entry.PublicKey),
+			Email:         entry.Email,
+			Suffix:        entry.Suffix,
+
+			AllowRedundantIPs: entry.AllowRedundantIPs,
+			MaxStorageBytes:   entry.MaxStorageBytes,
+
+			MaxContractsPerRegion: entry.MaxContractsPerRegion,
+			Paused:                entry.Paused,
+			PreferCollateral:      entry.PreferCollateral,
+			SpendRateRefresh:      entry.SpendRateRefresh,
+			MaxHostLatency:        entry.MaxHostLatency,
+			LocalRegion:           entry.LocalRegion,
+			LocalFraction:         entry.LocalFraction,
+			ReuseRefundAddress:    entry.ReuseRefundAddress,
+		}
+	}
+
+	return renters, nil
+}
@@ -0,0 +1,46 @@
+package contractor
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// MySQL error numbers of the transient write failures.
+const (
+	mysqlErrLockWaitTimeout = 1205
+	mysqlErrDeadlock        = 1213
+)
+
+// isTransientDBError returns true if the database write failed because of
+// lock contention and can be retried.
+func isTransientDBError(err error) bool {
+	var me *mysql.MySQLError
+	if errors.As(err, &me) {
+		return me.Number == mysqlErrLockWaitTimeout || me.Number == mysqlErrDeadlock
+	}
+	return false
+}
+
+// execWithRetry executes the database write. If the write fails because of
+// lock contention, it is retried up to DBWriteRetries times with an
+// exponential backoff. It must not be called with c.mu held, because the
+// lock would be held for the whole backoff.
+func (c *Contractor) execWithRetry(query string, args ...interface{}) (sql.Result, error) {
+	backoff := DBWriteRetryBackoff
+	for attempt := 0; ; attempt++ {
+		res, err := c.db.Exec(query, args...)
+		if err == nil || attempt >= DBWriteRetries || !isTransientDBError(err) {
+			return res, err
+		}
+		c.log.Printf("WARN: database write failed, retrying in %v: %v\n", backoff, err)
+		select {
+		case <-time.After(backoff):
+		case <-c.tg.StopChan():
+			return res, err
+		}
+		backoff *= 2
+	}
+}
@@ -0,0 +1,95 @@
+package contractor
+
+import (
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+
+	smodules "go.sia.tech/siad/modules"
+)
+
+// TestExecWithRetry tests that a database write is retried while the
+// database is busy, and that the other errors are returned at once.
+func TestExecWithRetry(t *testing.T) {
+	c, fake := newTestContractor(t)
+	defer func(backoff time.Duration) {
+		DBWriteRetryBackoff = backoff
+	}(DBWriteRetryBackoff)
+	DBWriteRetryBackoff = time.Millisecond
+
+	// The database is busy twice before the write succeeds.
+	var attempts int
+	fake.OnExec(func(query string, _ []driver.Value) error {
+		attempts++
+		switch attempts {
+		case 1:
+			return &mysql.MySQLError{Number: mysqlErrDeadlock}
+		case 2:
+			return &mysql.MySQLError{Number: mysqlErrLockWaitTimeout}
+		}
+		return nil
+	})
+	if _, err := c.execWithRetry("DELETE FROM contract_no_refresh"); err != nil {
+		t.Fatal(err)
+	}
+	if attempts != 3 {
+		t.Fatalf("expected 3 attempts, got %v", attempts)
+	}
+
+	// Any other error is not retried.
+	attempts = 0
+	fake.OnExec(func(query string, _ []driver.Value) error {
+		attempts++
+		return errors.New("database is locked")
+	})
+	if _, err := c.execWithRetry("DELETE FROM contract_no_refresh"); err == nil {
+		t.Fatal("expected the write to fail")
+	}
+	if attempts != 1 {
+		t.Fatalf("expected 1 attempt, got %v", attempts)
+	}
+}
+
+// TestPeriodUpdateUnlocked tests that the renter record is not written
+// with the contractor lock held when a new period starts.
+func TestPeriodUpdateUnlocked(t *testing.T) {
+	c, fake := newTestContractor(t)
+	newTestHostDB(c)
+	rpk := testKey(1)
+	testRenter(c, rpk)
+	defer func(backoff time.Duration) {
+		DBWriteRetryBackoff = backoff
+	}(DBWriteRetryBackoff)
+	DBWriteRetryBackoff = time.Millisecond
+
+	var attempts int
+	fake.OnExec(func(query string, _ []driver.Value) error {
+		if !strings.Contains(query, "UPDATE renters") {
+			return nil
+		}
+		attempts++
+		if !c.mu.TryLock() {
+			t.Error("renter updated with the contractor lock held")
+		} else {
+			c.mu.Unlock()
+		}
+		if attempts == 1 {
+			return &mysql.MySQLError{Number: mysqlErrDeadlock}
+		}
+		return nil
+	})
+	c.ProcessConsensusChange(smodules.ConsensusChange{BlockHeight: 1000})
+	if attempts != 2 {
+		t.Fatalf("expected 2 attempts, got %v", attempts)
+	}
+	c.mu.RLock()
+	period := c.renters[rpk.String()].CurrentPeriod
+	c.mu.RUnlock()
+	if period != 1000 {
+		t.Fatal("wrong current period:", period)
+	}
+}
@@ -0,0 +1,46 @@
+package contractor
+
+import (
+	"github.com/mike76-dev/sia-satellite/modules"
+
+	"go.sia.tech/siad/types"
+)
+
+// DebugSnapshot returns a copy of the contractor's internal bookkeeping of
+// the contracts. The maps are copied under the lock, which is held only for
+// the copying.
+func (c *Contractor) DebugSnapshot() modules.ContractorSnapshot {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	cs := modules.ContractorSnapshot{
+		RenewedFrom:          make(map[string]types.FileContractID, len(c.renewedFrom)),
+		RenewedTo:            make(map[string]types.FileContractID, len(c.renewedTo)),
+		OldContracts:         make([]types.FileContractID, 0, len(c.oldContracts)),
+		NumFailedRenews:      make(map[string]types.BlockHeight, len(c.numFailedRenews)),
+		Renewing:             make([]types.FileContractID, 0, len(c.renewing)),
+		DoubleSpentContracts: make(map[string]types.BlockHeight, len(c.doubleSpentContracts)),
+	}
+	for id, from := range c.renewedFrom {
+		cs.RenewedFrom[id.String()] = from
+	}
+	for id, to := range c.renewedTo {
+		cs.RenewedTo[id.String()] = to
+	}
+	for id := range c.oldContracts {
+		cs.OldContracts = append(cs.OldContracts, id)
+	}
+	for id, n := range c.numFailedRenews {
+		cs.NumFailedRenews[id.String()] = n
+	}
+	for id, renewing := range c.renewing {
+		if renewing {
+			cs.Renewing = append(cs.Renewing, id)
+		}
+	}
+	for id, height := range c.doubleSpentContracts {
+		cs.DoubleSpentContracts[id.String()] = height
+	}
+
+	return cs
+}
@@ -0,0 +1,59 @@
+package contractor
+
+import (
+	"testing"
+
+	"github.com/mike76-dev/sia-satellite/modules"
+
+	"go.sia.tech/siad/types"
+)
+
+// TestDebugSnapshot tests that the snapshot reflects the internal maps,
+// and that it isn't affected by the later changes to them.
+func TestDebugSnapshot(t *testing.T) {
+	c, _ := newTestContractor(t)
+	old, renewed, failing, spent := types.FileContractID{1}, types.FileContractID{2}, types.FileContractID{3}, types.FileContractID{4}
+
+	c.mu.Lock()
+	c.renewedFrom[renewed] = old
+	c.renewedTo[old] = renewed
+	c.oldContracts[old] = modules.RenterContract{ID: old}
+	c.numFailedRenews[failing] = 3
+	c.renewing[failing] = true
+	c.renewing[renewed] = false
+	c.doubleSpentContracts[spent] = 100
+	c.mu.Unlock()
+
+	check := func(cs modules.ContractorSnapshot) {
+		t.Helper()
+		if len(cs.RenewedFrom) != 1 || cs.RenewedFrom[renewed.String()] != old {
+			t.Fatal("wrong renewedFrom:", cs.RenewedFrom)
+		}
+		if len(cs.RenewedTo) != 1 || cs.RenewedTo[old.String()] != renewed {
+			t.Fatal("wrong renewedTo:", cs.RenewedTo)
+		}
+		if len(cs.OldContracts) != 1 || cs.OldContracts[0] != old {
+			t.Fatal("wrong oldContracts:", cs.OldContracts)
+		}
+		if len(cs.NumFailedRenews) != 1 || cs.NumFailedRenews[failing.String()] != 3 {
+			t.Fatal("wrong numFailedRenews:", cs.NumFailedRenews)
+		}
+		if len(cs.Renewing) != 1 || cs.Renewing[0] != failing {
+			t.Fatal("wrong renewing:", cs.Renewing)
+		}
+		if len(cs.DoubleSpentContracts) != 1 || cs.DoubleSpentContracts[spent.String()] != 100 {
+			t.Fatal("wrong doubleSpentContracts:", cs.DoubleSpentContracts)
+		}
+	}
+	cs := c.DebugSnapshot()
+	check(cs)
+
+	// The snapshot is a copy.
+	c.mu.Lock()
+	c.renewedFrom[types.FileContractID{5}] = renewed
+	c.numFailedRenews[failing] = 4
+	delete(c.renewing, failing)
+	c.doubleSpentContracts[spent] = 200
+	c.mu.Unlock()
+	check(cs)
+}
@@ -0,0 +1,51 @@
+package contractor
+
+import (
+	"github.com/mike76-dev/sia-satellite/modules"
+
+	"go.sia.tech/siad/types"
+)
+
+// hostDiversity returns how much the host adds to the region diversity of
+// the renter's other contracts, between 0 and 1. A host from a region none
+// of the other contracts are in scores 1, a host from the region all of
+// them are in scores 0. The hosts with an unknown region count as one
+// region.
+func hostDiversity(region string, counts map[string]uint64, others uint64) float64 {
+	if others == 0 {
+		return 0
+	}
+	return 1 - float64(counts[region]) / float64(others)
+}
+
+// managedDiversityAdjustedScores lowers the minimum scores a host needs to
+// stay GoodForRenew and GoodForUpload in proportion to how much the host
+// adds to the region diversity of the renter's contracts, so that a
+// slightly worse but more diverse host is kept. The scores are lowered by
+// at most the diversity weight; a zero weight leaves them unchanged.
+func (c *Contractor) managedDiversityAdjustedScores(contract modules.RenterContract, minScoreGFR, minScoreGFU types.Currency) (types.Currency, types.Currency) {
+	c.mu.RLock()
+	weight := c.diversityWeight
+	c.mu.RUnlock()
+	if weight <= 0 {
+		return minScoreGFR, minScoreGFU
+	}
+
+	host, exists, err := c.hdb.Host(contract.HostPublicKey)
+	if err != nil || !exists {
+		return minScoreGFR, minScoreGFU
+	}
+	var others []modules.RenterContract
+	for _, rc := range c.staticContracts.ByRenter(contract.RenterPublicKey) {
+		if rc.ID != contract.ID && activeContract(rc) {
+			others = append(others, rc)
+		}
+	}
+	diversity := hostDiversity(c.managedHostRegion(host), c.managedRegionCounts(others), uint64(len(others)))
+	if diversity == 0 {
+		return minScoreGFR, minScoreGFU
+	}
+
+	factor := 1 - weight * diversity
+	return minScoreGFR.MulFloat(factor), minScoreGFU.MulFloat(factor)
+}
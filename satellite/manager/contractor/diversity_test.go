@@ -0,0 +1,152 @@
+package contractor
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/mike76-dev/sia-satellite/modules"
+
+	smodules "go.sia.tech/siad/modules"
+	"go.sia.tech/siad/types"
+)
+
+// TestDiversityRetainsHost tests that with the diversity weighting a host
+// below the minimum score is kept if it adds to the region diversity of
+// the renter's contracts, and that the hosts with an unknown region count
+// as one region.
+func TestDiversityRetainsHost(t *testing.T) {
+	c, _ := newTestContractor(t)
+	rpk := testKey(1)
+	testRenter(c, rpk)
+	hdb := newTestHostDB(c)
+	hosts := []struct {
+		addr  string
+		score uint64
+	}{
+		{"host1.example.de:9982", 1000},
+		{"host2.example.de:9982", 1000},
+		{"host3.example.de:9982", 1000},
+		{"host4.example.de:9982", 1000},
+		{"host.example.fr:9982", 90},
+		{"10.0.0.1:9982", 90},
+		{"host5.example.de:9982", 70},
+	}
+	var contracts []modules.RenterContract
+	for i, h := range hosts {
+		host := testHost(byte(10 + i), h.addr)
+		hdb.addHost(host, h.score)
+		contract := testContract(t, c, rpk, host.PublicKey, byte(i + 1), 0, 1000, types.SiacoinPrecision.Mul64(10))
+		setTestUtility(t, c, contract.ID, smodules.ContractUtility{GoodForUpload: true, GoodForRenew: true})
+		contracts = append(contracts, contract)
+	}
+	minScore := types.NewCurrency64(100)
+
+	// Without the weighting, the hosts below the minimum score are dropped.
+	for _, contract := range contracts[4:] {
+		if err := c.managedMarkContractUtility(contract, minScore, minScore); err != nil {
+			t.Fatal(err)
+		}
+		if u, _ := c.managedContractUtility(contract.ID); u.GoodForRenew {
+			t.Fatal("host below the minimum score kept:", contract.HostPublicKey)
+		}
+	}
+
+	// With the weighting, the diverse hosts are kept, while the host from
+	// the crowded region is still dropped.
+	if err := setTestSettings(c, func(s *modules.ContractorSettings) { s.DiversityWeight = 0.5 }); err != nil {
+		t.Fatal(err)
+	}
+	for i, contract := range contracts {
+		if err := c.managedMarkContractUtility(contract, minScore, minScore); err != nil {
+			t.Fatal(err)
+		}
+		u, _ := c.managedContractUtility(contract.ID)
+		if kept := i < 6; u.GoodForRenew != kept || u.GoodForUpload != kept {
+			t.Fatalf("host %v: expected GFR and GFU %v, got %+v", hosts[i].addr, kept, u)
+		}
+	}
+}
+
+// sampleHostDB is a testHostDB that fails to return the random hosts for
+// the allowances with three hosts.
+type sampleHostDB struct {
+	*testHostDB
+}
+
+// RandomHostsWithLimits implements modules.HostDB.
+func (hdb *sampleHostDB) RandomHostsWithLimits(n int, blacklist, addressBlacklist []types.SiaPublicKey, a smodules.Allowance) ([]smodules.HostDBEntry, error) {
+	if a.Hosts == 3 {
+		return nil, errors.New("sampling failed")
+	}
+	return hdb.testHostDB.RandomHostsWithLimits(n, blacklist, addressBlacklist, a)
+}
+
+// TestMaintenanceUtilityChecks tests that the contract maintenance only
+// runs the utility checks with the diversity weighting, and that a renter
+// the minimum scores can't be found for is skipped without stopping the
+// other renters and the later phases.
+func TestMaintenanceUtilityChecks(t *testing.T) {
+	c, _ := newTestContractor(t)
+	setTestSynced(c)
+	c.wallet = &testWallet{unlocked: true}
+	hdb := &sampleHostDB{testHostDB: newTestHostDB(c)}
+	c.hdb = hdb
+	c.mu.Lock()
+	c.gracePassed = true
+	c.mu.Unlock()
+
+	// The host is filtered, which the utility checks would catch.
+	host := testHost(10, "host.example.com:9982")
+	host.Filtered = true
+	hdb.addHost(host, 100)
+	skipped, checked := testKey(1), testKey(2)
+	renter := testRenter(c, skipped)
+	renter.Allowance.Hosts = 3
+	c.mu.Lock()
+	c.renters[skipped.String()] = renter
+	c.mu.Unlock()
+	testRenter(c, checked)
+	skippedContract := testContract(t, c, skipped, host.PublicKey, 1, 0, 1000, types.SiacoinPrecision)
+	checkedContract := testContract(t, c, checked, host.PublicKey, 2, 0, 1000, types.SiacoinPrecision)
+	gfu := smodules.ContractUtility{GoodForUpload: true, GoodForRenew: true}
+	setTestUtility(t, c, skippedContract.ID, gfu)
+	setTestUtility(t, c, checkedContract.ID, gfu)
+	phases := func() string {
+		var names []string
+		for _, phase := range c.MaintenanceTimings().Phases {
+			names = append(names, phase.Name)
+		}
+		return strings.Join(names, ",")
+	}
+
+	// Without the weighting, the utility is left alone.
+	c.threadedContractMaintenance()
+	for _, id := range []types.FileContractID{skippedContract.ID, checkedContract.ID} {
+		if u, _ := c.managedContractUtility(id); !u.GoodForUpload || !u.GoodForRenew {
+			t.Fatal("expected the utility to be left alone, got", u)
+		}
+	}
+	if names := phases(); strings.Contains(names, "utility") {
+		t.Fatal("expected no utility checks without the weighting, got", names)
+	}
+
+	// With the weighting, the renter without the minimum scores is skipped,
+	// while the other renter and the later phases are not.
+	if err := setTestSettings(c, func(s *modules.ContractorSettings) { s.DiversityWeight = 0.5 }); err != nil {
+		t.Fatal(err)
+	}
+	c.threadedContractMaintenance()
+	if !strings.Contains(testLog(t, c), "unable to find the minimum host scores for renter " + skipped.String()) {
+		t.Fatal("expected the renter to be skipped")
+	}
+	if u, _ := c.managedContractUtility(skippedContract.ID); !u.GoodForUpload || !u.GoodForRenew {
+		t.Fatal("expected the skipped renter's utility to be left alone, got", u)
+	}
+	if u, _ := c.managedContractUtility(checkedContract.ID); u.GoodForUpload || u.GoodForRenew {
+		t.Fatal("expected the filtered host to lose its utility, got", u)
+	}
+	if names := phases(); !strings.HasSuffix(names, "prune,utility,hostdb-update,host-limit,gfu-limit") {
+		t.Fatal("expected all phases to run, got", names)
+	}
+}
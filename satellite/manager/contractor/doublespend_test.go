@@ -0,0 +1,104 @@
+package contractor
+
+import (
+	"sync"
+	"testing"
+
+	smodules "go.sia.tech/siad/modules"
+	"go.sia.tech/siad/types"
+)
+
+// TestDoubleSpendPeriodSpending tests that the double-spent contracts are
+// excluded from the period spending while the double-spends are processed
+// concurrently with the period spending reads.
+func TestDoubleSpendPeriodSpending(t *testing.T) {
+	c, _ := newTestContractor(t)
+	newTestHostDB(c)
+	rpk := testKey(1)
+	testRenter(c, rpk)
+
+	const numContracts = 10
+	funds := types.SiacoinPrecision.Mul64(10)
+	var spends []types.Transaction
+	for i := 0; i < numContracts; i++ {
+		contract := testContract(t, c, rpk, testKey(byte(10 + i)), byte(i + 1), 1, 1000, funds)
+
+		// The formation transaction spends an output that is spent by
+		// another transaction afterwards.
+		parent := types.SiacoinOutputID{byte(i + 1)}
+		formation := types.Transaction{
+			SiacoinInputs: []types.SiacoinInput{{ParentID: parent}},
+			ArbitraryData: [][]byte{{1}},
+		}
+		err := c.staticWatchdog.callMonitorContract(monitorContractArgs{
+			fcID:            contract.ID,
+			revisionTxn:     contract.Transaction,
+			formationTxnSet: []types.Transaction{formation},
+			sweepTxn:        types.Transaction{SiacoinInputs: []types.SiacoinInput{{ParentID: parent}}},
+			blockHeight:     1,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		spends = append(spends, types.Transaction{
+			SiacoinInputs: []types.SiacoinInput{{ParentID: parent}},
+			ArbitraryData: [][]byte{{2}},
+		})
+	}
+	spending, err := c.PeriodSpending(rpk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !spending.TotalAllocated.Equals(funds.Mul64(numContracts)) {
+		t.Fatal("wrong allocation before the double-spends:", spending.TotalAllocated)
+	}
+
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		defer close(done)
+		for i, txn := range spends {
+			c.ProcessConsensusChange(smodules.ConsensusChange{
+				ID: smodules.ConsensusChangeID{byte(i + 1)},
+				AppliedBlocks: []types.Block{{
+					Timestamp:    types.Timestamp(i + 1),
+					Transactions: []types.Transaction{txn},
+				}},
+			})
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			if _, err := c.PeriodSpending(rpk); err != nil {
+				t.Error(err)
+				return
+			}
+			c.WatchdogStatus()
+		}
+	}()
+	wg.Wait()
+
+	spending, err = c.PeriodSpending(rpk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !spending.TotalAllocated.IsZero() {
+		t.Fatal("double-spent contracts counted in the period spending:", spending.TotalAllocated)
+	}
+	if status := c.WatchdogStatus(); len(status.DoubleSpentContracts) != numContracts {
+		t.Fatalf("expected %v double-spent contracts, got %v", numContracts, len(status.DoubleSpentContracts))
+	}
+	for _, contract := range c.staticContracts.ByRenter(rpk) {
+		if contract.Utility.GoodForRenew || contract.Utility.GoodForUpload {
+			t.Fatal("double-spent contract not marked as bad:", contract.ID)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package contractor
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"gitlab.com/NebulousLabs/errors"
+
+	"go.sia.tech/siad/types"
+)
+
+const (
+	// LogFormatText is the default log format of the contractor.
+	LogFormatText = "text"
+
+	// LogFormatJSON makes the contractor additionally write the contract
+	// lifecycle events as JSON lines.
+	LogFormatJSON = "json"
+
+	// eventLogFilename is the name of the file the JSON events are written
+	// to. It must not collide with PersistFilename.
+	eventLogFilename = "events.json"
+)
+
+// Contract lifecycle events.
+const (
+	eventContractFormed          = "contract_formed"
+	eventContractFormationFailed = "contract_formation_failed"
+	eventContractRenewed         = "contract_renewed"
+	eventContractRenewalFailed   = "contract_renewal_failed"
+	eventContractCanceled        = "contract_canceled"
+	eventContractArchived        = "contract_archived"
+	eventContractPaidOut         = "contract_paid_out"
+)
+
+// logEvent is a structured record of a contract lifecycle event.
+type logEvent struct {
+	Time     time.Time `json:"time"`
+	Level    string    `json:"level"`
+	Event    string    `json:"event"`
+	Contract string    `json:"contract,omitempty"`
+	Host     string    `json:"host,omitempty"`
+	Renter   string    `json:"renter,omitempty"`
+	Message  string    `json:"message"`
+}
+
+// eventLogger writes the contract lifecycle events as JSON lines.
+type eventLogger struct {
+	file *os.File
+	enc  *json.Encoder
+	mu   sync.Mutex
+}
+
+// newEventLogger opens the JSON event log in the persist directory.
+func newEventLogger(persistDir string) (*eventLogger, error) {
+	f, err := os.OpenFile(filepath.Join(persistDir, eventLogFilename), os.O_RDWR|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, errors.AddContext(err, "unable to open the event log")
+	}
+	return &eventLogger{
+		file: f,
+		enc:  json.NewEncoder(f),
+	}, nil
+}
+
+// Close closes the event log file.
+func (el *eventLogger) Close() error {
+	el.mu.Lock()
+	defer el.mu.Unlock()
+	return el.file.Close()
+}
+
+// write writes a single event.
+func (el *eventLogger) write(e logEvent) error {
+	el.mu.Lock()
+	defer el.mu.Unlock()
+	return el.enc.Encode(e)
+}
+
+// logEvent records a contract lifecycle event if JSON logging is enabled.
+// Empty keys and contract IDs are omitted from the record.
+func (c *Contractor) logEvent(level, event string, fcid types.FileContractID, rpk, hpk types.SiaPublicKey, format string, args ...interface{}) {
+	if c.staticEventLog == nil {
+		return
+	}
+	e := logEvent{
+		Time:    time.Now().UTC(),
+		Level:   level,
+		Event:   event,
+		Message: fmt.Sprintf(format, args...),
+	}
+	if fcid != (types.FileContractID{}) {
+		e.Contract = fcid.String()
+	}
+	if len(rpk.Key) > 0 {
+		e.Renter = rpk.String()
+	}
+	if len(hpk.Key) > 0 {
+		e.Host = hpk.String()
+	}
+	if err := c.staticEventLog.write(e); err != nil {
+		c.log.Println("WARN: unable to write to the event log:", err)
+	}
+}
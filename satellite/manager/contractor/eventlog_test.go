@@ -0,0 +1,75 @@
+package contractor
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.sia.tech/siad/types"
+)
+
+// TestEventLogFormation tests the JSON fields of the formation events.
+func TestEventLogFormation(t *testing.T) {
+	c, _ := newTestContractor(t)
+	el, err := newEventLogger(c.persistDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.staticEventLog = el
+
+	rpk, hpk := testKey(1), testKey(10)
+	var fcid types.FileContractID
+	fcid[0] = 1
+	c.logEvent("INFO", eventContractFormed, fcid, rpk, hpk, "formed contract with %v for %v", "host.example.com:9982", "10 SC")
+	c.logEvent("WARN", eventContractFormationFailed, types.FileContractID{}, rpk, hpk, "negotiation with %v failed: %v", "host.example.com:9982", "timeout")
+	if err := el.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(filepath.Join(c.persistDir, eventLogFilename))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var events []map[string]interface{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			t.Fatal(err)
+		}
+		events = append(events, e)
+	}
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events, got %v", len(events))
+	}
+
+	formed := events[0]
+	expected := map[string]string{
+		"level":    "INFO",
+		"event":    "contract_formed",
+		"contract": fcid.String(),
+		"renter":   rpk.String(),
+		"host":     hpk.String(),
+		"message":  "formed contract with host.example.com:9982 for 10 SC",
+	}
+	for field, value := range expected {
+		if formed[field] != value {
+			t.Fatalf("expected %v to be %q, got %v", field, value, formed[field])
+		}
+	}
+	if _, ok := formed["time"].(string); !ok {
+		t.Fatal("time missing")
+	}
+
+	// The failed formation has no contract ID.
+	failed := events[1]
+	if failed["event"] != "contract_formation_failed" || failed["level"] != "WARN" {
+		t.Fatal("wrong failed formation event:", failed)
+	}
+	if _, ok := failed["contract"]; ok {
+		t.Fatal("empty contract ID not omitted")
+	}
+}
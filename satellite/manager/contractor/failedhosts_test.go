@@ -0,0 +1,45 @@
+package contractor
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/mike76-dev/sia-satellite/modules"
+
+	smodules "go.sia.tech/siad/modules"
+	"go.sia.tech/siad/types"
+)
+
+// TestFormationSkipsFailedHosts tests that a host that failed during a
+// formation run isn't attempted again if it shows up in the candidates a
+// second time, and that it is attempted again in the next run.
+func TestFormationSkipsFailedHosts(t *testing.T) {
+	c, _ := newTestContractor(t)
+	setTestSynced(c)
+	c.wallet = &testWallet{unlocked: true, balance: types.SiacoinPrecision.Mul64(1e4)}
+	newTestFundLocker(c)
+	rpk := testKey(1)
+	testRenter(c, rpk)
+
+	// The failing host is listed twice.
+	failing := testHost(10, "failing.example.com:9982")
+	hdb := newTestHostDB(c)
+	hdb.addHost(failing, 100)
+	hdb.addHost(testHost(11, "host.example.com:9982"), 90)
+	hdb.addHost(failing, 80)
+
+	attempts := make(map[string]int)
+	form := func(rpk types.SiaPublicKey, host smodules.HostDBEntry, funds types.Currency, endHeight types.BlockHeight) (types.Currency, modules.RenterContract, error) {
+		attempts[host.PublicKey.String()]++
+		return types.ZeroCurrency, modules.RenterContract{}, errors.New("negotiation failed")
+	}
+	for run := 1; run <= 2; run++ {
+		if _, err := c.managedFormContracts(context.Background(), rpk, nil, form); err != nil {
+			t.Fatal(err)
+		}
+		if n := attempts[failing.PublicKey.String()]; n != run {
+			t.Fatalf("run %v: expected %v attempts with the failing host, got %v", run, run, n)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package contractor
+
+import (
+	"testing"
+
+	"gitlab.com/NebulousLabs/errors"
+
+	smodules "go.sia.tech/siad/modules"
+	"go.sia.tech/siad/types"
+)
+
+// TestRenewalFeeBreakdown tests that the fee components of a renewal
+// estimate sum to its overhead.
+func TestRenewalFeeBreakdown(t *testing.T) {
+	c, _ := newTestContractor(t)
+	setTestSynced(c)
+	rpk := testKey(1)
+	testRenter(c, rpk)
+	host := testHost(2, "host.com:9982")
+	host.ContractPrice = types.SiacoinPrecision
+	host.StoragePrice = types.SiacoinPrecision.Div64(1e9)
+	host.UploadBandwidthPrice = types.SiacoinPrecision.Div64(1e10)
+	newTestHostDB(c, host)
+	rc := testContract(t, c, rpk, host.PublicKey, 1, 0, 1000, types.SiacoinPrecision.Mul64(100))
+
+	breakdown, err := c.RenewalFeeBreakdown(rpk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(breakdown) != 1 || breakdown[0].ContractID != rc.ID {
+		t.Fatal("expected the breakdown of the contract, got", breakdown)
+	}
+	fees := breakdown[0]
+	if !fees.ContractPrice.Equals(host.ContractPrice) {
+		t.Fatalf("expected contract price %v, got %v", host.ContractPrice, fees.ContractPrice)
+	}
+	_, maxFee := testTpool{}.FeeEstimation()
+	if txnFee := maxFee.Mul64(smodules.EstimatedFileContractTransactionSetSize); !fees.TxnFee.Equals(txnFee) {
+		t.Fatalf("expected txn fee %v, got %v", txnFee, fees.TxnFee)
+	}
+	if fees.SiafundFee.IsZero() {
+		t.Fatal("expected a siafund fee")
+	}
+	if overhead := fees.ContractPrice.Add(fees.SiafundFee).Add(fees.TxnFee); !fees.Overhead.Equals(overhead) {
+		t.Fatalf("expected overhead %v, got %v", overhead, fees.Overhead)
+	}
+	if fees.EstimatedCost.Cmp(fees.Overhead) < 0 {
+		t.Fatal("overhead exceeds the estimated cost")
+	}
+
+	// The breakdown matches the renewal estimate.
+	c.mu.RLock()
+	allowance := c.renters[rpk.String()].Allowance
+	c.mu.RUnlock()
+	estimate, err := c.managedEstimateRenewFundingRequirements(rc, 0, allowance)
+	if err != nil || !estimate.Equals(fees.EstimatedCost) {
+		t.Fatalf("expected estimated cost %v, got %v: %v", fees.EstimatedCost, estimate, err)
+	}
+
+	// An unknown renter has no breakdown.
+	if _, err := c.RenewalFeeBreakdown(testKey(3)); !errors.Contains(err, ErrRenterNotFound) {
+		t.Fatal("expected ErrRenterNotFound, got", err)
+	}
+}
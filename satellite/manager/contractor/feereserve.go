@@ -0,0 +1,68 @@
+package contractor
+
+import (
+	"sync"
+
+	"gitlab.com/NebulousLabs/errors"
+
+	"go.sia.tech/siad/types"
+)
+
+// errInsufficientUnreservedBalance is returned when the wallet balance that
+// is not reserved for the transaction fees of ongoing contract formations is
+// too low.
+var errInsufficientUnreservedBalance = errors.New("insufficient wallet balance after fee reservations")
+
+// feeReserve keeps track of the wallet funds that are set aside for the
+// transaction fees of ongoing contract formations. The wallet itself has no
+// notion of reservations, so the other wallet users in the contractor need
+// to check against the reserve before spending.
+type feeReserve struct {
+	reserved types.Currency
+	mu       sync.Mutex
+}
+
+// managedUnreservedBalance returns the confirmed wallet balance minus the
+// reserved fees.
+func (c *Contractor) managedUnreservedBalance() (types.Currency, error) {
+	balance, _, _, err := c.wallet.ConfirmedBalance()
+	if err != nil {
+		return types.ZeroCurrency, errors.AddContext(err, "unable to get the wallet balance")
+	}
+	c.staticFeeReserve.mu.Lock()
+	reserved := c.staticFeeReserve.reserved
+	c.staticFeeReserve.mu.Unlock()
+	if balance.Cmp(reserved) < 0 {
+		return types.ZeroCurrency, nil
+	}
+	return balance.Sub(reserved), nil
+}
+
+// managedReserveFees sets aside the given amount in the wallet for the
+// transaction fees of a contract formation.
+func (c *Contractor) managedReserveFees(amount types.Currency) error {
+	balance, _, _, err := c.wallet.ConfirmedBalance()
+	if err != nil {
+		return errors.AddContext(err, "unable to get the wallet balance")
+	}
+	c.staticFeeReserve.mu.Lock()
+	defer c.staticFeeReserve.mu.Unlock()
+	reserved := c.staticFeeReserve.reserved.Add(amount)
+	if balance.Cmp(reserved) < 0 {
+		return errInsufficientUnreservedBalance
+	}
+	c.staticFeeReserve.reserved = reserved
+	return nil
+}
+
+// managedReleaseFees returns the given amount of reserved fees to the wallet.
+func (c *Contractor) managedReleaseFees(amount types.Currency) {
+	c.staticFeeReserve.mu.Lock()
+	defer c.staticFeeReserve.mu.Unlock()
+	if c.staticFeeReserve.reserved.Cmp(amount) < 0 {
+		c.log.Critical("releasing more fees than reserved")
+		c.staticFeeReserve.reserved = types.ZeroCurrency
+		return
+	}
+	c.staticFeeReserve.reserved = c.staticFeeReserve.reserved.Sub(amount)
+}
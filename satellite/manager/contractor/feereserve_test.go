@@ -0,0 +1,70 @@
+package contractor
+
+import (
+	"sync"
+	"testing"
+
+	"gitlab.com/NebulousLabs/errors"
+
+	"go.sia.tech/siad/types"
+)
+
+// TestFeeReserveConcurrentDraws tests that the concurrent wallet draws
+// checking the unreserved balance leave the reserved formation fees in
+// the wallet.
+func TestFeeReserveConcurrentDraws(t *testing.T) {
+	c, _ := newTestContractor(t)
+	w := &testWallet{unlocked: true, balance: types.SiacoinPrecision.Mul64(100)}
+	c.wallet = w
+
+	fees := types.SiacoinPrecision.Mul64(30)
+	if err := c.managedReserveFees(fees); err != nil {
+		t.Fatal(err)
+	}
+
+	// Draw from the wallet concurrently. Each draw checks the unreserved
+	// balance first, like a renewal does, and the check and the draw are
+	// atomic, like the funding of a transaction.
+	draw := types.SiacoinPrecision.Mul64(10)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var draws int
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			mu.Lock()
+			defer mu.Unlock()
+			available, err := c.managedUnreservedBalance()
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if available.Cmp(draw) < 0 {
+				return
+			}
+			w.draw(draw)
+			draws++
+		}()
+	}
+	wg.Wait()
+
+	if draws != 7 {
+		t.Fatalf("expected 7 draws, got %v", draws)
+	}
+	balance, _, _, _ := w.ConfirmedBalance()
+	if !balance.Equals(fees) {
+		t.Fatalf("expected the fees of %v to remain, got %v", fees.HumanString(), balance.HumanString())
+	}
+
+	// Another formation can't reserve the fees anymore.
+	if err := c.managedReserveFees(types.SiacoinPrecision); !errors.Contains(err, errInsufficientUnreservedBalance) {
+		t.Fatal("expected errInsufficientUnreservedBalance, got", err)
+	}
+
+	// Once released, the fees are available again.
+	c.managedReleaseFees(fees)
+	if available, err := c.managedUnreservedBalance(); err != nil || !available.Equals(fees) {
+		t.Fatalf("expected %v available, got %v: %v", fees.HumanString(), available.HumanString(), err)
+	}
+}
@@ -0,0 +1,244 @@
+package contractor
+
+import (
+	"context"
+	"time"
+
+	"github.com/mike76-dev/sia-satellite/modules"
+
+	"gitlab.com/NebulousLabs/errors"
+
+	smodules "go.sia.tech/siad/modules"
+	"go.sia.tech/siad/types"
+)
+
+// formationPlan contains the hosts selected for the contract formation
+// and the parameters needed to fund the contracts with them.
+type formationPlan struct {
+	contractSet     []modules.RenterContract
+	neededContracts int
+	hosts           []smodules.HostDBEntry
+	endHeight       types.BlockHeight
+	fundsRemaining  types.Currency
+	txnFee          types.Currency
+	minFunds        types.Currency
+	maxFunds        types.Currency
+	regionCounts    map[string]uint64
+}
+
+// contractFunding returns the funding of a new contract with the host.
+func (fp *formationPlan) contractFunding(host smodules.HostDBEntry) types.Currency {
+	funds := host.ContractPrice.Add(fp.txnFee).Mul64(ContractFeeFundingMulFactor)
+
+	// Check that the contract funding is reasonable compared to the max and
+	// min initial funding. This is to protect against increases to
+	// allowances being used up to fast and not being able to spread the
+	// funds across new contracts properly, as well as protecting against
+	// contracts renewing too quickly.
+	if funds.Cmp(fp.maxFunds) > 0 {
+		funds = fp.maxFunds
+	}
+	if funds.Cmp(fp.minFunds) < 0 {
+		funds = fp.minFunds
+	}
+	return funds
+}
+
+// managedFormationPlan gathers and filters the candidate hosts for the
+// contract formation on behalf of the renter. The preferred hosts, if
+// any, are attempted before the randomly selected ones. A preview plan
+// doesn't change the state of the contractor. ctx bounds the host latency
+// probes.
+func (c *Contractor) managedFormationPlan(ctx context.Context, renter modules.Renter, preferred []types.SiaPublicKey, blockHeight types.BlockHeight, preview bool) (*formationPlan, error) {
+	// Check the renter's allowance.
+	if err := modules.ValidateAllowance(renter.Allowance, 0, 0); err != nil {
+		return nil, err
+	}
+	endHeight, err := c.managedFormationEndHeight(renter.Allowance, blockHeight)
+	if err != nil {
+		return nil, err
+	}
+	fp := &formationPlan{
+		endHeight: endHeight,
+	}
+
+	// Depend on the PeriodSpending function to get a breakdown of spending in
+	// the contractor. Then use that to determine how many funds remain
+	// available in the allowance.
+	spending, err := c.PeriodSpending(renter.PublicKey)
+	if err != nil {
+		// This should only error if the contractor is shutting down.
+		return nil, err
+	}
+
+	fp.fundsRemaining = c.managedRemainingFunds(renter, spending.TotalAllocated)
+
+	// Count the number of contracts which are good for uploading, and then make
+	// more as needed to fill the gap.
+	fp.contractSet = make([]modules.RenterContract, 0, renter.Allowance.Hosts)
+	uploadContracts := 0
+	for _, contract := range c.staticContracts.ByRenter(renter.PublicKey) {
+		if cu, ok := c.managedContractUtility(contract.ID); ok && cu.GoodForUpload {
+			fp.contractSet = append(fp.contractSet, contract)
+			uploadContracts++
+			if uploadContracts >= int(renter.Allowance.Hosts) {
+				break
+			}
+		}
+	}
+	fp.neededContracts = int(renter.Allowance.Hosts) - uploadContracts
+	if fp.neededContracts <= 0 {
+		return fp, nil
+	}
+
+	// Assemble two exclusion lists. The first one includes all hosts that we
+	// already have contracts with and the second one includes all hosts we
+	// have active contracts with. Then select a new batch of hosts to attempt
+	// contract formation with.
+	allContracts := c.staticContracts.ByRenter(renter.PublicKey)
+	var blacklist []types.SiaPublicKey
+	var addressBlacklist []types.SiaPublicKey
+	for _, contract := range allContracts {
+		blacklist = append(blacklist, contract.HostPublicKey)
+		if !contract.Utility.Locked || contract.Utility.GoodForRenew || contract.Utility.GoodForUpload {
+			addressBlacklist = append(addressBlacklist, contract.HostPublicKey)
+		}
+	}
+
+	// Exclude the hosts that have reached the contract limit.
+	blacklist = append(blacklist, c.managedHostsAtCap()...)
+
+	// Determine the max and min initial contract funding based on the
+	// allowance settings.
+	c.mu.RLock()
+	factors := c.initialFunding
+	oversample := c.hostOversample
+	c.mu.RUnlock()
+	fp.maxFunds, fp.minFunds = factors.limits(renter.Allowance.Funds.Div64(renter.Allowance.Hosts))
+
+	// Get Hosts. If the renter has an allowlist, only the listed hosts
+	// are considered, and they are subject to the same checks as the
+	// preferred hosts and to the IP filter of the random selection.
+	c.mu.RLock()
+	allowlist := c.hostAllowlists[renter.PublicKey.String()]
+	c.mu.RUnlock()
+	if len(allowlist) > 0 {
+		fp.hosts, err = c.managedFilterAddresses(c.managedPreferredHosts(renter.Allowance, allowlist, nil, blacklist, fp.endHeight - blockHeight), addressBlacklist)
+		if err != nil {
+			return nil, err
+		}
+		// The preferred hosts dropped by the IP filter stay dropped.
+		var allowed []types.SiaPublicKey
+		for _, host := range fp.hosts {
+			allowed = append(allowed, host.PublicKey)
+		}
+		preferred = allowedHosts(preferred, allowed)
+	} else {
+		fp.hosts, err = c.managedRandomHostsWithLimits(fp.neededContracts * oversample + randomHostsBufferForScore, blacklist, addressBlacklist, renter.Allowance, preview)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if !preview {
+		c.managedCheckHostSupply(renter.PublicKey, fp.neededContracts, len(fp.hosts))
+	}
+
+	// Prefer the hosts offering more collateral if the renter wants it.
+	// The region spread below takes precedence.
+	if renter.PreferCollateral {
+		fp.hosts = c.managedPreferCollateral(fp.hosts)
+	}
+
+	// Put the preferred hosts first.
+	if len(preferred) > 0 {
+		fp.hosts = c.managedPreferredHosts(renter.Allowance, preferred, fp.hosts, blacklist, fp.endHeight - blockHeight)
+	}
+
+	// Skip the slow hosts if the renter has a latency bound set.
+	if renter.MaxHostLatency > 0 {
+		fp.hosts = c.managedFilterSlowHosts(ctx, fp.hosts, time.Duration(renter.MaxHostLatency) * time.Millisecond)
+	}
+
+	// Spread the contracts across the regions if the renter wants it.
+	if renter.MaxContractsPerRegion > 0 {
+		fp.regionCounts = c.managedRegionCounts(fp.contractSet)
+		fp.hosts = c.managedDiversifyHosts(fp.hosts, fp.regionCounts, renter.MaxContractsPerRegion)
+	}
+
+	// Keep a share of the contracts in the local region if the renter
+	// wants it.
+	if renter.LocalRegion != "" && renter.LocalFraction > 0 {
+		fp.hosts = c.managedLocalizeHosts(fp.hosts, fp.contractSet, renter)
+	}
+
+	// Calculate the anticipated transaction fee.
+	_, maxFee := c.tpool.FeeEstimation()
+	fp.txnFee = maxFee.Mul64(smodules.EstimatedFileContractTransactionSetSize)
+
+	return fp, nil
+}
+
+// PreviewContracts runs the host selection of FormContracts with the given
+// allowance and preferred hosts and returns the hosts that a formation
+// would attempt first, together with the projected funding, without
+// forming any contracts. If a host selection seed is set, the next
+// formation selects the same hosts. Otherwise, the random part of the
+// selection is drawn anew by each call, and the preview is only
+// indicative of the hosts a formation would use.
+func (c *Contractor) PreviewContracts(ctx context.Context, rpk types.SiaPublicKey, a smodules.Allowance, preferred []types.SiaPublicKey) ([]modules.FormationPreview, error) {
+	// No contract formation until the contractor is synced.
+	if !c.managedSynced() {
+		return nil, errors.New("contractor isn't synced yet")
+	}
+
+	// Check if we know this renter.
+	c.mu.RLock()
+	renter, exists := c.renters[rpk.String()]
+	blockHeight := c.blockHeight
+	c.mu.RUnlock()
+	if !exists {
+		return nil, ErrRenterNotFound
+	}
+	renter.Allowance = a
+
+	// Check the renter's storage quota.
+	if err := checkStorageQuota(renter); err != nil {
+		return nil, err
+	}
+
+	fp, err := c.managedFormationPlan(ctx, renter, preferred, blockHeight, true)
+	if err != nil {
+		return nil, err
+	}
+
+	// Walk the hosts the same way FormContracts does, assuming that every
+	// formation succeeds.
+	var preview []modules.FormationPreview
+	for _, host := range fp.hosts {
+		if fp.neededContracts <= 0 {
+			break
+		}
+		var region string
+		if renter.MaxContractsPerRegion > 0 {
+			region = c.managedHostRegion(host)
+			if fp.regionCounts[region] >= renter.MaxContractsPerRegion {
+				continue
+			}
+		}
+		funds := fp.contractFunding(host)
+		if fp.fundsRemaining.Cmp(funds) < 0 {
+			break
+		}
+		preview = append(preview, modules.FormationPreview{
+			HostPublicKey: host.PublicKey,
+			Funding:       funds,
+		})
+		fp.fundsRemaining = fp.fundsRemaining.Sub(funds)
+		fp.neededContracts--
+		if renter.MaxContractsPerRegion > 0 {
+			fp.regionCounts[region]++
+		}
+	}
+
+	return preview, nil
+}
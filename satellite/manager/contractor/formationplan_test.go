@@ -0,0 +1,120 @@
+package contractor
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/mike76-dev/sia-satellite/modules"
+
+	smodules "go.sia.tech/siad/modules"
+	"go.sia.tech/siad/types"
+)
+
+// TestPreviewMatchesFormation tests that the preview lists the hosts that
+// the following formation attempts, with the preferred hosts first.
+func TestPreviewMatchesFormation(t *testing.T) {
+	c, _ := newTestContractor(t)
+	setTestSynced(c)
+	rpk := testKey(1)
+	renter := testRenter(c, rpk)
+	hdb := newTestHostDB(c)
+	for i := 0; i < 30; i++ {
+		hdb.addHost(testHost(byte(10 + i), fmt.Sprintf("host%v.example.com:9982", i)), 100)
+	}
+	if err := setTestSettings(c, func(s *modules.ContractorSettings) { s.HostSelectionSeed = 42 }); err != nil {
+		t.Fatal(err)
+	}
+
+	preferred := []types.SiaPublicKey{testKey(35), testKey(20)}
+	preview, err := c.PreviewContracts(context.Background(), rpk, renter.Allowance, preferred)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(preview) != int(renter.Allowance.Hosts) {
+		t.Fatalf("expected %v hosts, got %v", renter.Allowance.Hosts, len(preview))
+	}
+	for i, hpk := range preferred {
+		if !preview[i].HostPublicKey.Equals(hpk) {
+			t.Fatalf("preferred host %v not at position %v", hpk, i)
+		}
+	}
+
+	// A second preview doesn't consume the seed.
+	again, err := c.PreviewContracts(context.Background(), rpk, renter.Allowance, preferred)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range preview {
+		if !again[i].HostPublicKey.Equals(preview[i].HostPublicKey) {
+			t.Fatal("previews differ")
+		}
+	}
+
+	// The formation attempts the previewed hosts in the same order.
+	fp, err := c.managedFormationPlan(context.Background(), renter, preferred, 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, p := range preview {
+		if !fp.hosts[i].PublicKey.Equals(p.HostPublicKey) {
+			t.Fatalf("formation host %v differs from the preview", i)
+		}
+		if funds := fp.contractFunding(fp.hosts[i]); !funds.Equals(p.Funding) {
+			t.Fatalf("formation funding %v differs from the preview", i)
+		}
+	}
+}
+
+// TestNotEnoughHostsAlert tests that the not-enough-hosts alert is kept
+// per renter and that a preview doesn't register it.
+func TestNotEnoughHostsAlert(t *testing.T) {
+	c, _ := newTestContractor(t)
+	setTestSynced(c)
+	hdb := newTestHostDB(c)
+	for i := 0; i < 3; i++ {
+		hdb.addHost(testHost(byte(10 + i), fmt.Sprintf("host%v.example.com:9982", i)), 100)
+	}
+	renter1 := testRenter(c, testKey(1))
+	renter2 := testRenter(c, testKey(2))
+
+	// A preview leaves the alerts alone.
+	if _, err := c.PreviewContracts(context.Background(), renter1.PublicKey, renter1.Allowance, nil); err != nil {
+		t.Fatal(err)
+	}
+	if hasAlert(c, AlertMSGNotEnoughHosts) {
+		t.Fatal("preview registered the alert")
+	}
+
+	// Each renter short of hosts gets its own alert.
+	for _, renter := range []modules.Renter{renter1, renter2} {
+		if _, err := c.managedFormationPlan(context.Background(), renter, nil, 0, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := countAlerts(c, AlertMSGNotEnoughHosts); n != 2 {
+		t.Fatalf("expected 2 alerts, got %v", n)
+	}
+
+	// Enough hosts for the first renter clear only its alert.
+	renter1.Allowance.Hosts = 3
+	if _, err := c.managedFormationPlan(context.Background(), renter1, nil, 0, false); err != nil {
+		t.Fatal(err)
+	}
+	if n := countAlerts(c, AlertMSGNotEnoughHosts); n != 1 {
+		t.Fatalf("expected 1 alert, got %v", n)
+	}
+}
+
+// countAlerts returns the number of registered alerts with the message.
+func countAlerts(c *Contractor, msg string) (n int) {
+	crit, err, warn, info := c.staticAlerter.Alerts()
+	for _, alerts := range [][]smodules.Alert{crit, err, warn, info} {
+		for _, alert := range alerts {
+			if alert.Msg == msg {
+				n++
+			}
+		}
+	}
+	return
+}
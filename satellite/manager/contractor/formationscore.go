@@ -0,0 +1,64 @@
+package contractor
+
+import (
+	"github.com/mike76-dev/sia-satellite/modules"
+
+	smodules "go.sia.tech/siad/modules"
+	"go.sia.tech/siad/types"
+)
+
+// FormationScore returns the host score of the contract at the time it was
+// formed. False is returned if the score is unknown, e.g. because the
+// contract is a renewal.
+func (c *Contractor) FormationScore(id types.FileContractID) (types.Currency, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	score, exists := c.formationScores[id]
+	return score, exists
+}
+
+// managedRecordFormationScore records the current score of the host as
+// the formation-time score of the contract.
+func (c *Contractor) managedRecordFormationScore(id types.FileContractID, host smodules.HostDBEntry) {
+	sb, err := c.managedScoreBreakdown(host)
+	if err != nil {
+		c.log.Println("WARN: unable to get the host score:", err)
+		return
+	}
+	if _, err := c.execWithRetry(`
+		INSERT INTO contract_formation_scores (contract_id, score)
+		VALUES (?, ?)
+	`, id.String(), sb.Score.String()); err != nil {
+		c.log.Println("ERROR: unable to record the formation score:", err)
+		return
+	}
+	c.mu.Lock()
+	c.formationScores[id] = sb.Score
+	c.mu.Unlock()
+}
+
+// loadFormationScores loads the formation-time host scores of the
+// contracts.
+func (c *Contractor) loadFormationScores() error {
+	rows, err := c.db.Query("SELECT contract_id, score FROM contract_formation_scores")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var id, s string
+	for rows.Next() {
+		if err := rows.Scan(&id, &s); err != nil {
+			c.log.Println("Error scanning database row:", err)
+			continue
+		}
+		var fcid types.FileContractID
+		if err := fcid.LoadString(id); err != nil {
+			c.log.Println("ERROR: wrong contract ID:", err)
+			continue
+		}
+		c.formationScores[fcid] = modules.ReadCurrency(s)
+	}
+
+	return rows.Err()
+}
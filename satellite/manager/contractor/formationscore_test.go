@@ -0,0 +1,81 @@
+package contractor
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/mike76-dev/sia-satellite/internal/dbtest"
+	"github.com/mike76-dev/sia-satellite/modules"
+
+	smodules "go.sia.tech/siad/modules"
+	"go.sia.tech/siad/types"
+)
+
+// TestFormationScore tests that the host score of a formed contract is
+// recorded, and that it is loaded back from the database.
+func TestFormationScore(t *testing.T) {
+	c, fake := newTestContractor(t)
+	setTestSynced(c)
+	c.wallet = &testWallet{unlocked: true, balance: types.SiacoinPrecision.Mul64(1e4)}
+	newTestFundLocker(c)
+	hdb := newTestHostDB(c)
+	for i := 0; i < 10; i++ {
+		hdb.addHost(testHost(byte(10 + i), fmt.Sprintf("10.0.0.%v:9982", i + 1)), uint64(100 + i))
+	}
+
+	// Keep the recorded scores, so that they can be loaded back.
+	var scores [][]driver.Value
+	fake.OnExec(func(query string, args []driver.Value) error {
+		if strings.Contains(query, "INSERT INTO contract_formation_scores") {
+			scores = append(scores, args)
+		}
+		return nil
+	})
+
+	var id byte
+	hostScores := make(map[types.FileContractID]types.Currency)
+	form := func(rpk types.SiaPublicKey, host smodules.HostDBEntry, funds types.Currency, endHeight types.BlockHeight) (types.Currency, modules.RenterContract, error) {
+		id++
+		contract := testContract(t, c, rpk, host.PublicKey, id, 0, endHeight, funds)
+		hostScores[contract.ID] = hdb.scores[host.PublicKey.String()]
+		return funds, contract, nil
+	}
+	rpk := testKey(1)
+	testRenter(c, rpk)
+	if _, err := c.managedFormContracts(context.Background(), rpk, nil, form); err != nil {
+		t.Fatal(err)
+	}
+	if len(hostScores) != 10 || len(scores) != 10 {
+		t.Fatalf("expected 10 contracts with scores, got %v contracts and %v scores", len(hostScores), len(scores))
+	}
+	for fcid, score := range hostScores {
+		if s, exists := c.FormationScore(fcid); !exists || !s.Equals(score) {
+			t.Fatalf("expected contract %v to have score %v, got %v", fcid, score, s)
+		}
+	}
+
+	// A contract that wasn't formed has no score.
+	if _, exists := c.FormationScore(types.FileContractID{99}); exists {
+		t.Fatal("expected no score for an unknown contract")
+	}
+
+	// The scores persist.
+	c2, fake2 := newTestContractor(t)
+	fake2.OnQuery(func(query string, _ []driver.Value) (*dbtest.Rows, error) {
+		if !strings.Contains(query, "FROM contract_formation_scores") {
+			return nil, nil
+		}
+		return &dbtest.Rows{Columns: []string{"contract_id", "score"}, Values: scores}, nil
+	})
+	if err := c2.loadFormationScores(); err != nil {
+		t.Fatal(err)
+	}
+	for fcid, score := range hostScores {
+		if s, exists := c2.FormationScore(fcid); !exists || !s.Equals(score) {
+			t.Fatalf("expected contract %v to load score %v, got %v", fcid, score, s)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package contractor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/mike76-dev/sia-satellite/modules"
+
+	smodules "go.sia.tech/siad/modules"
+	"go.sia.tech/siad/types"
+)
+
+// TestFormationSpending tests that the per-host spending breakdown of a
+// formation includes the failed formations that spent funds, and that it
+// sums to the funds locked for the renter.
+func TestFormationSpending(t *testing.T) {
+	c, _ := newTestContractor(t)
+	setTestSynced(c)
+	c.wallet = &testWallet{unlocked: true, balance: types.SiacoinPrecision.Mul64(1e4)}
+	fl := newTestFundLocker(c)
+	rpk := testKey(1)
+	renter := testRenter(c, rpk)
+	hdb := newTestHostDB(c)
+	for i := 0; i < 4; i++ {
+		hdb.addHost(testHost(byte(10 + i), fmt.Sprintf("host%v.example.com:9982", i)), 100)
+	}
+
+	// The second host fails after spending the funds, the third one
+	// before spending anything.
+	var id byte
+	form := func(rpk types.SiaPublicKey, host smodules.HostDBEntry, funds types.Currency, endHeight types.BlockHeight) (types.Currency, modules.RenterContract, error) {
+		switch host.PublicKey.Key[0] {
+		case 11:
+			return types.SiacoinPrecision.Mul64(2), modules.RenterContract{}, errors.New("host already has a contract")
+		case 12:
+			return types.ZeroCurrency, modules.RenterContract{}, errors.New("negotiation failed")
+		}
+		id++
+		return types.SiacoinPrecision.Mul64(5), testContract(t, c, rpk, host.PublicKey, id, 0, endHeight, funds), nil
+	}
+	result, err := c.managedFormContracts(context.Background(), rpk, nil, form)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Contracts) != 2 {
+		t.Fatalf("expected 2 contracts, got %v", len(result.Contracts))
+	}
+	if len(result.Spending) != 3 {
+		t.Fatalf("expected 3 spending entries, got %v", len(result.Spending))
+	}
+
+	total := types.ZeroCurrency
+	for _, hs := range result.Spending {
+		total = total.Add(hs.Spent)
+		failed := hs.HostPublicKey.Key[0] == 11
+		if hs.Formed == failed || (failed && hs.Error == "") {
+			t.Fatalf("wrong outcome of the formation with %v", hs.HostPublicKey)
+		}
+	}
+	if !total.Equals(types.SiacoinPrecision.Mul64(12)) {
+		t.Fatalf("expected 12 SC spent, got %v", total)
+	}
+	if locked := fl.lockedFunds(renter.Email); math.Abs(locked - 12) > 1e-9 {
+		t.Fatalf("expected 12 SC locked, got %v", locked)
+	}
+}
@@ -0,0 +1,34 @@
+package contractor
+
+import (
+	"go.sia.tech/siad/types"
+)
+
+// managedStartGFUCooldown keeps the host out of the renter's GoodForUpload
+// set for the configured number of blocks, so that the GFU limiter doesn't
+// make the set flap between similar hosts.
+func (c *Contractor) managedStartGFUCooldown(rpk, hpk types.SiaPublicKey) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.gfuChurnCooldown == 0 {
+		return
+	}
+	c.gfuCooldowns[rpk.String() + hpk.String()] = c.blockHeight + c.gfuChurnCooldown
+}
+
+// managedInGFUCooldown returns true if the host is still in the cooldown
+// of the renter's GoodForUpload set. Expired cooldowns are removed.
+func (c *Contractor) managedInGFUCooldown(rpk, hpk types.SiaPublicKey) bool {
+	key := rpk.String() + hpk.String()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	expiry, exists := c.gfuCooldowns[key]
+	if !exists {
+		return false
+	}
+	if c.blockHeight >= expiry {
+		delete(c.gfuCooldowns, key)
+		return false
+	}
+	return true
+}
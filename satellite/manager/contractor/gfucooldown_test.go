@@ -0,0 +1,72 @@
+package contractor
+
+import (
+	"testing"
+
+	smodules "go.sia.tech/siad/modules"
+	"go.sia.tech/siad/types"
+)
+
+// TestGFUCooldown tests that a host dropped by the GFU limiter isn't made
+// GoodForUpload again until its cooldown has passed.
+func TestGFUCooldown(t *testing.T) {
+	c, _ := newTestContractor(t)
+	rpk := testKey(1)
+	renter := testRenter(c, rpk)
+	renter.Allowance.Hosts = 1
+	c.mu.Lock()
+	c.renters[rpk.String()] = renter
+	c.mu.Unlock()
+
+	best, churned := testHost(2, "best.example.com:9982"), testHost(3, "churned.example.com:9982")
+	hdb := newTestHostDB(c)
+	hdb.addHost(best, 100)
+	hdb.addHost(churned, 90)
+
+	gfu := smodules.ContractUtility{GoodForUpload: true, GoodForRenew: true}
+	bestContract := testContract(t, c, rpk, best.PublicKey, 1, 0, 1000, types.SiacoinPrecision)
+	churnedContract := testContract(t, c, rpk, churned.PublicKey, 2, 0, 1000, types.SiacoinPrecision)
+	setTestUtility(t, c, bestContract.ID, gfu)
+	setTestUtility(t, c, churnedContract.ID, gfu)
+
+	c.managedLimitGFUHosts()
+	if u, _ := c.managedContractUtility(churnedContract.ID); u.GoodForUpload {
+		t.Fatal("the worse host was kept by the limiter")
+	}
+
+	// The utility checks pass, but the host stays out during the cooldown.
+	minScore := types.NewCurrency64(1)
+	mark := func() smodules.ContractUtility {
+		t.Helper()
+		if err := c.managedMarkContractUtility(churnedContract, minScore, minScore); err != nil {
+			t.Fatal(err)
+		}
+		u, _ := c.managedContractUtility(churnedContract.ID)
+		return u
+	}
+	cooldown := c.ContractorSettings().GFUChurnCooldown
+	for _, height := range []types.BlockHeight{0, cooldown - 1} {
+		c.mu.Lock()
+		c.blockHeight = height
+		c.mu.Unlock()
+		if u := mark(); u.GoodForUpload || !u.GoodForRenew {
+			t.Fatalf("height %v: expected the host to be GFR but not GFU, got %+v", height, u)
+		}
+	}
+
+	// Once the cooldown has passed, the host is GoodForUpload again.
+	c.mu.Lock()
+	c.blockHeight = cooldown
+	c.mu.Unlock()
+	if u := mark(); !u.GoodForUpload || !u.GoodForRenew {
+		t.Fatal("expected the host to be GFU after the cooldown, got", u)
+	}
+	if c.managedInGFUCooldown(rpk, churned.PublicKey) {
+		t.Fatal("expected the expired cooldown to be removed")
+	}
+
+	// The kept host was never in the cooldown.
+	if c.managedInGFUCooldown(rpk, best.PublicKey) {
+		t.Fatal("the kept host was put in the cooldown")
+	}
+}
@@ -0,0 +1,57 @@
+package contractor
+
+import (
+	"testing"
+
+	"go.sia.tech/siad/types"
+
+	smodules "go.sia.tech/siad/modules"
+)
+
+// setTestFileSize sets the size of the data stored in the contract.
+func setTestFileSize(t *testing.T, c *Contractor, id types.FileContractID, size uint64) {
+	t.Helper()
+	fc, ok := c.staticContracts.Acquire(id)
+	if !ok {
+		t.Fatal("contract not found")
+	}
+	defer c.staticContracts.Return(fc)
+	rev := fc.LastRevision()
+	rev.NewFileSize = size
+	if err := fc.RecordPaymentIntent(rev, types.ZeroCurrency, smodules.SpendingDetails{}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestLimitGFUHostsStoredData tests that a lower-score host holding a lot
+// of the renter's data is kept over an empty host with a higher score.
+func TestLimitGFUHostsStoredData(t *testing.T) {
+	c, _ := newTestContractor(t)
+	rpk := testKey(1)
+	renter := testRenter(c, rpk)
+	renter.Allowance.Hosts = 1
+	c.mu.Lock()
+	c.renters[rpk.String()] = renter
+	c.mu.Unlock()
+
+	full, empty := testHost(2, "full.example.com:9982"), testHost(3, "empty.example.com:9982")
+	hdb := newTestHostDB(c)
+	hdb.addHost(full, 90)
+	hdb.addHost(empty, 100)
+
+	gfu := smodules.ContractUtility{GoodForUpload: true, GoodForRenew: true}
+	fullContract := testContract(t, c, rpk, full.PublicKey, 1, 0, 1000, types.SiacoinPrecision)
+	emptyContract := testContract(t, c, rpk, empty.PublicKey, 2, 0, 1000, types.SiacoinPrecision)
+	setTestUtility(t, c, fullContract.ID, gfu)
+	setTestUtility(t, c, emptyContract.ID, gfu)
+	setTestFileSize(t, c, fullContract.ID, 1 << 30)
+
+	c.managedLimitGFUHosts()
+
+	if u, _ := c.managedContractUtility(fullContract.ID); !u.GoodForUpload {
+		t.Fatal("the host with the data wasn't kept")
+	}
+	if u, _ := c.managedContractUtility(emptyContract.ID); u.GoodForUpload {
+		t.Fatal("the empty host was kept")
+	}
+}
@@ -0,0 +1,485 @@
+package contractor
+
+import (
+	"database/sql/driver"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/mike76-dev/sia-satellite/internal/dbtest"
+	"github.com/mike76-dev/sia-satellite/modules"
+	"github.com/mike76-dev/sia-satellite/satellite/manager/proto"
+
+	"go.sia.tech/siad/crypto"
+	smodules "go.sia.tech/siad/modules"
+	"go.sia.tech/siad/persist"
+	"go.sia.tech/siad/types"
+)
+
+// newTestContractor returns a contractor backed by a test database. The
+// consensus set, the wallet, the transaction pool and the hostdb are nil
+// unless set by the test.
+func newTestContractor(t *testing.T) (*Contractor, *dbtest.DB) {
+	t.Helper()
+	db, fake := dbtest.Open()
+	dir := t.TempDir()
+	logger, err := persist.NewFileLogger(filepath.Join(dir, "contractor.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { logger.Close() })
+	contractSet, err := proto.NewContractSet(db, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := newContractor(nil, nil, nil, nil, dir, contractSet, db, logger, nil)
+	return c, fake
+}
+
+// setTestSettings applies the changes made by update to the contractor
+// settings.
+func setTestSettings(c *Contractor, update func(*modules.ContractorSettings)) error {
+	settings := c.ContractorSettings()
+	update(&settings)
+	return c.SetContractorSettings(settings)
+}
+
+// testLog returns the contents of the contractor log.
+func testLog(t *testing.T, c *Contractor) string {
+	t.Helper()
+	b, err := os.ReadFile(filepath.Join(c.persistDir, "contractor.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+// testKey returns a public key that is unique for the byte.
+func testKey(b byte) types.SiaPublicKey {
+	key := make([]byte, crypto.PublicKeySize)
+	key[0] = b
+	return types.SiaPublicKey{
+		Algorithm: types.SignatureEd25519,
+		Key:       key,
+	}
+}
+
+// testRenter adds a renter with a default allowance to the contractor.
+func testRenter(c *Contractor, rpk types.SiaPublicKey) modules.Renter {
+	renter := modules.Renter{
+		Allowance: smodules.Allowance{
+			Funds:       types.SiacoinPrecision.Mul64(1000),
+			Hosts:       10,
+			Period:      1000,
+			RenewWindow: 100,
+
+			ExpectedStorage:    1e12,
+			ExpectedUpload:     1e9,
+			ExpectedDownload:   1e9,
+			ExpectedRedundancy: 3,
+		},
+		PublicKey: rpk,
+		Email:     "renter@example.com",
+	}
+	c.mu.Lock()
+	c.renters[rpk.String()] = renter
+	c.mu.Unlock()
+	return renter
+}
+
+// testContract inserts a contract between the renter and the host into
+// the contract set. The ID is derived from the id byte.
+func testContract(t *testing.T, c *Contractor, rpk, hpk types.SiaPublicKey, id byte, start, end types.BlockHeight, funds types.Currency) modules.RenterContract {
+	t.Helper()
+	var fcid types.FileContractID
+	fcid[0] = id
+	rev := types.FileContractRevision{
+		ParentID: fcid,
+		UnlockConditions: types.UnlockConditions{
+			PublicKeys:         []types.SiaPublicKey{rpk, hpk},
+			SignaturesRequired: 2,
+		},
+		NewWindowStart: end,
+		NewWindowEnd:   end + 144,
+		NewValidProofOutputs: []types.SiacoinOutput{
+			{Value: funds},
+			{Value: types.ZeroCurrency},
+		},
+		NewMissedProofOutputs: []types.SiacoinOutput{
+			{Value: funds},
+			{Value: types.ZeroCurrency},
+			{Value: types.ZeroCurrency},
+		},
+	}
+	rc := modules.RecoverableContract{
+		FileContract: types.FileContract{
+			ValidProofOutputs: rev.NewValidProofOutputs,
+		},
+		StartHeight: start,
+	}
+	txn := types.Transaction{
+		FileContractRevisions: []types.FileContractRevision{rev},
+	}
+	contract, err := c.staticContracts.InsertContract(rc, txn, nil, crypto.SecretKey{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return contract
+}
+
+// setTestUtility sets the utility of the contract.
+func setTestUtility(t *testing.T, c *Contractor, id types.FileContractID, u smodules.ContractUtility) {
+	t.Helper()
+	fc, ok := c.staticContracts.Acquire(id)
+	if !ok {
+		t.Fatal("contract not found")
+	}
+	defer c.staticContracts.Return(fc)
+	if err := fc.UpdateUtility(u); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// renterColumns are the columns returned by the renters query.
+var renterColumns = []string{
+	"email", "suffix", "public_key", "current_period", "funds", "hosts",
+	"period", "renew_window", "expected_storage", "expected_upload",
+	"expected_download", "expected_redundancy", "max_rpc_price",
+	"max_contract_price", "max_download_bandwidth_price",
+	"max_sector_access_price", "max_storage_price",
+	"max_upload_bandwidth_price", "allow_redundant_ips", "max_storage_bytes",
+	"max_contracts_per_region", "paused", "prefer_collateral",
+	"spend_rate_refresh", "max_host_latency", "local_region",
+	"local_fraction", "reuse_refund_address",
+}
+
+// renterRow returns the database row of the renter.
+func renterRow(r modules.Renter) []driver.Value {
+	a := r.Allowance
+	return []driver.Value{
+		r.Email, r.Suffix, r.PublicKey.String(), int64(r.CurrentPeriod),
+		a.Funds.String(), int64(a.Hosts), int64(a.Period), int64(a.RenewWindow),
+		int64(a.ExpectedStorage), int64(a.ExpectedUpload), int64(a.ExpectedDownload),
+		a.ExpectedRedundancy, a.MaxRPCPrice.String(), a.MaxContractPrice.String(),
+		a.MaxDownloadBandwidthPrice.String(), a.MaxSectorAccessPrice.String(),
+		a.MaxStoragePrice.String(), a.MaxUploadBandwidthPrice.String(),
+		r.AllowRedundantIPs, int64(r.MaxStorageBytes), int64(r.MaxContractsPerRegion),
+		r.Paused, r.PreferCollateral, r.SpendRateRefresh, int64(r.MaxHostLatency),
+		r.LocalRegion, r.LocalFraction, r.ReuseRefundAddress,
+	}
+}
+
+// serveRenters makes the renters query return the given renters.
+func serveRenters(fake *dbtest.DB, renters ...modules.Renter) {
+	fake.OnQuery(func(query string, _ []driver.Value) (*dbtest.Rows, error) {
+		if !strings.Contains(query, "FROM renters") {
+			return nil, nil
+		}
+		rows := &dbtest.Rows{Columns: renterColumns}
+		for _, r := range renters {
+			rows.Values = append(rows.Values, renterRow(r))
+		}
+		return rows, nil
+	})
+}
+
+// testHostDB is a hostdb with a fixed set of hosts. The methods that are
+// not overridden panic.
+type testHostDB struct {
+	modules.HostDB
+	mu     sync.Mutex
+	hosts  []smodules.HostDBEntry
+	scores map[string]types.Currency
+
+	filterMode smodules.FilterMode
+	filtered   map[string]types.SiaPublicKey
+}
+
+// newTestHostDB returns a hostdb with the given hosts and sets it as the
+// hostdb of the contractor.
+func newTestHostDB(c *Contractor, hosts ...smodules.HostDBEntry) *testHostDB {
+	hdb := &testHostDB{scores: make(map[string]types.Currency)}
+	for i, host := range hosts {
+		hdb.addHost(host, uint64(len(hosts) - i))
+	}
+	c.hdb = hdb
+	return hdb
+}
+
+// testHost returns a host with the given key and net address.
+func testHost(b byte, addr string) smodules.HostDBEntry {
+	var host smodules.HostDBEntry
+	host.PublicKey = testKey(b)
+	host.NetAddress = smodules.NetAddress(addr)
+	host.AcceptingContracts = true
+	host.MaxDuration = 1e6
+	host.Version = "1.5.10"
+	host.ScanHistory = smodules.HostDBScans{{Success: true}}
+	return host
+}
+
+// addHost adds the host with the given score.
+func (hdb *testHostDB) addHost(host smodules.HostDBEntry, score uint64) {
+	hdb.mu.Lock()
+	defer hdb.mu.Unlock()
+	hdb.hosts = append(hdb.hosts, host)
+	hdb.scores[host.PublicKey.String()] = types.NewCurrency64(score)
+}
+
+// Host implements modules.HostDB.
+func (hdb *testHostDB) Host(pk types.SiaPublicKey) (smodules.HostDBEntry, bool, error) {
+	hdb.mu.Lock()
+	defer hdb.mu.Unlock()
+	for _, host := range hdb.hosts {
+		if host.PublicKey.Equals(pk) {
+			return host, true, nil
+		}
+	}
+	return smodules.HostDBEntry{}, false, nil
+}
+
+// ScoreBreakdown implements modules.HostDB.
+func (hdb *testHostDB) ScoreBreakdown(host smodules.HostDBEntry) (smodules.HostScoreBreakdown, error) {
+	hdb.mu.Lock()
+	defer hdb.mu.Unlock()
+	return smodules.HostScoreBreakdown{Score: hdb.scores[host.PublicKey.String()]}, nil
+}
+
+// EstimateHostScore implements modules.HostDB.
+func (hdb *testHostDB) EstimateHostScore(host smodules.HostDBEntry, _ smodules.Allowance) (smodules.HostScoreBreakdown, error) {
+	return hdb.ScoreBreakdown(host)
+}
+
+// ActiveHosts implements modules.HostDB.
+func (hdb *testHostDB) ActiveHosts() ([]smodules.HostDBEntry, error) {
+	hdb.mu.Lock()
+	defer hdb.mu.Unlock()
+	return append([]smodules.HostDBEntry(nil), hdb.hosts...), nil
+}
+
+// AllHosts implements modules.HostDB.
+func (hdb *testHostDB) AllHosts() ([]smodules.HostDBEntry, error) {
+	return hdb.ActiveHosts()
+}
+
+// RandomHostsWithLimits implements modules.HostDB. It returns the first
+// n hosts that are not blacklisted.
+func (hdb *testHostDB) RandomHostsWithLimits(n int, blacklist, _ []types.SiaPublicKey, _ smodules.Allowance) ([]smodules.HostDBEntry, error) {
+	hdb.mu.Lock()
+	defer hdb.mu.Unlock()
+	var hosts []smodules.HostDBEntry
+	for _, host := range hdb.hosts {
+		if len(hosts) >= n {
+			break
+		}
+		blacklisted := false
+		for _, pk := range blacklist {
+			if pk.Equals(host.PublicKey) {
+				blacklisted = true
+				break
+			}
+		}
+		if !blacklisted {
+			hosts = append(hosts, host)
+		}
+	}
+	return hosts, nil
+}
+
+// RandomHostsWithLimitsSeeded implements modules.HostDB. The order of the
+// hosts is determined by the seed.
+func (hdb *testHostDB) RandomHostsWithLimitsSeeded(n int, blacklist, addressBlacklist []types.SiaPublicKey, a smodules.Allowance, seed int64) ([]smodules.HostDBEntry, error) {
+	hosts, _ := hdb.RandomHostsWithLimits(len(hdb.hosts), blacklist, addressBlacklist, a)
+	rand.New(rand.NewSource(seed)).Shuffle(len(hosts), func(i, j int) {
+		hosts[i], hosts[j] = hosts[j], hosts[i]
+	})
+	if len(hosts) > n {
+		hosts = hosts[:n]
+	}
+	return hosts, nil
+}
+
+// setFilter sets the filter mode and the listed hosts.
+func (hdb *testHostDB) setFilter(fm smodules.FilterMode, hosts ...types.SiaPublicKey) {
+	hdb.mu.Lock()
+	defer hdb.mu.Unlock()
+	hdb.filterMode = fm
+	hdb.filtered = make(map[string]types.SiaPublicKey)
+	for _, hpk := range hosts {
+		hdb.filtered[hpk.String()] = hpk
+	}
+}
+
+// Filter implements modules.HostDB. The filter is disabled unless set.
+func (hdb *testHostDB) Filter() (smodules.FilterMode, map[string]types.SiaPublicKey, []string, error) {
+	hdb.mu.Lock()
+	defer hdb.mu.Unlock()
+	if hdb.filterMode == smodules.HostDBFilterError {
+		return smodules.HostDBDisableFilter, nil, nil, nil
+	}
+	return hdb.filterMode, hdb.filtered, nil, nil
+}
+
+// CheckForIPViolations implements modules.HostDB. The hosts sharing a
+// host name violate the rules, except for the one that has had it for
+// the longest time, and so do the unknown hosts.
+func (hdb *testHostDB) CheckForIPViolations(pks []types.SiaPublicKey) ([]types.SiaPublicKey, error) {
+	var entries []smodules.HostDBEntry
+	var badHosts []types.SiaPublicKey
+	for _, pk := range pks {
+		host, exists, _ := hdb.Host(pk)
+		if !exists {
+			badHosts = append(badHosts, pk)
+			continue
+		}
+		entries = append(entries, host)
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].LastIPNetChange.Before(entries[j].LastIPNetChange)
+	})
+	seen := make(map[string]struct{})
+	for _, host := range entries {
+		if _, dup := seen[host.NetAddress.Host()]; dup {
+			badHosts = append(badHosts, host.PublicKey)
+			continue
+		}
+		seen[host.NetAddress.Host()] = struct{}{}
+	}
+	return badHosts, nil
+}
+
+// UpdateContracts implements modules.HostDB.
+func (hdb *testHostDB) UpdateContracts([]modules.RenterContract) error {
+	return nil
+}
+
+// testTpool is a transaction pool with fixed fee estimates and a fixed
+// number of pending transactions. The methods that are not overridden
+// panic.
+type testTpool struct {
+	smodules.TransactionPool
+	pending int
+}
+
+// FeeEstimation implements smodules.TransactionPool.
+func (testTpool) FeeEstimation() (types.Currency, types.Currency) {
+	return types.SiacoinPrecision.Div64(1e6), types.SiacoinPrecision.Div64(1e5)
+}
+
+// TransactionList implements smodules.TransactionPool.
+func (tp testTpool) TransactionList() []types.Transaction {
+	return make([]types.Transaction, tp.pending)
+}
+
+// setTestSynced marks the contractor as synced and sets its tpool.
+func setTestSynced(c *Contractor) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.tpool = testTpool{}
+	select {
+	case <-c.synced:
+	default:
+		close(c.synced)
+	}
+}
+
+// testFundLocker records the funds locked and unlocked per email.
+type testFundLocker struct {
+	mu     sync.Mutex
+	locked map[string]float64
+}
+
+// newTestFundLocker returns a fund locker and sets it as the satellite of
+// the contractor.
+func newTestFundLocker(c *Contractor) *testFundLocker {
+	fl := &testFundLocker{locked: make(map[string]float64)}
+	c.SetSatellite(fl)
+	return fl
+}
+
+// LockSiacoins implements modules.FundLocker.
+func (fl *testFundLocker) LockSiacoins(email string, amount float64) error {
+	fl.mu.Lock()
+	defer fl.mu.Unlock()
+	fl.locked[email] += amount
+	return nil
+}
+
+// UnlockSiacoins implements modules.FundLocker.
+func (fl *testFundLocker) UnlockSiacoins(email string, amount, _ float64) error {
+	fl.mu.Lock()
+	defer fl.mu.Unlock()
+	fl.locked[email] -= amount
+	return nil
+}
+
+// lockedFunds returns the funds locked for the email.
+func (fl *testFundLocker) lockedFunds(email string) float64 {
+	fl.mu.Lock()
+	defer fl.mu.Unlock()
+	return fl.locked[email]
+}
+
+// testWallet is a wallet that can be locked and unlocked. The methods
+// that are not overridden panic.
+type testWallet struct {
+	smodules.Wallet
+	mu       sync.Mutex
+	unlocked bool
+	checks   int
+	balance  types.Currency
+}
+
+// ConfirmedBalance implements smodules.Wallet.
+func (w *testWallet) ConfirmedBalance() (types.Currency, types.Currency, types.Currency, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.balance, types.ZeroCurrency, types.ZeroCurrency, nil
+}
+
+// draw takes the amount from the wallet balance.
+func (w *testWallet) draw(amount types.Currency) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.balance = w.balance.Sub(amount)
+}
+
+// Unlocked implements smodules.Wallet.
+func (w *testWallet) Unlocked() (bool, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.checks++
+	return w.unlocked, nil
+}
+
+// setUnlocked locks or unlocks the wallet.
+func (w *testWallet) setUnlocked(unlocked bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.unlocked = unlocked
+}
+
+// numChecks returns how many times the wallet was checked.
+func (w *testWallet) numChecks() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.checks
+}
+
+// hasAlert returns true if the contractor has an alert with the message
+// registered.
+func hasAlert(c *Contractor, msg string) bool {
+	crit, err, warn, info := c.staticAlerter.Alerts()
+	for _, alerts := range [][]smodules.Alert{crit, err, warn, info} {
+		for _, alert := range alerts {
+			if alert.Msg == msg {
+				return true
+			}
+		}
+	}
+	return false
+}
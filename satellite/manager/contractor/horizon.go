@@ -0,0 +1,83 @@
+package contractor
+
+import (
+	"fmt"
+
+	"github.com/mike76-dev/sia-satellite/modules"
+
+	"gitlab.com/NebulousLabs/errors"
+
+	smodules "go.sia.tech/siad/modules"
+	"go.sia.tech/siad/types"
+)
+
+var (
+	// errBeyondHorizon is returned when the contracts formed with an
+	// allowance would end beyond the end height horizon.
+	errBeyondHorizon = errors.New("period and renew window exceed the end height horizon")
+
+	// errHorizonTooShort is returned when the end height horizon doesn't
+	// exceed the renew window, so that the contracts would be due for
+	// renewal as soon as they are formed.
+	errHorizonTooShort = errors.New("end height horizon must be longer than the renew window")
+)
+
+// EndHeightHorizon returns how far in the future a formed contract may end,
+// and whether the end height is clamped to the horizon instead of refusing
+// the formation. A zero horizon means no limit.
+func (c *Contractor) EndHeightHorizon() (types.BlockHeight, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.endHeightHorizon, c.clampEndHeight
+}
+
+// managedCheckHorizon returns an error if the horizon doesn't exceed the
+// renew window of a renter with an allowance.
+func (c *Contractor) managedCheckHorizon(horizon types.BlockHeight) error {
+	if horizon == 0 {
+		return nil
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, renter := range c.renters {
+		if renter.Allowance.Active() && renter.Allowance.RenewWindow >= horizon {
+			return errors.AddContext(errHorizonTooShort, fmt.Sprintf("renter %v has a renew window of %v blocks", renter.PublicKey.String(), renter.Allowance.RenewWindow))
+		}
+	}
+	return nil
+}
+
+// managedFormationEndHeight returns the end height of the contracts formed
+// with the allowance at the given block height, applying the end height
+// horizon.
+func (c *Contractor) managedFormationEndHeight(a smodules.Allowance, blockHeight types.BlockHeight) (types.BlockHeight, error) {
+	horizon, clamp := c.EndHeightHorizon()
+	span := a.Period + a.RenewWindow
+	if horizon == 0 || span <= horizon {
+		return blockHeight + span, nil
+	}
+	if a.RenewWindow >= horizon {
+		return 0, errors.AddContext(errHorizonTooShort, fmt.Sprintf("renew window is %v blocks, horizon is %v blocks", a.RenewWindow, horizon))
+	}
+	if clamp {
+		return blockHeight + horizon, nil
+	}
+	return 0, errors.AddContext(errBeyondHorizon, fmt.Sprintf("%v blocks requested, horizon is %v blocks", span, horizon))
+}
+
+// managedRenewEndHeight returns the end height of the renter's contracts
+// renewed at the given block height, applying the end height horizon.
+func (c *Contractor) managedRenewEndHeight(renter modules.Renter, blockHeight types.BlockHeight) (types.BlockHeight, error) {
+	horizon, clamp := c.EndHeightHorizon()
+	endHeight := renter.ContractEndHeight()
+	if horizon == 0 || endHeight <= blockHeight + horizon {
+		return endHeight, nil
+	}
+	if renter.Allowance.RenewWindow >= horizon {
+		return 0, errors.AddContext(errHorizonTooShort, fmt.Sprintf("renew window is %v blocks, horizon is %v blocks", renter.Allowance.RenewWindow, horizon))
+	}
+	if clamp {
+		return blockHeight + horizon, nil
+	}
+	return 0, errors.AddContext(errBeyondHorizon, fmt.Sprintf("%v blocks requested, horizon is %v blocks", endHeight - blockHeight, horizon))
+}
@@ -0,0 +1,51 @@
+package contractor
+
+import (
+	"testing"
+
+	"github.com/mike76-dev/sia-satellite/modules"
+
+	"gitlab.com/NebulousLabs/errors"
+)
+
+// TestEndHeightHorizon tests that the horizon is applied to the renewals,
+// and that a horizon not exceeding the renew window is rejected.
+func TestEndHeightHorizon(t *testing.T) {
+	c, _ := newTestContractor(t)
+	renter := testRenter(c, testKey(1))
+
+	// The horizon must exceed the renew window of 100 blocks.
+	if err := setTestSettings(c, func(s *modules.ContractorSettings) { s.EndHeightHorizon, s.ClampEndHeight = 100, true }); !errors.Contains(err, errHorizonTooShort) {
+		t.Fatal("expected errHorizonTooShort, got", err)
+	}
+	c.mu.Lock()
+	c.endHeightHorizon = 100
+	c.mu.Unlock()
+	if _, err := c.managedFormationEndHeight(renter.Allowance, 0); !errors.Contains(err, errHorizonTooShort) {
+		t.Fatal("expected errHorizonTooShort, got", err)
+	}
+	if _, err := c.managedRenewEndHeight(renter, 0); !errors.Contains(err, errHorizonTooShort) {
+		t.Fatal("expected errHorizonTooShort, got", err)
+	}
+
+	// A renewal within the horizon keeps the end height.
+	if err := setTestSettings(c, func(s *modules.ContractorSettings) { s.EndHeightHorizon, s.ClampEndHeight = 500, true }); err != nil {
+		t.Fatal(err)
+	}
+	if endHeight, err := c.managedRenewEndHeight(renter, 900); err != nil || endHeight != renter.ContractEndHeight() {
+		t.Fatalf("expected end height %v, got %v: %v", renter.ContractEndHeight(), endHeight, err)
+	}
+
+	// A renewal beyond the horizon is clamped to it.
+	if endHeight, err := c.managedRenewEndHeight(renter, 100); err != nil || endHeight != 600 {
+		t.Fatalf("expected end height 600, got %v: %v", endHeight, err)
+	}
+
+	// Or refused if the horizon isn't clamping.
+	if err := setTestSettings(c, func(s *modules.ContractorSettings) { s.EndHeightHorizon, s.ClampEndHeight = 500, false }); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.managedRenewEndHeight(renter, 100); !errors.Contains(err, errBeyondHorizon) {
+		t.Fatal("expected errBeyondHorizon, got", err)
+	}
+}
@@ -0,0 +1,91 @@
+package contractor
+
+import (
+	"sort"
+
+	"github.com/mike76-dev/sia-satellite/modules"
+
+	"go.sia.tech/siad/types"
+)
+
+// activeContract returns true if the contract hasn't been canceled.
+func activeContract(rc modules.RenterContract) bool {
+	u := rc.Utility
+	return !u.Locked || u.GoodForRenew || u.GoodForUpload
+}
+
+// managedHostContractCounts returns the number of the active contracts
+// with each host across all renters, keyed by the host key.
+func (c *Contractor) managedHostContractCounts() map[string]int {
+	counts := make(map[string]int)
+	for _, contract := range c.staticContracts.ViewAll() {
+		if activeContract(contract) {
+			counts[contract.HostPublicKey.String()]++
+		}
+	}
+	return counts
+}
+
+// managedHostsAtCap returns the hosts that have reached the contract limit
+// across all renters. Nil is returned if there is no limit.
+func (c *Contractor) managedHostsAtCap() []types.SiaPublicKey {
+	c.mu.RLock()
+	limit := c.maxContractsPerHost
+	c.mu.RUnlock()
+	if limit <= 0 {
+		return nil
+	}
+
+	var hosts []types.SiaPublicKey
+	for key, n := range c.managedHostContractCounts() {
+		if n >= limit {
+			hosts = append(hosts, modules.ReadPublicKey(key))
+		}
+	}
+	return hosts
+}
+
+// managedChurnExcessHostContracts marks the contracts exceeding the
+// per-host contract limit as !GoodForUpload and !GoodForRenew, if the
+// operator opted in. The contracts with the least renter funds are churned
+// first. It returns the number of the contracts churned.
+func (c *Contractor) managedChurnExcessHostContracts() int {
+	c.mu.RLock()
+	limit := c.maxContractsPerHost
+	churn := c.churnExcessHostContracts
+	c.mu.RUnlock()
+	if limit <= 0 || !churn {
+		return 0
+	}
+
+	byHost := make(map[string][]modules.RenterContract)
+	for _, contract := range c.staticContracts.ViewAll() {
+		if contract.Utility.GoodForRenew || contract.Utility.GoodForUpload {
+			key := contract.HostPublicKey.String()
+			byHost[key] = append(byHost[key], contract)
+		}
+	}
+
+	var churned int
+	for _, contracts := range byHost {
+		if len(contracts) <= limit {
+			continue
+		}
+		sort.Slice(contracts, func(i, j int) bool {
+			return contracts[i].RenterFunds.Cmp(contracts[j].RenterFunds) < 0
+		})
+		for _, contract := range contracts[:len(contracts) - limit] {
+			u := contract.Utility
+			u.GoodForUpload = false
+			u.GoodForRenew = false
+			if err := c.managedAcquireAndUpdateContractUtility(contract.ID, u, "host over the contract limit"); err != nil {
+				c.log.Println("WARN: unable to update the utility of the contract with a host over the limit:", contract.ID, err)
+				continue
+			}
+			c.log.Println("INFO: contract marked as having no utility because the host is over the contract limit:", contract.ID)
+			churned++
+		}
+	}
+
+	return churned
+}
@@ -0,0 +1,92 @@
+package contractor
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/mike76-dev/sia-satellite/modules"
+
+	"gitlab.com/NebulousLabs/errors"
+
+	smodules "go.sia.tech/siad/modules"
+	"go.sia.tech/siad/types"
+)
+
+// TestHostContractLimit tests that the hosts at the contract limit are
+// excluded from the formations of further renters, and that the excess
+// contracts are churned if the operator opted in.
+func TestHostContractLimit(t *testing.T) {
+	c, _ := newTestContractor(t)
+	setTestSynced(c)
+	c.wallet = &testWallet{unlocked: true, balance: types.SiacoinPrecision.Mul64(1e4)}
+	newTestFundLocker(c)
+	hdb := newTestHostDB(c)
+	for i := 0; i < 12; i++ {
+		hdb.addHost(testHost(byte(10 + i), fmt.Sprintf("10.0.%v.1:9982", i)), 100)
+	}
+
+	// The limit must not be negative.
+	if err := setTestSettings(c, func(s *modules.ContractorSettings) { s.MaxContractsPerHost = -1 }); !errors.Contains(err, errInvalidHostContractLimit) {
+		t.Fatal("expected errInvalidHostContractLimit, got", err)
+	}
+	if err := setTestSettings(c, func(s *modules.ContractorSettings) { s.MaxContractsPerHost = 1 }); err != nil {
+		t.Fatal(err)
+	}
+
+	var id byte
+	hosts := make(map[string]int)
+	form := func(rpk types.SiaPublicKey, host smodules.HostDBEntry, funds types.Currency, endHeight types.BlockHeight) (types.Currency, modules.RenterContract, error) {
+		id++
+		hosts[host.PublicKey.String()]++
+		return funds, testContract(t, c, rpk, host.PublicKey, id, 0, endHeight, funds), nil
+	}
+
+	// The first renter uses 10 of the 12 hosts.
+	rpk := testKey(1)
+	testRenter(c, rpk)
+	if _, err := c.managedFormContracts(context.Background(), rpk, nil, form); err != nil {
+		t.Fatal(err)
+	}
+	if len(hosts) != 10 {
+		t.Fatal("expected 10 hosts used, got", len(hosts))
+	}
+
+	// The second renter only gets the 2 remaining hosts.
+	other := testKey(2)
+	testRenter(c, other)
+	if _, err := c.managedFormContracts(context.Background(), other, nil, form); err != nil {
+		t.Fatal(err)
+	}
+	if len(c.staticContracts.ByRenter(other)) != 2 || len(hosts) != 12 {
+		t.Fatalf("expected 2 contracts with new hosts, got %v with %v hosts in total", len(c.staticContracts.ByRenter(other)), len(hosts))
+	}
+	for key, n := range hosts {
+		if n != 1 {
+			t.Fatalf("expected 1 contract with host %v, got %v", key, n)
+		}
+	}
+
+	// Without the opt-in, the contracts over a lower limit are kept.
+	host := testKey(10)
+	extra := testContract(t, c, other, host, 100, 0, 1000, types.SiacoinPrecision)
+	setTestUtility(t, c, extra.ID, smodules.ContractUtility{GoodForUpload: true, GoodForRenew: true})
+	if n := c.managedChurnExcessHostContracts(); n != 0 {
+		t.Fatal("expected no contracts churned, got", n)
+	}
+
+	// With the opt-in, the one with the least funds is churned.
+	if err := setTestSettings(c, func(s *modules.ContractorSettings) { s.ChurnExcessHostContracts = true }); err != nil {
+		t.Fatal(err)
+	}
+	if n := c.managedChurnExcessHostContracts(); n != 1 {
+		t.Fatal("expected 1 contract churned, got", n)
+	}
+	for _, contract := range c.staticContracts.ViewAll() {
+		u := contract.Utility
+		churned := contract.ID == extra.ID
+		if churned == (u.GoodForUpload || u.GoodForRenew) {
+			t.Fatalf("contract %v has the wrong utility: %+v", contract.ID, u)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package contractor
+
+import (
+	"github.com/mike76-dev/sia-satellite/modules"
+
+	"gitlab.com/NebulousLabs/errors"
+
+	"go.sia.tech/siad/build"
+	smodules "go.sia.tech/siad/modules"
+	"go.sia.tech/siad/types"
+)
+
+// minHostVersion is the minimum host version accepted for contract
+// formation. Older hosts get the smallest possible score in the hostdb.
+const minHostVersion = "1.5.4"
+
+var errHostDecisionNoHost = errors.New("host not found in the hostdb")
+
+// priceCheck returns a HostCheck for a host price that must not exceed
+// the limit. A zero limit means no limit.
+func priceCheck(name string, price, limit types.Currency) modules.HostCheck {
+	if !limit.IsZero() && price.Cmp(limit) > 0 {
+		return modules.HostCheck{
+			Name:   name,
+			Reason: "host price " + price.HumanString() + " exceeds the limit of " + limit.HumanString(),
+		}
+	}
+	return modules.HostCheck{Name: name, Passed: true}
+}
+
+// HostDecision runs the checks that FormContracts applies to a host against
+// the renter's allowance and reports which of them the host passes. No
+// contract is formed.
+func (c *Contractor) HostDecision(rpk, hpk types.SiaPublicKey) (modules.HostDecision, error) {
+	c.mu.RLock()
+	renter, exists := c.renters[rpk.String()]
+	c.mu.RUnlock()
+	if !exists {
+		return modules.HostDecision{}, ErrRenterNotFound
+	}
+	host, exists, err := c.hdb.Host(hpk)
+	if err != nil {
+		return modules.HostDecision{}, err
+	}
+	if !exists {
+		return modules.HostDecision{}, errHostDecisionNoHost
+	}
+	a := renter.Allowance
+
+	var checks []modules.HostCheck
+	addCheck := func(name string, passed bool, reason string) {
+		hc := modules.HostCheck{Name: name, Passed: passed}
+		if !passed {
+			hc.Reason = reason
+		}
+		checks = append(checks, hc)
+	}
+
+	// Check the hostdb filter.
+	fm, filtered, _, err := c.hdb.Filter()
+	if err != nil {
+		return modules.HostDecision{}, errors.AddContext(err, "unable to get the hostdb filter")
+	}
+	_, listed := filtered[hpk.String()]
+	isWhitelist := fm == smodules.HostDBActiveWhitelist
+	addCheck("filter", isWhitelist == listed, "host is excluded by the hostdb filter mode "+fm.String())
+
+	// Check the host status.
+	addCheck("acceptingcontracts", host.AcceptingContracts, "host is not accepting contracts")
+	addCheck("online", !isOffline(host), "host is considered offline")
+	addCheck("version", build.VersionCmp(host.Version, minHostVersion) >= 0, "host version "+host.Version+" is older than "+minHostVersion)
+
+	// Check if there is a contract with this host already.
+	var hasContract bool
+	for _, contract := range c.staticContracts.ByRenter(rpk) {
+		if contract.HostPublicKey.String() == hpk.String() {
+			hasContract = true
+			break
+		}
+	}
+	addCheck("existingcontract", !hasContract, "renter already has a contract with the host")
+
+	// Check the allowance.
+	addCheck("allowance", a.Period > 0 && a.Hosts > 0, "renter allowance is not set")
+	addCheck("maxduration", host.MaxDuration >= a.Period, "host MaxDuration is shorter than the allowance period")
+
+	// Check the allowance price limits.
+	storageCeiling, _ := c.managedPriceCeilings()
+	checks = append(checks,
+		priceCheck("maxrpcprice", host.BaseRPCPrice, a.MaxRPCPrice),
+		priceCheck("maxcontractprice", host.ContractPrice, a.MaxContractPrice),
+		priceCheck("maxdownloadbandwidthprice", host.DownloadBandwidthPrice, a.MaxDownloadBandwidthPrice),
+		priceCheck("maxsectoraccessprice", host.SectorAccessPrice, a.MaxSectorAccessPrice),
+		priceCheck("maxstorageprice", host.StoragePrice, a.MaxStoragePrice),
+		priceCheck("maxuploadbandwidthprice", host.UploadBandwidthPrice, a.MaxUploadBandwidthPrice),
+		priceCheck("storagepriceceiling", host.StoragePrice, storageCeiling),
+	)
+
+	// Check for price gouging.
+	if err := checkFormContractGouging(a, host.HostExternalSettings); err != nil {
+		addCheck("gouging", false, err.Error())
+	} else {
+		addCheck("gouging", true, "")
+	}
+
+	hd := modules.HostDecision{
+		HostPublicKey: hpk,
+		Eligible:      true,
+		Checks:        checks,
+	}
+	for _, hc := range checks {
+		if !hc.Passed {
+			hd.Eligible = false
+			break
+		}
+	}
+
+	return hd, nil
+}
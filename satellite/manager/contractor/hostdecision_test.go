@@ -0,0 +1,125 @@
+package contractor
+
+import (
+	"testing"
+
+	smodules "go.sia.tech/siad/modules"
+	"go.sia.tech/siad/types"
+)
+
+// TestHostDecision tests that each check of the host decision reports a
+// reason for a host failing that check only.
+func TestHostDecision(t *testing.T) {
+	price := types.SiacoinPrecision
+	tests := []struct {
+		check string
+		setup func(c *Contractor, hdb *testHostDB, host *smodules.HostDBEntry, a *smodules.Allowance)
+	}{
+		{"filter", func(_ *Contractor, hdb *testHostDB, host *smodules.HostDBEntry, _ *smodules.Allowance) {
+			hdb.setFilter(smodules.HostDBActivateBlacklist, host.PublicKey)
+		}},
+		{"acceptingcontracts", func(_ *Contractor, _ *testHostDB, host *smodules.HostDBEntry, _ *smodules.Allowance) {
+			host.AcceptingContracts = false
+		}},
+		{"online", func(_ *Contractor, _ *testHostDB, host *smodules.HostDBEntry, _ *smodules.Allowance) {
+			host.ScanHistory = smodules.HostDBScans{{Success: false}}
+		}},
+		{"version", func(_ *Contractor, _ *testHostDB, host *smodules.HostDBEntry, _ *smodules.Allowance) {
+			host.Version = "1.4.0"
+		}},
+		{"existingcontract", func(c *Contractor, _ *testHostDB, host *smodules.HostDBEntry, _ *smodules.Allowance) {
+			testContract(t, c, testKey(1), host.PublicKey, 1, 0, 1000, price)
+		}},
+		{"allowance", func(_ *Contractor, _ *testHostDB, _ *smodules.HostDBEntry, a *smodules.Allowance) {
+			a.Hosts = 0
+		}},
+		{"maxduration", func(_ *Contractor, _ *testHostDB, host *smodules.HostDBEntry, _ *smodules.Allowance) {
+			host.MaxDuration = 10
+		}},
+		{"maxrpcprice", func(_ *Contractor, _ *testHostDB, host *smodules.HostDBEntry, a *smodules.Allowance) {
+			host.BaseRPCPrice, a.MaxRPCPrice = price, price.Div64(2)
+		}},
+		{"maxcontractprice", func(_ *Contractor, _ *testHostDB, host *smodules.HostDBEntry, a *smodules.Allowance) {
+			host.ContractPrice, a.MaxContractPrice = price, price.Div64(2)
+		}},
+		{"maxdownloadbandwidthprice", func(_ *Contractor, _ *testHostDB, host *smodules.HostDBEntry, a *smodules.Allowance) {
+			host.DownloadBandwidthPrice, a.MaxDownloadBandwidthPrice = price, price.Div64(2)
+		}},
+		{"maxsectoraccessprice", func(_ *Contractor, _ *testHostDB, host *smodules.HostDBEntry, a *smodules.Allowance) {
+			host.SectorAccessPrice, a.MaxSectorAccessPrice = price, price.Div64(2)
+		}},
+		{"maxstorageprice", func(_ *Contractor, _ *testHostDB, host *smodules.HostDBEntry, a *smodules.Allowance) {
+			host.StoragePrice, a.MaxStoragePrice = price, price.Div64(2)
+		}},
+		{"maxuploadbandwidthprice", func(_ *Contractor, _ *testHostDB, host *smodules.HostDBEntry, a *smodules.Allowance) {
+			host.UploadBandwidthPrice, a.MaxUploadBandwidthPrice = price, price.Div64(2)
+		}},
+		{"storagepriceceiling", func(c *Contractor, _ *testHostDB, host *smodules.HostDBEntry, _ *smodules.Allowance) {
+			host.StoragePrice = price
+			c.mu.Lock()
+			c.maxStoragePrice = price.Div64(2)
+			c.mu.Unlock()
+		}},
+	}
+
+	for _, tt := range tests {
+		c, _ := newTestContractor(t)
+		rpk := testKey(1)
+		renter := testRenter(c, rpk)
+		hdb := newTestHostDB(c)
+		host := testHost(10, "host.example.com:9982")
+		tt.setup(c, hdb, &host, &renter.Allowance)
+		hdb.addHost(host, 100)
+		c.mu.Lock()
+		c.renters[rpk.String()] = renter
+		c.mu.Unlock()
+
+		hd, err := c.HostDecision(rpk, host.PublicKey)
+		if err != nil {
+			t.Fatal(tt.check, err)
+		}
+		if hd.Eligible {
+			t.Fatalf("%v: host reported as eligible", tt.check)
+		}
+		var found bool
+		for _, hc := range hd.Checks {
+			if hc.Name != tt.check {
+				continue
+			}
+			found = true
+			if hc.Passed || hc.Reason == "" {
+				t.Fatalf("%v: check passed or has no reason: %+v", tt.check, hc)
+			}
+		}
+		if !found {
+			t.Fatalf("%v: check not reported", tt.check)
+		}
+	}
+
+	// A host passing all checks is eligible.
+	c, _ := newTestContractor(t)
+	rpk := testKey(1)
+	testRenter(c, rpk)
+	host := testHost(10, "host.example.com:9982")
+	newTestHostDB(c, host)
+	hd, err := c.HostDecision(rpk, host.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !hd.Eligible {
+		t.Fatalf("expected an eligible host: %+v", hd.Checks)
+	}
+	for _, hc := range hd.Checks {
+		if !hc.Passed || hc.Reason != "" {
+			t.Fatalf("unexpected failed check: %+v", hc)
+		}
+	}
+
+	// An unknown renter or host is an error.
+	if _, err := c.HostDecision(testKey(2), host.PublicKey); err != ErrRenterNotFound {
+		t.Fatal("expected ErrRenterNotFound, got", err)
+	}
+	if _, err := c.HostDecision(rpk, testKey(11)); err != errHostDecisionNoHost {
+		t.Fatal("expected errHostDecisionNoHost, got", err)
+	}
+}
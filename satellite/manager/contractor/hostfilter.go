@@ -0,0 +1,34 @@
+package contractor
+
+// ApplyHostFilter marks the contracts with the hosts excluded by the hostdb
+// filter as !GoodForUpload and !GoodForRenew, so that a change of the
+// filter takes effect without waiting for the next contract maintenance.
+// It returns the number of the contracts updated.
+func (c *Contractor) ApplyHostFilter() int {
+	if err := c.tg.Add(); err != nil {
+		return 0
+	}
+	defer c.tg.Done()
+
+	var churned int
+	for _, contract := range c.staticContracts.ViewAll() {
+		u := contract.Utility
+		if !u.GoodForUpload && !u.GoodForRenew {
+			continue
+		}
+		host, exists, err := c.hdb.Host(contract.HostPublicKey)
+		if err != nil || !exists || !host.Filtered {
+			continue
+		}
+		u.GoodForUpload = false
+		u.GoodForRenew = false
+		if err := c.managedAcquireAndUpdateContractUtility(contract.ID, u, "host filtered"); err != nil {
+			c.log.Println("WARN: unable to update the utility of the contract with a filtered host:", contract.ID, err)
+			continue
+		}
+		c.log.Println("INFO: contract marked as having no utility because the host is filtered:", contract.ID)
+		churned++
+	}
+
+	return churned
+}
@@ -0,0 +1,72 @@
+package contractor
+
+import (
+	"testing"
+
+	smodules "go.sia.tech/siad/modules"
+	"go.sia.tech/siad/types"
+)
+
+// filteringHostDB is a testHostDB that marks the hosts excluded by its
+// filter as filtered, like the hostdb does.
+type filteringHostDB struct {
+	*testHostDB
+}
+
+// Host implements modules.HostDB.
+func (hdb filteringHostDB) Host(pk types.SiaPublicKey) (smodules.HostDBEntry, bool, error) {
+	host, exists, err := hdb.testHostDB.Host(pk)
+	fm, filtered, _, _ := hdb.Filter()
+	_, listed := filtered[pk.String()]
+	switch fm {
+	case smodules.HostDBActivateBlacklist:
+		host.Filtered = listed
+	case smodules.HostDBActiveWhitelist:
+		host.Filtered = !listed
+	}
+	return host, exists, err
+}
+
+// TestApplyHostFilter tests that blocking a contracted host churns its
+// contracts right away, and leaves the other contracts alone.
+func TestApplyHostFilter(t *testing.T) {
+	c, _ := newTestContractor(t)
+	blocked, other := testHost(10, "blocked.example.com:9982"), testHost(11, "other.example.com:9982")
+	hdb := newTestHostDB(c, blocked, other)
+	c.hdb = filteringHostDB{hdb}
+
+	gfu := smodules.ContractUtility{GoodForUpload: true, GoodForRenew: true}
+	var blockedContracts []types.FileContractID
+	for i := byte(1); i <= 2; i++ {
+		rpk := testKey(i)
+		testRenter(c, rpk)
+		contract := testContract(t, c, rpk, blocked.PublicKey, i, 0, 1000, types.SiacoinPrecision)
+		setTestUtility(t, c, contract.ID, gfu)
+		blockedContracts = append(blockedContracts, contract.ID)
+	}
+	otherContract := testContract(t, c, testKey(1), other.PublicKey, 3, 0, 1000, types.SiacoinPrecision)
+	setTestUtility(t, c, otherContract.ID, gfu)
+
+	// Nothing is churned without the filter.
+	if n := c.ApplyHostFilter(); n != 0 {
+		t.Fatalf("expected no contracts churned, got %v", n)
+	}
+
+	hdb.setFilter(smodules.HostDBActivateBlacklist, blocked.PublicKey)
+	if n := c.ApplyHostFilter(); n != 2 {
+		t.Fatalf("expected 2 contracts churned, got %v", n)
+	}
+	for _, id := range blockedContracts {
+		if u, _ := c.managedContractUtility(id); u.GoodForUpload || u.GoodForRenew {
+			t.Fatalf("contract with the blocked host not churned: %+v", u)
+		}
+	}
+	if u, _ := c.managedContractUtility(otherContract.ID); !u.GoodForUpload || !u.GoodForRenew {
+		t.Fatalf("contract with the other host churned: %+v", u)
+	}
+
+	// The churned contracts aren't updated again.
+	if n := c.ApplyHostFilter(); n != 0 {
+		t.Fatalf("expected no more contracts churned, got %v", n)
+	}
+}
@@ -0,0 +1,17 @@
+package contractor
+
+import (
+	"gitlab.com/NebulousLabs/errors"
+)
+
+// errInvalidHostOversample is returned when the host oversample multiplier
+// is not positive.
+var errInvalidHostOversample = errors.New("host oversample must be at least 1")
+
+// HostOversample returns the number of candidate hosts fetched per needed
+// contract during the contract formation.
+func (c *Contractor) HostOversample() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.hostOversample
+}
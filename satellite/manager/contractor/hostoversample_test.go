@@ -0,0 +1,59 @@
+package contractor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mike76-dev/sia-satellite/modules"
+
+	"gitlab.com/NebulousLabs/errors"
+
+	smodules "go.sia.tech/siad/modules"
+	"go.sia.tech/siad/types"
+)
+
+// oversampleHostDB is a testHostDB that records the number of candidate
+// hosts requested.
+type oversampleHostDB struct {
+	*testHostDB
+	requested int
+}
+
+// RandomHostsWithLimits implements modules.HostDB.
+func (hdb *oversampleHostDB) RandomHostsWithLimits(n int, blacklist, addressBlacklist []types.SiaPublicKey, a smodules.Allowance) ([]smodules.HostDBEntry, error) {
+	hdb.requested = n
+	return hdb.testHostDB.RandomHostsWithLimits(n, blacklist, addressBlacklist, a)
+}
+
+// TestHostOversample tests that the number of candidate hosts requested
+// during the formation scales with the host oversample.
+func TestHostOversample(t *testing.T) {
+	c, _ := newTestContractor(t)
+	setTestSynced(c)
+	hdb := &oversampleHostDB{testHostDB: newTestHostDB(c)}
+	c.hdb = hdb
+	renter := testRenter(c, testKey(1))
+
+	for _, multiplier := range []int{defaultHostOversample, 1, 7} {
+		if multiplier != defaultHostOversample {
+			if err := setTestSettings(c, func(s *modules.ContractorSettings) { s.HostOversample = multiplier }); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if _, err := c.managedFormationPlan(context.Background(), renter, nil, 0, false); err != nil {
+			t.Fatal(err)
+		}
+		expected := int(renter.Allowance.Hosts) * multiplier + randomHostsBufferForScore
+		if hdb.requested != expected {
+			t.Fatalf("multiplier %v: expected %v candidates, got %v", multiplier, expected, hdb.requested)
+		}
+	}
+
+	// The multiplier must be positive.
+	if err := setTestSettings(c, func(s *modules.ContractorSettings) { s.HostOversample = 0 }); !errors.Contains(err, errInvalidHostOversample) {
+		t.Fatal("expected errInvalidHostOversample, got", err)
+	}
+	if c.HostOversample() != 7 {
+		t.Fatal("the host oversample was changed by an invalid value")
+	}
+}
@@ -0,0 +1,43 @@
+package contractor
+
+import (
+	"math/rand"
+
+	smodules "go.sia.tech/siad/modules"
+	"go.sia.tech/siad/types"
+)
+
+// setHostSelectionSeed makes the host selection during contract formation
+// deterministic. Each formation draws a new seed from a source initialized
+// with the provided one, so that the same sequence of formations selects
+// the same hosts in the same order. This is useful for debugging formation
+// decisions and for tests. A zero seed restores the random selection. The
+// caller must hold the lock.
+func (c *Contractor) setHostSelectionSeed(seed int64) {
+	c.hostSelectionSeed = seed
+	if seed == 0 {
+		c.hostSelectionRNG = nil
+		c.nextHostSelectionSeed = 0
+		return
+	}
+	c.hostSelectionRNG = rand.New(rand.NewSource(seed))
+	c.nextHostSelectionSeed = c.hostSelectionRNG.Int63()
+}
+
+// managedRandomHostsWithLimits selects the hosts for contract formation,
+// using the host selection seed if one was set. A preview uses the seed of
+// the next formation without consuming it, so that the formation selects
+// the same hosts as the preview.
+func (c *Contractor) managedRandomHostsWithLimits(n int, blacklist, addressBlacklist []types.SiaPublicKey, allowance smodules.Allowance, preview bool) ([]smodules.HostDBEntry, error) {
+	c.mu.Lock()
+	rng := c.hostSelectionRNG
+	seed := c.nextHostSelectionSeed
+	if rng != nil && !preview {
+		c.nextHostSelectionSeed = rng.Int63()
+	}
+	c.mu.Unlock()
+	if rng == nil {
+		return c.hdb.RandomHostsWithLimits(n, blacklist, addressBlacklist, allowance)
+	}
+	return c.hdb.RandomHostsWithLimitsSeeded(n, blacklist, addressBlacklist, allowance, seed)
+}
@@ -0,0 +1,57 @@
+package contractor
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/mike76-dev/sia-satellite/modules"
+
+	smodules "go.sia.tech/siad/modules"
+)
+
+// TestHostSelectionSeed tests that two runs with the same seed select the
+// same hosts in the same order.
+func TestHostSelectionSeed(t *testing.T) {
+	run := func(seed int64) []smodules.HostDBEntry {
+		c, _ := newTestContractor(t)
+		setTestSynced(c)
+		renter := testRenter(c, testKey(1))
+		hdb := newTestHostDB(c)
+		for i := 0; i < 50; i++ {
+			hdb.addHost(testHost(byte(10 + i), fmt.Sprintf("host%v.example.com:9982", i)), 100)
+		}
+		if err := setTestSettings(c, func(s *modules.ContractorSettings) { s.HostSelectionSeed = seed }); err != nil {
+			t.Fatal(err)
+		}
+		var hosts []smodules.HostDBEntry
+		for i := 0; i < 2; i++ {
+			fp, err := c.managedFormationPlan(context.Background(), renter, nil, 0, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			hosts = append(hosts, fp.hosts...)
+		}
+		return hosts
+	}
+
+	first, second := run(42), run(42)
+	if len(first) == 0 || len(first) != len(second) {
+		t.Fatalf("expected the same number of hosts, got %v and %v", len(first), len(second))
+	}
+	for i := range first {
+		if !first[i].PublicKey.Equals(second[i].PublicKey) {
+			t.Fatalf("runs differ at position %v", i)
+		}
+	}
+
+	// Another seed selects the hosts in another order.
+	other := run(43)
+	same := len(other) == len(first)
+	for i := 0; same && i < len(other); i++ {
+		same = other[i].PublicKey.Equals(first[i].PublicKey)
+	}
+	if same {
+		t.Fatal("different seeds selected the same hosts in the same order")
+	}
+}
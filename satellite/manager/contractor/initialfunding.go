@@ -0,0 +1,46 @@
+package contractor
+
+import (
+	"gitlab.com/NebulousLabs/errors"
+
+	"go.sia.tech/siad/types"
+)
+
+// errInvalidInitialFunding is returned when the initial funding factors
+// would divide by zero or leave no funds for a new contract.
+var errInvalidInitialFunding = errors.New("initial funding factors must be non-zero")
+
+// InitialFundingFactors determine how much of the allowance goes into a new
+// contract. The maximum is Funds / Hosts * MaxMulFactor / MaxDivFactor, the
+// minimum is Funds / Hosts / MinDivFactor.
+type InitialFundingFactors struct {
+	MaxMulFactor uint64 `json:"maxmulfactor"`
+	MaxDivFactor uint64 `json:"maxdivfactor"`
+	MinDivFactor uint64 `json:"mindivfactor"`
+}
+
+// defaultInitialFundingFactors returns the factors set by the package
+// constants.
+func defaultInitialFundingFactors() InitialFundingFactors {
+	return InitialFundingFactors{
+		MaxMulFactor: MaxInitialContractFundingMulFactor,
+		MaxDivFactor: MaxInitialContractFundingDivFactor,
+		MinDivFactor: MinInitialContractFundingDivFactor,
+	}
+}
+
+// limits returns the maximum and the minimum initial funding of a contract
+// given the per-contract share of the allowance.
+func (f InitialFundingFactors) limits(perContract types.Currency) (max, min types.Currency) {
+	max = perContract.Mul64(f.MaxMulFactor).Div64(f.MaxDivFactor)
+	min = perContract.Div64(f.MinDivFactor)
+	return
+}
+
+// InitialFundingFactors returns the factors used to calculate the initial
+// funding of new contracts.
+func (c *Contractor) InitialFundingFactors() InitialFundingFactors {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.initialFunding
+}
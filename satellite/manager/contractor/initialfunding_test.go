@@ -0,0 +1,78 @@
+package contractor
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/mike76-dev/sia-satellite/modules"
+
+	"gitlab.com/NebulousLabs/errors"
+
+	"go.sia.tech/siad/types"
+)
+
+// TestInitialFundingFactors tests that the maximum and the minimum initial
+// contract funds follow the configured factors.
+func TestInitialFundingFactors(t *testing.T) {
+	c, _ := newTestContractor(t)
+	setTestSynced(c)
+	renter := testRenter(c, testKey(1))
+	hdb := newTestHostDB(c)
+	for i := 0; i < 10; i++ {
+		hdb.addHost(testHost(byte(10 + i), fmt.Sprintf("host%v.example.com:9982", i)), 100)
+	}
+	perContract := renter.Allowance.Funds.Div64(renter.Allowance.Hosts)
+	plan := func() (max, min types.Currency) {
+		t.Helper()
+		fp, err := c.managedFormationPlan(context.Background(), renter, nil, 0, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return fp.maxFunds, fp.minFunds
+	}
+
+	// The defaults are the package constants.
+	if f := c.InitialFundingFactors(); f != defaultInitialFundingFactors() {
+		t.Fatal("unexpected default factors:", f)
+	}
+	max, min := plan()
+	if expected := perContract.Mul64(MaxInitialContractFundingMulFactor).Div64(MaxInitialContractFundingDivFactor); !max.Equals(expected) {
+		t.Fatalf("expected max funds %v, got %v", expected, max)
+	}
+	if expected := perContract.Div64(MinInitialContractFundingDivFactor); !min.Equals(expected) {
+		t.Fatalf("expected min funds %v, got %v", expected, min)
+	}
+
+	// The next formation uses the new factors.
+	f := InitialFundingFactors{MaxMulFactor: 3, MaxDivFactor: 2, MinDivFactor: 4}
+	if err := setTestSettings(c, func(s *modules.ContractorSettings) {
+		s.InitialFundingMaxMul, s.InitialFundingMaxDiv, s.InitialFundingMinDiv = 3, 2, 4
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if c.InitialFundingFactors() != f {
+		t.Fatal("factors not updated")
+	}
+	max, min = plan()
+	if expected := perContract.Mul64(3).Div64(2); !max.Equals(expected) {
+		t.Fatalf("expected max funds %v, got %v", expected, max)
+	}
+	if expected := perContract.Div64(4); !min.Equals(expected) {
+		t.Fatalf("expected min funds %v, got %v", expected, min)
+	}
+
+	// Zero factors are rejected and the previous ones are kept.
+	for _, update := range []func(*modules.ContractorSettings){
+		func(s *modules.ContractorSettings) { s.InitialFundingMaxMul = 0 },
+		func(s *modules.ContractorSettings) { s.InitialFundingMaxDiv = 0 },
+		func(s *modules.ContractorSettings) { s.InitialFundingMinDiv = 0 },
+	} {
+		if err := setTestSettings(c, update); !errors.Contains(err, errInvalidInitialFunding) {
+			t.Fatal("expected errInvalidInitialFunding, got", err)
+		}
+	}
+	if c.InitialFundingFactors() != f {
+		t.Fatal("factors changed by an invalid update")
+	}
+}
@@ -0,0 +1,191 @@
+package contractor
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"sync"
+	"time"
+
+	"gitlab.com/NebulousLabs/errors"
+
+	smodules "go.sia.tech/siad/modules"
+	"go.sia.tech/siad/types"
+)
+
+// maxSettingsLen is the maximum length in bytes of the host settings
+// read by a latency probe.
+const maxSettingsLen = 10e3
+
+// LatencyProber measures the response time of a host by fetching its
+// settings. A probe must not take much longer than the given timeout, and
+// must return early if ctx is canceled.
+type LatencyProber interface {
+	Latency(ctx context.Context, host smodules.HostDBEntry, timeout time.Duration) (smodules.HostExternalSettings, time.Duration, error)
+}
+
+// settingsProber is the default LatencyProber. It times dialing the host
+// and fetching its settings over RHP2.
+type settingsProber struct{}
+
+// Latency implements LatencyProber.
+func (settingsProber) Latency(ctx context.Context, host smodules.HostDBEntry, timeout time.Duration) (smodules.HostExternalSettings, time.Duration, error) {
+	start := time.Now()
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", string(host.NetAddress))
+	if err != nil {
+		return smodules.HostExternalSettings{}, 0, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(start.Add(timeout))
+
+	// Close the connection if ctx is canceled during the RPC.
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	s, _, err := smodules.NewRenterSession(conn, host.PublicKey)
+	if err != nil {
+		return smodules.HostExternalSettings{}, 0, errors.AddContext(err, "could not open RHP2 session")
+	}
+	defer s.WriteRequest(smodules.RPCLoopExit, nil)
+	if err := s.WriteRequest(smodules.RPCLoopSettings, nil); err != nil {
+		return smodules.HostExternalSettings{}, 0, errors.AddContext(err, "could not write the settings request")
+	}
+	var resp smodules.LoopSettingsResponse
+	if err := s.ReadResponse(&resp, maxSettingsLen); err != nil {
+		return smodules.HostExternalSettings{}, 0, errors.AddContext(err, "could not read the settings response")
+	}
+	latency := time.Since(start)
+
+	var settings smodules.HostExternalSettings
+	if err := json.Unmarshal(resp.Settings, &settings); err != nil {
+		return smodules.HostExternalSettings{}, 0, errors.AddContext(err, "could not decode the host settings")
+	}
+
+	return settings, latency, nil
+}
+
+// SetMaxHostLatency sets the maximum response time of a host for it to
+// be picked during the renter's contract formation. Zero means no limit.
+func (c *Contractor) SetMaxHostLatency(rpk types.SiaPublicKey, latency time.Duration) error {
+	if latency < 0 {
+		return errors.New("latency bound cannot be negative")
+	}
+	c.mu.Lock()
+	renter, exists := c.renters[rpk.String()]
+	if !exists {
+		c.mu.Unlock()
+		return ErrRenterNotFound
+	}
+	renter.MaxHostLatency = uint64(latency / time.Millisecond)
+	c.renters[rpk.String()] = renter
+	c.mu.Unlock()
+	return c.UpdateRenter(renter)
+}
+
+// managedHostLatency returns the response time of the host. The latency
+// of the settings fetched within HostSettingsCacheTTL is reused. Otherwise,
+// the settings are fetched over a pooled session with the host if there is
+// one, or by the latency prober, and cached for the renewals.
+func (c *Contractor) managedHostLatency(ctx context.Context, lp LatencyProber, host smodules.HostDBEntry, timeout time.Duration) (time.Duration, error) {
+	if latency, ok := c.staticSettingsCache.latency(host.PublicKey); ok {
+		return latency, nil
+	}
+
+	if hs := c.staticSessionPool.get(host.PublicKey, types.FileContractID{}); hs != nil {
+		start := time.Now()
+		settings, err := hs.Settings()
+		latency := time.Since(start)
+		if err == nil {
+			hs.Close()
+			c.staticSettingsCache.put(host.PublicKey, settings, latency)
+			return latency, nil
+		}
+		c.staticSessionPool.remove(host.PublicKey, hs)
+		hs.invalidate()
+		hs.Close()
+	}
+
+	settings, latency, err := lp.Latency(ctx, host, timeout)
+	if err != nil {
+		return 0, err
+	}
+	c.staticSettingsCache.put(host.PublicKey, settings, latency)
+	return latency, nil
+}
+
+// managedFilterSlowHosts probes the hosts in parallel and returns those
+// responding within maxLatency, keeping their order. A probe is cut off
+// at maxLatency, or at HostLatencyProbeTimeout if that is lower, so that
+// an unresponsive host cannot stall the formation. At most
+// HostLatencyMaxProbes hosts are probed, and no more probes are started
+// once ctx is canceled.
+func (c *Contractor) managedFilterSlowHosts(ctx context.Context, hosts []smodules.HostDBEntry, maxLatency time.Duration) []smodules.HostDBEntry {
+	c.mu.RLock()
+	lp := c.latencyProber
+	c.mu.RUnlock()
+
+	timeout := maxLatency
+	if timeout > HostLatencyProbeTimeout {
+		timeout = HostLatencyProbeTimeout
+	}
+	if len(hosts) > HostLatencyMaxProbes {
+		c.log.Printf("INFO: probing only %v of %v hosts for their response time\n", HostLatencyMaxProbes, len(hosts))
+		hosts = hosts[:HostLatencyMaxProbes]
+	}
+
+	// Stop the probes on shutdown too.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-c.tg.StopChan():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	fast := make([]bool, len(hosts))
+	sem := make(chan struct{}, HostLatencyProbeWorkers)
+	var wg sync.WaitGroup
+	for i := range hosts {
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			if ctx.Err() != nil {
+				return
+			}
+			latency, err := c.managedHostLatency(ctx, lp, hosts[i], timeout)
+			fast[i] = err == nil && latency <= maxLatency
+		}(i)
+	}
+	wg.Wait()
+
+	var filtered []smodules.HostDBEntry
+	for i, host := range hosts {
+		if fast[i] {
+			filtered = append(filtered, host)
+		}
+	}
+	if skipped := len(hosts) - len(filtered); skipped > 0 {
+		c.log.Printf("INFO: skipped %v hosts responding slower than %v\n", skipped, maxLatency)
+	}
+
+	return filtered
+}
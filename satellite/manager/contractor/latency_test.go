@@ -0,0 +1,74 @@
+package contractor
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	smodules "go.sia.tech/siad/modules"
+)
+
+// testProber is a LatencyProber that reports the same latency for every
+// host and counts the probes.
+type testProber struct {
+	latency time.Duration
+	probes  int
+	mu      sync.Mutex
+}
+
+// Latency implements LatencyProber.
+func (tp *testProber) Latency(ctx context.Context, _ smodules.HostDBEntry, _ time.Duration) (smodules.HostExternalSettings, time.Duration, error) {
+	tp.mu.Lock()
+	defer tp.mu.Unlock()
+	tp.probes++
+	return smodules.HostExternalSettings{AcceptingContracts: true}, tp.latency, ctx.Err()
+}
+
+// numProbes returns the number of probes made so far.
+func (tp *testProber) numProbes() int {
+	tp.mu.Lock()
+	defer tp.mu.Unlock()
+	return tp.probes
+}
+
+// TestFilterSlowHosts tests that the number of latency probes is capped,
+// that the cached latencies are reused, and that no probes are made once
+// the context is canceled.
+func TestFilterSlowHosts(t *testing.T) {
+	c, _ := newTestContractor(t)
+	tp := &testProber{latency: 100 * time.Millisecond}
+	c.latencyProber = tp
+
+	var hosts []smodules.HostDBEntry
+	for i := 0; i < HostLatencyMaxProbes + 10; i++ {
+		hosts = append(hosts, testHost(byte(i + 1), fmt.Sprintf("host%v.example.com:9982", i)))
+	}
+
+	// Only HostLatencyMaxProbes hosts are probed.
+	filtered := c.managedFilterSlowHosts(context.Background(), hosts, time.Second)
+	if len(filtered) != HostLatencyMaxProbes || tp.numProbes() != HostLatencyMaxProbes {
+		t.Fatalf("expected %v hosts probed, got %v hosts and %v probes", HostLatencyMaxProbes, len(filtered), tp.numProbes())
+	}
+	if filtered[0].PublicKey.String() != hosts[0].PublicKey.String() {
+		t.Fatal("the order of the hosts has changed")
+	}
+
+	// The cached latencies are reused.
+	filtered = c.managedFilterSlowHosts(context.Background(), hosts[:10], 50 * time.Millisecond)
+	if len(filtered) != 0 || tp.numProbes() != HostLatencyMaxProbes {
+		t.Fatalf("expected the cached latencies to be used, got %v hosts and %v probes", len(filtered), tp.numProbes())
+	}
+	if _, ok := c.staticSettingsCache.get(hosts[0].PublicKey); !ok {
+		t.Fatal("probed settings not cached")
+	}
+
+	// No probes are made with a canceled context.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	filtered = c.managedFilterSlowHosts(ctx, hosts[HostLatencyMaxProbes:], time.Second)
+	if len(filtered) != 0 || tp.numProbes() != HostLatencyMaxProbes {
+		t.Fatalf("expected no probes, got %v hosts and %v probes", len(filtered), tp.numProbes())
+	}
+}
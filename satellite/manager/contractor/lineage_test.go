@@ -0,0 +1,72 @@
+package contractor
+
+import (
+	"testing"
+
+	"github.com/mike76-dev/sia-satellite/modules"
+
+	"go.sia.tech/siad/types"
+)
+
+// TestContractLineage tests that the renewal chain of a contract is
+// returned in order, from the original contract to the latest renewal.
+func TestContractLineage(t *testing.T) {
+	c, _ := newTestContractor(t)
+	rpk, hpk := testKey(1), testKey(10)
+	testRenter(c, rpk)
+
+	// The original contract and the first renewal have expired, the
+	// other two renewals are active.
+	var ids []types.FileContractID
+	for i := 0; i < 4; i++ {
+		start := types.BlockHeight(i * 1000)
+		contract := testContract(t, c, rpk, hpk, byte(i + 1), start, start + 1000, types.SiacoinPrecision)
+		ids = append(ids, contract.ID)
+		if i < 2 {
+			fc, ok := c.staticContracts.Acquire(contract.ID)
+			if !ok {
+				t.Fatal("contract not found")
+			}
+			c.staticContracts.Delete(fc)
+			c.oldContracts[contract.ID] = contract
+		}
+		if i > 0 {
+			c.renewedFrom[contract.ID] = ids[i - 1]
+			c.renewedTo[ids[i - 1]] = contract.ID
+		}
+	}
+
+	for _, id := range ids {
+		lineage, err := c.ContractLineage(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(lineage) != len(ids) {
+			t.Fatalf("expected %v links, got %v", len(ids), len(lineage))
+		}
+		for i, link := range lineage {
+			expected := modules.ContractLineageLink{
+				ID:          ids[i],
+				StartHeight: types.BlockHeight(i * 1000),
+				EndHeight:   types.BlockHeight(i * 1000 + 1000),
+			}
+			if link != expected {
+				t.Fatalf("link %v: expected %v, got %v", i, expected, link)
+			}
+		}
+	}
+
+	// An unknown contract has no lineage.
+	var unknown types.FileContractID
+	unknown[0] = 100
+	if _, err := c.ContractLineage(unknown); err == nil {
+		t.Fatal("expected an error for an unknown contract")
+	}
+
+	// A cycle doesn't loop forever.
+	c.renewedTo[ids[3]] = ids[0]
+	c.renewedFrom[ids[0]] = ids[3]
+	if _, err := c.ContractLineage(ids[1]); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,82 @@
+package contractor
+
+import (
+	"math"
+	"strings"
+
+	"github.com/mike76-dev/sia-satellite/modules"
+
+	"gitlab.com/NebulousLabs/errors"
+
+	smodules "go.sia.tech/siad/modules"
+	"go.sia.tech/siad/types"
+)
+
+// errInvalidLocalFraction is returned if the local fraction is out of the
+// [0, 1] range.
+var errInvalidLocalFraction = errors.New("local fraction must be between 0 and 1")
+
+// errNoLocalRegion is returned if a local fraction is set without a region.
+var errNoLocalRegion = errors.New("local region must be set together with a non-zero fraction")
+
+// SetLocalRegion sets the region where the given fraction of the renter's
+// contracts is formed. The region is matched against the regions returned
+// by the regionResolver. A zero fraction disables the preference.
+func (c *Contractor) SetLocalRegion(rpk types.SiaPublicKey, region string, fraction float64) error {
+	if fraction < 0 || fraction > 1 || math.IsNaN(fraction) {
+		return errInvalidLocalFraction
+	}
+	region = strings.ToLower(strings.TrimSpace(region))
+	if fraction > 0 && region == "" {
+		return errNoLocalRegion
+	}
+	if fraction == 0 {
+		region = ""
+	}
+
+	c.mu.Lock()
+	renter, exists := c.renters[rpk.String()]
+	if !exists {
+		c.mu.Unlock()
+		return ErrRenterNotFound
+	}
+	renter.LocalRegion = region
+	renter.LocalFraction = fraction
+	c.renters[rpk.String()] = renter
+	c.mu.Unlock()
+	return c.UpdateRenter(renter)
+}
+
+// localTarget returns the number of the renter's contracts that should be
+// with the hosts in the local region.
+func localTarget(renter modules.Renter) uint64 {
+	return uint64(math.Ceil(float64(renter.Allowance.Hosts) * renter.LocalFraction))
+}
+
+// managedLocalizeHosts reorders the candidate hosts, so that the hosts
+// from the renter's local region come first until the local share of the
+// contracts is reached, followed by the hosts from the other regions. The
+// remaining local hosts are moved to the end of the list, so that they
+// are only tried if the others fail. The order within each group is kept.
+func (c *Contractor) managedLocalizeHosts(hosts []smodules.HostDBEntry, contractSet []modules.RenterContract, renter modules.Renter) []smodules.HostDBEntry {
+	target := localTarget(renter)
+	existing := c.managedRegionCounts(contractSet)[renter.LocalRegion]
+	var needed uint64
+	if existing < target {
+		needed = target - existing
+	}
+
+	local := make([]smodules.HostDBEntry, 0, needed)
+	var others, overflow []smodules.HostDBEntry
+	for _, host := range hosts {
+		if c.managedHostRegion(host) != renter.LocalRegion {
+			others = append(others, host)
+		} else if uint64(len(local)) < needed {
+			local = append(local, host)
+		} else {
+			overflow = append(overflow, host)
+		}
+	}
+
+	return append(append(local, others...), overflow...)
+}
@@ -0,0 +1,73 @@
+package contractor
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/mike76-dev/sia-satellite/modules"
+
+	"gitlab.com/NebulousLabs/errors"
+
+	smodules "go.sia.tech/siad/modules"
+	"go.sia.tech/siad/types"
+)
+
+// TestLocalRegion tests that with the local region preference the
+// configured fraction of the contracts is formed with the hosts in the
+// region, and the rest with the hosts elsewhere.
+func TestLocalRegion(t *testing.T) {
+	c, _ := newTestContractor(t)
+	setTestSynced(c)
+	c.wallet = &testWallet{unlocked: true, balance: types.SiacoinPrecision.Mul64(1e4)}
+	newTestFundLocker(c)
+	hdb := newTestHostDB(c)
+
+	// The hosts in the local region come last in the candidates.
+	for i := 0; i < 10; i++ {
+		hdb.addHost(testHost(byte(10 + i), fmt.Sprintf("host%v.example.fr:9982", i)), 100)
+	}
+	for i := 0; i < 10; i++ {
+		hdb.addHost(testHost(byte(30 + i), fmt.Sprintf("host%v.example.de:9982", i)), 100)
+	}
+
+	var id byte
+	regions := make(map[string]int)
+	form := func(rpk types.SiaPublicKey, host smodules.HostDBEntry, funds types.Currency, endHeight types.BlockHeight) (types.Currency, modules.RenterContract, error) {
+		id++
+		regions[c.managedHostRegion(host)]++
+		return funds, testContract(t, c, rpk, host.PublicKey, id, 0, endHeight, funds), nil
+	}
+
+	// Without the preference, the local hosts aren't used.
+	rpk := testKey(1)
+	testRenter(c, rpk)
+	if _, err := c.managedFormContracts(context.Background(), rpk, nil, form); err != nil {
+		t.Fatal(err)
+	}
+	if regions["fr"] != 10 || regions["de"] != 0 {
+		t.Fatal("expected all contracts outside the local region, got", regions)
+	}
+
+	// With the preference, 30% of the contracts are local.
+	rpk = testKey(2)
+	testRenter(c, rpk)
+	if err := c.SetLocalRegion(rpk, " DE ", 0.3); err != nil {
+		t.Fatal(err)
+	}
+	regions = make(map[string]int)
+	if _, err := c.managedFormContracts(context.Background(), rpk, nil, form); err != nil {
+		t.Fatal(err)
+	}
+	if regions["de"] != 3 || regions["fr"] != 7 {
+		t.Fatal("expected 3 local and 7 other contracts, got", regions)
+	}
+
+	// The fraction must be valid and come with a region.
+	if err := c.SetLocalRegion(rpk, "de", 1.5); !errors.Contains(err, errInvalidLocalFraction) {
+		t.Fatal("expected errInvalidLocalFraction, got", err)
+	}
+	if err := c.SetLocalRegion(rpk, "", 0.5); !errors.Contains(err, errNoLocalRegion) {
+		t.Fatal("expected errNoLocalRegion, got", err)
+	}
+}
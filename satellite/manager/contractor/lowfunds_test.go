@@ -0,0 +1,78 @@
+package contractor
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mike76-dev/sia-satellite/modules"
+
+	smodules "go.sia.tech/siad/modules"
+	"go.sia.tech/siad/types"
+)
+
+// TestLowFundsAlertGap tests that the low-funds alert reports the remaining
+// funds, the cost of the renewal that couldn't be afforded, the gap between
+// them, and the wallet balance.
+func TestLowFundsAlertGap(t *testing.T) {
+	c, _ := newTestContractor(t)
+	setTestSynced(c)
+	c.wallet = &testWallet{unlocked: true, balance: types.SiacoinPrecision.Mul64(42)}
+	newTestFundLocker(c)
+	hdb := newTestHostDB(c)
+	if err := setTestSettings(c, func(s *modules.ContractorSettings) { s.MinimumFunding = 0 }); err != nil {
+		t.Fatal(err)
+	}
+	rpk := testKey(1)
+	renter := testRenter(c, rpk)
+
+	host := testHost(10, "host.example.com:9982")
+	host.ContractPrice = types.SiacoinPrecision
+	hdb.addHost(host, 100)
+	contract := testContract(t, c, rpk, host.PublicKey, 1, 0, 60, types.SiacoinPrecision)
+	setTestUtility(t, c, contract.ID, smodules.ContractUtility{GoodForUpload: true, GoodForRenew: true})
+
+	// The funds cover half of the renewal.
+	amount, err := c.managedEstimateRenewFundingRequirements(contract, 0, renter.Allowance)
+	if err != nil {
+		t.Fatal(err)
+	}
+	spending, err := c.PeriodSpending(rpk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	remaining := amount.Div64(2)
+	renter.Allowance.Funds = spending.TotalAllocated.Add(remaining)
+	c.mu.Lock()
+	c.renters[rpk.String()] = renter
+	c.mu.Unlock()
+
+	renew := func(fileContractRenewal, types.BlockHeight, types.BlockHeight) (types.Currency, modules.RenterContract, error) {
+		t.Fatal("expected the renewal to be skipped")
+		return types.ZeroCurrency, modules.RenterContract{}, nil
+	}
+	if _, err := c.managedRenewContracts(rpk, []types.FileContractID{contract.ID}, renew); err != nil {
+		t.Fatal(err)
+	}
+
+	var cause string
+	_, _, warn, _ := c.staticAlerter.Alerts()
+	for _, alert := range warn {
+		if alert.Msg == AlertMSGAllowanceLowFunds {
+			cause = alert.Cause
+		}
+	}
+	if cause == "" {
+		t.Fatal("expected the low-funds alert")
+	}
+	for _, part := range []string{
+		AlertCauseInsufficientAllowanceFunds,
+		"remaining " + remaining.HumanString(),
+		"needed " + amount.HumanString(),
+		"gap " + amount.Sub(remaining).HumanString(),
+		"wallet balance " + types.SiacoinPrecision.Mul64(42).HumanString(),
+	} {
+		if !strings.Contains(cause, part) {
+			t.Fatalf("expected the cause %q to contain %q", cause, part)
+		}
+	}
+}
@@ -56,8 +56,11 @@ func (c *Contractor) renewedCheck(u smodules.ContractUtility, renewed bool) (smo
 }
 
 // managedCheckHostScore checks host scorebreakdown against minimum accepted
-// scores.  forceUpdate is true if the utility change must be taken.
+// scores, adjusted for the region diversity the host adds.  forceUpdate is
+// true if the utility change must be taken.
 func (c *Contractor) managedCheckHostScore(contract modules.RenterContract, sb smodules.HostScoreBreakdown, minScoreGFR, minScoreGFU types.Currency) (smodules.ContractUtility, utilityUpdateStatus) {
+	minScoreGFR, minScoreGFU = c.managedDiversityAdjustedScores(contract, minScoreGFR, minScoreGFU)
+
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
@@ -0,0 +1,57 @@
+package contractor
+
+import (
+	"time"
+
+	"github.com/mike76-dev/sia-satellite/modules"
+)
+
+// maintenanceTimer records the time spent in each phase of a maintenance
+// cycle.
+type maintenanceTimer struct {
+	started time.Time
+	last    time.Time
+	phases  []modules.MaintenancePhase
+}
+
+// newMaintenanceTimer returns a timer started at the current time.
+func newMaintenanceTimer() *maintenanceTimer {
+	now := time.Now()
+	return &maintenanceTimer{
+		started: now,
+		last:    now,
+	}
+}
+
+// record marks the end of the named phase. The phase is assumed to have
+// started when the previous one ended.
+func (mt *maintenanceTimer) record(name string) {
+	now := time.Now()
+	mt.phases = append(mt.phases, modules.MaintenancePhase{
+		Name:     name,
+		Duration: now.Sub(mt.last),
+	})
+	mt.last = now
+}
+
+// MaintenanceTimings returns the phase timings of the last maintenance
+// cycle.
+func (c *Contractor) MaintenanceTimings() modules.MaintenanceTimings {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return modules.MaintenanceTimings{
+		Started: c.lastMaintenance.Started,
+		Phases:  append([]modules.MaintenancePhase(nil), c.lastMaintenance.Phases...),
+	}
+}
+
+// managedSetMaintenanceTimings stores the timings of a finished
+// maintenance cycle.
+func (c *Contractor) managedSetMaintenanceTimings(mt *maintenanceTimer) {
+	c.mu.Lock()
+	c.lastMaintenance = modules.MaintenanceTimings{
+		Started: mt.started,
+		Phases:  mt.phases,
+	}
+	c.mu.Unlock()
+}
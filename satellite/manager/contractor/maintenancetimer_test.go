@@ -0,0 +1,73 @@
+package contractor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mike76-dev/sia-satellite/modules"
+
+	smodules "go.sia.tech/siad/modules"
+	"go.sia.tech/siad/types"
+)
+
+// slowHostDB is a testHostDB that takes a while to update the contracts.
+type slowHostDB struct {
+	*testHostDB
+	delay time.Duration
+}
+
+// UpdateContracts implements modules.HostDB.
+func (hdb *slowHostDB) UpdateContracts(contracts []modules.RenterContract) error {
+	time.Sleep(hdb.delay)
+	return hdb.testHostDB.UpdateContracts(contracts)
+}
+
+// TestMaintenanceTimings tests that the time spent in each maintenance
+// phase is recorded, so that a slow phase stands out.
+func TestMaintenanceTimings(t *testing.T) {
+	c, _ := newTestContractor(t)
+	setTestSynced(c)
+	c.wallet = &testWallet{unlocked: true}
+	hdb := &slowHostDB{testHostDB: newTestHostDB(c), delay: 100 * time.Millisecond}
+	c.hdb = hdb
+	rpk := testKey(1)
+	testRenter(c, rpk)
+	host := testHost(10, "host.example.com:9982")
+	hdb.addHost(host, 100)
+	contract := testContract(t, c, rpk, host.PublicKey, 1, 0, 1000, types.SiacoinPrecision)
+	setTestUtility(t, c, contract.ID, smodules.ContractUtility{GoodForUpload: true, GoodForRenew: true})
+
+	// Nothing is recorded before the first cycle.
+	if mt := c.MaintenanceTimings(); !mt.Started.IsZero() || len(mt.Phases) != 0 {
+		t.Fatal("expected no timings, got", mt)
+	}
+
+	c.mu.Lock()
+	c.gracePassed = true
+	c.mu.Unlock()
+	start := time.Now()
+	c.threadedContractMaintenance()
+
+	mt := c.MaintenanceTimings()
+	if mt.Started.Before(start) {
+		t.Fatal("expected the cycle to start after", start, "got", mt.Started)
+	}
+	expected := []string{"archive", "dedup", "pubkey-map", "renewed-links", "prune", "hostdb-update", "host-limit", "gfu-limit"}
+	if len(mt.Phases) != len(expected) {
+		t.Fatalf("expected %v phases, got %v", len(expected), mt.Phases)
+	}
+	var slowest modules.MaintenancePhase
+	for i, phase := range mt.Phases {
+		if phase.Name != expected[i] {
+			t.Fatalf("expected phase %v to be %v, got %v", i, expected[i], phase.Name)
+		}
+		if phase.Duration > slowest.Duration {
+			slowest = phase
+		}
+	}
+
+	// The slow phase reflects the delay, and stands out.
+	if slowest.Name != "hostdb-update" || slowest.Duration < hdb.delay {
+		t.Fatalf("expected hostdb-update to take at least %v, got %v", hdb.delay, mt.Phases)
+	}
+}
@@ -0,0 +1,26 @@
+package contractor
+
+import (
+	"gitlab.com/NebulousLabs/errors"
+
+	smodules "go.sia.tech/siad/modules"
+	"go.sia.tech/siad/types"
+)
+
+// errInvalidMinimumFunding is returned when the minimum funding fraction is
+// out of range.
+var errInvalidMinimumFunding = errors.New("minimum funding must be between 0 and 1")
+
+// MinimumFunding returns the lowest fraction of an allowance (on a
+// per-contract basis) that is allowed to go into funding a contract.
+func (c *Contractor) MinimumFunding() float64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.minimumFunding
+}
+
+// managedMinimumContractFunding returns the lowest amount of funds that a
+// contract formed within the allowance is allowed to have.
+func (c *Contractor) managedMinimumContractFunding(allowance smodules.Allowance) types.Currency {
+	return allowance.Funds.MulFloat(c.MinimumFunding()).Div64(allowance.Hosts)
+}
@@ -0,0 +1,45 @@
+package contractor
+
+import (
+	"testing"
+
+	"github.com/mike76-dev/sia-satellite/modules"
+
+	"go.sia.tech/siad/types"
+)
+
+// TestMinimumFunding tests that the minimum contract funding scales with
+// the configured fraction, both in the renewal estimate and the refresh.
+func TestMinimumFunding(t *testing.T) {
+	c, _ := newTestContractor(t)
+	setTestSynced(c)
+	rpk, hpk := testKey(1), testKey(10)
+	renter := testRenter(c, rpk)
+	newTestHostDB(c, testHost(10, "host.example.com:9982"))
+	contract := testContract(t, c, rpk, hpk, 1, 0, 1000, types.SiacoinPrecision)
+	refreshed := modules.RenterContract{TotalCost: types.SiacoinPrecision}
+
+	// The allowance of 1000 SC is spread over 10 hosts.
+	for _, fraction := range []float64{0.15, 0.05, 0.3} {
+		if err := setTestSettings(c, func(s *modules.ContractorSettings) { s.MinimumFunding = fraction }); err != nil {
+			t.Fatal(err)
+		}
+		minimum := types.SiacoinPrecision.Mul64(100).MulFloat(fraction)
+		estimate, err := c.managedEstimateRenewFundingRequirements(contract, 0, renter.Allowance)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !estimate.Equals(minimum) {
+			t.Fatalf("%v: expected the estimate %v, got %v", fraction, minimum, estimate)
+		}
+		if amount := c.managedRefreshAmount(refreshed, renter, 500, 1000); !amount.Equals(minimum) {
+			t.Fatalf("%v: expected the refresh amount %v, got %v", fraction, minimum, amount)
+		}
+	}
+
+	// A refresh above the minimum is kept.
+	refreshed.TotalCost = types.SiacoinPrecision.Mul64(100)
+	if amount := c.managedRefreshAmount(refreshed, renter, 500, 1000); !amount.Equals(refreshed.TotalCost.Mul64(2)) {
+		t.Fatalf("expected the refresh amount %v, got %v", refreshed.TotalCost.Mul64(2), amount)
+	}
+}
@@ -0,0 +1,98 @@
+package contractor
+
+import (
+	"go.sia.tech/siad/types"
+)
+
+// managedNoRefresh returns true if the contract is excluded from the
+// refreshes.
+func (c *Contractor) managedNoRefresh(id types.FileContractID) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	_, exists := c.noRefresh[id]
+	return exists
+}
+
+// SetNoRefresh sets whether the contract is excluded from the refreshes.
+// An excluded contract is left alone when it runs out of funds, but it is
+// still renewed when it reaches its renew window.
+func (c *Contractor) SetNoRefresh(id types.FileContractID, noRefresh bool) error {
+	if _, exists := c.staticContracts.View(id); !exists {
+		return errContractNotFound
+	}
+
+	if noRefresh {
+		_, err := c.execWithRetry(`
+			INSERT INTO contract_no_refresh (contract_id)
+			VALUES (?)
+			ON DUPLICATE KEY UPDATE contract_id = contract_id
+		`, id.String())
+		if err != nil {
+			return err
+		}
+		c.mu.Lock()
+		c.noRefresh[id] = struct{}{}
+		c.mu.Unlock()
+		return nil
+	}
+	if _, err := c.execWithRetry("DELETE FROM contract_no_refresh WHERE contract_id = ?", id.String()); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	delete(c.noRefresh, id)
+	c.mu.Unlock()
+	return nil
+}
+
+// managedClearNoRefresh removes the noRefresh flag of a contract that is
+// archived.
+func (c *Contractor) managedClearNoRefresh(id types.FileContractID) {
+	c.mu.Lock()
+	_, exists := c.noRefresh[id]
+	delete(c.noRefresh, id)
+	c.mu.Unlock()
+	if !exists {
+		return
+	}
+	if _, err := c.execWithRetry("DELETE FROM contract_no_refresh WHERE contract_id = ?", id.String()); err != nil {
+		c.log.Println("ERROR: couldn't delete the noRefresh flag:", err)
+	}
+}
+
+// managedCarryNoRefresh moves the noRefresh flag of a renewed contract
+// over to the new contract.
+func (c *Contractor) managedCarryNoRefresh(oldID, newID types.FileContractID) {
+	if !c.managedNoRefresh(oldID) {
+		return
+	}
+	if err := c.SetNoRefresh(newID, true); err != nil {
+		c.log.Println("ERROR: couldn't carry the noRefresh flag over to the renewed contract:", err)
+		return
+	}
+	c.managedClearNoRefresh(oldID)
+}
+
+// loadNoRefresh loads the IDs of the contracts excluded from the refreshes.
+func (c *Contractor) loadNoRefresh() error {
+	rows, err := c.db.Query("SELECT contract_id FROM contract_no_refresh")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var id string
+	var fcid types.FileContractID
+	for rows.Next() {
+		if err := rows.Scan(&id); err != nil {
+			c.log.Println("Error scanning database row:", err)
+			continue
+		}
+		if err := fcid.LoadString(id); err != nil {
+			c.log.Println("ERROR: wrong contract ID:", err)
+			continue
+		}
+		c.noRefresh[fcid] = struct{}{}
+	}
+
+	return rows.Err()
+}
@@ -0,0 +1,57 @@
+package contractor
+
+import (
+	"testing"
+
+	"go.sia.tech/siad/types"
+)
+
+// TestNoRefreshLifecycle tests that the noRefresh flag is set idempotently,
+// carried over to the renewed contract, and removed when the contract is
+// archived.
+func TestNoRefreshLifecycle(t *testing.T) {
+	c, fake := newTestContractor(t)
+	newTestHostDB(c)
+	rpk := testKey(1)
+	testRenter(c, rpk)
+	funds := types.SiacoinPrecision.Mul64(100)
+	old := testContract(t, c, rpk, testKey(2), 1, 0, 500, funds)
+	renewed := testContract(t, c, rpk, testKey(2), 2, 400, 1500, funds)
+
+	// Setting the flag twice upserts the row both times.
+	for i := 0; i < 2; i++ {
+		if err := c.SetNoRefresh(old.ID, true); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := len(fake.ExecsLike("ON DUPLICATE KEY")); n != 2 {
+		t.Fatalf("expected 2 upserts, got %v", n)
+	}
+	if !c.managedNoRefresh(old.ID) {
+		t.Fatal("flag not set")
+	}
+
+	// The flag moves over to the renewed contract.
+	c.managedCarryNoRefresh(old.ID, renewed.ID)
+	if c.managedNoRefresh(old.ID) || !c.managedNoRefresh(renewed.ID) {
+		t.Fatal("flag not carried over")
+	}
+	if len(fake.ExecsLike("DELETE FROM contract_no_refresh")) != 1 {
+		t.Fatal("old flag not deleted")
+	}
+
+	// The flag is removed when the contract expires.
+	c.mu.Lock()
+	c.blockHeight = 1501
+	c.mu.Unlock()
+	c.managedArchiveContracts()
+	if _, ok := c.staticContracts.View(renewed.ID); ok {
+		t.Fatal("contract not archived")
+	}
+	if c.managedNoRefresh(renewed.ID) {
+		t.Fatal("flag not removed on archive")
+	}
+	if len(fake.ExecsLike("DELETE FROM contract_no_refresh")) != 2 {
+		t.Fatal("flag not deleted on archive")
+	}
+}
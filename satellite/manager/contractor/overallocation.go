@@ -0,0 +1,74 @@
+package contractor
+
+import (
+	"github.com/mike76-dev/sia-satellite/modules"
+
+	"go.sia.tech/siad/types"
+)
+
+// managedUpdateOverAllocation records by how much the funds allocated to
+// the renter's contracts exceed the renter's allowance. This happens when
+// the allowance is reduced below what has already been allocated. The
+// excess is paid back as the contracts expire.
+func (c *Contractor) managedUpdateOverAllocation(rpk types.SiaPublicKey, funds types.Currency) {
+	spending, err := c.PeriodSpending(rpk)
+	if err != nil {
+		c.log.Println("WARN: unable to get period spending:", err)
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if spending.TotalAllocated.Cmp(funds) <= 0 {
+		delete(c.overAllocated, rpk.String())
+		return
+	}
+	excess := spending.TotalAllocated.Sub(funds)
+	c.overAllocated[rpk.String()] = excess
+	c.log.Printf("INFO: allowance of %v reduced below the allocated funds, %v will be released as the contracts expire\n", rpk.String(), excess.HumanString())
+}
+
+// managedReleaseOverAllocation reduces the over-allocation of the renter
+// by the renter funds left in the expired contract. Only these funds are
+// freed, the rest has been spent on the storage and the fees. They have
+// already been unlocked, so they are returned to the renter's balance
+// instead of being reallocated.
+func (c *Contractor) managedReleaseOverAllocation(contract modules.RenterContract) {
+	key := contract.RenterPublicKey.String()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	excess, exists := c.overAllocated[key]
+	if !exists {
+		return
+	}
+	freed := contract.RenterFunds
+	if excess.Cmp(freed) <= 0 {
+		delete(c.overAllocated, key)
+		c.log.Println("INFO: over-allocated funds fully released for", key)
+		return
+	}
+	c.overAllocated[key] = excess.Sub(freed)
+}
+
+// managedRemainingFunds returns the funds in the renter's allowance that
+// can still be allocated to the contracts. The funds that are yet to be
+// released because of a reduced allowance are excluded.
+func (c *Contractor) managedRemainingFunds(renter modules.Renter, totalAllocated types.Currency) types.Currency {
+	// Check for an underflow. This can happen if the user reduced their
+	// allowance at some point to less than what we've already spent.
+	var fundsRemaining types.Currency
+	if totalAllocated.Cmp(renter.Allowance.Funds) < 0 {
+		fundsRemaining = renter.Allowance.Funds.Sub(totalAllocated)
+	}
+
+	c.mu.RLock()
+	excess, exists := c.overAllocated[renter.PublicKey.String()]
+	c.mu.RUnlock()
+	if !exists {
+		return fundsRemaining
+	}
+	if totalAllocated.Cmp(renter.Allowance.Funds) >= 0 || excess.Cmp(fundsRemaining) >= 0 {
+		return types.ZeroCurrency
+	}
+	return fundsRemaining.Sub(excess)
+}
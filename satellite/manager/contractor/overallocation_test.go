@@ -0,0 +1,52 @@
+package contractor
+
+import (
+	"testing"
+
+	"github.com/mike76-dev/sia-satellite/modules"
+
+	"go.sia.tech/siad/types"
+)
+
+// TestReleaseOverAllocation tests that the over-allocation is released by
+// the renter funds left in the expired contracts, and that the released
+// funds are not reallocated.
+func TestReleaseOverAllocation(t *testing.T) {
+	c, _ := newTestContractor(t)
+	rpk := testKey(1)
+	renter := testRenter(c, rpk)
+	sc := types.SiacoinPrecision
+
+	// The allowance of 1000 SC was reduced below the 1300 SC allocated.
+	c.overAllocated[rpk.String()] = sc.Mul64(300)
+	if funds := c.managedRemainingFunds(renter, sc.Mul64(1300)); !funds.IsZero() {
+		t.Fatalf("expected no remaining funds, got %v", funds)
+	}
+
+	// A contract that cost 500 SC expires with 100 SC left.
+	contract := modules.RenterContract{
+		RenterPublicKey: rpk,
+		TotalCost:       sc.Mul64(500),
+		RenterFunds:     sc.Mul64(100),
+	}
+	c.managedReleaseOverAllocation(contract)
+	if excess := c.overAllocated[rpk.String()]; !excess.Equals(sc.Mul64(200)) {
+		t.Fatalf("expected 200 SC over-allocated, got %v", excess.HumanString())
+	}
+
+	// The allocation drops by the contract cost, but the freed funds are
+	// not available for new contracts.
+	if funds := c.managedRemainingFunds(renter, sc.Mul64(800)); !funds.IsZero() {
+		t.Fatalf("expected no remaining funds, got %v", funds.HumanString())
+	}
+
+	// Once enough has been freed, the over-allocation is cleared.
+	contract.RenterFunds = sc.Mul64(250)
+	c.managedReleaseOverAllocation(contract)
+	if _, exists := c.overAllocated[rpk.String()]; exists {
+		t.Fatal("over-allocation not cleared")
+	}
+	if funds := c.managedRemainingFunds(renter, sc.Mul64(300)); !funds.Equals(sc.Mul64(700)) {
+		t.Fatalf("expected 700 SC remaining, got %v", funds.HumanString())
+	}
+}
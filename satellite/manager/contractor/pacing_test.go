@@ -0,0 +1,59 @@
+package contractor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mike76-dev/sia-satellite/modules"
+
+	"gitlab.com/NebulousLabs/errors"
+)
+
+// TestPaceFormation tests that the delay is applied between the formations,
+// and that a stop during the delay returns promptly.
+func TestPaceFormation(t *testing.T) {
+	c, _ := newTestContractor(t)
+	setDelay := func(delay time.Duration) {
+		t.Helper()
+		if err := setTestSettings(c, func(s *modules.ContractorSettings) { s.FormationDelay = delay }); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// No delay by default.
+	start := time.Now()
+	if err := c.managedPaceFormation(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+		t.Fatal("expected no delay, got", elapsed)
+	}
+
+	if err := setTestSettings(c, func(s *modules.ContractorSettings) { s.FormationJitter = -time.Second }); !errors.Contains(err, errInvalidFormationDelay) {
+		t.Fatal("expected errInvalidFormationDelay, got", err)
+	}
+	delay := 100 * time.Millisecond
+	setDelay(delay)
+	start = time.Now()
+	if err := c.managedPaceFormation(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < delay {
+		t.Fatal("expected a delay of at least", delay, "got", elapsed)
+	}
+
+	// A stop during the delay returns promptly.
+	setDelay(time.Minute)
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		c.tg.Stop()
+	}()
+	start = time.Now()
+	if err := c.managedPaceFormation(context.Background()); err == nil {
+		t.Fatal("expected an error after the stop")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatal("the stop didn't interrupt the delay, took", elapsed)
+	}
+}
@@ -0,0 +1,47 @@
+package contractor
+
+import (
+	"github.com/mike76-dev/sia-satellite/modules"
+
+	"gitlab.com/NebulousLabs/errors"
+)
+
+// ErrRenterPaused is returned when a contract formation or renewal is
+// requested for a paused renter.
+var ErrRenterPaused = errors.New("renter is paused")
+
+// PauseRenter freezes all automated contract activity of the renters with
+// the given email. The existing contracts are left to expire naturally.
+func (c *Contractor) PauseRenter(email string) error {
+	return c.managedSetPaused(email, true)
+}
+
+// ResumeRenter restores the automated contract activity of the renters
+// with the given email.
+func (c *Contractor) ResumeRenter(email string) error {
+	return c.managedSetPaused(email, false)
+}
+
+// managedSetPaused sets the paused flag of all renters with the given
+// email.
+func (c *Contractor) managedSetPaused(email string, paused bool) error {
+	c.mu.Lock()
+	var updated []modules.Renter
+	for key, renter := range c.renters {
+		if renter.Email != email {
+			continue
+		}
+		renter.Paused = paused
+		c.renters[key] = renter
+		updated = append(updated, renter)
+	}
+	c.mu.Unlock()
+	if len(updated) == 0 {
+		return ErrRenterNotFound
+	}
+	var err error
+	for _, renter := range updated {
+		err = errors.Compose(err, c.UpdateRenter(renter))
+	}
+	return err
+}
@@ -0,0 +1,73 @@
+package contractor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mike76-dev/sia-satellite/modules"
+
+	"gitlab.com/NebulousLabs/errors"
+
+	smodules "go.sia.tech/siad/modules"
+	"go.sia.tech/siad/types"
+)
+
+// TestPauseRenter tests that the formations and renewals of a paused
+// renter are skipped, and that resuming the renter restores them.
+func TestPauseRenter(t *testing.T) {
+	c, fake := newTestContractor(t)
+	setTestSynced(c)
+	c.wallet = &testWallet{unlocked: true, balance: types.SiacoinPrecision.Mul64(1e4)}
+	newTestFundLocker(c)
+	newTestHostDB(c, testHost(10, "host.example.com:9982"))
+	rpk := testKey(1)
+	renter := testRenter(c, rpk)
+	contract := testContract(t, c, rpk, testKey(10), 1, 0, 1050, types.SiacoinPrecision)
+	setTestUtility(t, c, contract.ID, smodules.ContractUtility{GoodForUpload: true, GoodForRenew: true})
+	c.mu.Lock()
+	c.blockHeight = 1000
+	c.mu.Unlock()
+
+	var renewed int
+	renew := func(fileContractRenewal, types.BlockHeight, types.BlockHeight) (types.Currency, modules.RenterContract, error) {
+		renewed++
+		return types.ZeroCurrency, modules.RenterContract{}, errors.New("renewal failed")
+	}
+
+	if err := c.PauseRenter(renter.Email); err != nil {
+		t.Fatal(err)
+	}
+	if len(fake.ExecsLike("UPDATE renters")) != 1 {
+		t.Fatal("paused flag not persisted")
+	}
+	if _, err := c.FormContractsWithSpending(context.Background(), rpk, nil); !errors.Contains(err, ErrRenterPaused) {
+		t.Fatal("expected ErrRenterPaused, got", err)
+	}
+	if _, err := c.RenewContracts(rpk, []types.FileContractID{contract.ID}); !errors.Contains(err, ErrRenterPaused) {
+		t.Fatal("expected ErrRenterPaused, got", err)
+	}
+	if _, err := c.managedRenewAllDue(types.SiacoinPrecision.Mul64(1e3), renew); err != nil {
+		t.Fatal(err)
+	}
+	if renewed != 0 {
+		t.Fatal("renewed a contract of a paused renter")
+	}
+
+	if err := c.ResumeRenter(renter.Email); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.FormContractsWithSpending(context.Background(), rpk, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.managedRenewAllDue(types.SiacoinPrecision.Mul64(1e3), renew); err != nil {
+		t.Fatal(err)
+	}
+	if renewed != 1 {
+		t.Fatalf("expected 1 renewal after resuming, got %v", renewed)
+	}
+
+	// An unknown email is rejected.
+	if err := c.PauseRenter("unknown@example.com"); !errors.Contains(err, ErrRenterNotFound) {
+		t.Fatal("expected ErrRenterNotFound, got", err)
+	}
+}
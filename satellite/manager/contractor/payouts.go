@@ -0,0 +1,94 @@
+package contractor
+
+import (
+	"go.sia.tech/siad/types"
+)
+
+// managedRecordPayouts records the funds returned to the wallet by the
+// expired contracts that were not renewed. There is nothing to sweep: the
+// renter payout of a contract is sent to the refund address, which belongs
+// to the wallet, once the proof window has closed. The renewed contracts
+// are skipped, because their funds are carried over by the renewal.
+func (c *Contractor) managedRecordPayouts() {
+	type payout struct {
+		id     types.FileContractID
+		rpk    types.SiaPublicKey
+		hpk    types.SiaPublicKey
+		amount types.Currency
+	}
+	var payouts []payout
+	c.mu.RLock()
+	height := c.blockHeight
+	for id, contract := range c.oldContracts {
+		if _, recorded := c.payouts[id]; recorded {
+			continue
+		}
+		if _, renewed := c.renewedTo[id]; renewed {
+			continue
+		}
+		if _, doubleSpent := c.doubleSpentContracts[id]; doubleSpent {
+			continue
+		}
+		if len(contract.Transaction.FileContractRevisions) == 0 {
+			continue
+		}
+		rev := contract.Transaction.FileContractRevisions[0]
+		if height < rev.NewWindowEnd || len(rev.NewValidProofOutputs) == 0 {
+			continue
+		}
+		payouts = append(payouts, payout{
+			id:     id,
+			rpk:    contract.RenterPublicKey,
+			hpk:    contract.HostPublicKey,
+			amount: rev.NewValidProofOutputs[0].Value,
+		})
+	}
+	c.mu.RUnlock()
+
+	for _, p := range payouts {
+		if err := c.insertPayout(p.id, p.rpk, p.amount, height); err != nil {
+			c.log.Println("ERROR: couldn't record the contract payout:", err)
+			continue
+		}
+		c.mu.Lock()
+		c.payouts[p.id] = struct{}{}
+		c.mu.Unlock()
+		c.log.Printf("INFO: contract %v returned %v to the wallet\n", p.id, p.amount.HumanString())
+		c.logEvent("INFO", eventContractPaidOut, p.id, p.rpk, p.hpk, "unused funds returned: %v", p.amount.HumanString())
+	}
+}
+
+// insertPayout records the renter payout of the expired contract.
+func (c *Contractor) insertPayout(id types.FileContractID, rpk types.SiaPublicKey, amount types.Currency, height types.BlockHeight) error {
+	_, err := c.execWithRetry(`
+		INSERT INTO contract_payouts (contract_id, renter_pk, amount, height)
+		VALUES (?, ?, ?, ?)
+	`, id.String(), rpk.String(), amount.String(), uint64(height))
+	return err
+}
+
+// loadPayouts loads the IDs of the contracts whose payouts have been
+// recorded.
+func (c *Contractor) loadPayouts() error {
+	rows, err := c.db.Query("SELECT contract_id FROM contract_payouts")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var id string
+	var fcid types.FileContractID
+	for rows.Next() {
+		if err := rows.Scan(&id); err != nil {
+			c.log.Println("Error scanning database row:", err)
+			continue
+		}
+		if err := fcid.LoadString(id); err != nil {
+			c.log.Println("ERROR: wrong contract ID:", err)
+			continue
+		}
+		c.payouts[fcid] = struct{}{}
+	}
+
+	return rows.Err()
+}
@@ -0,0 +1,64 @@
+package contractor
+
+import (
+	"testing"
+
+	"go.sia.tech/siad/types"
+)
+
+// TestRecordPayouts tests that the funds returned by an expired contract
+// are recorded once, and that the renewed contracts and the contracts
+// still in the proof window are skipped.
+func TestRecordPayouts(t *testing.T) {
+	c, fake := newTestContractor(t)
+	rpk := testKey(1)
+	testRenter(c, rpk)
+
+	expire := func(id byte, end types.BlockHeight, funds types.Currency) types.FileContractID {
+		contract := testContract(t, c, rpk, testKey(10 + id), id, 0, end, funds)
+		fc, ok := c.staticContracts.Acquire(contract.ID)
+		if !ok {
+			t.Fatal("contract not found")
+		}
+		c.staticContracts.Delete(fc)
+		c.mu.Lock()
+		c.oldContracts[contract.ID] = contract
+		c.mu.Unlock()
+		return contract.ID
+	}
+	expired := expire(1, 1000, types.SiacoinPrecision.Mul64(5))
+	renewed := expire(2, 1000, types.SiacoinPrecision.Mul64(7))
+	pending := expire(3, 1100, types.SiacoinPrecision.Mul64(9))
+	successor := testContract(t, c, rpk, testKey(12), 4, 1000, 2000, types.SiacoinPrecision)
+	c.mu.Lock()
+	c.renewedTo[renewed] = successor.ID
+	c.renewedFrom[successor.ID] = renewed
+	c.blockHeight = 1200
+	c.mu.Unlock()
+
+	c.managedRecordPayouts()
+	inserts := fake.ExecsLike("INSERT INTO contract_payouts")
+	if len(inserts) != 1 {
+		t.Fatalf("expected 1 payout, got %v", len(inserts))
+	}
+	args := inserts[0].Args
+	if args[0] != expired.String() || args[1] != rpk.String() || args[2] != types.SiacoinPrecision.Mul64(5).String() {
+		t.Fatal("wrong payout recorded:", args)
+	}
+
+	// The payout isn't recorded twice.
+	c.managedRecordPayouts()
+	if inserts := fake.ExecsLike("INSERT INTO contract_payouts"); len(inserts) != 1 {
+		t.Fatalf("expected 1 payout, got %v", len(inserts))
+	}
+
+	// Once the proof window has closed, the other contract pays out too.
+	c.mu.Lock()
+	c.blockHeight = 1300
+	c.mu.Unlock()
+	c.managedRecordPayouts()
+	inserts = fake.ExecsLike("INSERT INTO contract_payouts")
+	if len(inserts) != 2 || inserts[1].Args[0] != pending.String() {
+		t.Fatal("expected the payout of the pending contract, got", inserts)
+	}
+}
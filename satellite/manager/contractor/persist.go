@@ -31,7 +31,9 @@ type contractorPersist struct {
 	LastChange           smodules.ConsensusChangeID      `json:"lastchange"`
 	OldContracts         []modules.RenterContract        `json:"oldcontracts"`
 	DoubleSpentContracts map[string]types.BlockHeight    `json:"doublespentcontracts"`
+	OverAllocated        map[string]types.Currency       `json:"overallocated"`
 	Synced               bool                            `json:"synced"`
+	Settings             *modules.ContractorSettings     `json:"settings,omitempty"`
 
 	// Subsystem persistence:
 	WatchdogData watchdogPersist `json:"watchdogdata"`
@@ -40,6 +42,7 @@ type contractorPersist struct {
 // renterData holds the MySQL entry of modules.Renter.
 type renterData struct {
 	Email         string
+	Suffix        string
 	PublicKey     string
 	CurrentPeriod uint64
 	Funds         string
@@ -58,6 +61,16 @@ type renterData struct {
 	MaxSectorAccessPrice      string
 	MaxStoragePrice           string
 	MaxUploadBandwidthPrice   string
+	AllowRedundantIPs         bool
+	MaxStorageBytes           uint64
+	MaxContractsPerRegion     uint64
+	Paused                    bool
+	PreferCollateral          bool
+	SpendRateRefresh          bool
+	MaxHostLatency            uint64
+	LocalRegion               string
+	LocalFraction             float64
+	ReuseRefundAddress        bool
 }
 
 // persistData returns the data in the Contractor that will be saved to disk.
@@ -68,11 +81,17 @@ func (c *Contractor) persistData() contractorPersist {
 		synced = true
 	default:
 	}
+	settings := c.settings()
 	data := contractorPersist{
 		BlockHeight:          c.blockHeight,
 		LastChange:           c.lastChange,
 		DoubleSpentContracts: make(map[string]types.BlockHeight),
+		OverAllocated:        make(map[string]types.Currency),
 		Synced:               synced,
+		Settings:             &settings,
+	}
+	for key, excess := range c.overAllocated {
+		data.OverAllocated[key] = excess
 	}
 	for _, contract := range c.oldContracts {
 		data.OldContracts = append(data.OldContracts, contract)
@@ -86,7 +105,10 @@ func (c *Contractor) persistData() contractorPersist {
 
 // load loads the Contractor persistence data from disk.
 func (c *Contractor) load() error {
-	var data contractorPersist
+	// Start from the current settings, so that the settings missing from
+	// an older file keep their defaults.
+	settings := c.settings()
+	data := contractorPersist{Settings: &settings}
 	err := persist.LoadJSON(persistMeta, &data, filepath.Join(c.persistDir, PersistFilename))
 	if err != nil {
 		return err
@@ -108,10 +130,36 @@ func (c *Contractor) load() error {
 		}
 		c.doubleSpentContracts[fcid] = height
 	}
+	for key, excess := range data.OverAllocated {
+		c.overAllocated[key] = excess
+	}
+	if data.Settings != nil {
+		c.applySettings(*data.Settings)
+	}
 	err = c.loadRenewHistory()
 	if err != nil {
 		return err
 	}
+	err = c.loadPayouts()
+	if err != nil {
+		return err
+	}
+	err = c.loadNoRefresh()
+	if err != nil {
+		return err
+	}
+	err = c.loadCancellations()
+	if err != nil {
+		return err
+	}
+	err = c.loadFormationScores()
+	if err != nil {
+		return err
+	}
+	err = c.loadHostAllowlists()
+	if err != nil {
+		return err
+	}
 
 	c.staticWatchdog, err = newWatchdogFromPersist(c, data.WatchdogData)
 	if err != nil {
@@ -120,49 +168,11 @@ func (c *Contractor) load() error {
 	c.staticWatchdog.blockHeight = data.BlockHeight
 
 	// Load the renters from the database.
-	rows, err := c.db.Query(`
-		SELECT email, public_key, current_period, funds, hosts, period, renew_window,
-			expected_storage, expected_upload, expected_download, expected_redundancy,
-			max_rpc_price, max_contract_price, max_download_bandwidth_price,
-			max_sector_access_price, max_storage_price, max_upload_bandwidth_price
-		FROM renters`)
+	c.renters, err = c.loadRenters()
 	if err != nil {
 		c.log.Println("ERROR: could not load the renters:", err)
 		return err
 	}
-	defer rows.Close()
-
-	var entry renterData
-	for rows.Next() {
-		if err := rows.Scan(&entry.Email, &entry.PublicKey, &entry.CurrentPeriod, &entry.Funds, &entry.Hosts, &entry.Period, &entry.RenewWindow, &entry.ExpectedStorage, &entry.ExpectedUpload, &entry.ExpectedDownload, &entry.ExpectedRedundancy, &entry.MaxRPCPrice, &entry.MaxContractPrice, &entry.MaxDownloadBandwidthPrice, &entry.MaxSectorAccessPrice, &entry.MaxStoragePrice, &entry.MaxUploadBandwidthPrice); err != nil {
-			c.log.Println("ERROR: could not load the renter:", err)
-			continue
-		}
-
-		c.renters[entry.PublicKey] = modules.Renter{
-			Allowance: smodules.Allowance{
-				Funds:       modules.ReadCurrency(entry.Funds),
-				Hosts:       entry.Hosts,
-				Period:      types.BlockHeight(entry.Period),
-				RenewWindow: types.BlockHeight(entry.RenewWindow),
-
-				ExpectedStorage:    entry.ExpectedStorage,
-				ExpectedUpload:     entry.ExpectedUpload,
-				ExpectedDownload:   entry.ExpectedDownload,
-				ExpectedRedundancy: entry.ExpectedRedundancy,
-
-				MaxRPCPrice:               modules.ReadCurrency(entry.MaxRPCPrice),
-				MaxContractPrice:          modules.ReadCurrency(entry.MaxContractPrice),
-				MaxDownloadBandwidthPrice: modules.ReadCurrency(entry.MaxDownloadBandwidthPrice),
-				MaxSectorAccessPrice:      modules.ReadCurrency(entry.MaxSectorAccessPrice),
-				MaxStoragePrice:           modules.ReadCurrency(entry.MaxStoragePrice),
-				MaxUploadBandwidthPrice:   modules.ReadCurrency(entry.MaxUploadBandwidthPrice),
-			},
-			CurrentPeriod: types.BlockHeight(entry.CurrentPeriod),
-			PublicKey:     modules.ReadPublicKey(entry.PublicKey),
-			Email:         entry.Email,
-		}
-	}
 
 	return nil
 }
@@ -233,6 +243,7 @@ func (c *Contractor) threadedSaveLoop() {
 			if err != nil {
 				c.log.Println("Difficulties saving the Contractor:", err)
 			}
+			c.managedLogRenterConsistency()
 		}
 	}
 }
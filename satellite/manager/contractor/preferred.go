@@ -0,0 +1,65 @@
+package contractor
+
+import (
+	"go.sia.tech/siad/build"
+	smodules "go.sia.tech/siad/modules"
+	"go.sia.tech/siad/types"
+)
+
+// managedPreferredHosts puts the renter's preferred hosts in front of the
+// randomly selected ones. A preferred host is skipped silently if it is
+// unknown to the hostdb, excluded by the hostdb filter, blacklisted, or if
+// it fails any of the checks the random selection applies.
+func (c *Contractor) managedPreferredHosts(a smodules.Allowance, preferred []types.SiaPublicKey, hosts []smodules.HostDBEntry, blacklist []types.SiaPublicKey, duration types.BlockHeight) []smodules.HostDBEntry {
+	fm, filtered, _, err := c.hdb.Filter()
+	if err != nil {
+		c.log.Println("WARN: unable to get the hostdb filter:", err)
+		return hosts
+	}
+	isWhitelist := fm == smodules.HostDBActiveWhitelist
+
+	excluded := make(map[string]struct{})
+	for _, pk := range blacklist {
+		excluded[pk.String()] = struct{}{}
+	}
+
+	var selected []smodules.HostDBEntry
+	for _, hpk := range preferred {
+		if _, skip := excluded[hpk.String()]; skip {
+			continue
+		}
+		host, exists, err := c.hdb.Host(hpk)
+		if err != nil || !exists {
+			continue
+		}
+		if _, listed := filtered[hpk.String()]; listed != isWhitelist {
+			continue
+		}
+		if !host.AcceptingContracts || isOffline(host) {
+			continue
+		}
+		if build.VersionCmp(host.Version, minHostVersion) < 0 {
+			continue
+		}
+		if host.MaxDuration < duration {
+			continue
+		}
+		if err := checkFormContractGouging(a, host.HostExternalSettings); err != nil {
+			continue
+		}
+		selected = append(selected, host)
+		excluded[hpk.String()] = struct{}{}
+	}
+	if len(selected) == 0 {
+		return hosts
+	}
+
+	// Append the random hosts that are not preferred.
+	for _, host := range hosts {
+		if _, skip := excluded[host.PublicKey.String()]; !skip {
+			selected = append(selected, host)
+		}
+	}
+
+	return selected
+}
@@ -0,0 +1,58 @@
+package contractor
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/mike76-dev/sia-satellite/modules"
+
+	smodules "go.sia.tech/siad/modules"
+	"go.sia.tech/siad/types"
+)
+
+// TestPreferredHostsFirst tests that the formation attempts the preferred
+// hosts first, and that a preferred host failing the checks is skipped.
+func TestPreferredHostsFirst(t *testing.T) {
+	c, _ := newTestContractor(t)
+	setTestSynced(c)
+	c.wallet = &testWallet{unlocked: true, balance: types.SiacoinPrecision.Mul64(1e4)}
+	newTestFundLocker(c)
+	rpk := testKey(1)
+	testRenter(c, rpk)
+	hdb := newTestHostDB(c)
+	for i := 0; i < 20; i++ {
+		host := testHost(byte(10 + i), fmt.Sprintf("host%v.example.com:9982", i))
+		if i == 17 {
+			host.Version = "1.3.0"
+		}
+		hdb.addHost(host, 100)
+	}
+
+	var attempted []types.SiaPublicKey
+	var id byte
+	form := func(rpk types.SiaPublicKey, host smodules.HostDBEntry, funds types.Currency, endHeight types.BlockHeight) (types.Currency, modules.RenterContract, error) {
+		attempted = append(attempted, host.PublicKey)
+		id++
+		return funds, testContract(t, c, rpk, host.PublicKey, id, 0, endHeight, funds), nil
+	}
+	// The outdated host and an unknown host are skipped.
+	preferred := []types.SiaPublicKey{testKey(25), testKey(27), testKey(99), testKey(18)}
+	if _, err := c.managedFormContracts(context.Background(), rpk, preferred, form); err != nil {
+		t.Fatal(err)
+	}
+	expected := []types.SiaPublicKey{testKey(25), testKey(18)}
+	if len(attempted) < len(expected) {
+		t.Fatalf("expected at least %v attempts, got %v", len(expected), len(attempted))
+	}
+	for i, hpk := range expected {
+		if !attempted[i].Equals(hpk) {
+			t.Fatalf("expected %v at position %v, got %v", hpk, i, attempted[i])
+		}
+	}
+	for _, hpk := range attempted {
+		if hpk.Equals(testKey(27)) {
+			t.Fatal("outdated preferred host attempted")
+		}
+	}
+}
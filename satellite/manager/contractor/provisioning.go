@@ -0,0 +1,64 @@
+package contractor
+
+import (
+	"fmt"
+
+	"github.com/mike76-dev/sia-satellite/modules"
+
+	smodules "go.sia.tech/siad/modules"
+	"go.sia.tech/siad/types"
+)
+
+// The likely causes of an under-provisioned renter.
+const (
+	underProvisionedNoHosts        = "no candidate hosts"
+	underProvisionedLowFunds       = "low allowance funds"
+	underProvisionedHostsExhausted = "all candidate hosts failed or were filtered"
+)
+
+// alertIDUnderProvisioned returns the ID of the alert registered when a
+// contract formation leaves the renter with too few contracts.
+func alertIDUnderProvisioned(rpk types.SiaPublicKey) smodules.AlertID {
+	return smodules.AlertID("contractor-under-provisioned-" + rpk.String())
+}
+
+// alertIDNotEnoughHosts returns the ID of the alert registered when the
+// hostdb returns fewer candidate hosts than the renter's contracts need.
+func alertIDNotEnoughHosts(rpk types.SiaPublicKey) smodules.AlertID {
+	return smodules.AlertID("contractor-not-enough-hosts-" + rpk.String())
+}
+
+// managedCheckHostSupply registers an informational alert if the hostdb
+// returned fewer candidate hosts than the renter's needed contracts, so
+// that a lack of hosts on the network can be told apart from a lack of
+// funds. The alert is unregistered once enough hosts are returned.
+func (c *Contractor) managedCheckHostSupply(rpk types.SiaPublicKey, needed, found int) {
+	id := alertIDNotEnoughHosts(rpk)
+	if found >= needed {
+		c.staticAlerter.UnregisterAlert(id)
+		return
+	}
+
+	cause := fmt.Sprintf("%v candidate hosts for %v needed contracts", found, needed)
+	c.log.Println("INFO: not enough hosts for renter", rpk.String() + ":", cause)
+	c.staticAlerter.RegisterAlert(id, AlertMSGNotEnoughHosts, cause, smodules.SeverityInfo)
+}
+
+// managedCheckProvisioning registers a critical alert if the renter has
+// GoodForUpload contracts with fewer than the minimum provisioned fraction
+// of the allowance hosts after a contract formation, and unregisters it
+// otherwise. The cause is included in the alert.
+func (c *Contractor) managedCheckProvisioning(renter modules.Renter, gfu int, cause string) {
+	id := alertIDUnderProvisioned(renter.PublicKey)
+	c.mu.RLock()
+	fraction := c.minProvisionedFraction
+	c.mu.RUnlock()
+	if float64(gfu) >= float64(renter.Allowance.Hosts) * fraction {
+		c.staticAlerter.UnregisterAlert(id)
+		return
+	}
+
+	msg := fmt.Sprintf("%v of %v contracts: %v", gfu, renter.Allowance.Hosts, cause)
+	c.log.Printf("WARN: renter %v is under-provisioned, %v\n", renter.PublicKey.String(), msg)
+	c.staticAlerter.RegisterAlert(id, AlertMSGUnderProvisioned, msg, smodules.SeverityCritical)
+}
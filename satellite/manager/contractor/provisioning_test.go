@@ -0,0 +1,92 @@
+package contractor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/mike76-dev/sia-satellite/modules"
+
+	smodules "go.sia.tech/siad/modules"
+	"go.sia.tech/siad/types"
+)
+
+// TestUnderProvisioned tests that a formation leaving the renter with too
+// few contracts registers a critical alert with the likely cause, and that
+// the alert is cleared once the renter has enough contracts.
+func TestUnderProvisioned(t *testing.T) {
+	c, _ := newTestContractor(t)
+	setTestSynced(c)
+	c.wallet = &testWallet{unlocked: true, balance: types.SiacoinPrecision.Mul64(1e4)}
+	newTestFundLocker(c)
+	rpk := testKey(1)
+	testRenter(c, rpk)
+	hdb := newTestHostDB(c)
+
+	// The last host fails the formation.
+	var id byte
+	form := func(rpk types.SiaPublicKey, host smodules.HostDBEntry, funds types.Currency, endHeight types.BlockHeight) (types.Currency, modules.RenterContract, error) {
+		if host.PublicKey.Key[0] == 12 {
+			return types.ZeroCurrency, modules.RenterContract{}, errors.New("negotiation failed")
+		}
+		id++
+		return funds, testContract(t, c, rpk, host.PublicKey, id, 0, endHeight, funds), nil
+	}
+	cause := func() string {
+		t.Helper()
+		crit, _, _, _ := c.staticAlerter.Alerts()
+		for _, alert := range crit {
+			if alert.Msg == AlertMSGUnderProvisioned {
+				return alert.Cause
+			}
+		}
+		return ""
+	}
+	formContracts := func() {
+		t.Helper()
+		if _, err := c.managedFormContracts(context.Background(), rpk, nil, form); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Without the candidates.
+	formContracts()
+	if cause := cause(); !strings.Contains(cause, "0 of 10 contracts: " + underProvisionedNoHosts) {
+		t.Fatal("expected the alert about no candidates, got", cause)
+	}
+
+	// With the candidates exhausted.
+	for i := 0; i < 3; i++ {
+		hdb.addHost(testHost(byte(10 + i), fmt.Sprintf("host%v.example.com:9982", i)), 100)
+	}
+	formContracts()
+	if cause := cause(); !strings.Contains(cause, "2 of 10 contracts: " + underProvisionedHostsExhausted) {
+		t.Fatal("expected the alert about the exhausted candidates, got", cause)
+	}
+
+	// A lower fraction is met by the formed contracts.
+	if err := setTestSettings(c, func(s *modules.ContractorSettings) { s.MinProvisionedFraction = 1.5 }); !errors.Is(err, errInvalidProvisionedFraction) {
+		t.Fatal("expected errInvalidProvisionedFraction, got", err)
+	}
+	if err := setTestSettings(c, func(s *modules.ContractorSettings) { s.MinProvisionedFraction = 0.2 }); err != nil {
+		t.Fatal(err)
+	}
+	formContracts()
+	if cause := cause(); cause != "" {
+		t.Fatal("expected the alert to be cleared, got", cause)
+	}
+	if err := setTestSettings(c, func(s *modules.ContractorSettings) { s.MinProvisionedFraction = defaultMinProvisionedFraction }); err != nil {
+		t.Fatal(err)
+	}
+
+	// With enough contracts.
+	for i := 3; i < 10; i++ {
+		hdb.addHost(testHost(byte(10 + i), fmt.Sprintf("host%v.example.com:9982", i)), 100)
+	}
+	formContracts()
+	if cause := cause(); cause != "" {
+		t.Fatal("expected the alert to be cleared, got", cause)
+	}
+}
@@ -0,0 +1,57 @@
+package contractor
+
+import (
+	"fmt"
+	"math/bits"
+
+	"github.com/mike76-dev/sia-satellite/modules"
+
+	smodules "go.sia.tech/siad/modules"
+	"go.sia.tech/siad/types"
+)
+
+// alertIDStorageQuota returns the ID of the alert registered when the
+// renter's allowance exceeds their storage quota.
+func alertIDStorageQuota(rpk types.SiaPublicKey) smodules.AlertID {
+	return smodules.AlertID("contractor-storage-quota-" + rpk.String())
+}
+
+// checkStorageQuota returns an error if the storage capacity provisioned by
+// the renter's allowance, i.e. the number of hosts times the expected
+// storage, exceeds the renter's quota. A zero quota means unlimited.
+func checkStorageQuota(renter modules.Renter) error {
+	if renter.MaxStorageBytes == 0 {
+		return nil
+	}
+	hi, capacity := bits.Mul64(renter.Allowance.Hosts, renter.Allowance.ExpectedStorage)
+	if hi != 0 || capacity > renter.MaxStorageBytes {
+		return fmt.Errorf("allowance of %v hosts with %v bytes of expected storage exceeds the storage quota of %v bytes", renter.Allowance.Hosts, renter.Allowance.ExpectedStorage, renter.MaxStorageBytes)
+	}
+	return nil
+}
+
+// managedCheckStorageQuota checks the renter's allowance against their
+// storage quota and registers or unregisters the respective alert.
+func (c *Contractor) managedCheckStorageQuota(renter modules.Renter) error {
+	err := checkStorageQuota(renter)
+	if err != nil {
+		c.staticAlerter.RegisterAlert(alertIDStorageQuota(renter.PublicKey), AlertMSGStorageQuotaExceeded, err.Error(), smodules.SeverityWarning)
+		return err
+	}
+	c.staticAlerter.UnregisterAlert(alertIDStorageQuota(renter.PublicKey))
+	return nil
+}
+
+// SetMaxStorageBytes sets the renter's storage quota. Zero means unlimited.
+func (c *Contractor) SetMaxStorageBytes(rpk types.SiaPublicKey, maxStorage uint64) error {
+	c.mu.Lock()
+	renter, exists := c.renters[rpk.String()]
+	if !exists {
+		c.mu.Unlock()
+		return ErrRenterNotFound
+	}
+	renter.MaxStorageBytes = maxStorage
+	c.renters[rpk.String()] = renter
+	c.mu.Unlock()
+	return c.UpdateRenter(renter)
+}
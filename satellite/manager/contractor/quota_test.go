@@ -0,0 +1,60 @@
+package contractor
+
+import (
+	"context"
+	"testing"
+
+	"go.sia.tech/siad/types"
+)
+
+// TestStorageQuotaFormation tests that a renter whose allowance exceeds
+// the storage quota can't form contracts, while a renter under the quota
+// can.
+func TestStorageQuotaFormation(t *testing.T) {
+	c, _ := newTestContractor(t)
+	setTestSynced(c)
+	c.wallet = &testWallet{unlocked: true, balance: types.SiacoinPrecision.Mul64(1e4)}
+	newTestHostDB(c)
+
+	// 10 hosts with 1 TB of expected storage each.
+	over, under := testKey(1), testKey(2)
+	testRenter(c, over)
+	testRenter(c, under)
+	if err := c.SetMaxStorageBytes(over, 5e12); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.SetMaxStorageBytes(under, 20e12); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := c.FormContractsWithSpending(context.Background(), over, nil); err == nil {
+		t.Fatal("renter over quota formed contracts")
+	}
+	if countAlerts(c, AlertMSGStorageQuotaExceeded) != 1 {
+		t.Fatal("quota alert not registered")
+	}
+
+	// The renter under the quota isn't stopped by it. There are no hosts
+	// to form the contracts with, though.
+	result, err := c.FormContractsWithSpending(context.Background(), under, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Contracts) != 0 {
+		t.Fatal("contracts formed without hosts")
+	}
+	if countAlerts(c, AlertMSGStorageQuotaExceeded) != 1 {
+		t.Fatal("quota alert registered for the renter under the quota")
+	}
+
+	// Raising the quota lets the first renter form contracts, too.
+	if err := c.SetMaxStorageBytes(over, 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.FormContractsWithSpending(context.Background(), over, nil); err != nil {
+		t.Fatal(err)
+	}
+	if countAlerts(c, AlertMSGStorageQuotaExceeded) != 0 {
+		t.Fatal("quota alert not unregistered")
+	}
+}
@@ -0,0 +1,53 @@
+package contractor
+
+import (
+	"github.com/mike76-dev/sia-satellite/modules"
+
+	"go.sia.tech/siad/types"
+)
+
+// SetSpendRateRefresh sets whether the refreshed contracts of the renter
+// are sized by their spend rate instead of doubling their funding.
+func (c *Contractor) SetSpendRateRefresh(rpk types.SiaPublicKey, enabled bool) error {
+	c.mu.Lock()
+	renter, exists := c.renters[rpk.String()]
+	if !exists {
+		c.mu.Unlock()
+		return ErrRenterNotFound
+	}
+	renter.SpendRateRefresh = enabled
+	c.renters[rpk.String()] = renter
+	c.mu.Unlock()
+	return c.UpdateRenter(renter)
+}
+
+// refreshFunding returns the funding of the contract that is refreshed
+// for running out of funds. By default, the funding is doubled. If
+// spendRate is set, the funding covers the spending projected from the
+// contract's spend rate until endHeight, plus RefreshSpendRateBuffer. If
+// the contract is too young or hasn't spent anything yet, the funding is
+// doubled anyway.
+func refreshFunding(rc modules.RenterContract, blockHeight, endHeight types.BlockHeight, spendRate bool) types.Currency {
+	doubled := rc.TotalCost.Mul64(2)
+	if !spendRate || blockHeight < rc.StartHeight + MinRefreshSpendHistory || endHeight <= blockHeight {
+		return doubled
+	}
+	spent := rc.UploadSpending.Add(rc.DownloadSpending).Add(rc.StorageSpending).Add(rc.FundAccountSpending)
+	if spent.IsZero() {
+		return doubled
+	}
+	elapsed := uint64(blockHeight - rc.StartHeight)
+	remaining := uint64(endHeight - blockHeight)
+	return spent.Mul64(remaining).Div64(elapsed).MulFloat(1 + RefreshSpendRateBuffer)
+}
+
+// managedRefreshAmount returns the funding of the refreshed contract, which
+// is never below the minimum contract funding of the renter's allowance.
+func (c *Contractor) managedRefreshAmount(rc modules.RenterContract, renter modules.Renter, blockHeight, endHeight types.BlockHeight) types.Currency {
+	amount := refreshFunding(rc, blockHeight, endHeight, renter.SpendRateRefresh)
+	minimum := c.managedMinimumContractFunding(renter.Allowance)
+	if amount.Cmp(minimum) < 0 {
+		return minimum
+	}
+	return amount
+}
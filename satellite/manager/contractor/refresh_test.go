@@ -0,0 +1,86 @@
+package contractor
+
+import (
+	"testing"
+
+	"github.com/mike76-dev/sia-satellite/modules"
+
+	"go.sia.tech/siad/types"
+)
+
+// TestRefreshFunding tests that the spend rate based refresh is sized by
+// the projected spending, while the default refresh doubles the funding,
+// and that the refresh falls back to doubling without a spend history.
+func TestRefreshFunding(t *testing.T) {
+	// A large contract that has spent 10 SC in the first half of its
+	// period.
+	rc := modules.RenterContract{
+		StartHeight:     0,
+		TotalCost:       types.SiacoinPrecision.Mul64(100),
+		StorageSpending: types.SiacoinPrecision.Mul64(4),
+		UploadSpending:  types.SiacoinPrecision.Mul64(6),
+	}
+	doubled := types.SiacoinPrecision.Mul64(200)
+
+	if amount := refreshFunding(rc, 500, 1000, false); !amount.Equals(doubled) {
+		t.Fatalf("expected the doubled funding %v, got %v", doubled, amount)
+	}
+
+	// 10 SC over the remaining 500 blocks plus the buffer.
+	expected := types.SiacoinPrecision.Mul64(10).MulFloat(1 + RefreshSpendRateBuffer)
+	amount := refreshFunding(rc, 500, 1000, true)
+	if !amount.Equals(expected) {
+		t.Fatalf("expected the rate based funding %v, got %v", expected, amount)
+	}
+	if amount.Cmp(doubled) >= 0 {
+		t.Fatal("rate based funding isn't smaller than the doubled funding")
+	}
+
+	// Half the remaining period needs half the funding.
+	if half := refreshFunding(rc, 500, 750, true); !half.Equals(expected.Div64(2)) {
+		t.Fatalf("expected %v, got %v", expected.Div64(2), half)
+	}
+
+	// Without enough history the funding is doubled.
+	if amount := refreshFunding(rc, MinRefreshSpendHistory - 1, 1000, true); !amount.Equals(doubled) {
+		t.Fatalf("expected the doubled funding for a young contract, got %v", amount)
+	}
+	idle := rc
+	idle.UploadSpending, idle.StorageSpending = types.ZeroCurrency, types.ZeroCurrency
+	if amount := refreshFunding(idle, 500, 1000, true); !amount.Equals(doubled) {
+		t.Fatalf("expected the doubled funding for an idle contract, got %v", amount)
+	}
+}
+
+// TestSpendRateRefresh tests that the refresh strategy is selected per
+// renter.
+func TestSpendRateRefresh(t *testing.T) {
+	c, _ := newTestContractor(t)
+	rpk := testKey(1)
+	testRenter(c, rpk)
+	if err := setTestSettings(c, func(s *modules.ContractorSettings) { s.MinimumFunding = 0 }); err != nil {
+		t.Fatal(err)
+	}
+	rc := modules.RenterContract{
+		TotalCost:      types.SiacoinPrecision.Mul64(100),
+		UploadSpending: types.SiacoinPrecision.Mul64(10),
+	}
+	renter := func() modules.Renter {
+		c.mu.RLock()
+		defer c.mu.RUnlock()
+		return c.renters[rpk.String()]
+	}
+
+	if amount := c.managedRefreshAmount(rc, renter(), 500, 1000); !amount.Equals(refreshFunding(rc, 500, 1000, false)) {
+		t.Fatal("expected the doubled funding by default, got", amount)
+	}
+	if err := c.SetSpendRateRefresh(rpk, true); err != nil {
+		t.Fatal(err)
+	}
+	if amount := c.managedRefreshAmount(rc, renter(), 500, 1000); !amount.Equals(refreshFunding(rc, 500, 1000, true)) {
+		t.Fatal("expected the rate based funding, got", amount)
+	}
+	if err := c.SetSpendRateRefresh(testKey(2), true); err != ErrRenterNotFound {
+		t.Fatal("expected ErrRenterNotFound, got", err)
+	}
+}
@@ -0,0 +1,64 @@
+package contractor
+
+import (
+	"github.com/mike76-dev/sia-satellite/modules"
+
+	"go.sia.tech/siad/types"
+)
+
+// SetReuseRefundAddress sets whether the contracts of the renter share a
+// single refund address instead of using a fresh one each.
+func (c *Contractor) SetReuseRefundAddress(rpk types.SiaPublicKey, enabled bool) error {
+	c.mu.Lock()
+	renter, exists := c.renters[rpk.String()]
+	if !exists {
+		c.mu.Unlock()
+		return ErrRenterNotFound
+	}
+	renter.ReuseRefundAddress = enabled
+	c.renters[rpk.String()] = renter
+	c.mu.Unlock()
+	return c.UpdateRenter(renter)
+}
+
+// managedRenterRefundAddress returns the refund address of the renter's
+// oldest contract, so that it can be reused by the new contracts. If the
+// renter has no contracts yet, false is returned.
+func (c *Contractor) managedRenterRefundAddress(rpk types.SiaPublicKey) (types.UnlockHash, bool) {
+	var oldest modules.RenterContract
+	var found bool
+	for _, contract := range c.staticContracts.ByRenter(rpk) {
+		if len(contract.Transaction.FileContractRevisions) == 0 {
+			continue
+		}
+		if len(contract.Transaction.FileContractRevisions[0].NewValidProofOutputs) == 0 {
+			continue
+		}
+		if !found || contract.StartHeight < oldest.StartHeight {
+			oldest = contract
+			found = true
+		}
+	}
+	if !found {
+		return types.UnlockHash{}, false
+	}
+	return oldest.Transaction.FileContractRevisions[0].NewValidProofOutputs[0].UnlockHash, true
+}
+
+// managedRefundAddress returns the refund address for a new or renewed
+// contract of the renter. If the renter reuses the refund address and
+// already has a contract, its address is returned. Otherwise, a fresh
+// address is taken from the wallet, and fresh is set to true, so that the
+// caller can mark it unused if the formation fails.
+func (c *Contractor) managedRefundAddress(renter modules.Renter) (uh types.UnlockHash, uc types.UnlockConditions, fresh bool, err error) {
+	if renter.ReuseRefundAddress {
+		if uh, exists := c.managedRenterRefundAddress(renter.PublicKey); exists {
+			return uh, types.UnlockConditions{}, false, nil
+		}
+	}
+	uc, err = c.wallet.NextAddress()
+	if err != nil {
+		return types.UnlockHash{}, types.UnlockConditions{}, false, err
+	}
+	return uc.UnlockHash(), uc, true, nil
+}
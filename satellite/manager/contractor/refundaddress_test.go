@@ -0,0 +1,140 @@
+package contractor
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/mike76-dev/sia-satellite/modules"
+
+	"gitlab.com/NebulousLabs/errors"
+
+	"go.sia.tech/siad/crypto"
+	"go.sia.tech/siad/types"
+)
+
+// freshWallet is a testWallet that hands out a new address on each call.
+type freshWallet struct {
+	*testWallet
+	mu        sync.Mutex
+	addresses int
+}
+
+// NextAddress implements modules.Wallet.
+func (w *freshWallet) NextAddress() (types.UnlockConditions, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.addresses++
+	return types.UnlockConditions{Timelock: types.BlockHeight(w.addresses)}, nil
+}
+
+// refundContract inserts a contract between the renter and the host that
+// refunds to the given address.
+func refundContract(t *testing.T, c *Contractor, rpk, hpk types.SiaPublicKey, id byte, start types.BlockHeight, refund types.UnlockHash) {
+	t.Helper()
+	var fcid types.FileContractID
+	fcid[0] = id
+	rev := types.FileContractRevision{
+		ParentID: fcid,
+		UnlockConditions: types.UnlockConditions{
+			PublicKeys:         []types.SiaPublicKey{rpk, hpk},
+			SignaturesRequired: 2,
+		},
+		NewWindowStart: 1000,
+		NewWindowEnd:   1144,
+		NewValidProofOutputs: []types.SiacoinOutput{
+			{Value: types.SiacoinPrecision, UnlockHash: refund},
+			{Value: types.ZeroCurrency},
+		},
+		NewMissedProofOutputs: []types.SiacoinOutput{
+			{Value: types.SiacoinPrecision, UnlockHash: refund},
+			{Value: types.ZeroCurrency},
+			{Value: types.ZeroCurrency},
+		},
+	}
+	rc := modules.RecoverableContract{
+		FileContract: types.FileContract{
+			ValidProofOutputs: rev.NewValidProofOutputs,
+		},
+		StartHeight: start,
+	}
+	txn := types.Transaction{
+		FileContractRevisions: []types.FileContractRevision{rev},
+	}
+	if _, err := c.staticContracts.InsertContract(rc, txn, nil, crypto.SecretKey{}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestReuseRefundAddress tests that the contracts of a renter reusing the
+// refund address share one, while the other renters get a fresh address
+// per contract.
+func TestReuseRefundAddress(t *testing.T) {
+	c, fake := newTestContractor(t)
+	w := &freshWallet{testWallet: &testWallet{unlocked: true}}
+	c.wallet = w
+
+	// form takes a refund address for the renter and forms a contract
+	// with it.
+	var id byte
+	form := func(rpk types.SiaPublicKey) (types.UnlockHash, bool) {
+		t.Helper()
+		c.mu.RLock()
+		renter := c.renters[rpk.String()]
+		c.mu.RUnlock()
+		uh, _, fresh, err := c.managedRefundAddress(renter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		id++
+		refundContract(t, c, rpk, testKey(10 + id), id, types.BlockHeight(id), uh)
+		return uh, fresh
+	}
+
+	// By default, each contract gets a fresh address.
+	rpk := testKey(1)
+	testRenter(c, rpk)
+	seen := make(map[types.UnlockHash]bool)
+	for i := 0; i < 3; i++ {
+		uh, fresh := form(rpk)
+		if !fresh || seen[uh] {
+			t.Fatalf("expected a fresh address for contract %v", i)
+		}
+		seen[uh] = true
+	}
+
+	// With the reuse enabled, the contracts share the first address.
+	other := testKey(2)
+	testRenter(c, other)
+	if err := c.SetReuseRefundAddress(other, true); err != nil {
+		t.Fatal(err)
+	}
+	if updates := fake.ExecsLike("reuse_refund_address"); len(updates) != 1 {
+		t.Fatal("expected the option to be saved, got", updates)
+	}
+	first, fresh := form(other)
+	if !fresh || seen[first] {
+		t.Fatal("expected a fresh address for the first contract")
+	}
+	addresses := w.addresses
+	for i := 0; i < 3; i++ {
+		if uh, fresh := form(other); fresh || uh != first {
+			t.Fatalf("expected contract %v to reuse %v, got %v", i, first, uh)
+		}
+	}
+	if w.addresses != addresses {
+		t.Fatalf("expected no new addresses, got %v", w.addresses - addresses)
+	}
+
+	// Disabling the reuse restores the fresh addresses.
+	if err := c.SetReuseRefundAddress(other, false); err != nil {
+		t.Fatal(err)
+	}
+	if uh, fresh := form(other); !fresh || uh == first {
+		t.Fatal("expected a fresh address after disabling the reuse")
+	}
+
+	// An unknown renter can't be configured.
+	if err := c.SetReuseRefundAddress(testKey(3), true); !errors.Contains(err, ErrRenterNotFound) {
+		t.Fatal("expected ErrRenterNotFound, got", err)
+	}
+}
@@ -0,0 +1,139 @@
+package contractor
+
+import (
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/mike76-dev/sia-satellite/modules"
+
+	smodules "go.sia.tech/siad/modules"
+	"go.sia.tech/siad/types"
+)
+
+// regionResolver maps a host's net address to the region the host is
+// located in. An empty region means that the location is unknown.
+type regionResolver interface {
+	Region(smodules.NetAddress) string
+}
+
+// tldResolver is the default regionResolver. It uses the country code
+// top-level domain of the host's hostname as the region. The hostdb has no
+// geolocation data, so only the hosts announced with a hostname are
+// covered: IP addresses and generic top-level domains resolve to an
+// unknown region. For the region cap, all hosts with an unknown region
+// count as one region, so that a renter can't end up with all contracts
+// in one place just because it is unknown.
+type tldResolver struct{}
+
+// Region implements regionResolver.
+func (tldResolver) Region(addr smodules.NetAddress) string {
+	host := addr.Host()
+	if net.ParseIP(host) != nil {
+		return ""
+	}
+	i := strings.LastIndex(host, ".")
+	if i < 0 {
+		return ""
+	}
+	tld := strings.ToLower(host[i+1:])
+	if len(tld) != 2 {
+		return ""
+	}
+	return tld
+}
+
+// SetMaxContractsPerRegion sets the maximum number of the renter's
+// contracts with the hosts located in any single region. Zero means no
+// limit.
+func (c *Contractor) SetMaxContractsPerRegion(rpk types.SiaPublicKey, maxContracts uint64) error {
+	c.mu.Lock()
+	renter, exists := c.renters[rpk.String()]
+	if !exists {
+		c.mu.Unlock()
+		return ErrRenterNotFound
+	}
+	renter.MaxContractsPerRegion = maxContracts
+	c.renters[rpk.String()] = renter
+	c.mu.Unlock()
+	return c.UpdateRenter(renter)
+}
+
+// managedHostRegion returns the region of the host.
+func (c *Contractor) managedHostRegion(host smodules.HostDBEntry) string {
+	c.mu.RLock()
+	rr := c.regionResolver
+	c.mu.RUnlock()
+	return rr.Region(host.NetAddress)
+}
+
+// managedRegionCounts returns the number of the given contracts per region.
+// The contracts with the hosts in an unknown region are counted under the
+// empty region.
+func (c *Contractor) managedRegionCounts(contracts []modules.RenterContract) map[string]uint64 {
+	counts := make(map[string]uint64)
+	for _, contract := range contracts {
+		host, exists, err := c.hdb.Host(contract.HostPublicKey)
+		if err != nil || !exists {
+			continue
+		}
+		counts[c.managedHostRegion(host)]++
+	}
+	return counts
+}
+
+// managedDiversifyHosts reorders the candidate hosts, so that the hosts
+// from the less used regions come first when their scores are within
+// RegionScoreTolerance of the best remaining host. The hosts from the
+// regions that would exceed the cap are moved to the end of the list, so
+// that they are only tried if the others fail. The counts map is not
+// modified.
+func (c *Contractor) managedDiversifyHosts(hosts []smodules.HostDBEntry, counts map[string]uint64, maxPerRegion uint64) []smodules.HostDBEntry {
+	type candidate struct {
+		host   smodules.HostDBEntry
+		region string
+		score  types.Currency
+	}
+	candidates := make([]candidate, 0, len(hosts))
+	for _, host := range hosts {
+		sb, err := c.managedScoreBreakdown(host)
+		if err != nil {
+			continue
+		}
+		candidates = append(candidates, candidate{
+			host:   host,
+			region: c.managedHostRegion(host),
+			score:  sb.Score,
+		})
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].score.Cmp(candidates[j].score) > 0
+	})
+
+	projected := make(map[string]uint64)
+	for region, n := range counts {
+		projected[region] = n
+	}
+	diversified := make([]smodules.HostDBEntry, 0, len(candidates))
+	var overflow []smodules.HostDBEntry
+	for len(candidates) > 0 {
+		// Among the hosts with a score close to the best one, pick the one
+		// from the least used region.
+		threshold := candidates[0].score.MulFloat(1 - RegionScoreTolerance)
+		best := 0
+		for i := 1; i < len(candidates) && candidates[i].score.Cmp(threshold) >= 0; i++ {
+			if projected[candidates[i].region] < projected[candidates[best].region] {
+				best = i
+			}
+		}
+		cand := candidates[best]
+		candidates = append(candidates[:best], candidates[best+1:]...)
+		if projected[cand.region] >= maxPerRegion {
+			overflow = append(overflow, cand.host)
+			continue
+		}
+		projected[cand.region]++
+		diversified = append(diversified, cand.host)
+	}
+	return append(diversified, overflow...)
+}
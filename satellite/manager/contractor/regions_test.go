@@ -0,0 +1,52 @@
+package contractor
+
+import (
+	"testing"
+
+	smodules "go.sia.tech/siad/modules"
+)
+
+// TestDiversifyHostsRegionCap tests that the region cap spreads the hosts
+// clustered in one region across the regions, and that the hosts with an
+// unknown region count as one region.
+func TestDiversifyHostsRegionCap(t *testing.T) {
+	c, _ := newTestContractor(t)
+	hosts := []smodules.HostDBEntry{
+		testHost(1, "host1.example.de:9982"),
+		testHost(2, "host2.example.de:9982"),
+		testHost(3, "host3.example.de:9982"),
+		testHost(4, "host4.example.de:9982"),
+		testHost(5, "host.example.fr:9982"),
+		testHost(6, "10.0.0.1:9982"),
+		testHost(7, "10.0.0.2:9982"),
+		testHost(8, "10.0.0.3:9982"),
+	}
+	hdb := newTestHostDB(c)
+	for i, host := range hosts {
+		score := uint64(100 - i)
+		if i >= 4 {
+			score = uint64(50 - i)
+		}
+		hdb.addHost(host, score)
+	}
+
+	diversified := c.managedDiversifyHosts(hosts, make(map[string]uint64), 2)
+	if len(diversified) != len(hosts) {
+		t.Fatalf("expected %v hosts, got %v", len(hosts), len(diversified))
+	}
+	expected := []byte{1, 2, 5, 6, 7, 3, 4, 8}
+	for i, b := range expected {
+		if !diversified[i].PublicKey.Equals(testKey(b)) {
+			t.Fatalf("wrong host at position %v: %v", i, diversified[i].NetAddress)
+		}
+	}
+
+	// The existing contracts count towards the cap.
+	diversified = c.managedDiversifyHosts(hosts, map[string]uint64{"de": 2, "": 1}, 2)
+	expected = []byte{5, 6, 1, 2, 3, 4, 7, 8}
+	for i, b := range expected {
+		if !diversified[i].PublicKey.Equals(testKey(b)) {
+			t.Fatalf("wrong host at position %v: %v", i, diversified[i].NetAddress)
+		}
+	}
+}
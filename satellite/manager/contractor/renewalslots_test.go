@@ -0,0 +1,104 @@
+package contractor
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mike76-dev/sia-satellite/modules"
+
+	"gitlab.com/NebulousLabs/errors"
+
+	"go.sia.tech/siad/types"
+
+	smodules "go.sia.tech/siad/modules"
+)
+
+// slotHostDB is a testHostDB that holds the renewals inside the host lookup
+// and records how many of them run at the same time.
+type slotHostDB struct {
+	*testHostDB
+	release chan struct{}
+
+	mu     sync.Mutex
+	active int
+	max    int
+}
+
+// Host implements modules.HostDB. The host is reported as missing, so that
+// the renewal fails once released.
+func (hdb *slotHostDB) Host(pk types.SiaPublicKey) (smodules.HostDBEntry, bool, error) {
+	hdb.mu.Lock()
+	hdb.active++
+	if hdb.active > hdb.max {
+		hdb.max = hdb.active
+	}
+	hdb.mu.Unlock()
+	<-hdb.release
+	hdb.mu.Lock()
+	hdb.active--
+	hdb.mu.Unlock()
+	return smodules.HostDBEntry{}, false, nil
+}
+
+// numActive returns the number of renewals being held.
+func (hdb *slotHostDB) numActive() int {
+	hdb.mu.Lock()
+	defer hdb.mu.Unlock()
+	return hdb.active
+}
+
+// TestMaxConcurrentRenewals tests that the renewals beyond the limit wait
+// for a free slot, and that the limit is never exceeded.
+func TestMaxConcurrentRenewals(t *testing.T) {
+	c, _ := newTestContractor(t)
+	if err := setTestSettings(c, func(s *modules.ContractorSettings) { s.MaxConcurrentRenewals = 0 }); !errors.Contains(err, errInvalidRenewalLimit) {
+		t.Fatal("expected errInvalidRenewalLimit, got", err)
+	}
+	limit := 2
+	if err := setTestSettings(c, func(s *modules.ContractorSettings) { s.MaxConcurrentRenewals = limit }); err != nil {
+		t.Fatal(err)
+	}
+	hdb := &slotHostDB{testHostDB: newTestHostDB(c), release: make(chan struct{})}
+	c.hdb = hdb
+
+	rpk := testKey(1)
+	testRenter(c, rpk)
+	var wg sync.WaitGroup
+	errs := make(chan error, 6)
+	for i := 0; i < 6; i++ {
+		host := testHost(byte(10 + i), "host.example.com:9982")
+		contract := testContract(t, c, rpk, host.PublicKey, byte(10 + i), 0, 1000, types.SiacoinPrecision)
+		c.staticSettingsCache.put(host.PublicKey, smodules.HostExternalSettings{}, time.Millisecond)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _, err := c.managedRenewContract(fileContractRenewal{
+				id:           contract.ID,
+				amount:       types.SiacoinPrecision,
+				renterPubKey: rpk,
+				hostPubKey:   host.PublicKey,
+			}, 0, 2000)
+			errs <- err
+		}()
+	}
+
+	// Wait for the slots to fill up, and give the other renewals a chance
+	// to exceed the limit.
+	for hdb.numActive() < limit {
+		time.Sleep(10 * time.Millisecond)
+	}
+	time.Sleep(100 * time.Millisecond)
+	close(hdb.release)
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err == nil {
+			t.Fatal("expected the renewal to fail")
+		}
+	}
+	if hdb.max != limit {
+		t.Fatalf("expected at most %v concurrent renewals, got %v", limit, hdb.max)
+	}
+}
@@ -0,0 +1,52 @@
+package contractor
+
+import (
+	"go.sia.tech/siad/types"
+)
+
+// managedRepairRenewedLinks removes the renewedFrom and renewedTo entries
+// that reference a contract which is neither active nor archived. Such
+// entries are left behind when a contract is deleted without clearing
+// the links, and would otherwise make a contract look renewed. The
+// number of removed entries is returned.
+// The active contracts are listed under the lock, so that a renewal
+// completing in the meantime can't make its new links look orphaned.
+func (c *Contractor) managedRepairRenewedLinks() int {
+	var orphanedFrom, orphanedTo []types.FileContractID
+	known := make(map[types.FileContractID]struct{})
+	c.mu.Lock()
+	for _, contract := range c.staticContracts.ViewAll() {
+		known[contract.ID] = struct{}{}
+	}
+	for id := range c.oldContracts {
+		known[id] = struct{}{}
+	}
+	for id, from := range c.renewedFrom {
+		if _, exists := known[from]; !exists {
+			delete(c.renewedFrom, id)
+			orphanedFrom = append(orphanedFrom, id)
+			c.log.Printf("WARN: removed the orphaned renewedFrom entry %v -> %v\n", id, from)
+		}
+	}
+	for id, to := range c.renewedTo {
+		if _, exists := known[to]; !exists {
+			delete(c.renewedTo, id)
+			orphanedTo = append(orphanedTo, id)
+			c.log.Printf("WARN: removed the orphaned renewedTo entry %v -> %v\n", id, to)
+		}
+	}
+	c.mu.Unlock()
+
+	for _, id := range orphanedFrom {
+		if err := c.clearRenewedLink(id, "renewed_from"); err != nil {
+			c.log.Println("ERROR: unable to clear the renewedFrom entry:", err)
+		}
+	}
+	for _, id := range orphanedTo {
+		if err := c.clearRenewedLink(id, "renewed_to"); err != nil {
+			c.log.Println("ERROR: unable to clear the renewedTo entry:", err)
+		}
+	}
+
+	return len(orphanedFrom) + len(orphanedTo)
+}
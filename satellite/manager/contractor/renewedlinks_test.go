@@ -0,0 +1,47 @@
+package contractor
+
+import (
+	"strings"
+	"testing"
+
+	smodules "go.sia.tech/siad/modules"
+	"go.sia.tech/siad/types"
+)
+
+// TestRepairRenewedLinks tests that an orphaned renewedTo entry is removed
+// from the memory and the database, and that the contract utility can then
+// be updated without the false CRITICAL message.
+func TestRepairRenewedLinks(t *testing.T) {
+	c, fake := newTestContractor(t)
+	rpk := testKey(1)
+	testRenter(c, rpk)
+	contract := testContract(t, c, rpk, testKey(10), 1, 0, 1000, types.SiacoinPrecision.Mul64(10))
+	renewed := testContract(t, c, rpk, testKey(11), 2, 0, 1000, types.SiacoinPrecision.Mul64(10))
+	var missing types.FileContractID
+	missing[0] = 3
+	c.mu.Lock()
+	c.renewedTo[contract.ID] = missing
+	c.renewedTo[renewed.ID] = contract.ID
+	c.mu.Unlock()
+
+	if n := c.managedRepairRenewedLinks(); n != 1 {
+		t.Fatalf("expected 1 repair, got %v", n)
+	}
+	c.mu.RLock()
+	_, orphaned := c.renewedTo[contract.ID]
+	_, kept := c.renewedTo[renewed.ID]
+	c.mu.RUnlock()
+	if orphaned || !kept {
+		t.Fatal("wrong renewedTo entries removed")
+	}
+	if len(fake.ExecsLike("SET renewed_to = ''")) != 1 {
+		t.Fatal("orphaned entry not cleared in the database")
+	}
+
+	if err := c.managedAcquireAndUpdateContractUtility(contract.ID, smodules.ContractUtility{GoodForUpload: true, GoodForRenew: true}, "test"); err != nil {
+		t.Fatal(err)
+	}
+	if log := testLog(t, c); strings.Contains(log, "CRITICAL") {
+		t.Fatal("false CRITICAL logged:", log)
+	}
+}
@@ -0,0 +1,83 @@
+package contractor
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mike76-dev/sia-satellite/modules"
+
+	"gitlab.com/NebulousLabs/errors"
+
+	"go.sia.tech/siad/types"
+)
+
+// TestRenewHistoryDepth tests that the estimate follows the renew history
+// of a contract at most MaxRenewHistoryDepth contracts deep, and that a
+// warning is logged when the limit is hit.
+func TestRenewHistoryDepth(t *testing.T) {
+	c, _ := newTestContractor(t)
+	if err := setTestSettings(c, func(s *modules.ContractorSettings) { s.MaxRenewHistoryDepth = 0 }); !errors.Contains(err, errInvalidRenewHistoryDepth) {
+		t.Fatal("expected errInvalidRenewHistoryDepth, got", err)
+	}
+	setTestSynced(c)
+	rpk, hpk := testKey(1), testKey(10)
+	renter := testRenter(c, rpk)
+	newTestHostDB(c, testHost(10, "host.example.com:9982"))
+	contract := testContract(t, c, rpk, hpk, 100, 0, 1000, types.SiacoinPrecision)
+
+	// The contract has a long renew history within the current period.
+	c.mu.Lock()
+	currentID := contract.ID
+	for i := 0; i < 20; i++ {
+		var id types.FileContractID
+		id[0], id[1] = byte(i), 1
+		c.renewedFrom[currentID] = id
+		c.oldContracts[id] = modules.RenterContract{
+			ID:               id,
+			RenterPublicKey:  rpk,
+			HostPublicKey:    hpk,
+			DownloadSpending: types.SiacoinPrecision.Mul64(1000),
+		}
+		currentID = id
+	}
+	c.mu.Unlock()
+
+	estimate := func(depth int) (types.Currency, bool) {
+		t.Helper()
+		if err := setTestSettings(c, func(s *modules.ContractorSettings) { s.MaxRenewHistoryDepth = depth }); err != nil {
+			t.Fatal(err)
+		}
+		warnings := strings.Count(testLog(t, c), "exceeds")
+		cost, err := c.managedEstimateRenewFundingRequirements(contract, 0, renter.Allowance)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cost, strings.Count(testLog(t, c), "exceeds") > warnings
+	}
+
+	// Each contract followed adds to the estimate, and hitting the limit
+	// is logged.
+	short, warned := estimate(5)
+	if !warned {
+		t.Fatal("expected a warning with the depth of 5")
+	}
+	long, warned := estimate(10)
+	if !warned {
+		t.Fatal("expected a warning with the depth of 10")
+	}
+	if long.Cmp(short) <= 0 {
+		t.Fatalf("expected a deeper history to increase the estimate, got %v and %v", short, long)
+	}
+
+	// The whole history fits into a larger limit.
+	full, warned := estimate(25)
+	if warned {
+		t.Fatal("expected no warning with the depth of 25")
+	}
+	if full.Cmp(long) <= 0 {
+		t.Fatalf("expected the full history to increase the estimate, got %v and %v", long, full)
+	}
+	if deeper, _ := estimate(100); !deeper.Equals(full) {
+		t.Fatalf("expected the estimate to stop at the end of the history, got %v and %v", full, deeper)
+	}
+}
@@ -0,0 +1,72 @@
+package contractor
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/mike76-dev/sia-satellite/modules"
+
+	smodules "go.sia.tech/siad/modules"
+	"go.sia.tech/siad/types"
+)
+
+// TestRenewOrdering tests that the contracts closest to expiring are
+// renewed first if the funds don't cover all renewals.
+func TestRenewOrdering(t *testing.T) {
+	c, _ := newTestContractor(t)
+	setTestSynced(c)
+	c.wallet = &testWallet{unlocked: true}
+	newTestFundLocker(c)
+	hdb := newTestHostDB(c)
+	if err := setTestSettings(c, func(s *modules.ContractorSettings) { s.MinimumFunding = 0 }); err != nil {
+		t.Fatal(err)
+	}
+	rpk := testKey(1)
+	renter := testRenter(c, rpk)
+
+	// Four contracts within the renew window, in no particular order.
+	var ids []types.FileContractID
+	byEnd := make(map[types.FileContractID]types.BlockHeight)
+	for i, end := range []types.BlockHeight{90, 60, 80, 70} {
+		host := testHost(byte(10 + i), fmt.Sprintf("host%v.example.com:9982", i))
+		host.ContractPrice = types.SiacoinPrecision
+		hdb.addHost(host, 100)
+		contract := testContract(t, c, rpk, host.PublicKey, byte(i + 1), 0, end, types.SiacoinPrecision)
+		setTestUtility(t, c, contract.ID, smodules.ContractUtility{GoodForUpload: true, GoodForRenew: true})
+		ids = append(ids, contract.ID)
+		byEnd[contract.ID] = end
+	}
+
+	// The funds cover two renewals.
+	contract, _ := c.staticContracts.View(ids[0])
+	amount, err := c.managedEstimateRenewFundingRequirements(contract, 0, renter.Allowance)
+	if err != nil {
+		t.Fatal(err)
+	}
+	spending, err := c.PeriodSpending(rpk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	renter.Allowance.Funds = spending.TotalAllocated.Add(amount.Mul64(5).Div64(2))
+	c.mu.Lock()
+	c.renters[rpk.String()] = renter
+	c.mu.Unlock()
+
+	var renewed []types.BlockHeight
+	id := byte(100)
+	renew := func(r fileContractRenewal, _, endHeight types.BlockHeight) (types.Currency, modules.RenterContract, error) {
+		if !r.amount.Equals(amount) {
+			t.Fatalf("expected renewal amount %v, got %v", amount, r.amount)
+		}
+		id++
+		renewed = append(renewed, byEnd[r.id])
+		rc := testContract(t, c, rpk, r.hostPubKey, id, 0, endHeight, r.amount)
+		return r.amount, rc, nil
+	}
+	if _, err := c.managedRenewContracts(rpk, ids, renew); err != nil {
+		t.Fatal(err)
+	}
+	if len(renewed) != 2 || renewed[0] != 60 || renewed[1] != 70 {
+		t.Fatal("expected the contracts ending at 60 and 70 to be renewed, got", renewed)
+	}
+}
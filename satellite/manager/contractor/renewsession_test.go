@@ -0,0 +1,60 @@
+package contractor
+
+import (
+	"testing"
+	"time"
+
+	smodules "go.sia.tech/siad/modules"
+	"go.sia.tech/siad/types"
+)
+
+// TestRenewReleasesSession tests that a failing renewal releases the
+// session on its contract, and that the sessions don't leak over repeated
+// failures.
+func TestRenewReleasesSession(t *testing.T) {
+	c, _ := newTestContractor(t)
+	d := &testDialer{}
+	c.sessionDialer = d
+	host := testHost(10, "host.example.com:9982")
+	newTestHostDB(c, host)
+	rpk := testKey(1)
+	testRenter(c, rpk)
+	contract := testContract(t, c, rpk, host.PublicKey, 1, 0, 1000, types.SiacoinPrecision)
+	c.mu.Lock()
+	c.pubKeysToContractID[rpk.String() + host.PublicKey.String()] = contract.ID
+	c.mu.Unlock()
+
+	const attempts = 5
+	for i := 0; i < attempts; i++ {
+		if _, err := c.Session(rpk, host.PublicKey, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		// The host doesn't accept the duration, so the renewal fails.
+		var settings smodules.HostExternalSettings
+		settings.MaxDuration = 10
+		c.staticSettingsCache.put(host.PublicKey, settings, time.Millisecond)
+		_, _, err := c.managedRenewContract(fileContractRenewal{
+			id:           contract.ID,
+			amount:       types.SiacoinPrecision,
+			renterPubKey: rpk,
+			hostPubKey:   host.PublicKey,
+		}, 0, 2000)
+		if err == nil {
+			t.Fatal("expected the renewal to fail")
+		}
+
+		if !d.sessions[i].isClosed() {
+			t.Fatalf("session of failed renewal %v not released", i)
+		}
+		c.mu.RLock()
+		n := len(c.sessions)
+		c.mu.RUnlock()
+		if n != 0 {
+			t.Fatalf("%v sessions leaked after failed renewal %v", n, i)
+		}
+	}
+	if n := d.numDials(); n != attempts {
+		t.Fatalf("expected %v sessions, got %v", attempts, n)
+	}
+}
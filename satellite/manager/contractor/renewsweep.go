@@ -0,0 +1,283 @@
+package contractor
+
+import (
+	"sort"
+
+	"github.com/mike76-dev/sia-satellite/modules"
+
+	"gitlab.com/NebulousLabs/errors"
+
+	"go.sia.tech/siad/types"
+)
+
+// managedDueRenewals returns the contracts of the renter that are within
+// the renew window and can be renewed, together with the funds remaining
+// in the renter's allowance.
+func (c *Contractor) managedDueRenewals(renter modules.Renter, blockHeight types.BlockHeight) ([]fileContractRenewal, types.Currency, error) {
+	spending, err := c.PeriodSpending(renter.PublicKey)
+	if err != nil {
+		return nil, types.ZeroCurrency, err
+	}
+	fundsRemaining := c.managedRemainingFunds(renter, spending.TotalAllocated)
+
+	var renewals []fileContractRenewal
+	for _, rc := range c.staticContracts.ByRenter(renter.PublicKey) {
+		if blockHeight + renter.Allowance.RenewWindow < rc.EndHeight {
+			continue
+		}
+		c.mu.RLock()
+		_, renewed := c.renewedTo[rc.ID]
+		c.mu.RUnlock()
+		if renewed {
+			continue
+		}
+		cu, ok := c.managedContractUtility(rc.ID)
+		if !ok || !cu.GoodForRenew {
+			continue
+		}
+		host, exists, err := c.hdb.Host(rc.HostPublicKey)
+		if err != nil || !exists || host.Filtered {
+			continue
+		}
+		amount, err := c.managedEstimateRenewFundingRequirements(rc, blockHeight, renter.Allowance)
+		if err != nil {
+			c.log.Println("WARN: unable to estimate renew funding requirements:", rc.ID, err)
+			continue
+		}
+		renewals = append(renewals, fileContractRenewal{
+			id:           rc.ID,
+			amount:       amount,
+			renterPubKey: renter.PublicKey,
+			hostPubKey:   rc.HostPublicKey,
+		})
+	}
+
+	return renewals, fundsRemaining, nil
+}
+
+// RenewAllDue renews the due contracts of all renters, spending no more
+// than maxSpend in total. The renters are served in turns, one contract at
+// a time, so that a limited budget is spread fairly among them. Each
+// renter's allowance is respected as well. If the wallet is locked, the
+// sweep is retried shortly.
+func (c *Contractor) RenewAllDue(maxSpend types.Currency) ([]modules.RenewalSummary, error) {
+	if err := c.tg.Add(); err != nil {
+		return nil, err
+	}
+	defer c.tg.Done()
+
+	summaries, err := c.managedRenewAllDue(maxSpend, c.managedRenewContract)
+	if errors.Contains(err, errWalletLocked) {
+		c.managedScheduleWalletRetry("renewal sweep", 1, func() error {
+			_, err := c.managedRenewAllDue(maxSpend, c.managedRenewContract)
+			return err
+		})
+	}
+	return summaries, err
+}
+
+// managedRenewAllDue performs the renewal sweep of RenewAllDue. Each
+// contract is renewed by calling renew.
+func (c *Contractor) managedRenewAllDue(maxSpend types.Currency, renew func(fileContractRenewal, types.BlockHeight, types.BlockHeight) (types.Currency, modules.RenterContract, error)) ([]modules.RenewalSummary, error) {
+	// No contract renewal until the contractor is synced.
+	if !c.managedSynced() {
+		return nil, errors.New("contractor isn't synced yet")
+	}
+	err := c.managedCheckWallet()
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.RLock()
+	blockHeight := c.blockHeight
+	c.mu.RUnlock()
+
+	// Sort the renters to make the order of the sweep predictable.
+	renters := c.Renters()
+	sort.Slice(renters, func(i, j int) bool {
+		return renters[i].PublicKey.String() < renters[j].PublicKey.String()
+	})
+
+	summaries := make([]modules.RenewalSummary, len(renters))
+	queues := make([][]fileContractRenewal, len(renters))
+	remaining := make([]types.Currency, len(renters))
+	endHeights := make([]types.BlockHeight, len(renters))
+	for i, renter := range renters {
+		summaries[i] = modules.RenewalSummary{
+			PublicKey: renter.PublicKey,
+			Email:     renter.Email,
+		}
+		queues[i], remaining[i], err = c.managedDueRenewals(renter, blockHeight)
+		if err != nil {
+			return nil, err
+		}
+		summaries[i].Due = len(queues[i])
+
+		// Skip the paused renters and the renters whose allowance exceeds
+		// their storage quota.
+		if renter.Paused {
+			c.log.Println("Skipping renewals of paused renter", renter.PublicKey.String())
+			summaries[i].Skipped = len(queues[i])
+			queues[i] = nil
+		} else if err := c.managedCheckStorageQuota(renter); err != nil {
+			c.log.Println("Skipping renewals of renter", renter.PublicKey.String(), err)
+			summaries[i].Skipped = len(queues[i])
+			queues[i] = nil
+		} else if endHeights[i], err = c.managedRenewEndHeight(renter, blockHeight); err != nil {
+			c.log.Println("Skipping renewals of renter", renter.PublicKey.String(), err)
+			summaries[i].Skipped = len(queues[i])
+			queues[i] = nil
+		}
+	}
+
+	// Take one contract from each renter in turn until all are processed.
+	var spent types.Currency
+	for pending := true; pending; {
+		pending = false
+		for i, renter := range renters {
+			if len(queues[i]) == 0 {
+				continue
+			}
+			pending = true
+			renewal := queues[i][0]
+			queues[i] = queues[i][1:]
+
+			// Return here if an interrupt or kill signal has been sent.
+			select {
+			case <-c.tg.StopChan():
+				return summaries, errors.New("the manager was stopped")
+			default:
+			}
+
+			// Skip the renewal if the renter's allowance or the global
+			// budget can't cover it.
+			if renewal.amount.Cmp(remaining[i]) > 0 || spent.Add(renewal.amount).Cmp(maxSpend) > 0 {
+				summaries[i].Skipped++
+				continue
+			}
+
+			// Check the allowance again, accounting for the concurrent
+			// formations and renewals.
+			_, reserved, err := c.managedReserveSpending(renter, renewal.amount)
+			if err != nil {
+				return summaries, err
+			}
+			if !reserved {
+				summaries[i].Skipped++
+				continue
+			}
+
+			fundsSpent, newContract, err := renew(renewal, blockHeight, endHeights[i])
+			c.managedReleaseSpending(renter.PublicKey, renewal.amount)
+			spent = spent.Add(fundsSpent)
+			summaries[i].Spent = summaries[i].Spent.Add(fundsSpent)
+			if fundsSpent.Cmp(remaining[i]) < 0 {
+				remaining[i] = remaining[i].Sub(fundsSpent)
+			} else {
+				remaining[i] = types.ZeroCurrency
+			}
+			if err != nil {
+				c.log.Println("Error renewing a contract", renewal.id, err)
+				summaries[i].Failed++
+				continue
+			}
+			summaries[i].Renewed++
+			c.managedFinalizeRenewal(renter.Email, fundsSpent, newContract)
+		}
+	}
+
+	return summaries, nil
+}
+
+// ContractsDue returns the active contracts of all renters that end within
+// the given number of blocks, together with the estimated cost of renewing
+// them, sorted by the end height.
+func (c *Contractor) ContractsDue(within types.BlockHeight) ([]modules.DueContract, error) {
+	if err := c.tg.Add(); err != nil {
+		return nil, err
+	}
+	defer c.tg.Done()
+
+	c.mu.RLock()
+	blockHeight := c.blockHeight
+	renters := make(map[string]modules.Renter, len(c.renters))
+	for key, renter := range c.renters {
+		renters[key] = renter
+	}
+	c.mu.RUnlock()
+
+	var due []modules.DueContract
+	for _, rc := range c.staticContracts.ViewAll() {
+		if rc.EndHeight > blockHeight + within {
+			continue
+		}
+		c.mu.RLock()
+		_, renewed := c.renewedTo[rc.ID]
+		c.mu.RUnlock()
+		if renewed {
+			continue
+		}
+		renter, exists := renters[rc.RenterPublicKey.String()]
+		if !exists {
+			continue
+		}
+		dc := modules.DueContract{
+			ID:              rc.ID,
+			RenterPublicKey: rc.RenterPublicKey,
+			Email:           renter.Email,
+			HostPublicKey:   rc.HostPublicKey,
+			EndHeight:       rc.EndHeight,
+		}
+		if rc.EndHeight > renter.Allowance.RenewWindow {
+			dc.RenewHeight = rc.EndHeight - renter.Allowance.RenewWindow
+		}
+		cost, err := c.managedEstimateRenewFundingRequirements(rc, blockHeight, renter.Allowance)
+		if err != nil {
+			c.log.Println("WARN: unable to estimate renew funding requirements:", rc.ID, err)
+		} else {
+			dc.EstimatedCost = cost
+		}
+		due = append(due, dc)
+	}
+
+	sort.Slice(due, func(i, j int) bool {
+		return due[i].EndHeight < due[j].EndHeight
+	})
+
+	return due, nil
+}
+
+// RenewalFeeBreakdown returns the fees included in the renewal estimates
+// of the renter's active contracts.
+func (c *Contractor) RenewalFeeBreakdown(rpk types.SiaPublicKey) ([]modules.RenewalFeeBreakdown, error) {
+	if err := c.tg.Add(); err != nil {
+		return nil, err
+	}
+	defer c.tg.Done()
+
+	c.mu.RLock()
+	renter, exists := c.renters[rpk.String()]
+	blockHeight := c.blockHeight
+	c.mu.RUnlock()
+	if !exists {
+		return nil, ErrRenterNotFound
+	}
+
+	breakdown := []modules.RenewalFeeBreakdown{}
+	for _, rc := range c.staticContracts.ByRenter(rpk) {
+		c.mu.RLock()
+		_, renewed := c.renewedTo[rc.ID]
+		c.mu.RUnlock()
+		if renewed {
+			continue
+		}
+		fees, err := c.managedEstimateRenewFunding(rc, blockHeight, renter.Allowance)
+		if err != nil {
+			c.log.Println("WARN: unable to estimate renew funding requirements:", rc.ID, err)
+			continue
+		}
+		breakdown = append(breakdown, fees)
+	}
+
+	return breakdown, nil
+}
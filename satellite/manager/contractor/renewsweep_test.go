@@ -0,0 +1,139 @@
+package contractor
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/mike76-dev/sia-satellite/modules"
+
+	smodules "go.sia.tech/siad/modules"
+	"go.sia.tech/siad/types"
+)
+
+// TestRenewAllDueBudget tests that a budget covering only some of the due
+// renewals is spread among the renters in turns.
+func TestRenewAllDueBudget(t *testing.T) {
+	c, _ := newTestContractor(t)
+	setTestSynced(c)
+	w := &testWallet{unlocked: true}
+	c.wallet = w
+	newTestFundLocker(c)
+	hdb := newTestHostDB(c)
+
+	// Three renters with three due contracts each.
+	var renters []modules.Renter
+	var id byte
+	for i := 0; i < 3; i++ {
+		rpk := testKey(byte(1 + i))
+		renter := testRenter(c, rpk)
+		renter.Email = fmt.Sprintf("renter%v@example.com", i)
+		c.mu.Lock()
+		c.renters[rpk.String()] = renter
+		c.mu.Unlock()
+		renters = append(renters, renter)
+		for j := 0; j < 3; j++ {
+			id++
+			hpk := testKey(100 + id)
+			hdb.addHost(testHost(100 + id, fmt.Sprintf("host%v.example.com:9982", id)), 100)
+			contract := testContract(t, c, rpk, hpk, id, 0, 50, types.SiacoinPrecision.Mul64(10))
+			setTestUtility(t, c, contract.ID, smodules.ContractUtility{GoodForUpload: true, GoodForRenew: true})
+		}
+	}
+
+	// The budget covers four renewals.
+	due, _, err := c.managedDueRenewals(renters[0], 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(due) != 3 {
+		t.Fatalf("expected 3 due contracts, got %v", len(due))
+	}
+	amount := due[0].amount
+	budget := amount.Mul64(9).Div64(2)
+
+	var renewed []types.SiaPublicKey
+	renew := func(r fileContractRenewal, _, endHeight types.BlockHeight) (types.Currency, modules.RenterContract, error) {
+		id++
+		renewed = append(renewed, r.renterPubKey)
+		rc := testContract(t, c, r.renterPubKey, r.hostPubKey, id, 0, endHeight, r.amount)
+		return r.amount, rc, nil
+	}
+	summaries, err := c.managedRenewAllDue(budget, renew)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(renewed) != 4 {
+		t.Fatalf("expected 4 renewals, got %v", len(renewed))
+	}
+
+	// Every renter got a renewal before any renter got a second one.
+	seen := make(map[string]struct{})
+	for _, rpk := range renewed[:3] {
+		seen[rpk.String()] = struct{}{}
+	}
+	if len(seen) != 3 {
+		t.Fatal("a renter was renewed twice before another was renewed once")
+	}
+	var total, skipped int
+	spent := types.ZeroCurrency
+	for _, s := range summaries {
+		if s.Due != 3 {
+			t.Fatalf("expected 3 due contracts, got %v", s.Due)
+		}
+		if s.Renewed < 1 || s.Renewed > 2 {
+			t.Fatalf("unfair renewals: %+v", s)
+		}
+		if s.Renewed + s.Skipped != s.Due {
+			t.Fatalf("renewals not accounted for: %+v", s)
+		}
+		total += s.Renewed
+		skipped += s.Skipped
+		spent = spent.Add(s.Spent)
+	}
+	if total != 4 || skipped != 5 {
+		t.Fatalf("expected 4 renewed and 5 skipped, got %v and %v", total, skipped)
+	}
+	if spent.Cmp(budget) > 0 {
+		t.Fatalf("spent %v of the budget of %v", spent.HumanString(), budget.HumanString())
+	}
+}
+
+// TestContractsDue tests that only the contracts ending within the given
+// number of blocks are listed, sorted by the end height.
+func TestContractsDue(t *testing.T) {
+	c, _ := newTestContractor(t)
+	setTestSynced(c)
+	hdb := newTestHostDB(c)
+	rpk := testKey(1)
+	testRenter(c, rpk)
+	c.mu.Lock()
+	c.blockHeight = 100
+	c.mu.Unlock()
+
+	// Contracts ending every 50 blocks, in no particular order.
+	for i, end := range []types.BlockHeight{400, 150, 300, 200, 250} {
+		hpk := testKey(byte(10 + i))
+		hdb.addHost(testHost(byte(10 + i), fmt.Sprintf("host%v.example.com:9982", i)), 100)
+		testContract(t, c, rpk, hpk, byte(i + 1), 0, end, types.SiacoinPrecision)
+	}
+
+	due, err := c.ContractsDue(150)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []types.BlockHeight{150, 200, 250}
+	if len(due) != len(expected) {
+		t.Fatalf("expected %v contracts, got %v", len(expected), len(due))
+	}
+	for i, dc := range due {
+		if dc.EndHeight != expected[i] {
+			t.Fatalf("expected end height %v at position %v, got %v", expected[i], i, dc.EndHeight)
+		}
+		if dc.RenewHeight != dc.EndHeight - 100 {
+			t.Fatalf("wrong renew height %v for end height %v", dc.RenewHeight, dc.EndHeight)
+		}
+		if !dc.RenterPublicKey.Equals(rpk) || dc.Email != "renter@example.com" {
+			t.Fatal("wrong renter:", dc.RenterPublicKey, dc.Email)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package contractor
+
+import (
+	"fmt"
+
+	"github.com/mike76-dev/sia-satellite/modules"
+
+	"gitlab.com/NebulousLabs/errors"
+
+	smodules "go.sia.tech/siad/modules"
+	"go.sia.tech/siad/types"
+)
+
+// alertIDRenewWindow returns the ID of the alert registered when the
+// renter's renew window is not shorter than the period.
+func alertIDRenewWindow(rpk types.SiaPublicKey) smodules.AlertID {
+	return smodules.AlertID("contractor-renew-window-" + rpk.String())
+}
+
+// managedCheckRenewWindow returns an error if the renter's renew window is
+// not shorter than the period. The contracts formed with such an allowance
+// would enter the renew window right away. The respective alert is
+// registered or unregistered.
+func (c *Contractor) managedCheckRenewWindow(renter modules.Renter) error {
+	a := renter.Allowance
+	if a.Period == 0 || a.RenewWindow < a.Period {
+		c.staticAlerter.UnregisterAlert(alertIDRenewWindow(renter.PublicKey))
+		return nil
+	}
+	err := errors.AddContext(modules.ErrAllowanceWindowSize, fmt.Sprintf("renew window of %v blocks, period of %v blocks", a.RenewWindow, a.Period))
+	c.staticAlerter.RegisterAlert(alertIDRenewWindow(renter.PublicKey), AlertMSGRenewWindowTooLong, err.Error(), smodules.SeverityWarning)
+	return err
+}
@@ -0,0 +1,72 @@
+package contractor
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/mike76-dev/sia-satellite/modules"
+
+	"gitlab.com/NebulousLabs/errors"
+
+	smodules "go.sia.tech/siad/modules"
+	"go.sia.tech/siad/types"
+)
+
+// TestRenewWindowFormation tests that the formation is refused with a
+// descriptive error and an alert if the renew window is not shorter than
+// the period, and that the alert is cleared once the allowance is fixed.
+func TestRenewWindowFormation(t *testing.T) {
+	c, _ := newTestContractor(t)
+	setTestSynced(c)
+	c.wallet = &testWallet{unlocked: true, balance: types.SiacoinPrecision.Mul64(1e4)}
+	newTestFundLocker(c)
+	rpk := testKey(1)
+	renter := testRenter(c, rpk)
+	hdb := newTestHostDB(c)
+	for i := 0; i < 4; i++ {
+		hdb.addHost(testHost(byte(10 + i), fmt.Sprintf("host%v.example.com:9982", i)), 100)
+	}
+
+	var formed int
+	form := func(rpk types.SiaPublicKey, host smodules.HostDBEntry, funds types.Currency, endHeight types.BlockHeight) (types.Currency, modules.RenterContract, error) {
+		formed++
+		return funds, testContract(t, c, rpk, host.PublicKey, byte(formed), 0, endHeight, funds), nil
+	}
+	setRenewWindow := func(window types.BlockHeight) {
+		renter.Allowance.RenewWindow = window
+		c.mu.Lock()
+		c.renters[rpk.String()] = renter
+		c.mu.Unlock()
+	}
+
+	for _, window := range []types.BlockHeight{renter.Allowance.Period, renter.Allowance.Period + 1} {
+		setRenewWindow(window)
+		_, err := c.managedFormContracts(context.Background(), rpk, nil, form)
+		if !errors.Contains(err, modules.ErrAllowanceWindowSize) {
+			t.Fatalf("renew window %v: expected ErrAllowanceWindowSize, got %v", window, err)
+		}
+		if expected := fmt.Sprintf("renew window of %v blocks, period of %v blocks", window, renter.Allowance.Period); !strings.Contains(err.Error(), expected) {
+			t.Fatalf("expected the error to contain %q, got %q", expected, err)
+		}
+		if !hasAlert(c, AlertMSGRenewWindowTooLong) {
+			t.Fatal("expected the renew window alert")
+		}
+	}
+	if formed != 0 {
+		t.Fatalf("expected no formations, got %v", formed)
+	}
+
+	// A shorter renew window is accepted.
+	setRenewWindow(renter.Allowance.Period - 1)
+	if _, err := c.managedFormContracts(context.Background(), rpk, nil, form); err != nil {
+		t.Fatal(err)
+	}
+	if formed == 0 {
+		t.Fatal("expected the contracts to be formed")
+	}
+	if hasAlert(c, AlertMSGRenewWindowTooLong) {
+		t.Fatal("expected the renew window alert to be cleared")
+	}
+}
@@ -0,0 +1,61 @@
+package contractor
+
+import (
+	"github.com/mike76-dev/sia-satellite/modules"
+
+	"go.sia.tech/siad/types"
+)
+
+// Runway projects whether the renter's unspent allowance funds last until
+// the end of the current period. The spend rate is the average spending
+// per block since the start of the period.
+func (c *Contractor) Runway(rpk types.SiaPublicKey) (modules.RenterRunway, error) {
+	spending, err := c.PeriodSpending(rpk)
+	if err != nil {
+		return modules.RenterRunway{}, err
+	}
+
+	c.mu.RLock()
+	renter, exists := c.renters[rpk.String()]
+	blockHeight := c.blockHeight
+	c.mu.RUnlock()
+	if !exists {
+		return modules.RenterRunway{}, ErrRenterNotFound
+	}
+
+	funds := renter.Allowance.Funds
+	periodEnd := renter.CurrentPeriod + renter.Allowance.Period
+	r := modules.RenterRunway{
+		BlockHeight: blockHeight,
+		PeriodEnd:   periodEnd,
+		Unspent:     spending.Unspent,
+	}
+	if funds.Cmp(spending.Unspent) > 0 {
+		r.Spent = funds.Sub(spending.Unspent)
+	}
+	if periodEnd > blockHeight {
+		r.BlocksRemaining = periodEnd - blockHeight
+	}
+
+	// There is no spend rate until at least a block has passed and some
+	// funds have been spent.
+	if blockHeight <= renter.CurrentPeriod || r.Spent.IsZero() {
+		return r, nil
+	}
+	elapsed := uint64(blockHeight - renter.CurrentPeriod)
+	r.SpendRate = r.Spent.Div64(elapsed)
+	r.ProjectedSpending = r.Spent.Mul64(uint64(r.BlocksRemaining)).Div64(elapsed)
+	if r.ProjectedSpending.Cmp(r.Unspent) <= 0 || r.SpendRate.IsZero() {
+		return r, nil
+	}
+
+	// The funds run out before the period ends.
+	r.RunsOut = true
+	blocks, err := r.Unspent.Mul64(elapsed).Div(r.Spent).Uint64()
+	if err != nil {
+		blocks = uint64(r.BlocksRemaining)
+	}
+	r.ExhaustionHeight = blockHeight + types.BlockHeight(blocks)
+
+	return r, nil
+}
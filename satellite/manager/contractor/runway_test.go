@@ -0,0 +1,68 @@
+package contractor
+
+import (
+	"testing"
+
+	"github.com/mike76-dev/sia-satellite/modules"
+
+	"go.sia.tech/siad/types"
+)
+
+// TestRunway tests that the renter's spend rate is projected to the end
+// of the period, and that the height at which the funds run out is
+// estimated.
+func TestRunway(t *testing.T) {
+	c, _ := newTestContractor(t)
+	newTestHostDB(c)
+	rpk := testKey(1)
+	testRenter(c, rpk)
+	sc := types.SiacoinPrecision
+
+	runway := func(height types.BlockHeight, spent types.Currency) modules.RenterRunway {
+		t.Helper()
+		c.mu.Lock()
+		c.blockHeight = height
+		c.oldContracts[types.FileContractID{1}] = modules.RenterContract{
+			ID:              types.FileContractID{1},
+			RenterPublicKey: rpk,
+			HostPublicKey:   testKey(10),
+			UploadSpending:  spent,
+		}
+		c.mu.Unlock()
+		r, err := c.Runway(rpk)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return r
+	}
+
+	// Nothing is projected at the start of the period.
+	r := runway(0, sc.Mul64(300))
+	if !r.SpendRate.IsZero() || r.RunsOut || r.BlocksRemaining != 1000 {
+		t.Fatal("expected no projection at the start of the period, got", r)
+	}
+
+	// 300 SC spent in 200 blocks leave 700 SC for 800 blocks, which last
+	// for another 466 blocks.
+	r = runway(200, sc.Mul64(300))
+	if !r.Spent.Equals(sc.Mul64(300)) || !r.Unspent.Equals(sc.Mul64(700)) {
+		t.Fatalf("expected 300 SC spent and 700 SC unspent, got %v and %v", r.Spent, r.Unspent)
+	}
+	if !r.SpendRate.Equals(sc.Mul64(3).Div64(2)) || !r.ProjectedSpending.Equals(sc.Mul64(1200)) {
+		t.Fatalf("expected a rate of 1.5 SC and 1200 SC projected, got %v and %v", r.SpendRate, r.ProjectedSpending)
+	}
+	if !r.RunsOut || r.ExhaustionHeight != 666 || r.PeriodEnd != 1000 || r.BlocksRemaining != 800 {
+		t.Fatal("expected the funds to run out at 666, got", r)
+	}
+
+	// 100 SC spent in 500 blocks last until the end of the period.
+	r = runway(500, sc.Mul64(100))
+	if r.RunsOut || r.ExhaustionHeight != 0 || !r.ProjectedSpending.Equals(sc.Mul64(100)) {
+		t.Fatal("expected the funds to last, got", r)
+	}
+
+	// An unknown renter has no runway.
+	if _, err := c.Runway(testKey(2)); err == nil {
+		t.Fatal("expected an unknown renter to fail")
+	}
+}
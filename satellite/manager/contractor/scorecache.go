@@ -0,0 +1,65 @@
+package contractor
+
+import (
+	"sync"
+
+	smodules "go.sia.tech/siad/modules"
+)
+
+// scoreCache keeps the host score breakdowns computed during a maintenance
+// cycle, so that the phases of the cycle don't score the same host more
+// than once. Outside of a cycle nothing is cached.
+type scoreCache struct {
+	active bool
+	scores map[string]smodules.HostScoreBreakdown
+	mu     sync.Mutex
+}
+
+// newScoreCache returns an inactive scoreCache.
+func newScoreCache() *scoreCache {
+	return &scoreCache{
+		scores: make(map[string]smodules.HostScoreBreakdown),
+	}
+}
+
+// begin clears the cache and starts caching the scores.
+func (sc *scoreCache) begin() {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	sc.active = true
+	sc.scores = make(map[string]smodules.HostScoreBreakdown)
+}
+
+// end stops caching the scores and clears the cache.
+func (sc *scoreCache) end() {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	sc.active = false
+	sc.scores = make(map[string]smodules.HostScoreBreakdown)
+}
+
+// managedScoreBreakdown returns the score breakdown of the host, using the
+// cached value if the host has already been scored during the current
+// maintenance cycle.
+func (c *Contractor) managedScoreBreakdown(host smodules.HostDBEntry) (smodules.HostScoreBreakdown, error) {
+	key := host.PublicKey.String()
+	c.staticScoreCache.mu.Lock()
+	sb, exists := c.staticScoreCache.scores[key]
+	active := c.staticScoreCache.active
+	c.staticScoreCache.mu.Unlock()
+	if exists {
+		return sb, nil
+	}
+
+	sb, err := c.hdb.ScoreBreakdown(host)
+	if err != nil || !active {
+		return sb, err
+	}
+
+	c.staticScoreCache.mu.Lock()
+	if c.staticScoreCache.active {
+		c.staticScoreCache.scores[key] = sb
+	}
+	c.staticScoreCache.mu.Unlock()
+	return sb, nil
+}
@@ -0,0 +1,80 @@
+package contractor
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	smodules "go.sia.tech/siad/modules"
+	"go.sia.tech/siad/types"
+)
+
+// countingHostDB is a testHostDB that counts the score breakdowns per host.
+type countingHostDB struct {
+	*testHostDB
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+// ScoreBreakdown implements modules.HostDB.
+func (hdb *countingHostDB) ScoreBreakdown(host smodules.HostDBEntry) (smodules.HostScoreBreakdown, error) {
+	hdb.mu.Lock()
+	hdb.counts[host.PublicKey.String()]++
+	hdb.mu.Unlock()
+	return hdb.testHostDB.ScoreBreakdown(host)
+}
+
+// TestScoreCache tests that each host is scored at most once during a
+// maintenance run.
+func TestScoreCache(t *testing.T) {
+	c, _ := newTestContractor(t)
+	setTestSynced(c)
+	c.wallet = &testWallet{unlocked: true}
+	hdb := &countingHostDB{testHostDB: newTestHostDB(c), counts: make(map[string]int)}
+	c.hdb = hdb
+
+	// Two renters have GFU contracts with the same hosts, more than they
+	// need.
+	var id byte
+	for r := byte(1); r <= 2; r++ {
+		rpk := testKey(r)
+		renter := testRenter(c, rpk)
+		renter.Allowance.Hosts = 3
+		c.mu.Lock()
+		c.renters[rpk.String()] = renter
+		c.mu.Unlock()
+		for i := byte(0); i < 5; i++ {
+			host := testHost(10 + i, fmt.Sprintf("host%v.example.com:9982", i))
+			if r == 1 {
+				hdb.addHost(host, uint64(100 + i))
+			}
+			id++
+			contract := testContract(t, c, rpk, host.PublicKey, id, 0, 1000, types.SiacoinPrecision)
+			setTestUtility(t, c, contract.ID, smodules.ContractUtility{GoodForUpload: true, GoodForRenew: true})
+		}
+	}
+
+	c.mu.Lock()
+	c.gracePassed = true
+	c.mu.Unlock()
+	c.threadedContractMaintenance()
+	if len(hdb.counts) != 5 {
+		t.Fatalf("expected 5 hosts to be scored, got %v", len(hdb.counts))
+	}
+	for key, n := range hdb.counts {
+		if n != 1 {
+			t.Fatalf("host %v scored %v times", key, n)
+		}
+	}
+
+	// Outside of a maintenance run, nothing is cached.
+	host, _, _ := hdb.Host(testKey(10))
+	for i := 0; i < 2; i++ {
+		if _, err := c.managedScoreBreakdown(host); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := hdb.counts[host.PublicKey.String()]; n != 3 {
+		t.Fatalf("expected the host to be scored 3 times, got %v", n)
+	}
+}
@@ -3,8 +3,6 @@ package contractor
 import (
 	"sync"
 
-	"github.com/mike76-dev/sia-satellite/satellite/manager/proto"
-
 	"gitlab.com/NebulousLabs/errors"
 
 	"go.sia.tech/siad/modules"
@@ -43,13 +41,40 @@ type Session interface {
 	Settings() (modules.HostExternalSettings, error)
 }
 
+// rpcSession is the renter-host RPC loop that a hostSession runs on.
+type rpcSession interface {
+	Close() error
+	HostSettings() modules.HostExternalSettings
+	Settings() (modules.HostExternalSettings, error)
+}
+
+// sessionDialer opens the RPC sessions with the hosts.
+type sessionDialer interface {
+	Dial(host modules.HostDBEntry, rpk types.SiaPublicKey, id types.FileContractID, height types.BlockHeight, cancel <-chan struct{}) (rpcSession, error)
+}
+
+// contractSetDialer is the default sessionDialer. It opens the sessions
+// through the contract set of the contractor.
+type contractSetDialer struct {
+	c *Contractor
+}
+
+// Dial implements sessionDialer.
+func (d contractSetDialer) Dial(host modules.HostDBEntry, rpk types.SiaPublicKey, id types.FileContractID, height types.BlockHeight, cancel <-chan struct{}) (rpcSession, error) {
+	s, err := d.c.staticContracts.NewSession(host, rpk, id, height, d.c.hdb, d.c.log, cancel)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
 // A hostSession modifies a Contract via the renter-host RPC loop. It
 // implements the Session interface. hostSessions are safe for use by multiple
 // goroutines.
 type hostSession struct {
 	clients    int // Safe to Close when 0.
 	contractor *Contractor
-	session    *proto.Session
+	session    rpcSession
 	endHeight  types.BlockHeight
 	id         types.FileContractID
 	invalid    bool // True if invalidate has been called.
@@ -145,6 +170,8 @@ func (c *Contractor) Session(rpk, hpk types.SiaPublicKey, cancel <-chan struct{}
 		return nil, errors.New("contract not found in the renter contract set")
 	}
 	host, haveHost, err := c.hdb.Host(contract.HostPublicKey)
+	storageCeiling, _ := c.managedPriceCeilings()
+	downloadCeiling, uploadCeiling := bandwidthCeilings(storageCeiling)
 	if err != nil {
 		return nil, errors.AddContext(err, "error getting host from hostdb:")
 	} else if height > contract.EndHeight {
@@ -153,14 +180,16 @@ func (c *Contractor) Session(rpk, hpk types.SiaPublicKey, cancel <-chan struct{}
 		return nil, errHostNotFound
 	} else if host.Filtered {
 		return nil, errHostBlocked
-	} else if host.StoragePrice.Cmp(maxStoragePrice) > 0 {
+	} else if host.StoragePrice.Cmp(storageCeiling) > 0 {
 		return nil, errTooExpensive
-	} else if host.UploadBandwidthPrice.Cmp(maxUploadPrice) > 0 {
+	} else if host.UploadBandwidthPrice.Cmp(uploadCeiling) > 0 {
+		return nil, errTooExpensive
+	} else if host.DownloadBandwidthPrice.Cmp(downloadCeiling) > 0 {
 		return nil, errTooExpensive
 	}
 
 	// Create the session.
-	s, err := c.staticContracts.NewSession(host, rpk, id, height, c.hdb, c.log, cancel)
+	s, err := c.sessionDialer.Dial(host, rpk, id, height, cancel)
 	if modules.IsContractNotRecognizedErr(err) {
 		err = errors.Compose(err, c.MarkContractBad(id))
 	}
@@ -178,8 +207,25 @@ func (c *Contractor) Session(rpk, hpk types.SiaPublicKey, cancel <-chan struct{}
 		netAddress: host.NetAddress,
 	}
 	c.mu.Lock()
+	if c.renewing[id] {
+		// The renewal started while the session was being created.
+		c.mu.Unlock()
+		s.Close()
+		return nil, ErrContractRenewing
+	}
 	c.sessions[contract.ID] = hs
 	c.mu.Unlock()
 
 	return hs, nil
 }
+
+// managedInvalidateSession invalidates the cached session on the contract,
+// if there is one.
+func (c *Contractor) managedInvalidateSession(id types.FileContractID) {
+	c.mu.RLock()
+	hs, haveSession := c.sessions[id]
+	c.mu.RUnlock()
+	if haveSession {
+		hs.invalidate()
+	}
+}
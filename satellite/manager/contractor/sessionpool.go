@@ -0,0 +1,183 @@
+package contractor
+
+import (
+	"sync"
+	"time"
+
+	"gitlab.com/NebulousLabs/errors"
+
+	smodules "go.sia.tech/siad/modules"
+	"go.sia.tech/siad/types"
+)
+
+// sessionPool keeps one session per host alive for SessionPoolTTL, so that
+// the settings fetches during a maintenance run can share it instead of
+// reopening a session for every renewal. The pool holds a client reference
+// to each pooled session, which is released when the TTL expires.
+type sessionPool struct {
+	sessions map[string]*pooledSession
+	mu       sync.Mutex
+}
+
+// pooledSession is a session in the pool together with its expiry timer.
+type pooledSession struct {
+	hs    *hostSession
+	timer *time.Timer
+}
+
+// newSessionPool returns an empty session pool.
+func newSessionPool() *sessionPool {
+	return &sessionPool{
+		sessions: make(map[string]*pooledSession),
+	}
+}
+
+// get returns the live pooled session with the host, unless it belongs to
+// the excluded contract. The caller must Close the returned session.
+func (sp *sessionPool) get(hpk types.SiaPublicKey, exclude types.FileContractID) *hostSession {
+	sp.mu.Lock()
+	ps, exists := sp.sessions[hpk.String()]
+	sp.mu.Unlock()
+	if !exists {
+		return nil
+	}
+
+	ps.hs.mu.Lock()
+	invalid := ps.hs.invalid
+	usable := !invalid && ps.hs.id != exclude
+	if usable {
+		ps.hs.clients++
+	}
+	ps.hs.mu.Unlock()
+	if invalid {
+		// The session was invalidated, e.g. because its contract is being
+		// renewed. Drop it from the pool.
+		sp.remove(hpk, ps.hs)
+	}
+	if !usable {
+		return nil
+	}
+	return ps.hs
+}
+
+// put adds the session to the pool, replacing the one with the same host.
+func (sp *sessionPool) put(hpk types.SiaPublicKey, hs *hostSession) {
+	hs.mu.Lock()
+	hs.clients++
+	hs.mu.Unlock()
+
+	sp.mu.Lock()
+	old, exists := sp.sessions[hpk.String()]
+	if exists {
+		old.timer.Stop()
+	}
+	ps := &pooledSession{hs: hs}
+	ps.timer = time.AfterFunc(SessionPoolTTL, func() {
+		sp.remove(hpk, hs)
+	})
+	sp.sessions[hpk.String()] = ps
+	sp.mu.Unlock()
+
+	if exists && old.hs != hs {
+		old.hs.Close()
+	}
+}
+
+// remove drops the session from the pool and releases the pool's reference
+// to it.
+func (sp *sessionPool) remove(hpk types.SiaPublicKey, hs *hostSession) {
+	sp.mu.Lock()
+	ps, exists := sp.sessions[hpk.String()]
+	if !exists || ps.hs != hs {
+		sp.mu.Unlock()
+		return
+	}
+	ps.timer.Stop()
+	delete(sp.sessions, hpk.String())
+	sp.mu.Unlock()
+
+	hs.Close()
+}
+
+// close releases all pooled sessions.
+func (sp *sessionPool) close() {
+	sp.mu.Lock()
+	sessions := sp.sessions
+	sp.sessions = make(map[string]*pooledSession)
+	sp.mu.Unlock()
+
+	for _, ps := range sessions {
+		ps.timer.Stop()
+		ps.hs.Close()
+	}
+}
+
+// managedPoolableContract returns another active contract with the host,
+// a session on which can be pooled.
+func (c *Contractor) managedPoolableContract(id types.FileContractID, hpk types.SiaPublicKey) (types.SiaPublicKey, bool) {
+	contracts := c.staticContracts.ViewAll()
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, contract := range contracts {
+		if contract.ID == id || contract.HostPublicKey.String() != hpk.String() {
+			continue
+		}
+		if c.renewing[contract.ID] || c.blockHeight > contract.EndHeight {
+			continue
+		}
+		if c.pubKeysToContractID[contract.RenterPublicKey.String() + hpk.String()] != contract.ID {
+			continue
+		}
+		return contract.RenterPublicKey, true
+	}
+	return types.SiaPublicKey{}, false
+}
+
+// managedFetchHostSettings fetches the host settings for the renewal of the
+// contract. A pooled session with the host is used if there is one. The
+// session on the contract itself can't be pooled, because the contract is
+// about to be renewed, so it is only used if the host has no other active
+// contracts.
+func (c *Contractor) managedFetchHostSettings(id types.FileContractID, rpk, hpk types.SiaPublicKey) (smodules.HostExternalSettings, error) {
+	// Try the pooled session first.
+	if hs := c.staticSessionPool.get(hpk, id); hs != nil {
+		settings, err := hs.Settings()
+		if err == nil {
+			hs.Close()
+			return settings, nil
+		}
+		c.staticSessionPool.remove(hpk, hs)
+		hs.invalidate()
+		hs.Close()
+	}
+
+	// Open a new session using another contract with the host and add it
+	// to the pool.
+	if other, ok := c.managedPoolableContract(id, hpk); ok {
+		s, err := c.Session(other, hpk, c.tg.StopChan())
+		if err == nil {
+			hs := s.(*hostSession)
+			settings, err := hs.Settings()
+			if err == nil {
+				c.staticSessionPool.put(hpk, hs)
+				hs.Close()
+				return settings, nil
+			}
+			hs.invalidate()
+			hs.Close()
+		}
+	}
+
+	// Use the Settings RPC with the host and then invalidate the session.
+	s, err := c.Session(rpk, hpk, c.tg.StopChan())
+	if err != nil {
+		return smodules.HostExternalSettings{}, errors.AddContext(err, "Unable to establish session with host")
+	}
+	hs := s.(*hostSession)
+	settings, err := hs.Settings()
+	hs.invalidate()
+	if err != nil {
+		return smodules.HostExternalSettings{}, errors.AddContext(err, "Unable to get host settings")
+	}
+	return settings, nil
+}
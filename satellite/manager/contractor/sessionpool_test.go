@@ -0,0 +1,156 @@
+package contractor
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	smodules "go.sia.tech/siad/modules"
+	"go.sia.tech/siad/types"
+)
+
+// testSession is an RPC session that counts its Settings calls.
+type testSession struct {
+	mu       sync.Mutex
+	settings int
+	closed   bool
+	fail     bool
+}
+
+// Close implements rpcSession.
+func (s *testSession) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	return nil
+}
+
+// HostSettings implements rpcSession.
+func (s *testSession) HostSettings() smodules.HostExternalSettings {
+	return smodules.HostExternalSettings{}
+}
+
+// Settings implements rpcSession.
+func (s *testSession) Settings() (smodules.HostExternalSettings, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.fail {
+		return smodules.HostExternalSettings{}, errors.New("settings RPC failed")
+	}
+	s.settings++
+	return smodules.HostExternalSettings{AcceptingContracts: true}, nil
+}
+
+// isClosed returns true if the session was closed.
+func (s *testSession) isClosed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closed
+}
+
+// testDialer is a sessionDialer that records the opened sessions.
+type testDialer struct {
+	mu       sync.Mutex
+	sessions []*testSession
+}
+
+// Dial implements sessionDialer.
+func (d *testDialer) Dial(smodules.HostDBEntry, types.SiaPublicKey, types.FileContractID, types.BlockHeight, <-chan struct{}) (rpcSession, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	s := &testSession{}
+	d.sessions = append(d.sessions, s)
+	return s, nil
+}
+
+// numDials returns the number of sessions opened.
+func (d *testDialer) numDials() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.sessions)
+}
+
+// TestSessionPool tests that the settings fetches for the renewals with one
+// host reuse a single pooled session, and that the pooled session is
+// released on an error and on the TTL expiry.
+func TestSessionPool(t *testing.T) {
+	c, _ := newTestContractor(t)
+	d := &testDialer{}
+	c.sessionDialer = d
+	host := testHost(10, "host.example.com:9982")
+	newTestHostDB(c, host)
+
+	// Four renters have a contract with the host.
+	var contracts []types.FileContractID
+	for i := byte(1); i <= 4; i++ {
+		rpk := testKey(i)
+		testRenter(c, rpk)
+		contract := testContract(t, c, rpk, host.PublicKey, i, 0, 1000, types.SiacoinPrecision)
+		c.mu.Lock()
+		c.pubKeysToContractID[rpk.String() + host.PublicKey.String()] = contract.ID
+		c.mu.Unlock()
+		contracts = append(contracts, contract.ID)
+	}
+	fetch := func(i int) error {
+		_, err := c.managedFetchHostSettings(contracts[i], testKey(byte(i + 1)), host.PublicKey)
+		return err
+	}
+
+	// The first fetch pools a session on another contract with the host.
+	// The renewals of the remaining contracts reuse it.
+	if err := fetch(0); err != nil {
+		t.Fatal(err)
+	}
+	c.staticSessionPool.mu.Lock()
+	ps := c.staticSessionPool.sessions[host.PublicKey.String()]
+	c.staticSessionPool.mu.Unlock()
+	if ps == nil {
+		t.Fatal("no session pooled")
+	}
+	for i := 1; i < len(contracts); i++ {
+		if contracts[i] == ps.hs.id {
+			continue
+		}
+		if err := fetch(i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := d.numDials(); n != 1 {
+		t.Fatalf("expected 1 session, got %v", n)
+	}
+	pooled := d.sessions[0]
+	if pooled.settings != 3 || pooled.isClosed() {
+		t.Fatalf("expected 3 settings fetches on the live pooled session, got %v", pooled.settings)
+	}
+
+	// A failing pooled session is invalidated and replaced.
+	pooled.mu.Lock()
+	pooled.fail = true
+	pooled.mu.Unlock()
+	if err := fetch(0); err != nil {
+		t.Fatal(err)
+	}
+	if !pooled.isClosed() {
+		t.Fatal("the failing session wasn't closed")
+	}
+	if n := d.numDials(); n != 2 {
+		t.Fatalf("expected a new session, got %v sessions", n)
+	}
+
+	// The pooled session is released once the TTL expires.
+	defer func(ttl time.Duration) { SessionPoolTTL = ttl }(SessionPoolTTL)
+	SessionPoolTTL = 50 * time.Millisecond
+	c.staticSessionPool.close()
+	if err := fetch(1); err != nil {
+		t.Fatal(err)
+	}
+	latest := d.sessions[len(d.sessions) - 1]
+	time.Sleep(200 * time.Millisecond)
+	if !latest.isClosed() {
+		t.Fatal("the pooled session wasn't released after the TTL")
+	}
+	if hs := c.staticSessionPool.get(host.PublicKey, types.FileContractID{}); hs != nil {
+		t.Fatal("the expired session is still pooled")
+	}
+}
@@ -0,0 +1,96 @@
+package contractor
+
+import (
+	"sync"
+	"time"
+
+	smodules "go.sia.tech/siad/modules"
+	"go.sia.tech/siad/types"
+)
+
+// cachedSettings is an entry of the settingsCache.
+type cachedSettings struct {
+	settings smodules.HostExternalSettings
+	latency  time.Duration
+	expires  time.Time
+}
+
+// settingsCache keeps the recently fetched host settings, so that the
+// renewals of several contracts with the same host don't make a Settings
+// RPC each.
+type settingsCache struct {
+	entries map[string]cachedSettings
+	mu      sync.Mutex
+}
+
+// newSettingsCache returns an empty settingsCache.
+func newSettingsCache() *settingsCache {
+	return &settingsCache{
+		entries: make(map[string]cachedSettings),
+	}
+}
+
+// entry returns the cached entry of the host if it hasn't expired yet.
+func (sc *settingsCache) entry(hpk types.SiaPublicKey) (cachedSettings, bool) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	entry, exists := sc.entries[hpk.String()]
+	if !exists {
+		return cachedSettings{}, false
+	}
+	if time.Now().After(entry.expires) {
+		delete(sc.entries, hpk.String())
+		return cachedSettings{}, false
+	}
+	return entry, true
+}
+
+// get returns the cached settings of the host if they haven't expired yet.
+func (sc *settingsCache) get(hpk types.SiaPublicKey) (smodules.HostExternalSettings, bool) {
+	entry, ok := sc.entry(hpk)
+	return entry.settings, ok
+}
+
+// latency returns the time it took to fetch the cached settings of the
+// host if they haven't expired yet.
+func (sc *settingsCache) latency(hpk types.SiaPublicKey) (time.Duration, bool) {
+	entry, ok := sc.entry(hpk)
+	return entry.latency, ok
+}
+
+// put caches the settings of the host for HostSettingsCacheTTL together
+// with the time it took to fetch them.
+func (sc *settingsCache) put(hpk types.SiaPublicKey, settings smodules.HostExternalSettings, latency time.Duration) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	sc.entries[hpk.String()] = cachedSettings{
+		settings: settings,
+		latency:  latency,
+		expires:  time.Now().Add(HostSettingsCacheTTL),
+	}
+}
+
+// invalidate removes the cached settings of the host.
+func (sc *settingsCache) invalidate(hpk types.SiaPublicKey) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	delete(sc.entries, hpk.String())
+}
+
+// managedHostSettings returns the host settings for the renewal of the
+// contract. The settings fetched within HostSettingsCacheTTL are reused;
+// otherwise they are fetched from the host. A failed fetch invalidates the
+// cached settings.
+func (c *Contractor) managedHostSettings(id types.FileContractID, rpk, hpk types.SiaPublicKey) (smodules.HostExternalSettings, error) {
+	if settings, ok := c.staticSettingsCache.get(hpk); ok {
+		return settings, nil
+	}
+	start := time.Now()
+	settings, err := c.managedFetchHostSettings(id, rpk, hpk)
+	if err != nil {
+		c.staticSettingsCache.invalidate(hpk)
+		return smodules.HostExternalSettings{}, err
+	}
+	c.staticSettingsCache.put(hpk, settings, time.Since(start))
+	return settings, nil
+}
@@ -0,0 +1,78 @@
+package contractor
+
+import (
+	"testing"
+	"time"
+
+	"go.sia.tech/siad/types"
+)
+
+// numSettings returns the number of Settings RPCs made over the sessions
+// of the dialer.
+func (d *testDialer) numSettings() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var n int
+	for _, s := range d.sessions {
+		s.mu.Lock()
+		n += s.settings
+		s.mu.Unlock()
+	}
+	return n
+}
+
+// TestSettingsCache tests that two renewals against one host within the
+// TTL make a single Settings RPC, and that the expired settings are
+// fetched again.
+func TestSettingsCache(t *testing.T) {
+	c, _ := newTestContractor(t)
+	d := &testDialer{}
+	c.sessionDialer = d
+	host := testHost(10, "host.example.com:9982")
+	newTestHostDB(c, host)
+
+	var contracts []types.FileContractID
+	for i := byte(1); i <= 3; i++ {
+		rpk := testKey(i)
+		testRenter(c, rpk)
+		contract := testContract(t, c, rpk, host.PublicKey, i, 0, 1000, types.SiacoinPrecision)
+		c.mu.Lock()
+		c.pubKeysToContractID[rpk.String() + host.PublicKey.String()] = contract.ID
+		c.mu.Unlock()
+		contracts = append(contracts, contract.ID)
+	}
+	fetch := func(i int) {
+		t.Helper()
+		settings, err := c.managedHostSettings(contracts[i], testKey(byte(i + 1)), host.PublicKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !settings.AcceptingContracts {
+			t.Fatal("wrong settings returned")
+		}
+	}
+
+	fetch(0)
+	fetch(1)
+	if n := d.numSettings(); n != 1 {
+		t.Fatalf("expected 1 Settings RPC, got %v", n)
+	}
+
+	// The expired settings are fetched again.
+	c.staticSettingsCache.mu.Lock()
+	entry := c.staticSettingsCache.entries[host.PublicKey.String()]
+	entry.expires = time.Now().Add(-time.Second)
+	c.staticSettingsCache.entries[host.PublicKey.String()] = entry
+	c.staticSettingsCache.mu.Unlock()
+	fetch(2)
+	if n := d.numSettings(); n != 2 {
+		t.Fatalf("expected 2 Settings RPCs after the expiry, got %v", n)
+	}
+
+	// The invalidated settings are fetched again.
+	c.staticSettingsCache.invalidate(host.PublicKey)
+	fetch(0)
+	if n := d.numSettings(); n != 3 {
+		t.Fatalf("expected 3 Settings RPCs after the invalidation, got %v", n)
+	}
+}
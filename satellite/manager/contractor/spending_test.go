@@ -0,0 +1,43 @@
+package contractor
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/mike76-dev/sia-satellite/modules"
+
+	"go.sia.tech/siad/types"
+)
+
+// TestChargeFormation tests that the spending breakdown sums to the funds
+// locked, and that the failed formations are marked as such.
+func TestChargeFormation(t *testing.T) {
+	c, _ := newTestContractor(t)
+	fl := newTestFundLocker(c)
+	renter := testRenter(c, testKey(1))
+	sc := types.SiacoinPrecision
+
+	var formed modules.RenterContract
+	formed.ID[0] = 1
+	spending := []modules.HostSpending{
+		c.managedChargeFormation(renter, testHost(2, "host2.example.com:9982"), sc.Mul64(40), formed, nil),
+		c.managedChargeFormation(renter, testHost(3, "host3.example.com:9982"), sc.Mul64(15), modules.RenterContract{}, errors.New("already has a contract")),
+	}
+
+	var total types.Currency
+	for _, hs := range spending {
+		total = total.Add(hs.Spent)
+	}
+	spent, _ := total.Div(sc).Float64()
+	if math.Abs(spent - fl.lockedFunds(renter.Email)) > 1e-9 {
+		t.Fatalf("spending of %v SC, but %v SC locked", spent, fl.lockedFunds(renter.Email))
+	}
+
+	if !spending[0].Formed || spending[0].ContractID != formed.ID || spending[0].Error != "" {
+		t.Fatal("formed contract not reported as formed:", spending[0])
+	}
+	if spending[1].Formed || spending[1].ContractID != (types.FileContractID{}) || spending[1].Error == "" {
+		t.Fatal("failed formation not reported as failed:", spending[1])
+	}
+}
@@ -0,0 +1,126 @@
+package contractor
+
+import (
+	"sync"
+
+	"github.com/mike76-dev/sia-satellite/modules"
+
+	"go.sia.tech/siad/types"
+)
+
+// spendReserve keeps track of the allowance funds that are set aside for
+// the ongoing contract formations and renewals of each renter. The period
+// spending only reflects a contract once it has been added to the contract
+// set, so the concurrent formations and renewals need to check against the
+// reserve to not overspend the allowance. The funds spent by the failed
+// formations never show up in the period spending, so they are kept here
+// for the rest of the period.
+type spendReserve struct {
+	reserved map[string]types.Currency
+	failed   map[string]failedSpending
+	mu       sync.Mutex
+}
+
+// failedSpending is the amount spent by the failed formations of a renter
+// in the given period.
+type failedSpending struct {
+	period types.BlockHeight
+	amount types.Currency
+}
+
+// newSpendReserve returns an empty spendReserve.
+func newSpendReserve() *spendReserve {
+	return &spendReserve{
+		reserved: make(map[string]types.Currency),
+		failed:   make(map[string]failedSpending),
+	}
+}
+
+// failedAmount returns the funds spent by the renter's failed formations
+// in the current period. The amounts from the previous periods are
+// dropped. The caller must hold the lock.
+func (sr *spendReserve) failedAmount(renter modules.Renter) types.Currency {
+	key := renter.PublicKey.String()
+	fs, exists := sr.failed[key]
+	if !exists {
+		return types.ZeroCurrency
+	}
+	if fs.period != renter.CurrentPeriod {
+		delete(sr.failed, key)
+		return types.ZeroCurrency
+	}
+	return fs.amount
+}
+
+// release returns the given amount of the reserved funds. The caller must
+// hold the lock. It returns false if more funds were released than
+// reserved.
+func (sr *spendReserve) release(key string, amount types.Currency) bool {
+	reserved := sr.reserved[key]
+	ok := reserved.Cmp(amount) >= 0
+	if !ok || reserved.Equals(amount) {
+		delete(sr.reserved, key)
+		return ok
+	}
+	sr.reserved[key] = reserved.Sub(amount)
+	return true
+}
+
+// managedReserveSpending re-reads the renter's period spending and sets
+// aside the given amount if it fits into the remaining allowance together
+// with the amounts reserved by the other formations and renewals. It
+// returns the funds that were available and whether the amount could be
+// reserved. A successful reservation must be released with
+// managedReleaseSpending once the contract is in the contract set or the
+// attempt has failed.
+func (c *Contractor) managedReserveSpending(renter modules.Renter, amount types.Currency) (types.Currency, bool, error) {
+	key := renter.PublicKey.String()
+	c.staticSpendReserve.mu.Lock()
+	defer c.staticSpendReserve.mu.Unlock()
+
+	spending, err := c.PeriodSpending(renter.PublicKey)
+	if err != nil {
+		return types.ZeroCurrency, false, err
+	}
+	available := c.managedRemainingFunds(renter, spending.TotalAllocated)
+	reserved := c.staticSpendReserve.reserved[key]
+	unavailable := reserved.Add(c.staticSpendReserve.failedAmount(renter))
+	if available.Cmp(unavailable) <= 0 {
+		available = types.ZeroCurrency
+	} else {
+		available = available.Sub(unavailable)
+	}
+	if available.Cmp(amount) < 0 {
+		return available, false, nil
+	}
+
+	c.staticSpendReserve.reserved[key] = reserved.Add(amount)
+	return available, true, nil
+}
+
+// managedReleaseSpending returns the given amount of the reserved funds to
+// the renter's allowance.
+func (c *Contractor) managedReleaseSpending(rpk types.SiaPublicKey, amount types.Currency) {
+	c.staticSpendReserve.mu.Lock()
+	defer c.staticSpendReserve.mu.Unlock()
+	if !c.staticSpendReserve.release(rpk.String(), amount) {
+		c.log.Critical("releasing more allowance funds than reserved")
+	}
+}
+
+// managedReleaseFailedSpending returns the given amount of the reserved
+// funds to the renter's allowance after a failed formation, except for the
+// funds the formation has spent anyway. Those stay unavailable for the rest
+// of the period.
+func (c *Contractor) managedReleaseFailedSpending(renter modules.Renter, amount, spent types.Currency) {
+	key := renter.PublicKey.String()
+	c.staticSpendReserve.mu.Lock()
+	defer c.staticSpendReserve.mu.Unlock()
+	if !c.staticSpendReserve.release(key, amount) {
+		c.log.Critical("releasing more allowance funds than reserved")
+	}
+	c.staticSpendReserve.failed[key] = failedSpending{
+		period: renter.CurrentPeriod,
+		amount: c.staticSpendReserve.failedAmount(renter).Add(spent),
+	}
+}
@@ -0,0 +1,88 @@
+package contractor
+
+import (
+	"sync"
+	"testing"
+
+	"go.sia.tech/siad/types"
+)
+
+// TestSpendReserveConcurrent tests that the concurrent formations and
+// renewals of a renter don't allocate more than the allowance.
+func TestSpendReserveConcurrent(t *testing.T) {
+	c, _ := newTestContractor(t)
+	newTestHostDB(c)
+	rpk := testKey(1)
+	renter := testRenter(c, rpk)
+	amount := types.SiacoinPrecision.Mul64(100)
+
+	// Twice as many formations and renewals are attempted as the
+	// allowance can pay for.
+	const attempts = 20
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var succeeded int
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, reserved, err := c.managedReserveSpending(renter, amount)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if !reserved {
+				return
+			}
+			// A formation adds a new contract, and a renewal a contract
+			// replacing an old one, which is paid for the same way.
+			testContract(t, c, rpk, testKey(byte(100 + i)), byte(i + 1), 0, 1000, amount)
+			c.managedReleaseSpending(rpk, amount)
+			mu.Lock()
+			succeeded++
+			mu.Unlock()
+		}(i)
+	}
+	wg.Wait()
+
+	spending, err := c.PeriodSpending(rpk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if spending.TotalAllocated.Cmp(renter.Allowance.Funds) > 0 {
+		t.Fatalf("allocated %v of %v", spending.TotalAllocated.HumanString(), renter.Allowance.Funds.HumanString())
+	}
+	if succeeded != 10 {
+		t.Fatalf("expected 10 reservations, got %v", succeeded)
+	}
+}
+
+// TestSpendReserveFailedFormation tests that the funds spent by a failed
+// formation stay unavailable until the next period.
+func TestSpendReserveFailedFormation(t *testing.T) {
+	c, _ := newTestContractor(t)
+	newTestHostDB(c)
+	rpk := testKey(1)
+	renter := testRenter(c, rpk)
+	funds := renter.Allowance.Funds
+	spent := types.SiacoinPrecision.Mul64(30)
+
+	if _, reserved, err := c.managedReserveSpending(renter, funds); err != nil || !reserved {
+		t.Fatal("couldn't reserve the allowance", err)
+	}
+	c.managedReleaseFailedSpending(renter, funds, spent)
+
+	available, reserved, err := c.managedReserveSpending(renter, funds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reserved || !available.Equals(funds.Sub(spent)) {
+		t.Fatalf("expected %v available, got %v", funds.Sub(spent).HumanString(), available.HumanString())
+	}
+
+	// The spent funds are available again in the next period.
+	renter.CurrentPeriod += renter.Allowance.Period
+	if _, reserved, err := c.managedReserveSpending(renter, funds); err != nil || !reserved {
+		t.Fatal("couldn't reserve the allowance in the next period", err)
+	}
+}
@@ -0,0 +1,65 @@
+package contractor
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	smodules "go.sia.tech/siad/modules"
+)
+
+// TestSyncGrace tests that a flapping sync status during the startup grace
+// delays the contract maintenance until the consensus has stayed synced
+// for long enough.
+func TestSyncGrace(t *testing.T) {
+	c, _ := newTestContractor(t)
+
+	// Hold the maintenance lock for the rest of the test, so that the
+	// maintenance past the grace check returns without doing any work.
+	c.maintenanceLock.Lock()
+
+	change := 0
+	process := func(synced bool) {
+		change++
+		c.ProcessConsensusChange(smodules.ConsensusChange{
+			ID:     smodules.ConsensusChangeID{byte(change)},
+			Synced: synced,
+		})
+	}
+
+	// The streak is restarted by every change that isn't synced.
+	for _, synced := range []bool{true, true, false, true, true} {
+		process(synced)
+		if c.managedSyncGracePassed() {
+			t.Fatal("expected the grace not to pass after change", change)
+		}
+	}
+	c.threadedContractMaintenance()
+	log := testLog(t, c)
+	if !strings.Contains(log, "Skipping contract maintenance since consensus hasn't been synced for long enough") {
+		t.Fatal("expected the maintenance to be skipped")
+	}
+	if strings.Contains(log, "maintenance lock could not be obtained") {
+		t.Fatal("expected the maintenance not to proceed during the grace")
+	}
+
+	// A third consecutive synced change ends the grace.
+	process(true)
+	if !c.managedSyncGracePassed() {
+		t.Fatal("expected the grace to pass")
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for !strings.Contains(testLog(t, c), "maintenance lock could not be obtained") {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the maintenance to proceed after the grace")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// Once passed, the grace isn't restarted by losing the sync.
+	process(false)
+	process(true)
+	if !c.managedSyncGracePassed() {
+		t.Fatal("expected the grace to stay passed")
+	}
+}
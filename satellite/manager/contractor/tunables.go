@@ -0,0 +1,222 @@
+package contractor
+
+import (
+	"github.com/mike76-dev/sia-satellite/modules"
+
+	"gitlab.com/NebulousLabs/errors"
+
+	"go.sia.tech/siad/types"
+)
+
+var (
+	// errInvalidPriceCeiling is returned when a price ceiling is zero.
+	errInvalidPriceCeiling = errors.New("price ceilings must be non-zero")
+
+	// errInvalidScoreLeeway is returned when the score leeways are zero or
+	// the GoodForUpload leeway exceeds the GoodForRenew one.
+	errInvalidScoreLeeway = errors.New("score leeways must be non-zero, and the GoodForUpload leeway must not exceed the GoodForRenew one")
+
+	// errInvalidHostContractLimit is returned when the per-host contract
+	// limit is negative.
+	errInvalidHostContractLimit = errors.New("per-host contract limit must not be negative")
+
+	// errInvalidDiversityWeight is returned when the diversity weight is
+	// out of range.
+	errInvalidDiversityWeight = errors.New("diversity weight must be at least 0 and less than 1")
+
+	// errInvalidRenewalLimit is returned when the number of concurrent
+	// renewals isn't positive.
+	errInvalidRenewalLimit = errors.New("number of concurrent renewals must be positive")
+
+	// errInvalidFormationDelay is returned when the formation delay or
+	// jitter is negative.
+	errInvalidFormationDelay = errors.New("formation delay and jitter must not be negative")
+
+	// errInvalidCongestionThreshold is returned when the transaction pool
+	// congestion threshold is negative.
+	errInvalidCongestionThreshold = errors.New("transaction pool congestion threshold must not be negative")
+
+	// errInvalidProvisionedFraction is returned when the minimum
+	// provisioned fraction is out of range.
+	errInvalidProvisionedFraction = errors.New("minimum provisioned fraction must be between 0 and 1")
+
+	// errInvalidRenewHistoryDepth is returned when the renew history depth
+	// isn't positive.
+	errInvalidRenewHistoryDepth = errors.New("renew history depth must be positive")
+
+	// errInvalidStoredDataWeight is returned when the weight of the stored
+	// data in the GFU limiter is out of range.
+	errInvalidStoredDataWeight = errors.New("stored data weight must be between 0 and 1")
+
+	// errInvalidSyncGrace is returned when the number of the synced
+	// changes in the startup grace is negative.
+	errInvalidSyncGrace = errors.New("sync grace changes must not be negative")
+
+	// errInvalidHostDBBreaker is returned when the hostdb breaker threshold
+	// or cooldown isn't positive.
+	errInvalidHostDBBreaker = errors.New("hostdb breaker threshold and cooldown must be positive")
+)
+
+// ContractorSettings returns the contractor tunables that can be adjusted
+// at runtime.
+func (c *Contractor) ContractorSettings() modules.ContractorSettings {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.settings()
+}
+
+// settings returns the contractor tunables. The caller must hold the lock.
+func (c *Contractor) settings() modules.ContractorSettings {
+	gfr, _ := c.scoreLeewayGFR.Uint64()
+	gfu, _ := c.scoreLeewayGFU.Uint64()
+	return modules.ContractorSettings{
+		MaxStoragePrice:          c.maxStoragePrice,
+		MaxCollateral:            c.maxCollateral,
+		ScoreLeewayGoodForRenew:  gfr,
+		ScoreLeewayGoodForUpload: gfu,
+		MinimumFunding:           c.minimumFunding,
+		InitialFundingMaxMul:     c.initialFunding.MaxMulFactor,
+		InitialFundingMaxDiv:     c.initialFunding.MaxDivFactor,
+		InitialFundingMinDiv:     c.initialFunding.MinDivFactor,
+		HostOversample:           c.hostOversample,
+		EndHeightHorizon:         c.endHeightHorizon,
+		ClampEndHeight:           c.clampEndHeight,
+		MaxContractsPerHost:      c.maxContractsPerHost,
+		ChurnExcessHostContracts: c.churnExcessHostContracts,
+		DiversityWeight:          c.diversityWeight,
+
+		MinRefreshRemainingBlocks: c.minRefreshRemainingBlocks,
+		MaxConcurrentRenewals:     c.maxConcurrentRenewals,
+		FormationDelay:            c.formationDelay,
+		FormationJitter:           c.formationJitter,
+		GFUChurnCooldown:          c.gfuChurnCooldown,
+		TpoolCongestionThreshold:  c.tpoolCongestionThreshold,
+		MinProvisionedFraction:    c.minProvisionedFraction,
+		MaxRenewHistoryDepth:      c.maxRenewHistoryDepth,
+		GFUStoredDataWeight:       c.gfuStoredDataWeight,
+		CanceledContractRetention: c.canceledContractRetention,
+		SyncGraceChanges:          c.syncGraceChanges,
+		HostDBBreakerThreshold:    c.hostDBBreakerThreshold,
+		HostDBBreakerCooldown:     c.hostDBBreakerCooldown,
+		HostSelectionSeed:         c.hostSelectionSeed,
+	}
+}
+
+// SetContractorSettings validates and applies the contractor tunables.
+// Either all of them are applied or none. They take effect with the next
+// formation or maintenance cycle, and are persisted across restarts.
+func (c *Contractor) SetContractorSettings(s modules.ContractorSettings) error {
+	if s.MaxStoragePrice.IsZero() || s.MaxCollateral.IsZero() {
+		return errInvalidPriceCeiling
+	}
+	if s.ScoreLeewayGoodForUpload == 0 || s.ScoreLeewayGoodForRenew < s.ScoreLeewayGoodForUpload {
+		return errInvalidScoreLeeway
+	}
+	if s.MinimumFunding < 0 || s.MinimumFunding > 1 {
+		return errInvalidMinimumFunding
+	}
+	if s.InitialFundingMaxMul == 0 || s.InitialFundingMaxDiv == 0 || s.InitialFundingMinDiv == 0 {
+		return errInvalidInitialFunding
+	}
+	if s.HostOversample < 1 {
+		return errInvalidHostOversample
+	}
+	if s.MaxContractsPerHost < 0 {
+		return errInvalidHostContractLimit
+	}
+	if s.DiversityWeight < 0 || s.DiversityWeight >= 1 {
+		return errInvalidDiversityWeight
+	}
+	if s.MaxConcurrentRenewals < 1 {
+		return errInvalidRenewalLimit
+	}
+	if s.FormationDelay < 0 || s.FormationJitter < 0 {
+		return errInvalidFormationDelay
+	}
+	if s.TpoolCongestionThreshold < 0 {
+		return errInvalidCongestionThreshold
+	}
+	if s.MinProvisionedFraction < 0 || s.MinProvisionedFraction > 1 {
+		return errInvalidProvisionedFraction
+	}
+	if s.MaxRenewHistoryDepth < 1 {
+		return errInvalidRenewHistoryDepth
+	}
+	if s.GFUStoredDataWeight < 0 || s.GFUStoredDataWeight > 1 {
+		return errInvalidStoredDataWeight
+	}
+	if s.SyncGraceChanges < 0 {
+		return errInvalidSyncGrace
+	}
+	if s.HostDBBreakerThreshold < 1 || s.HostDBBreakerCooldown <= 0 {
+		return errInvalidHostDBBreaker
+	}
+	if err := c.managedCheckHorizon(s.EndHeightHorizon); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.applySettings(s)
+	return errors.AddContext(c.save(), "unable to save the contractor settings")
+}
+
+// applySettings applies the contractor tunables without validating them.
+// The host selection seed is only reset if it changed, so that the
+// sequence of the selected hosts isn't restarted by unrelated changes.
+// Likewise, the renewal slots are only replaced if their number changed.
+// The caller must hold the lock.
+func (c *Contractor) applySettings(s modules.ContractorSettings) {
+	c.maxStoragePrice = s.MaxStoragePrice
+	c.maxCollateral = s.MaxCollateral
+	c.scoreLeewayGFR = types.NewCurrency64(s.ScoreLeewayGoodForRenew)
+	c.scoreLeewayGFU = types.NewCurrency64(s.ScoreLeewayGoodForUpload)
+	c.minimumFunding = s.MinimumFunding
+	c.initialFunding = InitialFundingFactors{
+		MaxMulFactor: s.InitialFundingMaxMul,
+		MaxDivFactor: s.InitialFundingMaxDiv,
+		MinDivFactor: s.InitialFundingMinDiv,
+	}
+	c.hostOversample = s.HostOversample
+	c.endHeightHorizon = s.EndHeightHorizon
+	c.clampEndHeight = s.ClampEndHeight
+	c.maxContractsPerHost = s.MaxContractsPerHost
+	c.churnExcessHostContracts = s.ChurnExcessHostContracts
+	c.diversityWeight = s.DiversityWeight
+	c.minRefreshRemainingBlocks = s.MinRefreshRemainingBlocks
+	c.formationDelay = s.FormationDelay
+	c.formationJitter = s.FormationJitter
+	c.gfuChurnCooldown = s.GFUChurnCooldown
+	c.tpoolCongestionThreshold = s.TpoolCongestionThreshold
+	c.minProvisionedFraction = s.MinProvisionedFraction
+	c.maxRenewHistoryDepth = s.MaxRenewHistoryDepth
+	c.gfuStoredDataWeight = s.GFUStoredDataWeight
+	c.canceledContractRetention = s.CanceledContractRetention
+	c.syncGraceChanges = s.SyncGraceChanges
+	c.hostDBBreakerThreshold = s.HostDBBreakerThreshold
+	c.hostDBBreakerCooldown = s.HostDBBreakerCooldown
+	c.staticHostDBBreaker.setLimits(s.HostDBBreakerThreshold, s.HostDBBreakerCooldown)
+	if s.MaxConcurrentRenewals != c.maxConcurrentRenewals {
+		c.maxConcurrentRenewals = s.MaxConcurrentRenewals
+		c.renewalSlots = make(chan struct{}, s.MaxConcurrentRenewals)
+	}
+	if s.HostSelectionSeed != c.hostSelectionSeed {
+		c.setHostSelectionSeed(s.HostSelectionSeed)
+	}
+}
+
+// managedPriceCeilings returns the highest storage price a host may charge
+// and the highest collateral a host may put into a contract.
+func (c *Contractor) managedPriceCeilings() (storage, collateral types.Currency) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.maxStoragePrice, c.maxCollateral
+}
+
+// bandwidthCeilings returns the highest upload and download bandwidth
+// prices a host may charge, which are three months of storage at the
+// storage price ceiling.
+func bandwidthCeilings(storageCeiling types.Currency) (download, upload types.Currency) {
+	ceiling := storageCeiling.Mul64(3 * uint64(types.BlocksPerMonth))
+	return ceiling, ceiling
+}
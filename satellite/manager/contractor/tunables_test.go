@@ -0,0 +1,113 @@
+package contractor
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/mike76-dev/sia-satellite/modules"
+
+	"gitlab.com/NebulousLabs/errors"
+
+	"go.sia.tech/siad/persist"
+	"go.sia.tech/siad/types"
+)
+
+// TestContractorSettingsPersist tests that the contractor settings survive
+// a restart, and that an invalid document is refused as a whole.
+func TestContractorSettingsPersist(t *testing.T) {
+	c, _ := newTestContractor(t)
+	defer c.tg.Stop()
+
+	settings := c.ContractorSettings()
+	settings.MaxStoragePrice = maxStoragePrice.Div64(2)
+	settings.HostOversample = 6
+	settings.MinimumFunding = 0.1
+	settings.HostSelectionSeed = 42
+	if err := c.SetContractorSettings(settings); err != nil {
+		t.Fatal(err)
+	}
+
+	// An invalid field leaves the settings unchanged.
+	invalid := settings
+	invalid.HostOversample = 8
+	invalid.MaxCollateral = types.ZeroCurrency
+	if err := c.SetContractorSettings(invalid); !errors.Contains(err, errInvalidPriceCeiling) {
+		t.Fatal("expected errInvalidPriceCeiling, got", err)
+	}
+	if c.HostOversample() != 6 {
+		t.Fatal("invalid settings partially applied")
+	}
+
+	// The settings are loaded on restart.
+	loaded := newContractor(nil, nil, nil, nil, c.persistDir, c.staticContracts, c.db, c.log, nil)
+	if err := loaded.load(); err != nil {
+		t.Fatal(err)
+	}
+	if got := loaded.ContractorSettings(); !reflect.DeepEqual(got, settings) {
+		t.Fatalf("expected %+v, got %+v", settings, got)
+	}
+	if loaded.hostSelectionRNG == nil {
+		t.Fatal("host selection seed not restored")
+	}
+}
+
+// TestContractorSettingsOlderFile tests that the settings missing from an
+// older persisted document keep their defaults, and that the hostdb breaker
+// follows the loaded settings.
+func TestContractorSettingsOlderFile(t *testing.T) {
+	c, _ := newTestContractor(t)
+	defer c.tg.Stop()
+
+	older := map[string]interface{}{
+		"settings": map[string]interface{}{
+			"hostoversample": 6,
+		},
+	}
+	if err := persist.SaveJSON(persistMeta, older, filepath.Join(c.persistDir, PersistFilename)); err != nil {
+		t.Fatal(err)
+	}
+	loaded := newContractor(nil, nil, nil, nil, c.persistDir, c.staticContracts, c.db, c.log, nil)
+	expected := loaded.ContractorSettings()
+	expected.HostOversample = 6
+	if err := loaded.load(); err != nil {
+		t.Fatal(err)
+	}
+	if got := loaded.ContractorSettings(); !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, got)
+	}
+
+	if err := setTestSettings(loaded, func(s *modules.ContractorSettings) { s.HostDBBreakerThreshold = 0 }); !errors.Contains(err, errInvalidHostDBBreaker) {
+		t.Fatal("expected errInvalidHostDBBreaker, got", err)
+	}
+	if err := setTestSettings(loaded, func(s *modules.ContractorSettings) { s.HostDBBreakerThreshold = 1 }); err != nil {
+		t.Fatal(err)
+	}
+	if !loaded.staticHostDBBreaker.failure() {
+		t.Fatal("expected the breaker to open after a single failure")
+	}
+}
+
+// TestPriceCeilingTakesEffect tests that a lowered storage price ceiling
+// is applied to the next formation and to the bandwidth ceilings.
+func TestPriceCeilingTakesEffect(t *testing.T) {
+	c, _ := newTestContractor(t)
+	rpk := testKey(1)
+	renter := testRenter(c, rpk)
+	host := testHost(10, "host.example.com:9982")
+	host.StoragePrice = maxStoragePrice.Div64(2)
+	storage, _ := c.managedPriceCeilings()
+	_, upload := bandwidthCeilings(storage)
+	host.UploadBandwidthPrice = upload.Div64(2)
+
+	if err := setTestSettings(c, func(s *modules.ContractorSettings) { s.MaxStoragePrice = maxStoragePrice.Div64(4) }); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := c.managedNewContract(rpk, host, renter.Allowance.Funds.Div64(10), renter.ContractEndHeight()); !errors.Contains(err, errTooExpensive) {
+		t.Fatal("expected errTooExpensive, got", err)
+	}
+	storage, _ = c.managedPriceCeilings()
+	if _, upload := bandwidthCeilings(storage); host.UploadBandwidthPrice.Cmp(upload) <= 0 {
+		t.Fatal("upload ceiling not derived from the storage ceiling")
+	}
+}
@@ -30,7 +30,9 @@ func hasFCIdentifier(txn types.Transaction) (modules.ContractSignedIdentifier, c
 }
 
 // managedArchiveContracts will figure out which contracts are no longer needed
-// and move them to the historic set of contracts.
+// and move them to the historic set of contracts. These are the expired and
+// renewed contracts, and the contracts that were canceled more than the
+// retention period ago.
 func (c *Contractor) managedArchiveContracts() {
 	// Determine the current block height.
 	c.mu.RLock()
@@ -40,6 +42,7 @@ func (c *Contractor) managedArchiveContracts() {
 	// Loop through the current set of contracts and migrate any expired ones to
 	// the set of old contracts.
 	var expired []types.FileContractID
+	var released []types.FileContractID
 	for _, contract := range c.staticContracts.ViewAll() {
 		// Check map of renewedTo in case renew code was interrupted before
 		// archiving old contract
@@ -52,7 +55,27 @@ func (c *Contractor) managedArchiveContracts() {
 			c.oldContracts[id] = contract
 			c.mu.Unlock()
 			expired = append(expired, id)
+			if !renewed {
+				released = append(released, id)
+			}
+			c.managedClearCancellation(id)
+			c.managedClearNoRefresh(id)
 			c.log.Println("INFO: archived expired contract", id)
+			c.logEvent("INFO", eventContractArchived, id, contract.RenterPublicKey, contract.HostPublicKey, "archived expired contract")
+			continue
+		}
+		if c.managedCanceledLongAgo(contract, currentHeight) {
+			id := contract.ID
+			c.mu.Lock()
+			c.oldContracts[id] = contract
+			c.mu.Unlock()
+			expired = append(expired, id)
+			released = append(released, id)
+			c.staticWatchdog.callArchiveContract(id)
+			c.managedClearCancellation(id)
+			c.managedClearNoRefresh(id)
+			c.log.Println("INFO: archived canceled contract", id)
+			c.logEvent("INFO", eventContractArchived, id, contract.RenterPublicKey, contract.HostPublicKey, "archived canceled contract")
 		}
 	}
 
@@ -68,6 +91,15 @@ func (c *Contractor) managedArchiveContracts() {
 			c.UnlockBalance(fc.Metadata().ID)
 		}
 	}
+
+	// Release the over-allocated funds of the contracts that expired
+	// without being renewed.
+	for _, id := range released {
+		c.mu.RLock()
+		contract := c.oldContracts[id]
+		c.mu.RUnlock()
+		c.managedReleaseOverAllocation(contract)
+	}
 }
 
 // ProcessConsensusChange will be called by the consensus set every time there
@@ -81,19 +113,23 @@ func (c *Contractor) ProcessConsensusChange(cc modules.ConsensusChange) {
 			c.blockHeight++
 		}
 	}
-	c.staticWatchdog.callScanConsensusChange(cc)
+	doubleSpends := c.staticWatchdog.callScanConsensusChange(cc)
+
+	// Mark the double-spent contracts. This will cause the contracts to be
+	// excluded in period spending.
+	for fcID, height := range doubleSpends {
+		c.log.Println("Watchdog found a double-spend: ", fcID, height)
+		c.doubleSpentContracts[fcID] = height
+	}
 
 	// If the allowance is set and we have entered the next period, update
-	// CurrentPeriod.
-	renters := c.renters
-	for key, renter := range renters {
+	// CurrentPeriod. The records are written after the lock is released.
+	var updated []string
+	for key, renter := range c.renters {
 		if renter.Allowance.Active() && c.blockHeight >= renter.CurrentPeriod + renter.Allowance.Period {
 			renter.CurrentPeriod += renter.Allowance.Period
 			c.renters[key] = renter
-			err := c.UpdateRenter(renter)
-			if err != nil {
-				c.log.Println("Unable to update renter:", err)
-			}
+			updated = append(updated, key)
 		}
 	}
 
@@ -111,6 +147,19 @@ func (c *Contractor) ProcessConsensusChange(cc modules.ConsensusChange) {
 	} else if synced && !cc.Synced {
 		c.synced = make(chan struct{})
 	}
+
+	// Count the consecutive synced changes until the startup grace is
+	// over. A change that isn't synced restarts the count.
+	if !c.gracePassed {
+		if cc.Synced {
+			c.syncedStreak++
+		} else {
+			c.syncedStreak = 0
+		}
+		if c.syncedStreak >= c.syncGraceChanges {
+			c.gracePassed = true
+		}
+	}
 	// Let the watchdog take any necessary actions and update its state. We do
 	// this before persisting the contractor so that the watchdog is up-to-date on
 	// reboot. Otherwise it is possible that e.g. that the watchdog thinks a
@@ -127,6 +176,23 @@ func (c *Contractor) ProcessConsensusChange(cc modules.ConsensusChange) {
 	}
 	c.mu.Unlock()
 
+	for _, key := range updated {
+		c.mu.RLock()
+		renter, exists := c.renters[key]
+		c.mu.RUnlock()
+		if !exists {
+			continue
+		}
+		if err := c.UpdateRenter(renter); err != nil {
+			c.log.Println("Unable to update renter:", err)
+		}
+	}
+
+	// Mark the double-spent contracts as bad.
+	for fcID := range doubleSpends {
+		c.managedMarkDoubleSpentBad(fcID)
+	}
+
 	// Perform contract maintenance if our blockchain is synced. Use a separate
 	// goroutine so that the rest of the contractor is not blocked during
 	// maintenance.
@@ -0,0 +1,76 @@
+package contractor
+
+import (
+	"database/sql/driver"
+	"strings"
+	"testing"
+
+	"github.com/mike76-dev/sia-satellite/internal/dbtest"
+
+	smodules "go.sia.tech/siad/modules"
+	"go.sia.tech/siad/types"
+)
+
+// TestUtilityHistory tests that the utility updates of a contract produce
+// its ordered utility history with the reasons.
+func TestUtilityHistory(t *testing.T) {
+	c, fake := newTestContractor(t)
+	rpk := testKey(1)
+	testRenter(c, rpk)
+	contract := testContract(t, c, rpk, testKey(10), 1, 0, 1000, types.SiacoinPrecision)
+
+	// Keep the recorded transitions, so that the history query returns
+	// them.
+	var history [][]driver.Value
+	fake.OnExec(func(query string, args []driver.Value) error {
+		if strings.Contains(query, "INSERT INTO contract_utility_history") && args[0] == contract.ID.String() {
+			history = append(history, args[1:])
+		}
+		return nil
+	})
+	fake.OnQuery(func(query string, _ []driver.Value) (*dbtest.Rows, error) {
+		if !strings.Contains(query, "FROM contract_utility_history") {
+			return nil, nil
+		}
+		return &dbtest.Rows{
+			Columns: []string{"old_good_for_upload", "old_good_for_renew", "old_bad_contract", "old_locked", "new_good_for_upload", "new_good_for_renew", "new_bad_contract", "new_locked", "height", "reason"},
+			Values:  history,
+		}, nil
+	})
+
+	updates := []struct {
+		height  types.BlockHeight
+		utility smodules.ContractUtility
+		reason  string
+	}{
+		{10, smodules.ContractUtility{GoodForUpload: true, GoodForRenew: true}, "formed"},
+		{20, smodules.ContractUtility{GoodForRenew: true}, "too many GFU hosts"},
+		{25, smodules.ContractUtility{GoodForRenew: true}, "unchanged"},
+		{30, smodules.ContractUtility{BadContract: true}, "host is offline"},
+	}
+	for _, u := range updates {
+		c.mu.Lock()
+		c.blockHeight = u.height
+		c.mu.Unlock()
+		if err := c.managedAcquireAndUpdateContractUtility(contract.ID, u.utility, u.reason); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	transitions, err := c.ContractUtilityHistory(contract.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []int{0, 1, 3}
+	if len(transitions) != len(expected) {
+		t.Fatalf("expected %v transitions, got %v", len(expected), len(transitions))
+	}
+	var old smodules.ContractUtility
+	for i, tr := range transitions {
+		u := updates[expected[i]]
+		if tr.Height != u.height || tr.Reason != u.reason || tr.OldUtility != old || tr.NewUtility != u.utility {
+			t.Fatalf("transition %v: expected %v at %v, got %+v", i, u.reason, u.height, tr)
+		}
+		old = u.utility
+	}
+}
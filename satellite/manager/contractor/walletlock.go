@@ -0,0 +1,70 @@
+package contractor
+
+import (
+	"time"
+
+	"gitlab.com/NebulousLabs/errors"
+
+	smodules "go.sia.tech/siad/modules"
+)
+
+// errWalletLocked is returned when a contract formation or renewal can't
+// proceed because the wallet is locked. Unlike other failures, this is
+// usually transient.
+var errWalletLocked = errors.New("the wallet is locked")
+
+// managedCheckWallet confirms that the wallet is unlocked. If it is locked,
+// an informational alert is registered and errWalletLocked is returned.
+// The alert is unregistered once the wallet is found unlocked.
+func (c *Contractor) managedCheckWallet() error {
+	unlocked, err := c.wallet.Unlocked()
+	if err != nil {
+		return errors.AddContext(err, "unable to check the wallet")
+	}
+	if unlocked {
+		c.staticAlerter.UnregisterAlert(AlertIDWalletLocked)
+		return nil
+	}
+
+	c.staticAlerter.RegisterAlert(AlertIDWalletLocked, AlertMSGWalletLockedDuringMaintenance, errWalletLocked.Error(), smodules.SeverityInfo)
+	return errWalletLocked
+}
+
+// managedScheduleWalletRetry runs the operation that failed because of a
+// locked wallet again after WalletLockedRetryInterval, unless a retry of
+// the same operation is already pending. If the wallet is still locked,
+// the retry is rescheduled, up to WalletLockedMaxRetries times.
+func (c *Contractor) managedScheduleWalletRetry(name string, attempt int, op func() error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, pending := c.walletRetries[name]; pending {
+		return
+	}
+	c.walletRetries[name] = struct{}{}
+	c.log.Printf("INFO: the wallet is locked, retrying %v in %v\n", name, WalletLockedRetryInterval)
+	time.AfterFunc(WalletLockedRetryInterval, func() {
+		if err := c.tg.Add(); err != nil {
+			return
+		}
+		defer c.tg.Done()
+		c.mu.Lock()
+		delete(c.walletRetries, name)
+		c.mu.Unlock()
+		select {
+		case <-c.tg.StopChan():
+			return
+		default:
+		}
+
+		err := op()
+		if errors.Contains(err, errWalletLocked) {
+			if attempt < WalletLockedMaxRetries {
+				c.managedScheduleWalletRetry(name, attempt + 1, op)
+			} else {
+				c.log.Printf("WARN: the wallet is still locked, giving up on %v\n", name)
+			}
+		} else if err != nil {
+			c.log.Printf("WARN: retrying %v failed: %v\n", name, err)
+		}
+	})
+}
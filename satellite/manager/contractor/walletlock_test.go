@@ -0,0 +1,85 @@
+package contractor
+
+import (
+	"testing"
+	"time"
+
+	"gitlab.com/NebulousLabs/errors"
+
+	"go.sia.tech/siad/types"
+)
+
+// TestWalletLockedRetry tests that a locked wallet registers the wallet
+// alert and schedules a retry of the failed operation, which clears the
+// alert once the wallet is unlocked.
+func TestWalletLockedRetry(t *testing.T) {
+	interval, retries := WalletLockedRetryInterval, WalletLockedMaxRetries
+	WalletLockedRetryInterval, WalletLockedMaxRetries = 10 * time.Millisecond, 3
+	defer func() {
+		WalletLockedRetryInterval, WalletLockedMaxRetries = interval, retries
+	}()
+
+	c, _ := newTestContractor(t)
+	defer c.tg.Stop()
+	setTestSynced(c)
+	w := &testWallet{}
+	c.wallet = w
+
+	_, err := c.RenewAllDue(types.SiacoinPrecision)
+	if !errors.Contains(err, errWalletLocked) {
+		t.Fatal("expected errWalletLocked, got", err)
+	}
+	if !hasAlert(c, AlertMSGWalletLockedDuringMaintenance) {
+		t.Fatal("wallet alert not registered")
+	}
+	c.mu.Lock()
+	_, pending := c.walletRetries["renewal sweep"]
+	c.mu.Unlock()
+	if !pending {
+		t.Fatal("no retry scheduled")
+	}
+
+	// A second failure doesn't schedule another retry.
+	c.RenewAllDue(types.SiacoinPrecision)
+	c.mu.Lock()
+	n := len(c.walletRetries)
+	c.mu.Unlock()
+	if n != 1 {
+		t.Fatalf("expected one pending retry, got %v", n)
+	}
+
+	// The retry clears the alert once the wallet is unlocked.
+	w.setUnlocked(true)
+	time.Sleep(50 * time.Millisecond)
+	if hasAlert(c, AlertMSGWalletLockedDuringMaintenance) {
+		t.Fatal("wallet alert not cleared")
+	}
+	c.mu.Lock()
+	n = len(c.walletRetries)
+	c.mu.Unlock()
+	if n != 0 {
+		t.Fatal("retry still pending")
+	}
+}
+
+// TestWalletLockedRetryLimit tests that the retries stop after
+// WalletLockedMaxRetries attempts.
+func TestWalletLockedRetryLimit(t *testing.T) {
+	interval, retries := WalletLockedRetryInterval, WalletLockedMaxRetries
+	WalletLockedRetryInterval, WalletLockedMaxRetries = 5 * time.Millisecond, 3
+	defer func() {
+		WalletLockedRetryInterval, WalletLockedMaxRetries = interval, retries
+	}()
+
+	c, _ := newTestContractor(t)
+	defer c.tg.Stop()
+	setTestSynced(c)
+	w := &testWallet{}
+	c.wallet = w
+
+	c.RenewAllDue(types.SiacoinPrecision)
+	time.Sleep(100 * time.Millisecond)
+	if checks := w.numChecks(); checks != 1 + WalletLockedMaxRetries {
+		t.Fatalf("expected %v wallet checks, got %v", 1 + WalletLockedMaxRetries, checks)
+	}
+}
@@ -0,0 +1,78 @@
+package contractor
+
+import (
+	"github.com/mike76-dev/sia-satellite/modules"
+
+	"gitlab.com/NebulousLabs/errors"
+
+	"go.sia.tech/siad/types"
+)
+
+// managedReserveAddress records the wallet address as reserved for a
+// formation or renewal in progress.
+func (c *Contractor) managedReserveAddress(uh types.UnlockHash) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.reservedAddresses[uh] = struct{}{}
+}
+
+// managedReleaseAddress removes the wallet address from the reserved ones
+// once the formation or renewal is over.
+func (c *Contractor) managedReleaseAddress(uh types.UnlockHash) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.reservedAddresses, uh)
+}
+
+// WalletUsage reports the wallet addresses reserved for the formations and
+// renewals in progress, and the refund addresses of the active contracts
+// compared against the addresses known to the wallet.
+func (c *Contractor) WalletUsage() (modules.WalletUsage, error) {
+	if err := c.tg.Add(); err != nil {
+		return modules.WalletUsage{}, err
+	}
+	defer c.tg.Done()
+
+	c.mu.RLock()
+	wu := modules.WalletUsage{
+		ReservedAddresses: make([]types.UnlockHash, 0, len(c.reservedAddresses)),
+	}
+	for uh := range c.reservedAddresses {
+		wu.ReservedAddresses = append(wu.ReservedAddresses, uh)
+	}
+	c.mu.RUnlock()
+
+	addrs, err := c.wallet.AllAddresses()
+	if err != nil {
+		return modules.WalletUsage{}, errors.AddContext(err, "unable to get the wallet addresses")
+	}
+	known := make(map[types.UnlockHash]struct{}, len(addrs))
+	for _, uh := range addrs {
+		known[uh] = struct{}{}
+	}
+	wu.WalletAddresses = len(addrs)
+
+	refunds := make(map[types.UnlockHash]struct{})
+	wu.UnknownRefundAddresses = make([]types.UnlockHash, 0)
+	for _, contract := range c.staticContracts.ViewAll() {
+		if len(contract.Transaction.FileContractRevisions) == 0 {
+			continue
+		}
+		rev := contract.Transaction.FileContractRevisions[0]
+		if len(rev.NewValidProofOutputs) == 0 {
+			continue
+		}
+		wu.ActiveContracts++
+		uh := rev.NewValidProofOutputs[0].UnlockHash
+		if _, exists := refunds[uh]; exists {
+			continue
+		}
+		refunds[uh] = struct{}{}
+		if _, exists := known[uh]; !exists {
+			wu.UnknownRefundAddresses = append(wu.UnknownRefundAddresses, uh)
+		}
+	}
+	wu.RefundAddresses = len(refunds)
+
+	return wu, nil
+}
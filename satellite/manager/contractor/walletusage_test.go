@@ -0,0 +1,75 @@
+package contractor
+
+import (
+	"testing"
+
+	"go.sia.tech/siad/types"
+)
+
+// addressWallet is a testWallet that knows a fixed set of addresses.
+type addressWallet struct {
+	*testWallet
+	addresses []types.UnlockHash
+}
+
+// AllAddresses implements smodules.Wallet.
+func (w *addressWallet) AllAddresses() ([]types.UnlockHash, error) {
+	return w.addresses, nil
+}
+
+// TestWalletUsage tests that the addresses reserved for the formations in
+// progress are reported, and that the refund addresses of the contracts
+// are checked against the wallet.
+func TestWalletUsage(t *testing.T) {
+	c, _ := newTestContractor(t)
+	w := &addressWallet{
+		testWallet: &testWallet{unlocked: true},
+		addresses:  []types.UnlockHash{{1}, {2}, {3}},
+	}
+	c.wallet = w
+	rpk := testKey(1)
+	testRenter(c, rpk)
+
+	// Three formations are in progress, and one is over.
+	for _, uh := range []types.UnlockHash{{1}, {2}, {3}} {
+		c.managedReserveAddress(uh)
+	}
+	c.managedReleaseAddress(types.UnlockHash{2})
+
+	// The test contracts refund to the zero address, which the wallet
+	// doesn't know.
+	testContract(t, c, rpk, testKey(10), 1, 0, 1000, types.SiacoinPrecision)
+	testContract(t, c, rpk, testKey(11), 2, 0, 1000, types.SiacoinPrecision)
+
+	wu, err := c.WalletUsage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(wu.ReservedAddresses) != 2 {
+		t.Fatalf("expected 2 reserved addresses, got %v", len(wu.ReservedAddresses))
+	}
+	for _, uh := range wu.ReservedAddresses {
+		if uh != (types.UnlockHash{1}) && uh != (types.UnlockHash{3}) {
+			t.Fatal("unexpected reserved address", uh)
+		}
+	}
+	if wu.ActiveContracts != 2 || wu.RefundAddresses != 1 || wu.WalletAddresses != 3 {
+		t.Fatalf("unexpected usage: %+v", wu)
+	}
+	if len(wu.UnknownRefundAddresses) != 1 || wu.UnknownRefundAddresses[0] != (types.UnlockHash{}) {
+		t.Fatal("expected the zero address to be unknown, got", wu.UnknownRefundAddresses)
+	}
+
+	// Once the formations are over, nothing is reserved, and a refund
+	// address known to the wallet isn't reported.
+	c.managedReleaseAddress(types.UnlockHash{1})
+	c.managedReleaseAddress(types.UnlockHash{3})
+	w.addresses = append(w.addresses, types.UnlockHash{})
+	wu, err = c.WalletUsage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(wu.ReservedAddresses) != 0 || len(wu.UnknownRefundAddresses) != 0 {
+		t.Fatalf("unexpected usage: %+v", wu)
+	}
+}
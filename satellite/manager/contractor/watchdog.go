@@ -60,6 +60,10 @@ type watchdog struct {
 	tpool      smodules.TransactionPool
 	contractor *Contractor
 
+	// doubleSpends are the contracts found double-spent during the current
+	// scan. They are handed over to the contractor at the end of the scan.
+	doubleSpends map[types.FileContractID]types.BlockHeight
+
 	mu sync.Mutex
 }
 
@@ -125,6 +129,7 @@ func newWatchdog(contractor *Contractor) *watchdog {
 		contracts:          make(map[types.FileContractID]*fileContractStatus),
 		archivedContracts:  make(map[types.FileContractID]smodules.ContractWatchStatus),
 		outputDependencies: make(map[types.SiacoinOutputID]map[types.FileContractID]struct{}),
+		doubleSpends:       make(map[types.FileContractID]types.BlockHeight),
 
 		renewWindows: renewWindows,
 		blockHeight:  contractor.blockHeight,
@@ -205,9 +210,14 @@ func (w *watchdog) callMonitorContract(args monitorContractArgs) error {
 
 // callScanConsensusChange scans applied and reverted blocks, updating the
 // watchdog's state with all information relevant to monitored contracts.
-func (w *watchdog) callScanConsensusChange(cc smodules.ConsensusChange) {
+// It returns the contracts whose inputs were double-spent, together with
+// the height of the double-spend.
+func (w *watchdog) callScanConsensusChange(cc smodules.ConsensusChange) map[types.FileContractID]types.BlockHeight {
 	w.mu.Lock()
 	defer w.mu.Unlock()
+	defer func() {
+		w.doubleSpends = make(map[types.FileContractID]types.BlockHeight)
+	}()
 	for _, block := range cc.RevertedBlocks {
 		if block.ID() != types.GenesisID {
 			w.blockHeight--
@@ -221,6 +231,7 @@ func (w *watchdog) callScanConsensusChange(cc smodules.ConsensusChange) {
 		}
 		w.scanAppliedBlock(block)
 	}
+	return w.doubleSpends
 }
 
 // sendTxnSet broadcasts a transaction set and logs errors that are not
@@ -269,6 +280,14 @@ func (w *watchdog) archiveContract(fcID types.FileContractID, doubleSpendHeight
 	delete(w.contracts, fcID)
 }
 
+// callArchiveContract stops monitoring the contract, e.g. because it was
+// moved out of the active contract set.
+func (w *watchdog) callArchiveContract(fcID types.FileContractID) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.archiveContract(fcID, 0)
+}
+
 // addOutputDependency marks the contract with fcID as dependent on this Siacoin
 // output.
 func (w *watchdog) addOutputDependency(outputID types.SiacoinOutputID, fcID types.FileContractID) {
@@ -459,7 +478,7 @@ func (w *watchdog) findDependencySpends(txn types.Transaction) {
 			// Signal to the contractor that this contract's inputs were
 			// double-spent and that it should be removed.
 			w.archiveContract(fcID, w.blockHeight)
-			go w.contractor.callNotifyDoubleSpend(fcID, w.blockHeight)
+			w.doubleSpends[fcID] = w.blockHeight
 			continue
 		}
 
@@ -71,6 +71,13 @@ func (w *watchdog) callPersistData() watchdogPersist {
 	return data
 }
 
+// callStatus returns the number of the monitored and archived contracts.
+func (w *watchdog) callStatus() (monitored, archived int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return len(w.contracts), len(w.archivedContracts)
+}
+
 // newWatchdogFromPersist creates a new watchdog and loads it with the
 // information stored in persistData.
 func newWatchdogFromPersist(contractor *Contractor, persistData watchdogPersist) (*watchdog, error) {
@@ -0,0 +1,47 @@
+package contractor
+
+import (
+	"testing"
+
+	"go.sia.tech/siad/types"
+)
+
+// TestWatchdogPersist tests that a monitored contract survives a restart.
+func TestWatchdogPersist(t *testing.T) {
+	c, _ := newTestContractor(t)
+	var fcid types.FileContractID
+	fcid[0] = 1
+	args := monitorContractArgs{
+		recovered: true,
+		fcID:      fcid,
+		revisionTxn: types.Transaction{
+			FileContractRevisions: []types.FileContractRevision{{
+				ParentID:       fcid,
+				NewWindowStart: 100,
+				NewWindowEnd:   244,
+			}},
+		},
+	}
+	if err := c.staticWatchdog.callMonitorContract(args); err != nil {
+		t.Fatal(err)
+	}
+	c.mu.Lock()
+	err := c.save()
+	c.mu.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Simulate a restart.
+	restarted := newContractor(nil, nil, nil, nil, c.persistDir, c.staticContracts, c.db, c.log, nil)
+	if err := restarted.load(); err != nil {
+		t.Fatal(err)
+	}
+	status := restarted.WatchdogStatus()
+	if status.MonitoredContracts != 1 {
+		t.Fatalf("expected 1 monitored contract, got %v", status.MonitoredContracts)
+	}
+	if _, ok := restarted.staticWatchdog.managedContractStatus(fcid); !ok {
+		t.Fatal("contract not monitored after the restart")
+	}
+}
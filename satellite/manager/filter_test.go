@@ -0,0 +1,82 @@
+package manager
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/mike76-dev/sia-satellite/modules"
+
+	smodules "go.sia.tech/siad/modules"
+	"go.sia.tech/siad/types"
+)
+
+// testHostDB is a hostdb that records the filter changes. The methods that
+// are not overridden panic.
+type testHostDB struct {
+	modules.HostDB
+	mode smodules.FilterMode
+	fail bool
+}
+
+// SetFilterMode implements modules.HostDB.
+func (hdb *testHostDB) SetFilterMode(fm smodules.FilterMode, _ []types.SiaPublicKey, _ []string) error {
+	if hdb.fail {
+		return errors.New("invalid filter")
+	}
+	hdb.mode = fm
+	return nil
+}
+
+// UpdateFilter implements modules.HostDB.
+func (hdb *testHostDB) UpdateFilter(_, _ []types.SiaPublicKey, _, _ []string) (smodules.FilterMode, map[string]types.SiaPublicKey, []string, error) {
+	if hdb.fail {
+		return smodules.HostDBFilterError, nil, nil, errors.New("invalid filter")
+	}
+	return hdb.mode, nil, nil, nil
+}
+
+// testContractor is a hostContractor that counts how often the filter was
+// applied to the contracts. The methods that are not overridden panic.
+type testContractor struct {
+	hostContractor
+	applied int
+}
+
+// ApplyHostFilter implements hostContractor.
+func (c *testContractor) ApplyHostFilter() int {
+	c.applied++
+	return 0
+}
+
+// TestFilterChurnsContracts tests that the contracts are churned right
+// after every successful filter change.
+func TestFilterChurnsContracts(t *testing.T) {
+	hdb, c := &testHostDB{}, &testContractor{}
+	m := &Manager{hostDB: hdb, hostContractor: c}
+	hosts := []types.SiaPublicKey{types.Ed25519PublicKey([32]byte{1})}
+
+	if err := m.SetFilterMode(smodules.HostDBActivateBlacklist, hosts, nil); err != nil {
+		t.Fatal(err)
+	}
+	if c.applied != 1 {
+		t.Fatalf("expected the filter to be applied once, got %v", c.applied)
+	}
+	if _, _, _, err := m.UpdateFilter(hosts, nil, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if c.applied != 2 {
+		t.Fatalf("expected the filter to be applied twice, got %v", c.applied)
+	}
+
+	// A rejected filter change isn't applied.
+	hdb.fail = true
+	if err := m.SetFilterMode(smodules.HostDBActivateBlacklist, hosts, nil); err == nil {
+		t.Fatal("expected the filter change to fail")
+	}
+	if _, _, _, err := m.UpdateFilter(hosts, nil, nil, nil); err == nil {
+		t.Fatal("expected the filter update to fail")
+	}
+	if c.applied != 2 {
+		t.Fatalf("expected a failed change not to be applied, got %v", c.applied)
+	}
+}
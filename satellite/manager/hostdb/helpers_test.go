@@ -0,0 +1,76 @@
+package hostdb
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/mike76-dev/sia-satellite/satellite/manager/hostdb/hosttree"
+
+	"go.sia.tech/siad/crypto"
+	smodules "go.sia.tech/siad/modules"
+	"go.sia.tech/siad/persist"
+	"go.sia.tech/siad/types"
+)
+
+// newTestHostDB returns a hostdb without the gateway, the consensus set
+// and the transaction pool, holding the given hosts.
+func newTestHostDB(t *testing.T, hosts ...smodules.HostDBEntry) *HostDB {
+	t.Helper()
+	dir := t.TempDir()
+	logger, err := persist.NewFileLogger(filepath.Join(dir, "hostdb.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { logger.Close() })
+	hdb := &HostDB{
+		resolver:   new(smodules.ProductionResolver),
+		persistDir: dir,
+		staticLog:  logger,
+
+		filteredDomains: newFilteredDomains(nil),
+		filteredHosts:   make(map[string]types.SiaPublicKey),
+		knownContracts:  make(map[string]contractInfo),
+		scanMap:         make(map[string]struct{}),
+		staticAlerter:   smodules.NewAlerter("hostdb"),
+	}
+	hdb.allowance = smodules.DefaultAllowance
+	hdb.weightFunc = hdb.managedCalculateHostWeightFn(hdb.allowance)
+	hdb.staticHostTree = hosttree.New(hdb.weightFunc, hdb.resolver)
+	hdb.filteredTree = hdb.staticHostTree
+	hdb.filterMode = smodules.HostDBDisableFilter
+	for _, host := range hosts {
+		if err := hdb.insert(host); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return hdb
+}
+
+// testKey returns a public key that is unique for the byte.
+func testKey(b byte) types.SiaPublicKey {
+	key := make([]byte, crypto.PublicKeySize)
+	key[0] = b
+	return types.SiaPublicKey{
+		Algorithm: types.SignatureEd25519,
+		Key:       key,
+	}
+}
+
+// testHost returns an online host with the given key and version. The
+// net address is an IP address, so that it doesn't need resolving, and
+// the collateral gives the host a score above the selection minimum.
+func testHost(b byte, version string) smodules.HostDBEntry {
+	var host smodules.HostDBEntry
+	host.PublicKey = testKey(b)
+	host.NetAddress = smodules.NetAddress(fmt.Sprintf("10.0.%v.1:9982", b))
+	host.AcceptingContracts = true
+	host.MaxDuration = 1e6
+	host.Version = version
+	host.ScanHistory = smodules.HostDBScans{{Success: true}}
+	host.RemainingStorage = 1e12
+	host.TotalStorage = 1e12
+	host.Collateral = types.SiacoinPrecision.Mul64(100).Div64(1e12).Div64(4320)
+	host.MaxCollateral = types.SiacoinPrecision.Mul64(1000)
+	return host
+}
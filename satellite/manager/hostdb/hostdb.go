@@ -632,7 +632,74 @@ func (hdb *HostDB) SetFilterMode(fm smodules.FilterMode, hosts []types.SiaPublic
 	defer hdb.tg.Done()
 	hdb.mu.Lock()
 	defer hdb.mu.Unlock()
+	return hdb.setFilterMode(fm, hosts, netAddresses)
+}
+
+// UpdateFilter adds hosts and net addresses to the current filter list and
+// removes others from it, keeping the filter mode. The changes are applied
+// under the hostdb lock, so concurrent updates don't get lost. The resulting
+// filter is returned.
+func (hdb *HostDB) UpdateFilter(addHosts, removeHosts []types.SiaPublicKey, addNetAddresses, removeNetAddresses []string) (smodules.FilterMode, map[string]types.SiaPublicKey, []string, error) {
+	if err := hdb.tg.Add(); err != nil {
+		return smodules.HostDBFilterError, nil, nil, errors.AddContext(err, "error adding hostdb threadgroup:")
+	}
+	defer hdb.tg.Done()
+	hdb.mu.Lock()
+	defer hdb.mu.Unlock()
+
+	fm := hdb.filterMode
+	if fm == smodules.HostDBDisableFilter {
+		return fm, nil, nil, errors.New("cannot update the filter list while the filter is disabled")
+	}
+
+	// Apply the changes to the current lists.
+	hostMap := make(map[string]types.SiaPublicKey)
+	for k, v := range hdb.filteredHosts {
+		hostMap[k] = v
+	}
+	for _, h := range addHosts {
+		hostMap[h.String()] = h
+	}
+	for _, h := range removeHosts {
+		delete(hostMap, h.String())
+	}
+	domainMap := make(map[string]struct{})
+	for _, d := range hdb.filteredDomains.managedFilteredDomains() {
+		domainMap[d] = struct{}{}
+	}
+	for _, d := range addNetAddresses {
+		domainMap[d] = struct{}{}
+	}
+	for _, d := range removeNetAddresses {
+		delete(domainMap, d)
+	}
+	hosts := make([]types.SiaPublicKey, 0, len(hostMap))
+	for _, h := range hostMap {
+		hosts = append(hosts, h)
+	}
+	netAddresses := make([]string, 0, len(domainMap))
+	for d := range domainMap {
+		netAddresses = append(netAddresses, d)
+	}
+
+	// Reset the filtered field of the removed hosts, setFilterMode only
+	// marks the new ones.
+	for _, h := range removeHosts {
+		if err := hdb.staticHostTree.SetFiltered(h, false); err != nil {
+			hdb.staticLog.Println("Unable to mark entry as not filtered:", err)
+		}
+	}
+
+	err := hdb.setFilterMode(fm, hosts, netAddresses)
+	filteredHosts := make(map[string]types.SiaPublicKey)
+	for k, v := range hdb.filteredHosts {
+		filteredHosts[k] = v
+	}
+	return hdb.filterMode, filteredHosts, hdb.filteredDomains.managedFilteredDomains(), err
+}
 
+// setFilterMode sets the hostdb filter mode. hdb.mu must be held.
+func (hdb *HostDB) setFilterMode(fm smodules.FilterMode, hosts []types.SiaPublicKey, netAddresses []string) error {
 	// Check for error.
 	if fm == smodules.HostDBFilterError {
 		return errors.New("Cannot set hostdb filter mode, provided filter mode is an error")
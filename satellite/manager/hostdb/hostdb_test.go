@@ -0,0 +1,92 @@
+package hostdb
+
+import (
+	"sync"
+	"testing"
+
+	smodules "go.sia.tech/siad/modules"
+	"go.sia.tech/siad/types"
+)
+
+// TestUpdateFilter tests adding hosts to and removing them from the
+// filter list.
+func TestUpdateFilter(t *testing.T) {
+	hdb := newTestHostDB(t, testHost(1, "1.5.10"), testHost(2, "1.5.10"), testHost(3, "1.5.10"))
+
+	// The filter list can't be edited while the filter is disabled.
+	if _, _, _, err := hdb.UpdateFilter([]types.SiaPublicKey{testKey(1)}, nil, nil, nil); err == nil {
+		t.Fatal("expected an error with the filter disabled")
+	}
+
+	if err := hdb.SetFilterMode(smodules.HostDBActivateBlacklist, []types.SiaPublicKey{testKey(1)}, nil); err != nil {
+		t.Fatal(err)
+	}
+	fm, filtered, _, err := hdb.UpdateFilter([]types.SiaPublicKey{testKey(2)}, []types.SiaPublicKey{testKey(1)}, []string{"example.com"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fm != smodules.HostDBActivateBlacklist {
+		t.Fatal("the filter mode has changed:", fm)
+	}
+	if _, ok := filtered[testKey(2).String()]; !ok || len(filtered) != 1 {
+		t.Fatal("wrong filtered hosts:", filtered)
+	}
+
+	// The removed host isn't filtered anymore.
+	for _, tt := range []struct {
+		b        byte
+		filtered bool
+	}{{1, false}, {2, true}, {3, false}} {
+		host, exists, err := hdb.Host(testKey(tt.b))
+		if err != nil || !exists {
+			t.Fatal("host not found", err)
+		}
+		if host.Filtered != tt.filtered {
+			t.Fatalf("host %v: expected filtered %v", tt.b, tt.filtered)
+		}
+	}
+
+	// Remove the net address.
+	_, _, domains, err := hdb.UpdateFilter(nil, nil, nil, []string{"example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(domains) != 0 {
+		t.Fatal("net address not removed:", domains)
+	}
+}
+
+// TestUpdateFilterConcurrent tests that the concurrent edits of the
+// filter list don't get lost.
+func TestUpdateFilterConcurrent(t *testing.T) {
+	hdb := newTestHostDB(t)
+	if err := hdb.SetFilterMode(smodules.HostDBActivateBlacklist, []types.SiaPublicKey{testKey(255)}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	const edits = 50
+	var wg sync.WaitGroup
+	for i := 0; i < edits; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if _, _, _, err := hdb.UpdateFilter([]types.SiaPublicKey{testKey(byte(i))}, nil, nil, nil); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	_, filtered, _, err := hdb.Filter()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(filtered) != edits + 1 {
+		t.Fatalf("expected %v filtered hosts, got %v", edits + 1, len(filtered))
+	}
+	for i := 0; i < edits; i++ {
+		if _, ok := filtered[testKey(byte(i)).String()]; !ok {
+			t.Fatalf("update %v lost", i)
+		}
+	}
+}
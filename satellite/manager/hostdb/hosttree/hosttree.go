@@ -2,6 +2,8 @@ package hosttree
 
 import (
 	"log"
+	"math/big"
+	"math/rand"
 	"sort"
 	"sync"
 
@@ -305,6 +307,13 @@ func (ht *HostTree) Select(spk types.SiaPublicKey) (modules.HostDBEntry, bool) {
 // intentionally being given a low score to indicate that the host should not be
 // used.
 func (ht *HostTree) SelectRandom(n int, blacklist, addressBlacklist []types.SiaPublicKey) []modules.HostDBEntry {
+	return ht.SelectRandomWithRNG(n, blacklist, addressBlacklist, nil)
+}
+
+// SelectRandomWithRNG works as SelectRandom but draws the random weights
+// from the provided source, so that the same source state yields the same
+// selection. A nil rng means a cryptographically secure source.
+func (ht *HostTree) SelectRandomWithRNG(n int, blacklist, addressBlacklist []types.SiaPublicKey, rng *rand.Rand) []modules.HostDBEntry {
 	ht.mu.Lock()
 	defer ht.mu.Unlock()
 
@@ -340,7 +349,12 @@ func (ht *HostTree) SelectRandom(n int, blacklist, addressBlacklist []types.SiaP
 	var hosts []modules.HostDBEntry
 
 	for len(hosts) < n && len(ht.hosts) > 0 {
-		randWeight := fastrand.BigIntn(ht.root.weight.Big())
+		var randWeight *big.Int
+		if rng != nil {
+			randWeight = new(big.Int).Rand(rng, ht.root.weight.Big())
+		} else {
+			randWeight = fastrand.BigIntn(ht.root.weight.Big())
+		}
 		node := ht.root.nodeAtWeight(types.NewCurrency(randWeight))
 		weightOne := types.NewCurrency64(1)
 
@@ -1,6 +1,9 @@
 package hostdb
 
 import (
+	"math/rand"
+	"sort"
+
 	"github.com/mike76-dev/sia-satellite/satellite/manager/hostdb/hosttree"
 
 	"gitlab.com/NebulousLabs/errors"
@@ -67,6 +70,20 @@ func (hdb *HostDB) RandomHostsWithAllowance(n int, blacklist, addressBlacklist [
 // RandomHostsWithLimits works as RandomHostsWithAllowance but uses the
 // limits set in the allowance instead of calculating the weight function.
 func (hdb *HostDB) RandomHostsWithLimits(n int, blacklist, addressBlacklist []types.SiaPublicKey, allowance modules.Allowance) ([]modules.HostDBEntry, error) {
+	return hdb.randomHostsWithLimits(n, blacklist, addressBlacklist, allowance, nil)
+}
+
+// RandomHostsWithLimitsSeeded works as RandomHostsWithLimits but the
+// selection is made deterministic by the provided seed. Given the same
+// state of the hostdb, the same seed yields the same hosts in the same
+// order.
+func (hdb *HostDB) RandomHostsWithLimitsSeeded(n int, blacklist, addressBlacklist []types.SiaPublicKey, allowance modules.Allowance, seed int64) ([]modules.HostDBEntry, error) {
+	return hdb.randomHostsWithLimits(n, blacklist, addressBlacklist, allowance, rand.New(rand.NewSource(seed)))
+}
+
+// randomHostsWithLimits is the implementation of RandomHostsWithLimits. A
+// nil rng means a random selection.
+func (hdb *HostDB) randomHostsWithLimits(n int, blacklist, addressBlacklist []types.SiaPublicKey, allowance modules.Allowance, rng *rand.Rand) ([]modules.HostDBEntry, error) {
 	hdb.mu.RLock()
 	initialScanComplete := hdb.initialScanComplete
 	filteredHosts := hdb.filteredHosts
@@ -83,6 +100,13 @@ func (hdb *HostDB) RandomHostsWithLimits(n int, blacklist, addressBlacklist []ty
 	defer hdb.mu.RUnlock()
 	var insertErrs error
 	allHosts := hdb.staticHostTree.All()
+	if rng != nil {
+		// The shape of the tree depends on the insertion order, so it has to
+		// be fixed for the selection to be repeatable.
+		sort.Slice(allHosts, func(i, j int) bool {
+			return allHosts[i].PublicKey.String() < allHosts[j].PublicKey.String()
+		})
+	}
 	isWhitelist := filterType == modules.HostDBActiveWhitelist
 	for _, host := range allHosts {
 		// Filter out listed hosts
@@ -101,7 +125,7 @@ func (hdb *HostDB) RandomHostsWithLimits(n int, blacklist, addressBlacklist []ty
 	}
 
 	// Select hosts from the temporary hosttree.
-	return ht.SelectRandom(n, blacklist, addressBlacklist), insertErrs
+	return ht.SelectRandomWithRNG(n, blacklist, addressBlacklist, rng), insertErrs
 }
 
 // limitsExceeded checks if the host falls out of the limits set
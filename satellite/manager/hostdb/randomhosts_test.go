@@ -0,0 +1,58 @@
+package hostdb
+
+import (
+	"testing"
+
+	smodules "go.sia.tech/siad/modules"
+)
+
+// TestRandomHostsWithLimitsSeeded tests that two runs with the same seed
+// select the same hosts in the same order, regardless of the order in
+// which the hosts were added.
+func TestRandomHostsWithLimitsSeeded(t *testing.T) {
+	var hosts, reversed []smodules.HostDBEntry
+	for i := 0; i < 30; i++ {
+		hosts = append(hosts, testHost(byte(i + 1), "1.5.10"))
+	}
+	for i := len(hosts) - 1; i >= 0; i-- {
+		reversed = append(reversed, hosts[i])
+	}
+	hdb1 := newTestHostDB(t, hosts...)
+	hdb2 := newTestHostDB(t, reversed...)
+	hdb1.initialScanComplete = true
+	hdb2.initialScanComplete = true
+
+	a := smodules.DefaultAllowance
+	selected1, err := hdb1.RandomHostsWithLimitsSeeded(10, nil, nil, a, 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	selected2, err := hdb2.RandomHostsWithLimitsSeeded(10, nil, nil, a, 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(selected1) != 10 || len(selected2) != 10 {
+		t.Fatalf("expected 10 hosts, got %v and %v", len(selected1), len(selected2))
+	}
+	for i := range selected1 {
+		if !selected1[i].PublicKey.Equals(selected2[i].PublicKey) {
+			t.Fatalf("selections differ at position %v", i)
+		}
+	}
+
+	// Another seed selects the hosts in another order.
+	other, err := hdb1.RandomHostsWithLimitsSeeded(10, nil, nil, a, 43)
+	if err != nil {
+		t.Fatal(err)
+	}
+	same := true
+	for i := range other {
+		if !other[i].PublicKey.Equals(selected1[i].PublicKey) {
+			same = false
+			break
+		}
+	}
+	if same {
+		t.Fatal("different seeds selected the same hosts in the same order")
+	}
+}
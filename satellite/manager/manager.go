@@ -2,12 +2,14 @@
 package manager
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
 	"sync"
+	"time"
 
 	"github.com/mike76-dev/sia-satellite/modules"
 	"github.com/mike76-dev/sia-satellite/satellite/manager/contractor"
@@ -46,6 +48,47 @@ type hostContractor interface {
 	// Contracts returns the staticContracts of the manager's hostContractor.
 	Contracts() []modules.RenterContract
 
+	// RenterContracts returns the renter's contracts that are not
+	// canceled.
+	RenterContracts(types.SiaPublicKey) []modules.RenterContract
+
+	// DeleteRenter removes the renter from memory.
+	DeleteRenter(types.SiaPublicKey)
+
+	// CheckRenterConsistency compares the renters in the database with the
+	// ones in memory, optionally repairing the differences.
+	CheckRenterConsistency(bool) ([]modules.RenterInconsistency, error)
+
+	// ContractorSettings returns the contractor tunables.
+	ContractorSettings() modules.ContractorSettings
+
+	// SetContractorSettings validates and applies the contractor
+	// tunables.
+	SetContractorSettings(modules.ContractorSettings) error
+
+	// DebugSnapshot returns a copy of the contractor's internal maps.
+	DebugSnapshot() modules.ContractorSnapshot
+
+	// MaintenanceTimings returns the phase timings of the last contract
+	// maintenance cycle.
+	MaintenanceTimings() modules.MaintenanceTimings
+
+	// ApplyHostFilter marks the contracts with the filtered hosts as
+	// !GoodForUpload and !GoodForRenew.
+	ApplyHostFilter() int
+
+	// SetNoRefresh sets whether the contract is excluded from the
+	// refreshes.
+	SetNoRefresh(types.FileContractID, bool) error
+
+	// FormationScore returns the host score of the contract at the time
+	// it was formed.
+	FormationScore(types.FileContractID) (types.Currency, bool)
+
+	// WalletUsage reports how the wallet addresses are used by the
+	// contracts.
+	WalletUsage() (modules.WalletUsage, error)
+
 	// ContractByPublicKeys returns the contract associated with the renter
 	// and the host keys.
 	ContractByPublicKeys(types.SiaPublicKey, types.SiaPublicKey) (modules.RenterContract, bool)
@@ -63,7 +106,11 @@ type hostContractor interface {
 	ContractStatus(fcID types.FileContractID) (smodules.ContractWatchStatus, bool)
 
 	// CreateNewRenter inserts a new renter into the map.
-	CreateNewRenter(string, types.SiaPublicKey)
+	CreateNewRenter(string, string, types.SiaPublicKey)
+
+	// AddRenter creates a new renter with the initial allowance, both in
+	// the database and in memory.
+	AddRenter(string, types.SiaPublicKey, smodules.Allowance) (modules.Renter, error)
 
 	// CurrentPeriod returns the height at which the current allowance period
 	// of the renter began.
@@ -73,8 +120,17 @@ type hostContractor interface {
 	GetRenter(types.SiaPublicKey) (modules.Renter, error)
 
 	// FormContracts forms up to the specified number of contracts, puts them
-	// in the contract set, and returns them.
-	FormContracts(types.SiaPublicKey) ([]modules.RenterContract, error)
+	// in the contract set, and returns them. The preferred hosts are
+	// attempted first.
+	FormContracts(context.Context, types.SiaPublicKey, []types.SiaPublicKey) ([]modules.RenterContract, error)
+
+	// FormContractsWithSpending forms contracts like FormContracts and also
+	// returns the funds spent per host.
+	FormContractsWithSpending(context.Context, types.SiaPublicKey, []types.SiaPublicKey) (modules.FormationResult, error)
+
+	// PreviewContracts returns the hosts that a contract formation with
+	// the given allowance would attempt, without forming any contracts.
+	PreviewContracts(context.Context, types.SiaPublicKey, smodules.Allowance, []types.SiaPublicKey) ([]modules.FormationPreview, error)
 
 	// PeriodSpending returns the amount spent on contracts during the current
 	// billing period of the renter.
@@ -88,6 +144,10 @@ type hostContractor interface {
 	// OldContracts returns the oldContracts of the manager's hostContractor.
 	OldContracts() []modules.RenterContract
 
+	// HostDecision reports which of the contract formation checks the host
+	// passes for the renter's allowance.
+	HostDecision(types.SiaPublicKey, types.SiaPublicKey) (modules.HostDecision, error)
+
 	// IsOffline reports whether the specified host is considered offline.
 	IsOffline(types.SiaPublicKey) bool
 
@@ -97,12 +157,86 @@ type hostContractor interface {
 	// RefreshedContract checks if the contract was previously refreshed.
 	RefreshedContract(fcid types.FileContractID) bool
 
+	// ContractLineage returns the renewal chain of the contract.
+	ContractLineage(types.FileContractID) ([]modules.ContractLineageLink, error)
+
+	// ContractUtilityHistory returns the utility transitions of the
+	// contract.
+	ContractUtilityHistory(types.FileContractID) ([]modules.UtilityTransition, error)
+
+	// WatchdogStatus returns the state of the contract watchdog.
+	WatchdogStatus() modules.WatchdogStatus
+
+	// RenewFailures returns the contracts with failed renewals.
+	RenewFailures() []modules.RenewFailure
+
+	// FundsAtRisk returns the renter funds locked in unhealthy contracts.
+	FundsAtRisk() modules.FundsAtRisk
+
 	// RenewContracts tries to renew the given set of contracts.
 	RenewContracts(types.SiaPublicKey, []types.FileContractID) ([]modules.RenterContract, error)
 
+	// RenewAllDue renews the due contracts of all renters within the given
+	// budget.
+	RenewAllDue(types.Currency) ([]modules.RenewalSummary, error)
+
 	// Renters return the list of renters.
 	Renters() []modules.Renter
 
+	// ContractsDue returns the contracts of all renters that end within the
+	// given number of blocks.
+	ContractsDue(types.BlockHeight) ([]modules.DueContract, error)
+
+	// RenewalFeeBreakdown returns the fees included in the renewal
+	// estimates of the renter's contracts.
+	RenewalFeeBreakdown(types.SiaPublicKey) ([]modules.RenewalFeeBreakdown, error)
+
+	// Runway projects whether the renter's funds last until the end of
+	// the current period.
+	Runway(types.SiaPublicKey) (modules.RenterRunway, error)
+
+	// SetAllowRedundantIPs sets whether the renter's contracts with the hosts
+	// sharing an address range are kept.
+	SetAllowRedundantIPs(types.SiaPublicKey, bool) error
+
+	// SetMaxStorageBytes sets the renter's storage quota.
+	SetMaxStorageBytes(types.SiaPublicKey, uint64) error
+
+	// SetMaxContractsPerRegion sets the renter's region cap.
+	SetMaxContractsPerRegion(types.SiaPublicKey, uint64) error
+
+	// SetPreferCollateral toggles the renter's collateral preference.
+	SetPreferCollateral(types.SiaPublicKey, bool) error
+
+	// SetSpendRateRefresh toggles the renter's refresh sizing strategy.
+	SetSpendRateRefresh(types.SiaPublicKey, bool) error
+
+	// SetMaxHostLatency sets the maximum host response time for the
+	// renter's contract formation.
+	SetMaxHostLatency(types.SiaPublicKey, time.Duration) error
+
+	// SetLocalRegion sets the region where the given fraction of the
+	// renter's contracts is formed.
+	SetLocalRegion(types.SiaPublicKey, string, float64) error
+
+	// SetReuseRefundAddress sets whether the renter's contracts share a
+	// single refund address.
+	SetReuseRefundAddress(types.SiaPublicKey, bool) error
+
+	// HostAllowlist returns the hosts that the renter's contracts are
+	// restricted to.
+	HostAllowlist(types.SiaPublicKey) ([]types.SiaPublicKey, error)
+
+	// SetHostAllowlist restricts the renter's new contracts to the given
+	// hosts. An empty list removes the restriction.
+	SetHostAllowlist(types.SiaPublicKey, []types.SiaPublicKey) error
+
+	// PauseRenter freezes all automated contract activity of the renter.
+	PauseRenter(string) error
+
+	// ResumeRenter restores the automated contract activity of the renter.
+	ResumeRenter(string) error
+
 	// Synced returns a channel that is closed when the contractor is fully
 	// synced with the peer-to-peer network.
 	Synced() <-chan struct{}
@@ -135,7 +269,7 @@ type Manager struct {
 }
 
 // New returns an initialized Manager.
-func New(cs smodules.ConsensusSet, g smodules.Gateway, tpool smodules.TransactionPool, wallet smodules.Wallet, db *sql.DB, mux *siamux.SiaMux, persistDir string) (*Manager, <-chan error) {
+func New(cs smodules.ConsensusSet, g smodules.Gateway, tpool smodules.TransactionPool, wallet smodules.Wallet, db *sql.DB, mux *siamux.SiaMux, persistDir string, logFormat string) (*Manager, <-chan error) {
 	errChan := make(chan error, 1)
 	var err error
 
@@ -147,7 +281,7 @@ func New(cs smodules.ConsensusSet, g smodules.Gateway, tpool smodules.Transactio
 	}
 
 	// Create the Contractor.
-	hc, errChanContractor := contractor.New(cs, wallet, tpool, hdb, db, persistDir)
+	hc, errChanContractor := contractor.New(cs, wallet, tpool, hdb, db, persistDir, logFormat)
 	if err := smodules.PeekErr(errChanContractor); err != nil {
 		errChan <- err
 		return nil, errChan
@@ -272,9 +406,29 @@ func (m *Manager) SetFilterMode(lm smodules.FilterMode, hosts []types.SiaPublicK
 		return err
 	}
 
+	// Churn the contracts with the newly filtered hosts right away.
+	m.hostContractor.ApplyHostFilter()
+
 	return nil
 }
 
+// UpdateFilter atomically updates the hostdb filter list.
+func (m *Manager) UpdateFilter(addHosts, removeHosts []types.SiaPublicKey, addNetAddresses, removeNetAddresses []string) (smodules.FilterMode, map[string]types.SiaPublicKey, []string, error) {
+	if err := m.threads.Add(); err != nil {
+		return smodules.HostDBFilterError, nil, nil, err
+	}
+	defer m.threads.Done()
+	fm, hosts, netAddresses, err := m.hostDB.UpdateFilter(addHosts, removeHosts, addNetAddresses, removeNetAddresses)
+	if err != nil {
+		return fm, hosts, netAddresses, err
+	}
+
+	// Churn the contracts with the newly filtered hosts right away.
+	m.hostContractor.ApplyHostFilter()
+
+	return fm, hosts, netAddresses, nil
+}
+
 // Host returns the host associated with the given public key.
 func (m *Manager) Host(spk types.SiaPublicKey) (smodules.HostDBEntry, bool, error) {
 	return m.hostDB.Host(spk)
@@ -312,11 +466,46 @@ func (m *Manager) Contracts() []modules.RenterContract {
 	return m.hostContractor.Contracts()
 }
 
+// DeleteRenter calls hostContractor.DeleteRenter.
+func (m *Manager) DeleteRenter(rpk types.SiaPublicKey) {
+	m.hostContractor.DeleteRenter(rpk)
+}
+
+// RenterContracts calls hostContractor.RenterContracts.
+func (m *Manager) RenterContracts(rpk types.SiaPublicKey) []modules.RenterContract {
+	return m.hostContractor.RenterContracts(rpk)
+}
+
 // RefreshedContract calls hostContractor.RefreshedContract
 func (m *Manager) RefreshedContract(fcid types.FileContractID) bool {
 	return m.hostContractor.RefreshedContract(fcid)
 }
 
+// ContractLineage calls hostContractor.ContractLineage.
+func (m *Manager) ContractLineage(fcid types.FileContractID) ([]modules.ContractLineageLink, error) {
+	return m.hostContractor.ContractLineage(fcid)
+}
+
+// ContractUtilityHistory calls hostContractor.ContractUtilityHistory.
+func (m *Manager) ContractUtilityHistory(fcid types.FileContractID) ([]modules.UtilityTransition, error) {
+	return m.hostContractor.ContractUtilityHistory(fcid)
+}
+
+// WatchdogStatus calls hostContractor.WatchdogStatus.
+func (m *Manager) WatchdogStatus() modules.WatchdogStatus {
+	return m.hostContractor.WatchdogStatus()
+}
+
+// RenewFailures calls hostContractor.RenewFailures.
+func (m *Manager) RenewFailures() []modules.RenewFailure {
+	return m.hostContractor.RenewFailures()
+}
+
+// FundsAtRisk calls hostContractor.FundsAtRisk.
+func (m *Manager) FundsAtRisk() modules.FundsAtRisk {
+	return m.hostContractor.FundsAtRisk()
+}
+
 // OldContracts calls hostContractor.OldContracts expired.
 func (m *Manager) OldContracts() []modules.RenterContract {
 	return m.hostContractor.OldContracts()
@@ -529,13 +718,28 @@ func (m *Manager) GetRenter(rpk types.SiaPublicKey) (modules.Renter, error) {
 }
 
 // CreateNewRenter calls hostContractor.CreateNewRenter.
-func (m *Manager) CreateNewRenter(email string, pk types.SiaPublicKey) {
-	m.hostContractor.CreateNewRenter(email, pk)
+func (m *Manager) CreateNewRenter(email, suffix string, pk types.SiaPublicKey) {
+	m.hostContractor.CreateNewRenter(email, suffix, pk)
+}
+
+// AddRenter calls hostContractor.AddRenter.
+func (m *Manager) AddRenter(email string, pk types.SiaPublicKey, a smodules.Allowance) (modules.Renter, error) {
+	return m.hostContractor.AddRenter(email, pk, a)
 }
 
 // FormContracts calls hostContractor.FormContracts.
-func (m *Manager) FormContracts(rpk types.SiaPublicKey) ([]modules.RenterContract, error) {
-	return m.hostContractor.FormContracts(rpk)
+func (m *Manager) FormContracts(ctx context.Context, rpk types.SiaPublicKey, preferred []types.SiaPublicKey) ([]modules.RenterContract, error) {
+	return m.hostContractor.FormContracts(ctx, rpk, preferred)
+}
+
+// FormContractsWithSpending calls hostContractor.FormContractsWithSpending.
+func (m *Manager) FormContractsWithSpending(ctx context.Context, rpk types.SiaPublicKey, preferred []types.SiaPublicKey) (modules.FormationResult, error) {
+	return m.hostContractor.FormContractsWithSpending(ctx, rpk, preferred)
+}
+
+// PreviewContracts calls hostContractor.PreviewContracts.
+func (m *Manager) PreviewContracts(ctx context.Context, rpk types.SiaPublicKey, a smodules.Allowance, preferred []types.SiaPublicKey) ([]modules.FormationPreview, error) {
+	return m.hostContractor.PreviewContracts(ctx, rpk, a, preferred)
 }
 
 // RenewContracts calls hostContractor.RenewContracts.
@@ -543,11 +747,136 @@ func (m *Manager) RenewContracts(rpk types.SiaPublicKey, contracts []types.FileC
 	return m.hostContractor.RenewContracts(rpk, contracts)
 }
 
+// RenewAllDue calls hostContractor.RenewAllDue.
+func (m *Manager) RenewAllDue(maxSpend types.Currency) ([]modules.RenewalSummary, error) {
+	return m.hostContractor.RenewAllDue(maxSpend)
+}
+
+// ContractsDue calls hostContractor.ContractsDue.
+func (m *Manager) ContractsDue(within types.BlockHeight) ([]modules.DueContract, error) {
+	return m.hostContractor.ContractsDue(within)
+}
+
+// RenewalFeeBreakdown calls hostContractor.RenewalFeeBreakdown.
+func (m *Manager) RenewalFeeBreakdown(rpk types.SiaPublicKey) ([]modules.RenewalFeeBreakdown, error) {
+	return m.hostContractor.RenewalFeeBreakdown(rpk)
+}
+
+// Runway calls hostContractor.Runway.
+func (m *Manager) Runway(rpk types.SiaPublicKey) (modules.RenterRunway, error) {
+	return m.hostContractor.Runway(rpk)
+}
+
 // Renters calls hostContractor.Renters.
 func (m *Manager) Renters() []modules.Renter {
 	return m.hostContractor.Renters()
 }
 
+// CheckRenterConsistency calls hostContractor.CheckRenterConsistency.
+func (m *Manager) CheckRenterConsistency(repair bool) ([]modules.RenterInconsistency, error) {
+	return m.hostContractor.CheckRenterConsistency(repair)
+}
+
+// ContractorSettings calls hostContractor.ContractorSettings.
+func (m *Manager) ContractorSettings() modules.ContractorSettings {
+	return m.hostContractor.ContractorSettings()
+}
+
+// SetContractorSettings calls hostContractor.SetContractorSettings.
+func (m *Manager) SetContractorSettings(s modules.ContractorSettings) error {
+	return m.hostContractor.SetContractorSettings(s)
+}
+
+// DebugSnapshot calls hostContractor.DebugSnapshot.
+func (m *Manager) DebugSnapshot() modules.ContractorSnapshot {
+	return m.hostContractor.DebugSnapshot()
+}
+
+// MaintenanceTimings calls hostContractor.MaintenanceTimings.
+func (m *Manager) MaintenanceTimings() modules.MaintenanceTimings {
+	return m.hostContractor.MaintenanceTimings()
+}
+
+// SetNoRefresh calls hostContractor.SetNoRefresh.
+func (m *Manager) SetNoRefresh(id types.FileContractID, noRefresh bool) error {
+	return m.hostContractor.SetNoRefresh(id, noRefresh)
+}
+
+// FormationScore calls hostContractor.FormationScore.
+func (m *Manager) FormationScore(id types.FileContractID) (types.Currency, bool) {
+	return m.hostContractor.FormationScore(id)
+}
+
+// WalletUsage calls hostContractor.WalletUsage.
+func (m *Manager) WalletUsage() (modules.WalletUsage, error) {
+	return m.hostContractor.WalletUsage()
+}
+
+// HostDecision calls hostContractor.HostDecision.
+func (m *Manager) HostDecision(rpk, hpk types.SiaPublicKey) (modules.HostDecision, error) {
+	return m.hostContractor.HostDecision(rpk, hpk)
+}
+
+// SetAllowRedundantIPs calls hostContractor.SetAllowRedundantIPs.
+func (m *Manager) SetAllowRedundantIPs(rpk types.SiaPublicKey, allow bool) error {
+	return m.hostContractor.SetAllowRedundantIPs(rpk, allow)
+}
+
+// SetMaxStorageBytes calls hostContractor.SetMaxStorageBytes.
+func (m *Manager) SetMaxStorageBytes(rpk types.SiaPublicKey, maxStorage uint64) error {
+	return m.hostContractor.SetMaxStorageBytes(rpk, maxStorage)
+}
+
+// SetMaxContractsPerRegion calls hostContractor.SetMaxContractsPerRegion.
+func (m *Manager) SetMaxContractsPerRegion(rpk types.SiaPublicKey, maxContracts uint64) error {
+	return m.hostContractor.SetMaxContractsPerRegion(rpk, maxContracts)
+}
+
+// SetPreferCollateral calls hostContractor.SetPreferCollateral.
+func (m *Manager) SetPreferCollateral(rpk types.SiaPublicKey, prefer bool) error {
+	return m.hostContractor.SetPreferCollateral(rpk, prefer)
+}
+
+// SetSpendRateRefresh calls hostContractor.SetSpendRateRefresh.
+func (m *Manager) SetSpendRateRefresh(rpk types.SiaPublicKey, enabled bool) error {
+	return m.hostContractor.SetSpendRateRefresh(rpk, enabled)
+}
+
+// SetMaxHostLatency calls hostContractor.SetMaxHostLatency.
+func (m *Manager) SetMaxHostLatency(rpk types.SiaPublicKey, latency time.Duration) error {
+	return m.hostContractor.SetMaxHostLatency(rpk, latency)
+}
+
+// SetLocalRegion calls hostContractor.SetLocalRegion.
+func (m *Manager) SetLocalRegion(rpk types.SiaPublicKey, region string, fraction float64) error {
+	return m.hostContractor.SetLocalRegion(rpk, region, fraction)
+}
+
+// SetReuseRefundAddress calls hostContractor.SetReuseRefundAddress.
+func (m *Manager) SetReuseRefundAddress(rpk types.SiaPublicKey, enabled bool) error {
+	return m.hostContractor.SetReuseRefundAddress(rpk, enabled)
+}
+
+// HostAllowlist calls hostContractor.HostAllowlist.
+func (m *Manager) HostAllowlist(rpk types.SiaPublicKey) ([]types.SiaPublicKey, error) {
+	return m.hostContractor.HostAllowlist(rpk)
+}
+
+// SetHostAllowlist calls hostContractor.SetHostAllowlist.
+func (m *Manager) SetHostAllowlist(rpk types.SiaPublicKey, hosts []types.SiaPublicKey) error {
+	return m.hostContractor.SetHostAllowlist(rpk, hosts)
+}
+
+// PauseRenter calls hostContractor.PauseRenter.
+func (m *Manager) PauseRenter(email string) error {
+	return m.hostContractor.PauseRenter(email)
+}
+
+// ResumeRenter calls hostContractor.ResumeRenter.
+func (m *Manager) ResumeRenter(email string) error {
+	return m.hostContractor.ResumeRenter(email)
+}
+
 // SetSatellite sets the satellite dependency of the contractor.
 func (m *Manager) SetSatellite(fl modules.FundLocker) {
 	m.hostContractor.SetSatellite(fl)
@@ -0,0 +1,104 @@
+package proto
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/mike76-dev/sia-satellite/internal/dbtest"
+	"github.com/mike76-dev/sia-satellite/modules"
+
+	"golang.org/x/crypto/ed25519"
+
+	"go.sia.tech/siad/crypto"
+	smodules "go.sia.tech/siad/modules"
+	"go.sia.tech/siad/persist"
+	"go.sia.tech/siad/types"
+)
+
+// newTestContractSet returns an empty contract set backed by a test
+// database.
+func newTestContractSet(t *testing.T) *ContractSet {
+	t.Helper()
+	db, _ := dbtest.Open()
+	logger, err := persist.NewFileLogger(filepath.Join(t.TempDir(), "proto.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { logger.Close() })
+	cs, err := NewContractSet(db, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cs
+}
+
+// testHeader returns the header of a contract between the renter and the
+// host.
+func testHeader(rpk, hpk types.SiaPublicKey, id byte) contractHeader {
+	rev := types.FileContractRevision{
+		UnlockConditions: types.UnlockConditions{
+			PublicKeys:         []types.SiaPublicKey{rpk, hpk},
+			SignaturesRequired: 2,
+		},
+		NewValidProofOutputs: []types.SiacoinOutput{
+			{Value: types.SiacoinPrecision},
+			{Value: types.ZeroCurrency},
+		},
+		NewMissedProofOutputs: []types.SiacoinOutput{
+			{Value: types.SiacoinPrecision},
+			{Value: types.ZeroCurrency},
+			{Value: types.ZeroCurrency},
+		},
+	}
+	rev.ParentID[0] = id
+	return contractHeader{
+		Transaction: types.Transaction{
+			FileContractRevisions: []types.FileContractRevision{rev},
+		},
+	}
+}
+
+// renterKey returns the public key of the renter identity.
+func renterKey(walletSeed smodules.Seed, email, suffix string) types.SiaPublicKey {
+	renterSeed := modules.DeriveRenterSeed(walletSeed, email, suffix)
+	var sk crypto.SecretKey
+	copy(sk[:], ed25519.NewKeyFromSeed(renterSeed[:]))
+	return types.Ed25519PublicKey(sk.PublicKey())
+}
+
+// TestContractSetIdentities tests that the two identities under one email
+// keep separate contract sets.
+func TestContractSetIdentities(t *testing.T) {
+	var walletSeed smodules.Seed
+	walletSeed[0] = 1
+	personal := renterKey(walletSeed, "user@example.com", "")
+	work := renterKey(walletSeed, "user@example.com", "work")
+	if personal.String() == work.String() {
+		t.Fatal("two identities share the renter key")
+	}
+
+	var host types.SiaPublicKey
+	host.Algorithm = types.SignatureEd25519
+	host.Key = make([]byte, 32)
+
+	cs := newTestContractSet(t)
+	if _, err := cs.managedInsertContract(testHeader(personal, host, 1)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cs.managedInsertContract(testHeader(work, host, 2)); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, rpk := range []types.SiaPublicKey{personal, work} {
+		contracts := cs.ByRenter(rpk)
+		if len(contracts) != 1 {
+			t.Fatalf("expected one contract, got %v", len(contracts))
+		}
+		if contracts[0].RenterPublicKey.String() != rpk.String() {
+			t.Fatal("contract of another identity returned")
+		}
+	}
+	if len(cs.IDs(personal)) != 1 || len(cs.IDs(work)) != 1 {
+		t.Fatal("the contract IDs are shared between the identities")
+	}
+}
@@ -0,0 +1,75 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	core "go.sia.tech/core/types"
+	"go.sia.tech/siad/crypto"
+	"go.sia.tech/siad/types"
+)
+
+// errFormationNotFound is returned when there is no contract formation in
+// progress matching a cancel request.
+var errFormationNotFound = errors.New("no such contract formation in progress")
+
+// formationKey returns the key of a contract formation requested by the
+// renter. The request is identified by its ID.
+func formationKey(rpk types.SiaPublicKey, id core.Hash256) string {
+	return rpk.String() + ":" + id.String()
+}
+
+// managedTrackFormation registers a contract formation in progress and
+// returns the context to pass to the formation, along with a function to
+// call when the formation is over.
+func (p *Provider) managedTrackFormation(rpk types.SiaPublicKey, id core.Hash256) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	key := formationKey(rpk, id)
+	p.mu.Lock()
+	p.formations[key] = cancel
+	p.mu.Unlock()
+	return ctx, func() {
+		p.mu.Lock()
+		delete(p.formations, key)
+		p.mu.Unlock()
+		cancel()
+	}
+}
+
+// managedCancelFormation cancels a contract formation in progress. The
+// contracts formed so far are kept and returned to the renter by the
+// formation RPC.
+func (p *Provider) managedCancelFormation(s *rpcSession) error {
+	// Read the request.
+	var cr cancelRequest
+	hash, err := s.readRequest(&cr, 1024)
+	if err != nil {
+		return fmt.Errorf("could not read renter request: %v", err)
+	}
+
+	// Verify the signature.
+	err = s.verifyRequest(hash, cr.PubKey, cr.Signature)
+	if err != nil {
+		return fmt.Errorf("could not verify renter signature: %v", err)
+	}
+
+	// Look up the formation.
+	rpk := types.Ed25519PublicKey(crypto.PublicKey(cr.PubKey))
+	key := formationKey(rpk, cr.RequestID)
+	p.mu.Lock()
+	cancel, exists := p.formations[key]
+	p.mu.Unlock()
+	if !exists {
+		if err := s.writeError(errFormationNotFound); err != nil {
+			return fmt.Errorf("could not send error to renter: %v", err)
+		}
+		return fmt.Errorf("renter %v: %v", rpk.String(), errFormationNotFound)
+	}
+
+	// Signal the cancellation.
+	cancel()
+	p.log.Printf("INFO: renter %v canceled contract formation %v\n", rpk.String(), cr.RequestID)
+
+	return s.writeResponse(&cancelResponse{Canceled: true})
+}
@@ -0,0 +1,46 @@
+package provider
+
+import (
+	"context"
+	"testing"
+
+	core "go.sia.tech/core/types"
+	"go.sia.tech/siad/crypto"
+	"go.sia.tech/siad/types"
+)
+
+// TestTrackFormation tests that a tracked formation is canceled through
+// its key, and that it is untracked once it is over.
+func TestTrackFormation(t *testing.T) {
+	p := &Provider{formations: make(map[string]context.CancelFunc)}
+	var pk crypto.PublicKey
+	pk[0] = 1
+	rpk := types.Ed25519PublicKey(pk)
+	id, other := core.Hash256{1}, core.Hash256{2}
+
+	ctx, done := p.managedTrackFormation(rpk, id)
+	otherCtx, otherDone := p.managedTrackFormation(rpk, other)
+	defer otherDone()
+
+	p.mu.Lock()
+	cancel, exists := p.formations[formationKey(rpk, id)]
+	p.mu.Unlock()
+	if !exists {
+		t.Fatal("formation not tracked")
+	}
+	cancel()
+	if ctx.Err() == nil {
+		t.Fatal("formation not canceled")
+	}
+	if otherCtx.Err() != nil {
+		t.Fatal("another formation of the renter was canceled")
+	}
+
+	done()
+	p.mu.Lock()
+	_, exists = p.formations[formationKey(rpk, id)]
+	p.mu.Unlock()
+	if exists {
+		t.Fatal("formation still tracked after it is over")
+	}
+}
@@ -8,6 +8,45 @@ import (
 // has to form contracts with the hosts.
 const formContractsTime = 10 * time.Minute
 
+// defaultMaxFormationExtension is the default longest extension of the
+// formation deadline that the provider grants to a renter.
+const defaultMaxFormationExtension = 5 * time.Minute
+
+// formationExtensionNotice is how long before the deadline the provider
+// decides whether to extend it.
+const formationExtensionNotice = time.Minute
+
+// formationExtensionThreshold is the fraction of the requested contracts
+// that need to be formed for the deadline to be extended.
+const formationExtensionThreshold = 0.75
+
 // renewContractsTime defines the amount of time that the provider
 // has to renew a set of contracts.
 const renewContractsTime = 10 * time.Minute
+
+// compressionThreshold is the minimum number of contracts in a set for
+// the set to be compressed, if the renter supports compression.
+const compressionThreshold = 10
+
+// maxContractSetSize is the maximum number of contracts in a set that can
+// be decoded.
+const maxContractSetSize = 1000
+
+// maxDecompressedSize is the maximum size of a decompressed contract set.
+const maxDecompressedSize = 1 << 24
+
+// defaultRPCRate is the default number of requests per minute a single
+// renter is allowed to make.
+const defaultRPCRate = 6
+
+// rpcBurst is the number of requests a renter can make in a row before
+// being rate limited.
+const rpcBurst = 5
+
+// formationResultTTL is how long the result of a contract formation is
+// kept for the retries of the same request.
+const formationResultTTL = 30 * time.Minute
+
+// rateLimiterPruneInterval defines how often the idle renters are removed
+// from the rate limiter.
+const rateLimiterPruneInterval = 10 * time.Minute
@@ -1,32 +1,60 @@
 package provider
 
 import (
+	"bytes"
+	"compress/flate"
+	"errors"
+	"io"
+
 	rhpv2 "go.sia.tech/core/rhp/v2"
 	"go.sia.tech/core/types"
 	"go.sia.tech/siad/crypto"
 )
 
 var (
-	// Handshake specifier.
-	loopEnterSpecifier = types.NewSpecifier("LoopEnter")
+	// Handshake specifiers. A renter entering with loopEnterV2Specifier
+	// lists its capabilities after the ciphers, and the provider replies
+	// with the ones it accepts.
+	loopEnterSpecifier   = types.NewSpecifier("LoopEnter")
+	loopEnterV2Specifier = types.NewSpecifier("LoopEnterV2")
 
 	// RPC ciphers.
 	cipherChaCha20Poly1305 = types.NewSpecifier("ChaCha20Poly1305")
 	cipherNoOverlap        = types.NewSpecifier("NoOverlap")
+
+	// capabilityFlate is advertised by the renter if it can accept
+	// compressed contract sets.
+	capabilityFlate = types.NewSpecifier("Flate")
+
+	// capabilityChallenge is advertised by the renter if it signs the
+	// requests together with the session challenge.
+	capabilityChallenge = types.NewSpecifier("SignChallenge")
 )
 
+// maxCapabilities is the maximum number of capabilities in a handshake
+// request.
+const maxCapabilities = 16
+
 // Handshake objects
 type (
 	loopKeyExchangeRequest struct {
 		Specifier types.Specifier
 		PublicKey [32]byte
 		Ciphers   []types.Specifier
+
+		// Capabilities are only sent with loopEnterV2Specifier.
+		Capabilities []types.Specifier
 	}
 
 	loopKeyExchangeResponse struct {
 		PublicKey [32]byte
 		Signature types.Signature
 		Cipher    types.Specifier
+
+		// Capabilities are the accepted capabilities. They are only sent
+		// if v2 is set.
+		Capabilities []types.Specifier
+		v2           bool
 	}
 )
 
@@ -43,6 +71,28 @@ func (r *loopKeyExchangeRequest) DecodeFrom(d *types.Decoder) {
 	for i := range r.Ciphers {
 		r.Ciphers[i].DecodeFrom(d)
 	}
+	if r.Specifier != loopEnterV2Specifier {
+		return
+	}
+	n := d.ReadPrefix()
+	if n > maxCapabilities {
+		d.SetErr(errors.New("too many capabilities"))
+		return
+	}
+	r.Capabilities = make([]types.Specifier, n)
+	for i := range r.Capabilities {
+		r.Capabilities[i].DecodeFrom(d)
+	}
+}
+
+// supports returns true if the renter has advertised the capability.
+func (r *loopKeyExchangeRequest) supports(capability types.Specifier) bool {
+	for _, c := range r.Capabilities {
+		if c == capability {
+			return true
+		}
+	}
+	return false
 }
 
 // EncodeTo implements types.ProtocolObject.
@@ -50,6 +100,12 @@ func (r *loopKeyExchangeResponse) EncodeTo(e *types.Encoder) {
 	e.Write(r.PublicKey[:])
 	e.WriteBytes(r.Signature[:])
 	r.Cipher.EncodeTo(e)
+	if r.v2 {
+		e.WritePrefix(len(r.Capabilities))
+		for _, c := range r.Capabilities {
+			c.EncodeTo(e)
+		}
+	}
 }
 
 // DecodeFrom implements types.ProtocolObject.
@@ -63,6 +119,14 @@ type requestBody interface {
 	EncodeTo(e *types.Encoder)
 }
 
+// optionalDecoder is implemented by the requests that have optional
+// trailing fields. decodeOptional is only called if the request has more
+// data after the mandatory fields. more reports if there is still data left
+// to decode.
+type optionalDecoder interface {
+	decodeOptional(d *types.Decoder, more func() bool)
+}
+
 // formRequest is used when the renter requests forming contracts with
 // the hosts.
 type formRequest struct {
@@ -86,6 +150,23 @@ type formRequest struct {
 	MaxSectorAccessPrice types.Currency
 
 	Signature types.Signature
+
+	// PreferredHosts is optional and follows the signature, so that
+	// the requests of older renters remain valid.
+	PreferredHosts []types.PublicKey
+
+	// RequestID is optional and follows PreferredHosts. If set, it
+	// identifies the request instead of its hash, so that a renter
+	// retrying a formation after a dropped connection receives the
+	// contracts formed by the first attempt.
+	RequestID types.Hash256
+
+	// MaxExtension is optional and follows RequestID. It is the longest
+	// extension of the formation deadline, in seconds, that the renter
+	// is willing to wait for. If set, the renter receives a
+	// formationExtension before the formation response. An extension
+	// longer than the provider's maximum is never granted.
+	MaxExtension uint64
 }
 
 // DecodeFrom implements requestBody.
@@ -108,6 +189,20 @@ func (fr *formRequest) DecodeFrom(d *types.Decoder) {
 	fr.Signature.DecodeFrom(d)
 }
 
+// decodeOptional implements optionalDecoder.
+func (fr *formRequest) decodeOptional(d *types.Decoder, more func() bool) {
+	fr.PreferredHosts = make([]types.PublicKey, d.ReadPrefix())
+	for i := range fr.PreferredHosts {
+		fr.PreferredHosts[i].DecodeFrom(d)
+	}
+	if more() {
+		fr.RequestID.DecodeFrom(d)
+	}
+	if more() {
+		fr.MaxExtension = d.ReadUint64()
+	}
+}
+
 // EncodeTo implements requestBody.
 func (fr *formRequest) EncodeTo(e *types.Encoder) {
 	e.WriteBytes(fr.PubKey[:])
@@ -125,6 +220,33 @@ func (fr *formRequest) EncodeTo(e *types.Encoder) {
 	fr.MaxUploadPrice.EncodeTo(e)
 	fr.MaxStoragePrice.EncodeTo(e)
 	fr.MaxSectorAccessPrice.EncodeTo(e)
+	if len(fr.PreferredHosts) > 0 || fr.RequestID != (types.Hash256{}) || fr.MaxExtension > 0 {
+		e.WritePrefix(len(fr.PreferredHosts))
+		for _, pk := range fr.PreferredHosts {
+			pk.EncodeTo(e)
+		}
+	}
+	if fr.RequestID != (types.Hash256{}) || fr.MaxExtension > 0 {
+		fr.RequestID.EncodeTo(e)
+	}
+	if fr.MaxExtension > 0 {
+		e.WriteUint64(fr.MaxExtension)
+	}
+}
+
+// id returns the ID of the request: RequestID if set, the hash of the
+// request otherwise.
+func (fr *formRequest) id(hash types.Hash256) types.Hash256 {
+	if fr.RequestID != (types.Hash256{}) {
+		return fr.RequestID
+	}
+	return hash
+}
+
+// idempotent returns true if the request carries a RequestID, so that its
+// retries are answered with the result of the first attempt.
+func (fr *formRequest) idempotent() bool {
+	return fr.RequestID != (types.Hash256{})
 }
 
 // renewRequest is used when the renter requests contract renewals.
@@ -197,13 +319,16 @@ func (rr *renewRequest) EncodeTo(e *types.Encoder) {
 	rr.MaxSectorAccessPrice.EncodeTo(e)
 }
 
-// contractSet is a collection of rhpv2.ContractRevision objects.
+// contractSet is a collection of rhpv2.ContractRevision objects. If
+// compression was negotiated during the handshake, a flag precedes the set
+// that indicates whether the set is compressed or not.
 type contractSet struct {
-	contracts []rhpv2.ContractRevision
+	contracts   []rhpv2.ContractRevision
+	compression bool
 }
 
-// EncodeTo implements requestBody.
-func (cs contractSet) EncodeTo(e *types.Encoder) {
+// encodeContracts writes the plain contract set.
+func (cs *contractSet) encodeContracts(e *types.Encoder) {
 	e.WriteUint64(uint64(len(cs.contracts)))
 	for _, cr := range cs.contracts {
 		cr.Revision.EncodeTo(e)
@@ -212,7 +337,171 @@ func (cs contractSet) EncodeTo(e *types.Encoder) {
 	}
 }
 
+// decodeContracts reads the plain contract set.
+func (cs *contractSet) decodeContracts(d *types.Decoder) {
+	num := d.ReadUint64()
+	if num > maxContractSetSize {
+		d.SetErr(errors.New("contract set too large"))
+		return
+	}
+	cs.contracts = make([]rhpv2.ContractRevision, num)
+	for i := range cs.contracts {
+		cs.contracts[i].Revision.DecodeFrom(d)
+		cs.contracts[i].Signatures[0].DecodeFrom(d)
+		cs.contracts[i].Signatures[1].DecodeFrom(d)
+	}
+}
+
+// EncodeTo implements requestBody.
+func (cs *contractSet) EncodeTo(e *types.Encoder) {
+	if !cs.compression {
+		cs.encodeContracts(e)
+		return
+	}
+
+	// Small sets are not worth compressing.
+	compress := len(cs.contracts) >= compressionThreshold
+	e.WriteBool(compress)
+	if !compress {
+		cs.encodeContracts(e)
+		return
+	}
+
+	var buf bytes.Buffer
+	fw, _ := flate.NewWriter(&buf, flate.DefaultCompression)
+	ce := types.NewEncoder(fw)
+	cs.encodeContracts(ce)
+	ce.Flush()
+	fw.Close()
+	e.WriteBytes(buf.Bytes())
+}
+
 // DecodeFrom implements requestBody.
-func (cs contractSet) DecodeFrom(d *types.Decoder) {
-	// Nothing to do here.
+func (cs *contractSet) DecodeFrom(d *types.Decoder) {
+	if !cs.compression || !d.ReadBool() {
+		cs.decodeContracts(d)
+		return
+	}
+
+	b := d.ReadBytes()
+	if d.Err() != nil {
+		return
+	}
+	fr := flate.NewReader(bytes.NewReader(b))
+	defer fr.Close()
+	cd := types.NewDecoder(io.LimitedReader{R: fr, N: maxDecompressedSize})
+	cs.decodeContracts(cd)
+	if err := cd.Err(); err != nil {
+		d.SetErr(err)
+	}
+}
+
+// hostPreview is a host that the provider intends to form a contract with,
+// together with the projected contract funding.
+type hostPreview struct {
+	PublicKey types.PublicKey
+	Funding   types.Currency
+}
+
+// formationPreview is the response to a formation preview request.
+type formationPreview struct {
+	hosts []hostPreview
+}
+
+// EncodeTo implements requestBody.
+func (fp *formationPreview) EncodeTo(e *types.Encoder) {
+	e.WriteUint64(uint64(len(fp.hosts)))
+	for _, h := range fp.hosts {
+		h.PublicKey.EncodeTo(e)
+		h.Funding.EncodeTo(e)
+	}
+}
+
+// DecodeFrom implements requestBody.
+func (fp *formationPreview) DecodeFrom(d *types.Decoder) {
+	num := d.ReadUint64()
+	if num > maxContractSetSize {
+		d.SetErr(errors.New("formation preview too large"))
+		return
+	}
+	fp.hosts = make([]hostPreview, num)
+	for i := range fp.hosts {
+		fp.hosts[i].PublicKey.DecodeFrom(d)
+		fp.hosts[i].Funding.DecodeFrom(d)
+	}
+}
+
+// cancelRequest is used when the renter requests to cancel a contract
+// formation in progress. RequestID is the RequestID of the formRequest if
+// it has one, or the hash of the formRequest otherwise.
+type cancelRequest struct {
+	PubKey    crypto.PublicKey
+	RequestID types.Hash256
+
+	Signature types.Signature
+}
+
+// DecodeFrom implements requestBody.
+func (cr *cancelRequest) DecodeFrom(d *types.Decoder) {
+	copy(cr.PubKey[:], d.ReadBytes())
+	cr.RequestID.DecodeFrom(d)
+	cr.Signature.DecodeFrom(d)
+}
+
+// EncodeTo implements requestBody.
+func (cr *cancelRequest) EncodeTo(e *types.Encoder) {
+	e.WriteBytes(cr.PubKey[:])
+	cr.RequestID.EncodeTo(e)
+}
+
+// cancelResponse is the response to a cancel request.
+type cancelResponse struct {
+	Canceled bool
+}
+
+// EncodeTo implements requestBody.
+func (cr *cancelResponse) EncodeTo(e *types.Encoder) {
+	e.WriteBool(cr.Canceled)
+}
+
+// DecodeFrom implements requestBody.
+func (cr *cancelResponse) DecodeFrom(d *types.Decoder) {
+	cr.Canceled = d.ReadBool()
+}
+
+// formationExtension is sent to the renters that allow extending the
+// formation deadline, right before the formation response or as soon as
+// the extension is granted. Seconds is the granted extension, zero if
+// none.
+type formationExtension struct {
+	Seconds uint64
+}
+
+// EncodeTo implements requestBody.
+func (fe *formationExtension) EncodeTo(e *types.Encoder) {
+	e.WriteUint64(fe.Seconds)
+}
+
+// DecodeFrom implements requestBody.
+func (fe *formationExtension) DecodeFrom(d *types.Decoder) {
+	fe.Seconds = d.ReadUint64()
+}
+
+// contractsRequest is used when the renter requests the set of its
+// contracts.
+type contractsRequest struct {
+	PubKey crypto.PublicKey
+
+	Signature types.Signature
+}
+
+// DecodeFrom implements requestBody.
+func (cr *contractsRequest) DecodeFrom(d *types.Decoder) {
+	copy(cr.PubKey[:], d.ReadBytes())
+	cr.Signature.DecodeFrom(d)
+}
+
+// EncodeTo implements requestBody.
+func (cr *contractsRequest) EncodeTo(e *types.Encoder) {
+	e.WriteBytes(cr.PubKey[:])
 }
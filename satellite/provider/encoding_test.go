@@ -0,0 +1,334 @@
+package provider
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"net"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/chacha20poly1305"
+
+	rhpv2 "go.sia.tech/core/rhp/v2"
+	"go.sia.tech/core/types"
+	"go.sia.tech/siad/crypto"
+	stypes "go.sia.tech/siad/types"
+)
+
+// testContractSet returns a contract set with n distinct revisions.
+func testContractSet(n int, compression bool) contractSet {
+	cs := contractSet{compression: compression}
+	for i := 0; i < n; i++ {
+		var cr rhpv2.ContractRevision
+		cr.Revision.ParentID[0] = byte(i)
+		cr.Revision.RevisionNumber = uint64(i)
+		cr.Revision.Filesize = uint64(i) * 4096
+		cr.Revision.ValidProofOutputs = []types.SiacoinOutput{
+			{Value: types.Siacoins(uint32(i))},
+			{Value: types.ZeroCurrency},
+		}
+		cr.Signatures[0].ParentID = types.Hash256(cr.Revision.ParentID)
+		cr.Signatures[1].PublicKeyIndex = 1
+		cs.contracts = append(cs.contracts, cr)
+	}
+	return cs
+}
+
+// roundTrip encodes the contract set and decodes it again.
+func roundTrip(t *testing.T, cs contractSet) (contractSet, int) {
+	t.Helper()
+	var buf bytes.Buffer
+	e := types.NewEncoder(&buf)
+	cs.EncodeTo(e)
+	e.Flush()
+	size := buf.Len()
+
+	decoded := contractSet{compression: cs.compression}
+	d := types.NewBufDecoder(buf.Bytes())
+	decoded.DecodeFrom(d)
+	if err := d.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return decoded, size
+}
+
+// TestContractSetRoundTrip tests that the contract sets survive the
+// encoding with and without compression.
+func TestContractSetRoundTrip(t *testing.T) {
+	tests := []struct {
+		name        string
+		n           int
+		compression bool
+	}{
+		{"uncompressed", 20, false},
+		{"negotiated but small", compressionThreshold - 1, true},
+		{"compressed", 50, true},
+	}
+	for _, tt := range tests {
+		cs := testContractSet(tt.n, tt.compression)
+		decoded, _ := roundTrip(t, cs)
+		if len(decoded.contracts) != len(cs.contracts) {
+			t.Fatalf("%v: expected %v contracts, got %v", tt.name, len(cs.contracts), len(decoded.contracts))
+		}
+		for i := range cs.contracts {
+			a, b := cs.contracts[i], decoded.contracts[i]
+			if a.Revision.ParentID != b.Revision.ParentID || a.Revision.RevisionNumber != b.Revision.RevisionNumber || a.Revision.Filesize != b.Revision.Filesize {
+				t.Fatalf("%v: revision %v doesn't match", tt.name, i)
+			}
+			if !a.Revision.ValidProofOutputs[0].Value.Equals(b.Revision.ValidProofOutputs[0].Value) {
+				t.Fatalf("%v: outputs of revision %v don't match", tt.name, i)
+			}
+			if a.Signatures[0].ParentID != b.Signatures[0].ParentID || a.Signatures[1].PublicKeyIndex != b.Signatures[1].PublicKeyIndex {
+				t.Fatalf("%v: signatures of revision %v don't match", tt.name, i)
+			}
+		}
+	}
+
+	// The compressed set must be smaller.
+	_, plain := roundTrip(t, testContractSet(50, false))
+	_, compressed := roundTrip(t, testContractSet(50, true))
+	if compressed >= plain {
+		t.Fatalf("compressed set isn't smaller: %v >= %v", compressed, plain)
+	}
+}
+
+// encodeHandshake encodes a handshake request the way the renter does.
+func encodeHandshake(specifier types.Specifier, ciphers, capabilities []types.Specifier) []byte {
+	var buf bytes.Buffer
+	e := types.NewEncoder(&buf)
+	specifier.EncodeTo(e)
+	e.Write(make([]byte, 32))
+	e.WritePrefix(len(ciphers))
+	for _, c := range ciphers {
+		c.EncodeTo(e)
+	}
+	if specifier == loopEnterV2Specifier {
+		e.WritePrefix(len(capabilities))
+		for _, c := range capabilities {
+			c.EncodeTo(e)
+		}
+	}
+	e.Flush()
+	return buf.Bytes()
+}
+
+// TestHandshakeCapabilities tests that the compression is only
+// negotiated by the renters entering with the second handshake version.
+func TestHandshakeCapabilities(t *testing.T) {
+	ciphers := []types.Specifier{cipherChaCha20Poly1305}
+
+	var v1 loopKeyExchangeRequest
+	d := types.NewBufDecoder(encodeHandshake(loopEnterSpecifier, ciphers, nil))
+	v1.DecodeFrom(d)
+	if err := d.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if v1.supports(capabilityFlate) {
+		t.Fatal("the first handshake version can't negotiate compression")
+	}
+
+	var v2 loopKeyExchangeRequest
+	d = types.NewBufDecoder(encodeHandshake(loopEnterV2Specifier, ciphers, []types.Specifier{capabilityFlate}))
+	v2.DecodeFrom(d)
+	if err := d.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if !v2.supports(capabilityFlate) || len(v2.Ciphers) != 1 {
+		t.Fatal("compression not negotiated")
+	}
+
+	// The response only carries the capabilities in the second version.
+	var b1, b2 bytes.Buffer
+	e := types.NewEncoder(&b1)
+	(&loopKeyExchangeResponse{Capabilities: []types.Specifier{capabilityFlate}}).EncodeTo(e)
+	e.Flush()
+	e = types.NewEncoder(&b2)
+	(&loopKeyExchangeResponse{Capabilities: []types.Specifier{capabilityFlate}, v2: true}).EncodeTo(e)
+	e.Flush()
+	if b2.Len() != b1.Len() + 8 + 16 {
+		t.Fatalf("unexpected response sizes: %v and %v", b1.Len(), b2.Len())
+	}
+}
+
+// sendFormRequest writes the form request to the session the way a renter
+// does: the optional fields follow the signature.
+func sendFormRequest(t *testing.T, fr formRequest, aead cipher.AEAD) *rpcSession {
+	t.Helper()
+	var full, base bytes.Buffer
+	e := types.NewEncoder(&full)
+	fr.EncodeTo(e)
+	e.Flush()
+	required := fr
+	required.PreferredHosts, required.RequestID, required.MaxExtension = nil, types.Hash256{}, 0
+	e = types.NewEncoder(&base)
+	required.EncodeTo(e)
+	fr.Signature.EncodeTo(e)
+	e.Flush()
+	// The optional fields follow the required ones in the hashed encoding.
+	plaintext := append(base.Bytes(), full.Bytes()[base.Len() - len(fr.Signature):]...)
+	return sendPlaintext(t, plaintext, aead)
+}
+
+// sendPlaintext writes the encrypted plaintext to the session as a single
+// request.
+func sendPlaintext(t *testing.T, plaintext []byte, aead cipher.AEAD) *rpcSession {
+	t.Helper()
+	renter, provider := net.Pipe()
+	t.Cleanup(func() { renter.Close(); provider.Close() })
+	go func() {
+		e := types.NewEncoder(renter)
+		e.WriteBytes(crypto.EncryptWithNonce(plaintext, aead))
+		e.Flush()
+	}()
+	return &rpcSession{conn: provider, aead: aead}
+}
+
+// TestFormRequestPreferredHosts tests that the preferred hosts survive the
+// encoding, that they are covered by the request hash, and that the
+// requests without them decode as before.
+func TestFormRequestPreferredHosts(t *testing.T) {
+	aead, err := chacha20poly1305.New(make([]byte, chacha20poly1305.KeySize))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fr := formRequest{
+		Hosts:       10,
+		Period:      1000,
+		RenewWindow: 100,
+		Storage:     1 << 30,
+		MinShards:   10,
+		TotalShards: 30,
+	}
+	fr.PubKey[0] = 1
+	fr.Signature[0] = 2
+
+	var old formRequest
+	oldHash, err := sendFormRequest(t, fr, aead).readRequest(&old, 1 << 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(old.PreferredHosts) != 0 || old.Signature != fr.Signature || old.Hosts != fr.Hosts {
+		t.Fatalf("request without preferred hosts decoded wrongly: %+v", old)
+	}
+
+	fr.PreferredHosts = []types.PublicKey{{3}, {4}}
+	var decoded formRequest
+	hash, err := sendFormRequest(t, fr, aead).readRequest(&decoded, 1 << 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded.PreferredHosts) != 2 || decoded.PreferredHosts[0] != fr.PreferredHosts[0] || decoded.PreferredHosts[1] != fr.PreferredHosts[1] {
+		t.Fatal("wrong preferred hosts decoded:", decoded.PreferredHosts)
+	}
+	if decoded.Signature != fr.Signature {
+		t.Fatal("signature decoded wrongly")
+	}
+	if hash == oldHash {
+		t.Fatal("preferred hosts not covered by the request hash")
+	}
+	hosts := decoded.preferredHosts()
+	if len(hosts) != 2 || hosts[0].Algorithm != stypes.SignatureEd25519 || hosts[1].Key[0] != 4 {
+		t.Fatal("wrong preferred host keys:", hosts)
+	}
+}
+
+// TestTruncatedFormRequest tests that a form request cut short, in the
+// required or in the optional fields, is rejected instead of being
+// decoded partially.
+func TestTruncatedFormRequest(t *testing.T) {
+	aead, err := chacha20poly1305.New(make([]byte, chacha20poly1305.KeySize))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fr := formRequest{
+		Hosts:  10,
+		Period: 1000,
+	}
+	fr.PubKey[0] = 1
+	fr.Signature[0] = 2
+	var buf bytes.Buffer
+	e := types.NewEncoder(&buf)
+	fr.EncodeTo(e)
+	fr.Signature.EncodeTo(e)
+	e.Flush()
+	required := buf.Bytes()
+
+	// The complete request is accepted.
+	var decoded formRequest
+	if _, err := sendPlaintext(t, required, aead).readRequest(&decoded, 1 << 16); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Hosts != fr.Hosts || decoded.Signature != fr.Signature {
+		t.Fatalf("request decoded wrongly: %+v", decoded)
+	}
+
+	// A preferred host list that is cut short.
+	var optional bytes.Buffer
+	e = types.NewEncoder(&optional)
+	e.WritePrefix(2)
+	types.PublicKey{3}.EncodeTo(e)
+	e.Flush()
+
+	tests := []struct {
+		name      string
+		plaintext []byte
+	}{
+		{"empty", nil},
+		{"required fields", required[:len(required) / 2]},
+		{"signature", required[:len(required) - 1]},
+		{"optional fields", append(append([]byte(nil), required...), optional.Bytes()...)},
+	}
+	for _, tt := range tests {
+		var decoded formRequest
+		_, err := sendPlaintext(t, tt.plaintext, aead).readRequest(&decoded, 1 << 16)
+		if err == nil || !strings.Contains(err.Error(), "could not decode request") {
+			t.Fatalf("%v: expected a decode error, got %v", tt.name, err)
+		}
+	}
+}
+
+// TestFormRequestID tests that the request ID survives the encoding, and
+// that it identifies the request in place of the hash.
+func TestFormRequestID(t *testing.T) {
+	aead, err := chacha20poly1305.New(make([]byte, chacha20poly1305.KeySize))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fr := formRequest{Hosts: 10, Period: 1000, RenewWindow: 100}
+	fr.PubKey[0] = 1
+
+	// Without an ID, the request is identified by its hash.
+	var decoded formRequest
+	hash, err := sendFormRequest(t, fr, aead).readRequest(&decoded, 1 << 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded.idempotent() || decoded.id(hash) != hash {
+		t.Fatal("expected the request to be identified by its hash")
+	}
+
+	// With an ID, it is identified by the ID.
+	fr.RequestID = types.Hash256{5}
+	decoded = formRequest{}
+	idHash, err := sendFormRequest(t, fr, aead).readRequest(&decoded, 1 << 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded.RequestID != fr.RequestID || !decoded.idempotent() || decoded.id(idHash) != fr.RequestID {
+		t.Fatal("expected the request to be identified by its ID, got", decoded.RequestID)
+	}
+	if idHash == hash {
+		t.Fatal("request ID not covered by the request hash")
+	}
+
+	// A retry with the same ID is the same formation for the same renter
+	// only.
+	rpk := stypes.Ed25519PublicKey(crypto.PublicKey(fr.PubKey))
+	other := stypes.Ed25519PublicKey(crypto.PublicKey{2})
+	if formationKey(rpk, decoded.id(idHash)) != formationKey(rpk, fr.RequestID) {
+		t.Fatal("expected the retry to map to the same formation")
+	}
+	if formationKey(other, fr.RequestID) == formationKey(rpk, fr.RequestID) {
+		t.Fatal("expected the renters not to share the formations")
+	}
+}
@@ -0,0 +1,111 @@
+package provider
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/mike76-dev/sia-satellite/modules"
+
+	"go.sia.tech/siad/types"
+)
+
+// extension returns the extension of the formation deadline that the
+// renter allows, or zero if it exceeds the maximum granted by the
+// provider. Such requests are refused instead of being shortened, so that
+// the renter isn't surprised by a shorter extension than it asked for.
+func (p *Provider) extension(fr *formRequest) time.Duration {
+	if fr.MaxExtension > uint64(p.staticMaxExtension / time.Second) {
+		return 0
+	}
+	return time.Duration(fr.MaxExtension) * time.Second
+}
+
+// nearlyComplete returns true if enough contracts have been formed for
+// the formation deadline to be extended.
+func nearlyComplete(formed int, requested uint64) bool {
+	return formed > 0 && float64(formed) >= float64(requested) * formationExtensionThreshold
+}
+
+// grantExtension returns the extension of the formation deadline granted
+// to the renter after the given number of contracts were formed, zero if
+// none.
+func (p *Provider) grantExtension(fr *formRequest, formed int) time.Duration {
+	if !nearlyComplete(formed, fr.Hosts) {
+		return 0
+	}
+	return p.extension(fr)
+}
+
+// managedWatchFormationDeadline extends the deadline of the formation
+// session if the renter allows it and the formation is nearly complete
+// shortly before the deadline. The progress is counted from the contracts
+// reported by the formation through the returned context. The renter is
+// notified of the extension, and the formation is canceled before the
+// extended deadline, so that the contracts formed so far can still be
+// returned. The returned function stops watching and reports the granted
+// extension.
+func (p *Provider) managedWatchFormationDeadline(ctx context.Context, s *rpcSession, rpk types.SiaPublicKey, fr *formRequest, deadline time.Time) (context.Context, func() time.Duration) {
+	ctx, cancel := context.WithCancel(ctx)
+	var mu sync.Mutex
+	var formed int
+	ctx = modules.WithFormationProgress(ctx, func() {
+		mu.Lock()
+		formed++
+		mu.Unlock()
+	})
+	stop := make(chan struct{})
+	finished := make(chan struct{})
+	var granted time.Duration
+
+	go func() {
+		defer close(finished)
+		if p.extension(fr) == 0 {
+			p.log.Printf("INFO: renter %v requested an extension of %vs, more than the maximum of %v, not extending the deadline\n", rpk.String(), fr.MaxExtension, p.staticMaxExtension)
+			return
+		}
+		timer := time.NewTimer(time.Until(deadline.Add(-formationExtensionNotice)))
+		defer timer.Stop()
+		select {
+		case <-stop:
+			return
+		case <-p.threads.StopChan():
+			return
+		case <-timer.C:
+		}
+
+		mu.Lock()
+		n := formed
+		mu.Unlock()
+		ext := p.grantExtension(fr, n)
+		if ext == 0 {
+			p.log.Printf("INFO: renter %v formed %v of %v contracts, not extending the deadline\n", rpk.String(), n, fr.Hosts)
+			return
+		}
+		if err := s.conn.SetDeadline(deadline.Add(ext)); err != nil {
+			p.log.Println("ERROR: unable to extend the deadline:", err)
+			return
+		}
+		if err := s.writeResponse(&formationExtension{Seconds: uint64(ext / time.Second)}); err != nil {
+			p.log.Println("ERROR: unable to send the deadline extension:", err)
+			return
+		}
+		granted = ext
+		p.log.Printf("INFO: extended the formation deadline of renter %v by %v\n", rpk.String(), ext)
+
+		timer.Reset(time.Until(deadline.Add(ext - formationExtensionNotice)))
+		select {
+		case <-stop:
+		case <-p.threads.StopChan():
+		case <-timer.C:
+			cancel()
+		}
+	}()
+
+	return ctx, func() time.Duration {
+		close(stop)
+		<-finished
+		cancel()
+		return granted
+	}
+}
@@ -0,0 +1,48 @@
+package provider
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mike76-dev/sia-satellite/modules"
+)
+
+// TestGrantExtension tests that an extension within the maximum is granted
+// to a nearly complete formation, and that a longer one is refused rather
+// than shortened.
+func TestGrantExtension(t *testing.T) {
+	p := &Provider{staticMaxExtension: 2 * time.Minute}
+	tests := []struct {
+		name    string
+		seconds uint64
+		formed  int
+		granted time.Duration
+	}{
+		{"within bounds", 60, 8, time.Minute},
+		{"at the maximum", 120, 8, 2 * time.Minute},
+		{"beyond the maximum", 121, 8, 0},
+		{"not nearly complete", 60, 7, 0},
+		{"nothing formed", 60, 0, 0},
+	}
+	for _, tt := range tests {
+		fr := &formRequest{Hosts: 10, MaxExtension: tt.seconds}
+		if granted := p.grantExtension(fr, tt.formed); granted != tt.granted {
+			t.Errorf("%v: expected %v, got %v", tt.name, tt.granted, granted)
+		}
+	}
+}
+
+// TestFormationProgress tests that the progress of the formation is
+// reported through the context.
+func TestFormationProgress(t *testing.T) {
+	var formed int
+	ctx := modules.WithFormationProgress(context.Background(), func() { formed++ })
+	for i := 0; i < 3; i++ {
+		modules.ReportFormationProgress(ctx)
+	}
+	modules.ReportFormationProgress(context.Background())
+	if formed != 3 {
+		t.Fatalf("expected 3 contracts, got %v", formed)
+	}
+}
@@ -0,0 +1,80 @@
+package provider
+
+import (
+	"sync"
+	"time"
+
+	"github.com/mike76-dev/sia-satellite/modules"
+)
+
+// formationResult is the outcome of a contract formation request. done is
+// closed once the formation is over.
+type formationResult struct {
+	done      chan struct{}
+	contracts []modules.RenterContract
+	err       error
+	expires   time.Time
+}
+
+// formationResults makes the contract formation requests idempotent. A
+// request is identified by the renter public key and the request ID, so a
+// renter retrying a request by sending it again receives the result of the
+// first one instead of forming the contracts twice. The requests without
+// an ID are not tracked, so that the identical requests sent on purpose
+// form the contracts each time.
+type formationResults struct {
+	results map[string]*formationResult
+	mu      sync.Mutex
+}
+
+// newFormationResults returns an empty formationResults.
+func newFormationResults() *formationResults {
+	return &formationResults{
+		results: make(map[string]*formationResult),
+	}
+}
+
+// start returns the result stored under the key. If there is none, a new
+// one is created, and the returned bool is true, meaning that the caller
+// has to run the formation and call finish afterwards. If the request is
+// not idempotent, the new result is not stored.
+func (fr *formationResults) start(key string, idempotent bool) (*formationResult, bool) {
+	if !idempotent {
+		return &formationResult{done: make(chan struct{})}, true
+	}
+
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+
+	// Remove the expired results.
+	now := time.Now()
+	for k, r := range fr.results {
+		if !r.expires.IsZero() && now.After(r.expires) {
+			delete(fr.results, k)
+		}
+	}
+
+	if r, exists := fr.results[key]; exists {
+		return r, false
+	}
+	r := &formationResult{done: make(chan struct{})}
+	fr.results[key] = r
+	return r, true
+}
+
+// finish stores the outcome of the formation. Failed formations are not
+// kept, so that they can be retried.
+func (fr *formationResults) finish(key string, r *formationResult, contracts []modules.RenterContract, err error) {
+	fr.mu.Lock()
+	r.contracts = contracts
+	r.err = err
+	if fr.results[key] == r {
+		if err != nil {
+			delete(fr.results, key)
+		} else {
+			r.expires = time.Now().Add(formationResultTTL)
+		}
+	}
+	fr.mu.Unlock()
+	close(r.done)
+}
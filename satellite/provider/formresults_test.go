@@ -0,0 +1,95 @@
+package provider
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/mike76-dev/sia-satellite/modules"
+
+	core "go.sia.tech/core/types"
+	"go.sia.tech/siad/types"
+)
+
+// testFormation mimics managedFormContracts: it forms the contracts unless
+// a request with the same ID has been received before.
+func testFormation(results *formationResults, rpk types.SiaPublicKey, fr *formRequest, hash core.Hash256, form func() ([]modules.RenterContract, error)) ([]modules.RenterContract, error) {
+	key := formationKey(rpk, fr.id(hash))
+	result, isNew := results.start(key, fr.idempotent())
+	if isNew {
+		contracts, err := form()
+		results.finish(key, result, contracts, err)
+	} else {
+		<-result.done
+	}
+	return result.contracts, result.err
+}
+
+// TestFormationResults tests that the formation requests with the same
+// RequestID form the contracts only once, and that the identical requests
+// without an ID form them each time.
+func TestFormationResults(t *testing.T) {
+	results := newFormationResults()
+	rpk := types.SiaPublicKey{Algorithm: types.SignatureEd25519, Key: make([]byte, 32)}
+	hash := core.Hash256{1}
+
+	var formed int32
+	form := func() ([]modules.RenterContract, error) {
+		n := atomic.AddInt32(&formed, 1)
+		var rc modules.RenterContract
+		rc.ID[0] = byte(n)
+		return []modules.RenterContract{rc}, nil
+	}
+
+	// Concurrent retries with the same RequestID.
+	fr := &formRequest{RequestID: core.Hash256{2}}
+	var wg sync.WaitGroup
+	ids := make([]types.FileContractID, 10)
+	for i := range ids {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			contracts, err := testFormation(results, rpk, fr, hash, form)
+			if err != nil || len(contracts) != 1 {
+				t.Error("unexpected result:", contracts, err)
+				return
+			}
+			ids[i] = contracts[0].ID
+		}(i)
+	}
+	wg.Wait()
+	if formed != 1 {
+		t.Fatalf("expected a single formation, got %v", formed)
+	}
+	for _, id := range ids {
+		if id != ids[0] {
+			t.Fatal("retries received different contracts")
+		}
+	}
+
+	// Identical requests without an ID are not deduplicated.
+	fr = &formRequest{}
+	for i := 0; i < 2; i++ {
+		if _, err := testFormation(results, rpk, fr, hash, form); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if formed != 3 {
+		t.Fatalf("expected 3 formations, got %v", formed)
+	}
+
+	// A failed formation can be retried.
+	fr = &formRequest{RequestID: core.Hash256{3}}
+	if _, err := testFormation(results, rpk, fr, hash, func() ([]modules.RenterContract, error) {
+		return nil, errors.New("failed")
+	}); err == nil {
+		t.Fatal("expected an error")
+	}
+	if _, err := testFormation(results, rpk, fr, hash, form); err != nil {
+		t.Fatal(err)
+	}
+	if formed != 4 {
+		t.Fatalf("expected 4 formations, got %v", formed)
+	}
+}
@@ -0,0 +1,155 @@
+package provider
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/mike76-dev/sia-satellite/modules"
+
+	"golang.org/x/crypto/chacha20poly1305"
+
+	core "go.sia.tech/core/types"
+	"go.sia.tech/siad/crypto"
+	"go.sia.tech/siad/types"
+)
+
+// testContractFormer is a satellite with a fixed set of renter contracts.
+// Only the methods used by the tests are implemented.
+type testContractFormer struct {
+	modules.ContractFormer
+	contracts map[string][]modules.RenterContract
+}
+
+// UserExists implements modules.ContractFormer.
+func (cf *testContractFormer) UserExists(rpk types.SiaPublicKey) (bool, error) {
+	_, exists := cf.contracts[rpk.String()]
+	return exists, nil
+}
+
+// RenterContracts implements modules.ContractFormer.
+func (cf *testContractFormer) RenterContracts(rpk types.SiaPublicKey) []modules.RenterContract {
+	return cf.contracts[rpk.String()]
+}
+
+// testRenterContract returns a signed contract between the renter and the
+// host.
+func testRenterContract(id byte, rpk, hpk types.SiaPublicKey) modules.RenterContract {
+	return modules.RenterContract{
+		ID:              types.FileContractID{id},
+		RenterPublicKey: rpk,
+		HostPublicKey:   hpk,
+		Transaction: types.Transaction{
+			FileContractRevisions: []types.FileContractRevision{{
+				ParentID:              types.FileContractID{id},
+				NewRevisionNumber:     uint64(id),
+				NewValidProofOutputs:  make([]types.SiacoinOutput, 2),
+				NewMissedProofOutputs: make([]types.SiacoinOutput, 3),
+			}},
+			TransactionSignatures: []types.TransactionSignature{
+				{Signature: []byte{1}},
+				{Signature: []byte{2}},
+			},
+		},
+	}
+}
+
+// requestContracts sends a signed contracts request to the provider and
+// returns the contract set in the response.
+func requestContracts(t *testing.T, p *Provider, sk crypto.SecretKey, pk crypto.PublicKey) (contractSet, error) {
+	t.Helper()
+	aead, err := chacha20poly1305.New(make([]byte, chacha20poly1305.KeySize))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cr := contractsRequest{PubKey: pk}
+	h := core.NewHasher()
+	cr.EncodeTo(h.E)
+	cr.Signature = core.Signature(crypto.SignHash(crypto.Hash(h.Sum()), sk))
+	var buf bytes.Buffer
+	e := core.NewEncoder(&buf)
+	cr.EncodeTo(e)
+	cr.Signature.EncodeTo(e)
+	e.Flush()
+
+	renter, conn := net.Pipe()
+	defer renter.Close()
+	defer conn.Close()
+	go func() {
+		e := core.NewEncoder(renter)
+		e.WriteBytes(crypto.EncryptWithNonce(buf.Bytes(), aead))
+		e.Flush()
+	}()
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- p.managedGetContracts(&rpcSession{conn: conn, aead: aead})
+		conn.Close()
+	}()
+
+	// Read and decrypt the response.
+	var cs contractSet
+	var prefix [8]byte
+	if _, err := io.ReadFull(renter, prefix[:]); err != nil {
+		return cs, <-errChan
+	}
+	msg := make([]byte, binary.LittleEndian.Uint64(prefix[:]))
+	if _, err := io.ReadFull(renter, msg); err != nil {
+		t.Fatal(err)
+	}
+	nonce := msg[:aead.NonceSize()]
+	plaintext, err := aead.Open(nil, nonce, msg[len(nonce):], nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := <-errChan; err != nil {
+		t.Fatal(err)
+	}
+	d := core.NewBufDecoder(plaintext)
+	if d.ReadBool() {
+		t.Fatal("expected a contract set, got an RPC error")
+	}
+	cs.DecodeFrom(d)
+	if err := d.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return cs, nil
+}
+
+// TestGetContracts tests that the renter receives the set of its contracts
+// held by the satellite, and that an unknown renter receives nothing.
+func TestGetContracts(t *testing.T) {
+	sk, pk := crypto.GenerateKeyPair()
+	rpk := types.Ed25519PublicKey(pk)
+	hpk := types.Ed25519PublicKey(crypto.PublicKey{10})
+	cf := &testContractFormer{contracts: map[string][]modules.RenterContract{
+		rpk.String(): {testRenterContract(1, rpk, hpk), testRenterContract(2, rpk, hpk)},
+	}}
+	p := &Provider{satellite: cf, staticRateLimiter: newRateLimiter(60, 5)}
+
+	cs, err := requestContracts(t, p, sk, pk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cs.contracts) != 2 {
+		t.Fatal("expected 2 contracts, got", len(cs.contracts))
+	}
+	for i, c := range cs.contracts {
+		if c.Revision.ParentID != (core.FileContractID{byte(i + 1)}) || c.Revision.RevisionNumber != uint64(i + 1) {
+			t.Fatalf("contract %v decoded wrongly: %+v", i, c.Revision)
+		}
+	}
+
+	// A renter without contracts receives an empty set.
+	cf.contracts[rpk.String()] = nil
+	if cs, err := requestContracts(t, p, sk, pk); err != nil || len(cs.contracts) != 0 {
+		t.Fatalf("expected no contracts, got %v: %v", len(cs.contracts), err)
+	}
+
+	// An unknown renter is refused.
+	sk, pk = crypto.GenerateKeyPair()
+	if _, err := requestContracts(t, p, sk, pk); err == nil {
+		t.Fatal("expected an unknown renter to be refused")
+	}
+}
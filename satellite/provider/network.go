@@ -32,6 +32,18 @@ var formContractsSpecifier = types.NewSpecifier("FormContracts")
 // contracts.
 var renewContractsSpecifier = types.NewSpecifier("RenewContracts")
 
+// cancelFormationSpecifier is used when a renter requests to cancel a
+// contract formation in progress.
+var cancelFormationSpecifier = types.NewSpecifier("CancelFormation")
+
+// previewContractsSpecifier is used when a renter requests to see which
+// hosts the contracts would be formed with.
+var previewContractsSpecifier = types.NewSpecifier("PreviewContracts")
+
+// getContractsSpecifier is used when a renter requests the set of its
+// contracts currently held by the satellite.
+var getContractsSpecifier = types.NewSpecifier("GetContracts")
+
 // threadedUpdateHostname periodically runs 'managedLearnHostname', which
 // checks if the Satellite's hostname has changed.
 func (p *Provider) threadedUpdateHostname(closeChan chan struct{}) {
@@ -206,7 +218,7 @@ func (p *Provider) threadedHandleConn(conn net.Conn) {
 		p.log.Println("ERROR: could not read handshake request:", err)
 		return
 	}
-	if req.Specifier != loopEnterSpecifier {
+	if req.Specifier != loopEnterSpecifier && req.Specifier != loopEnterV2Specifier {
 		p.log.Println("ERROR: wrong handshake request specifier")
 		return
 	}
@@ -233,6 +245,15 @@ func (p *Provider) threadedHandleConn(conn net.Conn) {
 	resp := loopKeyExchangeResponse{
 		Cipher:    cipherChaCha20Poly1305,
 		PublicKey: xpk,
+		v2:        req.Specifier == loopEnterV2Specifier,
+	}
+	compression := req.supports(capabilityFlate)
+	if compression {
+		resp.Capabilities = append(resp.Capabilities, capabilityFlate)
+	}
+	signChallenge := req.supports(capabilityChallenge)
+	if signChallenge {
+		resp.Capabilities = append(resp.Capabilities, capabilityChallenge)
 	}
 	copy(resp.Signature[:], pubkeySig[:])
 	resp.EncodeTo(e)
@@ -250,8 +271,11 @@ func (p *Provider) threadedHandleConn(conn net.Conn) {
 
 	// Create the session object.
 	s := &rpcSession{
-		conn: conn,
-		aead: aead,
+		conn:        conn,
+		aead:        aead,
+		compression: compression,
+
+		signChallenge: signChallenge,
 	}
 	fastrand.Read(s.challenge[:])
 
@@ -282,6 +306,21 @@ func (p *Provider) threadedHandleConn(conn net.Conn) {
 		if err != nil {
 			err = errors.Extend(errors.New("incoming RPCRenewContracts failed: "), err)
 		}
+	case previewContractsSpecifier:
+		err = p.managedPreviewContracts(s)
+		if err != nil {
+			err = errors.Extend(errors.New("incoming RPCPreviewContracts failed: "), err)
+		}
+	case cancelFormationSpecifier:
+		err = p.managedCancelFormation(s)
+		if err != nil {
+			err = errors.Extend(errors.New("incoming RPCCancelFormation failed: "), err)
+		}
+	case getContractsSpecifier:
+		err = p.managedGetContracts(s)
+		if err != nil {
+			err = errors.Extend(errors.New("incoming RPCGetContracts failed: "), err)
+		}
 	default:
 		p.log.Println("INFO: inbound connection from:", conn.RemoteAddr()) //TODO
 	}
@@ -2,11 +2,13 @@
 package provider
 
 import (
+	"context"
 	"fmt"
 	"net"
 	"os"
 	"path/filepath"
 	"sync"
+	"time"
 
 	"github.com/mike76-dev/sia-satellite/modules"
 
@@ -35,18 +37,47 @@ type Provider struct {
 	port          string
 	threads       siasync.ThreadGroup
 	staticAlerter *smodules.GenericAlerter
+
+	staticRateLimiter *rateLimiter
+
+	// staticMaxExtension is the longest extension of the formation
+	// deadline granted to a renter.
+	staticMaxExtension time.Duration
+
+	// formations holds the cancel functions of the contract formations
+	// in progress.
+	formations map[string]context.CancelFunc
+
+	// staticFormationResults keeps the recent formation results for the
+	// retried requests.
+	staticFormationResults *formationResults
 }
 
-// New returns an initialized Provider.
-func New(g smodules.Gateway, satelliteAddr string, persistDir string) (*Provider, <-chan error) {
+// New returns an initialized Provider. rpcRate is the number of requests
+// per minute a single renter is allowed to make, a non-positive value means
+// the default rate. maxExtension is the longest extension of the formation
+// deadline granted to a renter, a non-positive value means the default.
+func New(g smodules.Gateway, satelliteAddr string, persistDir string, rpcRate float64, maxExtension time.Duration) (*Provider, <-chan error) {
 	errChan := make(chan error, 1)
 	var err error
 
 	// Create the Provider object.
+	if rpcRate <= 0 {
+		rpcRate = defaultRPCRate
+	}
+	if maxExtension <= 0 {
+		maxExtension = defaultMaxFormationExtension
+	}
 	p := &Provider{
-		g:             g,
-		persistDir:    persistDir,
-		staticAlerter: smodules.NewAlerter("provider"),
+		g:                 g,
+		persistDir:        persistDir,
+		staticAlerter:     smodules.NewAlerter("provider"),
+		staticRateLimiter: newRateLimiter(rpcRate, rpcBurst),
+		formations:        make(map[string]context.CancelFunc),
+
+		staticMaxExtension: maxExtension,
+
+		staticFormationResults: newFormationResults(),
 	}
 
 	// Call stop in the event of a partial startup.
@@ -97,6 +128,8 @@ func New(g smodules.Gateway, satelliteAddr string, persistDir string) (*Provider
 		return nil, errChan
 	}
 
+	go p.threadedPruneRateLimiter()
+
 	return p, errChan
 }
 
@@ -0,0 +1,95 @@
+package provider
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"go.sia.tech/siad/types"
+)
+
+// errRateLimited is returned to a renter who has sent too many requests.
+var errRateLimited = errors.New("too many requests, please try again later")
+
+// A bucket is a token bucket of a single renter.
+type bucket struct {
+	tokens     float64
+	lastRefill time.Time
+}
+
+// rateLimiter is a token bucket rate limiter keyed by the renter public key.
+// Each bucket holds up to burst tokens and is refilled at the given rate.
+type rateLimiter struct {
+	rate    float64 // Tokens per second.
+	burst   float64
+	buckets map[string]*bucket
+	mu      sync.Mutex
+}
+
+// newRateLimiter returns a rate limiter that allows the given number of
+// requests per minute from each renter.
+func newRateLimiter(perMinute float64, burst int) *rateLimiter {
+	return &rateLimiter{
+		rate:    perMinute / 60,
+		burst:   float64(burst),
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// allow takes a token from the bucket of the renter. It returns false if the
+// bucket is empty.
+func (rl *rateLimiter) allow(rpk types.SiaPublicKey) bool {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	now := time.Now()
+	b, exists := rl.buckets[rpk.String()]
+	if !exists {
+		b = &bucket{
+			tokens:     rl.burst,
+			lastRefill: now,
+		}
+		rl.buckets[rpk.String()] = b
+	}
+
+	// Refill the bucket.
+	b.tokens += now.Sub(b.lastRefill).Seconds() * rl.rate
+	if b.tokens > rl.burst {
+		b.tokens = rl.burst
+	}
+	b.lastRefill = now
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// prune removes the buckets that have been full for a while, so that the
+// map doesn't grow indefinitely.
+func (rl *rateLimiter) prune() {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	now := time.Now()
+	for key, b := range rl.buckets {
+		if b.tokens+now.Sub(b.lastRefill).Seconds()*rl.rate >= rl.burst {
+			delete(rl.buckets, key)
+		}
+	}
+}
+
+// threadedPruneRateLimiter periodically prunes the rate limiter.
+func (p *Provider) threadedPruneRateLimiter() {
+	if err := p.threads.Add(); err != nil {
+		return
+	}
+	defer p.threads.Done()
+	for {
+		select {
+		case <-p.threads.StopChan():
+			return
+		case <-time.After(rateLimiterPruneInterval):
+		}
+		p.staticRateLimiter.prune()
+	}
+}
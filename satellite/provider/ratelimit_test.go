@@ -0,0 +1,58 @@
+package provider
+
+import (
+	"testing"
+	"time"
+
+	"go.sia.tech/siad/types"
+)
+
+// TestRateLimiter tests that a renter bursting requests is throttled while
+// another renter is unaffected, and that the bucket refills over time.
+func TestRateLimiter(t *testing.T) {
+	rl := newRateLimiter(60, 5)
+	rpk1 := types.SiaPublicKey{Algorithm: types.SignatureEd25519, Key: make([]byte, 32)}
+	rpk2 := types.SiaPublicKey{Algorithm: types.SignatureEd25519, Key: make([]byte, 32)}
+	rpk2.Key[0] = 1
+
+	// The first renter exhausts the burst.
+	for i := 0; i < 5; i++ {
+		if !rl.allow(rpk1) {
+			t.Fatalf("request %v throttled within the burst", i)
+		}
+	}
+	if rl.allow(rpk1) {
+		t.Fatal("request over the burst allowed")
+	}
+
+	// The second renter has a bucket of their own.
+	for i := 0; i < 5; i++ {
+		if !rl.allow(rpk2) {
+			t.Fatalf("request %v of the other renter throttled", i)
+		}
+	}
+
+	// A token is refilled after a second at 60 requests per minute.
+	rl.mu.Lock()
+	rl.buckets[rpk1.String()].lastRefill = time.Now().Add(-time.Second)
+	rl.mu.Unlock()
+	if !rl.allow(rpk1) {
+		t.Fatal("bucket not refilled")
+	}
+	if rl.allow(rpk1) {
+		t.Fatal("bucket refilled too much")
+	}
+
+	// The full buckets are pruned, the others are kept.
+	rl.mu.Lock()
+	rl.buckets[rpk2.String()].lastRefill = time.Now().Add(-time.Minute)
+	rl.mu.Unlock()
+	rl.prune()
+	rl.mu.Lock()
+	_, kept1 := rl.buckets[rpk1.String()]
+	_, kept2 := rl.buckets[rpk2.String()]
+	rl.mu.Unlock()
+	if !kept1 || kept2 {
+		t.Fatal("wrong buckets pruned")
+	}
+}
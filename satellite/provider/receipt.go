@@ -0,0 +1,111 @@
+package provider
+
+import (
+	"errors"
+
+	"github.com/mike76-dev/sia-satellite/modules"
+
+	"go.sia.tech/core/types"
+	"go.sia.tech/siad/crypto"
+)
+
+// receiptSpecifier separates the receipt signatures from the other
+// signatures made with the satellite key.
+var receiptSpecifier = types.NewSpecifier("FormationReceipt")
+
+// formationReceipt summarizes a contract formation performed on behalf of
+// a renter. It is signed by the satellite, so that the renter can keep it
+// as a proof of the satellite's actions.
+type formationReceipt struct {
+	RenterKey   types.PublicKey
+	Contracts   []types.FileContractID
+	TotalFunds  types.Currency
+	BlockHeight uint64
+
+	Signature types.Signature
+}
+
+// encodeFields writes the signed fields of the receipt.
+func (fr *formationReceipt) encodeFields(e *types.Encoder) {
+	fr.RenterKey.EncodeTo(e)
+	e.WritePrefix(len(fr.Contracts))
+	for _, id := range fr.Contracts {
+		id.EncodeTo(e)
+	}
+	fr.TotalFunds.EncodeTo(e)
+	e.WriteUint64(fr.BlockHeight)
+}
+
+// EncodeTo implements requestBody.
+func (fr *formationReceipt) EncodeTo(e *types.Encoder) {
+	fr.encodeFields(e)
+	fr.Signature.EncodeTo(e)
+}
+
+// DecodeFrom implements requestBody.
+func (fr *formationReceipt) DecodeFrom(d *types.Decoder) {
+	fr.RenterKey.DecodeFrom(d)
+	num := d.ReadPrefix()
+	if num > maxContractSetSize {
+		d.SetErr(errors.New("receipt too large"))
+		return
+	}
+	fr.Contracts = make([]types.FileContractID, num)
+	for i := range fr.Contracts {
+		fr.Contracts[i].DecodeFrom(d)
+	}
+	fr.TotalFunds.DecodeFrom(d)
+	fr.BlockHeight = d.ReadUint64()
+	fr.Signature.DecodeFrom(d)
+}
+
+// sigHash returns the hash of the signed fields.
+func (fr *formationReceipt) sigHash() types.Hash256 {
+	h := types.NewHasher()
+	receiptSpecifier.EncodeTo(h.E)
+	fr.encodeFields(h.E)
+	return h.Sum()
+}
+
+// sign signs the receipt with the satellite key.
+func (fr *formationReceipt) sign(sk crypto.SecretKey) {
+	sig := crypto.SignHash(crypto.Hash(fr.sigHash()), sk)
+	copy(fr.Signature[:], sig[:])
+}
+
+// newFormationReceipt returns a signed receipt of the contracts formed for
+// the renter.
+func (p *Provider) newFormationReceipt(rpk crypto.PublicKey, contracts []modules.RenterContract) formationReceipt {
+	fr := formationReceipt{
+		RenterKey:   types.PublicKey(rpk),
+		Contracts:   make([]types.FileContractID, 0, len(contracts)),
+		TotalFunds:  types.ZeroCurrency,
+		BlockHeight: uint64(p.satellite.BlockHeight()),
+	}
+	for _, contract := range contracts {
+		fr.Contracts = append(fr.Contracts, types.FileContractID(contract.ID))
+		fr.TotalFunds = fr.TotalFunds.Add(modules.ConvertCurrency(contract.TotalCost))
+	}
+	fr.sign(p.satellite.SecretKey())
+	return fr
+}
+
+// formationResponse is the response to a formation request. The receipt
+// follows the contract set, so that the renters not expecting it can
+// ignore it.
+type formationResponse struct {
+	contracts contractSet
+	receipt   formationReceipt
+}
+
+// EncodeTo implements requestBody.
+func (fr *formationResponse) EncodeTo(e *types.Encoder) {
+	fr.contracts.EncodeTo(e)
+	fr.receipt.EncodeTo(e)
+}
+
+// DecodeFrom implements requestBody.
+func (fr *formationResponse) DecodeFrom(d *types.Decoder) {
+	fr.contracts.DecodeFrom(d)
+	fr.receipt.DecodeFrom(d)
+}
@@ -0,0 +1,58 @@
+package provider
+
+import (
+	"bytes"
+	"testing"
+
+	"go.sia.tech/core/types"
+	"go.sia.tech/siad/crypto"
+)
+
+// verifyReceipt checks the receipt signature the way a renter would.
+func verifyReceipt(fr formationReceipt, pk crypto.PublicKey) error {
+	var sig crypto.Signature
+	copy(sig[:], fr.Signature[:])
+	return crypto.VerifyHash(crypto.Hash(fr.sigHash()), pk, sig)
+}
+
+// TestFormationReceipt tests that the receipt survives the encoding, and
+// that a tampered receipt or a signature over the bare fields is rejected.
+func TestFormationReceipt(t *testing.T) {
+	sk, pk := crypto.GenerateKeyPair()
+	fr := formationReceipt{
+		Contracts:   []types.FileContractID{{1}, {2}, {3}},
+		TotalFunds:  types.Siacoins(300),
+		BlockHeight: 1000,
+	}
+	fr.RenterKey[0] = 1
+	fr.sign(sk)
+
+	var buf bytes.Buffer
+	e := types.NewEncoder(&buf)
+	fr.EncodeTo(e)
+	e.Flush()
+	var decoded formationReceipt
+	d := types.NewBufDecoder(buf.Bytes())
+	decoded.DecodeFrom(d)
+	if err := d.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if err := verifyReceipt(decoded, pk); err != nil {
+		t.Fatal("signature rejected:", err)
+	}
+
+	tampered := decoded
+	tampered.BlockHeight++
+	if err := verifyReceipt(tampered, pk); err == nil {
+		t.Fatal("tampered receipt accepted")
+	}
+
+	// A signature over the fields without the specifier doesn't verify.
+	h := types.NewHasher()
+	decoded.encodeFields(h.E)
+	sig := crypto.SignHash(crypto.Hash(h.Sum()), sk)
+	copy(decoded.Signature[:], sig[:])
+	if err := verifyReceipt(decoded, pk); err == nil {
+		t.Fatal("signature without the specifier accepted")
+	}
+}
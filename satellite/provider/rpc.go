@@ -2,6 +2,7 @@ package provider
 
 import (
 	"bytes"
+	"context"
 	"crypto/cipher"
 	"encoding/binary"
 	"errors"
@@ -23,9 +24,14 @@ import (
 
 // An rpcSession contains the state of an RPC session with a renter.
 type rpcSession struct {
-	conn      net.Conn
-	aead      cipher.AEAD
-	challenge [16]byte
+	conn        net.Conn
+	aead        cipher.AEAD
+	challenge   [16]byte
+	compression bool
+
+	// signChallenge is set if the renter signs the requests together with
+	// the session challenge.
+	signChallenge bool
 }
 
 // readRequest reads an encrypted RPC request from the renter.
@@ -39,8 +45,18 @@ func (s *rpcSession) readRequest(req requestBody, maxLen uint64) (core.Hash256,
 	if err != nil {
 		return core.Hash256{}, err
 	}
-	b := core.NewBufDecoder(plaintext)
+	r := bytes.NewReader(plaintext)
+	b := core.NewDecoder(io.LimitedReader{R: r, N: int64(len(plaintext))})
 	req.DecodeFrom(b)
+	if o, ok := req.(optionalDecoder); ok && r.Len() > 0 {
+		o.decodeOptional(b, func() bool { return r.Len() > 0 })
+	}
+
+	// Reject a truncated or malformed request instead of acting on a
+	// partially decoded one.
+	if err := b.Err(); err != nil {
+		return core.Hash256{}, fmt.Errorf("could not decode request: %v", err)
+	}
 
 	// Calculate the hash.
 	h := core.NewHasher()
@@ -51,6 +67,17 @@ func (s *rpcSession) readRequest(req requestBody, maxLen uint64) (core.Hash256,
 
 // writeResponse sends an encrypted RPC response to the renter.
 func (s *rpcSession) writeResponse(resp requestBody) error {
+	return s.writeMessage(resp, nil)
+}
+
+// writeError sends an encrypted RPC error to the renter.
+func (s *rpcSession) writeError(err error) error {
+	return s.writeMessage(nil, &rhpv2.RPCError{Description: err.Error()})
+}
+
+// writeMessage sends either an encrypted RPC response or an RPC error to
+// the renter.
+func (s *rpcSession) writeMessage(resp requestBody, rpcErr *rhpv2.RPCError) error {
 	nonce := make([]byte, 32)[:s.aead.NonceSize()]
 	fastrand.Read(nonce)
 
@@ -59,8 +86,12 @@ func (s *rpcSession) writeResponse(resp requestBody) error {
 	e := core.NewEncoder(&buf)
 	e.WritePrefix(0) // Placeholder.
 	e.Write(nonce)
-	e.WriteBool(false) // Error.
-	resp.EncodeTo(e)
+	e.WriteBool(rpcErr != nil) // Error.
+	if rpcErr != nil {
+		rpcErr.EncodeTo(e)
+	} else {
+		resp.EncodeTo(e)
+	}
 	e.Flush()
 
 	// Overwrite message length.
@@ -86,7 +117,8 @@ func (s *rpcSession) writeResponse(resp requestBody) error {
 // on behalf of the renter.
 func (p *Provider) managedFormContracts(s *rpcSession) error {
 	// Extend the deadline to meet the formation of multiple contracts.
-	s.conn.SetDeadline(time.Now().Add(formContractsTime))
+	deadline := time.Now().Add(formContractsTime)
+	s.conn.SetDeadline(deadline)
 
 	// Read the request.
 	var fr formRequest
@@ -96,41 +128,108 @@ func (p *Provider) managedFormContracts(s *rpcSession) error {
 	}
 
 	// Verify the signature.
-	err = crypto.VerifyHash(crypto.Hash(hash), fr.PubKey, crypto.Signature(fr.Signature))
+	err = s.verifyRequest(hash, fr.PubKey, fr.Signature)
 	if err != nil {
 		return fmt.Errorf("could not verify renter signature: %v", err)
 	}
 
 	// Check if we know this renter.
 	rpk := types.Ed25519PublicKey(crypto.PublicKey(fr.PubKey))
+
+	// Check if the renter is within the rate limit.
+	if !p.staticRateLimiter.allow(rpk) {
+		if err := s.writeError(errRateLimited); err != nil {
+			return fmt.Errorf("could not send error to renter: %v", err)
+		}
+		return fmt.Errorf("renter %v: %v", rpk.String(), errRateLimited)
+	}
 	exists, err := p.satellite.UserExists(rpk)
 	if !exists || err != nil {
 		return fmt.Errorf("could not find renter in the database: %v", err)
 	}
 
 	// Sanity checks
-	if fr.Hosts == 0 {
-		return errors.New("can't form contracts with zero hosts")
+	if err := fr.validate(); err != nil {
+		return err
+	}
+
+	cs := contractSet{
+		contracts:   make([]rhpv2.ContractRevision, 0, fr.Hosts),
+		compression: s.compression,
 	}
-	if fr.Period == 0 {
-		return errors.New("can't form contracts with zero period")
+
+	// Form the contracts unless a request with the same RequestID has
+	// been received before, in which case wait for its result. The
+	// formation can be canceled by the renter using the ID of this
+	// request. If the renter allows it, the deadline can be extended.
+	id := fr.id(hash)
+	key := formationKey(rpk, id)
+	result, isNew := p.staticFormationResults.start(key, fr.idempotent())
+	var extended time.Duration
+	if isNew {
+		ctx, done := p.managedTrackFormation(rpk, id)
+		stopWatching := func() time.Duration { return 0 }
+		if fr.MaxExtension > 0 {
+			ctx, stopWatching = p.managedWatchFormationDeadline(ctx, s, rpk, &fr, deadline)
+		}
+		contracts, err := p.satellite.FormContracts(ctx, rpk, fr.allowance(), fr.preferredHosts())
+		extended = stopWatching()
+		done()
+		p.staticFormationResults.finish(key, result, contracts, err)
+	} else {
+		p.log.Printf("INFO: renter %v repeated formation request %v, reusing the result\n", rpk.String(), id)
+		select {
+		case <-result.done:
+		case <-p.threads.StopChan():
+			return errors.New("provider was stopped")
+		}
 	}
-	if fr.RenewWindow == 0 {
-		return errors.New("can't form contracts with zero renew window")
+	if result.err != nil {
+		return fmt.Errorf("could not form contracts: %v", result.err)
 	}
-	if fr.Storage == 0 {
-		return errors.New("can't form contracts with zero expected storage")
+
+	for _, contract := range result.contracts {
+		cr := convertContract(contract)
+		cs.contracts = append(cs.contracts, cr)
 	}
-	if fr.MinShards == 0 || fr.TotalShards == 0 {
-		return errors.New("can't form contracts with such redundancy params")
+
+	// A renter that allows extending the deadline receives exactly one
+	// formationExtension before the response.
+	if fr.MaxExtension > 0 && extended == 0 {
+		if err := s.writeResponse(&formationExtension{}); err != nil {
+			return err
+		}
 	}
 
-	cs := contractSet{
-		contracts: make([]rhpv2.ContractRevision, 0, fr.Hosts),
+	resp := formationResponse{
+		contracts: cs,
+		receipt:   p.newFormationReceipt(fr.PubKey, result.contracts),
 	}
+	err = s.writeResponse(&resp)
 
-	// Create an allowance.
-	a := smodules.Allowance{
+	return err
+}
+
+// validate performs the sanity checks of the request.
+func (fr *formRequest) validate() error {
+	if err := modules.ValidateAllowance(fr.allowance(), fr.MinShards, fr.TotalShards); err != nil {
+		return fmt.Errorf("can't form contracts: %v", err)
+	}
+	return nil
+}
+
+// redundancy returns the expected redundancy for the given erasure coding
+// parameters, or zero if they are invalid.
+func redundancy(minShards, totalShards uint64) float64 {
+	if minShards == 0 {
+		return 0
+	}
+	return float64(totalShards / minShards)
+}
+
+// allowance creates an allowance from the request.
+func (fr *formRequest) allowance() smodules.Allowance {
+	return smodules.Allowance{
 		Hosts:       fr.Hosts,
 		Period:      types.BlockHeight(fr.Period),
 		RenewWindow: types.BlockHeight(fr.RenewWindow),
@@ -138,7 +237,7 @@ func (p *Provider) managedFormContracts(s *rpcSession) error {
 		ExpectedStorage:    fr.Storage,
 		ExpectedUpload:     fr.Upload,
 		ExpectedDownload:   fr.Download,
-		ExpectedRedundancy: float64(fr.TotalShards / fr.MinShards),
+		ExpectedRedundancy: redundancy(fr.MinShards, fr.TotalShards),
 
 		MaxRPCPrice:               types.NewCurrency(fr.MaxRPCPrice.Big()),
 		MaxContractPrice:          types.NewCurrency(fr.MaxContractPrice.Big()),
@@ -147,21 +246,71 @@ func (p *Provider) managedFormContracts(s *rpcSession) error {
 		MaxStoragePrice:           types.NewCurrency(fr.MaxStoragePrice.Big()),
 		MaxUploadBandwidthPrice:   types.NewCurrency(fr.MaxUploadPrice.Big()),
 	}
+}
+
+// preferredHosts returns the preferred hosts from the request.
+func (fr *formRequest) preferredHosts() []types.SiaPublicKey {
+	hosts := make([]types.SiaPublicKey, 0, len(fr.PreferredHosts))
+	for _, pk := range fr.PreferredHosts {
+		hosts = append(hosts, types.Ed25519PublicKey(crypto.PublicKey(pk)))
+	}
+	return hosts
+}
 
-	// Form the contracts.
-	contracts, err := p.satellite.FormContracts(rpk, a)
+// managedPreviewContracts runs the host selection for a formRequest and
+// returns the hosts the provider intends to form contracts with, together
+// with the projected funding, without forming any contracts.
+func (p *Provider) managedPreviewContracts(s *rpcSession) error {
+	// Read the request.
+	var fr formRequest
+	hash, err := s.readRequest(&fr, 65536)
 	if err != nil {
-		return fmt.Errorf("could not form contracts: %v", err)
+		return fmt.Errorf("could not read renter request: %v", err)
 	}
 
-	for _, contract := range contracts {
-		cr := convertContract(contract)
-		cs.contracts = append(cs.contracts, cr)
+	// Verify the signature.
+	err = s.verifyRequest(hash, fr.PubKey, fr.Signature)
+	if err != nil {
+		return fmt.Errorf("could not verify renter signature: %v", err)
 	}
 
-	err = s.writeResponse(&cs)
+	// Check if we know this renter.
+	rpk := types.Ed25519PublicKey(crypto.PublicKey(fr.PubKey))
 
-	return err
+	// Check if the renter is within the rate limit.
+	if !p.staticRateLimiter.allow(rpk) {
+		if err := s.writeError(errRateLimited); err != nil {
+			return fmt.Errorf("could not send error to renter: %v", err)
+		}
+		return fmt.Errorf("renter %v: %v", rpk.String(), errRateLimited)
+	}
+	exists, err := p.satellite.UserExists(rpk)
+	if !exists || err != nil {
+		return fmt.Errorf("could not find renter in the database: %v", err)
+	}
+
+	// Sanity checks
+	if err := fr.validate(); err != nil {
+		return err
+	}
+
+	// Run the host selection.
+	hosts, err := p.satellite.PreviewContracts(context.Background(), rpk, fr.allowance(), fr.preferredHosts())
+	if err != nil {
+		return fmt.Errorf("could not preview contracts: %v", err)
+	}
+
+	fp := formationPreview{
+		hosts: make([]hostPreview, 0, len(hosts)),
+	}
+	for _, h := range hosts {
+		fp.hosts = append(fp.hosts, hostPreview{
+			PublicKey: core.PublicKey(h.HostPublicKey.ToPublicKey()),
+			Funding:   modules.ConvertCurrency(h.Funding),
+		})
+	}
+
+	return s.writeResponse(&fp)
 }
 
 // managedRenewContracts tries to renew the given set of contracts.
@@ -177,13 +326,21 @@ func (p *Provider) managedRenewContracts(s *rpcSession) error {
 	}
 
 	// Verify the signature.
-	err = crypto.VerifyHash(crypto.Hash(hash), rr.PubKey, crypto.Signature(rr.Signature))
+	err = s.verifyRequest(hash, rr.PubKey, rr.Signature)
 	if err != nil {
 		return fmt.Errorf("could not verify renter signature: %v", err)
 	}
 
 	// Check if we know this renter.
 	rpk := types.Ed25519PublicKey(crypto.PublicKey(rr.PubKey))
+
+	// Check if the renter is within the rate limit.
+	if !p.staticRateLimiter.allow(rpk) {
+		if err := s.writeError(errRateLimited); err != nil {
+			return fmt.Errorf("could not send error to renter: %v", err)
+		}
+		return fmt.Errorf("renter %v: %v", rpk.String(), errRateLimited)
+	}
 	exists, err := p.satellite.UserExists(rpk)
 	if !exists || err != nil {
 		return fmt.Errorf("could not find renter in the database: %v", err)
@@ -193,21 +350,10 @@ func (p *Provider) managedRenewContracts(s *rpcSession) error {
 	if len(rr.Contracts) == 0 {
 		return errors.New("can't renew an empty set of contracts")
 	}
-	if rr.Period == 0 {
-		return errors.New("can't renew contracts with zero period")
-	}
-	if rr.RenewWindow == 0 {
-		return errors.New("can't renew contracts with zero renew window")
-	}
-	if rr.Storage == 0 {
-		return errors.New("can't renew contracts with zero expected storage")
-	}
-	if rr.MinShards == 0 || rr.TotalShards == 0 {
-		return errors.New("can't renew contracts with such redundancy params")
-	}
 
 	cs := contractSet{
-		contracts: make([]rhpv2.ContractRevision, 0, len(rr.Contracts)),
+		contracts:   make([]rhpv2.ContractRevision, 0, len(rr.Contracts)),
+		compression: s.compression,
 	}
 
 	// Create an allowance.
@@ -219,7 +365,7 @@ func (p *Provider) managedRenewContracts(s *rpcSession) error {
 		ExpectedStorage:    rr.Storage,
 		ExpectedUpload:     rr.Upload,
 		ExpectedDownload:   rr.Download,
-		ExpectedRedundancy: float64(rr.TotalShards / rr.MinShards),
+		ExpectedRedundancy: redundancy(rr.MinShards, rr.TotalShards),
 
 		MaxRPCPrice:               types.NewCurrency(rr.MaxRPCPrice.Big()),
 		MaxContractPrice:          types.NewCurrency(rr.MaxContractPrice.Big()),
@@ -228,6 +374,9 @@ func (p *Provider) managedRenewContracts(s *rpcSession) error {
 		MaxStoragePrice:           types.NewCurrency(rr.MaxStoragePrice.Big()),
 		MaxUploadBandwidthPrice:   types.NewCurrency(rr.MaxUploadPrice.Big()),
 	}
+	if err := modules.ValidateAllowance(a, rr.MinShards, rr.TotalShards); err != nil {
+		return fmt.Errorf("can't renew contracts: %v", err)
+	}
 
 	// Renew the contracts.
 	fcids := make([]types.FileContractID, len(rr.Contracts))
@@ -249,6 +398,50 @@ func (p *Provider) managedRenewContracts(s *rpcSession) error {
 	return err
 }
 
+// managedGetContracts sends the renter the set of its contracts currently
+// held by the satellite. This allows a reconnecting renter to sync its
+// contracts.
+func (p *Provider) managedGetContracts(s *rpcSession) error {
+	// Read the request.
+	var cr contractsRequest
+	hash, err := s.readRequest(&cr, 1024)
+	if err != nil {
+		return fmt.Errorf("could not read renter request: %v", err)
+	}
+
+	// Verify the signature.
+	err = s.verifyRequest(hash, cr.PubKey, cr.Signature)
+	if err != nil {
+		return fmt.Errorf("could not verify renter signature: %v", err)
+	}
+
+	// Check if we know this renter.
+	rpk := types.Ed25519PublicKey(crypto.PublicKey(cr.PubKey))
+
+	// Check if the renter is within the rate limit.
+	if !p.staticRateLimiter.allow(rpk) {
+		if err := s.writeError(errRateLimited); err != nil {
+			return fmt.Errorf("could not send error to renter: %v", err)
+		}
+		return fmt.Errorf("renter %v: %v", rpk.String(), errRateLimited)
+	}
+	exists, err := p.satellite.UserExists(rpk)
+	if !exists || err != nil {
+		return fmt.Errorf("could not find renter in the database: %v", err)
+	}
+
+	contracts := p.satellite.RenterContracts(rpk)
+	cs := contractSet{
+		contracts:   make([]rhpv2.ContractRevision, 0, len(contracts)),
+		compression: s.compression,
+	}
+	for _, contract := range contracts {
+		cs.contracts = append(cs.contracts, convertContract(contract))
+	}
+
+	return s.writeResponse(&cs)
+}
+
 // convertContract converts the contract metadata from `siad`-style
 // into `core`-style.
 func convertContract(c modules.RenterContract) rhpv2.ContractRevision {
@@ -0,0 +1,22 @@
+package provider
+
+import (
+	core "go.sia.tech/core/types"
+	"go.sia.tech/siad/crypto"
+)
+
+// signedHash returns the hash the renter signs. If the renter has
+// advertised capabilityChallenge, the request hash is bound to the session
+// challenge, so that the request can't be replayed in another session.
+// The older renters only sign the request hash.
+func (s *rpcSession) signedHash(hash core.Hash256) crypto.Hash {
+	if !s.signChallenge {
+		return crypto.Hash(hash)
+	}
+	return crypto.HashAll(hash, s.challenge)
+}
+
+// verifyRequest checks the renter signature of the request.
+func (s *rpcSession) verifyRequest(hash core.Hash256, pk crypto.PublicKey, sig core.Signature) error {
+	return crypto.VerifyHash(s.signedHash(hash), pk, crypto.Signature(sig))
+}
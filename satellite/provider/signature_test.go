@@ -0,0 +1,46 @@
+package provider
+
+import (
+	"testing"
+
+	"gitlab.com/NebulousLabs/fastrand"
+	core "go.sia.tech/core/types"
+	"go.sia.tech/siad/crypto"
+)
+
+// TestVerifyRequest tests that the renters advertising capabilityChallenge
+// sign the session challenge, and that their requests can't be replayed in
+// another session, while the older renters keep working.
+func TestVerifyRequest(t *testing.T) {
+	sk, pk := crypto.GenerateKeyPair()
+	var hash core.Hash256
+	fastrand.Read(hash[:])
+
+	// An older renter signs the request hash.
+	legacy := &rpcSession{}
+	sig := crypto.SignHash(crypto.Hash(hash), sk)
+	if err := legacy.verifyRequest(hash, pk, core.Signature(sig)); err != nil {
+		t.Fatal("legacy signature rejected:", err)
+	}
+
+	// A newer renter signs the hash together with the challenge.
+	s1 := &rpcSession{signChallenge: true}
+	fastrand.Read(s1.challenge[:])
+	sig = crypto.SignHash(crypto.HashAll(hash, s1.challenge), sk)
+	if err := s1.verifyRequest(hash, pk, core.Signature(sig)); err != nil {
+		t.Fatal("signature rejected:", err)
+	}
+
+	// The same request is rejected in another session.
+	s2 := &rpcSession{signChallenge: true}
+	fastrand.Read(s2.challenge[:])
+	if err := s2.verifyRequest(hash, pk, core.Signature(sig)); err == nil {
+		t.Fatal("replayed request accepted")
+	}
+
+	// A request not bound to the challenge is rejected too.
+	sig = crypto.SignHash(crypto.Hash(hash), sk)
+	if err := s1.verifyRequest(hash, pk, core.Signature(sig)); err == nil {
+		t.Fatal("unbound request accepted")
+	}
+}
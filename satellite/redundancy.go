@@ -0,0 +1,59 @@
+package satellite
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/mike76-dev/sia-satellite/modules"
+
+	"go.sia.tech/siad/types"
+)
+
+// RedundancyHealth reports the redundancy the renter's GoodForUpload
+// contracts allow for the given erasure coding parameters. If the
+// parameters are zero, they are derived from the renter's allowance, with
+// one shard per host.
+func (s *Satellite) RedundancyHealth(rpk types.SiaPublicKey, minShards, totalShards uint64) (modules.RedundancyHealth, error) {
+	renter, err := s.GetRenter(rpk)
+	if err != nil {
+		return modules.RedundancyHealth{}, err
+	}
+	return redundancyHealth(renter, s.Contracts(), minShards, totalShards)
+}
+
+// redundancyHealth reports the redundancy the renter's GoodForUpload
+// contracts among the given ones allow.
+func redundancyHealth(renter modules.Renter, contracts []modules.RenterContract, minShards, totalShards uint64) (modules.RedundancyHealth, error) {
+	if minShards == 0 && totalShards == 0 {
+		a := renter.Allowance
+		totalShards = a.Hosts
+		if a.ExpectedRedundancy > 0 {
+			minShards = uint64(math.Round(float64(a.Hosts) / a.ExpectedRedundancy))
+		}
+	}
+	if minShards == 0 || totalShards < minShards {
+		return modules.RedundancyHealth{}, errors.New("invalid erasure coding parameters")
+	}
+
+	rh := modules.RedundancyHealth{
+		MinShards:   minShards,
+		TotalShards: totalShards,
+	}
+	for _, contract := range contracts {
+		if contract.RenterPublicKey.String() == renter.PublicKey.String() && contract.Utility.GoodForUpload {
+			rh.GFUContracts++
+		}
+	}
+	rh.Redundancy = float64(rh.GFUContracts) / float64(totalShards)
+	rh.MeetsMinShards = rh.GFUContracts >= minShards
+
+	switch {
+	case !rh.MeetsMinShards:
+		rh.Warning = fmt.Sprintf("only %v GoodForUpload contracts, at least %v needed to upload any data", rh.GFUContracts, minShards)
+	case rh.GFUContracts < totalShards:
+		rh.Warning = fmt.Sprintf("only %v GoodForUpload contracts, %v needed for the full redundancy", rh.GFUContracts, totalShards)
+	}
+
+	return rh, nil
+}
@@ -0,0 +1,76 @@
+package satellite
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mike76-dev/sia-satellite/modules"
+
+	"go.sia.tech/siad/crypto"
+	smodules "go.sia.tech/siad/modules"
+	"go.sia.tech/siad/types"
+)
+
+// TestRedundancyHealth tests the redundancy and the warning reported for
+// the GoodForUpload contract counts above and below the shard
+// requirements.
+func TestRedundancyHealth(t *testing.T) {
+	var pk, other crypto.PublicKey
+	pk[0], other[0] = 1, 2
+	renter := modules.Renter{
+		PublicKey: types.Ed25519PublicKey(pk),
+		Allowance: smodules.Allowance{Hosts: 30, ExpectedRedundancy: 3},
+	}
+
+	// The contracts of other renters and the ones not GoodForUpload don't
+	// count.
+	contracts := func(gfu int) []modules.RenterContract {
+		rcs := []modules.RenterContract{
+			{RenterPublicKey: types.Ed25519PublicKey(other), Utility: smodules.ContractUtility{GoodForUpload: true}},
+			{RenterPublicKey: renter.PublicKey},
+		}
+		for i := 0; i < gfu; i++ {
+			rcs = append(rcs, modules.RenterContract{
+				RenterPublicKey: renter.PublicKey,
+				Utility:         smodules.ContractUtility{GoodForUpload: true},
+			})
+		}
+		return rcs
+	}
+
+	tests := []struct {
+		name        string
+		minShards   uint64
+		totalShards uint64
+		gfu         int
+		redundancy  float64
+		meets       bool
+		warning     string
+	}{
+		{"full", 10, 30, 30, 1, true, ""},
+		{"above", 10, 30, 36, 1.2, true, ""},
+		{"below total", 10, 30, 15, 0.5, true, "15 GoodForUpload contracts, 30 needed"},
+		{"below min", 10, 30, 6, 0.2, false, "6 GoodForUpload contracts, at least 10 needed"},
+		{"from allowance", 0, 0, 9, 0.3, false, "9 GoodForUpload contracts, at least 10 needed"},
+	}
+	for _, tt := range tests {
+		rh, err := redundancyHealth(renter, contracts(tt.gfu), tt.minShards, tt.totalShards)
+		if err != nil {
+			t.Fatalf("%v: %v", tt.name, err)
+		}
+		if rh.MinShards != 10 || rh.TotalShards != 30 || rh.GFUContracts != uint64(tt.gfu) {
+			t.Fatalf("%v: unexpected health %+v", tt.name, rh)
+		}
+		if rh.Redundancy != tt.redundancy || rh.MeetsMinShards != tt.meets {
+			t.Fatalf("%v: expected redundancy %v and %v, got %v and %v", tt.name, tt.redundancy, tt.meets, rh.Redundancy, rh.MeetsMinShards)
+		}
+		if (tt.warning == "") != (rh.Warning == "") || !strings.Contains(rh.Warning, tt.warning) {
+			t.Fatalf("%v: expected warning %q, got %q", tt.name, tt.warning, rh.Warning)
+		}
+	}
+
+	// The parameters must allow for the data to be recovered.
+	if _, err := redundancyHealth(renter, nil, 20, 10); err == nil {
+		t.Fatal("expected the invalid parameters to be rejected")
+	}
+}
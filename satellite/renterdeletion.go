@@ -0,0 +1,117 @@
+package satellite
+
+import (
+	"errors"
+
+	"github.com/mike76-dev/sia-satellite/modules"
+
+	"go.sia.tech/siad/types"
+)
+
+var (
+	// errBulkDeleteUnconfirmed is returned when a bulk deletion of the
+	// renters is requested without the matching confirmation token.
+	errBulkDeleteUnconfirmed = errors.New("bulk deletion not confirmed")
+
+	// errRenterHasContracts is returned when a renter with active
+	// contracts is to be deleted without the force flag.
+	errRenterHasContracts = errors.New("renter has active contracts")
+
+	// errNoRenters is returned when there are no renters with the email.
+	errNoRenters = errors.New("no renters with this email")
+)
+
+// renterManager is the part of the manager used when deleting the
+// renters.
+type renterManager interface {
+	Renters() []modules.Renter
+	RenterContracts(types.SiaPublicKey) []modules.RenterContract
+	DeleteRenter(types.SiaPublicKey)
+}
+
+// DeleteRenters deletes the renters with the given emails, together with
+// their API tokens. The renters with active, non-expired contracts are
+// skipped unless force is set; their contracts are kept but won't be
+// renewed anymore. token must be the modules.BulkDeleteToken of the
+// request.
+func (s *Satellite) DeleteRenters(emails []string, force bool, token string) ([]modules.RenterDeletion, error) {
+	if token != modules.BulkDeleteToken(emails, force) {
+		return nil, errBulkDeleteUnconfirmed
+	}
+	return s.deleteRenterAccounts(s.m, emails, force, s.BlockHeight()), nil
+}
+
+// deleteRenterAccounts deletes the renters with the given emails from the
+// database and from the manager, and returns the outcome per email.
+func (s *Satellite) deleteRenterAccounts(m renterManager, emails []string, force bool, height types.BlockHeight) []modules.RenterDeletion {
+	byEmail := make(map[string][]types.SiaPublicKey)
+	for _, renter := range m.Renters() {
+		byEmail[renter.Email] = append(byEmail[renter.Email], renter.PublicKey)
+	}
+
+	results := make([]modules.RenterDeletion, 0, len(emails))
+	for _, email := range emails {
+		rd := modules.RenterDeletion{Email: email}
+		keys := byEmail[email]
+		if len(keys) == 0 {
+			rd.Error = errNoRenters.Error()
+			results = append(results, rd)
+			continue
+		}
+		if !force && hasActiveContracts(m, keys, height) {
+			rd.Error = errRenterHasContracts.Error()
+			results = append(results, rd)
+			continue
+		}
+		if err := s.deleteRenters(email, keys); err != nil {
+			rd.Error = err.Error()
+			results = append(results, rd)
+			continue
+		}
+		for _, rpk := range keys {
+			m.DeleteRenter(rpk)
+		}
+		rd.Deleted = len(keys)
+		s.log.Printf("INFO: deleted %v renters of %v\n", len(keys), email)
+		results = append(results, rd)
+	}
+
+	return results
+}
+
+// hasActiveContracts returns true if any of the renters has a contract
+// that hasn't been canceled and hasn't expired yet.
+func hasActiveContracts(m renterManager, keys []types.SiaPublicKey, height types.BlockHeight) bool {
+	for _, rpk := range keys {
+		for _, contract := range m.RenterContracts(rpk) {
+			if contract.EndHeight > height {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// deleteRenters removes the renter records, the API tokens and the host
+// allowlists of the renters from the database in a single transaction.
+func (s *Satellite) deleteRenters(email string, keys []types.SiaPublicKey) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	for _, rpk := range keys {
+		if _, err := tx.Exec("DELETE FROM renter_tokens WHERE renter_pk = ?", rpk.String()); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if _, err := tx.Exec("DELETE FROM renter_host_allowlists WHERE renter_pk = ?", rpk.String()); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	if _, err := tx.Exec("DELETE FROM renters WHERE email = ?", email); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,122 @@
+package satellite
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/mike76-dev/sia-satellite/internal/dbtest"
+	"github.com/mike76-dev/sia-satellite/modules"
+
+	"gitlab.com/NebulousLabs/errors"
+
+	"go.sia.tech/siad/crypto"
+	"go.sia.tech/siad/persist"
+	"go.sia.tech/siad/types"
+)
+
+// testRenterManager is a manager with a fixed set of renters and
+// contracts.
+type testRenterManager struct {
+	renters   []modules.Renter
+	contracts []modules.RenterContract
+	deleted   []types.SiaPublicKey
+}
+
+// Renters implements renterManager.
+func (m *testRenterManager) Renters() []modules.Renter {
+	return m.renters
+}
+
+// RenterContracts implements renterManager.
+func (m *testRenterManager) RenterContracts(rpk types.SiaPublicKey) []modules.RenterContract {
+	var contracts []modules.RenterContract
+	for _, contract := range m.contracts {
+		if contract.RenterPublicKey.Equals(rpk) {
+			contracts = append(contracts, contract)
+		}
+	}
+	return contracts
+}
+
+// DeleteRenter implements renterManager.
+func (m *testRenterManager) DeleteRenter(rpk types.SiaPublicKey) {
+	m.deleted = append(m.deleted, rpk)
+}
+
+// TestDeleteRenters tests that the renters are deleted together with
+// their tokens, that the renters with active contracts are only deleted
+// if forced, and that the deletion has to be confirmed.
+func TestDeleteRenters(t *testing.T) {
+	db, fake := dbtest.Open()
+	logger, err := persist.NewFileLogger(filepath.Join(t.TempDir(), "satellite.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer logger.Close()
+	s := &Satellite{db: db, log: logger}
+
+	key := func(b byte) types.SiaPublicKey {
+		var pk crypto.PublicKey
+		pk[0] = b
+		return types.Ed25519PublicKey(pk)
+	}
+	m := &testRenterManager{
+		renters: []modules.Renter{
+			{Email: "expired@example.com", PublicKey: key(1)},
+			{Email: "expired@example.com", PublicKey: key(2)},
+			{Email: "active@example.com", PublicKey: key(3)},
+		},
+		contracts: []modules.RenterContract{
+			{RenterPublicKey: key(1), EndHeight: 100},
+			{RenterPublicKey: key(3), EndHeight: 1000},
+		},
+	}
+
+	// An unconfirmed deletion is refused.
+	emails := []string{"expired@example.com", "active@example.com", "unknown@example.com"}
+	if _, err := s.DeleteRenters(emails, false, "wrong"); !errors.Contains(err, errBulkDeleteUnconfirmed) {
+		t.Fatal("expected errBulkDeleteUnconfirmed, got", err)
+	}
+	if len(fake.Execs()) != 0 {
+		t.Fatal("expected nothing deleted, got", fake.Execs())
+	}
+
+	// The renters without active contracts are deleted.
+	results := s.deleteRenterAccounts(m, emails, false, 500)
+	expected := []modules.RenterDeletion{
+		{Email: "expired@example.com", Deleted: 2},
+		{Email: "active@example.com", Error: errRenterHasContracts.Error()},
+		{Email: "unknown@example.com", Error: errNoRenters.Error()},
+	}
+	if len(results) != len(expected) {
+		t.Fatalf("expected %v results, got %v", len(expected), results)
+	}
+	for i, rd := range results {
+		if rd != expected[i] {
+			t.Fatalf("expected %+v, got %+v", expected[i], rd)
+		}
+	}
+	if len(m.deleted) != 2 || !m.deleted[0].Equals(key(1)) || !m.deleted[1].Equals(key(2)) {
+		t.Fatal("expected the expired renters deleted, got", m.deleted)
+	}
+	if n := len(fake.ExecsLike("DELETE FROM renter_tokens")); n != 2 {
+		t.Fatalf("expected 2 tokens deleted, got %v", n)
+	}
+	deletions := fake.ExecsLike("DELETE FROM renters")
+	if len(deletions) != 1 || deletions[0].Args[0] != "expired@example.com" {
+		t.Fatal("expected the expired account deleted, got", deletions)
+	}
+	if n := len(fake.ExecsLike("COMMIT")); n != 1 {
+		t.Fatalf("expected 1 transaction committed, got %v", n)
+	}
+
+	// The renter with active contracts is deleted if forced.
+	m.deleted = nil
+	results = s.deleteRenterAccounts(m, []string{"active@example.com"}, true, 500)
+	if len(results) != 1 || results[0].Deleted != 1 || results[0].Error != "" {
+		t.Fatal("expected the active renter deleted, got", results)
+	}
+	if len(m.deleted) != 1 || !m.deleted[0].Equals(key(3)) {
+		t.Fatal("expected the active renter deleted, got", m.deleted)
+	}
+}
@@ -3,11 +3,13 @@
 package satellite
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"os"
 	"path/filepath"
 	"sync"
+	"time"
 
 	"github.com/mike76-dev/sia-satellite/satellite/manager"
 	"github.com/mike76-dev/sia-satellite/satellite/provider"
@@ -49,6 +51,10 @@ type Satellite struct {
 	exchRates map[string]float64
 	scusdRate float64
 
+	// requireDeposit makes AddRenter reject the renters whose account
+	// balance doesn't cover the estimated cost of their allowance.
+	requireDeposit bool
+
 	// Submodules.
 	m *manager.Manager
 	p *provider.Provider
@@ -77,7 +83,7 @@ func (s *Satellite) SecretKey() crypto.SecretKey {
 }
 
 // New returns an initialized Satellite.
-func New(cs smodules.ConsensusSet, g smodules.Gateway, tpool smodules.TransactionPool, wallet smodules.Wallet, db *sql.DB, mux *siamux.SiaMux, satelliteAddr string, persistDir string) (*Satellite, error) {
+func New(cs smodules.ConsensusSet, g smodules.Gateway, tpool smodules.TransactionPool, wallet smodules.Wallet, db *sql.DB, mux *siamux.SiaMux, satelliteAddr string, persistDir string, logFormat string, rpcRate float64, maxExtension time.Duration, requireDeposit bool) (*Satellite, error) {
 	// Check that all the dependencies were provided.
 	if db == nil {
 		return nil, errNilDB
@@ -105,13 +111,13 @@ func New(cs smodules.ConsensusSet, g smodules.Gateway, tpool smodules.Transactio
 	}
 
 	// Create the manager.
-	m, errChanM := manager.New(cs, g, tpool, wallet, db, mux, persistDir)
+	m, errChanM := manager.New(cs, g, tpool, wallet, db, mux, persistDir, logFormat)
 	if err = smodules.PeekErr(errChanM); err != nil {
 		return nil, errors.AddContext(err, "unable to create manager")
 	}
 
 	// Create the provider.
-	p, errChanP := provider.New(g, satelliteAddr, persistDir)
+	p, errChanP := provider.New(g, satelliteAddr, persistDir, rpcRate, maxExtension)
 	if err = smodules.PeekErr(errChanP); err != nil {
 		return nil, errors.AddContext(err, "unable to create provider")
 	}
@@ -125,6 +131,8 @@ func New(cs smodules.ConsensusSet, g smodules.Gateway, tpool smodules.Transactio
 
 		exchRates: make(map[string]float64),
 
+		requireDeposit: requireDeposit,
+
 		db: db,
 		m:  m,
 		p:  p,
@@ -211,6 +219,11 @@ func (s *Satellite) Filter() (smodules.FilterMode, map[string]types.SiaPublicKey
 // SetFilterMode calls Manager.SetFilterMode.
 func (s *Satellite) SetFilterMode(lm smodules.FilterMode, hosts []types.SiaPublicKey, netAddresses []string) error { return s.m.SetFilterMode(lm, hosts, netAddresses) }
 
+// UpdateFilter calls Manager.UpdateFilter.
+func (s *Satellite) UpdateFilter(addHosts, removeHosts []types.SiaPublicKey, addNetAddresses, removeNetAddresses []string) (smodules.FilterMode, map[string]types.SiaPublicKey, []string, error) {
+	return s.m.UpdateFilter(addHosts, removeHosts, addNetAddresses, removeNetAddresses)
+}
+
 // Host calls Manager.Host.
 func (s *Satellite) Host(spk types.SiaPublicKey) (smodules.HostDBEntry, bool, error) { return s.m.Host(spk) }
 
@@ -252,8 +265,37 @@ func (s *Satellite) UserExists(rpk types.SiaPublicKey) (exists bool, err error)
 }
 
 // CreateNewRenter calls Manager.CreateNewRenter.
-func (s *Satellite) CreateNewRenter(email string, pk types.SiaPublicKey) {
-	s.m.CreateNewRenter(email, pk)
+func (s *Satellite) CreateNewRenter(email, suffix string, pk types.SiaPublicKey) {
+	s.m.CreateNewRenter(email, suffix, pk)
+}
+
+// AddRenter calls Manager.AddRenter. If the deposit is required, the
+// account balance must cover the estimated cost of the allowance.
+func (s *Satellite) AddRenter(email string, pk types.SiaPublicKey, a smodules.Allowance) (modules.Renter, error) {
+	if s.requireDeposit {
+		if err := s.checkDeposit(email, a, s.m.PriceEstimation); err != nil {
+			return modules.Renter{}, err
+		}
+	}
+	return s.m.AddRenter(email, pk, a)
+}
+
+// checkDeposit returns an error if the account balance is insufficient
+// to form the contracts according to the allowance. The cost of the
+// allowance is calculated by estimate.
+func (s *Satellite) checkDeposit(email string, a smodules.Allowance, estimate func(smodules.Allowance) (float64, smodules.Allowance, error)) error {
+	estimation, _, err := estimate(a)
+	if err != nil {
+		return errors.AddContext(err, "unable to estimate the allowance cost")
+	}
+	ub, err := s.GetBalance(email)
+	if err != nil {
+		return errors.AddContext(err, "unable to retrieve the account balance")
+	}
+	if ub.SCBalance < estimation {
+		return fmt.Errorf("insufficient deposit: the allowance requires %.2f SC, but only %.2f SC are available", estimation, ub.SCBalance)
+	}
+	return nil
 }
 
 // GetRenter calls Manager.GetRenter.
@@ -266,9 +308,136 @@ func (s *Satellite) Renters() []modules.Renter {
 	return s.m.Renters()
 }
 
+// CheckRenterConsistency calls Manager.CheckRenterConsistency.
+func (s *Satellite) CheckRenterConsistency(repair bool) ([]modules.RenterInconsistency, error) {
+	return s.m.CheckRenterConsistency(repair)
+}
+
+// ContractorSettings calls Manager.ContractorSettings.
+func (s *Satellite) ContractorSettings() modules.ContractorSettings {
+	return s.m.ContractorSettings()
+}
+
+// SetContractorSettings calls Manager.SetContractorSettings.
+func (s *Satellite) SetContractorSettings(cs modules.ContractorSettings) error {
+	return s.m.SetContractorSettings(cs)
+}
+
+// DebugSnapshot calls Manager.DebugSnapshot.
+func (s *Satellite) DebugSnapshot() modules.ContractorSnapshot {
+	return s.m.DebugSnapshot()
+}
+
+// MaintenanceTimings calls Manager.MaintenanceTimings.
+func (s *Satellite) MaintenanceTimings() modules.MaintenanceTimings {
+	return s.m.MaintenanceTimings()
+}
+
+// SetNoRefresh calls Manager.SetNoRefresh.
+func (s *Satellite) SetNoRefresh(id types.FileContractID, noRefresh bool) error {
+	return s.m.SetNoRefresh(id, noRefresh)
+}
+
+// FormationScore calls Manager.FormationScore.
+func (s *Satellite) FormationScore(id types.FileContractID) (types.Currency, bool) {
+	return s.m.FormationScore(id)
+}
+
+// WalletUsage calls Manager.WalletUsage.
+func (s *Satellite) WalletUsage() (modules.WalletUsage, error) {
+	return s.m.WalletUsage()
+}
+
+// SetAllowRedundantIPs calls Manager.SetAllowRedundantIPs.
+func (s *Satellite) SetAllowRedundantIPs(rpk types.SiaPublicKey, allow bool) error {
+	return s.m.SetAllowRedundantIPs(rpk, allow)
+}
+
+// SetMaxStorageBytes calls Manager.SetMaxStorageBytes.
+func (s *Satellite) SetMaxStorageBytes(rpk types.SiaPublicKey, maxStorage uint64) error {
+	return s.m.SetMaxStorageBytes(rpk, maxStorage)
+}
+
+// SetMaxContractsPerRegion calls Manager.SetMaxContractsPerRegion.
+func (s *Satellite) SetMaxContractsPerRegion(rpk types.SiaPublicKey, maxContracts uint64) error {
+	return s.m.SetMaxContractsPerRegion(rpk, maxContracts)
+}
+
+// SetPreferCollateral calls Manager.SetPreferCollateral.
+func (s *Satellite) SetPreferCollateral(rpk types.SiaPublicKey, prefer bool) error {
+	return s.m.SetPreferCollateral(rpk, prefer)
+}
+
+// SetSpendRateRefresh calls Manager.SetSpendRateRefresh.
+func (s *Satellite) SetSpendRateRefresh(rpk types.SiaPublicKey, enabled bool) error {
+	return s.m.SetSpendRateRefresh(rpk, enabled)
+}
+
+// SetMaxHostLatency calls Manager.SetMaxHostLatency.
+func (s *Satellite) SetMaxHostLatency(rpk types.SiaPublicKey, latency time.Duration) error {
+	return s.m.SetMaxHostLatency(rpk, latency)
+}
+
+// SetLocalRegion calls Manager.SetLocalRegion.
+func (s *Satellite) SetLocalRegion(rpk types.SiaPublicKey, region string, fraction float64) error {
+	return s.m.SetLocalRegion(rpk, region, fraction)
+}
+
+// SetReuseRefundAddress calls Manager.SetReuseRefundAddress.
+func (s *Satellite) SetReuseRefundAddress(rpk types.SiaPublicKey, enabled bool) error {
+	return s.m.SetReuseRefundAddress(rpk, enabled)
+}
+
+// HostAllowlist calls Manager.HostAllowlist.
+func (s *Satellite) HostAllowlist(rpk types.SiaPublicKey) ([]types.SiaPublicKey, error) {
+	return s.m.HostAllowlist(rpk)
+}
+
+// SetHostAllowlist calls Manager.SetHostAllowlist.
+func (s *Satellite) SetHostAllowlist(rpk types.SiaPublicKey, hosts []types.SiaPublicKey) error {
+	return s.m.SetHostAllowlist(rpk, hosts)
+}
+
+// PauseRenter calls Manager.PauseRenter.
+func (s *Satellite) PauseRenter(email string) error {
+	return s.m.PauseRenter(email)
+}
+
+// ResumeRenter calls Manager.ResumeRenter.
+func (s *Satellite) ResumeRenter(email string) error {
+	return s.m.ResumeRenter(email)
+}
+
+// ContractsDue calls Manager.ContractsDue.
+func (s *Satellite) ContractsDue(within types.BlockHeight) ([]modules.DueContract, error) {
+	return s.m.ContractsDue(within)
+}
+
+// RenewalFeeBreakdown calls Manager.RenewalFeeBreakdown.
+func (s *Satellite) RenewalFeeBreakdown(rpk types.SiaPublicKey) ([]modules.RenewalFeeBreakdown, error) {
+	return s.m.RenewalFeeBreakdown(rpk)
+}
+
+// Runway calls Manager.Runway.
+func (s *Satellite) Runway(rpk types.SiaPublicKey) (modules.RenterRunway, error) {
+	return s.m.Runway(rpk)
+}
+
+// RenewAllDue calls Manager.RenewAllDue.
+func (s *Satellite) RenewAllDue(maxSpend types.Currency) ([]modules.RenewalSummary, error) {
+	return s.m.RenewAllDue(maxSpend)
+}
+
+// HostDecision calls Manager.HostDecision.
+func (s *Satellite) HostDecision(rpk, hpk types.SiaPublicKey) (modules.HostDecision, error) {
+	return s.m.HostDecision(rpk, hpk)
+}
+
 // FormContracts forms the specified number of contracts with the hosts
-// and returns them.
-func (s *Satellite) FormContracts(rpk types.SiaPublicKey, a smodules.Allowance) ([]modules.RenterContract, error) {
+// and returns them. The preferred hosts are attempted first. If ctx is
+// canceled, no further contracts are formed and the ones formed so far
+// are returned.
+func (s *Satellite) FormContracts(ctx context.Context, rpk types.SiaPublicKey, a smodules.Allowance, preferred []types.SiaPublicKey) ([]modules.RenterContract, error) {
 	// Get the estimated costs and update the allowance with them.
 	estimation, a, err := s.m.PriceEstimation(a)
 	if err != nil {
@@ -295,21 +464,63 @@ func (s *Satellite) FormContracts(rpk types.SiaPublicKey, a smodules.Allowance)
 	}
 
 	// Form the contracts.
-	contractSet, err := s.m.FormContracts(rpk)
+	contractSet, err := s.m.FormContracts(ctx, rpk, preferred)
 
 	return contractSet, err
 }
 
+// PreviewContracts returns the hosts that FormContracts would attempt to
+// form the contracts with, together with the projected funding.
+func (s *Satellite) PreviewContracts(ctx context.Context, rpk types.SiaPublicKey, a smodules.Allowance, preferred []types.SiaPublicKey) ([]modules.FormationPreview, error) {
+	// Update the allowance with the estimated costs.
+	_, a, err := s.m.PriceEstimation(a)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.m.PreviewContracts(ctx, rpk, a, preferred)
+}
+
 // Contracts calls Manager.Contracts.
 func (s *Satellite) Contracts() []modules.RenterContract {
 	return s.m.Contracts()
 }
 
+// RenterContracts calls Manager.RenterContracts.
+func (s *Satellite) RenterContracts(rpk types.SiaPublicKey) []modules.RenterContract {
+	return s.m.RenterContracts(rpk)
+}
+
 // RefreshedContract calls Manager.RefreshedContract
 func (s *Satellite) RefreshedContract(fcid types.FileContractID) bool {
 	return s.m.RefreshedContract(fcid)
 }
 
+// ContractLineage calls Manager.ContractLineage.
+func (s *Satellite) ContractLineage(fcid types.FileContractID) ([]modules.ContractLineageLink, error) {
+	return s.m.ContractLineage(fcid)
+}
+
+// ContractUtilityHistory calls Manager.ContractUtilityHistory.
+func (s *Satellite) ContractUtilityHistory(fcid types.FileContractID) ([]modules.UtilityTransition, error) {
+	return s.m.ContractUtilityHistory(fcid)
+}
+
+// WatchdogStatus calls Manager.WatchdogStatus.
+func (s *Satellite) WatchdogStatus() modules.WatchdogStatus {
+	return s.m.WatchdogStatus()
+}
+
+// RenewFailures calls Manager.RenewFailures.
+func (s *Satellite) RenewFailures() []modules.RenewFailure {
+	return s.m.RenewFailures()
+}
+
+// FundsAtRisk calls Manager.FundsAtRisk.
+func (s *Satellite) FundsAtRisk() modules.FundsAtRisk {
+	return s.m.FundsAtRisk()
+}
+
 // OldContracts calls Manager.OldContracts expired.
 func (s *Satellite) OldContracts() []modules.RenterContract {
 	return s.m.OldContracts()
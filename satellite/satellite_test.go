@@ -0,0 +1,62 @@
+package satellite
+
+import (
+	"database/sql/driver"
+	"strings"
+	"testing"
+
+	"github.com/mike76-dev/sia-satellite/internal/dbtest"
+
+	"gitlab.com/NebulousLabs/errors"
+
+	smodules "go.sia.tech/siad/modules"
+)
+
+// TestCheckDeposit tests that a renter is only added if the account
+// balance covers the estimated cost of the allowance.
+func TestCheckDeposit(t *testing.T) {
+	db, fake := dbtest.Open()
+	s := &Satellite{
+		db:        db,
+		exchRates: map[string]float64{"USD": 1},
+		scusdRate: 0.01,
+	}
+
+	// The account holds 10 USD, which is 1000 SC.
+	fake.OnQuery(func(query string, args []driver.Value) (*dbtest.Rows, error) {
+		if strings.Contains(query, "FROM balances") && args[0] == "renter@example.com" {
+			return &dbtest.Rows{
+				Columns: []string{"subscribed", "balance", "locked", "currency", "stripe_id"},
+				Values:  [][]driver.Value{{false, 10.0, 0.0, "USD", ""}},
+			}, nil
+		}
+		return nil, nil
+	})
+	estimate := func(cost float64) func(smodules.Allowance) (float64, smodules.Allowance, error) {
+		return func(a smodules.Allowance) (float64, smodules.Allowance, error) {
+			return cost, a, nil
+		}
+	}
+	a := smodules.DefaultAllowance
+
+	if err := s.checkDeposit("renter@example.com", a, estimate(800)); err != nil {
+		t.Fatal("expected a sufficient deposit, got", err)
+	}
+	if err := s.checkDeposit("renter@example.com", a, estimate(1200)); err == nil || !strings.Contains(err.Error(), "insufficient deposit") {
+		t.Fatal("expected an insufficient deposit, got", err)
+	}
+
+	// An account without a balance has no deposit.
+	if err := s.checkDeposit("other@example.com", a, estimate(1)); err == nil || !strings.Contains(err.Error(), "insufficient deposit") {
+		t.Fatal("expected an insufficient deposit, got", err)
+	}
+
+	// The renter isn't added if the cost can't be estimated.
+	errEstimate := errors.New("no hosts")
+	failing := func(smodules.Allowance) (float64, smodules.Allowance, error) {
+		return 0, smodules.Allowance{}, errEstimate
+	}
+	if err := s.checkDeposit("renter@example.com", a, failing); !errors.Contains(err, errEstimate) {
+		t.Fatal("expected the estimation error, got", err)
+	}
+}
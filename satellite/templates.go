@@ -0,0 +1,149 @@
+package satellite
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/mike76-dev/sia-satellite/modules"
+
+	smodules "go.sia.tech/siad/modules"
+	"go.sia.tech/siad/types"
+)
+
+// AllowanceTemplates returns the allowance templates.
+func (s *Satellite) AllowanceTemplates() ([]modules.AllowanceTemplate, error) {
+	rows, err := s.db.Query(`
+		SELECT name, funds, hosts, period, renew_window,
+			expected_storage, expected_upload, expected_download, expected_redundancy,
+			max_rpc_price, max_contract_price, max_download_bandwidth_price,
+			max_sector_access_price, max_storage_price, max_upload_bandwidth_price
+		FROM allowance_templates
+		ORDER BY name ASC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var templates []modules.AllowanceTemplate
+	for rows.Next() {
+		t, err := scanAllowanceTemplate(rows)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, t)
+	}
+
+	return templates, rows.Err()
+}
+
+// AllowanceTemplate returns the allowance template with the given name.
+func (s *Satellite) AllowanceTemplate(name string) (modules.AllowanceTemplate, error) {
+	row := s.db.QueryRow(`
+		SELECT name, funds, hosts, period, renew_window,
+			expected_storage, expected_upload, expected_download, expected_redundancy,
+			max_rpc_price, max_contract_price, max_download_bandwidth_price,
+			max_sector_access_price, max_storage_price, max_upload_bandwidth_price
+		FROM allowance_templates
+		WHERE name = ?
+	`, name)
+	t, err := scanAllowanceTemplate(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return modules.AllowanceTemplate{}, modules.ErrTemplateNotFound
+	}
+	return t, err
+}
+
+// scanAllowanceTemplate reads an allowance template from a database row.
+func scanAllowanceTemplate(row interface{ Scan(...interface{}) error }) (modules.AllowanceTemplate, error) {
+	var name, funds string
+	var hosts, period, renewWindow, storage, upload, download uint64
+	var redundancy float64
+	var maxRPC, maxContract, maxDownload, maxSectorAccess, maxStorage, maxUpload string
+	err := row.Scan(&name, &funds, &hosts, &period, &renewWindow, &storage, &upload, &download, &redundancy, &maxRPC, &maxContract, &maxDownload, &maxSectorAccess, &maxStorage, &maxUpload)
+	if err != nil {
+		return modules.AllowanceTemplate{}, err
+	}
+
+	return modules.AllowanceTemplate{
+		Name: name,
+		Allowance: smodules.Allowance{
+			Funds:       modules.ReadCurrency(funds),
+			Hosts:       hosts,
+			Period:      types.BlockHeight(period),
+			RenewWindow: types.BlockHeight(renewWindow),
+
+			ExpectedStorage:    storage,
+			ExpectedUpload:     upload,
+			ExpectedDownload:   download,
+			ExpectedRedundancy: redundancy,
+
+			MaxRPCPrice:               modules.ReadCurrency(maxRPC),
+			MaxContractPrice:          modules.ReadCurrency(maxContract),
+			MaxDownloadBandwidthPrice: modules.ReadCurrency(maxDownload),
+			MaxSectorAccessPrice:      modules.ReadCurrency(maxSectorAccess),
+			MaxStoragePrice:           modules.ReadCurrency(maxStorage),
+			MaxUploadBandwidthPrice:   modules.ReadCurrency(maxUpload),
+		},
+	}, nil
+}
+
+// SaveAllowanceTemplate creates or replaces an allowance template.
+func (s *Satellite) SaveAllowanceTemplate(t modules.AllowanceTemplate) error {
+	if t.Name == "" {
+		return errors.New("template name not specified")
+	}
+	if err := modules.ValidateAllowance(t.Allowance, 0, 0); err != nil {
+		return err
+	}
+
+	// Check if there is a record already.
+	var c int
+	err := s.db.QueryRow("SELECT COUNT(*) FROM allowance_templates WHERE name = ?", t.Name).Scan(&c)
+	if err != nil {
+		return err
+	}
+
+	a := t.Allowance
+
+	// There is a record.
+	if c > 0 {
+		_, err := s.db.Exec(`
+			UPDATE allowance_templates
+			SET funds = ?, hosts = ?, period = ?, renew_window = ?,
+				expected_storage = ?, expected_upload = ?, expected_download = ?,
+				expected_redundancy = ?, max_rpc_price = ?, max_contract_price = ?,
+				max_download_bandwidth_price = ?, max_sector_access_price = ?,
+				max_storage_price = ?, max_upload_bandwidth_price = ?
+			WHERE name = ?
+		`, a.Funds.String(), a.Hosts, uint64(a.Period), uint64(a.RenewWindow), a.ExpectedStorage, a.ExpectedUpload, a.ExpectedDownload, a.ExpectedRedundancy, a.MaxRPCPrice.String(), a.MaxContractPrice.String(), a.MaxDownloadBandwidthPrice.String(), a.MaxSectorAccessPrice.String(), a.MaxStoragePrice.String(), a.MaxUploadBandwidthPrice.String(), t.Name)
+		return err
+	}
+
+	// No records found.
+	_, err = s.db.Exec(`
+		INSERT INTO allowance_templates (name, funds, hosts, period, renew_window,
+			expected_storage, expected_upload, expected_download, expected_redundancy,
+			max_rpc_price, max_contract_price, max_download_bandwidth_price,
+			max_sector_access_price, max_storage_price, max_upload_bandwidth_price)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, t.Name, a.Funds.String(), a.Hosts, uint64(a.Period), uint64(a.RenewWindow), a.ExpectedStorage, a.ExpectedUpload, a.ExpectedDownload, a.ExpectedRedundancy, a.MaxRPCPrice.String(), a.MaxContractPrice.String(), a.MaxDownloadBandwidthPrice.String(), a.MaxSectorAccessPrice.String(), a.MaxStoragePrice.String(), a.MaxUploadBandwidthPrice.String())
+
+	return err
+}
+
+// DeleteAllowanceTemplate removes the allowance template.
+func (s *Satellite) DeleteAllowanceTemplate(name string) error {
+	res, err := s.db.Exec("DELETE FROM allowance_templates WHERE name = ?", name)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return modules.ErrTemplateNotFound
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package satellite
+
+import (
+	"database/sql/driver"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mike76-dev/sia-satellite/internal/dbtest"
+	"github.com/mike76-dev/sia-satellite/modules"
+
+	"gitlab.com/NebulousLabs/errors"
+
+	smodules "go.sia.tech/siad/modules"
+	"go.sia.tech/siad/types"
+)
+
+// TestAllowanceTemplates tests that an allowance template is saved and
+// read back unchanged, and that an existing template is replaced.
+func TestAllowanceTemplates(t *testing.T) {
+	db, fake := dbtest.Open()
+	s := &Satellite{db: db}
+
+	// The database holds the columns of the last saved template.
+	var saved []driver.Value
+	fake.OnExec(func(query string, args []driver.Value) error {
+		if strings.Contains(query, "INSERT INTO allowance_templates") {
+			saved = args
+		}
+		return nil
+	})
+	fake.OnQuery(func(query string, args []driver.Value) (*dbtest.Rows, error) {
+		if !strings.Contains(query, "FROM allowance_templates") || saved == nil || args[0] != saved[0] {
+			return nil, nil
+		}
+		if strings.Contains(query, "COUNT(*)") {
+			return &dbtest.Rows{Columns: []string{"count"}, Values: [][]driver.Value{{int64(1)}}}, nil
+		}
+		return &dbtest.Rows{Columns: make([]string, len(saved)), Values: [][]driver.Value{saved}}, nil
+	})
+
+	template := modules.AllowanceTemplate{
+		Name: "standard",
+		Allowance: smodules.Allowance{
+			Funds:              types.SiacoinPrecision.Mul64(1000),
+			Hosts:              30,
+			Period:             4032,
+			RenewWindow:        1008,
+			ExpectedStorage:    1e12,
+			ExpectedUpload:     1e11,
+			ExpectedDownload:   1e11,
+			ExpectedRedundancy: 3,
+			MaxStoragePrice:    types.SiacoinPrecision.Div64(1e6),
+		},
+	}
+	if err := s.SaveAllowanceTemplate(template); err != nil {
+		t.Fatal(err)
+	}
+	got, err := s.AllowanceTemplate("standard")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, template) {
+		t.Fatalf("expected %+v, got %+v", template, got)
+	}
+
+	// Saving the template again replaces it.
+	template.Allowance.Hosts = 50
+	if err := s.SaveAllowanceTemplate(template); err != nil {
+		t.Fatal(err)
+	}
+	updates := fake.ExecsLike("UPDATE allowance_templates")
+	if len(updates) != 1 || updates[0].Args[1] != int64(50) || updates[0].Args[len(updates[0].Args) - 1] != "standard" {
+		t.Fatal("expected the template to be updated, got", updates)
+	}
+
+	// An unknown template isn't found, and an invalid one isn't saved.
+	if _, err := s.AllowanceTemplate("premium"); !errors.Contains(err, modules.ErrTemplateNotFound) {
+		t.Fatal("expected ErrTemplateNotFound, got", err)
+	}
+	if err := s.SaveAllowanceTemplate(modules.AllowanceTemplate{Name: "empty"}); err == nil {
+		t.Fatal("expected an invalid allowance to be rejected")
+	}
+}
@@ -0,0 +1,72 @@
+package satellite
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/mike76-dev/sia-satellite/modules"
+
+	"gitlab.com/NebulousLabs/fastrand"
+
+	"go.sia.tech/siad/crypto"
+	"go.sia.tech/siad/types"
+)
+
+// renterTokenSize is the size of a renter API token in bytes.
+const renterTokenSize = 32
+
+// hashRenterToken returns the hash of the renter API token as it is
+// stored in the database.
+func hashRenterToken(token string) string {
+	return crypto.HashBytes([]byte(token)).String()
+}
+
+// IssueRenterToken generates a new API token giving access to the renter's
+// own data, replacing the previous one. Only the hash of the token is
+// stored, so the token can't be retrieved later.
+func (s *Satellite) IssueRenterToken(rpk types.SiaPublicKey) (string, error) {
+	if _, err := s.GetRenter(rpk); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(fastrand.Bytes(renterTokenSize))
+	_, err := s.db.Exec(`
+		REPLACE INTO renter_tokens (renter_pk, token_hash, created)
+		VALUES (?, ?, ?)
+	`, rpk.String(), hashRenterToken(token), time.Now().Unix())
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// RevokeRenterToken removes the renter's API token.
+func (s *Satellite) RevokeRenterToken(rpk types.SiaPublicKey) error {
+	res, err := s.db.Exec("DELETE FROM renter_tokens WHERE renter_pk = ?", rpk.String())
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return modules.ErrRenterTokenNotFound
+	}
+	return nil
+}
+
+// VerifyRenterToken returns true if the token is the renter's API token.
+func (s *Satellite) VerifyRenterToken(rpk types.SiaPublicKey, token string) bool {
+	if token == "" {
+		return false
+	}
+	var stored string
+	err := s.db.QueryRow("SELECT token_hash FROM renter_tokens WHERE renter_pk = ?", rpk.String()).Scan(&stored)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false
+	}
+	if err != nil {
+		s.log.Println("ERROR: unable to retrieve renter token:", err)
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(stored), []byte(hashRenterToken(token))) == 1
+}